@@ -4,7 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
-	"net"
+	"strings"
 
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
@@ -20,7 +20,6 @@ import (
 	"github.com/operator-framework/operator-registry/pkg/lib/dns"
 	"github.com/operator-framework/operator-registry/pkg/lib/graceful"
 	"github.com/operator-framework/operator-registry/pkg/lib/log"
-	"github.com/operator-framework/operator-registry/pkg/registry"
 	"github.com/operator-framework/operator-registry/pkg/server"
 	"github.com/operator-framework/operator-registry/pkg/sqlite"
 )
@@ -46,8 +45,13 @@ func init() {
 	rootCmd.Flags().StringP("configMapName", "c", "", "name of a configmap")
 	rootCmd.Flags().StringP("configMapNamespace", "n", "", "namespace of a configmap")
 	rootCmd.Flags().StringP("port", "p", "50051", "port number to serve on")
+	rootCmd.Flags().StringArray("listen", nil, "address to serve on instead of --port, either host:port or the path of a unix domain socket, e.g. unix:///var/run/registry.sock; repeat to serve on several, e.g. 0.0.0.0:50051 and [::]:50051")
 	rootCmd.Flags().StringP("termination-log", "t", "/dev/termination-log", "path to a container termination log file")
 	rootCmd.Flags().Bool("permissive", false, "allow registry load errors")
+	rootCmd.Flags().Int("max-open-conns", 0, "if set, the most connections the database is read with at once")
+	rootCmd.Flags().Int("max-idle-conns", 0, "if set, how many connections to the database are kept open between queries, 2 otherwise")
+	rootCmd.Flags().Duration("busy-timeout", 0, "if set, how long a query waits for a lock on the database before it fails")
+	rootCmd.Flags().Int64("mmap-size", 0, "if set, how many bytes of the database each connection maps into memory")
 	if err := rootCmd.Flags().MarkHidden("debug"); err != nil {
 		logrus.Panic(err.Error())
 	}
@@ -81,6 +85,10 @@ func runCmdFunc(cmd *cobra.Command, args []string) error {
 	if err != nil {
 		return err
 	}
+	listen, err := cmd.Flags().GetStringArray("listen")
+	if err != nil {
+		return err
+	}
 	configMapName, err := cmd.Flags().GetString("configMapName")
 	if err != nil {
 		return err
@@ -127,14 +135,15 @@ func runCmdFunc(cmd *cobra.Command, args []string) error {
 		logger.WithError(err).Warn("permissive mode enabled")
 	}
 
-	var store registry.Query
-	store, err = sqlite.NewSQLLiteQuerier(dbName)
+	querierOpts, err := querierOptions(cmd)
 	if err != nil {
-		logger.WithError(err).Warnf("failed to load db")
+		return err
 	}
-	if store == nil {
-		store = registry.NewEmptyQuerier()
+	store, err := sqlite.NewSQLLiteQuerier(dbName, querierOpts...)
+	if err != nil {
+		return err
 	}
+	defer store.Close()
 
 	// sanity check that the db is available
 	tables, err := store.ListTables(context.TODO())
@@ -145,7 +154,10 @@ func runCmdFunc(cmd *cobra.Command, args []string) error {
 		logger.Warn("no tables found in db")
 	}
 
-	lis, err := net.Listen("tcp", ":"+port)
+	if len(listen) > 0 {
+		logger = logger.WithField("listen", strings.Join(listen, ","))
+	}
+	lis, err := server.ListenAll(server.ListenAddresses(listen, port)...)
 	if err != nil {
 		logger.Fatalf("failed to listen: %s", err)
 	}
@@ -157,7 +169,7 @@ func runCmdFunc(cmd *cobra.Command, args []string) error {
 
 	logger.Info("serving registry")
 	return graceful.Shutdown(logger, func() error {
-		return s.Serve(lis)
+		return server.Serve(s, lis)
 	}, func() {
 		s.GracefulStop()
 	})
@@ -182,3 +194,29 @@ func NewClientFromConfig(kubeconfig string, logger *logrus.Logger) kubernetes.In
 
 	return kubernetes.NewForConfigOrDie(config)
 }
+
+// querierOptions returns the options of the connections the database is read with
+func querierOptions(cmd *cobra.Command) ([]sqlite.QuerierOption, error) {
+	maxOpenConns, err := cmd.Flags().GetInt("max-open-conns")
+	if err != nil {
+		return nil, err
+	}
+	maxIdleConns, err := cmd.Flags().GetInt("max-idle-conns")
+	if err != nil {
+		return nil, err
+	}
+	busyTimeout, err := cmd.Flags().GetDuration("busy-timeout")
+	if err != nil {
+		return nil, err
+	}
+	mmapSize, err := cmd.Flags().GetInt64("mmap-size")
+	if err != nil {
+		return nil, err
+	}
+	return []sqlite.QuerierOption{
+		sqlite.WithMaxOpenConns(maxOpenConns),
+		sqlite.WithMaxIdleConns(maxIdleConns),
+		sqlite.WithQuerierBusyTimeout(busyTimeout),
+		sqlite.WithMmapSize(mmapSize),
+	}, nil
+}
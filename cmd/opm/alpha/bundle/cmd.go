@@ -14,6 +14,7 @@ func NewCmd() *cobra.Command {
 	runCmd.AddCommand(newBundleGenerateCmd())
 	runCmd.AddCommand(newBundleBuildCmd())
 	runCmd.AddCommand(newBundleValidateCmd())
+	runCmd.AddCommand(newBundleUnpackCmd())
 	runCmd.AddCommand(extractCmd)
 	return runCmd
 }
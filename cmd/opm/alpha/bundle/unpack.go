@@ -0,0 +1,70 @@
+package bundle
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/operator-framework/operator-registry/pkg/containertools"
+	"github.com/operator-framework/operator-registry/pkg/lib/bundle"
+)
+
+func newBundleUnpackCmd() *cobra.Command {
+	unpackCmd := &cobra.Command{
+		Use:   "unpack BUNDLE_IMAGE",
+		Short: "Unpacks the content of a bundle image",
+		Long: `The "opm alpha bundle unpack" command pulls a bundle image and writes its
+manifests and metadata to a directory, or to stdout as a single YAML stream.
+The --filter flag selects only part of the bundle.`,
+		Example: `$ opm alpha bundle unpack quay.io/test/test-operator:latest --filter csv --stdout`,
+		Args:    cobra.ExactArgs(1),
+
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if debug, _ := cmd.Flags().GetBool("debug"); debug {
+				logrus.SetLevel(logrus.DebugLevel)
+			}
+			return nil
+		},
+
+		RunE: runUnpackCmd,
+	}
+
+	unpackCmd.Flags().Bool("debug", false, "enable debug logging")
+	unpackCmd.Flags().StringP("out", "o", "./", "directory to unpack the bundle into")
+	unpackCmd.Flags().Bool("stdout", false, "write the unpacked files to stdout as a single YAML stream instead of to a directory")
+	unpackCmd.Flags().StringSlice("filter", []string{}, fmt.Sprintf("only unpack these parts of the bundle. Any of: [%s, %s, %s]", bundle.UnpackCSV, bundle.UnpackCRDs, bundle.UnpackMetadata))
+	unpackCmd.Flags().StringP("container-tool", "c", "none", "tool to interact with container images (save, build, etc.). One of: [none, docker, podman]")
+
+	return unpackCmd
+}
+
+func runUnpackCmd(cmd *cobra.Command, args []string) error {
+	out, err := cmd.Flags().GetString("out")
+	if err != nil {
+		return err
+	}
+	stdout, err := cmd.Flags().GetBool("stdout")
+	if err != nil {
+		return err
+	}
+	filter, err := cmd.Flags().GetStringSlice("filter")
+	if err != nil {
+		return err
+	}
+	containerTool, err := cmd.Flags().GetString("container-tool")
+	if err != nil {
+		return err
+	}
+
+	unpacker, err := bundle.NewUnpackerForBundle(args[0], containertools.NewContainerTool(containerTool, containertools.NoneTool), filter)
+	if err != nil {
+		return err
+	}
+	if stdout {
+		// logs go to stderr, so the stream can be piped
+		return unpacker.WriteYAML(os.Stdout)
+	}
+	return unpacker.Unpack(out)
+}
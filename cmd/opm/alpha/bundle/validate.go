@@ -2,7 +2,6 @@ package bundle
 
 import (
 	"fmt"
-	"io/ioutil"
 	"os"
 	"path/filepath"
 
@@ -14,6 +13,8 @@ import (
 	"github.com/operator-framework/operator-registry/pkg/image/containerdregistry"
 	"github.com/operator-framework/operator-registry/pkg/image/execregistry"
 	"github.com/operator-framework/operator-registry/pkg/lib/bundle"
+	"github.com/operator-framework/operator-registry/pkg/lib/report"
+	"github.com/operator-framework/operator-registry/pkg/lib/tmp"
 )
 
 func newBundleValidateCmd() *cobra.Command {
@@ -23,7 +24,9 @@ func newBundleValidateCmd() *cobra.Command {
 		Long: `The "opm alpha bundle validate" command will validate bundle image
 from a remote source to determine if its format and content information are
 accurate.`,
-		Example: `$ opm alpha bundle validate --tag quay.io/test/test-operator:latest --image-builder docker`,
+		Example: `$ opm alpha bundle validate --tag quay.io/test/test-operator:latest --image-builder docker
+$ opm alpha bundle validate --tag quay.io/test/test-operator:latest --output sarif > validate.sarif
+$ opm alpha bundle validate --tag quay.io/test/test-operator:latest --channel-policy channels.yaml`,
 		RunE:    validateFunc,
 	}
 
@@ -34,25 +37,61 @@ accurate.`,
 	}
 
 	bundleValidateCmd.Flags().StringVarP(&containerTool, "image-builder", "b", "docker", "Tool used to pull and unpack bundle images. One of: [none, docker, podman]")
+	bundleValidateCmd.Flags().StringP("output", "o", "", "if set, write the validation results to stdout in this format. One of: [text, json, junit, sarif]")
+	bundleValidateCmd.Flags().String("channel-policy", "", "if set, check the channel names of the bundle against the patterns in this rule file")
 
 	return bundleValidateCmd
 }
 
 func validateFunc(cmd *cobra.Command, args []string) error {
+	output, err := cmd.Flags().GetString("output")
+	if err != nil {
+		return err
+	}
+	var format report.Format
+	if output != "" {
+		if format, err = report.ParseFormat(output); err != nil {
+			return err
+		}
+	}
+	policyFile, err := cmd.Flags().GetString("channel-policy")
+	if err != nil {
+		return err
+	}
+	var policy *bundle.ChannelPolicy
+	if policyFile != "" {
+		if policy, err = bundle.LoadChannelPolicy(policyFile); err != nil {
+			return err
+		}
+	}
+
 	logger := log.WithFields(log.Fields{"container-tool": containerTool})
 	log.SetLevel(log.DebugLevel)
 
-	var (
-		registry image.Registry
-		err      error
-	)
+	var registry image.Registry
+
+	workspace, err := tmp.NewWorkspace("bundle-validate")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		err := workspace.Cleanup()
+		if err != nil {
+			logger.Error(err.Error())
+		}
+	}()
+	dir, err := workspace.Dir("bundle-")
+	logger.Infof("Create a temp directory at %s", dir)
+	if err != nil {
+		return err
+	}
 
 	tool := containertools.NewContainerTool(containerTool, containertools.NoneTool)
 	switch tool {
 	case containertools.PodmanTool, containertools.DockerTool:
 		registry, err = execregistry.NewRegistry(tool, logger)
 	case containertools.NoneTool:
-		registry, err = containerdregistry.NewRegistry(containerdregistry.WithLog(logger))
+		registry, err = containerdregistry.NewRegistry(containerdregistry.WithLog(logger), containerdregistry.WithCacheDir(filepath.Join(workspace.Path(), "cache")))
 	default:
 		err = fmt.Errorf("unrecognized container-tool option: %s", containerTool)
 	}
@@ -62,36 +101,91 @@ func validateFunc(cmd *cobra.Command, args []string) error {
 	}
 	imageValidator := bundle.NewImageValidator(registry, logger)
 
-	dir, err := ioutil.TempDir("", "bundle-")
-	logger.Infof("Create a temp directory at %s", dir)
+	err = imageValidator.PullBundleImage(tag, dir)
 	if err != nil {
 		return err
 	}
-	defer func() {
-		err := os.RemoveAll(dir)
+
+	logger.Info("Unpacked image layers, validating bundle image format & contents")
+
+	if output == "" {
+		err = imageValidator.ValidateBundleFormat(dir)
 		if err != nil {
-			logger.Error(err.Error())
+			return err
 		}
-	}()
 
-	err = imageValidator.PullBundleImage(tag, dir)
-	if err != nil {
-		return err
-	}
+		err = imageValidator.ValidateBundleContent(filepath.Join(dir, bundle.ManifestsDir))
+		if err != nil {
+			return err
+		}
 
-	logger.Info("Unpacked image layers, validating bundle image format & contents")
+		if policy != nil {
+			if err := bundle.ValidateBundleChannels(dir, policy); err != nil {
+				return err
+			}
+		}
 
-	err = imageValidator.ValidateBundleFormat(dir)
-	if err != nil {
-		return err
+		logger.Info("All validation tests have been completed successfully")
+
+		return nil
 	}
 
-	err = imageValidator.ValidateBundleContent(filepath.Join(dir, bundle.ManifestsDir))
+	rep, err := validationReport(imageValidator, policy, tag, dir)
 	if err != nil {
 		return err
 	}
+	if err := rep.Write(os.Stdout, format); err != nil {
+		return err
+	}
+	if rep.Failed() {
+		return fmt.Errorf("bundle %s failed validation", tag)
+	}
+	return nil
+}
 
-	logger.Info("All validation tests have been completed successfully")
+// validationReport validates the format, then the content, of an unpacked bundle and reports every validation error
+// as a finding about the bundle. The channel names are checked too if a channel policy is given. Errors that aren't
+// validation errors stop the validation.
+func validationReport(imageValidator bundle.BundleImageValidator, policy *bundle.ChannelPolicy, tag, dir string) (report.Report, error) {
+	rep := report.Report{Tool: "opm alpha bundle validate", Findings: []report.Finding{}}
+	type step struct {
+		rule     report.Rule
+		validate func() error
+	}
+	steps := []step{
+		{
+			rule:     report.Rule{ID: "format", Description: "the bundle has manifests and metadata directories with valid annotations and dependencies", Level: report.LevelError},
+			validate: func() error { return imageValidator.ValidateBundleFormat(dir) },
+		},
+		{
+			rule:     report.Rule{ID: "content", Description: "the bundle manifests are valid", Level: report.LevelError},
+			validate: func() error { return imageValidator.ValidateBundleContent(filepath.Join(dir, bundle.ManifestsDir)) },
+		},
+	}
+	if policy != nil {
+		steps = append(steps, step{
+			rule:     report.Rule{ID: "channels", Description: "the bundle channel names follow the channel policy", Level: report.LevelError},
+			validate: func() error { return bundle.ValidateBundleChannels(dir, policy) },
+		})
+	}
 
-	return nil
+	for _, step := range steps {
+		rep.Rules = append(rep.Rules, step.rule)
+		err := step.validate()
+		if err == nil {
+			continue
+		}
+		verr, ok := err.(bundle.ValidationError)
+		if !ok {
+			return rep, err
+		}
+		for _, e := range verr.Errors {
+			rep.Findings = append(rep.Findings, report.Finding{RuleID: step.rule.ID, Level: report.LevelError, Subject: tag, Message: e.Error()})
+		}
+		// nothing else can be validated in a bundle of the wrong format
+		if step.rule.ID == "format" {
+			break
+		}
+	}
+	return rep, nil
 }
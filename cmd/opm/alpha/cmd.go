@@ -2,6 +2,10 @@ package alpha
 
 import (
 	"github.com/operator-framework/operator-registry/cmd/opm/alpha/bundle"
+	"github.com/operator-framework/operator-registry/cmd/opm/alpha/list"
+	"github.com/operator-framework/operator-registry/cmd/opm/alpha/plugin"
+	"github.com/operator-framework/operator-registry/cmd/opm/alpha/preflight"
+	"github.com/operator-framework/operator-registry/cmd/opm/alpha/publish"
 	"github.com/spf13/cobra"
 )
 
@@ -13,5 +17,9 @@ func NewCmd() *cobra.Command {
 	}
 
 	runCmd.AddCommand(bundle.NewCmd())
+	runCmd.AddCommand(preflight.NewCmd())
+	runCmd.AddCommand(publish.NewStageCmd(), publish.NewPromoteCmd())
+	runCmd.AddCommand(list.NewCmd())
+	runCmd.AddCommand(plugin.NewCmd())
 	return runCmd
 }
@@ -0,0 +1,15 @@
+package list
+
+import (
+	"github.com/spf13/cobra"
+)
+
+func NewCmd() *cobra.Command {
+	runCmd := &cobra.Command{
+		Use:   "list",
+		Short: "List contents of an operator registry DB",
+	}
+
+	runCmd.AddCommand(newUpgradeGraphCmd())
+	return runCmd
+}
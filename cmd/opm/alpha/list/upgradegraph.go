@@ -0,0 +1,66 @@
+package list
+
+import (
+	"context"
+	"os"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/operator-framework/operator-registry/pkg/lib/upgradegraph"
+	"github.com/operator-framework/operator-registry/pkg/sqlite"
+)
+
+func newUpgradeGraphCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "upgrade-graph CATALOG_DATABASE PACKAGE",
+		Short: "Print the upgrade graph of a package",
+		Long: `The "opm alpha list upgrade-graph" command prints the upgrade graph of every channel of a package: its
+bundles, the head of the channel, and the replaces, skips and skip range edges from each bundle to the bundles that
+upgrade it. The graph is written as JSON, or as Graphviz DOT to be rendered, e.g. with "dot -Tsvg".`,
+		Example: `$ opm alpha list upgrade-graph index.db etcd
+$ opm alpha list upgrade-graph index.db etcd --output dot | dot -Tsvg > etcd.svg`,
+		Args: cobra.ExactArgs(2),
+
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if debug, _ := cmd.Flags().GetBool("debug"); debug {
+				logrus.SetLevel(logrus.DebugLevel)
+			}
+			return nil
+		},
+
+		RunE: runUpgradeGraphCmd,
+	}
+
+	cmd.Flags().Bool("debug", false, "enable debug logging")
+	cmd.Flags().StringP("output", "o", string(upgradegraph.FormatJSON), "format of the graph written to stdout. One of: [json, dot]")
+
+	return cmd
+}
+
+func runUpgradeGraphCmd(cmd *cobra.Command, args []string) error {
+	output, err := cmd.Flags().GetString("output")
+	if err != nil {
+		return err
+	}
+	format, err := upgradegraph.ParseFormat(output)
+	if err != nil {
+		return err
+	}
+
+	// opening a missing database would create it
+	if _, err := os.Stat(args[0]); err != nil {
+		return err
+	}
+	querier, err := sqlite.NewSQLLiteQuerier(args[0])
+	if err != nil {
+		return err
+	}
+	defer querier.Close()
+
+	graph, err := querier.GetUpgradeGraph(context.Background(), args[1])
+	if err != nil {
+		return err
+	}
+	return upgradegraph.Write(os.Stdout, graph, format)
+}
@@ -0,0 +1,47 @@
+package plugin
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/operator-framework/operator-registry/pkg/lib/plugin"
+)
+
+func NewCmd() *cobra.Command {
+	runCmd := &cobra.Command{
+		Use:   "plugin",
+		Short: "Work with opm plugins",
+		Long: `Plugins extend opm with commands of their own: an executable named opm-<name> on the PATH
+is run as "opm <name>", with every argument after the name. Plugins can't replace opm commands.`,
+	}
+
+	runCmd.AddCommand(newListCmd())
+	return runCmd
+}
+
+func newListCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "List the plugins on the PATH",
+		Long: `List the plugins on the PATH, warning about those that are never run because another
+executable of the same name comes before them on the PATH or opm has a command of the same name.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			plugins := plugin.Discover(os.Getenv("PATH"))
+			if len(plugins) == 0 {
+				return fmt.Errorf("no plugins found on the PATH")
+			}
+			for _, p := range plugins {
+				fmt.Fprintln(cmd.OutOrStdout(), p.Path)
+				if p.Shadowed {
+					fmt.Fprintf(cmd.ErrOrStderr(), "  warning: %s is shadowed by an earlier plugin of the same name\n", p.Path)
+				} else if c, _, err := cmd.Root().Find([]string{p.Name}); err == nil && c != cmd.Root() {
+					fmt.Fprintf(cmd.ErrOrStderr(), "  warning: %s is never run, opm has a %s command\n", p.Path, p.Name)
+				}
+			}
+			return nil
+		},
+	}
+}
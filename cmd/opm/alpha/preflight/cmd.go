@@ -0,0 +1,185 @@
+package preflight
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/operator-framework/operator-registry/pkg/image/containerdregistry"
+	"github.com/operator-framework/operator-registry/pkg/lib/certs"
+	"github.com/operator-framework/operator-registry/pkg/lib/preflight"
+	"github.com/operator-framework/operator-registry/pkg/lib/report"
+	"github.com/operator-framework/operator-registry/pkg/lib/tmp"
+	"github.com/operator-framework/operator-registry/pkg/registry"
+	"github.com/operator-framework/operator-registry/pkg/sqlite"
+)
+
+func NewCmd() *cobra.Command {
+	var checkNames []string
+	for _, check := range preflight.Checks() {
+		checkNames = append(checkNames, fmt.Sprintf("%s (%s, %s)", check.Name, check.Description, check.Severity))
+	}
+
+	cmd := &cobra.Command{
+		Use:   "preflight CATALOG_DATABASE",
+		Short: "Check a catalog before it is published",
+		Long: `The "opm alpha preflight" command runs every check a catalog database should pass before it is
+published, reports what they found and fails if any finding has error severity. Packages are checked
+concurrently and on their own, so a check that can't be run on one package is reported as an error
+of that package without stopping the others, and the report ends with a summary of the findings
+of each package.
+
+The checks, with their description and default severity, are:
+  ` + strings.Join(checkNames, "\n  "),
+		Example: `$ opm alpha preflight index.db --checks schema,graph,digests --severity digests=error --junit preflight.xml
+$ opm alpha preflight index.db --output sarif > preflight.sarif`,
+		Args:    cobra.ExactArgs(1),
+
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if debug, _ := cmd.Flags().GetBool("debug"); debug {
+				logrus.SetLevel(logrus.DebugLevel)
+			}
+			return nil
+		},
+
+		RunE: runPreflightCmd,
+	}
+
+	cmd.Flags().Bool("debug", false, "enable debug logging")
+	cmd.Flags().StringSlice("checks", nil, "checks to run, all of them by default")
+	cmd.Flags().StringToString("severity", nil, "severity of a check, as check=severity. Severity is one of: [error, warning, off]")
+	cmd.Flags().StringP("output", "o", string(report.FormatText), "format of the results written to stdout. One of: [text, json, junit, sarif]")
+	cmd.Flags().String("junit", "", "if set, also write the results as JUnit XML to this file")
+	cmd.Flags().Bool("skip-tls", false, "skip TLS certificate verification for container image registries while resolving images")
+	cmd.Flags().String("ca-file", "", "the root certificates to use when resolving images")
+	cmd.Flags().Int("parallelism", 0, "how many packages to check at once, the number of CPUs by default")
+	cmd.Flags().String("version-policy", "", "if set, the version policy rule file the versions check checks bundle versions against, instead of the strictest policy")
+
+	return cmd
+}
+
+func runPreflightCmd(cmd *cobra.Command, args []string) error {
+	names, err := cmd.Flags().GetStringSlice("checks")
+	if err != nil {
+		return err
+	}
+	severities, err := cmd.Flags().GetStringToString("severity")
+	if err != nil {
+		return err
+	}
+	junit, err := cmd.Flags().GetString("junit")
+	if err != nil {
+		return err
+	}
+	output, err := cmd.Flags().GetString("output")
+	if err != nil {
+		return err
+	}
+	format, err := report.ParseFormat(output)
+	if err != nil {
+		return err
+	}
+	skipTLS, err := cmd.Flags().GetBool("skip-tls")
+	if err != nil {
+		return err
+	}
+	caFile, err := cmd.Flags().GetString("ca-file")
+	if err != nil {
+		return err
+	}
+	versionPolicyFile, err := cmd.Flags().GetString("version-policy")
+	if err != nil {
+		return err
+	}
+	parallelism, err := cmd.Flags().GetInt("parallelism")
+	if err != nil {
+		return err
+	}
+
+	checks, err := preflight.SelectChecks(names, severities)
+	if err != nil {
+		return err
+	}
+
+	if _, err := os.Stat(args[0]); err != nil {
+		return err
+	}
+	querier, err := sqlite.NewSQLLiteQuerier(args[0])
+	if err != nil {
+		return err
+	}
+	defer querier.Close()
+	catalog := &preflight.Catalog{Querier: querier, Parallelism: parallelism}
+	if versionPolicyFile != "" {
+		if catalog.VersionPolicy, err = registry.LoadVersionPolicy(versionPolicyFile); err != nil {
+			return err
+		}
+	}
+
+	if resolvesImages(checks) {
+		workspace, err := tmp.NewWorkspace("preflight")
+		if err != nil {
+			return err
+		}
+		defer workspace.Cleanup()
+
+		rootCAs, err := certs.RootCAs(caFile)
+		if err != nil {
+			return fmt.Errorf("failed to get RootCAs: %v", err)
+		}
+		reg, err := containerdregistry.NewRegistry(containerdregistry.SkipTLS(skipTLS), containerdregistry.WithRootCAs(rootCAs), containerdregistry.WithCacheDir(filepath.Join(workspace.Path(), "cache")))
+		if err != nil {
+			return err
+		}
+		defer func() {
+			if err := reg.Destroy(); err != nil {
+				logrus.WithError(err).Warn("error destroying local cache")
+			}
+		}()
+		catalog.Resolver = reg
+	}
+
+	result, err := preflight.Run(context.Background(), catalog, checks)
+	if err != nil {
+		return err
+	}
+
+	rep := result.Report()
+	if err := rep.Write(os.Stdout, format); err != nil {
+		return err
+	}
+
+	if junit != "" {
+		f, err := os.Create(junit)
+		if err != nil {
+			return err
+		}
+		if err := rep.Write(f, report.FormatJUnit); err != nil {
+			f.Close()
+			return err
+		}
+		if err := f.Close(); err != nil {
+			return err
+		}
+	}
+
+	if result.Failed() {
+		return fmt.Errorf("catalog failed preflight checks")
+	}
+	return nil
+}
+
+// resolvesImages returns true if the images check is to be run, which needs a registry to resolve images with
+func resolvesImages(checks []preflight.Check) bool {
+	for _, check := range checks {
+		if check.Name == preflight.CheckImages && check.Severity != preflight.SeverityOff {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,103 @@
+package publish
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/operator-framework/operator-registry/pkg/image/containerdregistry"
+	"github.com/operator-framework/operator-registry/pkg/lib/certs"
+	"github.com/operator-framework/operator-registry/pkg/lib/preflight"
+	"github.com/operator-framework/operator-registry/pkg/lib/publish"
+	"github.com/operator-framework/operator-registry/pkg/lib/report"
+	"github.com/operator-framework/operator-registry/pkg/lib/tmp"
+	"github.com/operator-framework/operator-registry/pkg/registry"
+)
+
+// addFlags adds the flags shared by stage and promote: those of the preflight the catalog must pass, and those of
+// the registries images are pulled from and pushed to
+func addFlags(cmd *cobra.Command) {
+	cmd.Flags().Bool("debug", false, "enable debug logging")
+	cmd.Flags().StringSlice("checks", nil, "preflight checks to run, all of them by default")
+	cmd.Flags().StringToString("severity", nil, "severity of a preflight check, as check=severity. Severity is one of: [error, warning, off]")
+	cmd.Flags().String("version-policy", "", "if set, the version policy rule file the versions check checks bundle versions against, instead of the strictest policy")
+	cmd.Flags().StringP("output", "o", string(report.FormatText), "format of the preflight results written to stdout. One of: [text, json, junit, sarif]")
+	cmd.Flags().Bool("skip-tls", false, "skip TLS certificate verification for container image registries")
+	cmd.Flags().String("ca-file", "", "the root certificates to use with container image registries")
+}
+
+func setDebug(cmd *cobra.Command, args []string) error {
+	if debug, _ := cmd.Flags().GetBool("debug"); debug {
+		logrus.SetLevel(logrus.DebugLevel)
+	}
+	return nil
+}
+
+func preflightConfig(cmd *cobra.Command) (publish.Preflight, error) {
+	names, err := cmd.Flags().GetStringSlice("checks")
+	if err != nil {
+		return publish.Preflight{}, err
+	}
+	severities, err := cmd.Flags().GetStringToString("severity")
+	if err != nil {
+		return publish.Preflight{}, err
+	}
+	versionPolicyFile, err := cmd.Flags().GetString("version-policy")
+	if err != nil {
+		return publish.Preflight{}, err
+	}
+
+	var config publish.Preflight
+	if config.Checks, err = preflight.SelectChecks(names, severities); err != nil {
+		return publish.Preflight{}, err
+	}
+	if versionPolicyFile != "" {
+		if config.VersionPolicy, err = registry.LoadVersionPolicy(versionPolicyFile); err != nil {
+			return publish.Preflight{}, err
+		}
+	}
+	return config, nil
+}
+
+// newRegistry returns the registry images are pulled from, pushed to and resolved with, caching what it pulls in the
+// workspace
+func newRegistry(cmd *cobra.Command, workspace *tmp.Workspace, logger *logrus.Entry) (*containerdregistry.Registry, error) {
+	skipTLS, err := cmd.Flags().GetBool("skip-tls")
+	if err != nil {
+		return nil, err
+	}
+	caFile, err := cmd.Flags().GetString("ca-file")
+	if err != nil {
+		return nil, err
+	}
+	rootCAs, err := certs.RootCAs(caFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get RootCAs: %v", err)
+	}
+	return containerdregistry.NewRegistry(containerdregistry.SkipTLS(skipTLS), containerdregistry.WithRootCAs(rootCAs), containerdregistry.WithLog(logger), containerdregistry.WithCacheDir(filepath.Join(workspace.Path(), "cache")))
+}
+
+func outputFormat(cmd *cobra.Command) (report.Format, error) {
+	output, err := cmd.Flags().GetString("output")
+	if err != nil {
+		return "", err
+	}
+	return report.ParseFormat(output)
+}
+
+// writeResult writes the preflight report to stdout, and the digest of the published image, if there is one, to
+// stderr so that it doesn't mix with a machine readable report
+func writeResult(result publish.Result, format report.Format) error {
+	if len(result.Preflight.Checks) > 0 {
+		if err := result.Preflight.Report().Write(os.Stdout, format); err != nil {
+			return err
+		}
+	}
+	if result.Digest != "" {
+		fmt.Fprintf(os.Stderr, "digest: %s\n", result.Digest)
+	}
+	return nil
+}
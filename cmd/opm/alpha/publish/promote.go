@@ -0,0 +1,80 @@
+package publish
+
+import (
+	"context"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/operator-framework/operator-registry/pkg/lib/publish"
+	"github.com/operator-framework/operator-registry/pkg/lib/tmp"
+)
+
+func NewPromoteCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "promote STAGING_IMAGE PRODUCTION_IMAGE",
+		Short: "Promote a staged catalog to production",
+		Long: `The "opm alpha promote" command pulls a catalog staged with "opm alpha stage", runs preflight
+against the database it holds again and, if it passes, pushes that same image under the
+production reference. With --approved-digest, the staged image is only promoted if it is the
+image that was approved, so that an image staged after the approval isn't promoted in its place.
+
+The production reference is only updated once every layer of the image has been pushed, so
+clients pulling it get either the promoted catalog or the one it replaces, and it is then
+checked to name the promoted digest.`,
+		Example: `$ opm alpha promote quay.io/example/catalog:staging quay.io/example/catalog:latest --approved-digest sha256:...`,
+		Args:    cobra.ExactArgs(2),
+		PreRunE: setDebug,
+		RunE:    runPromoteCmd,
+	}
+
+	addFlags(cmd)
+	cmd.Flags().String("approved-digest", "", "if set, the digest the staged image must have to be promoted")
+
+	return cmd
+}
+
+func runPromoteCmd(cmd *cobra.Command, args []string) error {
+	approvedDigest, err := cmd.Flags().GetString("approved-digest")
+	if err != nil {
+		return err
+	}
+	format, err := outputFormat(cmd)
+	if err != nil {
+		return err
+	}
+	config, err := preflightConfig(cmd)
+	if err != nil {
+		return err
+	}
+
+	logger := logrus.WithFields(logrus.Fields{"staging": args[0], "production": args[1]})
+	workspace, err := tmp.NewWorkspace("promote")
+	if err != nil {
+		return err
+	}
+	defer workspace.Cleanup()
+	reg, err := newRegistry(cmd, workspace, logger)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := reg.Destroy(); err != nil {
+			logger.WithError(err).Warn("error destroying local cache")
+		}
+	}()
+	config.Resolver = reg
+
+	result, err := publish.Promote(context.Background(), publish.PromoteRequest{
+		StagingImage:    args[0],
+		ProductionImage: args[1],
+		ApprovedDigest:  approvedDigest,
+		Preflight:       config,
+		Registry:        reg,
+		Logger:          logger,
+	})
+	if werr := writeResult(result, format); werr != nil && err == nil {
+		err = werr
+	}
+	return err
+}
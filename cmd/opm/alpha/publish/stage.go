@@ -0,0 +1,84 @@
+package publish
+
+import (
+	"context"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/operator-framework/operator-registry/pkg/containertools"
+	"github.com/operator-framework/operator-registry/pkg/lib/publish"
+	"github.com/operator-framework/operator-registry/pkg/lib/tmp"
+)
+
+func NewStageCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "stage CATALOG_DATABASE STAGING_IMAGE",
+		Short: "Stage a catalog for promotion to production",
+		Long: `The "opm alpha stage" command runs preflight against a catalog database and, if it passes,
+builds it into an index image that is pushed under a staging reference, from which
+"opm alpha promote" promotes it to production. The digest of the staged image is written to
+stderr: it is what release engineering approves, and what "opm alpha promote --approved-digest"
+checks the staged image against.`,
+		Example: `$ opm alpha stage index.db quay.io/example/catalog:staging --container-tool docker`,
+		Args:    cobra.ExactArgs(2),
+		PreRunE: setDebug,
+		RunE:    runStageCmd,
+	}
+
+	addFlags(cmd)
+	cmd.Flags().StringP("binary-image", "i", "", "container image for on-image `opm` command")
+	cmd.Flags().StringP("container-tool", "c", "", "tool to build and push the staged image. One of: [docker, podman]. Defaults to podman.")
+
+	return cmd
+}
+
+func runStageCmd(cmd *cobra.Command, args []string) error {
+	binaryImage, err := cmd.Flags().GetString("binary-image")
+	if err != nil {
+		return err
+	}
+	containerTool, err := cmd.Flags().GetString("container-tool")
+	if err != nil {
+		return err
+	}
+	format, err := outputFormat(cmd)
+	if err != nil {
+		return err
+	}
+	config, err := preflightConfig(cmd)
+	if err != nil {
+		return err
+	}
+
+	logger := logrus.WithFields(logrus.Fields{"database": args[0], "staging": args[1]})
+	workspace, err := tmp.NewWorkspace("stage")
+	if err != nil {
+		return err
+	}
+	defer workspace.Cleanup()
+	reg, err := newRegistry(cmd, workspace, logger)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := reg.Destroy(); err != nil {
+			logger.WithError(err).Warn("error destroying local cache")
+		}
+	}()
+	config.Resolver = reg
+
+	result, err := publish.Stage(context.Background(), publish.StageRequest{
+		Database:          args[0],
+		StagingImage:      args[1],
+		BinarySourceImage: binaryImage,
+		Preflight:         config,
+		CommandRunner:     containertools.NewCommandRunner(containertools.NewContainerTool(containerTool, containertools.PodmanTool), logger),
+		Registry:          reg,
+		Logger:            logger,
+	})
+	if werr := writeResult(result, format); werr != nil && err == nil {
+		err = werr
+	}
+	return err
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"os"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/operator-framework/operator-registry/pkg/lib/progress"
+)
+
+// startEvents switches opm to the log format asked for with --log-format. For json-lines, it returns the event stream
+// of the command being run, after writing its start; logs and progress are written to the stream from then on.
+func startEvents(rootCmd *cobra.Command, format string) *progress.Stream {
+	logFormat, err := progress.ParseLogFormat(format)
+	if err != nil {
+		logrus.Fatal(err)
+	}
+	if logFormat != progress.LogFormatJSONLines {
+		return nil
+	}
+
+	cmd, _, err := rootCmd.Find(os.Args[1:])
+	if err != nil {
+		cmd = rootCmd
+	}
+	events := progress.NewStream(os.Stderr, cmd.CommandPath())
+	logrus.SetFormatter(events)
+	progress.SetStream(events)
+	// the error of the command is written in its result rather than printed, along with usage, as free-form text
+	rootCmd.SilenceErrors = true
+	rootCmd.SilenceUsage = true
+	events.Start(setFlags(cmd, os.Args[1:]))
+	return events
+}
@@ -7,8 +7,10 @@ import (
 	"github.com/spf13/cobra"
 	"k8s.io/kubectl/pkg/util/templates"
 
+	"github.com/operator-framework/operator-registry/cmd/opm/version"
 	"github.com/operator-framework/operator-registry/pkg/containertools"
 	"github.com/operator-framework/operator-registry/pkg/lib/indexer"
+	"github.com/operator-framework/operator-registry/pkg/lib/progress"
 	"github.com/operator-framework/operator-registry/pkg/registry"
 )
 
@@ -26,6 +28,15 @@ var (
 		# Add a single bundle image to an index image
 		%[1]s --bundles quay.io/operator-framework/operator-bundle-prometheus:0.15.0 --from-index quay.io/operator-framework/monitoring:1.0.0 --tag quay.io/operator-framework/monitoring:1.0.1
 
+		# Scan the bundle and operand images being added and fail on critical findings
+		%[1]s --bundles quay.io/operator-framework/operator-bundle-prometheus:0.15.0 --from-index quay.io/operator-framework/monitoring:1.0.0 --tag quay.io/operator-framework/monitoring:1.0.1 --scan-cmd "my-scanner --format json" --scan-fail-severity critical
+
+		# Record the provenance of the index, then push the image and attach the signed provenance to it
+		%[1]s --bundles quay.io/operator-framework/operator-bundle-prometheus:0.15.0 --from-index quay.io/operator-framework/monitoring:1.0.0 --tag quay.io/operator-framework/monitoring:1.0.1 --provenance provenance.json --provenance-cmd ./push-and-attest.sh
+
+		# Add many bundles, resuming from the bundles already pulled if a previous attempt failed
+		%[1]s --bundles quay.io/operator-framework/operator-bundle-prometheus:0.15.0,quay.io/operator-framework/operator-bundle-prometheus:0.22.2 --from-index quay.io/operator-framework/monitoring:1.0.0 --tag quay.io/operator-framework/monitoring:1.0.1 --resume
+
 		# Add multiple bundles to an index and generate a Dockerfile instead of an image
 		%[1]s --bundles quay.io/operator-framework/operator-bundle-prometheus:0.15.0,quay.io/operator-framework/operator-bundle-prometheus:0.22.2 --generate
 	`)
@@ -48,6 +59,7 @@ func addIndexAddCmd(parent *cobra.Command) {
 	indexCmd.Flags().Bool("debug", false, "enable debug logging")
 	indexCmd.Flags().Bool("generate", false, "if enabled, just creates the dockerfile and saves it to local disk")
 	indexCmd.Flags().StringP("out-dockerfile", "d", "", "if generating the dockerfile, this flag is used to (optionally) specify a dockerfile name")
+	addDockerfileFlags(indexCmd)
 	indexCmd.Flags().StringP("from-index", "f", "", "previous index to add to")
 	indexCmd.Flags().StringSliceP("bundles", "b", nil, "comma separated list of bundles to add")
 	if err := indexCmd.MarkFlagRequired("bundles"); err != nil {
@@ -58,8 +70,22 @@ func addIndexAddCmd(parent *cobra.Command) {
 	indexCmd.Flags().StringP("build-tool", "u", "", "tool to build container images. One of: [docker, podman]. Defaults to podman. Overrides part of container-tool.")
 	indexCmd.Flags().StringP("pull-tool", "p", "", "tool to pull container images. One of: [none, docker, podman]. Defaults to none. Overrides part of container-tool.")
 	indexCmd.Flags().StringP("tag", "t", "", "custom tag for container image being built")
+	indexCmd.Flags().String("progress", "none", "how to report progress while the command runs. One of: [none, bar, json]. Interrupting the command rolls back partial changes")
 	indexCmd.Flags().Bool("permissive", false, "allow registry load errors")
 	indexCmd.Flags().StringP("mode", "", "replaces", "graph update mode that defines how channel graphs are updated. One of: [replaces, semver, semver-skippatch]")
+	indexCmd.Flags().String("verify-cmd", "", "if set, verify each bundle image with this command before adding it and record the verification evidence. The image is appended as the last argument and a JSON attestation ({certificateIdentity, certificateIssuer, rekorLogIndex}) is read from stdout")
+	indexCmd.Flags().String("scan-cmd", "", "command run against each newly referenced bundle and operand image, with the image appended as the last argument. It must print a JSON list of findings ({id, severity, package, description}) to stdout")
+	indexCmd.Flags().String("scan-report", "", "if set, write the aggregated scan findings as JSON to this file")
+	indexCmd.Flags().Bool("resume", false, "keep the source database and the unpacked bundles across attempts, so re-running a failed add with the same index and bundles continues from where it stopped")
+	indexCmd.Flags().Bool("clean", false, "discard the state kept by previous attempts of this add before starting")
+	indexCmd.Flags().String("journal-mode", "", "SQLite journal mode to write the database with, WAL if not set. One of: [DELETE, TRUNCATE, PERSIST, MEMORY, WAL, OFF]. The database is checkpointed and switched back to DELETE once the bundles are added")
+	indexCmd.Flags().String("synchronous", "", "SQLite synchronous setting to write the database with, e.g. NORMAL. One of: [OFF, NORMAL, FULL, EXTRA]")
+	indexCmd.Flags().Bool("in-memory", false, "build the database in memory and only write it out once every bundle is added. Can't be used with --journal-mode or --synchronous")
+	indexCmd.Flags().String("provenance", "", "if set, write an in-toto statement of the SLSA provenance of the index to this file, recording the digests of the images it is built from and the parameters of the build")
+	indexCmd.Flags().String("provenance-cmd", "", "command run once the index is built, with the provenance file and the tag appended as the last arguments, e.g. to push the image and sign and attach the provenance. Requires --provenance")
+	indexCmd.Flags().String("csv-name-conflicts", "reject", "what is done with bundles whose CSV name already belongs to another package. One of: [reject, warn]. Warn skips them and adds the other bundles")
+	indexCmd.Flags().String("version-policy", "", "if set, reject bundles whose versions break the version policy in this rule file: versions must be valid semver, and the file sets whether build metadata, pre-releases outside some channels and downgrades are allowed")
+	indexCmd.Flags().String("scan-fail-severity", "", "fail the build if any scan finding is at or above this severity. One of: [low, medium, high, critical]")
 
 	if err := indexCmd.Flags().MarkHidden("debug"); err != nil {
 		logrus.Panic(err.Error())
@@ -82,6 +108,11 @@ func runIndexAddCmdFunc(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
+	labels, buildArgs, err := getDockerfileOptions(cmd)
+	if err != nil {
+		return err
+	}
+
 	fromIndex, err := cmd.Flags().GetString("from-index")
 	if err != nil {
 		return err
@@ -122,6 +153,76 @@ func runIndexAddCmdFunc(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
+	verifyCmd, err := cmd.Flags().GetString("verify-cmd")
+	if err != nil {
+		return err
+	}
+
+	scanCmd, err := cmd.Flags().GetString("scan-cmd")
+	if err != nil {
+		return err
+	}
+
+	scanReport, err := cmd.Flags().GetString("scan-report")
+	if err != nil {
+		return err
+	}
+
+	scanFailSeverity, err := cmd.Flags().GetString("scan-fail-severity")
+	if err != nil {
+		return err
+	}
+
+	resume, err := cmd.Flags().GetBool("resume")
+	if err != nil {
+		return err
+	}
+
+	clean, err := cmd.Flags().GetBool("clean")
+	if err != nil {
+		return err
+	}
+
+	journalMode, err := cmd.Flags().GetString("journal-mode")
+	if err != nil {
+		return err
+	}
+	synchronous, err := cmd.Flags().GetString("synchronous")
+	if err != nil {
+		return err
+	}
+	inMemory, err := cmd.Flags().GetBool("in-memory")
+	if err != nil {
+		return err
+	}
+	csvNameConflicts, err := cmd.Flags().GetString("csv-name-conflicts")
+	if err != nil {
+		return err
+	}
+	csvNameConflictPolicy, err := registry.GetConflictPolicyFromString(csvNameConflicts)
+	if err != nil {
+		return err
+	}
+	versionPolicyFile, err := cmd.Flags().GetString("version-policy")
+	if err != nil {
+		return err
+	}
+	var versionPolicy *registry.VersionPolicy
+	if versionPolicyFile != "" {
+		if versionPolicy, err = registry.LoadVersionPolicy(versionPolicyFile); err != nil {
+			return err
+		}
+	}
+
+	provenance, err := cmd.Flags().GetString("provenance")
+	if err != nil {
+		return err
+	}
+	provenanceCmd, err := cmd.Flags().GetString("provenance-cmd")
+	if err != nil {
+		return err
+	}
+
 	pullTool, buildTool, err := getContainerTools(cmd)
 	if err != nil {
 		return err
@@ -136,6 +237,16 @@ func runIndexAddCmdFunc(cmd *cobra.Command, args []string) error {
 		containertools.NewContainerTool(pullTool, containertools.NoneTool),
 		logger)
 
+	progressFormat, err := cmd.Flags().GetString("progress")
+	if err != nil {
+		return err
+	}
+	ctx, cancel, err := progress.Context(progressFormat)
+	if err != nil {
+		return err
+	}
+	defer cancel()
+
 	request := indexer.AddToIndexRequest{
 		Generate:          generate,
 		FromIndex:         fromIndex,
@@ -146,9 +257,27 @@ func runIndexAddCmdFunc(cmd *cobra.Command, args []string) error {
 		Permissive:        permissive,
 		Mode:              modeEnum,
 		SkipTLS:           skipTLS,
+		VerifyCommand:     verifyCmd,
+		ScanCommand:       scanCmd,
+		ScanReportFile:    scanReport,
+		ScanFailSeverity:  scanFailSeverity,
+		Resume:            resume,
+		Clean:             clean,
+		JournalMode:       journalMode,
+		Synchronous:       synchronous,
+		InMemory:          inMemory,
+		CSVNameConflicts:  csvNameConflictPolicy,
+		VersionPolicy:     versionPolicy,
+		ProvenanceFile:    provenance,
+		ProvenanceCommand: provenanceCmd,
+		BuilderVersion:    version.Get().OpmVersion,
+		DockerfileOptions: indexer.DockerfileOptions{
+			Labels:    labels,
+			BuildArgs: buildArgs,
+		},
 	}
 
-	err = indexAdder.AddToIndex(request)
+	err = indexAdder.AddToIndex(ctx, request)
 	if err != nil {
 		return err
 	}
@@ -1,8 +1,12 @@
 package index
 
 import (
+	"fmt"
+
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
+
+	"github.com/operator-framework/operator-registry/pkg/containertools"
 )
 
 // AddCommand adds the index subcommand to the given parent command.
@@ -34,3 +38,32 @@ func AddCommand(parent *cobra.Command) {
 	cmd.AddCommand(newIndexDeprecateTruncateCmd())
 	cmd.AddCommand(newIndexPruneStrandedCmd())
 }
+
+// addDockerfileFlags adds the flags that customize the generated index dockerfile
+func addDockerfileFlags(cmd *cobra.Command) {
+	cmd.Flags().StringArray("label", nil, "extra label to set on the index image, as key=value. May be repeated, and may reference build args, e.g. org.opencontainers.image.created=${BUILD_DATE}")
+	cmd.Flags().StringArray("build-arg", nil, "build arg to declare in the generated dockerfile, as key=default. May be repeated")
+}
+
+// getDockerfileOptions returns the labels and build args given with the flags of addDockerfileFlags
+func getDockerfileOptions(cmd *cobra.Command) (map[string]string, map[string]string, error) {
+	labelFlags, err := cmd.Flags().GetStringArray("label")
+	if err != nil {
+		return nil, nil, err
+	}
+	labels, err := containertools.ParseKeyValues(labelFlags)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid --label: %s", err)
+	}
+
+	buildArgFlags, err := cmd.Flags().GetStringArray("build-arg")
+	if err != nil {
+		return nil, nil, err
+	}
+	buildArgs, err := containertools.ParseKeyValues(buildArgFlags)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid --build-arg: %s", err)
+	}
+
+	return labels, buildArgs, nil
+}
@@ -1,6 +1,8 @@
 package index
 
 import (
+	"context"
+
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 
@@ -27,6 +29,7 @@ func newIndexDeleteCmd() *cobra.Command {
 	indexCmd.Flags().Bool("debug", false, "enable debug logging")
 	indexCmd.Flags().Bool("generate", false, "if enabled, just creates the dockerfile and saves it to local disk")
 	indexCmd.Flags().StringP("out-dockerfile", "d", "", "if generating the dockerfile, this flag is used to (optionally) specify a dockerfile name")
+	addDockerfileFlags(indexCmd)
 	indexCmd.Flags().StringP("from-index", "f", "", "previous index to delete from")
 	if err := indexCmd.MarkFlagRequired("from-index"); err != nil {
 		logrus.Panic("Failed to set required `from-index` flag for `index delete`")
@@ -61,6 +64,11 @@ func runIndexDeleteCmdFunc(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
+	labels, buildArgs, err := getDockerfileOptions(cmd)
+	if err != nil {
+		return err
+	}
+
 	fromIndex, err := cmd.Flags().GetString("from-index")
 	if err != nil {
 		return err
@@ -114,9 +122,13 @@ func runIndexDeleteCmdFunc(cmd *cobra.Command, args []string) error {
 		Tag:               tag,
 		Permissive:        permissive,
 		SkipTLS:           skipTLS,
+		DockerfileOptions: indexer.DockerfileOptions{
+			Labels:    labels,
+			BuildArgs: buildArgs,
+		},
 	}
 
-	err = indexDeleter.DeleteFromIndex(request)
+	err = indexDeleter.DeleteFromIndex(context.Background(), request)
 	if err != nil {
 		return err
 	}
@@ -1,6 +1,8 @@
 package index
 
 import (
+	"context"
+
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 	"k8s.io/kubectl/pkg/util/templates"
@@ -46,6 +48,7 @@ func newIndexDeprecateTruncateCmd() *cobra.Command {
 	indexCmd.Flags().Bool("debug", false, "enable debug logging")
 	indexCmd.Flags().Bool("generate", false, "if enabled, just creates the dockerfile and saves it to local disk")
 	indexCmd.Flags().StringP("out-dockerfile", "d", "", "if generating the dockerfile, this flag is used to (optionally) specify a dockerfile name")
+	addDockerfileFlags(indexCmd)
 	indexCmd.Flags().StringP("from-index", "f", "", "previous index to add to")
 	indexCmd.Flags().StringSliceP("bundles", "b", nil, "comma separated list of bundles to add")
 	if err := indexCmd.MarkFlagRequired("bundles"); err != nil {
@@ -75,6 +78,11 @@ func runIndexDeprecateTruncateCmdFunc(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
+	labels, buildArgs, err := getDockerfileOptions(cmd)
+	if err != nil {
+		return err
+	}
+
 	fromIndex, err := cmd.Flags().GetString("from-index")
 	if err != nil {
 		return err
@@ -128,9 +136,13 @@ func runIndexDeprecateTruncateCmdFunc(cmd *cobra.Command, args []string) error {
 		Bundles:           bundles,
 		Permissive:        permissive,
 		SkipTLS:           skipTLS,
+		DockerfileOptions: indexer.DockerfileOptions{
+			Labels:    labels,
+			BuildArgs: buildArgs,
+		},
 	}
 
-	err = indexDeprecator.DeprecateFromIndex(request)
+	err = indexDeprecator.DeprecateFromIndex(context.Background(), request)
 	if err != nil {
 		return err
 	}
@@ -7,6 +7,7 @@ import (
 
 	"github.com/operator-framework/operator-registry/pkg/containertools"
 	"github.com/operator-framework/operator-registry/pkg/lib/indexer"
+	"github.com/operator-framework/operator-registry/pkg/lib/progress"
 )
 
 var exportLong = templates.LongDesc(`
@@ -43,6 +44,7 @@ func newIndexExportCmd() *cobra.Command {
 	indexCmd.Flags().StringSliceP("package", "p", nil, "comma separated list of packages to export")
 	indexCmd.Flags().StringP("download-folder", "f", "downloaded", "directory where downloaded operator bundle(s) will be stored")
 	indexCmd.Flags().StringP("container-tool", "c", "none", "tool to interact with container images (save, build, etc.). One of: [none, docker, podman]")
+	indexCmd.Flags().String("progress", "none", "how to report progress while the command runs. One of: [none, bar, json]. Interrupting the command rolls back partial changes")
 	if err := indexCmd.Flags().MarkHidden("debug"); err != nil {
 		logrus.Panic(err.Error())
 	}
@@ -83,6 +85,16 @@ func runIndexExportCmdFunc(cmd *cobra.Command, args []string) error {
 
 	indexExporter := indexer.NewIndexExporter(containertools.NewContainerTool(containerTool, containertools.NoneTool), logger)
 
+	progressFormat, err := cmd.Flags().GetString("progress")
+	if err != nil {
+		return err
+	}
+	ctx, cancel, err := progress.Context(progressFormat)
+	if err != nil {
+		return err
+	}
+	defer cancel()
+
 	request := indexer.ExportFromIndexRequest{
 		Index:         index,
 		Packages:      packages,
@@ -91,7 +103,7 @@ func runIndexExportCmdFunc(cmd *cobra.Command, args []string) error {
 		SkipTLS:       skipTLS,
 	}
 
-	err = indexExporter.ExportFromIndex(request)
+	err = indexExporter.ExportFromIndex(ctx, request)
 	if err != nil {
 		return err
 	}
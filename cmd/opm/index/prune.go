@@ -8,6 +8,8 @@ import (
 
 	"github.com/operator-framework/operator-registry/pkg/containertools"
 	"github.com/operator-framework/operator-registry/pkg/lib/indexer"
+	"github.com/operator-framework/operator-registry/pkg/lib/progress"
+	"github.com/operator-framework/operator-registry/pkg/registry"
 )
 
 func newIndexPruneCmd() *cobra.Command {
@@ -29,17 +31,18 @@ func newIndexPruneCmd() *cobra.Command {
 	indexCmd.Flags().Bool("debug", false, "enable debug logging")
 	indexCmd.Flags().Bool("generate", false, "if enabled, just creates the dockerfile and saves it to local disk")
 	indexCmd.Flags().StringP("out-dockerfile", "d", "", "if generating the dockerfile, this flag is used to (optionally) specify a dockerfile name")
+	addDockerfileFlags(indexCmd)
 	indexCmd.Flags().StringP("from-index", "f", "", "index to prune")
 	if err := indexCmd.MarkFlagRequired("from-index"); err != nil {
 		logrus.Panic("Failed to set required `from-index` flag for `index prune`")
 	}
-	indexCmd.Flags().StringSliceP("packages", "p", nil, "comma separated list of packages to keep")
-	if err := indexCmd.MarkFlagRequired("packages"); err != nil {
-		logrus.Panic("Failed to set required `packages` flag for `index prune`")
-	}
+	indexCmd.Flags().StringSliceP("packages", "p", nil, "comma separated list of packages to keep, required unless --heads-only or --install-mode is set")
+	indexCmd.Flags().Bool("heads-only", false, "also remove every bundle that isn't the head of a channel, leaving no upgrade paths to the heads")
+	indexCmd.Flags().String("install-mode", "", "also remove every bundle that doesn't support this install mode, e.g. AllNamespaces, along with the bundles that could only be upgraded through one; channels whose head doesn't support it are removed, and packages whose default channel head doesn't are removed entirely")
 	indexCmd.Flags().StringP("binary-image", "i", "", "container image for on-image `opm` command")
 	indexCmd.Flags().StringP("container-tool", "c", "podman", "tool to interact with container images (save, build, etc.). One of: [docker, podman]")
 	indexCmd.Flags().StringP("tag", "t", "", "custom tag for container image being built")
+	indexCmd.Flags().String("progress", "none", "how to report progress while the command runs. One of: [none, bar, json]. Interrupting the command rolls back partial changes")
 	indexCmd.Flags().Bool("permissive", false, "allow registry load errors")
 
 	if err := indexCmd.Flags().MarkHidden("debug"); err != nil {
@@ -61,6 +64,11 @@ func runIndexPruneCmdFunc(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
+	labels, buildArgs, err := getDockerfileOptions(cmd)
+	if err != nil {
+		return err
+	}
+
 	fromIndex, err := cmd.Flags().GetString("from-index")
 	if err != nil {
 		return err
@@ -71,6 +79,23 @@ func runIndexPruneCmdFunc(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
+	headsOnly, err := cmd.Flags().GetBool("heads-only")
+	if err != nil {
+		return err
+	}
+	installMode, err := cmd.Flags().GetString("install-mode")
+	if err != nil {
+		return err
+	}
+	if installMode != "" {
+		if err := registry.ValidateInstallMode(installMode); err != nil {
+			return err
+		}
+	}
+	if len(packages) == 0 && !headsOnly && installMode == "" {
+		return fmt.Errorf("--packages is required unless --heads-only or --install-mode is set")
+	}
+
 	binaryImage, err := cmd.Flags().GetString("binary-image")
 	if err != nil {
 		return err
@@ -106,6 +131,16 @@ func runIndexPruneCmdFunc(cmd *cobra.Command, args []string) error {
 
 	indexPruner := indexer.NewIndexPruner(containertools.NewContainerTool(containerTool, containertools.PodmanTool), logger)
 
+	progressFormat, err := cmd.Flags().GetString("progress")
+	if err != nil {
+		return err
+	}
+	ctx, cancel, err := progress.Context(progressFormat)
+	if err != nil {
+		return err
+	}
+	defer cancel()
+
 	request := indexer.PruneFromIndexRequest{
 		Generate:          generate,
 		FromIndex:         fromIndex,
@@ -115,9 +150,15 @@ func runIndexPruneCmdFunc(cmd *cobra.Command, args []string) error {
 		Tag:               tag,
 		Permissive:        permissive,
 		SkipTLS:           skipTLS,
+		HeadsOnly:         headsOnly,
+		InstallMode:       installMode,
+		DockerfileOptions: indexer.DockerfileOptions{
+			Labels:    labels,
+			BuildArgs: buildArgs,
+		},
 	}
 
-	err = indexPruner.PruneFromIndex(request)
+	err = indexPruner.PruneFromIndex(ctx, request)
 	if err != nil {
 		return err
 	}
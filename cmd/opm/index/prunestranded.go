@@ -1,6 +1,7 @@
 package index
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/sirupsen/logrus"
@@ -29,6 +30,7 @@ func newIndexPruneStrandedCmd() *cobra.Command {
 	indexCmd.Flags().Bool("debug", false, "enable debug logging")
 	indexCmd.Flags().Bool("generate", false, "if enabled, just creates the dockerfile and saves it to local disk")
 	indexCmd.Flags().StringP("out-dockerfile", "d", "", "if generating the dockerfile, this flag is used to (optionally) specify a dockerfile name")
+	addDockerfileFlags(indexCmd)
 	indexCmd.Flags().StringP("from-index", "f", "", "index to prune")
 	if err := indexCmd.MarkFlagRequired("from-index"); err != nil {
 		logrus.Panic("Failed to set required `from-index` flag for `index prune-stranded`")
@@ -56,6 +58,11 @@ func runIndexPruneStrandedCmdFunc(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
+	labels, buildArgs, err := getDockerfileOptions(cmd)
+	if err != nil {
+		return err
+	}
+
 	fromIndex, err := cmd.Flags().GetString("from-index")
 	if err != nil {
 		return err
@@ -98,9 +105,13 @@ func runIndexPruneStrandedCmdFunc(cmd *cobra.Command, args []string) error {
 		OutDockerfile:     outDockerfile,
 		Tag:               tag,
 		SkipTLS:           skipTLS,
+		DockerfileOptions: indexer.DockerfileOptions{
+			Labels:    labels,
+			BuildArgs: buildArgs,
+		},
 	}
 
-	err = indexPruner.PruneStrandedFromIndex(request)
+	err = indexPruner.PruneStrandedFromIndex(context.Background(), request)
 	if err != nil {
 		return err
 	}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"os"
+	"time"
 
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
@@ -11,6 +12,9 @@ import (
 	"github.com/operator-framework/operator-registry/cmd/opm/index"
 	"github.com/operator-framework/operator-registry/cmd/opm/registry"
 	"github.com/operator-framework/operator-registry/cmd/opm/version"
+	"github.com/operator-framework/operator-registry/pkg/lib/progress"
+	"github.com/operator-framework/operator-registry/pkg/lib/telemetry"
+	"github.com/operator-framework/operator-registry/pkg/lib/tmp"
 	registrylib "github.com/operator-framework/operator-registry/pkg/registry"
 )
 
@@ -27,6 +31,15 @@ func main() {
 		},
 	}
 
+	var workDir, logFormat string
+	var events *progress.Stream
+	rootCmd.PersistentFlags().StringVar(&workDir, "workdir", "", "directory to create temporary files (unpacked images, build contexts, database copies) in. Defaults to the current directory")
+	rootCmd.PersistentFlags().StringVar(&logFormat, "log-format", string(progress.LogFormatText), "how to report what the command does. One of: [text, json-lines]; json-lines writes its start, progress, logs and result as json objects, one per line, in place of the logs")
+	cobra.OnInitialize(func() {
+		tmp.SetWorkDir(workDir)
+		events = startEvents(rootCmd, logFormat)
+	})
+
 	rootCmd.AddCommand(registry.NewOpmRegistryCmd(), alpha.NewCmd())
 	index.AddCommand(rootCmd)
 	version.AddCommand(rootCmd)
@@ -36,19 +49,39 @@ func main() {
 		logrus.Panic(err.Error())
 	}
 
-	if err := rootCmd.Execute(); err != nil {
-		agg, ok := err.(utilerrors.Aggregate)
-		if !ok {
-			os.Exit(1)
+	if ran, err := runPlugin(rootCmd, os.Args[1:]); ran {
+		exitPlugin(err)
+	}
+
+	started := time.Now()
+	cmd, err := rootCmd.ExecuteC()
+	recordTelemetry(telemetry.FromEnv(), cmd, err, started)
+	code := exitCode(err)
+	if events != nil {
+		events.Result(err, code, time.Since(started))
+	}
+	if code != 0 {
+		os.Exit(code)
+	}
+}
+
+// exitCode returns the status opm exits with after err: 2 if a bundle image was already added, 3 if a package version
+// was, and 1 for any other error
+func exitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	agg, ok := err.(utilerrors.Aggregate)
+	if !ok {
+		return 1
+	}
+	for _, e := range agg.Errors() {
+		if _, ok := e.(registrylib.BundleImageAlreadyAddedErr); ok {
+			return 2
 		}
-		for _, e := range agg.Errors() {
-			if _, ok := e.(registrylib.BundleImageAlreadyAddedErr); ok {
-				os.Exit(2)
-			}
-			if _, ok := e.(registrylib.PackageVersionAlreadyAddedErr); ok {
-				os.Exit(3)
-			}
+		if _, ok := e.(registrylib.PackageVersionAlreadyAddedErr); ok {
+			return 3
 		}
-		os.Exit(1)
 	}
+	return 1
 }
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/operator-framework/operator-registry/pkg/lib/plugin"
+	"github.com/operator-framework/operator-registry/pkg/lib/tmp"
+)
+
+// runPlugin runs the plugin named by the first argument with the rest of them. It returns false, without running
+// anything, if the first argument is an opm command, which plugins can't replace, or there is no such plugin.
+func runPlugin(rootCmd *cobra.Command, args []string) (bool, error) {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		return false, nil
+	}
+	rootCmd.InitDefaultHelpCmd()
+	if cmd, _, err := rootCmd.Find(args); err == nil && cmd != rootCmd {
+		return false, nil
+	}
+	p, ok := plugin.Lookup(args[0])
+	if !ok {
+		return false, nil
+	}
+
+	binary, err := os.Executable()
+	if err != nil {
+		return true, err
+	}
+	env := plugin.Env{Binary: binary, WorkDir: tmp.WorkDir(), Debug: logrus.IsLevelEnabled(logrus.DebugLevel)}
+	logrus.WithField("plugin", p.Path).Debug("running plugin")
+	return true, p.Run(context.Background(), args[1:], env, os.Stdin, os.Stdout, os.Stderr)
+}
+
+// exitPlugin exits with the status of a plugin that was run
+func exitPlugin(err error) {
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		os.Exit(exitErr.ExitCode())
+	}
+	if err != nil {
+		logrus.Fatal(err)
+	}
+	os.Exit(0)
+}
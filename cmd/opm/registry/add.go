@@ -5,6 +5,7 @@ import (
 	"github.com/spf13/cobra"
 
 	"github.com/operator-framework/operator-registry/pkg/containertools"
+	"github.com/operator-framework/operator-registry/pkg/lib/progress"
 	"github.com/operator-framework/operator-registry/pkg/lib/registry"
 	reg "github.com/operator-framework/operator-registry/pkg/registry"
 )
@@ -32,6 +33,16 @@ func newRegistryAddCmd() *cobra.Command {
 	rootCmd.Flags().Bool("skip-tls", false, "skip TLS certificate verification for container image registries while pulling bundles")
 	rootCmd.Flags().StringP("mode", "", "replaces", "graph update mode that defines how channel graphs are updated. One of: [replaces, semver, semver-skippatch]")
 	rootCmd.Flags().StringP("container-tool", "c", "none", "tool to interact with container images (save, build, etc.). One of: [none, docker, podman]")
+	rootCmd.Flags().String("verify-cmd", "", "if set, verify each bundle image with this command before adding it and record the verification evidence. The image is appended as the last argument and a JSON attestation ({certificateIdentity, certificateIssuer, rekorLogIndex}) is read from stdout")
+	rootCmd.Flags().StringSlice("credential-provider", []string{}, "get credentials for registries that have none in the docker config from the environment, when the container tool is none. Any of: [serviceaccount:<host>, gcr, acr, ecr]")
+	rootCmd.Flags().String("progress", "none", "how to report progress while the command runs. One of: [none, bar, json]. Interrupting the command rolls back partial changes")
+	rootCmd.Flags().String("journal-mode", "", "SQLite journal mode to write the database with, WAL if not set. One of: [DELETE, TRUNCATE, PERSIST, MEMORY, WAL, OFF]. The database is checkpointed and switched back to DELETE once the bundles are added")
+	rootCmd.Flags().String("synchronous", "", "SQLite synchronous setting to write the database with, e.g. NORMAL. One of: [OFF, NORMAL, FULL, EXTRA]")
+	rootCmd.Flags().StringSlice("channels", []string{}, "comma separated list of channels to add the bundles to, overriding the channels of their annotations. The override is recorded in the database")
+	rootCmd.Flags().String("default-channel", "", "default channel of the package, overriding the default channel of the bundle annotations. The override is recorded in the database")
+	rootCmd.Flags().Bool("in-memory", false, "build the database in memory and only write it out once every bundle is added. Can't be used with --journal-mode or --synchronous")
+	rootCmd.Flags().String("csv-name-conflicts", "reject", "what is done with bundles whose CSV name already belongs to another package. One of: [reject, warn]. Warn skips them and adds the other bundles")
+	rootCmd.Flags().String("version-policy", "", "if set, reject bundles whose versions break the version policy in this rule file: versions must be valid semver, and the file sets whether build metadata, pre-releases outside some channels and downgrades are allowed")
 
 	return rootCmd
 }
@@ -65,14 +76,78 @@ func addFunc(cmd *cobra.Command, args []string) error {
 	if err != nil {
 		return err
 	}
+	verifyCmd, err := cmd.Flags().GetString("verify-cmd")
+	if err != nil {
+		return err
+	}
+	credentialProviders, err := cmd.Flags().GetStringSlice("credential-provider")
+	if err != nil {
+		return err
+	}
+	journalMode, err := cmd.Flags().GetString("journal-mode")
+	if err != nil {
+		return err
+	}
+	synchronous, err := cmd.Flags().GetString("synchronous")
+	if err != nil {
+		return err
+	}
+	inMemory, err := cmd.Flags().GetBool("in-memory")
+	if err != nil {
+		return err
+	}
+	csvNameConflicts, err := cmd.Flags().GetString("csv-name-conflicts")
+	if err != nil {
+		return err
+	}
+	csvNameConflictPolicy, err := reg.GetConflictPolicyFromString(csvNameConflicts)
+	if err != nil {
+		return err
+	}
+	versionPolicyFile, err := cmd.Flags().GetString("version-policy")
+	if err != nil {
+		return err
+	}
+	var versionPolicy *reg.VersionPolicy
+	if versionPolicyFile != "" {
+		if versionPolicy, err = reg.LoadVersionPolicy(versionPolicyFile); err != nil {
+			return err
+		}
+	}
+	channels, err := cmd.Flags().GetStringSlice("channels")
+	if err != nil {
+		return err
+	}
+	defaultChannel, err := cmd.Flags().GetString("default-channel")
+	if err != nil {
+		return err
+	}
+
+	progressFormat, err := cmd.Flags().GetString("progress")
+	if err != nil {
+		return err
+	}
+	ctx, cancel, err := progress.Context(progressFormat)
+	if err != nil {
+		return err
+	}
+	defer cancel()
 
 	request := registry.AddToRegistryRequest{
-		Permissive:    permissive,
-		SkipTLS:       skipTLS,
-		InputDatabase: fromFilename,
-		Bundles:       bundleImages,
-		Mode:          modeEnum,
-		ContainerTool: containertools.NewContainerTool(containerTool, containertools.NoneTool),
+		Permissive:          permissive,
+		SkipTLS:             skipTLS,
+		InputDatabase:       fromFilename,
+		Bundles:             bundleImages,
+		Mode:                modeEnum,
+		ContainerTool:       containertools.NewContainerTool(containerTool, containertools.NoneTool),
+		VerifyCommand:       verifyCmd,
+		CredentialProviders: credentialProviders,
+		JournalMode:         journalMode,
+		Synchronous:         synchronous,
+		InMemory:            inMemory,
+		CSVNameConflicts:    csvNameConflictPolicy,
+		VersionPolicy:       versionPolicy,
+		ChannelOverride:     reg.ChannelOverride{Channels: channels, DefaultChannel: defaultChannel},
 	}
 
 	logger := logrus.WithFields(logrus.Fields{"bundles": bundleImages})
@@ -85,9 +160,12 @@ func addFunc(cmd *cobra.Command, args []string) error {
 
 	registryAdder := registry.NewRegistryAdder(logger)
 
-	err = registryAdder.AddToRegistry(request)
+	result, err := registryAdder.AddToRegistry(ctx, request)
 	if err != nil {
 		return err
 	}
+	if len(result.AlreadyPresent) > 0 {
+		logger.WithField("skipped", result.AlreadyPresent).Info("bundles already present with identical content were skipped")
+	}
 	return nil
 }
@@ -0,0 +1,64 @@
+package registry
+
+import (
+	"github.com/operator-framework/operator-registry/pkg/lib/registry"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+func newRegistryAnonymizeCmd() *cobra.Command {
+	rootCmd := &cobra.Command{
+		Use:   "anonymize",
+		Short: "write an anonymized copy of an operator registry DB",
+		Long: `Write an anonymized copy of an operator registry DB that can be shared, e.g. to reproduce a performance
+problem seen with a production catalog. The packages, channels, upgrade graph, versions and apis of the catalog are
+kept; every name is replaced by a stand-in and the csv of each bundle by a stub of --blob-size bytes.`,
+
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if debug, _ := cmd.Flags().GetBool("debug"); debug {
+				logrus.SetLevel(logrus.DebugLevel)
+			}
+			return nil
+		},
+
+		RunE: anonymizeFunc,
+	}
+
+	rootCmd.Flags().Bool("debug", false, "enable debug logging")
+	rootCmd.Flags().StringP("database", "d", "bundles.db", "relative path to database file")
+	rootCmd.Flags().StringP("output", "o", "", "relative path to the anonymized database file to write")
+	rootCmd.Flags().Int("blob-size", 1024, "size in bytes of the stub csv that replaces the csv of each bundle")
+	if err := rootCmd.MarkFlagRequired("output"); err != nil {
+		logrus.Panic("Failed to set required `output` flag for `registry anonymize`")
+	}
+
+	return rootCmd
+}
+
+func anonymizeFunc(cmd *cobra.Command, args []string) error {
+	fromFilename, err := cmd.Flags().GetString("database")
+	if err != nil {
+		return err
+	}
+	toFilename, err := cmd.Flags().GetString("output")
+	if err != nil {
+		return err
+	}
+	blobSize, err := cmd.Flags().GetInt("blob-size")
+	if err != nil {
+		return err
+	}
+
+	request := registry.AnonymizeRegistryRequest{
+		InputDatabase:  fromFilename,
+		OutputDatabase: toFilename,
+		BlobSize:       blobSize,
+	}
+
+	logger := logrus.WithFields(logrus.Fields{"database": fromFilename, "output": toFilename})
+
+	registryAnonymizer := registry.NewRegistryAnonymizer(logger)
+
+	return registryAnonymizer.AnonymizeRegistry(request)
+}
@@ -0,0 +1,70 @@
+package registry
+
+import (
+	"os"
+
+	"github.com/operator-framework/operator-registry/pkg/lib/registry"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+func newRegistryAPIMatrixCmd() *cobra.Command {
+	rootCmd := &cobra.Command{
+		Use:   "api-matrix",
+		Short: "export which packages provide and require each api",
+		Long: `Export a matrix of the GVKs provided and required by the bundles of an operator registry DB, listing for each
+one the packages that provide it and the packages that require it. GVKs provided by more than one package are
+marked as collisions.`,
+		Example: `$ opm registry api-matrix -d index.db -o csv > apis.csv
+$ opm registry api-matrix -d index.db -o json --collisions-only`,
+
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if debug, _ := cmd.Flags().GetBool("debug"); debug {
+				logrus.SetLevel(logrus.DebugLevel)
+			}
+			return nil
+		},
+
+		RunE: apiMatrixFunc,
+	}
+
+	rootCmd.Flags().Bool("debug", false, "enable debug logging")
+	rootCmd.Flags().StringP("database", "d", "bundles.db", "relative path to database file")
+	rootCmd.Flags().StringP("output", "o", registry.APIMatrixFormatCSV, "format of the matrix written to stdout. One of: [csv, json]")
+	rootCmd.Flags().Bool("collisions-only", false, "only export the apis provided by more than one package")
+
+	return rootCmd
+}
+
+func apiMatrixFunc(cmd *cobra.Command, args []string) error {
+	fromFilename, err := cmd.Flags().GetString("database")
+	if err != nil {
+		return err
+	}
+	format, err := cmd.Flags().GetString("output")
+	if err != nil {
+		return err
+	}
+	collisionsOnly, err := cmd.Flags().GetBool("collisions-only")
+	if err != nil {
+		return err
+	}
+
+	if _, err := os.Stat(fromFilename); err != nil {
+		return err
+	}
+
+	request := registry.ExportAPIMatrixRequest{
+		InputDatabase:  fromFilename,
+		Format:         format,
+		CollisionsOnly: collisionsOnly,
+		Output:         os.Stdout,
+	}
+
+	logger := logrus.WithFields(logrus.Fields{"database": fromFilename})
+
+	exporter := registry.NewRegistryAPIMatrixExporter(logger)
+
+	return exporter.ExportAPIMatrix(request)
+}
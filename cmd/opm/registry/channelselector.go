@@ -0,0 +1,108 @@
+package registry
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/operator-framework/operator-registry/pkg/lib/registry"
+	pregistry "github.com/operator-framework/operator-registry/pkg/registry"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+func newRegistryChannelSelectorCmd() *cobra.Command {
+	rootCmd := &cobra.Command{
+		Use:   "channel-selector",
+		Short: "define a channel of an operator registry DB by a selector",
+		Long: `Define a channel of a package by a selector over the versions and properties of its bundles, instead of
+by its entries. The channel holds every bundle of the package whose version is in the --versions range and that has
+each --property, upgrading from one to the next in version order with the highest version at its head. It is
+expanded again each time bundles are added to the DB and each time the DB is served, so that new bundles join it
+without being added to it by hand.
+
+A property is given as type=value, where value is JSON and matches the value of a property of the same type if that
+has every field of it, e.g. --property 'olm.label={"label":"lts"}'.`,
+		Example: `  opm registry channel-selector -d index.db --package etcd --channel 2.x --versions ">=2.0.0 <3.0.0"
+  opm registry channel-selector -d index.db --package etcd --channel 2.x --remove`,
+
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if debug, _ := cmd.Flags().GetBool("debug"); debug {
+				logrus.SetLevel(logrus.DebugLevel)
+			}
+			return nil
+		},
+
+		RunE: channelSelectorFunc,
+	}
+
+	rootCmd.Flags().Bool("debug", false, "enable debug logging")
+	rootCmd.Flags().StringP("database", "d", "bundles.db", "relative path to database file")
+	rootCmd.Flags().String("package", "", "package the channel belongs to")
+	rootCmd.Flags().String("channel", "", "channel to define by the selector")
+	rootCmd.Flags().String("versions", "", "semver range the version of each selected bundle must be in")
+	rootCmd.Flags().StringArray("property", nil, "property, as type=value, that each selected bundle must have")
+	rootCmd.Flags().Bool("remove", false, "remove the selector of the channel, leaving the entries it was last expanded into")
+	for _, name := range []string{"package", "channel"} {
+		if err := rootCmd.MarkFlagRequired(name); err != nil {
+			logrus.Panicf("Failed to set required `%s` flag for `registry channel-selector`", name)
+		}
+	}
+
+	return rootCmd
+}
+
+func channelSelectorFunc(cmd *cobra.Command, args []string) error {
+	fromFilename, err := cmd.Flags().GetString("database")
+	if err != nil {
+		return err
+	}
+	packageName, err := cmd.Flags().GetString("package")
+	if err != nil {
+		return err
+	}
+	channel, err := cmd.Flags().GetString("channel")
+	if err != nil {
+		return err
+	}
+	versions, err := cmd.Flags().GetString("versions")
+	if err != nil {
+		return err
+	}
+	propertyFlags, err := cmd.Flags().GetStringArray("property")
+	if err != nil {
+		return err
+	}
+	remove, err := cmd.Flags().GetBool("remove")
+	if err != nil {
+		return err
+	}
+	if remove && (versions != "" || len(propertyFlags) > 0) {
+		return fmt.Errorf("--remove can't be used with --versions or --property")
+	}
+
+	selector := pregistry.ChannelSelector{PackageName: packageName, ChannelName: channel, Versions: versions}
+	for _, p := range propertyFlags {
+		parts := strings.SplitN(p, "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return fmt.Errorf("invalid property %q, expected type=value", p)
+		}
+		if !json.Valid([]byte(parts[1])) {
+			return fmt.Errorf("invalid property %q, value must be JSON", p)
+		}
+		selector.Properties = append(selector.Properties, pregistry.Property{Type: parts[0], Value: json.RawMessage(parts[1])})
+	}
+
+	request := registry.ChannelSelectorRequest{
+		InputDatabase: fromFilename,
+		Selector:      selector,
+		Remove:        remove,
+	}
+
+	logger := logrus.WithFields(logrus.Fields{"database": fromFilename, "package": packageName, "channel": channel})
+
+	registryUpdater := registry.NewRegistryChannelSelectorUpdater(logger)
+
+	return registryUpdater.UpdateChannelSelector(request)
+}
@@ -23,8 +23,21 @@ func NewOpmRegistryCmd() *cobra.Command {
 	rootCmd.AddCommand(newRegistryServeCmd())
 	rootCmd.AddCommand(newRegistryAddCmd())
 	rootCmd.AddCommand(newRegistryRmCmd())
+	rootCmd.AddCommand(newRegistryPurgeCmd())
+	rootCmd.AddCommand(newRegistryRenameCmd())
+	rootCmd.AddCommand(newRegistryReplayCmd())
+	rootCmd.AddCommand(newRegistryShardCmd())
+	rootCmd.AddCommand(newRegistryAPIMatrixCmd())
 	rootCmd.AddCommand(newRegistryPruneCmd())
 	rootCmd.AddCommand(newRegistryPruneStrandedCmd())
+	rootCmd.AddCommand(newRegistryMigrateCmd())
+	rootCmd.AddCommand(newRegistryMirrorManifestsCmd())
+	rootCmd.AddCommand(newRegistryDeprecateCmd())
+	rootCmd.AddCommand(newRegistryAnonymizeCmd())
+	rootCmd.AddCommand(newRegistryEncryptCmd())
+	rootCmd.AddCommand(newRegistryDecryptCmd())
+	rootCmd.AddCommand(newRegistryChannelSelectorCmd())
+	rootCmd.AddCommand(newRegistryExportArtifactCmd())
 
 	return rootCmd
 }
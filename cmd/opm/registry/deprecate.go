@@ -0,0 +1,75 @@
+package registry
+
+import (
+	"github.com/operator-framework/operator-registry/pkg/lib/registry"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+func newRegistryDeprecateCmd() *cobra.Command {
+	rootCmd := &cobra.Command{
+		Use:   "deprecate",
+		Short: "mark bundles in an operator registry DB deprecated",
+		Long: `Mark bundles in an operator registry DB deprecated, along with every bundle before them in the upgrade graph.
+
+Deprecated bundles stay in the upgrade graph, so that installed operators can still upgrade past them, but are never
+returned as the head of a channel unless a client explicitly asks for deprecated bundles. With --truncate the bundles
+before the deprecated ones are removed instead, as opm index deprecatetruncate does.`,
+		Example: `
+  # Deprecate etcd 0.9.0 and every bundle it replaces or skips
+  opm registry deprecate -d index.db --bundle-images quay.io/example/etcd-bundle:0.9.0`,
+
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if debug, _ := cmd.Flags().GetBool("debug"); debug {
+				logrus.SetLevel(logrus.DebugLevel)
+			}
+			return nil
+		},
+
+		RunE: runRegistryDeprecateCmdFunc,
+	}
+
+	rootCmd.Flags().Bool("debug", false, "enable debug logging")
+	rootCmd.Flags().StringP("database", "d", "bundles.db", "relative path to database file")
+	rootCmd.Flags().StringSliceP("bundle-images", "b", nil, "comma separated list of the images of the bundles to deprecate")
+	if err := rootCmd.MarkFlagRequired("bundle-images"); err != nil {
+		logrus.Panic("Failed to set required `bundle-images` flag for `registry deprecate`")
+	}
+	rootCmd.Flags().Bool("truncate", false, "remove the bundles before the deprecated ones from the upgrade graph instead of marking them deprecated")
+	rootCmd.Flags().Bool("permissive", false, "allow registry load errors")
+
+	return rootCmd
+}
+
+func runRegistryDeprecateCmdFunc(cmd *cobra.Command, args []string) error {
+	fromFilename, err := cmd.Flags().GetString("database")
+	if err != nil {
+		return err
+	}
+	bundles, err := cmd.Flags().GetStringSlice("bundle-images")
+	if err != nil {
+		return err
+	}
+	truncate, err := cmd.Flags().GetBool("truncate")
+	if err != nil {
+		return err
+	}
+	permissive, err := cmd.Flags().GetBool("permissive")
+	if err != nil {
+		return err
+	}
+
+	request := registry.DeprecateFromRegistryRequest{
+		Bundles:          bundles,
+		InputDatabase:    fromFilename,
+		Permissive:       permissive,
+		KeepPredecessors: !truncate,
+	}
+
+	logger := logrus.WithFields(logrus.Fields{"bundles": bundles})
+	logger.Info("deprecating from the registry")
+
+	registryDeprecator := registry.NewRegistryDeprecator(logger)
+	return registryDeprecator.DeprecateFromRegistry(request)
+}
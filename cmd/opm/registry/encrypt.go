@@ -0,0 +1,107 @@
+package registry
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/operator-framework/operator-registry/pkg/sqlite"
+)
+
+func newRegistryEncryptCmd() *cobra.Command {
+	rootCmd := &cobra.Command{
+		Use:   "encrypt",
+		Short: "write an encrypted copy of an operator registry DB",
+		Long: `Write a copy of an operator registry DB that is encrypted at rest with the key in --key-file, or in
+$OPM_DATABASE_KEY. The key should be random, e.g. from openssl rand 32. opm registry serve decrypts the copy when it is
+served with the same key, without writing the decrypted catalog anywhere but its work directory.`,
+
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if debug, _ := cmd.Flags().GetBool("debug"); debug {
+				logrus.SetLevel(logrus.DebugLevel)
+			}
+			return nil
+		},
+
+		RunE: encryptFunc,
+	}
+
+	addEncryptFlags(rootCmd, "encrypted")
+
+	return rootCmd
+}
+
+func newRegistryDecryptCmd() *cobra.Command {
+	rootCmd := &cobra.Command{
+		Use:   "decrypt",
+		Short: "write a decrypted copy of an encrypted operator registry DB",
+		Long: `Write a decrypted copy of an operator registry DB written by opm registry encrypt, with the key in
+--key-file, or in $OPM_DATABASE_KEY, e.g. to modify it with opm registry add before encrypting it again.`,
+
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if debug, _ := cmd.Flags().GetBool("debug"); debug {
+				logrus.SetLevel(logrus.DebugLevel)
+			}
+			return nil
+		},
+
+		RunE: decryptFunc,
+	}
+
+	addEncryptFlags(rootCmd, "decrypted")
+
+	return rootCmd
+}
+
+func addEncryptFlags(cmd *cobra.Command, output string) {
+	cmd.Flags().Bool("debug", false, "enable debug logging")
+	cmd.Flags().StringP("database", "d", "bundles.db", "relative path to database file")
+	cmd.Flags().StringP("output", "o", "", fmt.Sprintf("relative path to the %s database file to write", output))
+	cmd.Flags().String("key-file", "", "path to the key, defaults to the key in $OPM_DATABASE_KEY")
+	if err := cmd.MarkFlagRequired("output"); err != nil {
+		logrus.Panicf("Failed to set required `output` flag for `registry %s`", cmd.Name())
+	}
+}
+
+func encryptFunc(cmd *cobra.Command, args []string) error {
+	fromFilename, toFilename, key, err := encryptArgs(cmd)
+	if err != nil {
+		return err
+	}
+	logrus.WithFields(logrus.Fields{"database": fromFilename, "output": toFilename}).Info("encrypting database")
+	return sqlite.EncryptDatabase(context.TODO(), key, fromFilename, toFilename)
+}
+
+func decryptFunc(cmd *cobra.Command, args []string) error {
+	fromFilename, toFilename, key, err := encryptArgs(cmd)
+	if err != nil {
+		return err
+	}
+	logrus.WithFields(logrus.Fields{"database": fromFilename, "output": toFilename}).Info("decrypting database")
+	return sqlite.DecryptDatabase(key, fromFilename, toFilename)
+}
+
+func encryptArgs(cmd *cobra.Command) (string, string, *sqlite.DatabaseKey, error) {
+	fromFilename, err := cmd.Flags().GetString("database")
+	if err != nil {
+		return "", "", nil, err
+	}
+	toFilename, err := cmd.Flags().GetString("output")
+	if err != nil {
+		return "", "", nil, err
+	}
+	keyFile, err := cmd.Flags().GetString("key-file")
+	if err != nil {
+		return "", "", nil, err
+	}
+	key, err := sqlite.LoadDatabaseKey(keyFile)
+	if err != nil {
+		return "", "", nil, err
+	}
+	if key == nil {
+		return "", "", nil, fmt.Errorf("a key is needed, set --key-file or $%s", sqlite.EnvDatabaseKey)
+	}
+	return fromFilename, toFilename, key, nil
+}
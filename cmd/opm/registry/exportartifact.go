@@ -0,0 +1,98 @@
+package registry
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/operator-framework/operator-registry/pkg/image"
+	"github.com/operator-framework/operator-registry/pkg/image/containerdregistry"
+	"github.com/operator-framework/operator-registry/pkg/lib/artifact"
+	"github.com/operator-framework/operator-registry/pkg/lib/certs"
+	"github.com/operator-framework/operator-registry/pkg/lib/tmp"
+)
+
+func newRegistryExportArtifactCmd() *cobra.Command {
+	rootCmd := &cobra.Command{
+		Use:   "export-artifact",
+		Short: "push an operator registry DB to an image registry as an OCI artifact",
+		Long: `Push an operator registry DB to an image registry as an OCI artifact rather than a runnable image: a
+manifest whose config lists the packages in the catalog and whose only layer is the database, with media types
+of their own, so that catalogs can be distributed through image registries without shipping executables.
+The artifact can be served with "opm registry serve --database oci://<ref> --database-sha256 <sha256>", and
+the digest of its manifest is written to stderr.`,
+		Example: `$ opm registry export-artifact -d index.db --to quay.io/example/catalog:v1`,
+
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if debug, _ := cmd.Flags().GetBool("debug"); debug {
+				logrus.SetLevel(logrus.DebugLevel)
+			}
+			return nil
+		},
+
+		RunE: exportArtifactFunc,
+	}
+
+	rootCmd.Flags().Bool("debug", false, "enable debug logging")
+	rootCmd.Flags().StringP("database", "d", "bundles.db", "relative path to database file")
+	rootCmd.Flags().String("to", "", "reference the artifact is pushed to")
+	if err := rootCmd.MarkFlagRequired("to"); err != nil {
+		logrus.Panic("Failed to set required `to` flag for `registry export-artifact`")
+	}
+	rootCmd.Flags().Bool("skip-tls", false, "skip TLS certificate verification for container image registries")
+	rootCmd.Flags().String("ca-file", "", "the root certificates to use with container image registries")
+
+	return rootCmd
+}
+
+func exportArtifactFunc(cmd *cobra.Command, args []string) error {
+	fromFilename, err := cmd.Flags().GetString("database")
+	if err != nil {
+		return err
+	}
+	to, err := cmd.Flags().GetString("to")
+	if err != nil {
+		return err
+	}
+	skipTLS, err := cmd.Flags().GetBool("skip-tls")
+	if err != nil {
+		return err
+	}
+	caFile, err := cmd.Flags().GetString("ca-file")
+	if err != nil {
+		return err
+	}
+	rootCAs, err := certs.RootCAs(caFile)
+	if err != nil {
+		return fmt.Errorf("failed to get RootCAs: %v", err)
+	}
+
+	logger := logrus.WithFields(logrus.Fields{"database": fromFilename, "to": to})
+
+	workspace, err := tmp.NewWorkspace("export-artifact")
+	if err != nil {
+		return err
+	}
+	defer workspace.Cleanup()
+	reg, err := containerdregistry.NewRegistry(containerdregistry.SkipTLS(skipTLS), containerdregistry.WithRootCAs(rootCAs), containerdregistry.WithLog(logger), containerdregistry.WithCacheDir(filepath.Join(workspace.Path(), "cache")))
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := reg.Destroy(); err != nil {
+			logger.WithError(err).Warn("error destroying local cache")
+		}
+	}()
+
+	digest, err := artifact.Push(context.Background(), reg, fromFilename, image.SimpleReference(to))
+	if err != nil {
+		return err
+	}
+	logger.WithField("digest", digest).Info("pushed artifact")
+	fmt.Fprintf(os.Stderr, "digest: %s\n", digest)
+	return nil
+}
@@ -0,0 +1,56 @@
+package registry
+
+import (
+	"github.com/operator-framework/operator-registry/pkg/lib/registry"
+	"github.com/operator-framework/operator-registry/pkg/sqlite/migrations"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+func newRegistryMigrateCmd() *cobra.Command {
+	rootCmd := &cobra.Command{
+		Use:   "migrate",
+		Short: "migrate the schema of an operator registry DB",
+		Long: `Migrate the schema of an operator registry DB in place. Databases are migrated up to the latest schema
+whenever opm loads them; this upgrades one ahead of time, or migrates it down to an earlier schema version for an
+older opm. Migrating down drops the data that the later schema versions hold.`,
+
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if debug, _ := cmd.Flags().GetBool("debug"); debug {
+				logrus.SetLevel(logrus.DebugLevel)
+			}
+			return nil
+		},
+
+		RunE: migrateFunc,
+	}
+
+	rootCmd.Flags().Bool("debug", false, "enable debug logging")
+	rootCmd.Flags().StringP("database", "d", "bundles.db", "relative path to database file")
+	rootCmd.Flags().Int("version", migrations.Latest(), "schema version to migrate the database to")
+
+	return rootCmd
+}
+
+func migrateFunc(cmd *cobra.Command, args []string) error {
+	fromFilename, err := cmd.Flags().GetString("database")
+	if err != nil {
+		return err
+	}
+	version, err := cmd.Flags().GetInt("version")
+	if err != nil {
+		return err
+	}
+
+	request := registry.MigrateRegistryRequest{
+		InputDatabase: fromFilename,
+		Version:       version,
+	}
+
+	logger := logrus.WithFields(logrus.Fields{"database": fromFilename})
+
+	registryMigrator := registry.NewRegistryMigrator(logger)
+
+	return registryMigrator.MigrateRegistry(request)
+}
@@ -0,0 +1,87 @@
+package registry
+
+import (
+	"os"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/operator-framework/operator-registry/pkg/mirror"
+)
+
+func newRegistryMirrorManifestsCmd() *cobra.Command {
+	rootCmd := &cobra.Command{
+		Use:   "mirror-manifests",
+		Short: "generate manifests to mirror the images of an operator registry DB",
+		Long: `Generate the manifests to mirror every image an operator registry DB refers to, its bundles and their related
+images, to another registry for a disconnected cluster. A mapping.txt for oc image mirror -f, and the
+ImageContentSourcePolicy, ImageDigestMirrorSet and, for images referenced by tag, ImageTagMirrorSet that redirect the
+cluster to the mirrored images are written to --to-manifests. Images aren't mirrored.`,
+
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if debug, _ := cmd.Flags().GetBool("debug"); debug {
+				logrus.SetLevel(logrus.DebugLevel)
+			}
+			return nil
+		},
+
+		RunE: mirrorManifestsFunc,
+	}
+
+	rootCmd.Flags().Bool("debug", false, "enable debug logging")
+	rootCmd.Flags().StringP("database", "d", "bundles.db", "relative path to database file")
+	rootCmd.Flags().String("dest", "", "registry, and optionally namespace, to mirror the images to, e.g. registry.example.com/mirror")
+	rootCmd.Flags().String("to-manifests", "manifests", "directory to write the mapping file and mirror manifests to")
+	rootCmd.Flags().String("name", mirror.DefaultName, "name of the mirror manifests")
+	if err := rootCmd.MarkFlagRequired("dest"); err != nil {
+		logrus.Panic("Failed to set required `dest` flag for `registry mirror-manifests`")
+	}
+
+	return rootCmd
+}
+
+func mirrorManifestsFunc(cmd *cobra.Command, args []string) error {
+	fromFilename, err := cmd.Flags().GetString("database")
+	if err != nil {
+		return err
+	}
+	dest, err := cmd.Flags().GetString("dest")
+	if err != nil {
+		return err
+	}
+	manifestDir, err := cmd.Flags().GetString("to-manifests")
+	if err != nil {
+		return err
+	}
+	name, err := cmd.Flags().GetString("name")
+	if err != nil {
+		return err
+	}
+
+	// opening a missing database would create it
+	if _, err := os.Stat(fromFilename); err != nil {
+		return err
+	}
+
+	// the database is read in place rather than extracted from an index image
+	var extractor mirror.DatabaseExtractorFunc = func(from string) (string, error) {
+		return from, nil
+	}
+	mirrorer, err := mirror.NewIndexImageMirror(
+		mirror.WithExtractor(extractor),
+		mirror.WithSource(fromFilename),
+		mirror.WithDest(dest),
+		mirror.WithManifestDir(manifestDir),
+		mirror.WithName(name),
+	)
+	if err != nil {
+		return err
+	}
+
+	mapping, err := mirrorer.Mirror()
+	if err != nil {
+		return err
+	}
+	logrus.WithField("manifests", manifestDir).Infof("wrote manifests to mirror %d images", len(mapping))
+	return nil
+}
@@ -1,7 +1,11 @@
 package registry
 
 import (
+	"fmt"
+
+	"github.com/operator-framework/operator-registry/pkg/lib/progress"
 	"github.com/operator-framework/operator-registry/pkg/lib/registry"
+	pregistry "github.com/operator-framework/operator-registry/pkg/registry"
 
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
@@ -25,11 +29,11 @@ func newRegistryPruneCmd() *cobra.Command {
 
 	rootCmd.Flags().Bool("debug", false, "enable debug logging")
 	rootCmd.Flags().StringP("database", "d", "bundles.db", "relative path to database file")
-	rootCmd.Flags().StringSliceP("packages", "p", []string{}, "comma separated list of package names to be kept")
-	if err := rootCmd.MarkFlagRequired("packages"); err != nil {
-		logrus.Panic("Failed to set required `packages` flag for `registry rm`")
-	}
+	rootCmd.Flags().StringSliceP("packages", "p", []string{}, "comma separated list of package names to be kept, required unless --heads-only or --install-mode is set")
+	rootCmd.Flags().Bool("heads-only", false, "also remove every bundle that isn't the head of a channel, leaving no upgrade paths to the heads")
+	rootCmd.Flags().String("install-mode", "", "also remove every bundle that doesn't support this install mode, e.g. AllNamespaces, along with the bundles that could only be upgraded through one; channels whose head doesn't support it are removed, and packages whose default channel head doesn't are removed entirely")
 	rootCmd.Flags().Bool("permissive", false, "allow registry load errors")
+	rootCmd.Flags().String("progress", "none", "how to report progress while the command runs. One of: [none, bar, json]. Interrupting the command rolls back partial changes")
 
 	return rootCmd
 }
@@ -47,11 +51,39 @@ func runRegistryPruneCmdFunc(cmd *cobra.Command, args []string) error {
 	if err != nil {
 		return err
 	}
+	headsOnly, err := cmd.Flags().GetBool("heads-only")
+	if err != nil {
+		return err
+	}
+	installMode, err := cmd.Flags().GetString("install-mode")
+	if err != nil {
+		return err
+	}
+	if installMode != "" {
+		if err := pregistry.ValidateInstallMode(installMode); err != nil {
+			return err
+		}
+	}
+	if len(packages) == 0 && !headsOnly && installMode == "" {
+		return fmt.Errorf("--packages is required unless --heads-only or --install-mode is set")
+	}
+
+	progressFormat, err := cmd.Flags().GetString("progress")
+	if err != nil {
+		return err
+	}
+	ctx, cancel, err := progress.Context(progressFormat)
+	if err != nil {
+		return err
+	}
+	defer cancel()
 
 	request := registry.PruneFromRegistryRequest{
 		Packages:      packages,
 		InputDatabase: fromFilename,
 		Permissive:    permissive,
+		HeadsOnly:     headsOnly,
+		InstallMode:   installMode,
 	}
 
 	logger := logrus.WithFields(logrus.Fields{"packages": packages})
@@ -60,7 +92,7 @@ func runRegistryPruneCmdFunc(cmd *cobra.Command, args []string) error {
 
 	registryPruner := registry.NewRegistryPruner(logger)
 
-	err = registryPruner.PruneFromRegistry(request)
+	err = registryPruner.PruneFromRegistry(ctx, request)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,66 @@
+package registry
+
+import (
+	"github.com/operator-framework/operator-registry/pkg/lib/registry"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+func newRegistryPurgeCmd() *cobra.Command {
+	rootCmd := &cobra.Command{
+		Use:   "purge",
+		Short: "permanently drop removed packages from operator registry DB",
+		Long:  `Permanently drop the tombstones kept for removed packages, after which they can no longer be restored`,
+
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if debug, _ := cmd.Flags().GetBool("debug"); debug {
+				logrus.SetLevel(logrus.DebugLevel)
+			}
+			return nil
+		},
+
+		RunE: purgeFunc,
+	}
+
+	rootCmd.Flags().Bool("debug", false, "enable debug logging")
+	rootCmd.Flags().StringP("database", "d", "bundles.db", "relative path to database file")
+	rootCmd.Flags().StringSliceP("packages", "o", nil, "comma separated list of removed package names to purge, all removed packages are purged if unset")
+	rootCmd.Flags().Duration("older-than", 0, "only purge packages removed at least this long ago")
+
+	return rootCmd
+}
+
+func purgeFunc(cmd *cobra.Command, args []string) error {
+	fromFilename, err := cmd.Flags().GetString("database")
+	if err != nil {
+		return err
+	}
+	packages, err := cmd.Flags().GetStringSlice("packages")
+	if err != nil {
+		return err
+	}
+	olderThan, err := cmd.Flags().GetDuration("older-than")
+	if err != nil {
+		return err
+	}
+
+	request := registry.PurgeFromRegistryRequest{
+		Packages:      packages,
+		InputDatabase: fromFilename,
+		OlderThan:     olderThan,
+	}
+
+	logger := logrus.WithFields(logrus.Fields{"packages": packages})
+
+	logger.Info("purging removed packages from the registry")
+
+	registryPurger := registry.NewRegistryPurger(logger)
+
+	err = registryPurger.PurgeFromRegistry(request)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,73 @@
+package registry
+
+import (
+	"github.com/operator-framework/operator-registry/pkg/lib/registry"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+func newRegistryRenameCmd() *cobra.Command {
+	rootCmd := &cobra.Command{
+		Use:   "rename",
+		Short: "rename a package in operator registry DB",
+		Long: `Rename a package across an operator registry DB: its channels, the package properties of its bundles and the
+dependencies other bundles declare on it. The former name is kept as an alias so that it can still be resolved.`,
+
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if debug, _ := cmd.Flags().GetBool("debug"); debug {
+				logrus.SetLevel(logrus.DebugLevel)
+			}
+			return nil
+		},
+
+		RunE: renameFunc,
+	}
+
+	rootCmd.Flags().Bool("debug", false, "enable debug logging")
+	rootCmd.Flags().StringP("database", "d", "bundles.db", "relative path to database file")
+	rootCmd.Flags().String("from", "", "current name of the package")
+	rootCmd.Flags().String("to", "", "new name of the package")
+	if err := rootCmd.MarkFlagRequired("from"); err != nil {
+		logrus.Panic("Failed to set required `from` flag for `registry rename`")
+	}
+	if err := rootCmd.MarkFlagRequired("to"); err != nil {
+		logrus.Panic("Failed to set required `to` flag for `registry rename`")
+	}
+
+	return rootCmd
+}
+
+func renameFunc(cmd *cobra.Command, args []string) error {
+	fromFilename, err := cmd.Flags().GetString("database")
+	if err != nil {
+		return err
+	}
+	from, err := cmd.Flags().GetString("from")
+	if err != nil {
+		return err
+	}
+	to, err := cmd.Flags().GetString("to")
+	if err != nil {
+		return err
+	}
+
+	request := registry.RenamePackageInRegistryRequest{
+		InputDatabase: fromFilename,
+		From:          from,
+		To:            to,
+	}
+
+	logger := logrus.WithFields(logrus.Fields{"from": from, "to": to})
+
+	logger.Info("renaming package in the registry")
+
+	registryRenamer := registry.NewRegistryRenamer(logger)
+
+	err = registryRenamer.RenamePackageInRegistry(request)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,91 @@
+package registry
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/operator-framework/operator-registry/pkg/client"
+	"github.com/operator-framework/operator-registry/pkg/lib/querylog"
+)
+
+func newRegistryReplayCmd() *cobra.Command {
+	rootCmd := &cobra.Command{
+		Use:   "replay",
+		Short: "replay a query log against two registries and diff their answers",
+		Long: `Replay the queries recorded by a registry served with --query-log against a baseline and a candidate registry,
+and report every query they answer differently. This is meant to check that a change to the catalog or to the
+registry itself doesn't change what clients see.`,
+
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if debug, _ := cmd.Flags().GetBool("debug"); debug {
+				logrus.SetLevel(logrus.DebugLevel)
+			}
+			return nil
+		},
+
+		RunE: replayFunc,
+	}
+
+	rootCmd.Flags().Bool("debug", false, "enable debug logging")
+	rootCmd.Flags().StringP("query-log", "q", "", "path to the query log to replay")
+	rootCmd.Flags().String("baseline", "", "address of the registry whose answers are expected")
+	rootCmd.Flags().String("candidate", "", "address of the registry whose answers are checked")
+	for _, flag := range []string{"query-log", "baseline", "candidate"} {
+		if err := rootCmd.MarkFlagRequired(flag); err != nil {
+			logrus.Panicf("Failed to set required `%s` flag for `registry replay`", flag)
+		}
+	}
+
+	return rootCmd
+}
+
+func replayFunc(cmd *cobra.Command, args []string) error {
+	queryLog, err := cmd.Flags().GetString("query-log")
+	if err != nil {
+		return err
+	}
+	baselineAddress, err := cmd.Flags().GetString("baseline")
+	if err != nil {
+		return err
+	}
+	candidateAddress, err := cmd.Flags().GetString("candidate")
+	if err != nil {
+		return err
+	}
+
+	logger := logrus.WithFields(logrus.Fields{"baseline": baselineAddress, "candidate": candidateAddress})
+
+	entries, err := querylog.ReadLog(queryLog)
+	if err != nil {
+		return err
+	}
+
+	baseline, err := client.NewClient(baselineAddress)
+	if err != nil {
+		return err
+	}
+	defer baseline.Close()
+	candidate, err := client.NewClient(candidateAddress)
+	if err != nil {
+		return err
+	}
+	defer candidate.Close()
+
+	logger.Infof("replaying %d queries", len(entries))
+	differences, err := querylog.Replay(context.TODO(), entries, baseline.Registry, candidate.Registry)
+	if err != nil {
+		return err
+	}
+
+	for _, d := range differences {
+		fmt.Fprintf(cmd.OutOrStdout(), "%s %s\n--- baseline\n%s\n+++ candidate\n%s\n\n", d.Entry.Method, d.Entry.Request, d.Baseline, d.Candidate)
+	}
+	if len(differences) > 0 {
+		return fmt.Errorf("%d of %d queries were answered differently", len(differences), len(entries))
+	}
+	logger.Info("all queries were answered the same")
+	return nil
+}
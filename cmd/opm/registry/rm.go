@@ -1,6 +1,8 @@
 package registry
 
 import (
+	"time"
+
 	"github.com/operator-framework/operator-registry/pkg/lib/registry"
 
 	"github.com/sirupsen/logrus"
@@ -11,7 +13,10 @@ func newRegistryRmCmd() *cobra.Command {
 	rootCmd := &cobra.Command{
 		Use:   "rm",
 		Short: "remove operator from operator registry DB",
-		Long:  `Remove operator from operator registry DB`,
+		Long: `Remove operator from operator registry DB.
+
+Removed packages are kept as tombstones for the undo window, and can be restored with --undo until then.
+Use 'registry purge' to drop tombstones before the window expires.`,
 
 		PreRunE: func(cmd *cobra.Command, args []string) error {
 			if debug, _ := cmd.Flags().GetBool("debug"); debug {
@@ -30,6 +35,8 @@ func newRegistryRmCmd() *cobra.Command {
 		logrus.Panic("Failed to set required `packages` flag for `registry rm`")
 	}
 	rootCmd.Flags().Bool("permissive", false, "allow registry load errors")
+	rootCmd.Flags().Bool("undo", false, "restore the given packages from a previous removal instead of removing them")
+	rootCmd.Flags().Duration("undo-window", 72*time.Hour, "how long removed packages can be restored for, 0 keeps them forever")
 
 	return rootCmd
 }
@@ -48,14 +55,36 @@ func rmFunc(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
+	undo, err := cmd.Flags().GetBool("undo")
+	if err != nil {
+		return err
+	}
+	undoWindow, err := cmd.Flags().GetDuration("undo-window")
+	if err != nil {
+		return err
+	}
+
+	logger := logrus.WithFields(logrus.Fields{"packages": packages})
+
+	if undo {
+		logger.Info("restoring to the registry")
+
+		registryRestorer := registry.NewRegistryRestorer(logger)
+		return registryRestorer.RestoreToRegistry(registry.RestoreToRegistryRequest{
+			Packages:      packages,
+			InputDatabase: fromFilename,
+			UndoWindow:    undoWindow,
+		})
+	}
+
 	request := registry.DeleteFromRegistryRequest{
 		Packages:      packages,
 		InputDatabase: fromFilename,
 		Permissive:    permissive,
+		Soft:          true,
+		UndoWindow:    undoWindow,
 	}
 
-	logger := logrus.WithFields(logrus.Fields{"packages": packages})
-
 	logger.Info("removing from the registry")
 
 	registryDeleter := registry.NewRegistryDeleter(logger)
@@ -2,24 +2,39 @@ package registry
 
 import (
 	"context"
+	"crypto/tls"
 	"database/sql"
 	"fmt"
 	"net"
+	"net/http"
 	"os"
+	"os/signal"
+	"path/filepath"
 	"strconv"
+	"strings"
+	"sync"
+	"syscall"
 	"time"
 
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
+	"golang.org/x/net/http2"
+	"golang.org/x/sync/errgroup"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/reflection"
 
 	"github.com/operator-framework/operator-registry/pkg/api"
 	health "github.com/operator-framework/operator-registry/pkg/api/grpc_health_v1"
+	"github.com/operator-framework/operator-registry/pkg/catalogapi"
+	"github.com/operator-framework/operator-registry/pkg/lib/contentsource"
 	"github.com/operator-framework/operator-registry/pkg/lib/dns"
+	"github.com/operator-framework/operator-registry/pkg/lib/filewatch"
 	"github.com/operator-framework/operator-registry/pkg/lib/graceful"
 	"github.com/operator-framework/operator-registry/pkg/lib/log"
+	"github.com/operator-framework/operator-registry/pkg/lib/remotedb"
+	"github.com/operator-framework/operator-registry/pkg/lib/serve"
 	"github.com/operator-framework/operator-registry/pkg/lib/tmp"
+	"github.com/operator-framework/operator-registry/pkg/registry"
 	"github.com/operator-framework/operator-registry/pkg/server"
 	"github.com/operator-framework/operator-registry/pkg/sqlite"
 )
@@ -41,11 +56,25 @@ func newRegistryServeCmd() *cobra.Command {
 	}
 
 	rootCmd.Flags().Bool("debug", false, "enable debug logging")
-	rootCmd.Flags().StringP("database", "d", "bundles.db", "relative path to sqlite db")
+	rootCmd.Flags().StringP("database", "d", "bundles.db", "relative path to sqlite db, an https:// url or oci:// index image reference to download it from at startup, or an s3:// or gs:// url of a bucket object to fetch it from")
+	rootCmd.Flags().StringP("manifests", "m", "", "path to a directory of operator manifests, laid out as the initializer reads them, to build the served catalog from instead of --database; it is rebuilt from the directory on every SIGHUP, and on every change to it with --watch")
+	rootCmd.Flags().String("database-sha256", "", "sha256 that a database downloaded from a url or index image must match, required with a remote --database")
 	rootCmd.Flags().StringP("port", "p", "50051", "port number to serve on")
+	rootCmd.Flags().StringArray("listen", nil, "address to serve on instead of --port, either host:port or the path of a unix domain socket, e.g. unix:///var/run/registry.sock; repeat to serve on several, e.g. 0.0.0.0:50051 and [::]:50051")
 	rootCmd.Flags().StringP("termination-log", "t", "/dev/termination-log", "path to a container termination log file")
-	rootCmd.Flags().Bool("skip-migrate", false, "do  not attempt to migrate to the latest db revision when starting")
+	rootCmd.Flags().StringSlice("shard", nil, "path to a shard db written by opm registry shard; repeat in shard order to serve a sharded catalog instead of --database")
+	rootCmd.Flags().Bool("refresh-on-sighup", false, "rebuild the served database from --database on SIGHUP, switching to it once it is valid without interrupting queries")
+	rootCmd.Flags().Bool("watch", false, "watch a local --database, or the --manifests directory, for changes and serve each new version of it as --refresh-on-sighup does, once its content has changed")
+	rootCmd.Flags().Duration("poll-interval", 0, "how often to check an s3:// or gs:// --database for a new version and refresh the served database with it; 0 only checks on SIGHUP")
 	rootCmd.Flags().String("timeout-seconds", "infinite", "Timeout in seconds. This flag will be removed later.")
+	rootCmd.Flags().String("api-listen", "", "address to also serve the catalog on as a read-only Kubernetes aggregated api, e.g. :8443, for registering with an APIService")
+	rootCmd.Flags().String("api-tls-cert", "", "path to the serving certificate of the aggregated api, required with --api-listen")
+	rootCmd.Flags().String("api-tls-key", "", "path to the key of the serving certificate of the aggregated api, required with --api-listen")
+	rootCmd.Flags().String("http-listen", "", "address to also serve the registry api on as json over http, e.g. :8080, for clients that can't speak grpc; served over tls with --tls-cert and --tls-key when they are set")
+	serve.AddFlags(rootCmd.Flags())
+	rootCmd.Flags().String("client-ca", "", "path to the CA that the client certificates of grpc clients are verified against; the common name of a verified certificate identifies its client to --visibility")
+	rootCmd.Flags().String("visibility", "", "path to a visibility config (yaml or json) that limits the packages each client sees, by the common name of its client certificate or a claim of its bearer token")
+	rootCmd.Flags().String("api-client-ca", "", "path to the CA the client certificates of aggregated api requests must be signed by, usually the requestheader client CA of the cluster")
 
 	return rootCmd
 }
@@ -66,6 +95,10 @@ func serveFunc(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
+	// fetching, loading and refreshing the database stop once the server is shut down
+	ctx, cancel := graceful.Context(cmd.Context())
+	defer cancel()
+
 	dbName, err := cmd.Flags().GetString("database")
 	if err != nil {
 		return err
@@ -75,38 +108,231 @@ func serveFunc(cmd *cobra.Command, args []string) error {
 	if err != nil {
 		return err
 	}
+	listen, err := cmd.Flags().GetStringArray("listen")
+	if err != nil {
+		return err
+	}
 
 	logger := logrus.WithFields(logrus.Fields{"database": dbName, "port": port})
 
-	// make a writable copy of the db for migrations
-	tmpdb, err := tmp.CopyTmpDB(dbName)
+	// a sharded catalog is served from all of its shards instead of a single database
+	shards, err := cmd.Flags().GetStringSlice("shard")
 	if err != nil {
 		return err
 	}
-	defer os.Remove(tmpdb)
+	databases := []string{dbName}
+	if len(shards) > 0 {
+		databases = shards
+		logger = logrus.WithFields(logrus.Fields{"shards": strings.Join(shards, ","), "port": port})
+	}
 
-	db, err := sql.Open("sqlite3", tmpdb)
+	// a catalog being edited is built from its manifests rather than served from a database
+	manifests, err := cmd.Flags().GetString("manifests")
 	if err != nil {
 		return err
 	}
+	if manifests != "" {
+		if cmd.Flags().Changed("database") || len(shards) > 0 {
+			return fmt.Errorf("--manifests can't be used with --database or --shard")
+		}
+		if info, err := os.Stat(manifests); err != nil {
+			return err
+		} else if !info.IsDir() {
+			return fmt.Errorf("--manifests %s is not a directory", manifests)
+		}
+		logger = logrus.WithFields(logrus.Fields{"manifests": manifests, "port": port})
+	}
 
-	// migrate to the latest version
-	if err := migrate(cmd, db); err != nil {
-		logger.WithError(err).Warnf("couldn't migrate db")
+	refresh, err := cmd.Flags().GetBool("refresh-on-sighup")
+	if err != nil {
+		return err
+	}
+	if refresh && len(shards) > 0 {
+		return fmt.Errorf("--refresh-on-sighup can't be used with --shard")
 	}
+	watch, err := cmd.Flags().GetBool("watch")
+	if err != nil {
+		return err
+	}
+	if watch && (len(shards) > 0 || contentsource.IsObjectStorage(dbName) || remotedb.IsRemote(dbName)) {
+		return fmt.Errorf("--watch only applies to a local --database")
+	}
+	refresh = refresh || watch || manifests != ""
 
-	store := sqlite.NewSQLLiteQuerierFromDb(db)
+	checksum, err := cmd.Flags().GetString("database-sha256")
+	if err != nil {
+		return err
+	}
+	pollInterval, err := cmd.Flags().GetDuration("poll-interval")
+	if err != nil {
+		return err
+	}
+	if pollInterval != 0 && !contentsource.IsObjectStorage(dbName) {
+		return fmt.Errorf("--poll-interval only applies to a database in an s3:// or gs:// bucket")
+	}
 
-	// sanity check that the db is available
-	tables, err := store.ListTables(context.TODO())
+	// a database in object storage is fetched into a workspace, and each new version of it is served in turn
+	var source contentsource.Source
+	var sourceVersion string
+	if contentsource.IsObjectStorage(dbName) && len(shards) == 0 {
+		if checksum != "" {
+			return fmt.Errorf("--database-sha256 can't be used with a database in object storage, which changes with every version published to it")
+		}
+		if source, err = contentsource.New(dbName); err != nil {
+			return err
+		}
+		workspace, err := tmp.NewWorkspace("serve")
+		if err != nil {
+			return err
+		}
+		defer workspace.Cleanup()
+		fetched := filepath.Join(workspace.Path(), "index.db")
+		if sourceVersion, err = source.Fetch(ctx, fetched, ""); err != nil {
+			return fmt.Errorf("error fetching database %s: %s", dbName, err)
+		}
+		logger.WithField("version", sourceVersion).Info("fetched database")
+		dbName = fetched
+		refresh = true
+	} else if remotedb.IsRemote(dbName) && len(shards) == 0 {
+		if refresh {
+			return fmt.Errorf("--refresh-on-sighup can't be used with a remote --database, which is only downloaded at startup")
+		}
+		workspace, err := tmp.NewWorkspace("serve")
+		if err != nil {
+			return err
+		}
+		defer workspace.Cleanup()
+		if dbName, err = remotedb.Download(ctx, logger, dbName, checksum, workspace.Path()); err != nil {
+			return err
+		}
+	} else if checksum != "" {
+		return fmt.Errorf("--database-sha256 only applies to a database downloaded from a url or index image")
+	}
+
+	dbOpts, err := serve.DatabaseOptionsFromFlags(cmd.Flags())
+	if err != nil {
+		return err
+	}
+	cacheOpts, err := serve.QueryCacheOptions(cmd.Flags())
 	if err != nil {
-		logger.WithError(err).Warnf("couldn't list tables in db")
+		return err
 	}
-	if len(tables) == 0 {
-		logger.Warn("no tables found in db")
+
+	healthServer := server.NewHealthServer()
+	var store registry.Query
+	var serverOpts []server.RegistryServerOption
+	if refresh {
+		// the database is rebuilt into a new file, or in memory, on every refresh, and the one being served is swapped for it
+		var features *sqlite.FeatureSet
+		var swapper *sqlite.DatabaseSwapper
+		if dbOpts.Key != nil && manifests == "" {
+			// a database that may be encrypted is only ever decrypted into memory
+			swapper, err = sqlite.NewMemoryDatabaseSwapper(ctx, func(ctx context.Context) (*sqlite.MemoryDatabase, error) {
+				var m *sqlite.MemoryDatabase
+				var err error
+				m, features, err = dbOpts.Load(ctx, logger, dbName)
+				return m, err
+			}, nil)
+		} else {
+			swapper, err = sqlite.NewDatabaseSwapper(ctx, func(ctx context.Context, path string) error {
+				var err error
+				if manifests != "" {
+					features, err = buildDatabaseFromManifests(ctx, dbOpts, logger, manifests, path)
+					return err
+				}
+				features, err = dbOpts.Build(ctx, logger, dbName, path)
+				return err
+			}, nil)
+		}
+		if err != nil {
+			return err
+		}
+		defer swapper.Close()
+		var invalidate func()
+		if store, invalidate, err = serve.CacheQuery(swapper.Query(), cacheOpts); err != nil {
+			return err
+		}
+		if features != nil {
+			healthServer.SetFeatures(features.Available, features.Defaulted)
+		}
+		// each database served may support different features of the schema, which are reported once it is served,
+		// and results cached from the previous one are dropped. Refreshes on a signal and on a change may overlap, so
+		// they are made one at a time.
+		var refreshing sync.Mutex
+		refreshDatabase := func(ctx context.Context) error {
+			refreshing.Lock()
+			defer refreshing.Unlock()
+			if err := swapper.Refresh(ctx); err != nil {
+				return err
+			}
+			invalidate()
+			if features != nil {
+				healthServer.SetFeatures(features.Available, features.Defaulted)
+			}
+			return nil
+		}
+		refreshFunc := func(ctx context.Context) (bool, error) {
+			return true, refreshDatabase(ctx)
+		}
+		if source != nil {
+			refreshFunc = func(ctx context.Context) (bool, error) {
+				version, err := source.Fetch(ctx, dbName, sourceVersion)
+				if err == contentsource.ErrNotModified {
+					return false, nil
+				}
+				if err != nil {
+					return false, err
+				}
+				// a version that can't be served isn't fetched again until another one replaces it
+				sourceVersion = version
+				logger.WithField("version", version).Info("fetched new database")
+				return true, refreshDatabase(ctx)
+			}
+		}
+		// clients can watch a catalog that is refreshed for the changes each refresh brings
+		events, err := server.NewCatalogEvents(ctx, swapper.Query())
+		if err != nil {
+			return err
+		}
+		serverOpts = append(serverOpts, server.WithCatalogEvents(events))
+		refreshFunc = notifyRefresh(logger, events, refreshFunc)
+
+		defer refreshOnSignal(ctx, logger, pollInterval, refreshFunc)()
+		if watch {
+			stop, err := refreshOnChange(ctx, logger, dbName, manifests, refreshFunc)
+			if err != nil {
+				return err
+			}
+			defer stop()
+		}
+	} else {
+		var queriers []registry.Query
+		for i, database := range databases {
+			store, features, cleanup, err := dbOpts.Open(ctx, logger.WithField("database", database), database, i, len(databases))
+			if err != nil {
+				return err
+			}
+			defer cleanup()
+			queriers = append(queriers, store)
+			// every shard has the same schema, so the first one speaks for all of them
+			if i == 0 && features != nil {
+				healthServer = server.NewHealthServerWithFeatures(features.Available, features.Defaulted)
+			}
+		}
+
+		store = queriers[0]
+		if len(shards) > 0 {
+			store = registry.NewShardedQuery(queriers)
+		}
+		if store, _, err = serve.CacheQuery(store, cacheOpts); err != nil {
+			return err
+		}
 	}
 
-	lis, err := net.Listen("tcp", ":"+port)
+	if len(listen) > 0 {
+		logger = logger.WithField("listen", strings.Join(listen, ","))
+	}
+	lis, err := server.ListenAll(server.ListenAddresses(listen, port)...)
 	if err != nil {
 		logger.Fatalf("failed to listen: %s", err)
 	}
@@ -116,7 +342,67 @@ func serveFunc(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
-	s := grpc.NewServer()
+	opts, err := serve.RequestLogOptions(cmd.Flags(), logger)
+	if err != nil {
+		return err
+	}
+
+	queryLogOpts, closeQueryLog, err := serve.QueryLogOptions(cmd.Flags())
+	if err != nil {
+		return err
+	}
+	defer closeQueryLog()
+	opts = append(opts, queryLogOpts...)
+
+	// the gateway calls the registry through a grpc server of its own, which shares the request and query logs and
+	// the rate limits of each client but not the options of grpc connections
+	gatewayOpts := append([]grpc.ServerOption(nil), opts...)
+
+	keepaliveOpts, err := serve.KeepaliveOptions(cmd.Flags())
+	if err != nil {
+		return err
+	}
+	opts = append(opts, keepaliveOpts...)
+
+	rateLimits, err := serve.RateLimitOptions(cmd.Flags())
+	if err != nil {
+		return err
+	}
+	rateLimitOpts, gatewayRateLimitOpts := rateLimits.ServerOptionsWithGateway()
+	opts = append(opts, rateLimitOpts...)
+	gatewayOpts = append(gatewayOpts, gatewayRateLimitOpts...)
+
+	// clients may connect without a certificate, and identify themselves to --visibility with a bearer token instead
+	tlsOpts, err := serve.TLSOptions(cmd.Flags(), logger, "client-ca", tls.VerifyClientCertIfGiven)
+	if err != nil {
+		return err
+	}
+	opts = append(opts, tlsOpts...)
+
+	visibilityOpts, err := visibilityOptions(cmd)
+	if err != nil {
+		return err
+	}
+	serverOpts = append(serverOpts, visibilityOpts...)
+
+	redactionOpts, err := serve.RedactionOptions(cmd.Flags())
+	if err != nil {
+		return err
+	}
+	serverOpts = append(serverOpts, redactionOpts...)
+
+	apiServer, err := catalogAPIServer(cmd, store, logger)
+	if err != nil {
+		return err
+	}
+
+	registryServer := server.NewRegistryServer(store, serverOpts...)
+	gatewayServer, err := gatewayServer(cmd, registryServer, gatewayOpts, rateLimits.MaxConcurrentStreams, logger)
+	if err != nil {
+		return err
+	}
+
+	s := grpc.NewServer(opts...)
 	logger.Printf("Keeping server open for %s seconds", timeout)
 	if timeout != "infinite" {
 		timeoutSeconds, err := strconv.ParseUint(timeout, 10, 16)
@@ -132,33 +418,280 @@ func serveFunc(cmd *cobra.Command, args []string) error {
 		defer timer.Stop()
 	}
 
-	api.RegisterRegistryServer(s, server.NewRegistryServer(store))
-	health.RegisterHealthServer(s, server.NewHealthServer())
+	api.RegisterRegistryServer(s, registryServer)
+	health.RegisterHealthServer(s, healthServer)
 	reflection.Register(s)
 	logger.Info("serving registry")
-	return graceful.Shutdown(logger, func() error {
-		return s.Serve(lis)
+	return graceful.ShutdownContext(ctx, logger, func() error {
+		var g errgroup.Group
+		g.Go(func() error {
+			return server.Serve(s, lis)
+		})
+		if apiServer != nil {
+			g.Go(apiServer.serve)
+		}
+		if gatewayServer != nil {
+			g.Go(gatewayServer.serve)
+		}
+		return g.Wait()
 	}, func() {
 		s.GracefulStop()
+		cancel()
+		if apiServer != nil {
+			apiServer.stop()
+		}
+		if gatewayServer != nil {
+			gatewayServer.stop()
+		}
 	})
 }
 
-func migrate(cmd *cobra.Command, db *sql.DB) error {
-	shouldSkipMigrate, err := cmd.Flags().GetBool("skip-migrate")
-	if err != nil {
+// httpAPI serves an http api alongside the grpc api, over tls if the server has a tls config
+type httpAPI struct {
+	server   *http.Server
+	listener net.Listener
+	// close is called once the server has shut down, if set
+	close func() error
+}
+
+func (a *httpAPI) serve() error {
+	var err error
+	if a.server.TLSConfig != nil {
+		err = a.server.ServeTLS(a.listener, "", "")
+	} else {
+		err = a.server.Serve(a.listener)
+	}
+	if err != http.ErrServerClosed {
 		return err
 	}
-	if shouldSkipMigrate {
-		return nil
+	return nil
+}
+
+func (a *httpAPI) stop() {
+	a.server.Shutdown(context.Background())
+	if a.close != nil {
+		a.close()
 	}
+}
 
-	migrator, err := sqlite.NewSQLLiteMigrator(db)
+// catalogAPIServer returns the aggregated api server asked for with --api-listen, listening but not yet serving, or
+// nil if none was asked for
+func catalogAPIServer(cmd *cobra.Command, store registry.Query, logger *logrus.Entry) (*httpAPI, error) {
+	listen, err := cmd.Flags().GetString("api-listen")
+	if err != nil || listen == "" {
+		return nil, err
+	}
+	certFile, err := cmd.Flags().GetString("api-tls-cert")
 	if err != nil {
-		return err
+		return nil, err
+	}
+	keyFile, err := cmd.Flags().GetString("api-tls-key")
+	if err != nil {
+		return nil, err
+	}
+	clientCAFile, err := cmd.Flags().GetString("api-client-ca")
+	if err != nil {
+		return nil, err
+	}
+	if visibility, _ := cmd.Flags().GetString("visibility"); visibility != "" {
+		return nil, fmt.Errorf("--visibility doesn't apply to the aggregated api, which would serve every package to every client")
+	}
+	if certFile == "" || keyFile == "" {
+		return nil, fmt.Errorf("--api-listen needs a serving certificate, set with --api-tls-cert and --api-tls-key")
+	}
+
+	tlsConfig, err := catalogapi.TLSConfig(certFile, keyFile, clientCAFile)
+	if err != nil {
+		return nil, err
+	}
+	lis, err := server.Listen(listen)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %s", listen, err)
+	}
+	logger.WithField("api-listen", listen).Info("serving aggregated api")
+	return &httpAPI{
+		server: &http.Server{
+			Handler:   catalogapi.NewHandler(store, logger),
+			TLSConfig: tlsConfig,
+		},
+		listener: lis,
+	}, nil
+}
+
+// gatewayServer returns the json gateway to the registry api asked for with --http-listen, listening but not yet
+// serving, or nil if none was asked for. It is served with the serving certificate and client CA of the grpc api, and
+// the streams of its http/2 connections are limited as those of grpc connections are.
+func gatewayServer(cmd *cobra.Command, srv api.RegistryServer, opts []grpc.ServerOption, maxConcurrentStreams uint32, logger *logrus.Entry) (*httpAPI, error) {
+	listen, err := cmd.Flags().GetString("http-listen")
+	if err != nil || listen == "" {
+		return nil, err
+	}
+	certFile, err := cmd.Flags().GetString("tls-cert")
+	if err != nil {
+		return nil, err
+	}
+	keyFile, err := cmd.Flags().GetString("tls-key")
+	if err != nil {
+		return nil, err
 	}
-	if migrator == nil {
-		return fmt.Errorf("failed to load migrator")
+	clientCAFile, err := cmd.Flags().GetString("client-ca")
+	if err != nil {
+		return nil, err
+	}
+	var tlsConfig *tls.Config
+	if certFile != "" && keyFile != "" {
+		reloader, err := server.NewTLSReloader(logger, certFile, keyFile, clientCAFile, tls.VerifyClientCertIfGiven)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig = reloader.Config("h2", "http/1.1")
+	}
+
+	gateway, err := server.NewGateway(srv, logger, opts...)
+	if err != nil {
+		return nil, err
+	}
+	lis, err := server.Listen(listen)
+	if err != nil {
+		gateway.Close()
+		return nil, fmt.Errorf("failed to listen on %s: %s", listen, err)
+	}
+	httpServer := &http.Server{
+		Handler:   gateway,
+		TLSConfig: tlsConfig,
+	}
+	// http/1.1 connections make one request at a time, but http/2 ones are limited like grpc connections
+	if tlsConfig != nil && maxConcurrentStreams != 0 {
+		if err := http2.ConfigureServer(httpServer, &http2.Server{MaxConcurrentStreams: maxConcurrentStreams}); err != nil {
+			lis.Close()
+			gateway.Close()
+			return nil, err
+		}
 	}
+	logger.WithField("http-listen", listen).Info("serving json gateway")
+	return &httpAPI{
+		server:   httpServer,
+		listener: lis,
+		close:    gateway.Close,
+	}, nil
+}
+
+// visibilityOptions returns the registry server options that limit the packages each client sees, if a visibility
+// config was given
+func visibilityOptions(cmd *cobra.Command) ([]server.RegistryServerOption, error) {
+	path, err := cmd.Flags().GetString("visibility")
+	if err != nil || path == "" {
+		return nil, err
+	}
+	visibility, err := server.LoadVisibility(path)
+	if err != nil {
+		return nil, err
+	}
+	return []server.RegistryServerOption{server.WithVisibility(visibility)}, nil
+}
 
-	return migrator.Migrate(context.TODO())
+// buildDatabaseFromManifests writes a database built from a directory of manifests, prepared to be served, to path.
+// Manifests that can't be loaded fail the build, so that a catalog being edited is only served once it is valid
+// again.
+func buildDatabaseFromManifests(ctx context.Context, dbOpts serve.DatabaseOptions, logger *logrus.Entry, manifests, path string) (*sqlite.FeatureSet, error) {
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+	load, err := sqlite.NewSQLLiteLoader(db)
+	if err != nil {
+		return nil, err
+	}
+	if err := load.Migrate(ctx); err != nil {
+		return nil, err
+	}
+	if err := sqlite.NewSQLLoaderForDirectory(load, manifests).Populate(); err != nil {
+		return nil, fmt.Errorf("error loading manifests from %s: %s", manifests, err)
+	}
+	return dbOpts.Prepare(ctx, logger, db, 0, 1)
+}
+
+// refreshOnSignal refreshes the served database each time the process gets a SIGHUP, and every interval unless it is
+// zero, until the returned func is called. refresh returns false if there was nothing new to serve, and is passed ctx.
+func refreshOnSignal(ctx context.Context, logger *logrus.Entry, interval time.Duration, refresh func(ctx context.Context) (bool, error)) func() {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	var tick <-chan time.Time
+	var ticker *time.Ticker
+	if interval > 0 {
+		ticker = time.NewTicker(interval)
+		tick = ticker.C
+	}
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-hup:
+				logger.Info("refreshing database")
+			case <-tick:
+				logger.Debug("checking for a new database")
+			case <-done:
+				return
+			}
+			refreshed, err := refresh(ctx)
+			if err != nil {
+				logger.WithError(err).Warn("couldn't refresh database, still serving the previous one")
+				continue
+			}
+			if refreshed {
+				logger.Info("serving refreshed database")
+			}
+		}
+	}()
+	return func() {
+		signal.Stop(hup)
+		if ticker != nil {
+			ticker.Stop()
+		}
+		close(done)
+	}
+}
+
+// refreshOnChange refreshes the served database each time the content of the database at dbName, or of the
+// directory of manifests it is built from if one is given, changes, until the returned func is called. refresh is
+// passed ctx.
+func refreshOnChange(ctx context.Context, logger *logrus.Entry, dbName, manifests string, refresh func(ctx context.Context) (bool, error)) (func() error, error) {
+	changed := "database changed, refreshing it"
+	if manifests != "" {
+		changed = "manifests changed, rebuilding the database from them"
+	}
+	onChange := func() {
+		logger.Info(changed)
+		refreshed, err := refresh(ctx)
+		if err != nil {
+			logger.WithError(err).Warn("couldn't refresh database, still serving the previous one")
+			return
+		}
+		if refreshed {
+			logger.Info("serving refreshed database")
+		}
+	}
+	if manifests != "" {
+		return filewatch.WatchDir(manifests, time.Second, onChange)
+	}
+	// a database in wal mode may only be changed in its wal file until it is checkpointed
+	return filewatch.Watch([]string{dbName, dbName + "-wal"}, time.Second, onChange)
+}
+
+// notifyRefresh tells the clients watching the catalog what changed each time refresh serves a new database
+func notifyRefresh(logger *logrus.Entry, events *server.CatalogEvents, refresh func(ctx context.Context) (bool, error)) func(ctx context.Context) (bool, error) {
+	return func(ctx context.Context) (bool, error) {
+		refreshed, err := refresh(ctx)
+		if err != nil || !refreshed {
+			return refreshed, err
+		}
+		changed, err := events.Check(ctx)
+		if err != nil {
+			logger.WithError(err).Warn("couldn't check the refreshed database for changes to notify watchers of")
+		} else if changed {
+			logger.WithField("hash", events.Hash()).Info("notified watchers of catalog changes")
+		}
+		return refreshed, nil
+	}
 }
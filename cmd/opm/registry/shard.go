@@ -0,0 +1,67 @@
+package registry
+
+import (
+	"github.com/operator-framework/operator-registry/pkg/lib/registry"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+func newRegistryShardCmd() *cobra.Command {
+	rootCmd := &cobra.Command{
+		Use:   "shard",
+		Short: "split an operator registry DB into shards",
+		Long: `Split an operator registry DB into shard DBs by package name hash, so that a very large catalog can be stored
+and served in parts. The shards can be served together with opm registry serve --shard.`,
+
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if debug, _ := cmd.Flags().GetBool("debug"); debug {
+				logrus.SetLevel(logrus.DebugLevel)
+			}
+			return nil
+		},
+
+		RunE: shardFunc,
+	}
+
+	rootCmd.Flags().Bool("debug", false, "enable debug logging")
+	rootCmd.Flags().StringP("database", "d", "bundles.db", "relative path to database file")
+	rootCmd.Flags().IntP("shards", "n", 2, "number of shards to split the database into")
+	rootCmd.Flags().StringP("output-dir", "o", "shards", "directory to write the shard databases to")
+
+	return rootCmd
+}
+
+func shardFunc(cmd *cobra.Command, args []string) error {
+	fromFilename, err := cmd.Flags().GetString("database")
+	if err != nil {
+		return err
+	}
+	shards, err := cmd.Flags().GetInt("shards")
+	if err != nil {
+		return err
+	}
+	outputDir, err := cmd.Flags().GetString("output-dir")
+	if err != nil {
+		return err
+	}
+
+	request := registry.ShardRegistryRequest{
+		InputDatabase: fromFilename,
+		Shards:        shards,
+		OutputDir:     outputDir,
+	}
+
+	logger := logrus.WithFields(logrus.Fields{"shards": shards, "output-dir": outputDir})
+
+	logger.Info("splitting the registry into shards")
+
+	registrySharder := registry.NewRegistrySharder(logger)
+
+	_, err = registrySharder.ShardRegistry(request)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/operator-framework/operator-registry/cmd/opm/version"
+	"github.com/operator-framework/operator-registry/pkg/lib/telemetry"
+)
+
+// recordTelemetry sends an event about the command that was run, if telemetry was opted in to. Failures to send it
+// are only logged at debug level.
+func recordTelemetry(recorder *telemetry.Recorder, cmd *cobra.Command, err error, started time.Time) {
+	if !recorder.Enabled() || cmd == nil {
+		return
+	}
+
+	v := version.Get()
+	event := telemetry.Event{
+		Command:         cmd.CommandPath(),
+		Success:         err == nil,
+		DurationSeconds: time.Since(started).Seconds(),
+		OpmVersion:      v.OpmVersion,
+		GoOs:            v.GoOs,
+		GoArch:          v.GoArch,
+	}
+	event.Flags = setFlags(cmd, os.Args[1:])
+
+	// commands that work on a catalog database name it with --database
+	if f := cmd.Flags().Lookup("database"); f != nil {
+		if size, err := telemetry.CatalogSizeOf(context.Background(), f.Value.String()); err == nil {
+			event.Catalog = size
+		}
+	}
+
+	if err := recorder.Record(context.Background(), event); err != nil {
+		logrus.WithError(err).Debug("error sending telemetry")
+	}
+}
+
+// setFlags returns the sorted names of the flags of cmd that were set in args
+func setFlags(cmd *cobra.Command, args []string) []string {
+	seen := map[string]struct{}{}
+	var names []string
+	for _, arg := range args {
+		if arg == "--" {
+			break
+		}
+		if !strings.HasPrefix(arg, "-") || arg == "-" {
+			continue
+		}
+		name := strings.SplitN(strings.TrimLeft(arg, "-"), "=", 2)[0]
+		f := cmd.Flags().Lookup(name)
+		if !strings.HasPrefix(arg, "--") && len(name) > 0 {
+			f = cmd.Flags().ShorthandLookup(name[:1])
+		}
+		if f == nil || !f.Changed {
+			continue
+		}
+		if _, ok := seen[f.Name]; !ok {
+			seen[f.Name] = struct{}{}
+			names = append(names, f.Name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
@@ -35,6 +35,11 @@ func getVersion() Version {
 	}
 }
 
+// Get returns the version of the running opm binary
+func Get() Version {
+	return getVersion()
+}
+
 func (v Version) Print() {
 	fmt.Printf("Version: %#v\n", v)
 }
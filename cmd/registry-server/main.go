@@ -2,10 +2,13 @@ package main
 
 import (
 	"context"
-	"database/sql"
+	"crypto/tls"
 	"fmt"
-	"net"
-	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
@@ -15,9 +18,11 @@ import (
 	"github.com/operator-framework/operator-registry/pkg/api"
 	health "github.com/operator-framework/operator-registry/pkg/api/grpc_health_v1"
 	"github.com/operator-framework/operator-registry/pkg/lib/dns"
+	"github.com/operator-framework/operator-registry/pkg/lib/filewatch"
 	"github.com/operator-framework/operator-registry/pkg/lib/graceful"
 	"github.com/operator-framework/operator-registry/pkg/lib/log"
-	"github.com/operator-framework/operator-registry/pkg/lib/tmp"
+	"github.com/operator-framework/operator-registry/pkg/lib/serve"
+	"github.com/operator-framework/operator-registry/pkg/registry"
 	"github.com/operator-framework/operator-registry/pkg/server"
 	"github.com/operator-framework/operator-registry/pkg/sqlite"
 )
@@ -38,10 +43,14 @@ var rootCmd = &cobra.Command{
 
 func init() {
 	rootCmd.Flags().Bool("debug", false, "enable debug logging")
-	rootCmd.Flags().StringP("database", "d", "bundles.db", "relative path to sqlite db")
+	rootCmd.Flags().StringArrayP("database", "d", []string{"bundles.db"}, "relative path to sqlite db; repeat to serve several catalogs from one server, each under the service of its catalog name, given as <name>=<path> or taken from the file name, and the first also as the default registry service")
+	rootCmd.Flags().String("database-dir", "", "directory of sqlite dbs (*.db) to serve instead of --database, each as the catalog named after its file, and the first in name order also as the default registry service")
 	rootCmd.Flags().StringP("port", "p", "50051", "port number to serve on")
+	rootCmd.Flags().StringArray("listen", nil, "address to serve on instead of --port, either host:port or the path of a unix domain socket, e.g. unix:///var/run/registry.sock; repeat to serve on several, e.g. 0.0.0.0:50051 and [::]:50051")
 	rootCmd.Flags().StringP("termination-log", "t", "/dev/termination-log", "path to a container termination log file")
-	rootCmd.Flags().Bool("skip-migrate", false, "do  not attempt to migrate to the latest db revision when starting")
+	rootCmd.Flags().Bool("watch", false, "watch each --database for changes, e.g. when a sidecar replaces it with a newer index, and serve each new version once it is migrated and valid without interrupting queries; health checks report NOT_SERVING while a changed database is reloaded")
+	serve.AddFlags(rootCmd.Flags())
+	rootCmd.Flags().String("ca-file", "", "path to the CA that clients must present a certificate signed by, for mutual tls; reloaded when it is rotated")
 	if err := rootCmd.Flags().MarkHidden("debug"); err != nil {
 		logrus.Panic(err.Error())
 	}
@@ -67,7 +76,7 @@ func runCmdFunc(cmd *cobra.Command, args []string) error {
 	if err := dns.EnsureNsswitch(); err != nil {
 		return err
 	}
-	dbName, err := cmd.Flags().GetString("database")
+	databases, err := catalogDatabases(cmd)
 	if err != nil {
 		return err
 	}
@@ -76,71 +85,251 @@ func runCmdFunc(cmd *cobra.Command, args []string) error {
 	if err != nil {
 		return err
 	}
+	listen, err := cmd.Flags().GetStringArray("listen")
+	if err != nil {
+		return err
+	}
+
+	var paths []string
+	for _, database := range databases {
+		paths = append(paths, database.path)
+	}
+	logger := logrus.WithFields(logrus.Fields{"database": strings.Join(paths, ","), "port": port})
 
-	logger := logrus.WithFields(logrus.Fields{"database": dbName, "port": port})
+	// queries made while loading and reloading databases stop once the server is shut down
+	ctx, cancel := graceful.Context(cmd.Context())
+	defer cancel()
 
-	// make a writable copy of the db for migrations
-	tmpdb, err := tmp.CopyTmpDB(dbName)
+	dbOpts, err := serve.DatabaseOptionsFromFlags(cmd.Flags())
 	if err != nil {
 		return err
 	}
-	defer os.Remove(tmpdb)
 
-	db, err := sql.Open("sqlite3", tmpdb)
+	watch, err := cmd.Flags().GetBool("watch")
+	if err != nil {
+		return err
+	}
+	cacheOpts, err := serve.QueryCacheOptions(cmd.Flags())
 	if err != nil {
 		return err
 	}
 
-	// migrate to the latest version
-	if err := migrate(cmd, db); err != nil {
-		logger.WithError(err).Warnf("couldn't migrate db")
+	healthServer := server.NewHealthServer()
+	reloader := &databaseReloader{health: healthServer}
+	stores := make([]registry.Query, len(databases))
+	for i, database := range databases {
+		dbLogger := logger.WithField("database", database.path)
+		// the first catalog is the one served to clients that don't name one, so its features are the ones reported
+		setFeatures := func(features *sqlite.FeatureSet) {}
+		if i == 0 {
+			setFeatures = func(features *sqlite.FeatureSet) {
+				if features != nil {
+					healthServer.SetFeatures(features.Available, features.Defaulted)
+				}
+			}
+		}
+
+		var store registry.Query
+		var features *sqlite.FeatureSet
+		var cleanup func()
+		if watch {
+			store, features, cleanup, err = reloader.watch(ctx, dbLogger, database.path, dbOpts, cacheOpts, setFeatures)
+		} else {
+			store, features, cleanup, err = dbOpts.Open(ctx, dbLogger, database.path, 0, 1)
+		}
+		if err != nil {
+			return err
+		}
+		defer cleanup()
+		if !watch {
+			if store, _, err = serve.CacheQuery(store, cacheOpts); err != nil {
+				return err
+			}
+		}
+		stores[i] = store
+		setFeatures(features)
 	}
 
-	store := sqlite.NewSQLLiteQuerierFromDb(db)
+	if len(listen) > 0 {
+		logger = logger.WithField("listen", strings.Join(listen, ","))
+	}
+	lis, err := server.ListenAll(server.ListenAddresses(listen, port)...)
+	if err != nil {
+		logger.Fatalf("failed to listen: %s", err)
+	}
+	opts, err := serve.RequestLogOptions(cmd.Flags(), logger)
+	if err != nil {
+		return err
+	}
 
-	// sanity check that the db is available
-	tables, err := store.ListTables(context.TODO())
+	queryLogOpts, closeQueryLog, err := serve.QueryLogOptions(cmd.Flags())
 	if err != nil {
-		logger.WithError(err).Warnf("couldn't list tables in db")
+		return err
 	}
-	if len(tables) == 0 {
-		logger.Warn("no tables found in db")
+	defer closeQueryLog()
+	opts = append(opts, queryLogOpts...)
+
+	keepaliveOpts, err := serve.KeepaliveOptions(cmd.Flags())
+	if err != nil {
+		return err
 	}
+	opts = append(opts, keepaliveOpts...)
 
-	lis, err := net.Listen("tcp", ":"+port)
+	rateLimits, err := serve.RateLimitOptions(cmd.Flags())
 	if err != nil {
-		logger.Fatalf("failed to listen: %s", err)
+		return err
+	}
+	opts = append(opts, rateLimits.ServerOptions()...)
+
+	// without identities to tell clients apart, a client CA is only useful to keep out clients without a certificate
+	tlsOpts, err := serve.TLSOptions(cmd.Flags(), logger, "ca-file", tls.RequireAndVerifyClientCert)
+	if err != nil {
+		return err
+	}
+	opts = append(opts, tlsOpts...)
+
+	serverOpts, err := serve.RedactionOptions(cmd.Flags())
+	if err != nil {
+		return err
 	}
-	s := grpc.NewServer()
 
-	api.RegisterRegistryServer(s, server.NewRegistryServer(store))
-	health.RegisterHealthServer(s, server.NewHealthServer())
+	s := grpc.NewServer(opts...)
+
+	api.RegisterRegistryServer(s, server.NewRegistryServer(stores[0], serverOpts...))
+	for i, database := range databases {
+		if database.name != "" {
+			api.RegisterCatalogRegistryServer(s, database.name, server.NewRegistryServer(stores[i], serverOpts...))
+		}
+	}
+	health.RegisterHealthServer(s, healthServer)
 	reflection.Register(s)
 	logger.Info("serving registry")
 
-	return graceful.Shutdown(logger, func() error {
-		return s.Serve(lis)
+	return graceful.ShutdownContext(ctx, logger, func() error {
+		return server.Serve(s, lis)
 	}, func() {
 		s.GracefulStop()
+		cancel()
 	})
 }
 
-func migrate(cmd *cobra.Command, db *sql.DB) error {
-	shouldSkipMigrate, err := cmd.Flags().GetBool("skip-migrate")
+// catalogDatabase is a database served by the server, under the service of its catalog name unless it's the only one
+type catalogDatabase struct {
+	name, path string
+}
+
+// catalogDatabases returns the databases to serve, each given by --database as a path or as name=path, or found in
+// --database-dir. Databases that aren't named explicitly are named after their file when there are several.
+func catalogDatabases(cmd *cobra.Command) ([]catalogDatabase, error) {
+	specs, err := cmd.Flags().GetStringArray("database")
 	if err != nil {
-		return err
+		return nil, err
 	}
-	if shouldSkipMigrate {
-		return nil
+	dir, err := cmd.Flags().GetString("database-dir")
+	if err != nil {
+		return nil, err
+	}
+	if dir != "" {
+		if cmd.Flags().Changed("database") {
+			return nil, fmt.Errorf("--database and --database-dir can't be used together")
+		}
+		if specs, err = filepath.Glob(filepath.Join(dir, "*.db")); err != nil {
+			return nil, err
+		}
+		if len(specs) == 0 {
+			return nil, fmt.Errorf("no databases found in %s", dir)
+		}
+		sort.Strings(specs)
 	}
 
-	migrator, err := sqlite.NewSQLLiteMigrator(db)
+	var databases []catalogDatabase
+	names := map[string]string{}
+	for _, spec := range specs {
+		database := catalogDatabase{path: spec}
+		if i := strings.Index(spec, "="); i > 0 && api.ValidateCatalogName(spec[:i]) == nil {
+			database = catalogDatabase{name: spec[:i], path: spec[i+1:]}
+		} else if len(specs) > 1 {
+			database.name = strings.TrimSuffix(filepath.Base(spec), filepath.Ext(spec))
+			if err := api.ValidateCatalogName(database.name); err != nil {
+				return nil, fmt.Errorf("%s, name the catalog of %s with --database <name>=%s", err, spec, spec)
+			}
+		}
+		if database.name != "" {
+			if other, ok := names[database.name]; ok {
+				return nil, fmt.Errorf("%s and %s are both served as catalog %s", other, database.path, database.name)
+			}
+			names[database.name] = database.path
+		}
+		databases = append(databases, database)
+	}
+
+	if len(databases) > 1 && cmd.Flags().Changed("overlay") {
+		return nil, fmt.Errorf("--overlay can only be applied when serving a single database")
+	}
+	return databases, nil
+}
+
+// databaseReloader serves databases that are reloaded each time their file changes. Reloads are made one at a time,
+// and the server is reported as not serving while one is being made, since the database it serves is out of date.
+type databaseReloader struct {
+	mu     sync.Mutex
+	health *server.HealthServer
+}
+
+// watch serves a copy of the database at dbName that is replaced with a copy of each new version of it once that is
+// migrated and valid, without interrupting the queries being served. onReload is passed the features of each new
+// version that is served. The results cached in front of the database, if any, are dropped on every reload. Reloads
+// stop once ctx is done.
+func (r *databaseReloader) watch(ctx context.Context, logger *logrus.Entry, dbName string, dbOpts serve.DatabaseOptions, cacheOpts *registry.QueryCacheOptions, onReload func(*sqlite.FeatureSet)) (registry.Query, *sqlite.FeatureSet, func(), error) {
+	var features *sqlite.FeatureSet
+	var swapper *sqlite.DatabaseSwapper
+	var err error
+	if dbOpts.Key != nil {
+		// a database that may be encrypted is only ever decrypted into memory
+		swapper, err = sqlite.NewMemoryDatabaseSwapper(ctx, func(ctx context.Context) (*sqlite.MemoryDatabase, error) {
+			var m *sqlite.MemoryDatabase
+			var err error
+			m, features, err = dbOpts.Load(ctx, logger, dbName)
+			return m, err
+		}, nil)
+	} else {
+		swapper, err = sqlite.NewDatabaseSwapper(ctx, func(ctx context.Context, path string) error {
+			var err error
+			features, err = dbOpts.Build(ctx, logger, dbName, path)
+			return err
+		}, nil)
+	}
 	if err != nil {
-		return err
+		return nil, nil, nil, err
 	}
-	if migrator == nil {
-		return fmt.Errorf("failed to load migrator")
+	served := features
+	query, invalidate, err := serve.CacheQuery(swapper.Query(), cacheOpts)
+	if err != nil {
+		swapper.Close()
+		return nil, nil, nil, err
 	}
 
-	return migrator.Migrate(context.TODO())
+	// a database in wal mode may only be changed in its wal file until it is checkpointed
+	stop, err := filewatch.Watch([]string{dbName, dbName + "-wal"}, time.Second, func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		logger.Info("database changed, reloading it")
+		r.health.SetServing(false)
+		defer r.health.SetServing(true)
+		if err := swapper.Refresh(ctx); err != nil {
+			logger.WithError(err).Warn("couldn't reload database, still serving the previous one")
+			return
+		}
+		invalidate()
+		onReload(features)
+		logger.Info("serving reloaded database")
+	})
+	if err != nil {
+		swapper.Close()
+		return nil, nil, nil, err
+	}
+	return query, served, func() {
+		stop()
+		swapper.Close()
+	}, nil
 }
@@ -0,0 +1,66 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"google.golang.org/grpc"
+)
+
+// catalogNameRegexp matches the names catalogs can be served under, which are DNS labels so that they can be derived
+// from and used in the names of kubernetes objects
+var catalogNameRegexp = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`)
+
+// ValidateCatalogName returns an error if a server can't serve a catalog named name
+func ValidateCatalogName(name string) error {
+	if len(name) > 63 || !catalogNameRegexp.MatchString(name) {
+		return fmt.Errorf("invalid catalog name %q, must be a lowercase DNS label, e.g. community-operators", name)
+	}
+	return nil
+}
+
+// CatalogServiceName returns the name of the registry service of the named catalog, for a server that serves several
+// catalogs, e.g. community.api.Registry for the catalog named community. Its methods and messages are those of the
+// Registry service.
+func CatalogServiceName(catalog string) string {
+	return catalog + "." + _Registry_serviceDesc.ServiceName
+}
+
+// RegisterCatalogRegistryServer registers srv as the registry service of the named catalog
+func RegisterCatalogRegistryServer(s *grpc.Server, catalog string, srv RegistryServer) {
+	desc := _Registry_serviceDesc
+	desc.ServiceName = CatalogServiceName(catalog)
+	s.RegisterService(&desc, srv)
+}
+
+// NewCatalogRegistryClient returns a client of the registry service of the named catalog
+func NewCatalogRegistryClient(cc grpc.ClientConnInterface, catalog string) RegistryClient {
+	return NewRegistryClient(&catalogConn{
+		ClientConnInterface: cc,
+		from:                "/" + _Registry_serviceDesc.ServiceName + "/",
+		to:                  "/" + CatalogServiceName(catalog) + "/",
+	})
+}
+
+// catalogConn calls the methods of the registry service of a catalog in place of those of the Registry service
+type catalogConn struct {
+	grpc.ClientConnInterface
+	from, to string
+}
+
+func (c *catalogConn) Invoke(ctx context.Context, method string, args, reply interface{}, opts ...grpc.CallOption) error {
+	return c.ClientConnInterface.Invoke(ctx, c.method(method), args, reply, opts...)
+}
+
+func (c *catalogConn) NewStream(ctx context.Context, desc *grpc.StreamDesc, method string, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	return c.ClientConnInterface.NewStream(ctx, desc, c.method(method), opts...)
+}
+
+func (c *catalogConn) method(method string) string {
+	if strings.HasPrefix(method, c.from) {
+		return c.to + strings.TrimPrefix(method, c.from)
+	}
+	return method
+}
@@ -0,0 +1,9 @@
+// Package api holds the protobuf-backed types the registry serves: Package, Channel, Bundle, GroupVersionKind and
+// the requests and responses of the Registry service. They are the stable import surface of this module; the
+// storage and loading types in pkg/registry and pkg/sqlite may change between releases, but these only change in
+// backwards compatible ways within a Version. Use the converters in pkg/registry to build them from registry types.
+package api
+
+// Version is the version of the types in this package. Fields are only ever added within a version; renaming or
+// removing a field, or changing its number or meaning, needs a new one.
+const Version = "v1"
@@ -0,0 +1,60 @@
+package api
+
+// Features a registry server advertises through GetAPIVersion. Clients consuming catalogs served by different
+// releases check for a feature before relying on it; a server that predates GetAPIVersion supports none of them.
+const (
+	// FeatureStreaming is set when ListPackages and ListBundles stream their results
+	FeatureStreaming = "streaming"
+	// FeatureDeprecations is set when packages carry a deprecationNotice and deprecated bundles an olm.deprecated property
+	FeatureDeprecations = "deprecations"
+	// FeatureProperties is set when bundles carry their properties and dependencies
+	FeatureProperties = "properties"
+	// FeatureLocales is set when GetPackage can localize a package
+	FeatureLocales = "locales"
+	// FeatureBundlesExist is set when the server implements BundlesExist
+	FeatureBundlesExist = "bundlesExist"
+	// FeaturePackageStats is set when the server implements GetPackageStats
+	FeaturePackageStats = "packageStats"
+	// FeatureDefaultChannelHeads is set when the server implements GetDefaultChannelHeads
+	FeatureDefaultChannelHeads = "defaultChannelHeads"
+	// FeatureBundleFields is set when the rpcs that return bundles only return the fields named in a request
+	FeatureBundleFields = "bundleFields"
+	// FeaturePackagePages is set when ListPackages can list the packages a page at a time
+	FeaturePackagePages = "packagePages"
+	// FeatureBundleSearch is set when the server implements SearchBundles
+	FeatureBundleSearch = "bundleSearch"
+	// FeatureDeprecatedHeads is set when deprecated bundles are left out of channel heads unless GetBundleForChannel
+	// is asked to include them
+	FeatureDeprecatedHeads = "deprecatedHeads"
+	// FeatureBundleFilters is set when ListBundles only lists the bundles selected by the package, channel and label
+	// selector of a request
+	FeatureBundleFilters = "bundleFilters"
+	// FeatureBundlesForPackage is set when the server implements GetBundlesForPackage
+	FeatureBundlesForPackage = "bundlesForPackage"
+	// FeatureWatch is set when clients can watch the catalog for changes with Watch, which is only the case for
+	// catalogs that are refreshed while they are served
+	FeatureWatch = "watch"
+	// FeatureBundleStats is set when the server implements GetBundleStats and package stats carry the size of the
+	// bundles of their package
+	FeatureBundleStats = "bundleStats"
+	// FeatureTimeBudgets is set when GetPackageStats, GetBundleStats and SearchBundles return partial results and a
+	// continuation once the time budget of a request is spent
+	FeatureTimeBudgets = "timeBudgets"
+	// FeatureInstallModes is set when ListBundles only lists the bundles that support the install mode of a request,
+	// and bundles carry the install modes they support as an olm.installModes property
+	FeatureInstallModes = "installModes"
+)
+
+// ContinuationTrailer is the trailer a request with a time budget is given the continuation of its results in, if it
+// returned partial results
+const ContinuationTrailer = "x-continuation"
+
+// HasFeature returns true if the server advertised the named feature
+func (v *APIVersion) HasFeature(name string) bool {
+	for _, f := range v.GetFeatures() {
+		if f == name {
+			return true
+		}
+	}
+	return false
+}
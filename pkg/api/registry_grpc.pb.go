@@ -27,6 +27,14 @@ type RegistryClient interface {
 	GetLatestChannelEntriesThatProvide(ctx context.Context, in *GetLatestProvidersRequest, opts ...grpc.CallOption) (Registry_GetLatestChannelEntriesThatProvideClient, error)
 	GetDefaultBundleThatProvides(ctx context.Context, in *GetDefaultProviderRequest, opts ...grpc.CallOption) (*Bundle, error)
 	ListBundles(ctx context.Context, in *ListBundlesRequest, opts ...grpc.CallOption) (Registry_ListBundlesClient, error)
+	BundlesExist(ctx context.Context, in *BundlesExistRequest, opts ...grpc.CallOption) (*BundlesExistResponse, error)
+	GetAPIVersion(ctx context.Context, in *GetAPIVersionRequest, opts ...grpc.CallOption) (*APIVersion, error)
+	GetPackageStats(ctx context.Context, in *GetPackageStatsRequest, opts ...grpc.CallOption) (Registry_GetPackageStatsClient, error)
+	GetDefaultChannelHeads(ctx context.Context, in *GetDefaultChannelHeadsRequest, opts ...grpc.CallOption) (Registry_GetDefaultChannelHeadsClient, error)
+	SearchBundles(ctx context.Context, in *SearchBundlesRequest, opts ...grpc.CallOption) (Registry_SearchBundlesClient, error)
+	GetBundlesForPackage(ctx context.Context, in *GetBundlesForPackageRequest, opts ...grpc.CallOption) (*PackageBundles, error)
+	Watch(ctx context.Context, in *WatchRequest, opts ...grpc.CallOption) (Registry_WatchClient, error)
+	GetBundleStats(ctx context.Context, in *GetBundleStatsRequest, opts ...grpc.CallOption) (Registry_GetBundleStatsClient, error)
 }
 
 type registryClient struct {
@@ -242,6 +250,193 @@ func (x *registryListBundlesClient) Recv() (*Bundle, error) {
 	return m, nil
 }
 
+func (c *registryClient) BundlesExist(ctx context.Context, in *BundlesExistRequest, opts ...grpc.CallOption) (*BundlesExistResponse, error) {
+	out := new(BundlesExistResponse)
+	err := c.cc.Invoke(ctx, "/api.Registry/BundlesExist", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *registryClient) GetAPIVersion(ctx context.Context, in *GetAPIVersionRequest, opts ...grpc.CallOption) (*APIVersion, error) {
+	out := new(APIVersion)
+	err := c.cc.Invoke(ctx, "/api.Registry/GetAPIVersion", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *registryClient) GetPackageStats(ctx context.Context, in *GetPackageStatsRequest, opts ...grpc.CallOption) (Registry_GetPackageStatsClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_Registry_serviceDesc.Streams[5], "/api.Registry/GetPackageStats", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &registryGetPackageStatsClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Registry_GetPackageStatsClient interface {
+	Recv() (*PackageStats, error)
+	grpc.ClientStream
+}
+
+type registryGetPackageStatsClient struct {
+	grpc.ClientStream
+}
+
+func (x *registryGetPackageStatsClient) Recv() (*PackageStats, error) {
+	m := new(PackageStats)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *registryClient) GetDefaultChannelHeads(ctx context.Context, in *GetDefaultChannelHeadsRequest, opts ...grpc.CallOption) (Registry_GetDefaultChannelHeadsClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_Registry_serviceDesc.Streams[6], "/api.Registry/GetDefaultChannelHeads", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &registryGetDefaultChannelHeadsClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Registry_GetDefaultChannelHeadsClient interface {
+	Recv() (*BundleSummary, error)
+	grpc.ClientStream
+}
+
+type registryGetDefaultChannelHeadsClient struct {
+	grpc.ClientStream
+}
+
+func (x *registryGetDefaultChannelHeadsClient) Recv() (*BundleSummary, error) {
+	m := new(BundleSummary)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *registryClient) SearchBundles(ctx context.Context, in *SearchBundlesRequest, opts ...grpc.CallOption) (Registry_SearchBundlesClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_Registry_serviceDesc.Streams[7], "/api.Registry/SearchBundles", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &registrySearchBundlesClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Registry_SearchBundlesClient interface {
+	Recv() (*BundleSummary, error)
+	grpc.ClientStream
+}
+
+type registrySearchBundlesClient struct {
+	grpc.ClientStream
+}
+
+func (x *registrySearchBundlesClient) Recv() (*BundleSummary, error) {
+	m := new(BundleSummary)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *registryClient) GetBundlesForPackage(ctx context.Context, in *GetBundlesForPackageRequest, opts ...grpc.CallOption) (*PackageBundles, error) {
+	out := new(PackageBundles)
+	err := c.cc.Invoke(ctx, "/api.Registry/GetBundlesForPackage", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *registryClient) Watch(ctx context.Context, in *WatchRequest, opts ...grpc.CallOption) (Registry_WatchClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_Registry_serviceDesc.Streams[8], "/api.Registry/Watch", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &registryWatchClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Registry_WatchClient interface {
+	Recv() (*CatalogEvent, error)
+	grpc.ClientStream
+}
+
+type registryWatchClient struct {
+	grpc.ClientStream
+}
+
+func (x *registryWatchClient) Recv() (*CatalogEvent, error) {
+	m := new(CatalogEvent)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *registryClient) GetBundleStats(ctx context.Context, in *GetBundleStatsRequest, opts ...grpc.CallOption) (Registry_GetBundleStatsClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_Registry_serviceDesc.Streams[9], "/api.Registry/GetBundleStats", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &registryGetBundleStatsClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Registry_GetBundleStatsClient interface {
+	Recv() (*BundleStats, error)
+	grpc.ClientStream
+}
+
+type registryGetBundleStatsClient struct {
+	grpc.ClientStream
+}
+
+func (x *registryGetBundleStatsClient) Recv() (*BundleStats, error) {
+	m := new(BundleStats)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 // RegistryServer is the server API for Registry service.
 // All implementations must embed UnimplementedRegistryServer
 // for forward compatibility
@@ -256,6 +451,14 @@ type RegistryServer interface {
 	GetLatestChannelEntriesThatProvide(*GetLatestProvidersRequest, Registry_GetLatestChannelEntriesThatProvideServer) error
 	GetDefaultBundleThatProvides(context.Context, *GetDefaultProviderRequest) (*Bundle, error)
 	ListBundles(*ListBundlesRequest, Registry_ListBundlesServer) error
+	BundlesExist(context.Context, *BundlesExistRequest) (*BundlesExistResponse, error)
+	GetAPIVersion(context.Context, *GetAPIVersionRequest) (*APIVersion, error)
+	GetPackageStats(*GetPackageStatsRequest, Registry_GetPackageStatsServer) error
+	GetDefaultChannelHeads(*GetDefaultChannelHeadsRequest, Registry_GetDefaultChannelHeadsServer) error
+	SearchBundles(*SearchBundlesRequest, Registry_SearchBundlesServer) error
+	GetBundlesForPackage(context.Context, *GetBundlesForPackageRequest) (*PackageBundles, error)
+	Watch(*WatchRequest, Registry_WatchServer) error
+	GetBundleStats(*GetBundleStatsRequest, Registry_GetBundleStatsServer) error
 	mustEmbedUnimplementedRegistryServer()
 }
 
@@ -293,6 +496,30 @@ func (*UnimplementedRegistryServer) GetDefaultBundleThatProvides(context.Context
 func (*UnimplementedRegistryServer) ListBundles(*ListBundlesRequest, Registry_ListBundlesServer) error {
 	return status.Errorf(codes.Unimplemented, "method ListBundles not implemented")
 }
+func (*UnimplementedRegistryServer) BundlesExist(context.Context, *BundlesExistRequest) (*BundlesExistResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method BundlesExist not implemented")
+}
+func (*UnimplementedRegistryServer) GetAPIVersion(context.Context, *GetAPIVersionRequest) (*APIVersion, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetAPIVersion not implemented")
+}
+func (*UnimplementedRegistryServer) GetPackageStats(*GetPackageStatsRequest, Registry_GetPackageStatsServer) error {
+	return status.Errorf(codes.Unimplemented, "method GetPackageStats not implemented")
+}
+func (*UnimplementedRegistryServer) GetDefaultChannelHeads(*GetDefaultChannelHeadsRequest, Registry_GetDefaultChannelHeadsServer) error {
+	return status.Errorf(codes.Unimplemented, "method GetDefaultChannelHeads not implemented")
+}
+func (*UnimplementedRegistryServer) SearchBundles(*SearchBundlesRequest, Registry_SearchBundlesServer) error {
+	return status.Errorf(codes.Unimplemented, "method SearchBundles not implemented")
+}
+func (*UnimplementedRegistryServer) GetBundlesForPackage(context.Context, *GetBundlesForPackageRequest) (*PackageBundles, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetBundlesForPackage not implemented")
+}
+func (*UnimplementedRegistryServer) Watch(*WatchRequest, Registry_WatchServer) error {
+	return status.Errorf(codes.Unimplemented, "method Watch not implemented")
+}
+func (*UnimplementedRegistryServer) GetBundleStats(*GetBundleStatsRequest, Registry_GetBundleStatsServer) error {
+	return status.Errorf(codes.Unimplemented, "method GetBundleStats not implemented")
+}
 func (*UnimplementedRegistryServer) mustEmbedUnimplementedRegistryServer() {}
 
 func RegisterRegistryServer(s *grpc.Server, srv RegistryServer) {
@@ -494,6 +721,165 @@ func (x *registryListBundlesServer) Send(m *Bundle) error {
 	return x.ServerStream.SendMsg(m)
 }
 
+func _Registry_BundlesExist_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BundlesExistRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RegistryServer).BundlesExist(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/api.Registry/BundlesExist",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RegistryServer).BundlesExist(ctx, req.(*BundlesExistRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Registry_GetAPIVersion_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetAPIVersionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RegistryServer).GetAPIVersion(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/api.Registry/GetAPIVersion",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RegistryServer).GetAPIVersion(ctx, req.(*GetAPIVersionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Registry_GetPackageStats_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(GetPackageStatsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(RegistryServer).GetPackageStats(m, &registryGetPackageStatsServer{stream})
+}
+
+type Registry_GetPackageStatsServer interface {
+	Send(*PackageStats) error
+	grpc.ServerStream
+}
+
+type registryGetPackageStatsServer struct {
+	grpc.ServerStream
+}
+
+func (x *registryGetPackageStatsServer) Send(m *PackageStats) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _Registry_GetDefaultChannelHeads_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(GetDefaultChannelHeadsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(RegistryServer).GetDefaultChannelHeads(m, &registryGetDefaultChannelHeadsServer{stream})
+}
+
+type Registry_GetDefaultChannelHeadsServer interface {
+	Send(*BundleSummary) error
+	grpc.ServerStream
+}
+
+type registryGetDefaultChannelHeadsServer struct {
+	grpc.ServerStream
+}
+
+func (x *registryGetDefaultChannelHeadsServer) Send(m *BundleSummary) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _Registry_SearchBundles_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(SearchBundlesRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(RegistryServer).SearchBundles(m, &registrySearchBundlesServer{stream})
+}
+
+type Registry_SearchBundlesServer interface {
+	Send(*BundleSummary) error
+	grpc.ServerStream
+}
+
+type registrySearchBundlesServer struct {
+	grpc.ServerStream
+}
+
+func (x *registrySearchBundlesServer) Send(m *BundleSummary) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _Registry_GetBundlesForPackage_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetBundlesForPackageRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RegistryServer).GetBundlesForPackage(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/api.Registry/GetBundlesForPackage",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RegistryServer).GetBundlesForPackage(ctx, req.(*GetBundlesForPackageRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Registry_Watch_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(RegistryServer).Watch(m, &registryWatchServer{stream})
+}
+
+type Registry_WatchServer interface {
+	Send(*CatalogEvent) error
+	grpc.ServerStream
+}
+
+type registryWatchServer struct {
+	grpc.ServerStream
+}
+
+func (x *registryWatchServer) Send(m *CatalogEvent) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _Registry_GetBundleStats_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(GetBundleStatsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(RegistryServer).GetBundleStats(m, &registryGetBundleStatsServer{stream})
+}
+
+type Registry_GetBundleStatsServer interface {
+	Send(*BundleStats) error
+	grpc.ServerStream
+}
+
+type registryGetBundleStatsServer struct {
+	grpc.ServerStream
+}
+
+func (x *registryGetBundleStatsServer) Send(m *BundleStats) error {
+	return x.ServerStream.SendMsg(m)
+}
+
 var _Registry_serviceDesc = grpc.ServiceDesc{
 	ServiceName: "api.Registry",
 	HandlerType: (*RegistryServer)(nil),
@@ -518,6 +904,18 @@ var _Registry_serviceDesc = grpc.ServiceDesc{
 			MethodName: "GetDefaultBundleThatProvides",
 			Handler:    _Registry_GetDefaultBundleThatProvides_Handler,
 		},
+		{
+			MethodName: "BundlesExist",
+			Handler:    _Registry_BundlesExist_Handler,
+		},
+		{
+			MethodName: "GetAPIVersion",
+			Handler:    _Registry_GetAPIVersion_Handler,
+		},
+		{
+			MethodName: "GetBundlesForPackage",
+			Handler:    _Registry_GetBundlesForPackage_Handler,
+		},
 	},
 	Streams: []grpc.StreamDesc{
 		{
@@ -545,6 +943,31 @@ var _Registry_serviceDesc = grpc.ServiceDesc{
 			Handler:       _Registry_ListBundles_Handler,
 			ServerStreams: true,
 		},
+		{
+			StreamName:    "GetPackageStats",
+			Handler:       _Registry_GetPackageStats_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "GetDefaultChannelHeads",
+			Handler:       _Registry_GetDefaultChannelHeads_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "SearchBundles",
+			Handler:       _Registry_SearchBundles_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "Watch",
+			Handler:       _Registry_Watch_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "GetBundleStats",
+			Handler:       _Registry_GetBundleStats_Handler,
+			ServerStreams: true,
+		},
 	},
 	Metadata: "registry.proto",
 }
@@ -0,0 +1,299 @@
+package catalogapi
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/operator-framework/operator-registry/pkg/registry"
+)
+
+const (
+	packageManifests = "packagemanifests"
+	bundles          = "bundles"
+)
+
+// Handler serves a catalog as a read-only Kubernetes api, so that it can be registered with an APIService and
+// browsed with kubectl. Packages are served as packagemanifests and bundles as bundles, both cluster scoped, and
+// lists can be filtered with a label selector.
+type Handler struct {
+	store  registry.Query
+	logger logrus.FieldLogger
+}
+
+// NewHandler returns a handler that serves the catalog of store
+func NewHandler(store registry.Query, logger logrus.FieldLogger) *Handler {
+	return &Handler{store: store, logger: logger}
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == "/healthz" || r.URL.Path == "/readyz" {
+		w.Write([]byte("ok"))
+		return
+	}
+	if r.Method != http.MethodGet {
+		h.writeError(w, apierrors.NewMethodNotSupported(schema.GroupResource{Group: GroupName}, strings.ToLower(r.Method)))
+		return
+	}
+
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	switch {
+	case len(parts) == 1 && parts[0] == "apis":
+		h.write(w, &metav1.APIGroupList{
+			TypeMeta: metav1.TypeMeta{Kind: "APIGroupList", APIVersion: "v1"},
+			Groups:   []metav1.APIGroup{apiGroup()},
+		})
+	case len(parts) == 2 && parts[0] == "apis" && parts[1] == GroupName:
+		group := apiGroup()
+		group.TypeMeta = metav1.TypeMeta{Kind: "APIGroup", APIVersion: "v1"}
+		h.write(w, &group)
+	case len(parts) == 3 && parts[0] == "apis" && parts[1] == GroupName && parts[2] == Version:
+		h.write(w, apiResources())
+	case len(parts) >= 4 && len(parts) <= 5 && parts[0] == "apis" && parts[1] == GroupName && parts[2] == Version:
+		name := ""
+		if len(parts) == 5 {
+			name = parts[4]
+		}
+		h.serveResource(w, r, parts[3], name)
+	default:
+		h.writeError(w, apierrors.NewNotFound(schema.GroupResource{Group: GroupName}, r.URL.Path))
+	}
+}
+
+func (h *Handler) serveResource(w http.ResponseWriter, r *http.Request, resource, name string) {
+	selector, err := labels.Parse(r.URL.Query().Get("labelSelector"))
+	if err != nil {
+		h.writeError(w, apierrors.NewBadRequest(err.Error()))
+		return
+	}
+
+	ctx := r.Context()
+	switch resource {
+	case packageManifests:
+		items, err := h.packageManifests(ctx, name)
+		if err != nil {
+			h.writeError(w, err)
+			return
+		}
+		if name != "" {
+			h.write(w, &items[0])
+			return
+		}
+		list := &PackageManifestList{TypeMeta: typeMeta("PackageManifestList"), Items: []PackageManifest{}}
+		for _, item := range items {
+			if selector.Matches(labels.Set(item.Labels)) {
+				list.Items = append(list.Items, item)
+			}
+		}
+		h.write(w, list)
+	case bundles:
+		items, err := h.bundles(ctx, name)
+		if err != nil {
+			h.writeError(w, err)
+			return
+		}
+		if name != "" {
+			h.write(w, &items[0])
+			return
+		}
+		list := &BundleList{TypeMeta: typeMeta("BundleList"), Items: []Bundle{}}
+		for _, item := range items {
+			if selector.Matches(labels.Set(item.Labels)) {
+				list.Items = append(list.Items, item)
+			}
+		}
+		h.write(w, list)
+	default:
+		h.writeError(w, apierrors.NewNotFound(schema.GroupResource{Group: GroupName, Resource: resource}, name))
+	}
+}
+
+// packageManifests returns every package, or the package called name if it's set
+func (h *Handler) packageManifests(ctx context.Context, name string) ([]PackageManifest, error) {
+	names, err := h.store.ListPackages(ctx)
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(names)
+	if name != "" {
+		i := sort.SearchStrings(names, name)
+		if i == len(names) || names[i] != name {
+			return nil, apierrors.NewNotFound(schema.GroupResource{Group: GroupName, Resource: packageManifests}, name)
+		}
+		names = []string{name}
+	}
+
+	var items []PackageManifest
+	for _, n := range names {
+		pkg, err := h.store.GetPackage(ctx, n)
+		if err != nil {
+			return nil, err
+		}
+		item := PackageManifest{
+			TypeMeta:   typeMeta("PackageManifest"),
+			ObjectMeta: metav1.ObjectMeta{Name: pkg.PackageName},
+			Status: PackageManifestStatus{
+				PackageName:    pkg.PackageName,
+				DefaultChannel: pkg.DefaultChannelName,
+				Channels:       []PackageChannel{},
+			},
+		}
+		for _, c := range pkg.Channels {
+			item.Status.Channels = append(item.Status.Channels, PackageChannel{Name: c.Name, CurrentCSV: c.CurrentCSVName})
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// bundles returns every bundle, or the bundle called name if it's set. Bundles are listed from their summaries,
+// without reading their manifests.
+func (h *Handler) bundles(ctx context.Context, name string) ([]Bundle, error) {
+	summaries, err := h.store.ListBundleSummaries(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// a bundle has a summary for every channel it is in
+	byName := map[string]*Bundle{}
+	var names []string
+	for _, s := range summaries {
+		if name != "" && s.CsvName != name {
+			continue
+		}
+		if b, ok := byName[s.CsvName]; ok {
+			b.Spec.Channels = append(b.Spec.Channels, s.ChannelName)
+			continue
+		}
+		b := &Bundle{
+			TypeMeta: typeMeta("Bundle"),
+			ObjectMeta: metav1.ObjectMeta{
+				Name:   s.CsvName,
+				Labels: map[string]string{PackageLabel: s.PackageName},
+			},
+			Spec: BundleSpec{
+				PackageName:  s.PackageName,
+				Channels:     []string{s.ChannelName},
+				Version:      s.Version,
+				BundlePath:   s.BundlePath,
+				Replaces:     s.Replaces,
+				Skips:        s.Skips,
+				SkipRange:    s.SkipRange,
+				ProvidedAPIs: groupVersionKinds(s.ProvidedApis),
+				RequiredAPIs: groupVersionKinds(s.RequiredApis),
+			},
+		}
+		byName[s.CsvName] = b
+		names = append(names, s.CsvName)
+	}
+	if name != "" && len(names) == 0 {
+		return nil, apierrors.NewNotFound(schema.GroupResource{Group: GroupName, Resource: bundles}, name)
+	}
+
+	sort.Strings(names)
+	items := make([]Bundle, 0, len(names))
+	for _, n := range names {
+		b := byName[n]
+		sort.Strings(b.Spec.Channels)
+		items = append(items, *b)
+	}
+	return items, nil
+}
+
+func (h *Handler) write(w http.ResponseWriter, obj interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(obj); err != nil {
+		h.logger.WithError(err).Warn("error writing response")
+	}
+}
+
+// writeError writes an error as a Status, as the Kubernetes api does. Errors that aren't api errors are reported
+// as internal errors.
+func (h *Handler) writeError(w http.ResponseWriter, err error) {
+	status, ok := err.(apierrors.APIStatus)
+	if !ok {
+		h.logger.WithError(err).Warn("error serving catalog")
+		status = apierrors.NewInternalError(err)
+	}
+	s := status.Status()
+	s.TypeMeta = metav1.TypeMeta{Kind: "Status", APIVersion: "v1"}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(int(s.Code))
+	if err := json.NewEncoder(w).Encode(&s); err != nil {
+		h.logger.WithError(err).Warn("error writing response")
+	}
+}
+
+func typeMeta(kind string) metav1.TypeMeta {
+	return metav1.TypeMeta{Kind: kind, APIVersion: SchemeGroupVersion.String()}
+}
+
+func apiGroup() metav1.APIGroup {
+	version := metav1.GroupVersionForDiscovery{GroupVersion: SchemeGroupVersion.String(), Version: Version}
+	return metav1.APIGroup{
+		Name:             GroupName,
+		Versions:         []metav1.GroupVersionForDiscovery{version},
+		PreferredVersion: version,
+	}
+}
+
+func apiResources() *metav1.APIResourceList {
+	verbs := metav1.Verbs{"get", "list"}
+	return &metav1.APIResourceList{
+		TypeMeta:     metav1.TypeMeta{Kind: "APIResourceList", APIVersion: "v1"},
+		GroupVersion: SchemeGroupVersion.String(),
+		APIResources: []metav1.APIResource{
+			{Name: packageManifests, SingularName: "packagemanifest", Kind: "PackageManifest", Verbs: verbs},
+			{Name: bundles, SingularName: "bundle", Kind: "Bundle", Verbs: verbs},
+		},
+	}
+}
+
+func groupVersionKinds(keys []registry.APIKey) []GroupVersionKind {
+	var gvks []GroupVersionKind
+	for _, k := range keys {
+		gvks = append(gvks, GroupVersionKind{Group: k.Group, Version: k.Version, Kind: k.Kind, Plural: k.Plural})
+	}
+	return gvks
+}
+
+// TLSConfig returns the tls config to serve the api with. The api is served through the Kubernetes api server,
+// which authenticates as a client of the api with its front proxy client certificate, so when a client CA is given
+// clients must present a certificate signed by it.
+func TLSConfig(certFile, keyFile, clientCAFile string) (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("error loading serving certificate: %s", err)
+	}
+	config := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+	if clientCAFile == "" {
+		return config, nil
+	}
+
+	pem, err := ioutil.ReadFile(clientCAFile)
+	if err != nil {
+		return nil, fmt.Errorf("error reading client CA: %s", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no certificates found in client CA %s", clientCAFile)
+	}
+	config.ClientCAs = pool
+	config.ClientAuth = tls.RequireAndVerifyClientCert
+	return config, nil
+}
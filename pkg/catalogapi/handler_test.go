@@ -0,0 +1,136 @@
+package catalogapi
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/operator-framework/operator-registry/pkg/sqlite"
+)
+
+// newTestServer serves the catalog of the manifests directory. The returned func stops the server and removes the
+// catalog.
+func newTestServer(t *testing.T) (*httptest.Server, func()) {
+	dir, err := ioutil.TempDir("", "catalogapi-")
+	require.NoError(t, err)
+
+	dbPath := filepath.Join(dir, "index.db")
+	db, err := sql.Open("sqlite3", dbPath)
+	require.NoError(t, err)
+	load, err := sqlite.NewSQLLiteLoader(db)
+	require.NoError(t, err)
+	require.NoError(t, load.Migrate(context.TODO()))
+	require.NoError(t, sqlite.NewSQLLoaderForDirectory(load, "../../manifests").Populate())
+	require.NoError(t, db.Close())
+
+	store, err := sqlite.NewSQLLiteQuerier(dbPath)
+	require.NoError(t, err)
+	s := httptest.NewServer(NewHandler(store, logrus.NewEntry(logrus.New())))
+	return s, func() {
+		s.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+func get(t *testing.T, s *httptest.Server, path string, code int, into interface{}) {
+	resp, err := http.Get(s.URL + path)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, code, resp.StatusCode, path)
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(into))
+}
+
+func TestDiscovery(t *testing.T) {
+	s, cleanup := newTestServer(t)
+	defer cleanup()
+
+	var groups metav1.APIGroupList
+	get(t, s, "/apis", http.StatusOK, &groups)
+	require.Len(t, groups.Groups, 1)
+	require.Equal(t, GroupName, groups.Groups[0].Name)
+	require.Equal(t, "registry.operatorframework.io/v1alpha1", groups.Groups[0].PreferredVersion.GroupVersion)
+
+	var group metav1.APIGroup
+	get(t, s, "/apis/"+GroupName, http.StatusOK, &group)
+	require.Equal(t, GroupName, group.Name)
+
+	var resources metav1.APIResourceList
+	get(t, s, "/apis/"+GroupName+"/"+Version, http.StatusOK, &resources)
+	var names []string
+	for _, r := range resources.APIResources {
+		require.False(t, r.Namespaced)
+		require.Equal(t, metav1.Verbs{"get", "list"}, r.Verbs)
+		names = append(names, r.Name)
+	}
+	require.Equal(t, []string{"packagemanifests", "bundles"}, names)
+}
+
+func TestPackageManifests(t *testing.T) {
+	s, cleanup := newTestServer(t)
+	defer cleanup()
+	base := "/apis/" + GroupName + "/" + Version + "/packagemanifests"
+
+	var list PackageManifestList
+	get(t, s, base, http.StatusOK, &list)
+	var names []string
+	for _, item := range list.Items {
+		names = append(names, item.Name)
+	}
+	require.Equal(t, []string{"etcd", "prometheus", "strimzi-kafka-operator"}, names)
+
+	var etcd PackageManifest
+	get(t, s, base+"/etcd", http.StatusOK, &etcd)
+	require.Equal(t, "PackageManifest", etcd.Kind)
+	require.Equal(t, "etcd", etcd.Status.PackageName)
+	require.Equal(t, "alpha", etcd.Status.DefaultChannel)
+	require.Contains(t, etcd.Status.Channels, PackageChannel{Name: "alpha", CurrentCSV: "etcdoperator.v0.9.2"})
+
+	var status metav1.Status
+	get(t, s, base+"/missing", http.StatusNotFound, &status)
+	require.Equal(t, metav1.StatusReasonNotFound, status.Reason)
+}
+
+func TestBundles(t *testing.T) {
+	s, cleanup := newTestServer(t)
+	defer cleanup()
+	base := "/apis/" + GroupName + "/" + Version + "/bundles"
+
+	var list BundleList
+	get(t, s, base+"?labelSelector="+url.QueryEscape(PackageLabel+"=etcd"), http.StatusOK, &list)
+	require.NotEmpty(t, list.Items)
+	for _, item := range list.Items {
+		require.Equal(t, "etcd", item.Spec.PackageName)
+	}
+
+	var bundle Bundle
+	get(t, s, base+"/etcdoperator.v0.9.2", http.StatusOK, &bundle)
+	require.Equal(t, "etcd", bundle.Labels[PackageLabel])
+	require.Equal(t, "0.9.2", bundle.Spec.Version)
+	require.Equal(t, "etcdoperator.v0.9.0", bundle.Spec.Replaces)
+	require.Contains(t, bundle.Spec.ProvidedAPIs, GroupVersionKind{Group: "etcd.database.coreos.com", Version: "v1beta2", Kind: "EtcdCluster", Plural: "etcdclusters"})
+
+	var status metav1.Status
+	get(t, s, base+"?labelSelector="+url.QueryEscape("!!"), http.StatusBadRequest, &status)
+	get(t, s, base+"/missing", http.StatusNotFound, &status)
+}
+
+func TestReadOnly(t *testing.T) {
+	s, cleanup := newTestServer(t)
+	defer cleanup()
+
+	resp, err := http.Post(s.URL+"/apis/"+GroupName+"/"+Version+"/packagemanifests", "application/json", nil)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
+}
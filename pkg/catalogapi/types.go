@@ -0,0 +1,87 @@
+package catalogapi
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+const (
+	// GroupName is the api group the catalog is served in. It is distinct from the group of the packageserver, so
+	// both can be registered in a cluster at once.
+	GroupName = "registry.operatorframework.io"
+	// Version is the version of the api the catalog is served in
+	Version = "v1alpha1"
+
+	// PackageLabel is set on every bundle to the name of its package, so that bundles can be selected by package
+	PackageLabel = GroupName + "/package"
+)
+
+// SchemeGroupVersion is the group and version the catalog is served in
+var SchemeGroupVersion = schema.GroupVersion{Group: GroupName, Version: Version}
+
+// PackageManifest is a package of the catalog, with its channels and their heads
+type PackageManifest struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Status PackageManifestStatus `json:"status"`
+}
+
+// PackageManifestStatus holds the channels of a package
+type PackageManifestStatus struct {
+	PackageName    string           `json:"packageName"`
+	DefaultChannel string           `json:"defaultChannel"`
+	Channels       []PackageChannel `json:"channels"`
+}
+
+// PackageChannel is a channel of a package and the bundle at its head
+type PackageChannel struct {
+	Name       string `json:"name"`
+	CurrentCSV string `json:"currentCSV"`
+}
+
+// PackageManifestList is a list of packages
+type PackageManifestList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+
+	Items []PackageManifest `json:"items"`
+}
+
+// Bundle is a bundle of the catalog, named by its CSV, with its place in the upgrade graph of the channels it is in.
+// Its manifests aren't served.
+type Bundle struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec BundleSpec `json:"spec"`
+}
+
+// BundleSpec holds what the catalog knows about a bundle
+type BundleSpec struct {
+	PackageName  string             `json:"packageName"`
+	Channels     []string           `json:"channels"`
+	Version      string             `json:"version,omitempty"`
+	BundlePath   string             `json:"bundlePath,omitempty"`
+	Replaces     string             `json:"replaces,omitempty"`
+	Skips        []string           `json:"skips,omitempty"`
+	SkipRange    string             `json:"skipRange,omitempty"`
+	ProvidedAPIs []GroupVersionKind `json:"providedAPIs,omitempty"`
+	RequiredAPIs []GroupVersionKind `json:"requiredAPIs,omitempty"`
+}
+
+// GroupVersionKind is an api provided or required by a bundle
+type GroupVersionKind struct {
+	Group   string `json:"group"`
+	Version string `json:"version"`
+	Kind    string `json:"kind"`
+	Plural  string `json:"plural,omitempty"`
+}
+
+// BundleList is a list of bundles
+type BundleList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+
+	Items []Bundle `json:"items"`
+}
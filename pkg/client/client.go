@@ -3,15 +3,21 @@ package client
 import (
 	"context"
 	"io"
+	"net"
+	"strings"
 	"time"
 
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/connectivity"
+	"google.golang.org/grpc/status"
 
 	"github.com/operator-framework/operator-registry/pkg/api"
 	"github.com/operator-framework/operator-registry/pkg/api/grpc_health_v1"
 )
 
+const unixScheme = "unix://"
+
 type Interface interface {
 	GetBundle(ctx context.Context, packageName, channelName, csvName string) (*api.Bundle, error)
 	GetBundleInPackageChannel(ctx context.Context, packageName, channelName string) (*api.Bundle, error)
@@ -19,6 +25,15 @@ type Interface interface {
 	GetBundleThatProvides(ctx context.Context, group, version, kind string) (*api.Bundle, error)
 	ListBundles(ctx context.Context) (*BundleIterator, error)
 	GetPackage(ctx context.Context, packageName string) (*api.Package, error)
+	ListPackagesPaged(ctx context.Context, after string, limit int) ([]string, error)
+	BundlesExist(ctx context.Context, bundles []string) (present, missing []string, err error)
+	GetAPIVersion(ctx context.Context) (*api.APIVersion, error)
+	GetPackageStats(ctx context.Context, packages ...string) ([]*api.PackageStats, error)
+	GetDefaultChannelHeads(ctx context.Context, packages ...string) ([]*api.BundleSummary, error)
+	SearchBundles(ctx context.Context, query string) ([]*api.BundleSummary, error)
+	GetBundlesForPackage(ctx context.Context, packageName string) (*api.PackageBundles, error)
+	Watch(ctx context.Context, packages ...string) (api.Registry_WatchClient, error)
+	GetBundleStats(ctx context.Context, limit int, packages ...string) ([]*api.BundleStats, error)
 	HealthCheck(ctx context.Context, reconnectTimeout time.Duration) (bool, error)
 	Close() error
 }
@@ -90,6 +105,138 @@ func (c *Client) GetPackage(ctx context.Context, packageName string) (*api.Packa
 	return c.Registry.GetPackage(ctx, &api.GetPackageRequest{Name: packageName})
 }
 
+// BundlesExist splits bundles, named by CSV name, image reference or image digest, into those the registry has and
+// those it doesn't, in a single request
+func (c *Client) BundlesExist(ctx context.Context, bundles []string) (present, missing []string, err error) {
+	res, err := c.Registry.BundlesExist(ctx, &api.BundlesExistRequest{Bundles: bundles})
+	if err != nil {
+		return nil, nil, err
+	}
+	return res.GetPresent(), res.GetMissing(), nil
+}
+
+// ListPackagesPaged returns, in order, up to limit names of the packages that sort after the given name. Passing the
+// last name of each page as the next after pages through all packages.
+func (c *Client) ListPackagesPaged(ctx context.Context, after string, limit int) ([]string, error) {
+	stream, err := c.Registry.ListPackages(ctx, &api.ListPackageRequest{After: after, Limit: int32(limit)})
+	if err != nil {
+		return nil, err
+	}
+	var packages []string
+	for {
+		pkg, err := stream.Recv()
+		if err == io.EOF {
+			return packages, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		packages = append(packages, pkg.GetName())
+	}
+}
+
+// GetPackageStats returns the bundle count, channels, latest version and bundle sizes of the given packages, or of
+// every package if none are given
+func (c *Client) GetPackageStats(ctx context.Context, packages ...string) ([]*api.PackageStats, error) {
+	stream, err := c.Registry.GetPackageStats(ctx, &api.GetPackageStatsRequest{Packages: packages})
+	if err != nil {
+		return nil, err
+	}
+	var stats []*api.PackageStats
+	for {
+		pkg, err := stream.Recv()
+		if err == io.EOF {
+			return stats, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		stats = append(stats, pkg)
+	}
+}
+
+// GetBundleStats returns the size and object counts of the bundles of the given packages, or of every bundle if none
+// are given, largest first, and at most limit of them unless it is zero
+func (c *Client) GetBundleStats(ctx context.Context, limit int, packages ...string) ([]*api.BundleStats, error) {
+	stream, err := c.Registry.GetBundleStats(ctx, &api.GetBundleStatsRequest{Packages: packages, Limit: int32(limit)})
+	if err != nil {
+		return nil, err
+	}
+	var stats []*api.BundleStats
+	for {
+		bundle, err := stream.Recv()
+		if err == io.EOF {
+			return stats, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		stats = append(stats, bundle)
+	}
+}
+
+// GetDefaultChannelHeads returns the head of the default channel of the given packages, or of every package if none
+// are given
+func (c *Client) GetDefaultChannelHeads(ctx context.Context, packages ...string) ([]*api.BundleSummary, error) {
+	stream, err := c.Registry.GetDefaultChannelHeads(ctx, &api.GetDefaultChannelHeadsRequest{Packages: packages})
+	if err != nil {
+		return nil, err
+	}
+	var heads []*api.BundleSummary
+	for {
+		head, err := stream.Recv()
+		if err == io.EOF {
+			return heads, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		heads = append(heads, head)
+	}
+}
+
+// SearchBundles returns the bundles whose name, display name, description or keywords contain every word of the
+// query, best matches first
+func (c *Client) SearchBundles(ctx context.Context, query string) ([]*api.BundleSummary, error) {
+	stream, err := c.Registry.SearchBundles(ctx, &api.SearchBundlesRequest{Query: query})
+	if err != nil {
+		return nil, err
+	}
+	var bundles []*api.BundleSummary
+	for {
+		bundle, err := stream.Recv()
+		if err == io.EOF {
+			return bundles, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		bundles = append(bundles, bundle)
+	}
+}
+
+// GetBundlesForPackage returns a package along with the bundle at the head of each of its channels
+func (c *Client) GetBundlesForPackage(ctx context.Context, packageName string) (*api.PackageBundles, error) {
+	return c.Registry.GetBundlesForPackage(ctx, &api.GetBundlesForPackageRequest{PkgName: packageName})
+}
+
+// Watch streams the changes to the given packages of the catalog, or to every package if none are given, until ctx is
+// done. The registry has to advertise api.FeatureWatch.
+func (c *Client) Watch(ctx context.Context, packages ...string) (api.Registry_WatchClient, error) {
+	return c.Registry.Watch(ctx, &api.WatchRequest{Packages: packages})
+}
+
+// GetAPIVersion returns the api version and features of the registry. A registry that predates GetAPIVersion is
+// reported with an empty version and no features rather than an error, so one client can consume old and new
+// registries alike.
+func (c *Client) GetAPIVersion(ctx context.Context) (*api.APIVersion, error) {
+	version, err := c.Registry.GetAPIVersion(ctx, &api.GetAPIVersionRequest{})
+	if status.Code(err) == codes.Unimplemented {
+		return &api.APIVersion{}, nil
+	}
+	return version, err
+}
+
 func (c *Client) Close() error {
 	if c.Conn == nil {
 		return nil
@@ -115,14 +262,35 @@ func (c *Client) HealthCheck(ctx context.Context, reconnectTimeout time.Duration
 	return true, nil
 }
 
+// NewClient connects to a registry at a host:port, or at a unix domain socket when the address starts with unix://
 func NewClient(address string) (*Client, error) {
-	conn, err := grpc.Dial(address, grpc.WithInsecure())
+	opts := []grpc.DialOption{grpc.WithInsecure()}
+	if strings.HasPrefix(address, unixScheme) {
+		path := strings.TrimPrefix(address, unixScheme)
+		address = "passthrough:///" + path
+		opts = append(opts, grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, "unix", path)
+		}))
+	}
+	conn, err := grpc.Dial(address, opts...)
 	if err != nil {
 		return nil, err
 	}
 	return NewClientFromConn(conn), nil
 }
 
+// NewCatalogClient connects to the named catalog of a registry-server that serves several, at an address as NewClient
+// takes
+func NewCatalogClient(address, catalog string) (*Client, error) {
+	c, err := NewClient(address)
+	if err != nil {
+		return nil, err
+	}
+	c.Registry = api.NewCatalogRegistryClient(c.Conn, catalog)
+	return c, nil
+}
+
 func NewClientFromConn(conn *grpc.ClientConn) *Client {
 	return &Client{
 		Registry: api.NewRegistryClient(conn),
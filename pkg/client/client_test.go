@@ -11,12 +11,15 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 type RegistryClientStub struct {
 	ListBundlesClient api.Registry_ListBundlesClient
 	PackageName       string
 	Package           *api.Package
+	APIVersion        *api.APIVersion
 	Error             error
 }
 
@@ -61,6 +64,38 @@ func (s *RegistryClientStub) ListBundles(ctx context.Context, in *api.ListBundle
 	return s.ListBundlesClient, s.Error
 }
 
+func (s *RegistryClientStub) BundlesExist(ctx context.Context, in *api.BundlesExistRequest, opts ...grpc.CallOption) (*api.BundlesExistResponse, error) {
+	return nil, nil
+}
+
+func (s *RegistryClientStub) GetAPIVersion(ctx context.Context, in *api.GetAPIVersionRequest, opts ...grpc.CallOption) (*api.APIVersion, error) {
+	return s.APIVersion, s.Error
+}
+
+func (s *RegistryClientStub) GetPackageStats(ctx context.Context, in *api.GetPackageStatsRequest, opts ...grpc.CallOption) (api.Registry_GetPackageStatsClient, error) {
+	return nil, nil
+}
+
+func (s *RegistryClientStub) GetDefaultChannelHeads(ctx context.Context, in *api.GetDefaultChannelHeadsRequest, opts ...grpc.CallOption) (api.Registry_GetDefaultChannelHeadsClient, error) {
+	return nil, nil
+}
+
+func (s *RegistryClientStub) SearchBundles(ctx context.Context, in *api.SearchBundlesRequest, opts ...grpc.CallOption) (api.Registry_SearchBundlesClient, error) {
+	return nil, nil
+}
+
+func (s *RegistryClientStub) GetBundlesForPackage(ctx context.Context, in *api.GetBundlesForPackageRequest, opts ...grpc.CallOption) (*api.PackageBundles, error) {
+	return nil, nil
+}
+
+func (s *RegistryClientStub) Watch(ctx context.Context, in *api.WatchRequest, opts ...grpc.CallOption) (api.Registry_WatchClient, error) {
+	return nil, nil
+}
+
+func (s *RegistryClientStub) GetBundleStats(ctx context.Context, in *api.GetBundleStatsRequest, opts ...grpc.CallOption) (api.Registry_GetBundleStatsClient, error) {
+	return nil, nil
+}
+
 func (s *RegistryClientStub) Check(ctx context.Context, in *grpc_health_v1.HealthCheckRequest, opts ...grpc.CallOption) (*grpc_health_v1.HealthCheckResponse, error) {
 	return nil, nil
 }
@@ -170,3 +205,45 @@ func TestGetPackage(t *testing.T) {
 		})
 	}
 }
+
+func TestGetAPIVersion(t *testing.T) {
+	for _, tt := range []struct {
+		Name       string
+		APIVersion *api.APIVersion
+		Error      error
+		Expected   *api.APIVersion
+		ExpectErr  bool
+	}{
+		{
+			Name:       "success",
+			APIVersion: &api.APIVersion{Version: api.Version, Features: []string{api.FeatureStreaming}},
+			Expected:   &api.APIVersion{Version: api.Version, Features: []string{api.FeatureStreaming}},
+		},
+		{
+			Name:     "unimplemented",
+			Error:    status.Error(codes.Unimplemented, "method GetAPIVersion not implemented"),
+			Expected: &api.APIVersion{},
+		},
+		{
+			Name:      "error",
+			Error:     errors.New("test error"),
+			ExpectErr: true,
+		},
+	} {
+		t.Run(tt.Name, func(t *testing.T) {
+			stub := &RegistryClientStub{
+				APIVersion: tt.APIVersion,
+				Error:      tt.Error,
+			}
+			c := Client{Registry: stub, Health: stub}
+			actual, err := c.GetAPIVersion(context.TODO())
+			if tt.ExpectErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.Expected, actual)
+			require.Equal(t, tt.Expected.HasFeature(api.FeatureStreaming), actual.HasFeature(api.FeatureStreaming))
+		})
+	}
+}
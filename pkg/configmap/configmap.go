@@ -1,6 +1,7 @@
 package configmap
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"strings"
@@ -61,7 +62,7 @@ func loadBundle(entry *logrus.Entry, data map[string]string) (bundle *api.Bundle
 			"key": name,
 		})
 
-		resource, decodeErr := registry.DecodeUnstructured(reader)
+		resources, decodeErr := registry.DecodeUnstructuredList(reader)
 		if decodeErr != nil {
 			logger.Infof("skipping due to decode error - %v", decodeErr)
 
@@ -71,16 +72,24 @@ func loadBundle(entry *logrus.Entry, data map[string]string) (bundle *api.Bundle
 			continue
 		}
 
-		if resource.GetKind() == "ClusterServiceVersion" {
-			csvBytes, err := resource.MarshalJSON()
+		// A key may hold several manifests, each is served as an object of its own.
+		for _, resource := range resources {
+			objBytes, err := json.Marshal(resource.Object)
 			if err != nil {
 				return nil, nil, err
 			}
-			bundle.CsvJson = string(csvBytes)
-			bundle.CsvName = resource.GetName()
+
+			if resource.GetKind() == "ClusterServiceVersion" {
+				csvBytes, err := resource.MarshalJSON()
+				if err != nil {
+					return nil, nil, err
+				}
+				bundle.CsvJson = string(csvBytes)
+				bundle.CsvName = resource.GetName()
+			}
+			bundle.Object = append(bundle.Object, string(objBytes))
+			logger.Infof("added to bundle, Kind=%s", resource.GetKind())
 		}
-		bundle.Object = append(bundle.Object, content)
-		logger.Infof("added to bundle, Kind=%s", resource.GetKind())
 	}
 
 	return
@@ -1,6 +1,7 @@
 package configmap
 
 import (
+	"encoding/json"
 	"os"
 	"strings"
 	"testing"
@@ -63,6 +64,23 @@ func TestLoad(t *testing.T) {
 				assert.NotNil(t, csvGot)
 			},
 		},
+		{
+			name:   "BundleWithMultipleManifestsPerKey",
+			source: "testdata/bundle-with-multiple-manifests-per-key.cm.yaml",
+			assertFunc: func(t *testing.T, bundleGot *api.Bundle) {
+				assert.Equal(t, "etcdoperator.v0.6.1", bundleGot.GetCsvName())
+
+				objects := bundleGot.GetObject()
+				// 1 CRD + 1 CSV in a single key, the empty document is left out
+				require.Equal(t, 2, len(objects))
+				for _, obj := range objects {
+					// every object is a json document of its own
+					assert.True(t, json.Valid([]byte(obj)))
+				}
+				assert.Equal(t, "CustomResourceDefinition", getUnstructured(t, objects[0]).GetKind())
+				assert.Equal(t, "ClusterServiceVersion", getUnstructured(t, objects[1]).GetKind())
+			},
+		},
 		{
 			name:   "BundleWithAll",
 			source: "testdata/bundle-with-all.yaml",
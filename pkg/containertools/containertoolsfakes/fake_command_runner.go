@@ -20,6 +20,19 @@ type FakeCommandRunner struct {
 	buildReturnsOnCall map[int]struct {
 		result1 error
 	}
+	BuildFromContextStub        func(string, string, string) error
+	buildFromContextMutex       sync.RWMutex
+	buildFromContextArgsForCall []struct {
+		arg1 string
+		arg2 string
+		arg3 string
+	}
+	buildFromContextReturns struct {
+		result1 error
+	}
+	buildFromContextReturnsOnCall map[int]struct {
+		result1 error
+	}
 	GetToolNameStub        func() string
 	getToolNameMutex       sync.RWMutex
 	getToolNameArgsForCall []struct {
@@ -54,6 +67,17 @@ type FakeCommandRunner struct {
 	pullReturnsOnCall map[int]struct {
 		result1 error
 	}
+	PushStub        func(string) error
+	pushMutex       sync.RWMutex
+	pushArgsForCall []struct {
+		arg1 string
+	}
+	pushReturns struct {
+		result1 error
+	}
+	pushReturnsOnCall map[int]struct {
+		result1 error
+	}
 	SaveStub        func(string, string) error
 	saveMutex       sync.RWMutex
 	saveArgsForCall []struct {
@@ -131,6 +155,68 @@ func (fake *FakeCommandRunner) BuildReturnsOnCall(i int, result1 error) {
 	}{result1}
 }
 
+func (fake *FakeCommandRunner) BuildFromContext(arg1 string, arg2 string, arg3 string) error {
+	fake.buildFromContextMutex.Lock()
+	ret, specificReturn := fake.buildFromContextReturnsOnCall[len(fake.buildFromContextArgsForCall)]
+	fake.buildFromContextArgsForCall = append(fake.buildFromContextArgsForCall, struct {
+		arg1 string
+		arg2 string
+		arg3 string
+	}{arg1, arg2, arg3})
+	fake.recordInvocation("BuildFromContext", []interface{}{arg1, arg2, arg3})
+	fake.buildFromContextMutex.Unlock()
+	if fake.BuildFromContextStub != nil {
+		return fake.BuildFromContextStub(arg1, arg2, arg3)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	fakeReturns := fake.buildFromContextReturns
+	return fakeReturns.result1
+}
+
+func (fake *FakeCommandRunner) BuildFromContextCallCount() int {
+	fake.buildFromContextMutex.RLock()
+	defer fake.buildFromContextMutex.RUnlock()
+	return len(fake.buildFromContextArgsForCall)
+}
+
+func (fake *FakeCommandRunner) BuildFromContextCalls(stub func(string, string, string) error) {
+	fake.buildFromContextMutex.Lock()
+	defer fake.buildFromContextMutex.Unlock()
+	fake.BuildFromContextStub = stub
+}
+
+func (fake *FakeCommandRunner) BuildFromContextArgsForCall(i int) (string, string, string) {
+	fake.buildFromContextMutex.RLock()
+	defer fake.buildFromContextMutex.RUnlock()
+	argsForCall := fake.buildFromContextArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3
+}
+
+func (fake *FakeCommandRunner) BuildFromContextReturns(result1 error) {
+	fake.buildFromContextMutex.Lock()
+	defer fake.buildFromContextMutex.Unlock()
+	fake.BuildFromContextStub = nil
+	fake.buildFromContextReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeCommandRunner) BuildFromContextReturnsOnCall(i int, result1 error) {
+	fake.buildFromContextMutex.Lock()
+	defer fake.buildFromContextMutex.Unlock()
+	fake.BuildFromContextStub = nil
+	if fake.buildFromContextReturnsOnCall == nil {
+		fake.buildFromContextReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.buildFromContextReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
 func (fake *FakeCommandRunner) GetToolName() string {
 	fake.getToolNameMutex.Lock()
 	ret, specificReturn := fake.getToolNameReturnsOnCall[len(fake.getToolNameArgsForCall)]
@@ -306,6 +392,66 @@ func (fake *FakeCommandRunner) PullReturnsOnCall(i int, result1 error) {
 	}{result1}
 }
 
+func (fake *FakeCommandRunner) Push(arg1 string) error {
+	fake.pushMutex.Lock()
+	ret, specificReturn := fake.pushReturnsOnCall[len(fake.pushArgsForCall)]
+	fake.pushArgsForCall = append(fake.pushArgsForCall, struct {
+		arg1 string
+	}{arg1})
+	fake.recordInvocation("Push", []interface{}{arg1})
+	fake.pushMutex.Unlock()
+	if fake.PushStub != nil {
+		return fake.PushStub(arg1)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	fakeReturns := fake.pushReturns
+	return fakeReturns.result1
+}
+
+func (fake *FakeCommandRunner) PushCallCount() int {
+	fake.pushMutex.RLock()
+	defer fake.pushMutex.RUnlock()
+	return len(fake.pushArgsForCall)
+}
+
+func (fake *FakeCommandRunner) PushCalls(stub func(string) error) {
+	fake.pushMutex.Lock()
+	defer fake.pushMutex.Unlock()
+	fake.PushStub = stub
+}
+
+func (fake *FakeCommandRunner) PushArgsForCall(i int) string {
+	fake.pushMutex.RLock()
+	defer fake.pushMutex.RUnlock()
+	argsForCall := fake.pushArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakeCommandRunner) PushReturns(result1 error) {
+	fake.pushMutex.Lock()
+	defer fake.pushMutex.Unlock()
+	fake.PushStub = nil
+	fake.pushReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeCommandRunner) PushReturnsOnCall(i int, result1 error) {
+	fake.pushMutex.Lock()
+	defer fake.pushMutex.Unlock()
+	fake.PushStub = nil
+	if fake.pushReturnsOnCall == nil {
+		fake.pushReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.pushReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
 func (fake *FakeCommandRunner) Save(arg1 string, arg2 string) error {
 	fake.saveMutex.Lock()
 	ret, specificReturn := fake.saveReturnsOnCall[len(fake.saveArgsForCall)]
@@ -372,12 +518,16 @@ func (fake *FakeCommandRunner) Invocations() map[string][][]interface{} {
 	defer fake.invocationsMutex.RUnlock()
 	fake.buildMutex.RLock()
 	defer fake.buildMutex.RUnlock()
+	fake.buildFromContextMutex.RLock()
+	defer fake.buildFromContextMutex.RUnlock()
 	fake.getToolNameMutex.RLock()
 	defer fake.getToolNameMutex.RUnlock()
 	fake.inspectMutex.RLock()
 	defer fake.inspectMutex.RUnlock()
 	fake.pullMutex.RLock()
 	defer fake.pullMutex.RUnlock()
+	fake.pushMutex.RLock()
+	defer fake.pushMutex.RUnlock()
 	fake.saveMutex.RLock()
 	defer fake.saveMutex.RUnlock()
 	copiedInvocations := map[string][][]interface{}{}
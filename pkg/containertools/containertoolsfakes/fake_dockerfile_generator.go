@@ -8,11 +8,12 @@ import (
 )
 
 type FakeDockerfileGenerator struct {
-	GenerateIndexDockerfileStub        func(string, string) string
+	GenerateIndexDockerfileStub        func(string, string, ...containertools.DockerfileOption) string
 	generateIndexDockerfileMutex       sync.RWMutex
 	generateIndexDockerfileArgsForCall []struct {
 		arg1 string
 		arg2 string
+		arg3 []containertools.DockerfileOption
 	}
 	generateIndexDockerfileReturns struct {
 		result1 string
@@ -24,17 +25,18 @@ type FakeDockerfileGenerator struct {
 	invocationsMutex sync.RWMutex
 }
 
-func (fake *FakeDockerfileGenerator) GenerateIndexDockerfile(arg1 string, arg2 string) string {
+func (fake *FakeDockerfileGenerator) GenerateIndexDockerfile(arg1 string, arg2 string, arg3 ...containertools.DockerfileOption) string {
 	fake.generateIndexDockerfileMutex.Lock()
 	ret, specificReturn := fake.generateIndexDockerfileReturnsOnCall[len(fake.generateIndexDockerfileArgsForCall)]
 	fake.generateIndexDockerfileArgsForCall = append(fake.generateIndexDockerfileArgsForCall, struct {
 		arg1 string
 		arg2 string
-	}{arg1, arg2})
-	fake.recordInvocation("GenerateIndexDockerfile", []interface{}{arg1, arg2})
+		arg3 []containertools.DockerfileOption
+	}{arg1, arg2, arg3})
+	fake.recordInvocation("GenerateIndexDockerfile", []interface{}{arg1, arg2, arg3})
 	fake.generateIndexDockerfileMutex.Unlock()
 	if fake.GenerateIndexDockerfileStub != nil {
-		return fake.GenerateIndexDockerfileStub(arg1, arg2)
+		return fake.GenerateIndexDockerfileStub(arg1, arg2, arg3...)
 	}
 	if specificReturn {
 		return ret.result1
@@ -49,17 +51,17 @@ func (fake *FakeDockerfileGenerator) GenerateIndexDockerfileCallCount() int {
 	return len(fake.generateIndexDockerfileArgsForCall)
 }
 
-func (fake *FakeDockerfileGenerator) GenerateIndexDockerfileCalls(stub func(string, string) string) {
+func (fake *FakeDockerfileGenerator) GenerateIndexDockerfileCalls(stub func(string, string, ...containertools.DockerfileOption) string) {
 	fake.generateIndexDockerfileMutex.Lock()
 	defer fake.generateIndexDockerfileMutex.Unlock()
 	fake.GenerateIndexDockerfileStub = stub
 }
 
-func (fake *FakeDockerfileGenerator) GenerateIndexDockerfileArgsForCall(i int) (string, string) {
+func (fake *FakeDockerfileGenerator) GenerateIndexDockerfileArgsForCall(i int) (string, string, []containertools.DockerfileOption) {
 	fake.generateIndexDockerfileMutex.RLock()
 	defer fake.generateIndexDockerfileMutex.RUnlock()
 	argsForCall := fake.generateIndexDockerfileArgsForCall[i]
-	return argsForCall.arg1, argsForCall.arg2
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3
 }
 
 func (fake *FakeDockerfileGenerator) GenerateIndexDockerfileReturns(result1 string) {
@@ -2,20 +2,120 @@
 package containertools
 
 import (
+	"bytes"
 	"fmt"
+	"sort"
+	"strings"
+	"text/template"
 
 	"github.com/sirupsen/logrus"
 )
 
 const (
-	defaultBinarySourceImage = "quay.io/operator-framework/upstream-opm-builder"
+	DefaultBinarySourceImage = "quay.io/operator-framework/upstream-opm-builder"
 	DefaultDbLocation        = "/database/index.db"
 	DbLocationLabel          = "operators.operatorframework.io.index.database.v1"
 )
 
+// IndexDockerfileTemplate is the text/template an index dockerfile is rendered from. It is executed with an
+// IndexDockerfile and the functions of DockerfileFuncs.
+const IndexDockerfileTemplate = `FROM {{ .BinarySourceImage }}
+{{- range $key := sortedKeys .BuildArgs }}
+ARG {{ $key }}={{ quote (index $.BuildArgs $key) }}
+{{- end }}
+LABEL {{ .DbLocationLabel }}={{ .DbLocation }}
+{{- range $key := sortedKeys .Labels }}
+LABEL {{ quote $key }}={{ quote (index $.Labels $key) }}
+{{- end }}
+ADD {{ .DatabasePath }} {{ .DbLocation }}
+EXPOSE 50051
+ENTRYPOINT ["/bin/opm"]
+CMD ["registry", "serve", "--database", "{{ .DbLocation }}"]
+`
+
+var indexDockerfileTemplate = template.Must(template.New("index.Dockerfile").Funcs(DockerfileFuncs()).Parse(IndexDockerfileTemplate))
+
+// IndexDockerfile holds the values an index dockerfile template is rendered with
+type IndexDockerfile struct {
+	BinarySourceImage string
+	DatabasePath      string
+	DbLocation        string
+	DbLocationLabel   string
+	Labels            map[string]string
+	BuildArgs         map[string]string
+}
+
+// DockerfileFuncs returns the functions available to dockerfile templates: quote renders a value as a dockerfile
+// string, and sortedKeys returns the keys of a map in order so the rendered dockerfile is stable.
+func DockerfileFuncs() template.FuncMap {
+	return template.FuncMap{
+		"quote":      quoteDockerfileValue,
+		"sortedKeys": sortedKeys,
+	}
+}
+
+// quoteDockerfileValue double quotes a value when it can't be written bare. Variables such as ${BUILD_DATE} are
+// still expanded by the builder inside double quotes.
+func quoteDockerfileValue(value string) string {
+	if value != "" && !strings.ContainsAny(value, " \t\"'\\=#") {
+		return value
+	}
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(value) + `"`
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// ParseKeyValues parses key=value pairs, such as the labels and build args given on the command line, into a map
+func ParseKeyValues(pairs []string) (map[string]string, error) {
+	values := map[string]string{}
+	for _, pair := range pairs {
+		split := strings.SplitN(pair, "=", 2)
+		if len(split) != 2 || split[0] == "" {
+			return nil, fmt.Errorf("%q must be of the form key=value", pair)
+		}
+		if strings.ContainsAny(split[0], " \t\r\n\"'\\=") {
+			return nil, fmt.Errorf("key %q must not contain whitespace, quotes, backslashes or '='", split[0])
+		}
+		if strings.ContainsAny(split[1], "\r\n") {
+			return nil, fmt.Errorf("value of %q must not contain line breaks", split[0])
+		}
+		values[split[0]] = split[1]
+	}
+	return values, nil
+}
+
+// DockerfileOptions are the extra values rendered into a generated dockerfile
+type DockerfileOptions struct {
+	Labels    map[string]string
+	BuildArgs map[string]string
+}
+
+type DockerfileOption func(*DockerfileOptions)
+
+// WithLabels adds LABEL instructions, such as the OCI image annotations, to the dockerfile
+func WithLabels(labels map[string]string) DockerfileOption {
+	return func(o *DockerfileOptions) {
+		o.Labels = labels
+	}
+}
+
+// WithBuildArgs adds ARG instructions with default values to the dockerfile, which labels may reference
+func WithBuildArgs(buildArgs map[string]string) DockerfileOption {
+	return func(o *DockerfileOptions) {
+		o.BuildArgs = buildArgs
+	}
+}
+
 // DockerfileGenerator defines functions to generate index dockerfiles
 type DockerfileGenerator interface {
-	GenerateIndexDockerfile(string, string) string
+	GenerateIndexDockerfile(string, string, ...DockerfileOption) string
 }
 
 // IndexDockerfileGenerator struct implementation of DockerfileGenerator interface
@@ -32,26 +132,41 @@ func NewDockerfileGenerator(logger *logrus.Entry) DockerfileGenerator {
 
 // GenerateIndexDockerfile builds a string representation of a dockerfile to use when building
 // an operator-registry index image
-func (g *IndexDockerfileGenerator) GenerateIndexDockerfile(binarySourceImage, databasePath string) string {
-	var dockerfile string
+func (g *IndexDockerfileGenerator) GenerateIndexDockerfile(binarySourceImage, databasePath string, opts ...DockerfileOption) string {
+	options := DockerfileOptions{}
+	for _, opt := range opts {
+		opt(&options)
+	}
 
 	if binarySourceImage == "" {
-		binarySourceImage = defaultBinarySourceImage
+		binarySourceImage = DefaultBinarySourceImage
 	}
 
-	g.Logger.Info("Generating dockerfile")
-
-	// From
-	dockerfile += fmt.Sprintf("FROM %s\n", binarySourceImage)
+	// the database location label is what serves the index, so it can't be overridden
+	labels := map[string]string{}
+	for key, value := range options.Labels {
+		if key == DbLocationLabel {
+			g.Logger.Warnf("ignoring label %s, it is set by the index", key)
+			continue
+		}
+		labels[key] = value
+	}
 
-	// Labels
-	dockerfile += fmt.Sprintf("LABEL %s=%s\n", DbLocationLabel, DefaultDbLocation)
+	g.Logger.Info("Generating dockerfile")
 
-	// Content
-	dockerfile += fmt.Sprintf("ADD %s %s\n", databasePath, DefaultDbLocation)
-	dockerfile += fmt.Sprintf("EXPOSE 50051\n")
-	dockerfile += fmt.Sprintf("ENTRYPOINT [\"/bin/opm\"]\n")
-	dockerfile += fmt.Sprintf("CMD [\"registry\", \"serve\", \"--database\", \"%s\"]\n", DefaultDbLocation)
+	var dockerfile bytes.Buffer
+	err := indexDockerfileTemplate.Execute(&dockerfile, IndexDockerfile{
+		BinarySourceImage: binarySourceImage,
+		DatabasePath:      databasePath,
+		DbLocation:        DefaultDbLocation,
+		DbLocationLabel:   DbLocationLabel,
+		Labels:            labels,
+		BuildArgs:         options.BuildArgs,
+	})
+	if err != nil {
+		// the template is fixed and its functions can't fail, so this is a programming error
+		panic(fmt.Sprintf("rendering index dockerfile: %s", err))
+	}
 
-	return dockerfile
+	return dockerfile.String()
 }
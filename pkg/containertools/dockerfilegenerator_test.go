@@ -56,3 +56,49 @@ CMD ["registry", "serve", "--database", "/database/index.db"]
 	dockerfile := dockerfileGenerator.GenerateIndexDockerfile("", databasePath)
 	require.Equal(t, dockerfile, expectedDockerfile)
 }
+
+func TestGenerateDockerfile_LabelsAndBuildArgs(t *testing.T) {
+	databasePath := "database/index.db"
+	expectedDockerfile := `FROM quay.io/operator-framework/builder
+ARG BUILD_DATE=""
+ARG RELEASE=1
+LABEL operators.operatorframework.io.index.database.v1=/database/index.db
+LABEL org.opencontainers.image.created=${BUILD_DATE}
+LABEL org.opencontainers.image.vendor="Example \"Corp\""
+LABEL release=${RELEASE}
+ADD database/index.db /database/index.db
+EXPOSE 50051
+ENTRYPOINT ["/bin/opm"]
+CMD ["registry", "serve", "--database", "/database/index.db"]
+`
+
+	labels, err := containertools.ParseKeyValues([]string{
+		"release=${RELEASE}",
+		`org.opencontainers.image.vendor=Example "Corp"`,
+		"org.opencontainers.image.created=${BUILD_DATE}",
+		"operators.operatorframework.io.index.database.v1=/elsewhere.db",
+	})
+	require.NoError(t, err)
+	buildArgs, err := containertools.ParseKeyValues([]string{"RELEASE=1", "BUILD_DATE="})
+	require.NoError(t, err)
+
+	dockerfileGenerator := containertools.IndexDockerfileGenerator{
+		Logger: logrus.NewEntry(logrus.New()),
+	}
+
+	// the database location label can't be overridden
+	dockerfile := dockerfileGenerator.GenerateIndexDockerfile("quay.io/operator-framework/builder", databasePath,
+		containertools.WithLabels(labels), containertools.WithBuildArgs(buildArgs))
+	require.Equal(t, expectedDockerfile, dockerfile)
+}
+
+func TestParseKeyValues(t *testing.T) {
+	values, err := containertools.ParseKeyValues([]string{"vendor=Example", "url=https://example.com/?a=b", "empty="})
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"vendor": "Example", "url": "https://example.com/?a=b", "empty": ""}, values)
+
+	for _, invalid := range []string{"vendor", "=Example", "a key=value", "key=line\nbreak"} {
+		_, err := containertools.ParseKeyValues([]string{invalid})
+		require.Error(t, err, invalid)
+	}
+}
@@ -14,7 +14,9 @@ type CommandRunner interface {
 	GetToolName() string
 	Pull(image string) error
 	Build(dockerfile, tag string) error
+	BuildFromContext(dockerfile, tag, buildContext string) error
 	Inspect(image string) ([]byte, error)
+	Push(image string) error
 }
 
 // ContainerCommandRunner is configured to select a container cli tool and
@@ -96,14 +98,37 @@ func (r *ContainerCommandRunner) Pull(image string) error {
 	return nil
 }
 
+// Push takes a container image path and runs the push command to upload a local image to the container registry it
+// names
+func (r *ContainerCommandRunner) Push(image string) error {
+	args := r.argsForCmd("push", image)
+
+	command := exec.Command(r.containerTool.String(), args...)
+
+	r.logger.Infof("running %s", command.String())
+
+	out, err := command.CombinedOutput()
+	if err != nil {
+		r.logger.Errorf(string(out))
+		return fmt.Errorf("error pushing image: %s. %v", string(out), err)
+	}
+
+	return nil
+}
+
 // Build takes a dockerfile and a tag and builds a container image
 func (r *ContainerCommandRunner) Build(dockerfile, tag string) error {
+	return r.BuildFromContext(dockerfile, tag, ".")
+}
+
+// BuildFromContext builds a container image from the dockerfile, sending the given directory as the build context.
+func (r *ContainerCommandRunner) BuildFromContext(dockerfile, tag, buildContext string) error {
 	o := DefaultBuildOptions()
 	if tag != "" {
 		o.AddTag(tag)
 	}
 	o.SetDockerfile(dockerfile)
-	o.SetContext(".")
+	o.SetContext(buildContext)
 	command, err := r.containerTool.CommandFactory().BuildCommand(o)
 	if err != nil {
 		return fmt.Errorf("unable to perform build: %v", err)
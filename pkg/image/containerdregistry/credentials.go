@@ -0,0 +1,284 @@
+package containerdregistry
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/docker/docker-credential-helpers/client"
+)
+
+const (
+	// ServiceAccountTokenPath is where the token of a pod's service account is mounted
+	ServiceAccountTokenPath = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+
+	gcrTokenURL      = "http://metadata.google.internal/computeMetadata/v1/instance/service-accounts/default/token"
+	azureTokenURL    = "http://169.254.169.254/metadata/identity/oauth2/token?api-version=2018-02-01&resource=https%3A%2F%2Fmanagement.azure.com%2F"
+	acrTokenUsername = "00000000-0000-0000-0000-000000000000"
+	ecrHelper        = "docker-credential-ecr-login"
+
+	// credentials are refreshed this long before they expire
+	expiryMargin = time.Minute
+)
+
+var ecrHost = regexp.MustCompile(`^\d{12}\.dkr\.ecr(-fips)?\.[a-z0-9-]+\.amazonaws\.com(\.cn)?$`)
+
+// Credential is a username and secret for a registry. A zero Expiry means the credential isn't cached.
+type Credential struct {
+	Username string
+	Secret   string
+	Expiry   time.Time
+}
+
+// CredentialProvider supplies registry credentials from the environment opm runs in, such as the service account
+// of its pod or the metadata service of its cloud, so that pulls need no long-lived pull secret.
+type CredentialProvider interface {
+	// Matches returns true if the provider supplies credentials for the registry host
+	Matches(host string) bool
+	// Credential returns credentials for the registry host
+	Credential(ctx context.Context, host string) (*Credential, error)
+}
+
+// ParseCredentialProviders returns the credential providers named by specs. A spec is one of serviceaccount:<host>
+// for the token of the pod's service account, gcr for gcr.io and Artifact Registry, acr for Azure Container Registry,
+// or ecr for Amazon ECR.
+func ParseCredentialProviders(specs []string) ([]CredentialProvider, error) {
+	var providers []CredentialProvider
+	var hosts []string
+	for _, spec := range specs {
+		name, arg := spec, ""
+		if i := strings.Index(spec, ":"); i >= 0 {
+			name, arg = spec[:i], spec[i+1:]
+		}
+		switch name {
+		case "serviceaccount":
+			if arg == "" {
+				return nil, fmt.Errorf("credential provider %q must name a registry host, as serviceaccount:<host>", spec)
+			}
+			hosts = append(hosts, arg)
+			continue
+		case "gcr":
+			providers = append(providers, GCRCredentials())
+		case "acr":
+			providers = append(providers, ACRCredentials())
+		case "ecr":
+			providers = append(providers, ECRCredentials())
+		default:
+			return nil, fmt.Errorf("unknown credential provider %q", spec)
+		}
+		if arg != "" {
+			return nil, fmt.Errorf("credential provider %q takes no arguments", name)
+		}
+	}
+	if len(hosts) > 0 {
+		providers = append([]CredentialProvider{ServiceAccountCredentials(hosts...)}, providers...)
+	}
+	return providers, nil
+}
+
+// ServiceAccountCredentials returns a provider that authenticates to the given registry hosts, such as an
+// in-cluster registry, with the token of the pod's service account
+func ServiceAccountCredentials(hosts ...string) CredentialProvider {
+	return &serviceAccountProvider{tokenPath: ServiceAccountTokenPath, hosts: hosts}
+}
+
+type serviceAccountProvider struct {
+	tokenPath string
+	hosts     []string
+}
+
+func (p *serviceAccountProvider) Matches(host string) bool {
+	for _, h := range p.hosts {
+		if h == host {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *serviceAccountProvider) Credential(ctx context.Context, host string) (*Credential, error) {
+	// the token is read every time, since projected tokens are rotated on disk
+	token, err := ioutil.ReadFile(p.tokenPath)
+	if err != nil {
+		return nil, fmt.Errorf("error reading service account token: %v", err)
+	}
+	return &Credential{Username: "serviceaccount", Secret: strings.TrimSpace(string(token))}, nil
+}
+
+// GCRCredentials returns a provider that authenticates to gcr.io and Artifact Registry with an access token of the
+// instance's service account, taken from the GCE metadata server
+func GCRCredentials() CredentialProvider {
+	return &gcrProvider{client: http.DefaultClient, tokenURL: gcrTokenURL}
+}
+
+type gcrProvider struct {
+	client   *http.Client
+	tokenURL string
+}
+
+func (p *gcrProvider) Matches(host string) bool {
+	return host == "gcr.io" || strings.HasSuffix(host, ".gcr.io") || strings.HasSuffix(host, "-docker.pkg.dev")
+}
+
+func (p *gcrProvider) Credential(ctx context.Context, host string) (*Credential, error) {
+	req, err := http.NewRequest(http.MethodGet, p.tokenURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Metadata-Flavor", "Google")
+
+	var token struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int64  `json:"expires_in"`
+	}
+	if err := doJSON(ctx, p.client, req, &token); err != nil {
+		return nil, fmt.Errorf("error getting token from the metadata server: %v", err)
+	}
+	return &Credential{
+		Username: "oauth2accesstoken",
+		Secret:   token.AccessToken,
+		Expiry:   time.Now().Add(time.Duration(token.ExpiresIn) * time.Second),
+	}, nil
+}
+
+// ACRCredentials returns a provider that authenticates to Azure Container Registry by exchanging a token of the
+// instance's managed identity, taken from the Azure instance metadata service, for a registry refresh token
+func ACRCredentials() CredentialProvider {
+	return &acrProvider{client: http.DefaultClient, tokenURL: azureTokenURL, scheme: "https"}
+}
+
+type acrProvider struct {
+	client   *http.Client
+	tokenURL string
+	scheme   string
+}
+
+func (p *acrProvider) Matches(host string) bool {
+	return strings.HasSuffix(host, ".azurecr.io")
+}
+
+func (p *acrProvider) Credential(ctx context.Context, host string) (*Credential, error) {
+	req, err := http.NewRequest(http.MethodGet, p.tokenURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Metadata", "true")
+
+	var token struct {
+		AccessToken string `json:"access_token"`
+		ExpiresOn   string `json:"expires_on"`
+	}
+	if err := doJSON(ctx, p.client, req, &token); err != nil {
+		return nil, fmt.Errorf("error getting token from the instance metadata service: %v", err)
+	}
+
+	form := url.Values{
+		"grant_type":   {"access_token"},
+		"service":      {host},
+		"access_token": {token.AccessToken},
+	}
+	req, err = http.NewRequest(http.MethodPost, fmt.Sprintf("%s://%s/oauth2/exchange", p.scheme, host), strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	var exchanged struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+	if err := doJSON(ctx, p.client, req, &exchanged); err != nil {
+		return nil, fmt.Errorf("error exchanging token with %s: %v", host, err)
+	}
+
+	// the refresh token is valid for as long as the token it was exchanged for
+	credential := &Credential{Username: acrTokenUsername, Secret: exchanged.RefreshToken}
+	if expiresOn, err := strconv.ParseInt(token.ExpiresOn, 10, 64); err == nil {
+		credential.Expiry = time.Unix(expiresOn, 0)
+	}
+	return credential, nil
+}
+
+// ECRCredentials returns a provider that authenticates to Amazon ECR through the docker-credential-ecr-login helper,
+// which takes credentials from the environment, such as an IAM role for the pod's service account
+func ECRCredentials() CredentialProvider {
+	return &ecrProvider{program: client.NewShellProgramFunc(ecrHelper)}
+}
+
+type ecrProvider struct {
+	program client.ProgramFunc
+}
+
+func (p *ecrProvider) Matches(host string) bool {
+	return ecrHost.MatchString(host)
+}
+
+func (p *ecrProvider) Credential(ctx context.Context, host string) (*Credential, error) {
+	// the helper caches the tokens it gets itself
+	creds, err := client.Get(p.program, host)
+	if err != nil {
+		return nil, fmt.Errorf("error getting credentials from %s: %v", ecrHelper, err)
+	}
+	return &Credential{Username: creds.Username, Secret: creds.Secret}, nil
+}
+
+func doJSON(ctx context.Context, c *http.Client, req *http.Request, out interface{}) error {
+	resp, err := c.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("unexpected status %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// credentialCache asks the first matching provider for a registry's credentials and keeps them until they expire
+type credentialCache struct {
+	providers []CredentialProvider
+	timeout   time.Duration
+
+	mu          sync.Mutex
+	credentials map[string]*Credential
+}
+
+func newCredentialCache(providers []CredentialProvider) *credentialCache {
+	return &credentialCache{providers: providers, timeout: 30 * time.Second, credentials: map[string]*Credential{}}
+}
+
+// get returns nil when no provider matches the host
+func (c *credentialCache) get(host string) (*Credential, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if credential, ok := c.credentials[host]; ok && time.Now().Add(expiryMargin).Before(credential.Expiry) {
+		return credential, nil
+	}
+
+	for _, provider := range c.providers {
+		if !provider.Matches(host) {
+			continue
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+		defer cancel()
+		credential, err := provider.Credential(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+		if !credential.Expiry.IsZero() {
+			c.credentials[host] = credential
+		}
+		return credential, nil
+	}
+	return nil, nil
+}
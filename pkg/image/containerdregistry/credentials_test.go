@@ -0,0 +1,152 @@
+package containerdregistry
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCredentialProviders(t *testing.T) {
+	providers, err := ParseCredentialProviders(nil)
+	require.NoError(t, err)
+	require.Empty(t, providers)
+
+	providers, err = ParseCredentialProviders([]string{"gcr", "serviceaccount:registry.svc:5000", "ecr", "serviceaccount:other.svc", "acr"})
+	require.NoError(t, err)
+	require.Len(t, providers, 4)
+
+	// service account hosts are matched before any cloud registry
+	require.True(t, providers[0].Matches("registry.svc:5000"))
+	require.True(t, providers[0].Matches("other.svc"))
+	require.True(t, providers[1].Matches("gcr.io"))
+	require.True(t, providers[1].Matches("eu.gcr.io"))
+	require.True(t, providers[1].Matches("us-central1-docker.pkg.dev"))
+	require.True(t, providers[2].Matches("123456789012.dkr.ecr.us-east-1.amazonaws.com"))
+	require.False(t, providers[2].Matches("public.ecr.aws"))
+	require.True(t, providers[3].Matches("example.azurecr.io"))
+	require.False(t, providers[3].Matches("quay.io"))
+
+	for _, invalid := range []string{"serviceaccount", "serviceaccount:", "gcr:host", "unknown"} {
+		_, err := ParseCredentialProviders([]string{invalid})
+		require.Error(t, err, invalid)
+	}
+}
+
+func TestServiceAccountCredentials(t *testing.T) {
+	dir, err := ioutil.TempDir("", "serviceaccount-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	provider := &serviceAccountProvider{tokenPath: filepath.Join(dir, "token"), hosts: []string{"registry.svc"}}
+	_, err = provider.Credential(context.TODO(), "registry.svc")
+	require.Error(t, err)
+
+	// a rotated token is picked up
+	for _, token := range []string{"first", "second"} {
+		require.NoError(t, ioutil.WriteFile(provider.tokenPath, []byte(token+"\n"), 0600))
+		credential, err := provider.Credential(context.TODO(), "registry.svc")
+		require.NoError(t, err)
+		require.Equal(t, &Credential{Username: "serviceaccount", Secret: token}, credential)
+	}
+}
+
+func TestGCRCredentials(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Metadata-Flavor") != "Google" {
+			http.Error(w, "missing metadata header", http.StatusForbidden)
+			return
+		}
+		fmt.Fprint(w, `{"access_token":"gcp-token","expires_in":3600,"token_type":"Bearer"}`)
+	}))
+	defer server.Close()
+
+	provider := &gcrProvider{client: server.Client(), tokenURL: server.URL}
+	credential, err := provider.Credential(context.TODO(), "gcr.io")
+	require.NoError(t, err)
+	require.Equal(t, "oauth2accesstoken", credential.Username)
+	require.Equal(t, "gcp-token", credential.Secret)
+	require.WithinDuration(t, time.Now().Add(time.Hour), credential.Expiry, time.Minute)
+}
+
+func TestACRCredentials(t *testing.T) {
+	expiresOn := time.Now().Add(time.Hour).Unix()
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/metadata/identity/oauth2/token":
+			if r.Header.Get("Metadata") != "true" {
+				http.Error(w, "missing metadata header", http.StatusBadRequest)
+				return
+			}
+			fmt.Fprintf(w, `{"access_token":"aad-token","expires_on":"%d"}`, expiresOn)
+		case "/oauth2/exchange":
+			require.NoError(t, r.ParseForm())
+			require.Equal(t, "access_token", r.PostForm.Get("grant_type"))
+			require.Equal(t, strings.TrimPrefix(server.URL, "http://"), r.PostForm.Get("service"))
+			if r.PostForm.Get("access_token") != "aad-token" {
+				http.Error(w, "invalid token", http.StatusUnauthorized)
+				return
+			}
+			fmt.Fprint(w, `{"refresh_token":"acr-refresh-token"}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	provider := &acrProvider{client: server.Client(), tokenURL: server.URL + "/metadata/identity/oauth2/token", scheme: "http"}
+	credential, err := provider.Credential(context.TODO(), strings.TrimPrefix(server.URL, "http://"))
+	require.NoError(t, err)
+	require.Equal(t, &Credential{Username: acrTokenUsername, Secret: "acr-refresh-token", Expiry: time.Unix(expiresOn, 0)}, credential)
+}
+
+type countingProvider struct {
+	calls  int
+	expiry time.Duration
+}
+
+func (p *countingProvider) Matches(host string) bool {
+	return host == "registry.example.com"
+}
+
+func (p *countingProvider) Credential(ctx context.Context, host string) (*Credential, error) {
+	p.calls++
+	credential := &Credential{Username: "user", Secret: fmt.Sprintf("secret-%d", p.calls)}
+	if p.expiry != 0 {
+		credential.Expiry = time.Now().Add(p.expiry)
+	}
+	return credential, nil
+}
+
+func TestCredentialCache(t *testing.T) {
+	lasting := &countingProvider{expiry: time.Hour}
+	cache := newCredentialCache([]CredentialProvider{lasting})
+
+	credential, err := cache.get("quay.io")
+	require.NoError(t, err)
+	require.Nil(t, credential)
+
+	// credentials are kept until they are about to expire
+	for i := 0; i < 2; i++ {
+		credential, err = cache.get("registry.example.com")
+		require.NoError(t, err)
+		require.Equal(t, "secret-1", credential.Secret)
+	}
+
+	expiring := &countingProvider{expiry: expiryMargin / 2}
+	cache = newCredentialCache([]CredentialProvider{expiring})
+	for i := 1; i <= 2; i++ {
+		credential, err = cache.get("registry.example.com")
+		require.NoError(t, err)
+		require.Equal(t, fmt.Sprintf("secret-%d", i), credential.Secret)
+	}
+}
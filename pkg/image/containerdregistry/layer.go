@@ -0,0 +1,84 @@
+package containerdregistry
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os/exec"
+
+	"github.com/containerd/containerd/archive"
+	"github.com/containerd/containerd/archive/compression"
+)
+
+// zstdMagic starts every zstd frame
+var zstdMagic = []byte{0x28, 0xB5, 0x2F, 0xFD}
+
+// applyLayer unpacks a layer on top of the layers already unpacked into dir. The layer may be uncompressed, gzip or
+// zstd compressed, and its whiteouts remove files unpacked from earlier layers.
+func applyLayer(ctx context.Context, layer io.Reader, dir string) error {
+	decompressed, err := decompressLayer(ctx, layer)
+	if err != nil {
+		return err
+	}
+	defer decompressed.Close()
+
+	_, err = archive.Apply(ctx, dir, decompressed, archive.WithFilter(adjustPerms))
+	return err
+}
+
+// decompressLayer detects the compression of a layer from its content rather than its media type, since registries
+// and build tools don't always agree on the media type.
+func decompressLayer(ctx context.Context, layer io.Reader) (io.ReadCloser, error) {
+	buf := bufio.NewReader(layer)
+	magic, err := buf.Peek(len(zstdMagic))
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	if bytes.Equal(magic, zstdMagic) {
+		return zstdDecompress(ctx, buf)
+	}
+
+	// containerd handles uncompressed and gzip compressed layers
+	return compression.DecompressStream(buf)
+}
+
+func zstdDecompress(ctx context.Context, layer io.Reader) (io.ReadCloser, error) {
+	path, err := exec.LookPath("zstd")
+	if err != nil {
+		return nil, fmt.Errorf("layer is zstd compressed, unpacking it requires zstd on the PATH: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	cmd := exec.CommandContext(ctx, path, "-d", "-c")
+	reader, writer := io.Pipe()
+	var stderr bytes.Buffer
+	cmd.Stdin = layer
+	cmd.Stdout = writer
+	cmd.Stderr = &stderr
+	if err := cmd.Start(); err != nil {
+		cancel()
+		return nil, err
+	}
+	go func() {
+		if err := cmd.Wait(); err != nil {
+			writer.CloseWithError(fmt.Errorf("error decompressing zstd layer: %v: %s", err, stderr.String()))
+			return
+		}
+		writer.Close()
+	}()
+
+	return &zstdReadCloser{PipeReader: reader, cancel: cancel}, nil
+}
+
+type zstdReadCloser struct {
+	*io.PipeReader
+	cancel context.CancelFunc
+}
+
+func (z *zstdReadCloser) Close() error {
+	// stops zstd if the layer wasn't read to the end
+	z.cancel()
+	return z.PipeReader.Close()
+}
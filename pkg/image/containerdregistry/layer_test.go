@@ -0,0 +1,99 @@
+package containerdregistry
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// layer returns an uncompressed layer holding the given files
+func layer(t *testing.T, files map[string]string) []byte {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for name, content := range files {
+		require.NoError(t, tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(content)), Typeflag: tar.TypeReg}))
+		_, err := tw.Write([]byte(content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.Close())
+	return buf.Bytes()
+}
+
+func gzipLayer(t *testing.T, files map[string]string) []byte {
+	var buf bytes.Buffer
+	gw := gzip.NewWriter(&buf)
+	_, err := gw.Write(layer(t, files))
+	require.NoError(t, err)
+	require.NoError(t, gw.Close())
+	return buf.Bytes()
+}
+
+func zstdLayer(t *testing.T, files map[string]string) []byte {
+	cmd := exec.Command("zstd", "-c")
+	cmd.Stdin = bytes.NewReader(layer(t, files))
+	out, err := cmd.Output()
+	require.NoError(t, err)
+	return out
+}
+
+func TestApplyLayers(t *testing.T) {
+	dir, err := ioutil.TempDir("", "layers-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	layers := [][]byte{
+		gzipLayer(t, map[string]string{
+			"manifests/csv.yaml":     "csv",
+			"manifests/old-crd.yaml": "old crd",
+			"metadata/notes.txt":     "notes",
+		}),
+		// a later layer removes a file and a directory of an earlier one
+		layer(t, map[string]string{
+			"manifests/.wh.old-crd.yaml": "",
+			".wh.metadata":               "",
+			"manifests/crd.yaml":         "crd",
+		}),
+	}
+	if _, err := exec.LookPath("zstd"); err == nil {
+		layers = append(layers, zstdLayer(t, map[string]string{"metadata/annotations.yaml": "annotations"}))
+	} else {
+		t.Log("zstd not found, not testing zstd compressed layers")
+	}
+
+	for _, l := range layers {
+		require.NoError(t, applyLayer(context.TODO(), bytes.NewReader(l), dir))
+	}
+
+	expected := map[string]string{
+		"manifests/csv.yaml": "csv",
+		"manifests/crd.yaml": "crd",
+	}
+	if len(layers) > 2 {
+		expected["metadata/annotations.yaml"] = "annotations"
+	}
+	actual := map[string]string{}
+	require.NoError(t, filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		content, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		actual[filepath.ToSlash(rel)] = string(content)
+		return nil
+	}))
+	require.Equal(t, expected, actual)
+}
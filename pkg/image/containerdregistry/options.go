@@ -23,6 +23,7 @@ type RegistryConfig struct {
 	PreserveCache     bool
 	SkipTLS           bool
 	Roots             *x509.CertPool
+	Credentials       []CredentialProvider
 }
 
 func (r *RegistryConfig) apply(options []RegistryOption) {
@@ -90,7 +91,7 @@ func NewRegistry(options ...RegistryOption) (registry *Registry, err error) {
 	}
 
 	var resolver remotes.Resolver
-	resolver, err = NewResolver(config.ResolverConfigDir, config.SkipTLS, config.Roots)
+	resolver, err = NewResolver(config.ResolverConfigDir, config.SkipTLS, config.Roots, config.Credentials...)
 	if err != nil {
 		return
 	}
@@ -145,3 +146,10 @@ func SkipTLS(skip bool) RegistryOption {
 		config.SkipTLS = skip
 	}
 }
+
+// WithCredentialProviders sets providers for the credentials of registries that have none in the docker config
+func WithCredentialProviders(providers ...CredentialProvider) RegistryOption {
+	return func(config *RegistryConfig) {
+		config.Credentials = append(config.Credentials, providers...)
+	}
+}
@@ -9,7 +9,6 @@ import (
 	"io"
 	"os"
 
-	"github.com/containerd/containerd/archive"
 	"github.com/containerd/containerd/archive/compression"
 	"github.com/containerd/containerd/errdefs"
 	"github.com/containerd/containerd/images"
@@ -66,6 +65,27 @@ func (r *Registry) Pull(ctx context.Context, ref image.Reference) error {
 	return err
 }
 
+// Push uploads an image that was pulled to the remote registry of another reference. Its manifest is pushed after
+// the content it refers to, so the reference names the whole image or is left as it was.
+func (r *Registry) Push(ctx context.Context, ref, to image.Reference) error {
+	// Set the default namespace if unset
+	ctx = ensureNamespace(ctx)
+
+	img, err := r.Images().Get(ctx, ref.String())
+	if err != nil {
+		return err
+	}
+	pusher, err := r.resolver.Pusher(ctx, to.String())
+	if err != nil {
+		return err
+	}
+	r.log.Infof("pushing %s to %s", ref.String(), to.String())
+	if err := remotes.PushContent(ctx, pusher, img.Target, r.Content(), platforms.All, nil); err != nil {
+		return fmt.Errorf("error pushing %s: %v", to.String(), err)
+	}
+	return nil
+}
+
 // Unpack writes the unpackaged content of an image to a directory.
 // If the referenced image does not exist in the registry, an error is returned.
 func (r *Registry) Unpack(ctx context.Context, ref image.Reference, dir string) error {
@@ -108,6 +128,30 @@ func (r *Registry) Labels(ctx context.Context, ref image.Reference) (map[string]
 	return imageConfig.Config.Labels, nil
 }
 
+// Resolve returns the digest of the manifest an image reference names, without fetching any content
+func (r *Registry) Resolve(ctx context.Context, ref image.Reference) (string, error) {
+	// Set the default namespace if unset
+	ctx = ensureNamespace(ctx)
+
+	_, desc, err := r.resolver.Resolve(ctx, ref.String())
+	if err != nil {
+		return "", fmt.Errorf("error resolving name %s: %v", ref.String(), err)
+	}
+	return desc.Digest.String(), nil
+}
+
+// Digest returns the digest of the manifest of an image that was pulled, as it was when it was pulled
+func (r *Registry) Digest(ctx context.Context, ref image.Reference) (string, error) {
+	// Set the default namespace if unset
+	ctx = ensureNamespace(ctx)
+
+	img, err := r.Images().Get(ctx, ref.String())
+	if err != nil {
+		return "", err
+	}
+	return img.Target.Digest.String(), nil
+}
+
 // Destroy cleans up the on-disk boltdb file and other cache files, unless preserve cache is true
 func (r *Registry) Destroy() (err error) {
 	return r.destroy()
@@ -179,13 +223,7 @@ func (r *Registry) unpackLayer(ctx context.Context, layer ocispec.Descriptor, di
 	defer ra.Close()
 
 	// TODO(njhale): Chunk layer reading
-	decompressed, err := compression.DecompressStream(io.NewSectionReader(ra, 0, ra.Size()))
-	if err != nil {
-		return err
-	}
-	_, err = archive.Apply(ctx, dir, decompressed, archive.WithFilter(adjustPerms))
-
-	return err
+	return applyLayer(ctx, io.NewSectionReader(ra, 0, ra.Size()), dir)
 }
 
 func ensureNamespace(ctx context.Context) context.Context {
@@ -15,7 +15,9 @@ import (
 	"github.com/docker/docker/registry"
 )
 
-func NewResolver(configDir string, insecure bool, roots *x509.CertPool) (remotes.Resolver, error) {
+// NewResolver returns a resolver that authenticates with the credentials in the docker config, falling back to the
+// given credential providers for registries the config has no credentials for.
+func NewResolver(configDir string, insecure bool, roots *x509.CertPool, providers ...CredentialProvider) (remotes.Resolver, error) {
 	transport := &http.Transport{
 		Proxy: http.ProxyFromEnvironment,
 		DialContext: (&net.Dialer{
@@ -52,7 +54,7 @@ func NewResolver(configDir string, insecure bool, roots *x509.CertPool) (remotes
 		docker.WithAuthorizer(docker.NewDockerAuthorizer(
 			docker.WithAuthClient(client),
 			docker.WithAuthHeader(headers),
-			docker.WithAuthCreds(credential(cfg)),
+			docker.WithAuthCreds(credential(cfg, newCredentialCache(providers))),
 		)),
 		docker.WithClient(client),
 	}
@@ -68,7 +70,7 @@ func NewResolver(configDir string, insecure bool, roots *x509.CertPool) (remotes
 	return docker.NewResolver(opts), nil
 }
 
-func credential(cfg *configfile.ConfigFile) func(string) (string, string, error) {
+func credential(cfg *configfile.ConfigFile, cache *credentialCache) func(string) (string, string, error) {
 	return func(hostname string) (string, string, error) {
 		hostname = resolveHostname(hostname)
 		auth, err := cfg.GetAuthConfig(hostname)
@@ -79,7 +81,11 @@ func credential(cfg *configfile.ConfigFile) func(string) (string, string, error)
 			return "", auth.IdentityToken, nil
 		}
 		if auth.Username == "" && auth.Password == "" {
-			return "", "", nil
+			provided, err := cache.get(hostname)
+			if err != nil || provided == nil {
+				return "", "", err
+			}
+			return provided.Username, provided.Secret, nil
 		}
 
 		return auth.Username, auth.Password, nil
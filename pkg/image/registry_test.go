@@ -7,8 +7,10 @@ import (
 	"io/ioutil"
 	"math/rand"
 	"os"
+	"path/filepath"
 	"testing"
 
+	"github.com/otiai10/copy"
 	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/require"
 	"golang.org/x/mod/sumdb/dirhash"
@@ -142,6 +144,44 @@ func testPullAndUnpack(t *testing.T, newRegistry newRegistryFunc) {
 	}
 }
 
+func TestContainerdRegistryPush(t *testing.T) {
+	ctx, close := context.WithCancel(context.Background())
+	defer close()
+
+	// the registry stores pushed images under its root, so it is served from a copy of the golden images
+	rootDir, err := ioutil.TempDir("", "push-")
+	require.NoError(t, err)
+	defer os.RemoveAll(rootDir)
+	require.NoError(t, copy.Copy("testdata/golden", filepath.Join(rootDir, "golden")))
+
+	host, cafile, err := libimage.RunDockerRegistry(ctx, filepath.Join(rootDir, "golden"))
+	require.NoError(t, err)
+
+	r, err := containerdregistry.NewRegistry(
+		containerdregistry.WithLog(logrus.New().WithField("test", t.Name())),
+		containerdregistry.WithCacheDir(filepath.Join(rootDir, "cache")),
+		containerdregistry.WithRootCAs(poolForCertFile(t, cafile)),
+	)
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, r.Destroy())
+	}()
+
+	ref := image.SimpleReference(host + "/olmtest/kiali:1.4.2")
+	to := image.SimpleReference(host + "/olmtest/kiali:promoted")
+	require.Error(t, r.Push(ctx, ref, to), "an image that wasn't pulled can't be pushed")
+
+	require.NoError(t, r.Pull(ctx, ref))
+	require.NoError(t, r.Push(ctx, ref, to))
+
+	// the pushed image is the same image, under the new reference
+	pulled, err := r.Resolve(ctx, ref)
+	require.NoError(t, err)
+	pushed, err := r.Resolve(ctx, to)
+	require.NoError(t, err)
+	require.Equal(t, pulled, pushed)
+}
+
 func dirChecksum(t *testing.T, dir string) string {
 	sum, err := dirhash.HashDir(dir, "", dirhash.DefaultHash)
 	require.NoError(t, err)
@@ -0,0 +1,204 @@
+// Package artifact distributes catalog databases through image registries as OCI artifacts: manifests whose config
+// and only layer have media types of their own rather than those of a container image, so that a catalog can be
+// pushed and pulled without shipping an image that runs it.
+package artifact
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/containerd/containerd/content"
+	"github.com/containerd/containerd/errdefs"
+	"github.com/containerd/containerd/images"
+	"github.com/containerd/containerd/namespaces"
+	"github.com/opencontainers/go-digest"
+	"github.com/opencontainers/image-spec/specs-go"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+
+	"github.com/operator-framework/operator-registry/pkg/image"
+	"github.com/operator-framework/operator-registry/pkg/image/containerdregistry"
+	"github.com/operator-framework/operator-registry/pkg/sqlite"
+)
+
+const (
+	// ConfigMediaType is the media type of the config of a catalog artifact, which lists the packages in the catalog
+	ConfigMediaType = "application/vnd.operatorframework.catalog.config.v1+json"
+	// DatabaseMediaType is the media type of the layer of a catalog artifact, the catalog database as it is served
+	DatabaseMediaType = "application/vnd.operatorframework.catalog.database.v1.sqlite3"
+)
+
+// databaseFile is the name the database is titled with in an artifact, and unpacked as
+const databaseFile = "index.db"
+
+// ErrNotArtifact is returned when unpacking an image that isn't a catalog artifact, e.g. an index image
+var ErrNotArtifact = errors.New("not a catalog artifact")
+
+// Config is the config of a catalog artifact
+type Config struct {
+	// Packages are the names of the packages in the catalog
+	Packages []string `json:"packages"`
+}
+
+// Registry stores the content of artifacts and pushes them to, and pulls them from, remote registries
+type Registry interface {
+	containerdregistry.Store
+	Pull(ctx context.Context, ref image.Reference) error
+	Push(ctx context.Context, ref, to image.Reference) error
+}
+
+// Push stores the catalog database as an artifact and pushes it to the remote registry of to, returning the digest of
+// its manifest. The manifest is pushed after the database, so the reference names the whole artifact or is left as
+// it was.
+func Push(ctx context.Context, reg Registry, database string, to image.Reference) (string, error) {
+	ctx = ensureNamespace(ctx)
+
+	packages, err := listPackages(ctx, database)
+	if err != nil {
+		return "", fmt.Errorf("%s is not a catalog database: %v", database, err)
+	}
+	config, err := json.Marshal(Config{Packages: packages})
+	if err != nil {
+		return "", err
+	}
+	configDesc, err := writeBytes(ctx, reg, ConfigMediaType, config)
+	if err != nil {
+		return "", err
+	}
+	layerDesc, err := writeFile(ctx, reg, DatabaseMediaType, database)
+	if err != nil {
+		return "", err
+	}
+	layerDesc.Annotations = map[string]string{ocispec.AnnotationTitle: databaseFile}
+
+	manifest, err := json.Marshal(ocispec.Manifest{
+		Versioned: specs.Versioned{SchemaVersion: 2},
+		Config:    configDesc,
+		Layers:    []ocispec.Descriptor{layerDesc},
+	})
+	if err != nil {
+		return "", err
+	}
+	manifestDesc, err := writeBytes(ctx, reg, ocispec.MediaTypeImageManifest, manifest)
+	if err != nil {
+		return "", err
+	}
+
+	img := images.Image{Name: to.String(), Target: manifestDesc}
+	if _, err := reg.Images().Create(ctx, img); err != nil {
+		if !errdefs.IsAlreadyExists(err) {
+			return "", err
+		}
+		if _, err := reg.Images().Update(ctx, img); err != nil {
+			return "", err
+		}
+	}
+	if err := reg.Push(ctx, to, to); err != nil {
+		return "", err
+	}
+	return manifestDesc.Digest.String(), nil
+}
+
+// Unpack writes the database of a catalog artifact that was pulled into dir and returns its path. It returns
+// ErrNotArtifact if the image isn't a catalog artifact.
+func Unpack(ctx context.Context, reg Registry, ref image.Reference, dir string) (string, error) {
+	ctx = ensureNamespace(ctx)
+
+	img, err := reg.Images().Get(ctx, ref.String())
+	if err != nil {
+		return "", err
+	}
+	if img.Target.MediaType != ocispec.MediaTypeImageManifest && img.Target.MediaType != images.MediaTypeDockerSchema2Manifest {
+		return "", fmt.Errorf("%s: %w", ref.String(), ErrNotArtifact)
+	}
+	p, err := content.ReadBlob(ctx, reg.Content(), img.Target)
+	if err != nil {
+		return "", err
+	}
+	var manifest ocispec.Manifest
+	if err := json.Unmarshal(p, &manifest); err != nil {
+		return "", err
+	}
+	if manifest.Config.MediaType != ConfigMediaType {
+		return "", fmt.Errorf("%s: %w", ref.String(), ErrNotArtifact)
+	}
+	for _, layer := range manifest.Layers {
+		if layer.MediaType == DatabaseMediaType {
+			return unpackDatabase(ctx, reg, layer, dir)
+		}
+	}
+	return "", fmt.Errorf("catalog artifact %s has no %s layer", ref.String(), DatabaseMediaType)
+}
+
+func unpackDatabase(ctx context.Context, reg Registry, layer ocispec.Descriptor, dir string) (string, error) {
+	ra, err := reg.Content().ReaderAt(ctx, layer)
+	if err != nil {
+		return "", err
+	}
+	defer ra.Close()
+
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, databaseFile)
+	f, err := os.Create(path)
+	if err != nil {
+		return "", err
+	}
+	if _, err := io.Copy(f, content.NewReader(ra)); err != nil {
+		f.Close()
+		return "", err
+	}
+	return path, f.Close()
+}
+
+func listPackages(ctx context.Context, database string) ([]string, error) {
+	if _, err := os.Stat(database); err != nil {
+		return nil, err
+	}
+	querier, err := sqlite.NewSQLLiteQuerier(database)
+	if err != nil {
+		return nil, err
+	}
+	defer querier.Close()
+	return querier.ListPackages(ctx)
+}
+
+func writeBytes(ctx context.Context, reg Registry, mediaType string, p []byte) (ocispec.Descriptor, error) {
+	desc := ocispec.Descriptor{MediaType: mediaType, Digest: digest.FromBytes(p), Size: int64(len(p))}
+	return desc, content.WriteBlob(ctx, reg.Content(), desc.Digest.String(), bytes.NewReader(p), desc)
+}
+
+// writeFile stores a file, which is read twice so that it isn't held in memory: once to digest it, and once to store it
+func writeFile(ctx context.Context, reg Registry, mediaType, path string) (ocispec.Descriptor, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return ocispec.Descriptor{}, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return ocispec.Descriptor{}, err
+	}
+	dgst, err := digest.FromReader(f)
+	if err != nil {
+		return ocispec.Descriptor{}, err
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return ocispec.Descriptor{}, err
+	}
+	desc := ocispec.Descriptor{MediaType: mediaType, Digest: dgst, Size: info.Size()}
+	return desc, content.WriteBlob(ctx, reg.Content(), desc.Digest.String(), f, desc)
+}
+
+func ensureNamespace(ctx context.Context) context.Context {
+	if _, namespaced := namespaces.Namespace(ctx); !namespaced {
+		return namespaces.WithNamespace(ctx, namespaces.Default)
+	}
+	return ctx
+}
@@ -0,0 +1,98 @@
+package artifact
+
+import (
+	"context"
+	"crypto/x509"
+	"database/sql"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/otiai10/copy"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+
+	"github.com/operator-framework/operator-registry/pkg/image"
+	"github.com/operator-framework/operator-registry/pkg/image/containerdregistry"
+	libimage "github.com/operator-framework/operator-registry/pkg/lib/image"
+	"github.com/operator-framework/operator-registry/pkg/sqlite"
+)
+
+func newRegistry(t *testing.T, cacheDir, cafile string) *containerdregistry.Registry {
+	certs, err := ioutil.ReadFile(cafile)
+	require.NoError(t, err)
+	rootCAs := x509.NewCertPool()
+	require.True(t, rootCAs.AppendCertsFromPEM(certs))
+	r, err := containerdregistry.NewRegistry(
+		containerdregistry.WithLog(logrus.New().WithField("test", t.Name())),
+		containerdregistry.WithCacheDir(cacheDir),
+		containerdregistry.WithRootCAs(rootCAs),
+	)
+	require.NoError(t, err)
+	return r
+}
+
+func TestPushAndUnpack(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// the registry also serves an index image, from a copy of the golden images
+	rootDir, err := ioutil.TempDir("", "artifact-")
+	require.NoError(t, err)
+	defer os.RemoveAll(rootDir)
+	require.NoError(t, copy.Copy("../../image/testdata/golden", filepath.Join(rootDir, "golden")))
+	host, cafile, err := libimage.RunDockerRegistry(ctx, filepath.Join(rootDir, "golden"))
+	require.NoError(t, err)
+
+	database := filepath.Join(rootDir, "index.db")
+	db, err := sql.Open("sqlite3", database)
+	require.NoError(t, err)
+	load, err := sqlite.NewSQLLiteLoader(db)
+	require.NoError(t, err)
+	require.NoError(t, load.Migrate(ctx))
+	require.NoError(t, sqlite.NewSQLLoaderForDirectory(load, "../../../manifests").Populate())
+	require.NoError(t, db.Close())
+
+	pusher := newRegistry(t, filepath.Join(rootDir, "push-cache"), cafile)
+	defer func() {
+		require.NoError(t, pusher.Destroy())
+	}()
+	ref := image.SimpleReference(host + "/olmtest/catalog:v1")
+	digest, err := Push(ctx, pusher, database, ref)
+	require.NoError(t, err)
+	require.NotEmpty(t, digest)
+
+	// a file that isn't a catalog database isn't pushed
+	notDatabase := filepath.Join(rootDir, "not.db")
+	require.NoError(t, ioutil.WriteFile(notDatabase, []byte("not a database"), 0644))
+	_, err = Push(ctx, pusher, notDatabase, image.SimpleReference(host+"/olmtest/catalog:invalid"))
+	require.Error(t, err)
+
+	// the database is unpacked as it was pushed by another registry client
+	puller := newRegistry(t, filepath.Join(rootDir, "pull-cache"), cafile)
+	defer func() {
+		require.NoError(t, puller.Destroy())
+	}()
+	require.NoError(t, puller.Pull(ctx, ref))
+	path, err := Unpack(ctx, puller, ref, filepath.Join(rootDir, "unpacked"))
+	require.NoError(t, err)
+	pushed, err := ioutil.ReadFile(database)
+	require.NoError(t, err)
+	unpacked, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, pushed, unpacked)
+	querier, err := sqlite.NewSQLLiteQuerier(path)
+	require.NoError(t, err)
+	defer querier.Close()
+	packages, err := querier.ListPackages(ctx)
+	require.NoError(t, err)
+	require.ElementsMatch(t, []string{"etcd", "prometheus", "strimzi-kafka-operator"}, packages)
+
+	// images that aren't catalog artifacts are told apart
+	kiali := image.SimpleReference(host + "/olmtest/kiali:1.4.2")
+	require.NoError(t, puller.Pull(ctx, kiali))
+	_, err = Unpack(ctx, puller, kiali, filepath.Join(rootDir, "kiali"))
+	require.True(t, errors.Is(err, ErrNotArtifact))
+}
@@ -0,0 +1,125 @@
+package bundle
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/operator-framework/operator-registry/pkg/registry"
+)
+
+// ChannelPolicy is an organization's convention for channel names, e.g. that channels are named stable, fast or
+// candidate, optionally followed by a version. Typos in channel names start new channels that fragment the upgrade
+// graph of a package, so they are best caught before a bundle is published.
+//
+// A policy is read from a YAML or JSON rule file:
+//
+//	patterns:
+//	- (stable|fast|candidate)(-v\d+\.\d+)?
+//	- v\d+\.\d+
+type ChannelPolicy struct {
+	// Patterns are regular expressions, one of which every channel name must match in full
+	Patterns []string `json:"patterns"`
+
+	compiled []*regexp.Regexp
+}
+
+// NewChannelPolicy returns a policy that accepts the channel names that match one of the patterns in full
+func NewChannelPolicy(patterns ...string) (*ChannelPolicy, error) {
+	if len(patterns) == 0 {
+		return nil, fmt.Errorf("channel policy has no patterns")
+	}
+	p := &ChannelPolicy{Patterns: patterns}
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(`^(?:` + pattern + `)$`)
+		if err != nil {
+			return nil, fmt.Errorf("invalid channel pattern %q: %s", pattern, err)
+		}
+		p.compiled = append(p.compiled, re)
+	}
+	return p, nil
+}
+
+// LoadChannelPolicy reads a channel policy from a rule file
+func LoadChannelPolicy(path string) (*ChannelPolicy, error) {
+	var p ChannelPolicy
+	if err := registry.DecodeFile(path, &p); err != nil {
+		return nil, fmt.Errorf("error reading channel policy %s: %s", path, err)
+	}
+	return NewChannelPolicy(p.Patterns...)
+}
+
+// Validate returns an error for every channel whose name matches none of the patterns of the policy
+func (p *ChannelPolicy) Validate(channels ...string) []error {
+	var errs []error
+	for _, channel := range channels {
+		if !p.allows(channel) {
+			errs = append(errs, fmt.Errorf("channel %q does not match any of the channel name patterns %s", channel, strings.Join(p.Patterns, ", ")))
+		}
+	}
+	return errs
+}
+
+func (p *ChannelPolicy) allows(channel string) bool {
+	for _, re := range p.compiled {
+		if re.MatchString(channel) {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateBundleChannels checks that the channels and default channel in the annotations of the bundle in directory
+// follow the policy.
+// Outputs:
+// error: ValidationError which contains a list of errors
+func ValidateBundleChannels(directory string, policy *ChannelPolicy) error {
+	metadataDir := filepath.Join(directory, MetadataDir)
+	files, err := ioutil.ReadDir(metadataDir)
+	if err != nil {
+		return err
+	}
+
+	var annotations *AnnotationMetadata
+	for _, f := range files {
+		fileAnnotations := &AnnotationMetadata{}
+		if err := registry.DecodeFile(filepath.Join(metadataDir, f.Name()), fileAnnotations); err == nil && fileAnnotations.Annotations != nil {
+			annotations = fileAnnotations
+			break
+		}
+	}
+	if annotations == nil {
+		return NewValidationError([]error{fmt.Errorf("Could not find annotations file")})
+	}
+
+	var channels []string
+	for _, c := range strings.Split(annotations.Annotations[ChannelsLabel], ",") {
+		if c = strings.TrimSpace(c); c != "" {
+			channels = append(channels, c)
+		}
+	}
+	if defaultChannel := annotations.Annotations[ChannelDefaultLabel]; defaultChannel != "" {
+		channels = append(channels, defaultChannel)
+	}
+
+	if errs := policy.Validate(uniqueChannels(channels)...); len(errs) > 0 {
+		return NewValidationError(errs)
+	}
+	return nil
+}
+
+// uniqueChannels drops repeated channels, keeping the order they were first seen in
+func uniqueChannels(channels []string) []string {
+	seen := map[string]struct{}{}
+	var out []string
+	for _, c := range channels {
+		if _, ok := seen[c]; ok {
+			continue
+		}
+		seen[c] = struct{}{}
+		out = append(out, c)
+	}
+	return out
+}
@@ -0,0 +1,50 @@
+package bundle
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestChannelPolicy(t *testing.T) {
+	policy, err := NewChannelPolicy(`(stable|fast|candidate)(-v\d+\.\d+)?`, `v\d+\.\d+`)
+	require.NoError(t, err)
+
+	require.Empty(t, policy.Validate("stable", "fast-v4.6", "candidate", "v1.2"))
+	// patterns match the whole name
+	require.Len(t, policy.Validate("stabel", "stable-typo", "alpha"), 3)
+
+	_, err = NewChannelPolicy()
+	require.Error(t, err)
+	_, err = NewChannelPolicy("(stable")
+	require.Error(t, err)
+}
+
+func TestLoadChannelPolicy(t *testing.T) {
+	dir, err := ioutil.TempDir("", "channel-policy-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "channels.yaml")
+	require.NoError(t, ioutil.WriteFile(path, []byte("patterns:\n- stable\n- beta\n"), 0644))
+	policy, err := LoadChannelPolicy(path)
+	require.NoError(t, err)
+	require.Equal(t, []string{"stable", "beta"}, policy.Patterns)
+
+	require.NoError(t, ValidateBundleChannels("./testdata/validate/valid_bundle/", policy))
+
+	policy, err = NewChannelPolicy("stable")
+	require.NoError(t, err)
+	err = ValidateBundleChannels("./testdata/validate/valid_bundle/", policy)
+	require.Error(t, err)
+	verr, ok := err.(ValidationError)
+	require.True(t, ok)
+	require.Len(t, verr.Errors, 1)
+	require.Contains(t, verr.Errors[0].Error(), `"beta"`)
+
+	_, err = LoadChannelPolicy(filepath.Join(dir, "missing.yaml"))
+	require.Error(t, err)
+}
@@ -2,7 +2,6 @@ package bundle
 
 import (
 	"context"
-	"io/ioutil"
 	"os"
 	"path/filepath"
 
@@ -13,6 +12,7 @@ import (
 	"github.com/operator-framework/operator-registry/pkg/image"
 	"github.com/operator-framework/operator-registry/pkg/image/containerdregistry"
 	"github.com/operator-framework/operator-registry/pkg/image/execregistry"
+	"github.com/operator-framework/operator-registry/pkg/lib/tmp"
 )
 
 // BundleExporter exports the manifests of a bundle image into a directory
@@ -34,21 +34,35 @@ func (i *BundleExporter) Export() error {
 
 	log := logrus.WithField("img", i.image)
 
-	tmpDir, err := ioutil.TempDir("./", "bundle_tmp")
+	workspace, err := tmp.NewWorkspace("bundle-export")
 	if err != nil {
 		return err
 	}
-	defer os.RemoveAll(tmpDir)
+	defer workspace.Cleanup()
+	tmpDir := workspace.Path()
 
+	if err := unpackImage(i.image, i.containerTool, filepath.Join(tmpDir, "cacheDir"), tmpDir, log); err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(i.directory, 0777); err != nil {
+		return err
+	}
+
+	return copy.Copy(filepath.Join(tmpDir, "manifests"), i.directory)
+}
+
+// unpackImage pulls an image with the given tool and unpacks its content into dir
+func unpackImage(img string, containerTool containertools.ContainerTool, cacheDir, dir string, log *logrus.Entry) error {
 	var reg image.Registry
 	var rerr error
-	switch i.containerTool {
+	switch containerTool {
 	case containertools.NoneTool:
-		reg, rerr = containerdregistry.NewRegistry(containerdregistry.WithLog(log), containerdregistry.WithCacheDir(filepath.Join(tmpDir, "cacheDir")))
+		reg, rerr = containerdregistry.NewRegistry(containerdregistry.WithLog(log), containerdregistry.WithCacheDir(cacheDir))
 	case containertools.PodmanTool:
 		fallthrough
 	case containertools.DockerTool:
-		reg, rerr = execregistry.NewRegistry(i.containerTool, log)
+		reg, rerr = execregistry.NewRegistry(containerTool, log)
 	}
 	if rerr != nil {
 		return rerr
@@ -59,17 +73,9 @@ func (i *BundleExporter) Export() error {
 		}
 	}()
 
-	if err := reg.Pull(context.TODO(), image.SimpleReference(i.image)); err != nil {
+	if err := reg.Pull(context.TODO(), image.SimpleReference(img)); err != nil {
 		return err
 	}
 
-	if err := reg.Unpack(context.TODO(), image.SimpleReference(i.image), tmpDir); err != nil {
-		return err
-	}
-
-	if err := os.MkdirAll(i.directory, 0777); err != nil {
-		return err
-	}
-
-	return copy.Copy(filepath.Join(tmpDir, "manifests"), i.directory)
+	return reg.Unpack(context.TODO(), image.SimpleReference(img), dir)
 }
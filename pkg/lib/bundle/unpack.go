@@ -0,0 +1,186 @@
+package bundle
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/otiai10/copy"
+	"github.com/sirupsen/logrus"
+
+	"github.com/operator-framework/operator-registry/pkg/containertools"
+	"github.com/operator-framework/operator-registry/pkg/lib/tmp"
+	"github.com/operator-framework/operator-registry/pkg/registry"
+)
+
+// Parts of a bundle that unpacking can be filtered to
+const (
+	UnpackCSV      = "csv"
+	UnpackCRDs     = "crds"
+	UnpackMetadata = "metadata"
+)
+
+// BundleUnpacker unpacks all or part of the content of a bundle image
+type BundleUnpacker struct {
+	image         string
+	containerTool containertools.ContainerTool
+	filter        []string
+	logger        *logrus.Entry
+}
+
+// NewUnpackerForBundle returns an unpacker for the parts of a bundle image named by filter, any of UnpackCSV,
+// UnpackCRDs and UnpackMetadata. An empty filter unpacks the whole bundle.
+func NewUnpackerForBundle(image string, containerTool containertools.ContainerTool, filter []string) (*BundleUnpacker, error) {
+	for _, f := range filter {
+		switch f {
+		case UnpackCSV, UnpackCRDs, UnpackMetadata:
+		default:
+			return nil, fmt.Errorf("unknown filter %q, must be one of [%s, %s, %s]", f, UnpackCSV, UnpackCRDs, UnpackMetadata)
+		}
+	}
+	return &BundleUnpacker{
+		image:         image,
+		containerTool: containerTool,
+		filter:        filter,
+		logger:        logrus.WithField("img", image),
+	}, nil
+}
+
+// Unpack writes the selected files of the bundle into directory, keeping their manifests/ and metadata/ layout
+func (u *BundleUnpacker) Unpack(directory string) error {
+	return u.unpack(func(bundleDir string, files []string) error {
+		for _, f := range files {
+			if err := copy.Copy(filepath.Join(bundleDir, f), filepath.Join(directory, f)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// WriteYAML writes the selected files of the bundle to w as a single yaml stream
+func (u *BundleUnpacker) WriteYAML(w io.Writer) error {
+	return u.unpack(func(bundleDir string, files []string) error {
+		return WriteYAMLStream(w, bundleDir, files)
+	})
+}
+
+func (u *BundleUnpacker) unpack(write func(bundleDir string, files []string) error) error {
+	workspace, err := tmp.NewWorkspace("bundle-unpack")
+	if err != nil {
+		return err
+	}
+	defer workspace.Cleanup()
+	bundleDir, err := workspace.Dir("bundle-")
+	if err != nil {
+		return err
+	}
+
+	if err := unpackImage(u.image, u.containerTool, filepath.Join(workspace.Path(), "cache"), bundleDir, u.logger); err != nil {
+		return err
+	}
+
+	files, err := SelectBundleFiles(bundleDir, u.filter)
+	if err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		return fmt.Errorf("no files in bundle %s match filter %v", u.image, u.filter)
+	}
+	return write(bundleDir, files)
+}
+
+// SelectBundleFiles returns the files of an unpacked bundle that belong to the parts named by filter, as paths
+// relative to bundleDir in lexical order. An empty filter selects every file of the manifests and metadata
+// directories.
+func SelectBundleFiles(bundleDir string, filter []string) ([]string, error) {
+	wanted := map[string]bool{}
+	for _, f := range filter {
+		wanted[f] = true
+	}
+	all := len(wanted) == 0
+
+	var files []string
+	for _, dir := range []string{ManifestsDir, MetadataDir} {
+		err := filepath.Walk(filepath.Join(bundleDir, dir), func(path string, info os.FileInfo, err error) error {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			if err != nil || info.IsDir() {
+				return err
+			}
+			rel, err := filepath.Rel(bundleDir, path)
+			if err != nil {
+				return err
+			}
+
+			if all || (dir == MetadataDir && wanted[UnpackMetadata]) {
+				files = append(files, rel)
+				return nil
+			}
+			if dir == MetadataDir {
+				return nil
+			}
+
+			kinds, err := manifestKinds(path)
+			if err != nil {
+				// files that aren't manifests only belong to the whole bundle
+				return nil
+			}
+			if (wanted[UnpackCSV] && kinds[CSVKind]) || (wanted[UnpackCRDs] && kinds[CRDKind]) {
+				files = append(files, rel)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+func manifestKinds(path string) (map[string]bool, error) {
+	objs, err := registry.DecodeManifestFile(path)
+	if err != nil {
+		return nil, err
+	}
+	kinds := map[string]bool{}
+	for _, obj := range objs {
+		kinds[obj.GetKind()] = true
+	}
+	return kinds, nil
+}
+
+// WriteYAMLStream writes files, relative to dir, to w as documents of a single yaml stream
+func WriteYAMLStream(w io.Writer, dir string, files []string) error {
+	for i, f := range files {
+		content, err := ioutil.ReadFile(filepath.Join(dir, f))
+		if err != nil {
+			return err
+		}
+		content = bytes.TrimPrefix(content, []byte("---\n"))
+		if i > 0 {
+			if _, err := io.WriteString(w, "---\n"); err != nil {
+				return err
+			}
+		}
+		if _, err := fmt.Fprintf(w, "# %s\n", filepath.ToSlash(f)); err != nil {
+			return err
+		}
+		if _, err := w.Write(content); err != nil {
+			return err
+		}
+		if !strings.HasSuffix(string(content), "\n") {
+			if _, err := io.WriteString(w, "\n"); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
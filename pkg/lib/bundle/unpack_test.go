@@ -0,0 +1,68 @@
+package bundle
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/operator-framework/operator-registry/pkg/containertools"
+	"github.com/operator-framework/operator-registry/pkg/registry"
+)
+
+func TestSelectBundleFiles(t *testing.T) {
+	const bundleDir = "./testdata/validate/valid_bundle"
+	crds := []string{
+		"manifests/etcdbackups.etcd.database.coreos.com.crd.yaml",
+		"manifests/etcdclusters.etcd.database.coreos.com.crd.yaml",
+		"manifests/etcdrestores.etcd.database.coreos.com.crd.yaml",
+	}
+	csv := "manifests/etcdoperator.v0.9.4.clusterserviceversion.yaml"
+	metadata := "metadata/annotations.yaml"
+
+	tests := []struct {
+		filter   []string
+		expected []string
+	}{
+		{filter: []string{UnpackCSV}, expected: []string{csv}},
+		{filter: []string{UnpackCRDs}, expected: crds},
+		{filter: []string{UnpackMetadata}, expected: []string{metadata}},
+		{filter: []string{UnpackMetadata, UnpackCSV}, expected: []string{csv, metadata}},
+	}
+	for _, tt := range tests {
+		t.Run(strings.Join(tt.filter, ","), func(t *testing.T) {
+			files, err := SelectBundleFiles(bundleDir, tt.filter)
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, files)
+		})
+	}
+
+	// without a filter, the whole bundle is selected
+	files, err := SelectBundleFiles(bundleDir, nil)
+	require.NoError(t, err)
+	require.Len(t, files, 10)
+	require.Contains(t, files, metadata)
+
+	_, err = NewUnpackerForBundle("quay.io/example/bundle:latest", containertools.NoneTool, []string{"deployments"})
+	require.Error(t, err)
+}
+
+func TestWriteYAMLStream(t *testing.T) {
+	const bundleDir = "./testdata/validate/valid_bundle"
+	files, err := SelectBundleFiles(bundleDir, []string{UnpackCSV, UnpackCRDs})
+	require.NoError(t, err)
+
+	var out bytes.Buffer
+	require.NoError(t, WriteYAMLStream(&out, bundleDir, files))
+
+	// the stream decodes back into one document per selected file
+	objs, err := registry.DecodeUnstructuredList(&out)
+	require.NoError(t, err)
+	require.Len(t, objs, 4)
+	var kinds []string
+	for _, obj := range objs {
+		kinds = append(kinds, obj.GetKind())
+	}
+	require.Equal(t, []string{CRDKind, CRDKind, CSVKind, CRDKind}, kinds)
+}
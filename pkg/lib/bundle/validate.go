@@ -1,6 +1,7 @@
 package bundle
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
@@ -273,23 +274,31 @@ func (i imageValidator) ValidateBundleContent(manifestDir string) error {
 	csvValidator := v.ClusterServiceVersionValidator
 	crdValidator := v.CustomResourceDefinitionValidator
 
-	// Read all files in manifests directory
-	items, err := ioutil.ReadDir(manifestDir)
+	// Read all manifests in the manifests directory
+	fileNames, err := registry.ManifestFiles(manifestDir, true)
 	if err != nil {
 		validationErrors = append(validationErrors, err)
 	}
 
-	for _, item := range items {
-		fileWithPath := filepath.Join(manifestDir, item.Name())
-		data, err := ioutil.ReadFile(fileWithPath)
+	type manifest struct {
+		file string
+		obj  *unstructured.Unstructured
+	}
+	var manifests []manifest
+	for _, fileWithPath := range fileNames {
+		objs, err := registry.DecodeManifestFile(fileWithPath)
 		if err != nil {
-			validationErrors = append(validationErrors, fmt.Errorf("Unable to read file %s in supported types", fileWithPath))
+			validationErrors = append(validationErrors, err)
 			continue
 		}
+		for _, obj := range objs {
+			manifests = append(manifests, manifest{file: fileWithPath, obj: obj})
+		}
+	}
 
-		dec := k8syaml.NewYAMLOrJSONDecoder(strings.NewReader(string(data)), 30)
-		k8sFile := &unstructured.Unstructured{}
-		err = dec.Decode(k8sFile)
+	for _, m := range manifests {
+		fileWithPath, k8sFile := m.file, m.obj
+		data, err := k8sFile.MarshalJSON()
 		if err != nil {
 			validationErrors = append(validationErrors, err)
 			continue
@@ -297,7 +306,7 @@ func (i imageValidator) ValidateBundleContent(manifestDir string) error {
 
 		unstObjs = append(unstObjs, k8sFile)
 		gvk := k8sFile.GetObjectKind().GroupVersionKind()
-		i.logger.Debugf(`Validating "%s" from file "%s"`, gvk.String(), item.Name())
+		i.logger.Debugf(`Validating "%s" from file "%s"`, gvk.String(), filepath.Base(fileWithPath))
 		// Verify if the object kind is supported for RegistryV1 format
 		ok, _ := IsSupported(gvk.Kind)
 		if mediaType == RegistryV1Type && !ok {
@@ -321,7 +330,7 @@ func (i imageValidator) ValidateBundleContent(manifestDir string) error {
 				}
 			}
 		} else if gvk.Kind == CRDKind {
-			dec := k8syaml.NewYAMLOrJSONDecoder(strings.NewReader(string(data)), 30)
+			dec := k8syaml.NewYAMLOrJSONDecoder(bytes.NewReader(data), 30)
 			switch gv := gvk.GroupVersion().String(); gv {
 			case v1CRDapiVersion:
 				crd := &apiextensionsv1.CustomResourceDefinition{}
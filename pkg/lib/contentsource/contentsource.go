@@ -0,0 +1,135 @@
+package contentsource
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// S3Scheme prefixes the location of a catalog in an S3 or S3 compatible bucket, e.g. s3://catalogs/operators/index.db
+	S3Scheme = "s3://"
+	// GCSScheme prefixes the location of a catalog in a Google Cloud Storage bucket, e.g. gs://catalogs/operators/index.db
+	GCSScheme = "gs://"
+)
+
+// ErrNotModified is returned by Fetch when the content of a source is still at the version the caller has
+var ErrNotModified = errors.New("content not modified")
+
+// Source is where a catalog is published, e.g. the bucket a release pipeline uploads each new database to. Sources are
+// versioned, so that a catalog can be polled for changes without downloading it again each time.
+type Source interface {
+	// Fetch writes the content of the source to path and returns its version, unless the content is still at version,
+	// in which case it returns ErrNotModified and leaves path alone. An empty version always fetches the content.
+	Fetch(ctx context.Context, path, version string) (string, error)
+}
+
+// IsObjectStorage returns true if location is in a bucket rather than a local path
+func IsObjectStorage(location string) bool {
+	return strings.HasPrefix(location, S3Scheme) || strings.HasPrefix(location, GCSScheme)
+}
+
+// New returns the source at location, an s3:// or gs:// url of an object. Credentials and endpoints are read from the
+// environment the way the cloud providers' own tools read them:
+//
+// - s3:// objects are signed with AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY and AWS_SESSION_TOKEN for AWS_REGION, and
+// requested anonymously if no keys are set. AWS_ENDPOINT_URL_S3 or AWS_ENDPOINT_URL point them at an S3 compatible
+// store, e.g. MinIO.
+//
+// - gs:// objects are requested with the access token in GOOGLE_OAUTH_ACCESS_TOKEN, and anonymously if it isn't set.
+// STORAGE_EMULATOR_HOST points them at an emulator.
+func New(location string) (Source, error) {
+	var scheme string
+	switch {
+	case strings.HasPrefix(location, S3Scheme):
+		scheme = S3Scheme
+	case strings.HasPrefix(location, GCSScheme):
+		scheme = GCSScheme
+	default:
+		return nil, fmt.Errorf("%s is not in object storage, expected an %s or %s url", location, S3Scheme, GCSScheme)
+	}
+
+	parts := strings.SplitN(strings.TrimPrefix(location, scheme), "/", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return nil, fmt.Errorf("%s doesn't name a bucket and an object", location)
+	}
+	if scheme == S3Scheme {
+		return newS3Source(parts[0], parts[1], s3ConfigFromEnv()), nil
+	}
+	return newGCSSource(parts[0], parts[1], gcsConfigFromEnv()), nil
+}
+
+// objectSource fetches an object over http, using its ETag as the version
+type objectSource struct {
+	url    string
+	client *http.Client
+	// authorize adds the credentials of the store to a request
+	authorize func(req *http.Request)
+}
+
+func (s *objectSource) Fetch(ctx context.Context, path, version string) (string, error) {
+	req, err := http.NewRequest(http.MethodGet, s.url, nil)
+	if err != nil {
+		return "", err
+	}
+	if version != "" {
+		req.Header.Set("If-None-Match", version)
+	}
+	if s.authorize != nil {
+		s.authorize(req)
+	}
+
+	resp, err := s.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotModified:
+		return "", ErrNotModified
+	default:
+		return "", fmt.Errorf("error fetching %s: server returned %s", s.url, resp.Status)
+	}
+
+	// the content is written next to path and moved over it once complete, so a failed download never leaves a
+	// partial file behind
+	f, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".part-")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(f.Name())
+	if _, err := io.Copy(f, resp.Body); err != nil {
+		f.Close()
+		return "", fmt.Errorf("error fetching %s: %s", s.url, err)
+	}
+	if err := f.Close(); err != nil {
+		return "", err
+	}
+	if err := os.Rename(f.Name(), path); err != nil {
+		return "", err
+	}
+	return resp.Header.Get("ETag"), nil
+}
+
+// escapePath percent encodes every byte of an object key other than the unreserved characters and slashes, which is
+// how both S3 and GCS expect keys in request paths
+func escapePath(key string) string {
+	var b strings.Builder
+	for i := 0; i < len(key); i++ {
+		c := key[i]
+		if c == '/' || c == '-' || c == '_' || c == '.' || c == '~' ||
+			('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') || ('0' <= c && c <= '9') {
+			b.WriteByte(c)
+			continue
+		}
+		fmt.Fprintf(&b, "%%%02X", c)
+	}
+	return b.String()
+}
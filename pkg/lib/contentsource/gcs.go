@@ -0,0 +1,40 @@
+package contentsource
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+)
+
+const defaultGCSEndpoint = "https://storage.googleapis.com"
+
+// gcsConfig is how a Google Cloud Storage bucket is reached
+type gcsConfig struct {
+	endpoint    string
+	accessToken string
+}
+
+func gcsConfigFromEnv() gcsConfig {
+	config := gcsConfig{endpoint: defaultGCSEndpoint, accessToken: os.Getenv("GOOGLE_OAUTH_ACCESS_TOKEN")}
+	if host := os.Getenv("STORAGE_EMULATOR_HOST"); host != "" {
+		config.endpoint = host
+		if !strings.Contains(host, "://") {
+			config.endpoint = "http://" + host
+		}
+	}
+	return config
+}
+
+func newGCSSource(bucket, object string, config gcsConfig) *objectSource {
+	s := &objectSource{
+		url:    fmt.Sprintf("%s/%s/%s", strings.TrimSuffix(config.endpoint, "/"), bucket, escapePath(object)),
+		client: http.DefaultClient,
+	}
+	if config.accessToken != "" {
+		s.authorize = func(req *http.Request) {
+			req.Header.Set("Authorization", "Bearer "+config.accessToken)
+		}
+	}
+	return s
+}
@@ -0,0 +1,200 @@
+// Package filewatch reports changes to the content of files, e.g. to serve each new version of a database as it is
+// written.
+package filewatch
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/fsnotify.v1"
+)
+
+// Watch calls onChange each time the content of the files at paths changes, until the returned func is called.
+// Changes are noticed through the filesystem events of the directories of the files, so that a file replaced by
+// renaming another over it is noticed along with one written in place. onChange is only called once the files have
+// had no events for delay and their content hash differs from the last one reported, so that a file being written is
+// reported once and a file touched or rewritten with the same content isn't reported at all. Files may be missing,
+// which is a change of content of its own.
+func Watch(paths []string, delay time.Duration, onChange func()) (func() error, error) {
+	watched := map[string]struct{}{}
+	dirs := map[string]struct{}{}
+	for i, path := range paths {
+		abs, err := filepath.Abs(path)
+		if err != nil {
+			return nil, err
+		}
+		paths[i] = abs
+		watched[abs] = struct{}{}
+		dirs[filepath.Dir(abs)] = struct{}{}
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	for dir := range dirs {
+		if err := watcher.Add(dir); err != nil {
+			watcher.Close()
+			return nil, err
+		}
+	}
+	relevant := func(event fsnotify.Event) bool {
+		_, ok := watched[filepath.Clean(event.Name)]
+		return ok
+	}
+	return run(watcher, delay, relevant, func() (string, error) { return contentHash(paths) }, onChange)
+}
+
+// WatchDir calls onChange each time the content of the files in the tree under dir changes, until the returned func
+// is called, e.g. to rebuild a catalog from the manifests in it as they are edited. Directories created in the tree
+// are watched as they appear, and files added, removed or renamed in it are changes of its content. As with Watch,
+// onChange is only called once the tree has had no events for delay and its content hash has changed. Hidden files
+// and directories, such as the swap files of editors, aren't part of the content.
+func WatchDir(dir string, delay time.Duration, onChange func()) (func() error, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	if err := addTree(watcher, dir); err != nil {
+		watcher.Close()
+		return nil, err
+	}
+	relevant := func(event fsnotify.Event) bool {
+		if event.Op&fsnotify.Create != 0 {
+			// a directory that can't be watched yet is hashed all the same, and may be watched once it is written
+			addTree(watcher, event.Name)
+		}
+		return true
+	}
+	return run(watcher, delay, relevant, func() (string, error) { return treeHash(dir) }, onChange)
+}
+
+// run reports the changes to the content hashed by hash that the relevant events of watcher bring, as Watch
+// describes, until the returned func is called
+func run(watcher *fsnotify.Watcher, delay time.Duration, relevant func(fsnotify.Event) bool, hash func() (string, error), onChange func()) (func() error, error) {
+	last, err := hash()
+	if err != nil {
+		watcher.Close()
+		return nil, err
+	}
+
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		var settled <-chan time.Time
+		for {
+			select {
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if relevant(event) {
+					settled = time.After(delay)
+				}
+			case <-watcher.Errors:
+				// events that were missed are made up for by the next one, which hashes the files anew
+			case <-settled:
+				settled = nil
+				current, err := hash()
+				if err != nil || current == last {
+					continue
+				}
+				last = current
+				onChange()
+			case <-done:
+				return
+			}
+		}
+	}()
+	return func() error {
+		close(done)
+		<-stopped
+		return watcher.Close()
+	}, nil
+}
+
+// addTree watches every directory in the tree under dir, skipping hidden ones. A dir that isn't a directory is left
+// alone.
+func addTree(watcher *fsnotify.Watcher, dir string) error {
+	return filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || !info.IsDir() {
+			return err
+		}
+		if path != dir && hidden(path) {
+			return filepath.SkipDir
+		}
+		return watcher.Add(path)
+	})
+}
+
+// contentHash returns a hash of the content of the files at paths, telling missing files apart from empty ones
+func contentHash(paths []string) (string, error) {
+	h := sha256.New()
+	for _, path := range paths {
+		f, err := os.Open(path)
+		if os.IsNotExist(err) {
+			h.Write([]byte{0})
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		h.Write([]byte{1})
+		_, err = io.Copy(h, f)
+		f.Close()
+		if err != nil {
+			return "", err
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// treeHash returns a hash of the paths and content of the files in the tree under dir, leaving out hidden ones
+func treeHash(dir string) (string, error) {
+	h := sha256.New()
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if path != dir && hidden(path) {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			h.Write([]byte(rel + "/\x00"))
+			return nil
+		}
+		h.Write([]byte(rel + "\x00"))
+		content, err := contentHash([]string{path})
+		if err != nil {
+			return err
+		}
+		h.Write([]byte(content))
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func hidden(path string) bool {
+	return strings.HasPrefix(filepath.Base(path), ".")
+}
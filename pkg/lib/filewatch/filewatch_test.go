@@ -0,0 +1,110 @@
+package filewatch
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWatch(t *testing.T) {
+	dir, err := ioutil.TempDir("", "filewatch-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "index.db")
+	require.NoError(t, ioutil.WriteFile(path, []byte("v1"), 0644))
+
+	changes := make(chan struct{}, 10)
+	stop, err := Watch([]string{path, path + "-wal"}, 50*time.Millisecond, func() {
+		changes <- struct{}{}
+	})
+	require.NoError(t, err)
+
+	expectChange := func(changed bool) {
+		select {
+		case <-changes:
+			require.True(t, changed, "unexpected change")
+		case <-time.After(500 * time.Millisecond):
+			require.False(t, changed, "expected a change")
+		}
+	}
+
+	// several writes in a row are reported once
+	require.NoError(t, ioutil.WriteFile(path, []byte("v2"), 0644))
+	require.NoError(t, ioutil.WriteFile(path, []byte("v3"), 0644))
+	expectChange(true)
+	expectChange(false)
+
+	// rewriting the same content and writing other files report nothing
+	require.NoError(t, ioutil.WriteFile(path, []byte("v3"), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "other.db"), []byte("v1"), 0644))
+	expectChange(false)
+
+	// a file renamed over the watched one and a new related file are reported
+	next := filepath.Join(dir, "next.db")
+	require.NoError(t, ioutil.WriteFile(next, []byte("v4"), 0644))
+	require.NoError(t, os.Rename(next, path))
+	expectChange(true)
+	require.NoError(t, ioutil.WriteFile(path+"-wal", []byte("wal"), 0644))
+	expectChange(true)
+
+	require.NoError(t, stop())
+	require.NoError(t, ioutil.WriteFile(path, []byte("v5"), 0644))
+	expectChange(false)
+}
+
+func TestWatchDir(t *testing.T) {
+	dir, err := ioutil.TempDir("", "filewatch-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	pkg := filepath.Join(dir, "etcd")
+	require.NoError(t, os.Mkdir(pkg, 0755))
+	csv := filepath.Join(pkg, "etcd.v1.yaml")
+	require.NoError(t, ioutil.WriteFile(csv, []byte("v1"), 0644))
+
+	changes := make(chan struct{}, 10)
+	stop, err := WatchDir(dir, 50*time.Millisecond, func() {
+		changes <- struct{}{}
+	})
+	require.NoError(t, err)
+
+	expectChange := func(changed bool) {
+		select {
+		case <-changes:
+			require.True(t, changed, "unexpected change")
+		case <-time.After(500 * time.Millisecond):
+			require.False(t, changed, "expected a change")
+		}
+	}
+
+	// files edited in subdirectories are reported once their writes settle
+	require.NoError(t, ioutil.WriteFile(csv, []byte("v2"), 0644))
+	require.NoError(t, ioutil.WriteFile(csv, []byte("v3"), 0644))
+	expectChange(true)
+	expectChange(false)
+
+	// rewriting the same content and writing hidden files report nothing
+	require.NoError(t, ioutil.WriteFile(csv, []byte("v3"), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(pkg, ".etcd.v1.yaml.swp"), []byte("swap"), 0644))
+	expectChange(false)
+
+	// files in new directories are watched, and renaming or removing files is a change
+	next := filepath.Join(pkg, "0.2.0")
+	require.NoError(t, os.Mkdir(next, 0755))
+	expectChange(true)
+	require.NoError(t, ioutil.WriteFile(filepath.Join(next, "etcd.v2.yaml"), []byte("v1"), 0644))
+	expectChange(true)
+	require.NoError(t, ioutil.WriteFile(filepath.Join(next, "etcd.v2.yaml"), []byte("v2"), 0644))
+	expectChange(true)
+	require.NoError(t, os.Rename(csv, filepath.Join(pkg, "etcd.v1.yml")))
+	expectChange(true)
+	require.NoError(t, os.RemoveAll(next))
+	expectChange(true)
+
+	require.NoError(t, stop())
+	require.NoError(t, ioutil.WriteFile(filepath.Join(pkg, "etcd.v1.yml"), []byte("v4"), 0644))
+	expectChange(false)
+}
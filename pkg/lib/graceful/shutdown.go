@@ -11,11 +11,17 @@ import (
 )
 
 func Shutdown(logger logrus.FieldLogger, run func() error, cleanup func()) error {
+	return ShutdownContext(context.Background(), logger, run, cleanup)
+}
+
+// ShutdownContext is Shutdown, and also shuts down once parent is done, e.g. a context returned by Context whose
+// process was asked to shut down before run was started.
+func ShutdownContext(parent context.Context, logger logrus.FieldLogger, run func() error, cleanup func()) error {
 	interrupt := make(chan os.Signal, 1)
 	signal.Notify(interrupt, os.Interrupt, syscall.SIGTERM)
 	defer signal.Stop(interrupt)
 
-	g, ctx := errgroup.WithContext(context.Background())
+	g, ctx := errgroup.WithContext(parent)
 	g.Go(run)
 
 	select {
@@ -31,3 +37,21 @@ func Shutdown(logger logrus.FieldLogger, run func() error, cleanup func()) error
 
 	return g.Wait()
 }
+
+// Context returns a copy of parent that is cancelled once the process is asked to shut down, by an interrupt or
+// SIGTERM, so that work started on its behalf, such as loading or refreshing a database, stops with it. The returned
+// func releases the context and must be called once it is no longer used.
+func Context(parent context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(parent)
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		defer signal.Stop(interrupt)
+		select {
+		case <-interrupt:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	return ctx, cancel
+}
@@ -5,13 +5,14 @@ import (
 	"database/sql"
 	"fmt"
 	"io"
-	"io/ioutil"
 	"math/rand"
 	"os"
 	"path"
 	"path/filepath"
 	"strconv"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/sirupsen/logrus"
 	"gopkg.in/yaml.v2"
@@ -23,7 +24,9 @@ import (
 	"github.com/operator-framework/operator-registry/pkg/image/execregistry"
 	"github.com/operator-framework/operator-registry/pkg/lib/bundle"
 	"github.com/operator-framework/operator-registry/pkg/lib/certs"
+	"github.com/operator-framework/operator-registry/pkg/lib/progress"
 	"github.com/operator-framework/operator-registry/pkg/lib/registry"
+	"github.com/operator-framework/operator-registry/pkg/lib/tmp"
 	pregistry "github.com/operator-framework/operator-registry/pkg/registry"
 	"github.com/operator-framework/operator-registry/pkg/sqlite"
 )
@@ -50,7 +53,18 @@ type ImageIndexer struct {
 	RegistryDeprecator     registry.RegistryDeprecator
 	BuildTool              containertools.ContainerTool
 	PullTool               containertools.ContainerTool
-	Logger                 *logrus.Entry
+	Scanner                ImageScanner
+	// Resolver resolves the digests of the images an index is built from for its provenance. If nil, images are
+	// resolved against their registries.
+	Resolver ImageResolver
+	Logger   *logrus.Entry
+}
+
+// DockerfileOptions are the extra instructions rendered into the dockerfile generated for an index: Labels
+// become LABEL instructions and BuildArgs become ARG instructions with the given defaults.
+type DockerfileOptions struct {
+	Labels    map[string]string
+	BuildArgs map[string]string
 }
 
 // AddToIndexRequest defines the parameters to send to the AddToIndex API
@@ -65,55 +79,232 @@ type AddToIndexRequest struct {
 	Mode              pregistry.Mode
 	CaFile            string
 	SkipTLS           bool
+	VerifyCommand     string
+	ScanCommand       string
+	ScanReportFile    string
+	ScanFailSeverity  string
+	DockerfileOptions
+	// Resume keeps the source database and the unpacked bundles across attempts, so re-running a failed add with
+	// the same index and bundles continues from where it stopped. Clean discards the state of previous attempts.
+	Resume bool
+	Clean  bool
+	// JournalMode and Synchronous, if set, are the SQLite journal mode and synchronous setting the database is
+	// written with, see registry.AddToRegistryRequest
+	JournalMode string
+	Synchronous string
+	// InMemory builds the database in memory, see registry.AddToRegistryRequest
+	InMemory bool
+	// CSVNameConflicts is what is done with bundles whose CSV name already belongs to another package
+	CSVNameConflicts pregistry.ConflictPolicy
+	// VersionPolicy, if set, rejects bundles whose versions break it
+	VersionPolicy *pregistry.VersionPolicy
+	// ProvenanceFile, if set, is where an in-toto statement of the SLSA provenance of the index is written, naming
+	// the version of opm, BuilderVersion, that built it. ProvenanceCommand, if set, is run once the image is built
+	// with the provenance file and the tag appended, e.g. to push the image and sign and attach the provenance.
+	ProvenanceFile    string
+	ProvenanceCommand string
+	BuilderVersion    string
 }
 
-// AddToIndex is an aggregate API used to generate a registry index image with additional bundles
-func (i ImageIndexer) AddToIndex(request AddToIndexRequest) error {
-	buildDir, outDockerfile, cleanup, err := buildContext(request.Generate, request.OutDockerfile)
-	defer cleanup()
+// AddToIndex is an aggregate API used to generate a registry index image with additional bundles. ctx carries the
+// progress reporter; cancelling it aborts the build.
+func (i ImageIndexer) AddToIndex(ctx context.Context, request AddToIndexRequest) error {
+	workspace, err := tmp.NewWorkspace("index-add")
+	if err != nil {
+		return err
+	}
+	defer workspace.Cleanup()
+
+	buildDir, outDockerfile, err := buildContext(workspace, request.Generate, request.OutDockerfile)
+	if err != nil {
+		return err
+	}
+
+	state, err := i.openBuildState(request)
 	if err != nil {
 		return err
 	}
 
-	databasePath, err := i.extractDatabase(buildDir, request.FromIndex, request.CaFile, request.SkipTLS)
+	err = i.addToIndex(ctx, request, workspace, buildDir, outDockerfile, state)
+	if state == nil {
+		return err
+	}
+	if err != nil {
+		i.Logger.Warnf("build state kept in %s, re-run with --resume to continue", state.Dir())
+		return err
+	}
+	return state.Remove()
+}
+
+// openBuildState returns the state of previous attempts at the same add when resuming, and discards it when
+// asked to start clean
+func (i ImageIndexer) openBuildState(request AddToIndexRequest) (*registry.BuildState, error) {
+	if !request.Resume && !request.Clean {
+		return nil, nil
+	}
+
+	dir := registry.BuildStateDir("index-add", append([]string{request.FromIndex, strconv.Itoa(int(request.Mode))}, request.Bundles...)...)
+	if request.Clean {
+		i.Logger.Infof("removing build state %s", dir)
+		if err := os.RemoveAll(dir); err != nil {
+			return nil, err
+		}
+	}
+	if !request.Resume {
+		return nil, nil
+	}
+	return registry.OpenBuildState(dir)
+}
+
+func (i ImageIndexer) addToIndex(ctx context.Context, request AddToIndexRequest, workspace *tmp.Workspace, buildDir, outDockerfile string, state *registry.BuildState) error {
+	if request.ProvenanceCommand != "" && request.ProvenanceFile == "" {
+		return fmt.Errorf("a provenance command requires a provenance file")
+	}
+	if request.ProvenanceCommand != "" && request.Generate {
+		return fmt.Errorf("a provenance command can't be run when only generating the dockerfile")
+	}
+	started := time.Now()
+
+	databasePath, err := i.extractDatabaseWithState(ctx, workspace, buildDir, request.FromIndex, request.CaFile, request.SkipTLS, state)
+	if err != nil {
+		return err
+	}
+
+	var failSeverity Severity
+	if request.ScanFailSeverity != "" {
+		failSeverity, err = ParseSeverity(request.ScanFailSeverity)
+		if err != nil {
+			return err
+		}
+	}
+
+	existingImages, err := listIndexImages(databasePath)
 	if err != nil {
 		return err
 	}
 
 	// Run opm registry add on the database
 	addToRegistryReq := registry.AddToRegistryRequest{
-		Bundles:       request.Bundles,
-		InputDatabase: databasePath,
-		Permissive:    request.Permissive,
-		Mode:          request.Mode,
-		SkipTLS:       request.SkipTLS,
-		ContainerTool: i.PullTool,
+		Bundles:          request.Bundles,
+		InputDatabase:    databasePath,
+		Permissive:       request.Permissive,
+		Mode:             request.Mode,
+		SkipTLS:          request.SkipTLS,
+		ContainerTool:    i.PullTool,
+		VerifyCommand:    request.VerifyCommand,
+		State:            state,
+		JournalMode:      request.JournalMode,
+		Synchronous:      request.Synchronous,
+		InMemory:         request.InMemory,
+		CSVNameConflicts: request.CSVNameConflicts,
+		VersionPolicy:    request.VersionPolicy,
 	}
 
 	// Add the bundles to the registry
-	err = i.RegistryAdder.AddToRegistry(addToRegistryReq)
+	result, err := i.RegistryAdder.AddToRegistry(ctx, addToRegistryReq)
 	if err != nil {
 		i.Logger.WithError(err).Debugf("unable to add bundle to registry")
 		return err
 	}
+	if len(result.AlreadyPresent) > 0 {
+		i.Logger.WithField("skipped", result.AlreadyPresent).Info("bundles already present with identical content were skipped")
+	}
+
+	// Scan the bundle and operand images referenced by the added bundles
+	if request.ScanCommand != "" {
+		if err := i.scanAddedImages(ctx, request, databasePath, existingImages, failSeverity); err != nil {
+			return err
+		}
+	}
 
 	// generate the dockerfile
-	dockerfile := i.DockerfileGenerator.GenerateIndexDockerfile(request.BinarySourceImage, databasePath)
-	err = write(dockerfile, outDockerfile, i.Logger)
+	if err := i.writeDockerfile(request.BinarySourceImage, buildDir, databasePath, outDockerfile, request.DockerfileOptions); err != nil {
+		return err
+	}
+
+	if !request.Generate {
+		// build the dockerfile
+		err = buildWithProgress(ctx, outDockerfile, buildDir, request.Tag, i.CommandRunner, i.Logger)
+		if err != nil {
+			return err
+		}
+	}
+
+	if request.ProvenanceFile == "" {
+		return nil
+	}
+	return i.recordProvenance(ctx, request, workspace, databasePath, started)
+}
+
+// recordProvenance writes the provenance of an index build and runs the provenance command on it
+func (i ImageIndexer) recordProvenance(ctx context.Context, request AddToIndexRequest, workspace *tmp.Workspace, databasePath string, started time.Time) error {
+	resolver := i.Resolver
+	if resolver == nil {
+		rootCAs, err := certs.RootCAs(request.CaFile)
+		if err != nil {
+			return fmt.Errorf("failed to get RootCAs: %v", err)
+		}
+		reg, err := containerdregistry.NewRegistry(containerdregistry.SkipTLS(request.SkipTLS), containerdregistry.WithRootCAs(rootCAs), containerdregistry.WithCacheDir(filepath.Join(workspace.Path(), "resolver-cache")))
+		if err != nil {
+			return err
+		}
+		defer func() {
+			if err := reg.Destroy(); err != nil {
+				i.Logger.WithError(err).Warn("error destroying local cache")
+			}
+		}()
+		resolver = reg
+	}
+
+	statement, err := newProvenance(ctx, resolver, request, databasePath, started, time.Now())
 	if err != nil {
 		return err
 	}
+	if err := writeProvenance(statement, request.ProvenanceFile); err != nil {
+		return err
+	}
+	i.Logger.Infof("wrote provenance to %s", request.ProvenanceFile)
 
-	if request.Generate {
+	if request.ProvenanceCommand == "" {
 		return nil
 	}
+	return runProvenanceCommand(ctx, request.ProvenanceCommand, request.ProvenanceFile, request.Tag, i.Logger)
+}
 
-	// build the dockerfile
-	err = build(outDockerfile, request.Tag, i.CommandRunner, i.Logger)
+// scanAddedImages runs the configured scanner against every image that was newly
+// referenced by the index and fails if any finding reaches the requested severity
+func (i ImageIndexer) scanAddedImages(ctx context.Context, request AddToIndexRequest, databasePath string, existingImages map[string]struct{}, failSeverity Severity) error {
+	currentImages, err := listIndexImages(databasePath)
 	if err != nil {
 		return err
 	}
 
+	scanner := i.Scanner
+	if scanner == nil {
+		scanner = NewCommandScanner(request.ScanCommand, i.Logger)
+	}
+
+	report, err := scanImages(ctx, scanner, addedImages(existingImages, currentImages))
+	if err != nil {
+		return err
+	}
+
+	for _, result := range report.Results {
+		i.Logger.WithField("image", result.Image).Infof("scan reported %d findings", len(result.Findings))
+	}
+
+	if request.ScanReportFile != "" {
+		if err := writeScanReport(report, request.ScanReportFile); err != nil {
+			return err
+		}
+	}
+
+	if request.ScanFailSeverity == "" {
+		return nil
+	}
+	if failing := report.ImagesAtOrAbove(failSeverity); len(failing) > 0 {
+		return fmt.Errorf("images with findings of severity %s or higher: %s", failSeverity, strings.Join(failing, ", "))
+	}
 	return nil
 }
 
@@ -128,18 +319,24 @@ type DeleteFromIndexRequest struct {
 	Operators         []string
 	SkipTLS           bool
 	CaFile            string
+	DockerfileOptions
 }
 
 // DeleteFromIndex is an aggregate API used to generate a registry index image
 // without specific operators
-func (i ImageIndexer) DeleteFromIndex(request DeleteFromIndexRequest) error {
-	buildDir, outDockerfile, cleanup, err := buildContext(request.Generate, request.OutDockerfile)
-	defer cleanup()
+func (i ImageIndexer) DeleteFromIndex(ctx context.Context, request DeleteFromIndexRequest) error {
+	workspace, err := tmp.NewWorkspace("index-rm")
+	if err != nil {
+		return err
+	}
+	defer workspace.Cleanup()
+
+	buildDir, outDockerfile, err := buildContext(workspace, request.Generate, request.OutDockerfile)
 	if err != nil {
 		return err
 	}
 
-	databasePath, err := i.extractDatabase(buildDir, request.FromIndex, request.CaFile, request.SkipTLS)
+	databasePath, err := i.extractDatabase(ctx, workspace, buildDir, request.FromIndex, request.CaFile, request.SkipTLS)
 	if err != nil {
 		return err
 	}
@@ -158,9 +355,7 @@ func (i ImageIndexer) DeleteFromIndex(request DeleteFromIndexRequest) error {
 	}
 
 	// generate the dockerfile
-	dockerfile := i.DockerfileGenerator.GenerateIndexDockerfile(request.BinarySourceImage, databasePath)
-	err = write(dockerfile, outDockerfile, i.Logger)
-	if err != nil {
+	if err := i.writeDockerfile(request.BinarySourceImage, buildDir, databasePath, outDockerfile, request.DockerfileOptions); err != nil {
 		return err
 	}
 
@@ -169,7 +364,7 @@ func (i ImageIndexer) DeleteFromIndex(request DeleteFromIndexRequest) error {
 	}
 
 	// build the dockerfile
-	err = build(outDockerfile, request.Tag, i.CommandRunner, i.Logger)
+	err = build(outDockerfile, buildDir, request.Tag, i.CommandRunner, i.Logger)
 	if err != nil {
 		return err
 	}
@@ -186,18 +381,24 @@ type PruneStrandedFromIndexRequest struct {
 	Tag               string
 	CaFile            string
 	SkipTLS           bool
+	DockerfileOptions
 }
 
 // PruneStrandedFromIndex is an aggregate API used to generate a registry index image
 // that has removed stranded bundles from the index
-func (i ImageIndexer) PruneStrandedFromIndex(request PruneStrandedFromIndexRequest) error {
-	buildDir, outDockerfile, cleanup, err := buildContext(request.Generate, request.OutDockerfile)
-	defer cleanup()
+func (i ImageIndexer) PruneStrandedFromIndex(ctx context.Context, request PruneStrandedFromIndexRequest) error {
+	workspace, err := tmp.NewWorkspace("index-prune-stranded")
+	if err != nil {
+		return err
+	}
+	defer workspace.Cleanup()
+
+	buildDir, outDockerfile, err := buildContext(workspace, request.Generate, request.OutDockerfile)
 	if err != nil {
 		return err
 	}
 
-	databasePath, err := i.extractDatabase(buildDir, request.FromIndex, request.CaFile, request.SkipTLS)
+	databasePath, err := i.extractDatabase(ctx, workspace, buildDir, request.FromIndex, request.CaFile, request.SkipTLS)
 	if err != nil {
 		return err
 	}
@@ -214,9 +415,7 @@ func (i ImageIndexer) PruneStrandedFromIndex(request PruneStrandedFromIndexReque
 	}
 
 	// generate the dockerfile
-	dockerfile := i.DockerfileGenerator.GenerateIndexDockerfile(request.BinarySourceImage, databasePath)
-	err = write(dockerfile, outDockerfile, i.Logger)
-	if err != nil {
+	if err := i.writeDockerfile(request.BinarySourceImage, buildDir, databasePath, outDockerfile, request.DockerfileOptions); err != nil {
 		return err
 	}
 
@@ -225,7 +424,7 @@ func (i ImageIndexer) PruneStrandedFromIndex(request PruneStrandedFromIndexReque
 	}
 
 	// build the dockerfile
-	err = build(outDockerfile, request.Tag, i.CommandRunner, i.Logger)
+	err = build(outDockerfile, buildDir, request.Tag, i.CommandRunner, i.Logger)
 	if err != nil {
 		return err
 	}
@@ -243,16 +442,28 @@ type PruneFromIndexRequest struct {
 	Packages          []string
 	CaFile            string
 	SkipTLS           bool
+	// HeadsOnly also removes every bundle that isn't the head of a channel
+	HeadsOnly bool
+	// InstallMode also removes every bundle that doesn't support this install mode
+	InstallMode string
+	DockerfileOptions
 }
 
-func (i ImageIndexer) PruneFromIndex(request PruneFromIndexRequest) error {
-	buildDir, outDockerfile, cleanup, err := buildContext(request.Generate, request.OutDockerfile)
-	defer cleanup()
+// PruneFromIndex is an aggregate API used to generate a registry index image with only the requested packages. ctx
+// carries the progress reporter; cancelling it aborts the build.
+func (i ImageIndexer) PruneFromIndex(ctx context.Context, request PruneFromIndexRequest) error {
+	workspace, err := tmp.NewWorkspace("index-prune")
 	if err != nil {
 		return err
 	}
+	defer workspace.Cleanup()
 
-	databasePath, err := i.extractDatabase(buildDir, request.FromIndex, request.CaFile, request.SkipTLS)
+	buildDir, outDockerfile, err := buildContext(workspace, request.Generate, request.OutDockerfile)
+	if err != nil {
+		return err
+	}
+
+	databasePath, err := i.extractDatabase(ctx, workspace, buildDir, request.FromIndex, request.CaFile, request.SkipTLS)
 	if err != nil {
 		return err
 	}
@@ -262,18 +473,18 @@ func (i ImageIndexer) PruneFromIndex(request PruneFromIndexRequest) error {
 		Packages:      request.Packages,
 		InputDatabase: databasePath,
 		Permissive:    request.Permissive,
+		HeadsOnly:     request.HeadsOnly,
+		InstallMode:   request.InstallMode,
 	}
 
 	// Prune the bundles from the registry
-	err = i.RegistryPruner.PruneFromRegistry(pruneFromRegistryReq)
+	err = i.RegistryPruner.PruneFromRegistry(ctx, pruneFromRegistryReq)
 	if err != nil {
 		return err
 	}
 
 	// generate the dockerfile
-	dockerfile := i.DockerfileGenerator.GenerateIndexDockerfile(request.BinarySourceImage, databasePath)
-	err = write(dockerfile, outDockerfile, i.Logger)
-	if err != nil {
+	if err := i.writeDockerfile(request.BinarySourceImage, buildDir, databasePath, outDockerfile, request.DockerfileOptions); err != nil {
 		return err
 	}
 
@@ -282,7 +493,7 @@ func (i ImageIndexer) PruneFromIndex(request PruneFromIndexRequest) error {
 	}
 
 	// build the dockerfile
-	err = build(outDockerfile, request.Tag, i.CommandRunner, i.Logger)
+	err = buildWithProgress(ctx, outDockerfile, buildDir, request.Tag, i.CommandRunner, i.Logger)
 	if err != nil {
 		return err
 	}
@@ -290,23 +501,40 @@ func (i ImageIndexer) PruneFromIndex(request PruneFromIndexRequest) error {
 	return nil
 }
 
-// extractDatabase sets a temp directory for unpacking an image
-func (i ImageIndexer) extractDatabase(buildDir, fromIndex, caFile string, skipTLS bool) (string, error) {
-	tmpDir, err := ioutil.TempDir("./", tmpDirPrefix)
+// extractDatabase unpacks the index image's database into the workspace and copies it into the build directory
+func (i ImageIndexer) extractDatabase(ctx context.Context, workspace *tmp.Workspace, buildDir, fromIndex, caFile string, skipTLS bool) (string, error) {
+	databaseFile, err := i.getDatabaseFile(ctx, workspace, fromIndex, caFile, skipTLS)
 	if err != nil {
 		return "", err
 	}
-	defer os.RemoveAll(tmpDir)
+	// copy the index to the database folder in the build directory
+	return copyDatabaseTo(databaseFile, filepath.Join(buildDir, defaultDatabaseFolder))
+}
+
+// extractDatabaseWithState extracts the database like extractDatabase, but takes it from the build state when a
+// previous attempt saved it there, and saves it otherwise
+func (i ImageIndexer) extractDatabaseWithState(ctx context.Context, workspace *tmp.Workspace, buildDir, fromIndex, caFile string, skipTLS bool, state *registry.BuildState) (string, error) {
+	if state == nil {
+		return i.extractDatabase(ctx, workspace, buildDir, fromIndex, caFile, skipTLS)
+	}
+	if saved, ok := state.SavedDatabase(); ok {
+		i.Logger.Infof("resuming with the database saved by a previous attempt in %s", state.Dir())
+		return copyDatabaseTo(saved, filepath.Join(buildDir, defaultDatabaseFolder))
+	}
 
-	databaseFile, err := i.getDatabaseFile(tmpDir, fromIndex, caFile, skipTLS)
+	databasePath, err := i.extractDatabase(ctx, workspace, buildDir, fromIndex, caFile, skipTLS)
 	if err != nil {
 		return "", err
 	}
-	// copy the index to the database folder in the build directory
-	return copyDatabaseTo(databaseFile, filepath.Join(buildDir, defaultDatabaseFolder))
+	return databasePath, state.SaveDatabase(databasePath)
 }
 
-func (i ImageIndexer) getDatabaseFile(workingDir, fromIndex, caFile string, skipTLS bool) (string, error) {
+func (i ImageIndexer) getDatabaseFile(ctx context.Context, workspace *tmp.Workspace, fromIndex, caFile string, skipTLS bool) (string, error) {
+	workingDir, err := workspace.Dir(tmpDirPrefix)
+	if err != nil {
+		return "", err
+	}
+
 	if fromIndex == "" {
 		return path.Join(workingDir, defaultDatabaseFile), nil
 	}
@@ -322,7 +550,7 @@ func (i ImageIndexer) getDatabaseFile(workingDir, fromIndex, caFile string, skip
 		if err != nil {
 			return "", fmt.Errorf("failed to get RootCAs: %v", err)
 		}
-		reg, rerr = containerdregistry.NewRegistry(containerdregistry.SkipTLS(skipTLS), containerdregistry.WithLog(i.Logger), containerdregistry.WithRootCAs(rootCAs))
+		reg, rerr = containerdregistry.NewRegistry(containerdregistry.SkipTLS(skipTLS), containerdregistry.WithLog(i.Logger), containerdregistry.WithRootCAs(rootCAs), containerdregistry.WithCacheDir(filepath.Join(workspace.Path(), "cache")))
 	case containertools.PodmanTool:
 		fallthrough
 	case containertools.DockerTool:
@@ -339,12 +567,12 @@ func (i ImageIndexer) getDatabaseFile(workingDir, fromIndex, caFile string, skip
 
 	imageRef := image.SimpleReference(fromIndex)
 
-	if err := reg.Pull(context.TODO(), imageRef); err != nil {
+	if err := reg.Pull(ctx, imageRef); err != nil {
 		return "", err
 	}
 
 	// Get the old index image's dbLocationLabel to find this path
-	labels, err := reg.Labels(context.TODO(), imageRef)
+	labels, err := reg.Labels(ctx, imageRef)
 	if err != nil {
 		return "", err
 	}
@@ -354,7 +582,7 @@ func (i ImageIndexer) getDatabaseFile(workingDir, fromIndex, caFile string, skip
 		return "", fmt.Errorf("index image %s missing label %s", fromIndex, containertools.DbLocationLabel)
 	}
 
-	if err := reg.Unpack(context.TODO(), imageRef, workingDir); err != nil {
+	if err := reg.Unpack(ctx, imageRef, workingDir); err != nil {
 		return "", err
 	}
 
@@ -392,7 +620,9 @@ func copyDatabaseTo(databaseFile, targetDir string) (string, error) {
 	return to.Name(), err
 }
 
-func buildContext(generate bool, requestedDockerfile string) (buildDir, outDockerfile string, cleanup func(), err error) {
+// buildContext returns the directory used as the context of the image build, and the dockerfile to write.
+// Generated output is kept in the current directory; everything else lives in the workspace.
+func buildContext(workspace *tmp.Workspace, generate bool, requestedDockerfile string) (buildDir, outDockerfile string, err error) {
 	if generate {
 		buildDir = "./"
 		if len(requestedDockerfile) == 0 {
@@ -400,18 +630,14 @@ func buildContext(generate bool, requestedDockerfile string) (buildDir, outDocke
 		} else {
 			outDockerfile = requestedDockerfile
 		}
-		cleanup = func() {}
 		return
 	}
 
 	// set a temp directory for building the new image
-	buildDir, err = ioutil.TempDir(".", tmpBuildDirPrefix)
+	buildDir, err = workspace.Dir(tmpBuildDirPrefix)
 	if err != nil {
 		return
 	}
-	cleanup = func() {
-		os.RemoveAll(buildDir)
-	}
 
 	if len(requestedDockerfile) > 0 {
 		outDockerfile = requestedDockerfile
@@ -419,28 +645,33 @@ func buildContext(generate bool, requestedDockerfile string) (buildDir, outDocke
 	}
 
 	// generate a temp dockerfile if needed
-	tempDockerfile, err := ioutil.TempFile(".", defaultDockerfileName)
+	tempDockerfile, err := workspace.File(defaultDockerfileName)
 	if err != nil {
-		defer cleanup()
 		return
 	}
 	outDockerfile = tempDockerfile.Name()
-	cleanup = func() {
-		os.RemoveAll(buildDir)
-		os.Remove(outDockerfile)
-	}
+	err = tempDockerfile.Close()
 
 	return
 }
 
-func build(dockerfilePath, imageTag string, commandRunner containertools.CommandRunner, logger *logrus.Entry) error {
+// contextPath returns the path of a file in the build directory as seen from the build context
+func contextPath(buildDir, file string) string {
+	rel, err := filepath.Rel(buildDir, file)
+	if err != nil {
+		return file
+	}
+	return rel
+}
+
+func build(dockerfilePath, buildDir, imageTag string, commandRunner containertools.CommandRunner, logger *logrus.Entry) error {
 	if imageTag == "" {
 		imageTag = defaultImageTag
 	}
 
 	logger.Debugf("building container image: %s", imageTag)
 
-	err := commandRunner.Build(dockerfilePath, imageTag)
+	err := commandRunner.BuildFromContext(dockerfilePath, imageTag, buildDir)
 	if err != nil {
 		return err
 	}
@@ -448,6 +679,29 @@ func build(dockerfilePath, imageTag string, commandRunner containertools.Command
 	return nil
 }
 
+// buildWithProgress builds the index image unless the operation has been cancelled, reporting the build as a single step
+func buildWithProgress(ctx context.Context, dockerfilePath, buildDir, imageTag string, commandRunner containertools.CommandRunner, logger *logrus.Entry) error {
+	if err := progress.Canceled(ctx); err != nil {
+		return err
+	}
+	progress.Report(ctx, "build", imageTag, 0, 1)
+	if err := build(dockerfilePath, buildDir, imageTag, commandRunner, logger); err != nil {
+		return err
+	}
+	progress.Report(ctx, "build", imageTag, 1, 1)
+	return nil
+}
+
+// writeDockerfile writes the dockerfile that adds the database to the binary source image. The database is
+// normalized first, so that an unchanged catalog is added as the same layer as in the previous build.
+func (i ImageIndexer) writeDockerfile(binarySourceImage, buildDir, databasePath, outDockerfile string, options DockerfileOptions) error {
+	if err := normalizeDatabaseLayer(databasePath); err != nil {
+		return fmt.Errorf("error normalizing database %s: %s", databasePath, err)
+	}
+	dockerfile := i.DockerfileGenerator.GenerateIndexDockerfile(binarySourceImage, contextPath(buildDir, databasePath), containertools.WithLabels(options.Labels), containertools.WithBuildArgs(options.BuildArgs))
+	return write(dockerfile, outDockerfile, i.Logger)
+}
+
 func write(dockerfileText, outDockerfile string, logger *logrus.Entry) error {
 	if outDockerfile == "" {
 		outDockerfile = defaultDockerfileName
@@ -479,17 +733,16 @@ type ExportFromIndexRequest struct {
 }
 
 // ExportFromIndex is an aggregate API used to specify operators from
-// an index image
-func (i ImageIndexer) ExportFromIndex(request ExportFromIndexRequest) error {
-	// set a temp directory
-	workingDir, err := ioutil.TempDir("./", tmpDirPrefix)
+// an index image. ctx carries the progress reporter; cancelling it aborts the export and removes the partial output.
+func (i ImageIndexer) ExportFromIndex(ctx context.Context, request ExportFromIndexRequest) error {
+	workspace, err := tmp.NewWorkspace("index-export")
 	if err != nil {
 		return err
 	}
-	defer os.RemoveAll(workingDir)
+	defer workspace.Cleanup()
 
 	// extract the index database to the file
-	databaseFile, err := i.getDatabaseFile(workingDir, request.Index, request.CaFile, request.SkipTLS)
+	databaseFile, err := i.getDatabaseFile(ctx, workspace, request.Index, request.CaFile, request.SkipTLS)
 	if err != nil {
 		return err
 	}
@@ -504,7 +757,7 @@ func (i ImageIndexer) ExportFromIndex(request ExportFromIndexRequest) error {
 
 	// fetch all packages from the index image if packages is empty
 	if len(request.Packages) == 0 {
-		request.Packages, err = dbQuerier.ListPackages(context.TODO())
+		request.Packages, err = dbQuerier.ListPackages(ctx)
 		if err != nil {
 			return err
 		}
@@ -517,6 +770,14 @@ func (i ImageIndexer) ExportFromIndex(request ExportFromIndexRequest) error {
 
 	i.Logger.Infof("Preparing to pull bundles %+q", bundles)
 
+	// remember the package directories that already exist so an interrupted export only removes its own output
+	existingDirs := make(map[string]bool)
+	for _, packageName := range request.Packages {
+		if _, err := os.Stat(filepath.Join(request.DownloadPath, packageName)); err == nil {
+			existingDirs[packageName] = true
+		}
+	}
+
 	// Creating downloadPath dir
 	if err := os.MkdirAll(request.DownloadPath, 0777); err != nil {
 		return err
@@ -526,6 +787,7 @@ func (i ImageIndexer) ExportFromIndex(request ExportFromIndexRequest) error {
 	var wg sync.WaitGroup
 	wg.Add(len(bundles))
 	var mu = &sync.Mutex{}
+	exported := 0
 
 	sem := make(chan struct{}, concurrencyLimitForExport)
 
@@ -538,6 +800,10 @@ func (i ImageIndexer) ExportFromIndex(request ExportFromIndexRequest) error {
 				<-sem
 			}()
 
+			if progress.Canceled(ctx) != nil {
+				return
+			}
+
 			// generate a random folder name if bundle version is empty
 			if bundleDir.bundleVersion == "" {
 				bundleDir.bundleVersion = strconv.Itoa(rand.Intn(10000))
@@ -548,12 +814,27 @@ func (i ImageIndexer) ExportFromIndex(request ExportFromIndexRequest) error {
 				mu.Lock()
 				errs = append(errs, err)
 				mu.Unlock()
+				return
 			}
+
+			mu.Lock()
+			exported++
+			progress.Report(ctx, "export", bundleImage, exported, len(bundles))
+			mu.Unlock()
 		}(bundleImage, bundleDir)
 	}
 	// Wait for all the go routines to finish export
 	wg.Wait()
 
+	if err := progress.Canceled(ctx); err != nil {
+		for _, packageName := range request.Packages {
+			if !existingDirs[packageName] {
+				os.RemoveAll(filepath.Join(request.DownloadPath, packageName))
+			}
+		}
+		return err
+	}
+
 	if errs != nil {
 		return utilerrors.NewAggregate(errs)
 	}
@@ -590,38 +871,14 @@ func getBundlesToExport(dbQuerier pregistry.Query, packages []string) (map[strin
 func generatePackageYaml(dbQuerier pregistry.Query, packageName, downloadPath string) error {
 	var errs []error
 
-	defaultChannel, err := dbQuerier.GetDefaultChannelForPackage(context.TODO(), packageName)
+	// GetPackage, unlike ListChannels, doesn't need the deprecated table, which the unmigrated databases of older
+	// index images don't have
+	manifest, err := dbQuerier.GetPackage(context.TODO(), packageName)
 	if err != nil {
 		return err
 	}
 
-	channelList, err := dbQuerier.ListChannels(context.TODO(), packageName)
-	if err != nil {
-		return err
-	}
-
-	channels := []pregistry.PackageChannel{}
-	for _, ch := range channelList {
-		csvName, err := dbQuerier.GetCurrentCSVNameForChannel(context.TODO(), packageName, ch)
-		if err != nil {
-			err = fmt.Errorf("error exporting bundle from image: %s", err)
-			errs = append(errs, err)
-			continue
-		}
-		channels = append(channels,
-			pregistry.PackageChannel{
-				Name:           ch,
-				CurrentCSVName: csvName,
-			})
-	}
-
-	manifest := pregistry.PackageManifest{
-		PackageName:        packageName,
-		DefaultChannelName: defaultChannel,
-		Channels:           channels,
-	}
-
-	manifestBytes, err := yaml.Marshal(&manifest)
+	manifestBytes, err := yaml.Marshal(manifest)
 	if err != nil {
 		errs = append(errs, err)
 		return utilerrors.NewAggregate(errs)
@@ -646,18 +903,24 @@ type DeprecateFromIndexRequest struct {
 	Tag               string
 	CaFile            string
 	SkipTLS           bool
+	DockerfileOptions
 }
 
 // DeprecateFromIndex takes a DeprecateFromIndexRequest and deprecates the requested
 // bundles.
-func (i ImageIndexer) DeprecateFromIndex(request DeprecateFromIndexRequest) error {
-	buildDir, outDockerfile, cleanup, err := buildContext(request.Generate, request.OutDockerfile)
-	defer cleanup()
+func (i ImageIndexer) DeprecateFromIndex(ctx context.Context, request DeprecateFromIndexRequest) error {
+	workspace, err := tmp.NewWorkspace("index-deprecatetruncate")
 	if err != nil {
 		return err
 	}
+	defer workspace.Cleanup()
 
-	databasePath, err := i.extractDatabase(buildDir, request.FromIndex, request.CaFile, request.SkipTLS)
+	buildDir, outDockerfile, err := buildContext(workspace, request.Generate, request.OutDockerfile)
+	if err != nil {
+		return err
+	}
+
+	databasePath, err := i.extractDatabase(ctx, workspace, buildDir, request.FromIndex, request.CaFile, request.SkipTLS)
 	if err != nil {
 		return err
 	}
@@ -676,9 +939,7 @@ func (i ImageIndexer) DeprecateFromIndex(request DeprecateFromIndexRequest) erro
 	}
 
 	// generate the dockerfile
-	dockerfile := i.DockerfileGenerator.GenerateIndexDockerfile(request.BinarySourceImage, databasePath)
-	err = write(dockerfile, outDockerfile, i.Logger)
-	if err != nil {
+	if err := i.writeDockerfile(request.BinarySourceImage, buildDir, databasePath, outDockerfile, request.DockerfileOptions); err != nil {
 		return err
 	}
 
@@ -687,7 +948,7 @@ func (i ImageIndexer) DeprecateFromIndex(request DeprecateFromIndexRequest) erro
 	}
 
 	// build the dockerfile with requested tooling
-	err = build(outDockerfile, request.Tag, i.CommandRunner, i.Logger)
+	err = build(outDockerfile, buildDir, request.Tag, i.CommandRunner, i.Logger)
 	if err != nil {
 		return err
 	}
@@ -2,16 +2,18 @@
 package indexerfakes
 
 import (
+	"context"
 	"sync"
 
 	"github.com/operator-framework/operator-registry/pkg/lib/indexer"
 )
 
 type FakeIndexAdder struct {
-	AddToIndexStub        func(indexer.AddToIndexRequest) error
+	AddToIndexStub        func(context.Context, indexer.AddToIndexRequest) error
 	addToIndexMutex       sync.RWMutex
 	addToIndexArgsForCall []struct {
-		arg1 indexer.AddToIndexRequest
+		arg1 context.Context
+		arg2 indexer.AddToIndexRequest
 	}
 	addToIndexReturns struct {
 		result1 error
@@ -23,16 +25,17 @@ type FakeIndexAdder struct {
 	invocationsMutex sync.RWMutex
 }
 
-func (fake *FakeIndexAdder) AddToIndex(arg1 indexer.AddToIndexRequest) error {
+func (fake *FakeIndexAdder) AddToIndex(arg1 context.Context, arg2 indexer.AddToIndexRequest) error {
 	fake.addToIndexMutex.Lock()
 	ret, specificReturn := fake.addToIndexReturnsOnCall[len(fake.addToIndexArgsForCall)]
 	fake.addToIndexArgsForCall = append(fake.addToIndexArgsForCall, struct {
-		arg1 indexer.AddToIndexRequest
-	}{arg1})
-	fake.recordInvocation("AddToIndex", []interface{}{arg1})
+		arg1 context.Context
+		arg2 indexer.AddToIndexRequest
+	}{arg1, arg2})
+	fake.recordInvocation("AddToIndex", []interface{}{arg1, arg2})
 	fake.addToIndexMutex.Unlock()
 	if fake.AddToIndexStub != nil {
-		return fake.AddToIndexStub(arg1)
+		return fake.AddToIndexStub(arg1, arg2)
 	}
 	if specificReturn {
 		return ret.result1
@@ -47,17 +50,17 @@ func (fake *FakeIndexAdder) AddToIndexCallCount() int {
 	return len(fake.addToIndexArgsForCall)
 }
 
-func (fake *FakeIndexAdder) AddToIndexCalls(stub func(indexer.AddToIndexRequest) error) {
+func (fake *FakeIndexAdder) AddToIndexCalls(stub func(context.Context, indexer.AddToIndexRequest) error) {
 	fake.addToIndexMutex.Lock()
 	defer fake.addToIndexMutex.Unlock()
 	fake.AddToIndexStub = stub
 }
 
-func (fake *FakeIndexAdder) AddToIndexArgsForCall(i int) indexer.AddToIndexRequest {
+func (fake *FakeIndexAdder) AddToIndexArgsForCall(i int) (context.Context, indexer.AddToIndexRequest) {
 	fake.addToIndexMutex.RLock()
 	defer fake.addToIndexMutex.RUnlock()
 	argsForCall := fake.addToIndexArgsForCall[i]
-	return argsForCall.arg1
+	return argsForCall.arg1, argsForCall.arg2
 }
 
 func (fake *FakeIndexAdder) AddToIndexReturns(result1 error) {
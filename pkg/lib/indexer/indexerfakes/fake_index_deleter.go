@@ -2,16 +2,18 @@
 package indexerfakes
 
 import (
+	"context"
 	"sync"
 
 	"github.com/operator-framework/operator-registry/pkg/lib/indexer"
 )
 
 type FakeIndexDeleter struct {
-	DeleteFromIndexStub        func(indexer.DeleteFromIndexRequest) error
+	DeleteFromIndexStub        func(context.Context, indexer.DeleteFromIndexRequest) error
 	deleteFromIndexMutex       sync.RWMutex
 	deleteFromIndexArgsForCall []struct {
-		arg1 indexer.DeleteFromIndexRequest
+		arg1 context.Context
+		arg2 indexer.DeleteFromIndexRequest
 	}
 	deleteFromIndexReturns struct {
 		result1 error
@@ -23,16 +25,17 @@ type FakeIndexDeleter struct {
 	invocationsMutex sync.RWMutex
 }
 
-func (fake *FakeIndexDeleter) DeleteFromIndex(arg1 indexer.DeleteFromIndexRequest) error {
+func (fake *FakeIndexDeleter) DeleteFromIndex(arg1 context.Context, arg2 indexer.DeleteFromIndexRequest) error {
 	fake.deleteFromIndexMutex.Lock()
 	ret, specificReturn := fake.deleteFromIndexReturnsOnCall[len(fake.deleteFromIndexArgsForCall)]
 	fake.deleteFromIndexArgsForCall = append(fake.deleteFromIndexArgsForCall, struct {
-		arg1 indexer.DeleteFromIndexRequest
-	}{arg1})
-	fake.recordInvocation("DeleteFromIndex", []interface{}{arg1})
+		arg1 context.Context
+		arg2 indexer.DeleteFromIndexRequest
+	}{arg1, arg2})
+	fake.recordInvocation("DeleteFromIndex", []interface{}{arg1, arg2})
 	fake.deleteFromIndexMutex.Unlock()
 	if fake.DeleteFromIndexStub != nil {
-		return fake.DeleteFromIndexStub(arg1)
+		return fake.DeleteFromIndexStub(arg1, arg2)
 	}
 	if specificReturn {
 		return ret.result1
@@ -47,17 +50,17 @@ func (fake *FakeIndexDeleter) DeleteFromIndexCallCount() int {
 	return len(fake.deleteFromIndexArgsForCall)
 }
 
-func (fake *FakeIndexDeleter) DeleteFromIndexCalls(stub func(indexer.DeleteFromIndexRequest) error) {
+func (fake *FakeIndexDeleter) DeleteFromIndexCalls(stub func(context.Context, indexer.DeleteFromIndexRequest) error) {
 	fake.deleteFromIndexMutex.Lock()
 	defer fake.deleteFromIndexMutex.Unlock()
 	fake.DeleteFromIndexStub = stub
 }
 
-func (fake *FakeIndexDeleter) DeleteFromIndexArgsForCall(i int) indexer.DeleteFromIndexRequest {
+func (fake *FakeIndexDeleter) DeleteFromIndexArgsForCall(i int) (context.Context, indexer.DeleteFromIndexRequest) {
 	fake.deleteFromIndexMutex.RLock()
 	defer fake.deleteFromIndexMutex.RUnlock()
 	argsForCall := fake.deleteFromIndexArgsForCall[i]
-	return argsForCall.arg1
+	return argsForCall.arg1, argsForCall.arg2
 }
 
 func (fake *FakeIndexDeleter) DeleteFromIndexReturns(result1 error) {
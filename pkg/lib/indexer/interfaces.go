@@ -2,6 +2,8 @@
 package indexer
 
 import (
+	"context"
+
 	"github.com/operator-framework/operator-registry/pkg/containertools"
 	"github.com/operator-framework/operator-registry/pkg/lib/registry"
 	"github.com/sirupsen/logrus"
@@ -11,7 +13,7 @@ import (
 // based on previous index images
 //counterfeiter:generate . IndexAdder
 type IndexAdder interface {
-	AddToIndex(AddToIndexRequest) error
+	AddToIndex(context.Context, AddToIndexRequest) error
 }
 
 // NewIndexAdder is a constructor that returns an IndexAdder
@@ -31,7 +33,7 @@ func NewIndexAdder(buildTool, pullTool containertools.ContainerTool, logger *log
 // from them
 //counterfeiter:generate . IndexDeleter
 type IndexDeleter interface {
-	DeleteFromIndex(DeleteFromIndexRequest) error
+	DeleteFromIndex(context.Context, DeleteFromIndexRequest) error
 }
 
 // NewIndexDeleter is a constructor that returns an IndexDeleter
@@ -49,7 +51,7 @@ func NewIndexDeleter(buildTool, pullTool containertools.ContainerTool, logger *l
 
 //counterfeiter:generate . IndexExporter
 type IndexExporter interface {
-	ExportFromIndex(ExportFromIndexRequest) error
+	ExportFromIndex(context.Context, ExportFromIndexRequest) error
 }
 
 // NewIndexExporter is a constructor that returns an IndexExporter
@@ -66,7 +68,7 @@ func NewIndexExporter(containerTool containertools.ContainerTool, logger *logrus
 
 // IndexStrandedPruner prunes operators out of an index
 type IndexStrandedPruner interface {
-	PruneStrandedFromIndex(PruneStrandedFromIndexRequest) error
+	PruneStrandedFromIndex(context.Context, PruneStrandedFromIndexRequest) error
 }
 
 func NewIndexStrandedPruner(containerTool containertools.ContainerTool, logger *logrus.Entry) IndexStrandedPruner {
@@ -83,7 +85,7 @@ func NewIndexStrandedPruner(containerTool containertools.ContainerTool, logger *
 
 // IndexPruner prunes operators out of an index
 type IndexPruner interface {
-	PruneFromIndex(PruneFromIndexRequest) error
+	PruneFromIndex(context.Context, PruneFromIndexRequest) error
 }
 
 func NewIndexPruner(containerTool containertools.ContainerTool, logger *logrus.Entry) IndexPruner {
@@ -100,7 +102,7 @@ func NewIndexPruner(containerTool containertools.ContainerTool, logger *logrus.E
 
 // IndexDeprecator prunes operators out of an index
 type IndexDeprecator interface {
-	DeprecateFromIndex(DeprecateFromIndexRequest) error
+	DeprecateFromIndex(context.Context, DeprecateFromIndexRequest) error
 }
 
 func NewIndexDeprecator(buildTool, pullTool containertools.ContainerTool, logger *logrus.Entry) IndexDeprecator {
@@ -0,0 +1,45 @@
+package indexer
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+)
+
+// SourceDateEpochEnv is the environment variable that sets the modification time of the database in an index image,
+// as seconds since the unix epoch. See https://reproducible-builds.org/specs/source-date-epoch/
+const SourceDateEpochEnv = "SOURCE_DATE_EPOCH"
+
+// databaseMode is the file mode of the database in an index image, whatever the umask it was written with
+const databaseMode os.FileMode = 0644
+
+// normalizeDatabaseLayer fixes the metadata of the database file an index image adds, so that the layer it is added
+// in only depends on its content. The binaries come from the layers of the binary source image, which are shared by
+// every index built from it, and the database is the only layer an index build adds. An index rebuilt without
+// changes to its catalog then has the same layer digest as before, which registries and mirrors don't need to store
+// or transfer again.
+func normalizeDatabaseLayer(path string) error {
+	modified, err := layerTime()
+	if err != nil {
+		return err
+	}
+	if err := os.Chmod(path, databaseMode); err != nil {
+		return err
+	}
+	return os.Chtimes(path, modified, modified)
+}
+
+// layerTime returns the modification time of the files in the layers of an index image, the time set in
+// SOURCE_DATE_EPOCH or the unix epoch if it isn't set
+func layerTime() (time.Time, error) {
+	epoch := os.Getenv(SourceDateEpochEnv)
+	if epoch == "" {
+		return time.Unix(0, 0), nil
+	}
+	seconds, err := strconv.ParseInt(epoch, 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid %s %q: %s", SourceDateEpochEnv, epoch, err)
+	}
+	return time.Unix(seconds, 0), nil
+}
@@ -0,0 +1,37 @@
+package indexer
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalizeDatabaseLayer(t *testing.T) {
+	dir, err := ioutil.TempDir("", "database-layer-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "index.db")
+	require.NoError(t, ioutil.WriteFile(path, []byte("catalog"), 0600))
+
+	defer os.Unsetenv(SourceDateEpochEnv)
+	os.Unsetenv(SourceDateEpochEnv)
+	require.NoError(t, normalizeDatabaseLayer(path))
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	require.Equal(t, databaseMode, info.Mode())
+	require.True(t, info.ModTime().Equal(time.Unix(0, 0)))
+
+	os.Setenv(SourceDateEpochEnv, "1600000000")
+	require.NoError(t, normalizeDatabaseLayer(path))
+	info, err = os.Stat(path)
+	require.NoError(t, err)
+	require.True(t, info.ModTime().Equal(time.Unix(1600000000, 0)))
+
+	os.Setenv(SourceDateEpochEnv, "yesterday")
+	require.Error(t, normalizeDatabaseLayer(path))
+}
@@ -0,0 +1,234 @@
+package indexer
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/operator-framework/operator-registry/pkg/containertools"
+	"github.com/operator-framework/operator-registry/pkg/image"
+)
+
+const (
+	// InTotoStatementType is the type of the in-toto statement a provenance is written as
+	InTotoStatementType = "https://in-toto.io/Statement/v0.1"
+	// SLSAProvenancePredicateType is the type of the SLSA provenance predicate of the statement
+	SLSAProvenancePredicateType = "https://slsa.dev/provenance/v0.2"
+	// IndexAddBuildType identifies an index built by opm index add, whose parameters are IndexAddParameters
+	IndexAddBuildType = "https://github.com/operator-framework/operator-registry/index-add@v1"
+	// OpmBuilderID identifies opm as the builder of an index. The id of a build is followed by the version of opm.
+	OpmBuilderID = "https://github.com/operator-framework/operator-registry/cmd/opm"
+)
+
+// ProvenanceStatement is an in-toto statement of how an index was built, with a SLSA provenance predicate
+type ProvenanceStatement struct {
+	Type          string              `json:"_type"`
+	PredicateType string              `json:"predicateType"`
+	Subject       []ProvenanceSubject `json:"subject"`
+	Predicate     Provenance          `json:"predicate"`
+}
+
+// ProvenanceSubject is an artifact the statement is about
+type ProvenanceSubject struct {
+	Name   string            `json:"name"`
+	Digest map[string]string `json:"digest"`
+}
+
+// Provenance describes the builder, parameters and inputs of a build
+type Provenance struct {
+	Builder    ProvenanceBuilder    `json:"builder"`
+	BuildType  string               `json:"buildType"`
+	Invocation ProvenanceInvocation `json:"invocation"`
+	Metadata   ProvenanceMetadata   `json:"metadata"`
+	Materials  []ProvenanceMaterial `json:"materials"`
+}
+
+// ProvenanceBuilder identifies what ran a build
+type ProvenanceBuilder struct {
+	ID string `json:"id"`
+}
+
+// ProvenanceInvocation holds the parameters a build was run with
+type ProvenanceInvocation struct {
+	Parameters IndexAddParameters `json:"parameters"`
+}
+
+// ProvenanceMetadata holds when a build ran and how complete its provenance is
+type ProvenanceMetadata struct {
+	BuildStartedOn  time.Time              `json:"buildStartedOn"`
+	BuildFinishedOn time.Time              `json:"buildFinishedOn"`
+	Completeness    ProvenanceCompleteness `json:"completeness"`
+	Reproducible    bool                   `json:"reproducible"`
+}
+
+// ProvenanceCompleteness tells which parts of a provenance list everything that went into a build
+type ProvenanceCompleteness struct {
+	Parameters  bool `json:"parameters"`
+	Environment bool `json:"environment"`
+	Materials   bool `json:"materials"`
+}
+
+// ProvenanceMaterial is an input of a build
+type ProvenanceMaterial struct {
+	URI    string            `json:"uri"`
+	Digest map[string]string `json:"digest"`
+}
+
+// IndexAddParameters are the parameters of an index add that determine the index that is built
+type IndexAddParameters struct {
+	FromIndex         string            `json:"fromIndex,omitempty"`
+	Bundles           []string          `json:"bundles"`
+	BinarySourceImage string            `json:"binarySourceImage"`
+	Tag               string            `json:"tag,omitempty"`
+	Mode              string            `json:"mode"`
+	Permissive        bool              `json:"permissive"`
+	CSVNameConflicts  string            `json:"csvNameConflicts,omitempty"`
+	Labels            map[string]string `json:"labels,omitempty"`
+	BuildArgs         map[string]string `json:"buildArgs,omitempty"`
+}
+
+// ImageResolver resolves an image reference to the digest of its manifest without pulling its content
+type ImageResolver interface {
+	Resolve(ctx context.Context, ref image.Reference) (string, error)
+}
+
+// newProvenance returns the provenance of an index add whose database was written to databasePath. The images the
+// index was built from are recorded by digest, resolving those that weren't pulled by digest.
+func newProvenance(ctx context.Context, resolver ImageResolver, request AddToIndexRequest, databasePath string, started, finished time.Time) (*ProvenanceStatement, error) {
+	databaseDigest, err := fileDigest(databasePath)
+	if err != nil {
+		return nil, err
+	}
+
+	binarySourceImage := request.BinarySourceImage
+	if binarySourceImage == "" {
+		binarySourceImage = containertools.DefaultBinarySourceImage
+	}
+	images := []string{binarySourceImage}
+	if request.FromIndex != "" {
+		images = append(images, request.FromIndex)
+	}
+	images = append(images, request.Bundles...)
+
+	var materials []ProvenanceMaterial
+	for _, img := range images {
+		digest, err := imageDigest(ctx, resolver, img)
+		if err != nil {
+			return nil, err
+		}
+		materials = append(materials, ProvenanceMaterial{URI: img, Digest: digest})
+	}
+
+	builderVersion := request.BuilderVersion
+	if builderVersion == "" {
+		builderVersion = "unknown"
+	}
+
+	return &ProvenanceStatement{
+		Type:          InTotoStatementType,
+		PredicateType: SLSAProvenancePredicateType,
+		Subject: []ProvenanceSubject{{
+			Name:   containertools.DefaultDbLocation,
+			Digest: databaseDigest,
+		}},
+		Predicate: Provenance{
+			Builder:   ProvenanceBuilder{ID: OpmBuilderID + "@" + builderVersion},
+			BuildType: IndexAddBuildType,
+			Invocation: ProvenanceInvocation{
+				Parameters: IndexAddParameters{
+					FromIndex:         request.FromIndex,
+					Bundles:           request.Bundles,
+					BinarySourceImage: binarySourceImage,
+					Tag:               request.Tag,
+					Mode:              request.Mode.String(),
+					Permissive:        request.Permissive,
+					CSVNameConflicts:  string(request.CSVNameConflicts),
+					Labels:            request.Labels,
+					BuildArgs:         request.BuildArgs,
+				},
+			},
+			Metadata: ProvenanceMetadata{
+				BuildStartedOn:  started.UTC(),
+				BuildFinishedOn: finished.UTC(),
+				Completeness: ProvenanceCompleteness{
+					Parameters: true,
+					Materials:  true,
+				},
+			},
+			Materials: materials,
+		},
+	}, nil
+}
+
+// imageDigest returns the digest of an image, taken from its reference if it was pulled by digest
+func imageDigest(ctx context.Context, resolver ImageResolver, img string) (map[string]string, error) {
+	digest := ""
+	if i := strings.LastIndex(img, "@"); i >= 0 {
+		digest = img[i+1:]
+	} else {
+		if resolver == nil {
+			return nil, fmt.Errorf("no image resolver to resolve the digest of %s with", img)
+		}
+		var err error
+		if digest, err = resolver.Resolve(ctx, image.SimpleReference(img)); err != nil {
+			return nil, fmt.Errorf("error resolving the digest of %s: %s", img, err)
+		}
+	}
+
+	split := strings.SplitN(digest, ":", 2)
+	if len(split) != 2 || split[0] == "" || split[1] == "" {
+		return nil, fmt.Errorf("invalid digest %q of %s", digest, img)
+	}
+	return map[string]string{split[0]: split[1]}, nil
+}
+
+// fileDigest returns the sha256 digest of a file
+func fileDigest(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return nil, err
+	}
+	return map[string]string{"sha256": hex.EncodeToString(h.Sum(nil))}, nil
+}
+
+// writeProvenance writes a provenance statement as JSON to path
+func writeProvenance(statement *ProvenanceStatement, path string) error {
+	out, err := json.MarshalIndent(statement, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, out, 0644)
+}
+
+// runProvenanceCommand runs the given command with the path of the provenance and the tag of the built image
+// appended, e.g. to push the image and sign and attach the provenance to it
+func runProvenanceCommand(ctx context.Context, command, provenancePath, tag string, logger *logrus.Entry) error {
+	fields := strings.Fields(command)
+	if len(fields) == 0 {
+		return fmt.Errorf("no provenance command configured")
+	}
+
+	args := append(append([]string{}, fields[1:]...), provenancePath, tag)
+	cmd := exec.CommandContext(ctx, fields[0], args...)
+	logger.Infof("running %s", strings.Join(cmd.Args, " "))
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("error running provenance command: %s: %s", err, out)
+	}
+	return nil
+}
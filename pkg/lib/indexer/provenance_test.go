@@ -0,0 +1,116 @@
+package indexer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+
+	"github.com/operator-framework/operator-registry/pkg/containertools"
+	"github.com/operator-framework/operator-registry/pkg/containertools/containertoolsfakes"
+	"github.com/operator-framework/operator-registry/pkg/image"
+	"github.com/operator-framework/operator-registry/pkg/lib/registry"
+	"github.com/operator-framework/operator-registry/pkg/lib/registry/registryfakes"
+	"github.com/operator-framework/operator-registry/pkg/lib/tmp"
+)
+
+type fakeResolver map[string]string
+
+func (f fakeResolver) Resolve(_ context.Context, ref image.Reference) (string, error) {
+	digest, ok := f[ref.String()]
+	if !ok {
+		return "", fmt.Errorf("unexpected image %s", ref)
+	}
+	return digest, nil
+}
+
+func TestAddToIndexProvenance(t *testing.T) {
+	workDir, err := ioutil.TempDir("", "index-provenance-")
+	require.NoError(t, err)
+	defer os.RemoveAll(workDir)
+	tmp.SetWorkDir(workDir)
+	defer tmp.SetWorkDir("")
+
+	const (
+		bundle       = "quay.io/example/bundle:v1"
+		pinnedBundle = "quay.io/example/bundle@sha256:2222"
+	)
+	adder := &registryfakes.FakeRegistryAdder{}
+	adder.AddToRegistryCalls(func(ctx context.Context, r registry.AddToRegistryRequest) (registry.AddToRegistryResult, error) {
+		return registry.AddToRegistryResult{}, ioutil.WriteFile(r.InputDatabase, []byte("catalog"), 0644)
+	})
+	indexer := ImageIndexer{
+		DockerfileGenerator: &containertoolsfakes.FakeDockerfileGenerator{},
+		CommandRunner:       &containertoolsfakes.FakeCommandRunner{},
+		RegistryAdder:       adder,
+		PullTool:            containertools.NoneTool,
+		Resolver: fakeResolver{
+			bundle:                                  "sha256:1111",
+			containertools.DefaultBinarySourceImage: "sha256:0000",
+		},
+		Logger: logrus.NewEntry(logrus.New()),
+	}
+
+	provenancePath := filepath.Join(workDir, "provenance.json")
+	// the provenance command gets the provenance and the tag, so copying one to the other shows it ran
+	attached := filepath.Join(workDir, "attached.json")
+	request := AddToIndexRequest{
+		Bundles:           []string{bundle, pinnedBundle},
+		OutDockerfile:     filepath.Join(workDir, "index.Dockerfile"),
+		Tag:               attached,
+		DockerfileOptions: DockerfileOptions{Labels: map[string]string{"team": "monitoring"}},
+		ProvenanceFile:    provenancePath,
+		ProvenanceCommand: "cp",
+		BuilderVersion:    "v1.2.3",
+	}
+	require.NoError(t, indexer.AddToIndex(context.TODO(), request))
+
+	out, err := ioutil.ReadFile(provenancePath)
+	require.NoError(t, err)
+	var statement ProvenanceStatement
+	require.NoError(t, json.Unmarshal(out, &statement))
+
+	require.Equal(t, InTotoStatementType, statement.Type)
+	require.Equal(t, SLSAProvenancePredicateType, statement.PredicateType)
+	// sha256 of "catalog"
+	require.Equal(t, []ProvenanceSubject{{
+		Name:   containertools.DefaultDbLocation,
+		Digest: map[string]string{"sha256": "652f55016243bf1b9f1bbea46d5749ef892dbe394e46de9d66ab1aacf0b4af57"},
+	}}, statement.Subject)
+
+	predicate := statement.Predicate
+	require.Equal(t, OpmBuilderID+"@v1.2.3", predicate.Builder.ID)
+	require.Equal(t, IndexAddBuildType, predicate.BuildType)
+	require.Equal(t, IndexAddParameters{
+		Bundles:           []string{bundle, pinnedBundle},
+		BinarySourceImage: containertools.DefaultBinarySourceImage,
+		Tag:               attached,
+		Mode:              "replaces",
+		Labels:            map[string]string{"team": "monitoring"},
+	}, predicate.Invocation.Parameters)
+	require.Equal(t, []ProvenanceMaterial{
+		{URI: containertools.DefaultBinarySourceImage, Digest: map[string]string{"sha256": "0000"}},
+		{URI: bundle, Digest: map[string]string{"sha256": "1111"}},
+		{URI: pinnedBundle, Digest: map[string]string{"sha256": "2222"}},
+	}, predicate.Materials)
+	require.False(t, predicate.Metadata.BuildStartedOn.IsZero())
+	require.False(t, predicate.Metadata.BuildFinishedOn.Before(predicate.Metadata.BuildStartedOn))
+
+	copied, err := ioutil.ReadFile(attached)
+	require.NoError(t, err)
+	require.Equal(t, out, copied)
+
+	// images that can't be resolved fail the build rather than leave the provenance incomplete
+	request.Bundles = []string{"quay.io/example/unknown:v1"}
+	require.Error(t, indexer.AddToIndex(context.TODO(), request))
+
+	// a provenance command needs a provenance to run on
+	request.ProvenanceFile = ""
+	require.Error(t, indexer.AddToIndex(context.TODO(), request))
+}
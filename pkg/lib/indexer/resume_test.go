@@ -0,0 +1,94 @@
+package indexer
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+
+	"github.com/operator-framework/operator-registry/pkg/containertools"
+	"github.com/operator-framework/operator-registry/pkg/containertools/containertoolsfakes"
+	"github.com/operator-framework/operator-registry/pkg/lib/registry"
+	"github.com/operator-framework/operator-registry/pkg/lib/registry/registryfakes"
+	"github.com/operator-framework/operator-registry/pkg/lib/tmp"
+)
+
+func TestAddToIndexResume(t *testing.T) {
+	workDir, err := ioutil.TempDir("", "index-resume-")
+	require.NoError(t, err)
+	defer os.RemoveAll(workDir)
+	tmp.SetWorkDir(workDir)
+	defer tmp.SetWorkDir("")
+
+	const bundle = "quay.io/example/bundle:v1"
+	adder := &registryfakes.FakeRegistryAdder{}
+	indexer := ImageIndexer{
+		DockerfileGenerator: &containertoolsfakes.FakeDockerfileGenerator{},
+		CommandRunner:       &containertoolsfakes.FakeCommandRunner{},
+		RegistryAdder:       adder,
+		PullTool:            containertools.NoneTool,
+		Logger:              logrus.NewEntry(logrus.New()),
+	}
+	request := AddToIndexRequest{
+		Bundles:       []string{bundle},
+		OutDockerfile: filepath.Join(workDir, "index.Dockerfile"),
+		Resume:        true,
+	}
+	stateDir := registry.BuildStateDir("index-add", "", "0", bundle)
+
+	// the first attempt unpacks the bundle, then fails
+	adder.AddToRegistryCalls(func(ctx context.Context, r registry.AddToRegistryRequest) (registry.AddToRegistryResult, error) {
+		require.NotNil(t, r.State)
+		_, ok := r.State.Unpacked(bundle)
+		require.False(t, ok)
+		dir, err := r.State.BundleDir()
+		require.NoError(t, err)
+		require.NoError(t, r.State.MarkUnpacked(bundle, dir))
+		return registry.AddToRegistryResult{}, errors.New("transient failure")
+	})
+	require.Error(t, indexer.AddToIndex(context.TODO(), request))
+	require.DirExists(t, stateDir)
+
+	// the second attempt finds the bundle unpacked, and the state is removed once it succeeds
+	adder.AddToRegistryCalls(func(ctx context.Context, r registry.AddToRegistryRequest) (registry.AddToRegistryResult, error) {
+		_, ok := r.State.Unpacked(bundle)
+		require.True(t, ok)
+		_, ok = r.State.SavedDatabase()
+		require.True(t, ok)
+		return registry.AddToRegistryResult{}, nil
+	})
+	require.NoError(t, indexer.AddToIndex(context.TODO(), request))
+	_, err = os.Stat(stateDir)
+	require.True(t, os.IsNotExist(err))
+
+	// a clean attempt starts over
+	adder.AddToRegistryCalls(func(ctx context.Context, r registry.AddToRegistryRequest) (registry.AddToRegistryResult, error) {
+		dir, err := r.State.BundleDir()
+		require.NoError(t, err)
+		require.NoError(t, r.State.MarkUnpacked(bundle, dir))
+		return registry.AddToRegistryResult{}, errors.New("transient failure")
+	})
+	require.Error(t, indexer.AddToIndex(context.TODO(), request))
+	request.Clean = true
+	adder.AddToRegistryCalls(func(ctx context.Context, r registry.AddToRegistryRequest) (registry.AddToRegistryResult, error) {
+		_, ok := r.State.Unpacked(bundle)
+		require.False(t, ok)
+		return registry.AddToRegistryResult{}, nil
+	})
+	require.NoError(t, indexer.AddToIndex(context.TODO(), request))
+
+	// without resume, no state is kept
+	request.Resume, request.Clean = false, false
+	adder.AddToRegistryCalls(func(ctx context.Context, r registry.AddToRegistryRequest) (registry.AddToRegistryResult, error) {
+		require.Nil(t, r.State)
+		return registry.AddToRegistryResult{}, errors.New("transient failure")
+	})
+	require.Error(t, indexer.AddToIndex(context.TODO(), request))
+	_, err = os.Stat(stateDir)
+	require.True(t, os.IsNotExist(err))
+}
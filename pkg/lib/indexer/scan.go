@@ -0,0 +1,205 @@
+package indexer
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/operator-framework/operator-registry/pkg/lib/progress"
+	"github.com/operator-framework/operator-registry/pkg/sqlite"
+)
+
+// Severity is the ordered severity of a vulnerability finding
+type Severity int
+
+const (
+	SeverityUnknown Severity = iota
+	SeverityLow
+	SeverityMedium
+	SeverityHigh
+	SeverityCritical
+)
+
+var severityNames = map[Severity]string{
+	SeverityUnknown:  "unknown",
+	SeverityLow:      "low",
+	SeverityMedium:   "medium",
+	SeverityHigh:     "high",
+	SeverityCritical: "critical",
+}
+
+func (s Severity) String() string {
+	return severityNames[s]
+}
+
+// ParseSeverity returns the Severity matching the given (case insensitive) name
+func ParseSeverity(name string) (Severity, error) {
+	for severity, severityName := range severityNames {
+		if strings.EqualFold(name, severityName) {
+			return severity, nil
+		}
+	}
+	return SeverityUnknown, fmt.Errorf("invalid severity %q, must be one of: [low, medium, high, critical]", name)
+}
+
+// Finding is a single vulnerability reported by an image scanner
+type Finding struct {
+	ID          string `json:"id"`
+	Severity    string `json:"severity"`
+	Package     string `json:"package,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// ImageScanResult holds the findings reported for a single image
+type ImageScanResult struct {
+	Image    string    `json:"image"`
+	Findings []Finding `json:"findings"`
+}
+
+// ScanReport aggregates the scan results of every image referenced by a build
+type ScanReport struct {
+	Results []ImageScanResult `json:"results"`
+}
+
+// ImagesAtOrAbove returns the images with at least one finding at or above the given severity
+func (r ScanReport) ImagesAtOrAbove(threshold Severity) []string {
+	var images []string
+	for _, result := range r.Results {
+		for _, finding := range result.Findings {
+			severity, err := ParseSeverity(finding.Severity)
+			if err != nil {
+				severity = SeverityUnknown
+			}
+			if severity >= threshold {
+				images = append(images, result.Image)
+				break
+			}
+		}
+	}
+	return images
+}
+
+// ImageScanner scans a container image for vulnerabilities
+type ImageScanner interface {
+	Scan(ctx context.Context, image string) ([]Finding, error)
+}
+
+// CommandScanner is an ImageScanner that shells out to an external scanner
+type CommandScanner struct {
+	command []string
+	logger  *logrus.Entry
+}
+
+// NewCommandScanner returns an ImageScanner that runs the given command with
+// the image reference appended as the last argument. The command is expected to
+// print a JSON list of findings to stdout.
+func NewCommandScanner(command string, logger *logrus.Entry) *CommandScanner {
+	return &CommandScanner{
+		command: strings.Fields(command),
+		logger:  logger,
+	}
+}
+
+// Scan runs the scan command against the given image and parses its findings
+func (s *CommandScanner) Scan(ctx context.Context, image string) ([]Finding, error) {
+	if len(s.command) == 0 {
+		return nil, fmt.Errorf("no scan command configured")
+	}
+
+	args := append([]string{}, s.command[1:]...)
+	args = append(args, image)
+	cmd := exec.CommandContext(ctx, s.command[0], args...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	s.logger.Infof("running %s", strings.Join(cmd.Args, " "))
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("error scanning %s: %s: %s", image, err, stderr.String())
+	}
+
+	var findings []Finding
+	if len(bytes.TrimSpace(stdout.Bytes())) == 0 {
+		return findings, nil
+	}
+	if err := json.Unmarshal(stdout.Bytes(), &findings); err != nil {
+		return nil, fmt.Errorf("error parsing scan output for %s: %s", image, err)
+	}
+	return findings, nil
+}
+
+// scanImages scans each image and aggregates the findings into a report
+func scanImages(ctx context.Context, scanner ImageScanner, images []string) (*ScanReport, error) {
+	report := &ScanReport{}
+	for i, image := range images {
+		if err := progress.Canceled(ctx); err != nil {
+			return nil, err
+		}
+		findings, err := scanner.Scan(ctx, image)
+		if err != nil {
+			return nil, err
+		}
+		report.Results = append(report.Results, ImageScanResult{
+			Image:    image,
+			Findings: findings,
+		})
+		progress.Report(ctx, "scan", image, i+1, len(images))
+	}
+	return report, nil
+}
+
+// writeScanReport writes the report as json to the given file
+func writeScanReport(report *ScanReport, path string) error {
+	out, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, out, 0644)
+}
+
+// listIndexImages returns every bundle and operand image referenced by the database
+func listIndexImages(databasePath string) (map[string]struct{}, error) {
+	imageSet := make(map[string]struct{})
+
+	// a new index starts out as an empty database file
+	if info, err := os.Stat(databasePath); err != nil || info.Size() == 0 {
+		return imageSet, nil
+	}
+
+	db, err := sql.Open("sqlite3", databasePath)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	images, err := sqlite.NewSQLLiteQuerierFromDb(db).ListImages(context.TODO())
+	if err != nil {
+		return nil, err
+	}
+
+	for _, image := range images {
+		imageSet[image] = struct{}{}
+	}
+	return imageSet, nil
+}
+
+// addedImages returns the sorted images present in after but not in before
+func addedImages(before, after map[string]struct{}) []string {
+	var images []string
+	for image := range after {
+		if _, ok := before[image]; !ok {
+			images = append(images, image)
+		}
+	}
+	sort.Strings(images)
+	return images
+}
@@ -0,0 +1,71 @@
+package indexer
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+type fakeScanner map[string][]Finding
+
+func (f fakeScanner) Scan(_ context.Context, image string) ([]Finding, error) {
+	findings, ok := f[image]
+	if !ok {
+		return nil, fmt.Errorf("unexpected image %s", image)
+	}
+	return findings, nil
+}
+
+func TestScanImages(t *testing.T) {
+	before, err := listIndexImages("./testdata/does-not-exist.db")
+	if err != nil {
+		t.Fatalf("listing images of a new index: %s", err)
+	}
+
+	after, err := listIndexImages("./testdata/bundles.db")
+	if err != nil {
+		t.Fatalf("listing images: %s", err)
+	}
+
+	images := addedImages(before, after)
+	if len(images) != len(after) {
+		t.Fatalf("expected all %d images to be added, got %d", len(after), len(images))
+	}
+
+	scanner := fakeScanner{}
+	for _, image := range images {
+		scanner[image] = nil
+	}
+	scanner[images[0]] = []Finding{{ID: "CVE-2020-0001", Severity: "Medium"}}
+	scanner[images[1]] = []Finding{{ID: "CVE-2020-0002", Severity: "low"}, {ID: "CVE-2020-0003", Severity: "critical"}}
+
+	report, err := scanImages(context.TODO(), scanner, images)
+	if err != nil {
+		t.Fatalf("scanning images: %s", err)
+	}
+	if len(report.Results) != len(images) {
+		t.Fatalf("expected %d scan results, got %d", len(images), len(report.Results))
+	}
+
+	if failing := report.ImagesAtOrAbove(SeverityMedium); !reflect.DeepEqual(images[:2], failing) {
+		t.Fatalf("expected %v at or above medium, got %v", images[:2], failing)
+	}
+	if failing := report.ImagesAtOrAbove(SeverityCritical); !reflect.DeepEqual(images[1:2], failing) {
+		t.Fatalf("expected %v at or above critical, got %v", images[1:2], failing)
+	}
+}
+
+func TestParseSeverity(t *testing.T) {
+	severity, err := ParseSeverity("HIGH")
+	if err != nil {
+		t.Fatalf("parsing severity: %s", err)
+	}
+	if severity != SeverityHigh {
+		t.Fatalf("expected %s, got %s", SeverityHigh, severity)
+	}
+
+	if _, err := ParseSeverity("severe"); err == nil {
+		t.Fatalf("expected error parsing invalid severity")
+	}
+}
@@ -0,0 +1,109 @@
+// Package plugin finds and runs the executables that extend opm with commands of their own, the way kubectl plugins
+// extend kubectl: an executable named opm-<name> on the PATH is run as "opm <name>". It is also the SDK plugins are
+// written against, giving them the settings opm was run with and the catalog loading opm itself uses.
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Prefix prefixes the names of plugin executables
+const Prefix = "opm-"
+
+const (
+	// EnvBinary is set to the path of the opm binary that ran the plugin, so that the plugin can run opm commands
+	EnvBinary = "OPM_BINARY"
+	// EnvWorkDir is set to the directory opm creates temporary files in, as set with --workdir
+	EnvWorkDir = "OPM_WORKDIR"
+	// EnvDebug is set to true if debug logging was enabled
+	EnvDebug = "OPM_DEBUG"
+)
+
+// Plugin is an executable that extends opm with a command
+type Plugin struct {
+	// Name is the command the plugin is run as, e.g. lint for opm-lint
+	Name string
+	// Path is the path of the executable
+	Path string
+	// Shadowed is set for a plugin that is never run because an executable of the same name comes before it on the PATH
+	Shadowed bool
+}
+
+// Discover returns the plugins in the directories of path, a list in the format of the PATH environment variable,
+// ordered by name and then by the order of their directories. Directories that can't be read are skipped.
+func Discover(path string) []Plugin {
+	var plugins []Plugin
+	seen := map[string]struct{}{}
+	for _, dir := range filepath.SplitList(path) {
+		if dir == "" {
+			dir = "."
+		}
+		files, err := ioutil.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, f := range files {
+			name := pluginName(f.Name())
+			if name == "" || f.IsDir() || f.Mode()&0111 == 0 {
+				continue
+			}
+			_, shadowed := seen[name]
+			seen[name] = struct{}{}
+			plugins = append(plugins, Plugin{Name: name, Path: filepath.Join(dir, f.Name()), Shadowed: shadowed})
+		}
+	}
+	sort.SliceStable(plugins, func(i, j int) bool {
+		return plugins[i].Name < plugins[j].Name
+	})
+	return plugins
+}
+
+// Lookup returns the plugin run as the named command, the first on the PATH
+func Lookup(name string) (Plugin, bool) {
+	if name == "" || strings.ContainsAny(name, `/\`) {
+		return Plugin{}, false
+	}
+	path, err := exec.LookPath(Prefix + name)
+	if err != nil {
+		return Plugin{}, false
+	}
+	return Plugin{Name: name, Path: path}, true
+}
+
+// Run runs the plugin with args, passing it the settings in env along with the environment of opm. A plugin that
+// exits with a non-zero status returns an *exec.ExitError.
+func (p Plugin) Run(ctx context.Context, args []string, env Env, stdin io.Reader, stdout, stderr io.Writer) error {
+	cmd := exec.CommandContext(ctx, p.Path, args...)
+	cmd.Env = append(os.Environ(), env.Environ()...)
+	cmd.Stdin = stdin
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	if err := cmd.Run(); err != nil {
+		if _, ok := err.(*exec.ExitError); ok {
+			return err
+		}
+		return fmt.Errorf("error running plugin %s: %s", p.Path, err)
+	}
+	return nil
+}
+
+// pluginName returns the command a plugin executable is run as, or nothing if file isn't named like a plugin. Windows
+// executables are named without their extension.
+func pluginName(file string) string {
+	if !strings.HasPrefix(file, Prefix) {
+		return ""
+	}
+	name := strings.TrimPrefix(file, Prefix)
+	if ext := filepath.Ext(name); strings.EqualFold(ext, ".exe") {
+		name = strings.TrimSuffix(name, ext)
+	}
+	return name
+}
@@ -0,0 +1,150 @@
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+
+	"github.com/operator-framework/operator-registry/pkg/lib/tmp"
+	"github.com/operator-framework/operator-registry/pkg/sqlite"
+)
+
+func writeExecutable(t *testing.T, path, script string, mode os.FileMode) {
+	require.NoError(t, ioutil.WriteFile(path, []byte(script), mode))
+}
+
+func TestDiscover(t *testing.T) {
+	dir, err := ioutil.TempDir("", "plugin-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	first, second := filepath.Join(dir, "first"), filepath.Join(dir, "second")
+	require.NoError(t, os.Mkdir(first, 0755))
+	require.NoError(t, os.Mkdir(second, 0755))
+
+	writeExecutable(t, filepath.Join(first, "opm-lint"), "#!/bin/sh\n", 0755)
+	writeExecutable(t, filepath.Join(first, "opm-readme"), "not executable", 0644)
+	writeExecutable(t, filepath.Join(first, "kubectl-lint"), "#!/bin/sh\n", 0755)
+	writeExecutable(t, filepath.Join(second, "opm-lint"), "#!/bin/sh\n", 0755)
+	writeExecutable(t, filepath.Join(second, "opm-diff.exe"), "#!/bin/sh\n", 0755)
+	require.NoError(t, os.Mkdir(filepath.Join(second, "opm-dir"), 0755))
+
+	plugins := Discover(strings.Join([]string{first, filepath.Join(dir, "missing"), second}, string(os.PathListSeparator)))
+	require.Equal(t, []Plugin{
+		{Name: "diff", Path: filepath.Join(second, "opm-diff.exe")},
+		{Name: "lint", Path: filepath.Join(first, "opm-lint")},
+		{Name: "lint", Path: filepath.Join(second, "opm-lint"), Shadowed: true},
+	}, plugins)
+}
+
+func TestRun(t *testing.T) {
+	dir, err := ioutil.TempDir("", "plugin-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	writeExecutable(t, filepath.Join(dir, "opm-echo"), `#!/bin/sh
+echo "$@" "$OPM_BINARY" "$OPM_WORKDIR" "$OPM_DEBUG"
+exit $1
+`, 0755)
+
+	path := os.Getenv("PATH")
+	defer os.Setenv("PATH", path)
+	require.NoError(t, os.Setenv("PATH", dir+string(os.PathListSeparator)+path))
+
+	_, ok := Lookup("missing")
+	require.False(t, ok)
+	_, ok = Lookup("../echo")
+	require.False(t, ok)
+	p, ok := Lookup("echo")
+	require.True(t, ok)
+	require.Equal(t, filepath.Join(dir, "opm-echo"), p.Path)
+
+	env := Env{Binary: "/usr/bin/opm", WorkDir: "/tmp/work", Debug: true}
+	var out bytes.Buffer
+	require.NoError(t, p.Run(context.TODO(), []string{"0", "--flag"}, env, nil, &out, ioutil.Discard))
+	require.Equal(t, "0 --flag /usr/bin/opm /tmp/work true\n", out.String())
+
+	// the exit status of the plugin is kept
+	err = p.Run(context.TODO(), []string{"3"}, env, nil, ioutil.Discard, ioutil.Discard)
+	exitErr, ok := err.(*exec.ExitError)
+	require.True(t, ok)
+	require.Equal(t, 3, exitErr.ExitCode())
+}
+
+func TestEnv(t *testing.T) {
+	for _, name := range []string{EnvBinary, EnvWorkDir, EnvDebug} {
+		defer os.Setenv(name, os.Getenv(name))
+	}
+	env := Env{Binary: "/usr/bin/opm", WorkDir: "/tmp/work", Debug: true}
+	for _, v := range env.Environ() {
+		parts := strings.SplitN(v, "=", 2)
+		require.NoError(t, os.Setenv(parts[0], parts[1]))
+	}
+	require.Equal(t, env, FromEnv())
+
+	require.NoError(t, os.Unsetenv(EnvDebug))
+	require.False(t, FromEnv().Debug)
+}
+
+func TestLoadCatalog(t *testing.T) {
+	dir, err := ioutil.TempDir("", "plugin-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	tmp.SetWorkDir(dir)
+	defer tmp.SetWorkDir("")
+
+	loaded, err := LoadCatalog(context.TODO(), "../../../manifests")
+	require.NoError(t, err)
+	packages, err := loaded.ListPackages(context.TODO())
+	require.NoError(t, err)
+	require.Equal(t, []string{"etcd", "prometheus", "strimzi-kafka-operator"}, packages)
+
+	// a loaded catalog can be opened again by its path while it is open
+	opened, err := OpenCatalog(context.TODO(), logrus.NewEntry(logrus.New()), loaded.Path, "")
+	require.NoError(t, err)
+	bundle, err := opened.GetBundleForChannel(context.TODO(), "etcd", "alpha")
+	require.NoError(t, err)
+	require.Equal(t, "etcdoperator.v0.9.2", bundle.CsvName)
+	require.NoError(t, opened.Close())
+	_, err = os.Stat(loaded.Path)
+	require.NoError(t, err)
+
+	// an encrypted catalog is opened with the key in the environment
+	key, err := sqlite.NewDatabaseKey([]byte("0123456789abcdef"))
+	require.NoError(t, err)
+	encrypted := filepath.Join(dir, "encrypted.db")
+	require.NoError(t, sqlite.EncryptDatabase(context.TODO(), key, loaded.Path, encrypted))
+	os.Unsetenv(sqlite.EnvDatabaseKey)
+	_, err = OpenCatalog(context.TODO(), logrus.NewEntry(logrus.New()), encrypted, "")
+	require.Error(t, err)
+	os.Setenv(sqlite.EnvDatabaseKey, "0123456789abcdef")
+	defer os.Unsetenv(sqlite.EnvDatabaseKey)
+	opened, err = OpenCatalog(context.TODO(), logrus.NewEntry(logrus.New()), encrypted, "")
+	require.NoError(t, err)
+	packages, err = opened.ListPackages(context.TODO())
+	require.NoError(t, err)
+	require.Equal(t, []string{"etcd", "prometheus", "strimzi-kafka-operator"}, packages)
+	// the decrypted database is only held in memory
+	require.Equal(t, encrypted, opened.Path)
+	files, err := ioutil.ReadDir(dir)
+	require.NoError(t, err)
+	for _, f := range files {
+		require.NotContains(t, f.Name(), "decrypted")
+	}
+	require.NoError(t, opened.Close())
+
+	require.NoError(t, loaded.Close())
+	_, err = os.Stat(loaded.Path)
+	require.True(t, os.IsNotExist(err))
+
+	_, err = OpenCatalog(context.TODO(), logrus.NewEntry(logrus.New()), filepath.Join(dir, "missing.db"), "")
+	require.Error(t, err)
+	_, err = OpenCatalog(context.TODO(), logrus.NewEntry(logrus.New()), "https://example.com/index.db", "")
+	require.Error(t, err)
+}
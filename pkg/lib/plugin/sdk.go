@@ -0,0 +1,183 @@
+package plugin
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/operator-framework/operator-registry/pkg/lib/contentsource"
+	"github.com/operator-framework/operator-registry/pkg/lib/remotedb"
+	"github.com/operator-framework/operator-registry/pkg/lib/tmp"
+	"github.com/operator-framework/operator-registry/pkg/registry"
+	"github.com/operator-framework/operator-registry/pkg/sqlite"
+)
+
+// Env holds the settings opm was run with, which it passes on to the plugins it runs
+type Env struct {
+	// Binary is the path of the opm binary
+	Binary string
+	// WorkDir is the directory temporary files are created in
+	WorkDir string
+	// Debug is set if debug logging is enabled
+	Debug bool
+}
+
+// Environ returns env as environment variables
+func (e Env) Environ() []string {
+	return []string{
+		EnvBinary + "=" + e.Binary,
+		EnvWorkDir + "=" + e.WorkDir,
+		EnvDebug + "=" + strconv.FormatBool(e.Debug),
+	}
+}
+
+// FromEnv returns the settings a plugin was run with. Settings opm didn't pass, e.g. because the plugin was run on its
+// own, are left empty.
+func FromEnv() Env {
+	debug, _ := strconv.ParseBool(os.Getenv(EnvDebug))
+	return Env{
+		Binary:  os.Getenv(EnvBinary),
+		WorkDir: os.Getenv(EnvWorkDir),
+		Debug:   debug,
+	}
+}
+
+// Apply configures the plugin the way opm is configured: temporary files are created in the same directory, and
+// debug logging is enabled if it is in opm
+func (e Env) Apply() {
+	tmp.SetWorkDir(e.WorkDir)
+	if e.Debug {
+		logrus.SetLevel(logrus.DebugLevel)
+	}
+}
+
+// Catalog is a catalog database opened for querying
+type Catalog struct {
+	registry.Query
+	// Path is the path of the database, which is a temporary copy for a catalog that was downloaded or loaded. An
+	// encrypted database is queried from memory once it is decrypted, Path is the encrypted database.
+	Path string
+
+	close     func() error
+	workspace *tmp.Workspace
+}
+
+// Close closes the database and removes the temporary files of the catalog
+func (c *Catalog) Close() error {
+	err := c.close()
+	if c.workspace != nil {
+		if cerr := c.workspace.Cleanup(); err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
+
+// OpenCatalog opens the catalog database at source for querying, from any of the places opm registry serve serves a
+// database from: a local path, an s3:// or gs:// bucket, or an http(s) url or oci:// index image, which has to match
+// checksum, the sha256 of the database. An encrypted database is decrypted with the key in $OPM_DATABASE_KEY.
+func OpenCatalog(ctx context.Context, logger *logrus.Entry, source, checksum string) (*Catalog, error) {
+	if !contentsource.IsObjectStorage(source) && !remotedb.IsRemote(source) {
+		if _, err := os.Stat(source); err != nil {
+			return nil, err
+		}
+		return openCatalog(source, nil)
+	}
+
+	workspace, err := tmp.NewWorkspace("plugin")
+	if err != nil {
+		return nil, err
+	}
+	var path string
+	if contentsource.IsObjectStorage(source) {
+		var s contentsource.Source
+		if s, err = contentsource.New(source); err == nil {
+			path = filepath.Join(workspace.Path(), "index.db")
+			if _, err = s.Fetch(ctx, path, ""); err != nil {
+				err = fmt.Errorf("error fetching database %s: %s", source, err)
+			}
+		}
+	} else {
+		path, err = remotedb.Download(ctx, logger, source, checksum, workspace.Path())
+	}
+	if err != nil {
+		workspace.Cleanup()
+		return nil, err
+	}
+	return openCatalog(path, workspace)
+}
+
+// LoadCatalog loads a directory of package manifests, laid out as opm registry add --directory expects them, into a
+// temporary catalog database
+func LoadCatalog(ctx context.Context, directory string) (*Catalog, error) {
+	workspace, err := tmp.NewWorkspace("plugin")
+	if err != nil {
+		return nil, err
+	}
+	path := filepath.Join(workspace.Path(), "index.db")
+	if err := loadCatalog(ctx, directory, path); err != nil {
+		workspace.Cleanup()
+		return nil, err
+	}
+	return openCatalog(path, workspace)
+}
+
+func loadCatalog(ctx context.Context, directory, path string) error {
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	store, err := sqlite.NewSQLLiteLoader(db)
+	if err != nil {
+		return err
+	}
+	if err := store.Migrate(ctx); err != nil {
+		return err
+	}
+	return sqlite.NewSQLLoaderForDirectory(store, directory).Populate()
+}
+
+func openCatalog(path string, workspace *tmp.Workspace) (*Catalog, error) {
+	cleanup := func() {
+		if workspace != nil {
+			workspace.Cleanup()
+		}
+	}
+	encrypted, err := sqlite.IsEncrypted(path)
+	if err != nil {
+		cleanup()
+		return nil, err
+	}
+	if encrypted {
+		m, err := decryptCatalog(path)
+		if err != nil {
+			cleanup()
+			return nil, err
+		}
+		return &Catalog{Query: sqlite.NewSQLLiteQuerierFromDb(m.DB()), Path: path, close: m.Close, workspace: workspace}, nil
+	}
+	db, err := sql.Open("sqlite3", "file:"+path+"?immutable=true")
+	if err != nil {
+		cleanup()
+		return nil, err
+	}
+	return &Catalog{Query: sqlite.NewSQLLiteQuerierFromDb(db), Path: path, close: db.Close, workspace: workspace}, nil
+}
+
+// decryptCatalog decrypts the encrypted database at path into memory
+func decryptCatalog(path string) (*sqlite.MemoryDatabase, error) {
+	key, err := sqlite.LoadDatabaseKey("")
+	if err != nil {
+		return nil, err
+	}
+	if key == nil {
+		return nil, fmt.Errorf("%s: %s", path, sqlite.ErrDatabaseEncrypted)
+	}
+	return sqlite.LoadMemoryDatabaseWithKey(context.TODO(), path, key)
+}
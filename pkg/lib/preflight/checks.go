@@ -0,0 +1,426 @@
+package preflight
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sync"
+
+	"github.com/blang/semver"
+
+	"github.com/operator-framework/operator-registry/pkg/api"
+	"github.com/operator-framework/operator-registry/pkg/image"
+	"github.com/operator-framework/operator-registry/pkg/lib/validation"
+	"github.com/operator-framework/operator-registry/pkg/registry"
+)
+
+// pinnedImage matches image references that name their manifest by digest, e.g. quay.io/example/bundle@sha256:...
+var pinnedImage = regexp.MustCompile(`@[a-z0-9]+(?:[.+_-][a-z0-9]+)*:[a-zA-Z0-9=_-]+$`)
+
+func checkSchema(ctx context.Context, catalog *Catalog, pkg *catalogPackage) ([]Finding, error) {
+	var findings []Finding
+	for _, b := range uniqueBundles(pkg.bundles) {
+		// bundles loaded without their manifests have nothing to validate
+		if len(b.GetObject()) == 0 {
+			continue
+		}
+		bundle, err := registry.NewBundleFromStrings(b.CsvName, b.PackageName, nil, b.GetObject())
+		if err != nil {
+			findings = append(findings, Finding{Subject: b.CsvName, Message: fmt.Sprintf("manifests don't decode: %s", err)})
+			continue
+		}
+		for _, result := range validation.BundleValidator.Validate(bundle) {
+			for _, e := range result.Errors {
+				findings = append(findings, Finding{Subject: b.CsvName, Message: e.Error()})
+			}
+		}
+	}
+	return findings, nil
+}
+
+func checkGraph(ctx context.Context, catalog *Catalog, pkg *catalogPackage) ([]Finding, error) {
+	channels := map[string]map[string]*api.Bundle{}
+	for _, b := range pkg.bundles {
+		if channels[b.ChannelName] == nil {
+			channels[b.ChannelName] = map[string]*api.Bundle{}
+		}
+		channels[b.ChannelName][b.CsvName] = b
+	}
+
+	p, err := catalog.Querier.GetPackage(ctx, pkg.name)
+	if err != nil {
+		return nil, err
+	}
+
+	var findings []Finding
+	hasDefault := false
+	for _, channel := range p.Channels {
+		if channel.Name == p.DefaultChannelName {
+			hasDefault = true
+		}
+
+		subject := pkg.name + "/" + channel.Name
+		entries := channels[channel.Name]
+		head, ok := entries[channel.CurrentCSVName]
+		if !ok {
+			findings = append(findings, Finding{Subject: subject, Message: fmt.Sprintf("channel head %q is not in the channel", channel.CurrentCSVName)})
+			continue
+		}
+		findings = append(findings, checkChannel(subject, head, entries)...)
+	}
+	if !hasDefault {
+		findings = append(findings, Finding{Subject: pkg.name, Message: fmt.Sprintf("default channel %q is not a channel of the package", p.DefaultChannelName)})
+	}
+	return findings, nil
+}
+
+// checkChannel walks the replaces and skips of a channel from its head, reporting the edges to bundles that aren't
+// in the channel and the bundles that can't be reached
+func checkChannel(subject string, head *api.Bundle, entries map[string]*api.Bundle) []Finding {
+	var findings []Finding
+
+	reached := map[string]struct{}{}
+	queue := []*api.Bundle{head}
+	for len(queue) > 0 {
+		b := queue[0]
+		queue = queue[1:]
+		if _, ok := reached[b.CsvName]; ok {
+			continue
+		}
+		reached[b.CsvName] = struct{}{}
+
+		skipped := map[string]struct{}{}
+		for _, skip := range b.Skips {
+			skipped[skip] = struct{}{}
+			if next, ok := entries[skip]; ok {
+				queue = append(queue, next)
+			}
+		}
+		if b.Replaces == "" {
+			continue
+		}
+		if next, ok := entries[b.Replaces]; ok {
+			queue = append(queue, next)
+		} else if _, ok := skipped[b.Replaces]; !ok {
+			findings = append(findings, Finding{
+				Subject:    subject,
+				Message:    fmt.Sprintf("%s replaces %s, which is not in the channel", b.CsvName, b.Replaces),
+				Suggestion: suggestReplaces(b, entries),
+			})
+		}
+	}
+
+	for name, b := range entries {
+		if _, ok := reached[name]; !ok {
+			findings = append(findings, Finding{
+				Subject:    subject,
+				Message:    fmt.Sprintf("%s can't be reached from the channel head %s", name, head.CsvName),
+				Suggestion: suggestReachable(b, head, entries, reached),
+			})
+		}
+	}
+	return findings
+}
+
+// suggestReplaces suggests how to fix a bundle that replaces a bundle that isn't in its channel: replace the bundle of
+// the channel with the next lower version instead, or skip the missing bundle if there is none
+func suggestReplaces(b *api.Bundle, entries map[string]*api.Bundle) string {
+	if prev := closestVersion(b, entries, func(v, other semver.Version) bool { return v.LT(other) }); prev != nil {
+		return fmt.Sprintf("set the replaces of %s to %s", b.CsvName, prev.CsvName)
+	}
+	return fmt.Sprintf("add %s to the skips of %s", b.Replaces, b.CsvName)
+}
+
+// suggestReachable suggests how to connect a bundle that can't be reached from the head of its channel: skip it from
+// the reachable bundle with the next higher version, or make it the head if it is newer than every reachable bundle
+func suggestReachable(b, head *api.Bundle, entries map[string]*api.Bundle, reached map[string]struct{}) string {
+	reachable := map[string]*api.Bundle{}
+	for name := range reached {
+		reachable[name] = entries[name]
+	}
+	if next := closestVersion(b, reachable, func(v, other semver.Version) bool { return v.GT(other) }); next != nil {
+		return fmt.Sprintf("add %s to the skips of %s", b.CsvName, next.CsvName)
+	}
+	if v, err := semver.Parse(b.Version); err == nil {
+		if headVersion, err := semver.Parse(head.Version); err == nil && v.GT(headVersion) {
+			return fmt.Sprintf("set the replaces of %s to %s, making it the channel head", b.CsvName, head.CsvName)
+		}
+	}
+	return ""
+}
+
+// closestVersion returns the bundle among candidates whose version v is closest to that of b on the side of it where
+// beyond(v, version of b) holds. Bundles whose versions don't parse are never suggested.
+func closestVersion(b *api.Bundle, candidates map[string]*api.Bundle, beyond func(v, other semver.Version) bool) *api.Bundle {
+	v, err := semver.Parse(b.Version)
+	if err != nil {
+		return nil
+	}
+	var closest *api.Bundle
+	var closestV semver.Version
+	for _, c := range candidates {
+		if c.CsvName == b.CsvName {
+			continue
+		}
+		cv, err := semver.Parse(c.Version)
+		if err != nil || !beyond(cv, v) {
+			continue
+		}
+		if closest == nil || beyond(closestV, cv) || (cv.EQ(closestV) && c.CsvName < closest.CsvName) {
+			closest, closestV = c, cv
+		}
+	}
+	return closest
+}
+
+// providedIndex is what the bundles of a catalog provide to the dependencies of others
+type providedIndex struct {
+	apis     map[registry.GVKDependency]struct{}
+	versions map[string][]semver.Version
+	labels   map[string]struct{}
+}
+
+// prepareDependencies indexes what the whole catalog provides, since a bundle may depend on any package of it
+func prepareDependencies(ctx context.Context, catalog *Catalog) error {
+	bundles, err := catalog.listBundles(ctx)
+	if err != nil {
+		return err
+	}
+
+	index := &providedIndex{
+		apis:     map[registry.GVKDependency]struct{}{},
+		versions: map[string][]semver.Version{},
+		labels:   map[string]struct{}{},
+	}
+	for _, b := range uniqueBundles(bundles) {
+		for _, gvk := range b.ProvidedApis {
+			index.apis[registry.GVKDependency{Group: gvk.Group, Version: gvk.Version, Kind: gvk.Kind}] = struct{}{}
+		}
+		if v, err := semver.Parse(b.Version); err == nil {
+			index.versions[b.PackageName] = append(index.versions[b.PackageName], v)
+		}
+		for _, p := range b.Properties {
+			if p.Type != registry.LabelType {
+				continue
+			}
+			var label registry.LabelProperty
+			if err := json.Unmarshal([]byte(p.Value), &label); err == nil {
+				index.labels[label.Label] = struct{}{}
+			}
+		}
+	}
+	catalog.provided = index
+	return nil
+}
+
+func checkDependencies(ctx context.Context, catalog *Catalog, pkg *catalogPackage) ([]Finding, error) {
+	provided, versions, labels := catalog.provided.apis, catalog.provided.versions, catalog.provided.labels
+
+	var findings []Finding
+	for _, b := range uniqueBundles(pkg.bundles) {
+		missing := map[string]struct{}{}
+		report := func(message string) {
+			if _, ok := missing[message]; ok {
+				return
+			}
+			missing[message] = struct{}{}
+			findings = append(findings, Finding{Subject: b.CsvName, Message: message})
+		}
+
+		for _, gvk := range b.RequiredApis {
+			dep := registry.GVKDependency{Group: gvk.Group, Version: gvk.Version, Kind: gvk.Kind}
+			if _, ok := provided[dep]; !ok {
+				report(fmt.Sprintf("no bundle provides the required API %s/%s/%s", dep.Group, dep.Version, dep.Kind))
+			}
+		}
+
+		for _, d := range b.Dependencies {
+			dep := registry.Dependency{Type: d.Type, Value: json.RawMessage(d.Value)}
+			switch value := dep.GetTypeValue().(type) {
+			case registry.GVKDependency:
+				if _, ok := provided[value]; !ok {
+					report(fmt.Sprintf("no bundle provides the required API %s/%s/%s", value.Group, value.Version, value.Kind))
+				}
+			case registry.PackageDependency:
+				if errs := value.Validate(); len(errs) > 0 {
+					report(fmt.Sprintf("invalid package dependency %s: %v", d.Value, errs))
+					continue
+				}
+				if !satisfied(versions[value.PackageName], value.Version) {
+					report(fmt.Sprintf("no bundle of package %s has a version in %s", value.PackageName, value.Version))
+				}
+			case registry.LabelDependency:
+				if _, ok := labels[value.Label]; !ok {
+					report(fmt.Sprintf("no bundle has the label %s", value.Label))
+				}
+			default:
+				report(fmt.Sprintf("invalid dependency of type %s: %s", d.Type, d.Value))
+			}
+		}
+	}
+	return findings, nil
+}
+
+func satisfied(versions []semver.Version, versionRange string) bool {
+	inRange, err := semver.ParseRange(versionRange)
+	if err != nil {
+		return false
+	}
+	for _, v := range versions {
+		if inRange(v) {
+			return true
+		}
+	}
+	return false
+}
+
+// bundleImages returns the bundle image and the related images of every bundle of the package
+func bundleImages(ctx context.Context, catalog *Catalog, pkg *catalogPackage) (map[string][]string, []string, error) {
+	images := map[string][]string{}
+	var names []string
+	for _, b := range uniqueBundles(pkg.bundles) {
+		related, err := catalog.Querier.GetImagesForBundle(ctx, b.CsvName)
+		if err != nil {
+			return nil, nil, err
+		}
+		seen := map[string]struct{}{}
+		for _, img := range append([]string{b.BundlePath}, related...) {
+			if _, ok := seen[img]; ok || img == "" {
+				continue
+			}
+			seen[img] = struct{}{}
+			images[b.CsvName] = append(images[b.CsvName], img)
+		}
+		names = append(names, b.CsvName)
+	}
+	return images, names, nil
+}
+
+// resolvedImages holds the outcome of resolving each image, shared by the packages being checked at once
+type resolvedImages struct {
+	mu     sync.Mutex
+	images map[string]*resolvedImage
+}
+
+type resolvedImage struct {
+	once sync.Once
+	err  error
+}
+
+// resolve resolves img, or returns the outcome of resolving it before
+func (r *resolvedImages) resolve(ctx context.Context, resolver ImageResolver, img string) error {
+	r.mu.Lock()
+	entry, ok := r.images[img]
+	if !ok {
+		entry = &resolvedImage{}
+		r.images[img] = entry
+	}
+	r.mu.Unlock()
+
+	entry.once.Do(func() {
+		_, entry.err = resolver.Resolve(ctx, image.SimpleReference(img))
+	})
+	return entry.err
+}
+
+func prepareImages(ctx context.Context, catalog *Catalog) error {
+	if catalog.Resolver == nil {
+		return fmt.Errorf("no image resolver to check images with")
+	}
+	catalog.resolved = &resolvedImages{images: map[string]*resolvedImage{}}
+	return nil
+}
+
+func checkImages(ctx context.Context, catalog *Catalog, pkg *catalogPackage) ([]Finding, error) {
+	images, names, err := bundleImages(ctx, catalog, pkg)
+	if err != nil {
+		return nil, err
+	}
+
+	var findings []Finding
+	for _, name := range names {
+		for _, img := range images[name] {
+			if rerr := catalog.resolved.resolve(ctx, catalog.Resolver, img); rerr != nil {
+				findings = append(findings, Finding{Subject: name, Message: fmt.Sprintf("image %s can't be resolved: %s", img, rerr)})
+			}
+		}
+	}
+	return findings, nil
+}
+
+func checkDigests(ctx context.Context, catalog *Catalog, pkg *catalogPackage) ([]Finding, error) {
+	images, names, err := bundleImages(ctx, catalog, pkg)
+	if err != nil {
+		return nil, err
+	}
+
+	var findings []Finding
+	for _, name := range names {
+		for _, img := range images[name] {
+			if !pinnedImage.MatchString(img) {
+				findings = append(findings, Finding{Subject: name, Message: fmt.Sprintf("image %s is not pinned by digest", img)})
+			}
+		}
+	}
+	return findings, nil
+}
+
+func checkDeprecations(ctx context.Context, catalog *Catalog, pkg *catalogPackage) ([]Finding, error) {
+	deprecated := map[string]struct{}{}
+	var findings []Finding
+	for _, b := range uniqueBundles(pkg.bundles) {
+		for _, p := range b.Properties {
+			if p.Type != registry.DeprecatedType {
+				continue
+			}
+			deprecated[b.CsvName] = struct{}{}
+			var value map[string]interface{}
+			if err := json.Unmarshal([]byte(p.Value), &value); err != nil || value == nil {
+				findings = append(findings, Finding{Subject: b.CsvName, Message: fmt.Sprintf("%s property %q is not a json object", registry.DeprecatedType, p.Value)})
+			}
+		}
+	}
+	if len(deprecated) == 0 {
+		return findings, nil
+	}
+
+	p, err := catalog.Querier.GetPackage(ctx, pkg.name)
+	if err != nil {
+		return nil, err
+	}
+	for _, channel := range p.Channels {
+		if _, ok := deprecated[channel.CurrentCSVName]; ok {
+			findings = append(findings, Finding{Subject: pkg.name + "/" + channel.Name, Message: fmt.Sprintf("channel head %s is deprecated, so nothing can be installed from the channel", channel.CurrentCSVName)})
+		}
+	}
+	return findings, nil
+}
+
+func checkVersions(ctx context.Context, catalog *Catalog, pkg *catalogPackage) ([]Finding, error) {
+	policy := catalog.VersionPolicy
+	if policy == nil {
+		policy = &registry.VersionPolicy{}
+	}
+
+	channels := map[string][]string{}
+	for _, b := range pkg.bundles {
+		channels[b.CsvName] = append(channels[b.CsvName], b.ChannelName)
+	}
+
+	var findings []Finding
+	for _, b := range uniqueBundles(pkg.bundles) {
+		for _, err := range policy.ValidateVersion(b.Version, channels[b.CsvName]...) {
+			findings = append(findings, Finding{Subject: b.CsvName, Message: err.Error()})
+		}
+	}
+
+	graph, err := catalog.Querier.GetUpgradeGraph(ctx, pkg.name)
+	if err != nil {
+		return nil, err
+	}
+	for _, err := range policy.ValidateGraph(graph) {
+		findings = append(findings, Finding{Subject: pkg.name, Message: err.Error()})
+	}
+	return findings, nil
+}
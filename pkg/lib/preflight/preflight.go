@@ -0,0 +1,414 @@
+// Package preflight runs the checks a catalog should pass before it is published: that its bundles are valid, its
+// channel graphs are sound, its dependencies can be met and its images can be pulled reproducibly.
+package preflight
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/operator-framework/operator-registry/pkg/api"
+	"github.com/operator-framework/operator-registry/pkg/image"
+	"github.com/operator-framework/operator-registry/pkg/lib/report"
+	"github.com/operator-framework/operator-registry/pkg/registry"
+)
+
+// Severity is how much a failed check matters. Error findings fail the preflight, warning findings are reported and
+// off disables the check.
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+	SeverityOff     Severity = "off"
+)
+
+// ParseSeverity returns the Severity named by s
+func ParseSeverity(s string) (Severity, error) {
+	switch severity := Severity(strings.ToLower(s)); severity {
+	case SeverityError, SeverityWarning, SeverityOff:
+		return severity, nil
+	}
+	return "", fmt.Errorf("unknown severity %q, must be one of [%s, %s, %s]", s, SeverityError, SeverityWarning, SeverityOff)
+}
+
+// Names of the checks
+const (
+	CheckSchema       = "schema"
+	CheckGraph        = "graph"
+	CheckDependencies = "dependencies"
+	CheckImages       = "images"
+	CheckDigests      = "digests"
+	CheckDeprecations = "deprecations"
+	CheckVersions     = "versions"
+)
+
+// ImageResolver resolves an image reference to the digest of its manifest without pulling its content
+type ImageResolver interface {
+	Resolve(ctx context.Context, ref image.Reference) (string, error)
+}
+
+// Catalog is what the checks run against
+type Catalog struct {
+	Querier registry.Query
+	// Resolver is used by the images check, which can't be run without one
+	Resolver ImageResolver
+	// VersionPolicy is what the versions check checks bundle versions against, the strictest policy if unset
+	VersionPolicy *registry.VersionPolicy
+	// Parallelism is how many packages are checked at once, the number of CPUs if it isn't positive
+	Parallelism int
+
+	// bundles is every bundle of the catalog, one per channel it is in
+	bundles []*api.Bundle
+	// provided indexes what the bundles of the catalog provide, for the dependencies check
+	provided *providedIndex
+	// resolved holds the outcome of resolving each image, so that an image referenced by many bundles is resolved once
+	resolved *resolvedImages
+}
+
+// Check is a single preflight check
+type Check struct {
+	Name        string
+	Description string
+	Severity    Severity
+	// prepare, if set, is run once against the whole catalog before run is run against each of its packages
+	prepare func(ctx context.Context, catalog *Catalog) error
+	run     func(ctx context.Context, catalog *Catalog, pkg *catalogPackage) ([]Finding, error)
+}
+
+// Finding is something a check found wrong with part of the catalog
+type Finding struct {
+	Check    string
+	Severity Severity
+	// Package is the package the finding is about, or that its subject belongs to
+	Package string
+	// Subject is the part of the catalog the finding is about, e.g. a bundle or a package
+	Subject string
+	Message string
+	// Suggestion, if one can be made, is a change to the catalog that would fix what was found
+	Suggestion string
+}
+
+func (f Finding) String() string {
+	if f.Suggestion != "" {
+		return fmt.Sprintf("[%s] %s: %s: %s (suggested fix: %s)", f.Severity, f.Check, f.Subject, f.Message, f.Suggestion)
+	}
+	return fmt.Sprintf("[%s] %s: %s: %s", f.Severity, f.Check, f.Subject, f.Message)
+}
+
+// Checks returns every check with its default severity, in the order they are run
+func Checks() []Check {
+	return []Check{
+		{Name: CheckSchema, Description: "bundle manifests are valid", Severity: SeverityError, run: checkSchema},
+		{Name: CheckGraph, Description: "channel graphs have heads, and every bundle is reachable from them", Severity: SeverityError, run: checkGraph},
+		{Name: CheckDependencies, Description: "the dependencies and required APIs of every bundle are provided by the catalog", Severity: SeverityError, prepare: prepareDependencies, run: checkDependencies},
+		{Name: CheckImages, Description: "bundle and related images can be resolved", Severity: SeverityError, prepare: prepareImages, run: checkImages},
+		{Name: CheckDigests, Description: "bundle and related images are pinned by digest", Severity: SeverityWarning, run: checkDigests},
+		{Name: CheckDeprecations, Description: "deprecations are well-formed and leave every channel installable", Severity: SeverityError, run: checkDeprecations},
+		{Name: CheckVersions, Description: "bundle versions are valid semver, follow the version policy and increase along upgrades", Severity: SeverityError, run: checkVersions},
+	}
+}
+
+// Result holds the outcome of each check that was run
+type Result struct {
+	Checks   []Check
+	Findings map[string][]Finding
+	// Packages are the packages that were checked, in order
+	Packages []string
+}
+
+// PackageSummary counts the findings about a package
+type PackageSummary struct {
+	Package  string
+	Errors   int
+	Warnings int
+}
+
+// Summary returns the count of findings about each package that was checked, the packages with the most errors, and
+// then the most warnings, first
+func (r Result) Summary() []PackageSummary {
+	counts := map[string]*PackageSummary{}
+	summaries := make([]PackageSummary, 0, len(r.Packages))
+	for _, pkg := range r.Packages {
+		counts[pkg] = &PackageSummary{Package: pkg}
+	}
+	for _, findings := range r.Findings {
+		for _, f := range findings {
+			c, ok := counts[f.Package]
+			if !ok {
+				continue
+			}
+			if f.Severity == SeverityError {
+				c.Errors++
+			} else {
+				c.Warnings++
+			}
+		}
+	}
+	for _, pkg := range r.Packages {
+		summaries = append(summaries, *counts[pkg])
+	}
+	sort.SliceStable(summaries, func(i, j int) bool {
+		if summaries[i].Errors != summaries[j].Errors {
+			return summaries[i].Errors > summaries[j].Errors
+		}
+		return summaries[i].Warnings > summaries[j].Warnings
+	})
+	return summaries
+}
+
+// Failed returns true if any check found something with error severity
+func (r Result) Failed() bool {
+	for _, findings := range r.Findings {
+		for _, f := range findings {
+			if f.Severity == SeverityError {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Report returns the result as a report, which can be written in any of the report formats
+func (r Result) Report() report.Report {
+	rep := report.Report{Tool: "opm alpha preflight", Rules: []report.Rule{}, Findings: []report.Finding{}}
+	for _, check := range r.Checks {
+		level := report.LevelError
+		if check.Severity == SeverityWarning {
+			level = report.LevelWarning
+		}
+		rep.Rules = append(rep.Rules, report.Rule{ID: check.Name, Description: check.Description, Level: level})
+		for _, f := range r.Findings[check.Name] {
+			rep.Findings = append(rep.Findings, report.Finding{RuleID: check.Name, Level: level, Group: f.Package, Subject: f.Subject, Message: f.Message, Suggestion: f.Suggestion})
+		}
+	}
+	rep.GroupBy = "package"
+	for _, s := range r.Summary() {
+		rep.Groups = append(rep.Groups, report.Group{Name: s.Package, Errors: s.Errors, Warnings: s.Warnings})
+	}
+	return rep
+}
+
+// Run runs the checks that aren't off against the catalog. Packages are checked concurrently, each on its own: a
+// check that fails on a package, e.g. because part of the package can't be queried, is reported as an error finding
+// about that package and the other packages are still checked. A check that can't be run at all, e.g. because the
+// catalog can't be queried, returns an error.
+func Run(ctx context.Context, catalog *Catalog, checks []Check) (Result, error) {
+	result := Result{Findings: map[string][]Finding{}}
+	var enabled []Check
+	for _, check := range checks {
+		if check.Severity == SeverityOff {
+			continue
+		}
+		if check.prepare != nil {
+			if err := check.prepare(ctx, catalog); err != nil {
+				return result, fmt.Errorf("running check %s: %s", check.Name, err)
+			}
+		}
+		enabled = append(enabled, check)
+	}
+
+	packages, err := catalog.packages(ctx)
+	if err != nil {
+		return result, err
+	}
+	perPackage := make([][]Finding, len(packages))
+	work := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < catalog.parallelism(len(packages)); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range work {
+				perPackage[i] = checkPackage(ctx, catalog, enabled, packages[i])
+			}
+		}()
+	}
+	for i := range packages {
+		select {
+		case work <- i:
+			continue
+		case <-ctx.Done():
+		}
+		break
+	}
+	close(work)
+	wg.Wait()
+	if err := ctx.Err(); err != nil {
+		return result, err
+	}
+
+	for _, check := range enabled {
+		var findings []Finding
+		for _, pkgFindings := range perPackage {
+			for _, f := range pkgFindings {
+				if f.Check == check.Name {
+					findings = append(findings, f)
+				}
+			}
+		}
+		sort.SliceStable(findings, func(i, j int) bool {
+			return findings[i].Subject < findings[j].Subject
+		})
+		result.Checks = append(result.Checks, check)
+		result.Findings[check.Name] = findings
+	}
+	for _, pkg := range packages {
+		result.Packages = append(result.Packages, pkg.name)
+	}
+	return result, nil
+}
+
+// checkPackage runs the checks against a package. A check that fails or panics on the package is reported as an
+// error finding about the package.
+func checkPackage(ctx context.Context, catalog *Catalog, checks []Check, pkg *catalogPackage) []Finding {
+	var all []Finding
+	for _, check := range checks {
+		severity := check.Severity
+		findings, err := runCheck(ctx, catalog, check, pkg)
+		if err != nil {
+			severity = SeverityError
+			findings = []Finding{{Subject: pkg.name, Message: fmt.Sprintf("check couldn't be run: %s", err)}}
+		}
+		for i := range findings {
+			findings[i].Check = check.Name
+			findings[i].Severity = severity
+			findings[i].Package = pkg.name
+		}
+		all = append(all, findings...)
+	}
+	return all
+}
+
+func runCheck(ctx context.Context, catalog *Catalog, check Check, pkg *catalogPackage) (findings []Finding, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return check.run(ctx, catalog, pkg)
+}
+
+// SelectChecks returns the named checks, or all of them if none are named, with the given severities applied
+func SelectChecks(names []string, severities map[string]string) ([]Check, error) {
+	all := Checks()
+	byName := map[string]int{}
+	for i, check := range all {
+		byName[check.Name] = i
+	}
+
+	for name, s := range severities {
+		i, ok := byName[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown check %q", name)
+		}
+		severity, err := ParseSeverity(s)
+		if err != nil {
+			return nil, err
+		}
+		all[i].Severity = severity
+	}
+
+	if len(names) == 0 {
+		return all, nil
+	}
+	var selected []Check
+	for _, name := range names {
+		i, ok := byName[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown check %q", name)
+		}
+		selected = append(selected, all[i])
+	}
+	return selected, nil
+}
+
+// listBundles returns every bundle of the catalog, one per channel it is in, querying the catalog only once
+func (c *Catalog) listBundles(ctx context.Context) ([]*api.Bundle, error) {
+	if c.bundles != nil {
+		return c.bundles, nil
+	}
+	bundles, err := c.Querier.ListBundles(ctx)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(bundles, func(i, j int) bool {
+		if bundles[i].CsvName != bundles[j].CsvName {
+			return bundles[i].CsvName < bundles[j].CsvName
+		}
+		return bundles[i].ChannelName < bundles[j].ChannelName
+	})
+	c.bundles = bundles
+	return bundles, nil
+}
+
+// catalogPackage is a package of the catalog along with its bundles, which are what the checks of the package look at
+type catalogPackage struct {
+	name string
+	// bundles are the bundles of the package, one per channel they are in, ordered as the catalog lists them
+	bundles []*api.Bundle
+}
+
+// packages returns every package of the catalog with its bundles, ordered by name
+func (c *Catalog) packages(ctx context.Context) ([]*catalogPackage, error) {
+	bundles, err := c.listBundles(ctx)
+	if err != nil {
+		return nil, err
+	}
+	names, err := c.Querier.ListPackages(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	byName := map[string]*catalogPackage{}
+	var packages []*catalogPackage
+	add := func(name string) *catalogPackage {
+		pkg, ok := byName[name]
+		if !ok {
+			pkg = &catalogPackage{name: name}
+			byName[name] = pkg
+			packages = append(packages, pkg)
+		}
+		return pkg
+	}
+	for _, name := range names {
+		add(name)
+	}
+	for _, b := range bundles {
+		pkg := add(b.PackageName)
+		pkg.bundles = append(pkg.bundles, b)
+	}
+	sort.Slice(packages, func(i, j int) bool {
+		return packages[i].name < packages[j].name
+	})
+	return packages, nil
+}
+
+// parallelism returns how many of the given number of packages are checked at once
+func (c *Catalog) parallelism(packages int) int {
+	n := c.Parallelism
+	if n <= 0 {
+		n = runtime.NumCPU()
+	}
+	if n > packages {
+		n = packages
+	}
+	return n
+}
+
+// uniqueBundles returns one entry per bundle, dropping the entries of the other channels it is in
+func uniqueBundles(bundles []*api.Bundle) []*api.Bundle {
+	seen := map[string]struct{}{}
+	var unique []*api.Bundle
+	for _, b := range bundles {
+		if _, ok := seen[b.CsvName]; ok {
+			continue
+		}
+		seen[b.CsvName] = struct{}{}
+		unique = append(unique, b)
+	}
+	return unique
+}
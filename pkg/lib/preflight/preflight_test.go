@@ -0,0 +1,249 @@
+package preflight
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/operator-framework/operator-registry/pkg/api"
+	"github.com/operator-framework/operator-registry/pkg/image"
+	"github.com/operator-framework/operator-registry/pkg/lib/report"
+	"github.com/operator-framework/operator-registry/pkg/registry"
+	"github.com/operator-framework/operator-registry/pkg/sqlite"
+)
+
+type fakeResolver map[string]bool
+
+func (r fakeResolver) Resolve(ctx context.Context, ref image.Reference) (string, error) {
+	if r[ref.String()] {
+		return "sha256:abc", nil
+	}
+	return "", fmt.Errorf("not found")
+}
+
+func loadCatalog(t *testing.T) (*sql.DB, string, func()) {
+	dir, err := ioutil.TempDir("", "preflight-")
+	require.NoError(t, err)
+	path := filepath.Join(dir, "index.db")
+	db, err := sql.Open("sqlite3", path)
+	require.NoError(t, err)
+
+	store, err := sqlite.NewSQLLiteLoader(db)
+	require.NoError(t, err)
+	require.NoError(t, store.Migrate(context.TODO()))
+	require.NoError(t, sqlite.NewSQLLoaderForDirectory(store, "../../../manifests").Populate())
+
+	return db, path, func() {
+		db.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+func TestRun(t *testing.T) {
+	db, _, cleanup := loadCatalog(t)
+	defer cleanup()
+
+	resolver := fakeResolver{}
+	querier := sqlite.NewSQLLiteQuerierFromDb(db)
+	images, err := querier.ListImages(context.TODO())
+	require.NoError(t, err)
+	for _, img := range images {
+		resolver[img] = img != "strimzi/operator:0.12.2"
+	}
+	catalog := &Catalog{Querier: querier, Resolver: resolver}
+
+	checks, err := SelectChecks(nil, nil)
+	require.NoError(t, err)
+	result, err := Run(context.TODO(), catalog, checks)
+	require.NoError(t, err)
+	require.True(t, result.Failed())
+
+	subjects := func(check string) []string {
+		var s []string
+		for _, f := range result.Findings[check] {
+			s = append(s, f.Subject)
+		}
+		return s
+	}
+	// the sample prometheus and strimzi bundles have CRDs their CSVs don't own
+	require.Equal(t, []string{"prometheusoperator.0.14.0", "prometheusoperator.0.15.0", "strimzi-cluster-operator.v0.11.0", "strimzi-cluster-operator.v0.11.1"}, subjects(CheckSchema))
+	require.Empty(t, result.Findings[CheckGraph])
+	require.Empty(t, result.Findings[CheckDependencies])
+	require.Equal(t, []string{"strimzi-cluster-operator.v0.12.2"}, subjects(CheckImages))
+	require.Equal(t, []string{"strimzi-cluster-operator.v0.11.0", "strimzi-cluster-operator.v0.11.1", "strimzi-cluster-operator.v0.12.1", "strimzi-cluster-operator.v0.12.2"}, subjects(CheckDigests))
+	for _, f := range result.Findings[CheckDigests] {
+		require.Equal(t, SeverityWarning, f.Severity)
+	}
+	require.Empty(t, result.Findings[CheckDeprecations])
+	require.Empty(t, result.Findings[CheckVersions])
+
+	// the schema errors of the prometheus and strimzi bundles rank them above etcd
+	require.Equal(t, []PackageSummary{
+		{Package: "strimzi-kafka-operator", Errors: 3, Warnings: 4},
+		{Package: "prometheus", Errors: 2},
+		{Package: "etcd"},
+	}, result.Summary())
+
+	var junit bytes.Buffer
+	require.NoError(t, result.Report().Write(&junit, report.FormatJUnit))
+	require.Contains(t, junit.String(), `<testsuite name="schema" tests="4" failures="4">`)
+	require.Contains(t, junit.String(), `<testsuite name="digests" tests="4" failures="0">`)
+	require.Contains(t, junit.String(), `<testsuite name="graph" tests="1" failures="0">`)
+
+	// warnings alone don't fail the preflight
+	checks, err = SelectChecks([]string{CheckGraph, CheckDigests}, map[string]string{CheckImages: "off"})
+	require.NoError(t, err)
+	result, err = Run(context.TODO(), catalog, checks)
+	require.NoError(t, err)
+	require.False(t, result.Failed())
+	require.Len(t, result.Checks, 2)
+
+	_, err = SelectChecks([]string{"lint"}, nil)
+	require.Error(t, err)
+	_, err = SelectChecks(nil, map[string]string{CheckGraph: "fatal"})
+	require.Error(t, err)
+}
+
+func TestRunBrokenCatalog(t *testing.T) {
+	db, path, cleanup := loadCatalog(t)
+	defer cleanup()
+
+	// a default channel that doesn't exist can only be written on a connection without foreign keys
+	unchecked, err := sql.Open("sqlite3", path)
+	require.NoError(t, err)
+	defer unchecked.Close()
+	_, err = unchecked.Exec(`UPDATE package SET default_channel = 'nightly' WHERE name = 'etcd'`)
+	require.NoError(t, err)
+	_, err = db.Exec(`INSERT INTO properties (type, value, operatorbundle_name, operatorbundle_version, operatorbundle_path) VALUES
+		('olm.deprecated', '{}', 'etcdoperator.v0.9.2', '0.9.2', ''),
+		('olm.deprecated', 'true', 'etcdoperator.v0.6.1', '0.6.1', '')`)
+	require.NoError(t, err)
+	_, err = db.Exec(`INSERT INTO dependencies (type, value, operatorbundle_name, operatorbundle_version, operatorbundle_path) VALUES
+		('olm.package', '{"packageName": "etcd", "version": ">1.0.0"}', 'prometheusoperator.0.22.2', '0.22.2', '')`)
+	require.NoError(t, err)
+
+	checks, err := SelectChecks([]string{CheckGraph, CheckDependencies, CheckDeprecations}, nil)
+	require.NoError(t, err)
+	result, err := Run(context.TODO(), &Catalog{Querier: sqlite.NewSQLLiteQuerierFromDb(db)}, checks)
+	require.NoError(t, err)
+
+	require.Equal(t, []Finding{{Check: CheckGraph, Severity: SeverityError, Package: "etcd", Subject: "etcd", Message: `default channel "nightly" is not a channel of the package`}}, result.Findings[CheckGraph])
+	require.Equal(t, []Finding{{Check: CheckDependencies, Severity: SeverityError, Package: "prometheus", Subject: "prometheusoperator.0.22.2", Message: "no bundle of package etcd has a version in >1.0.0"}}, result.Findings[CheckDependencies])
+	require.Equal(t, []Finding{
+		{Check: CheckDeprecations, Severity: SeverityError, Package: "etcd", Subject: "etcd/alpha", Message: "channel head etcdoperator.v0.9.2 is deprecated, so nothing can be installed from the channel"},
+		{Check: CheckDeprecations, Severity: SeverityError, Package: "etcd", Subject: "etcd/stable", Message: "channel head etcdoperator.v0.9.2 is deprecated, so nothing can be installed from the channel"},
+		{Check: CheckDeprecations, Severity: SeverityError, Package: "etcd", Subject: "etcdoperator.v0.6.1", Message: `olm.deprecated property "true" is not a json object`},
+	}, result.Findings[CheckDeprecations])
+
+	// the images check can't run without a resolver
+	checks, err = SelectChecks([]string{CheckImages}, nil)
+	require.NoError(t, err)
+	_, err = Run(context.TODO(), &Catalog{Querier: sqlite.NewSQLLiteQuerierFromDb(db)}, checks)
+	require.Error(t, err)
+}
+
+func TestRunIsolatesPackages(t *testing.T) {
+	db, _, cleanup := loadCatalog(t)
+	defer cleanup()
+
+	// a check that fails on one package and panics on another still reports on the rest
+	checks := []Check{{Name: "bundles", Severity: SeverityWarning, run: func(ctx context.Context, catalog *Catalog, pkg *catalogPackage) ([]Finding, error) {
+		switch pkg.name {
+		case "etcd":
+			return nil, fmt.Errorf("etcd is broken")
+		case "prometheus":
+			panic("prometheus is broken")
+		}
+		return []Finding{{Subject: pkg.name, Message: fmt.Sprintf("%d bundles", len(uniqueBundles(pkg.bundles)))}}, nil
+	}}}
+	for _, parallelism := range []int{1, 3, 0} {
+		result, err := Run(context.TODO(), &Catalog{Querier: sqlite.NewSQLLiteQuerierFromDb(db), Parallelism: parallelism}, checks)
+		require.NoError(t, err)
+		require.Equal(t, []string{"etcd", "prometheus", "strimzi-kafka-operator"}, result.Packages)
+		require.Equal(t, []Finding{
+			{Check: "bundles", Severity: SeverityError, Package: "etcd", Subject: "etcd", Message: "check couldn't be run: etcd is broken"},
+			{Check: "bundles", Severity: SeverityError, Package: "prometheus", Subject: "prometheus", Message: "check couldn't be run: panic: prometheus is broken"},
+			{Check: "bundles", Severity: SeverityWarning, Package: "strimzi-kafka-operator", Subject: "strimzi-kafka-operator", Message: "4 bundles"},
+		}, result.Findings["bundles"])
+		require.Equal(t, []PackageSummary{
+			{Package: "etcd", Errors: 1},
+			{Package: "prometheus", Errors: 1},
+			{Package: "strimzi-kafka-operator", Warnings: 1},
+		}, result.Summary())
+	}
+
+	ctx, cancel := context.WithCancel(context.TODO())
+	cancel()
+	_, err := Run(ctx, &Catalog{Querier: sqlite.NewSQLLiteQuerierFromDb(db)}, checks)
+	require.Error(t, err)
+}
+
+func TestCheckVersions(t *testing.T) {
+	db, _, cleanup := loadCatalog(t)
+	defer cleanup()
+
+	_, err := db.Exec(`UPDATE operatorbundle SET version = 'v0.9.0' WHERE name = 'etcdoperator.v0.9.0'`)
+	require.NoError(t, err)
+	_, err = db.Exec(`UPDATE operatorbundle SET version = '0.23.0-rc.1+build.2' WHERE name = 'prometheusoperator.0.22.2'`)
+	require.NoError(t, err)
+	_, err = db.Exec(`UPDATE operatorbundle SET version = '0.15.1' WHERE name = 'prometheusoperator.0.14.0'`)
+	require.NoError(t, err)
+
+	checks, err := SelectChecks([]string{CheckVersions}, nil)
+	require.NoError(t, err)
+	catalog := &Catalog{Querier: sqlite.NewSQLLiteQuerierFromDb(db)}
+	result, err := Run(context.TODO(), catalog, checks)
+	require.NoError(t, err)
+	require.Equal(t, []Finding{
+		{Check: CheckVersions, Severity: SeverityError, Package: "etcd", Subject: "etcdoperator.v0.9.0", Message: `version "v0.9.0" is not valid semver: Invalid character(s) found in major number "v0"`},
+		{Check: CheckVersions, Severity: SeverityError, Package: "prometheus", Subject: "prometheus", Message: `prometheusoperator.0.15.0 replaces prometheusoperator.0.14.0: version "0.15.0" upgrades from version "0.15.1", which isn't lower`},
+		{Check: CheckVersions, Severity: SeverityError, Package: "prometheus", Subject: "prometheusoperator.0.22.2", Message: `version "0.23.0-rc.1+build.2" has build metadata, which semver precedence ignores`},
+	}, result.Findings[CheckVersions])
+
+	catalog.VersionPolicy, err = registry.NewVersionPolicy(registry.VersionPolicy{AllowBuildMetadata: true, PrereleaseChannels: []string{"beta"}, AllowDowngrades: true})
+	require.NoError(t, err)
+	result, err = Run(context.TODO(), catalog, checks)
+	require.NoError(t, err)
+	require.Equal(t, []Finding{
+		{Check: CheckVersions, Severity: SeverityError, Package: "etcd", Subject: "etcdoperator.v0.9.0", Message: `version "v0.9.0" is not valid semver: Invalid character(s) found in major number "v0"`},
+		{Check: CheckVersions, Severity: SeverityError, Package: "prometheus", Subject: "prometheusoperator.0.22.2", Message: `version "0.23.0-rc.1+build.2" is a pre-release, which channel "preview" can't hold`},
+	}, result.Findings[CheckVersions])
+}
+
+func TestCheckChannelSuggestions(t *testing.T) {
+	bundle := func(name, version, replaces string, skips ...string) *api.Bundle {
+		return &api.Bundle{CsvName: name, Version: version, Replaces: replaces, Skips: skips}
+	}
+	entries := map[string]*api.Bundle{
+		"a.v1.3.0": bundle("a.v1.3.0", "1.3.0", "a.v1.2.0"),
+		"a.v1.2.0": bundle("a.v1.2.0", "1.2.0", "a.v1.1.5"),
+		"a.v1.1.0": bundle("a.v1.1.0", "1.1.0", ""),
+		"a.v1.0.0": bundle("a.v1.0.0", "1.0.0", ""),
+	}
+	findings := checkChannel("a/stable", entries["a.v1.3.0"], entries)
+	require.ElementsMatch(t, []Finding{
+		{Subject: "a/stable", Message: "a.v1.2.0 replaces a.v1.1.5, which is not in the channel", Suggestion: "set the replaces of a.v1.2.0 to a.v1.1.0"},
+		{Subject: "a/stable", Message: "a.v1.1.0 can't be reached from the channel head a.v1.3.0", Suggestion: "add a.v1.1.0 to the skips of a.v1.2.0"},
+		{Subject: "a/stable", Message: "a.v1.0.0 can't be reached from the channel head a.v1.3.0", Suggestion: "add a.v1.0.0 to the skips of a.v1.2.0"},
+	}, findings)
+
+	// the oldest bundle can only skip what it replaces, and bundles newer than the head can only become the head
+	entries = map[string]*api.Bundle{
+		"a.v1.0.0": bundle("a.v1.0.0", "1.0.0", "a.v0.9.0"),
+		"a.v2.0.0": bundle("a.v2.0.0", "2.0.0", ""),
+		"a.vnext":  bundle("a.vnext", "", ""),
+	}
+	findings = checkChannel("a/stable", entries["a.v1.0.0"], entries)
+	require.ElementsMatch(t, []Finding{
+		{Subject: "a/stable", Message: "a.v1.0.0 replaces a.v0.9.0, which is not in the channel", Suggestion: "add a.v0.9.0 to the skips of a.v1.0.0"},
+		{Subject: "a/stable", Message: "a.v2.0.0 can't be reached from the channel head a.v1.0.0", Suggestion: "set the replaces of a.v2.0.0 to a.v1.0.0, making it the channel head"},
+		{Subject: "a/stable", Message: "a.vnext can't be reached from the channel head a.v1.0.0"},
+	}, findings)
+}
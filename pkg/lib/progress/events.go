@@ -0,0 +1,172 @@
+package progress
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// LogFormat selects how a command reports what it does
+type LogFormat string
+
+const (
+	// LogFormatText logs free-form lines
+	LogFormatText LogFormat = "text"
+	// LogFormatJSONLines writes a json object per line for every event of the command: its start, each log line and
+	// progress event, and its result
+	LogFormatJSONLines LogFormat = "json-lines"
+)
+
+// RecordType is the kind of event a Record describes
+type RecordType string
+
+const (
+	RecordStart    RecordType = "start"
+	RecordProgress RecordType = "progress"
+	RecordLog      RecordType = "log"
+	RecordWarning  RecordType = "warning"
+	RecordError    RecordType = "error"
+	RecordResult   RecordType = "result"
+)
+
+// Record is a line of the event stream of a command
+type Record struct {
+	Time    time.Time  `json:"time"`
+	Type    RecordType `json:"type"`
+	Command string     `json:"command,omitempty"`
+
+	// Flags are the names of the flags the command was started with
+	Flags []string `json:"flags,omitempty"`
+
+	// Level, Message and Fields are those of a log line
+	Level   string                 `json:"level,omitempty"`
+	Message string                 `json:"message,omitempty"`
+	Fields  map[string]interface{} `json:"fields,omitempty"`
+
+	Progress *ProgressRecord `json:"progress,omitempty"`
+
+	// Success, Error, ExitCode and DurationSeconds are the result of the command
+	Success         *bool   `json:"success,omitempty"`
+	Error           string  `json:"error,omitempty"`
+	ExitCode        *int    `json:"exitCode,omitempty"`
+	DurationSeconds float64 `json:"durationSeconds,omitempty"`
+}
+
+// ProgressRecord is a progress event along with its completion
+type ProgressRecord struct {
+	Event
+	Percent float64 `json:"percent"`
+}
+
+// Stream writes the events of a command to w as json lines, so that wrappers and UIs can follow the command and
+// collect its diagnostics without parsing free-form logs. It reports progress events as a Reporter, and formats log
+// lines as a logrus Formatter.
+type Stream struct {
+	mu      sync.Mutex
+	w       io.Writer
+	command string
+}
+
+// NewStream returns a stream of the events of command that writes them to w
+func NewStream(w io.Writer, command string) *Stream {
+	return &Stream{w: w, command: command}
+}
+
+// Start writes the start of the command, which was run with the named flags
+func (s *Stream) Start(flags []string) {
+	s.write(Record{Type: RecordStart, Flags: flags})
+}
+
+// Report writes a progress event
+func (s *Stream) Report(e Event) {
+	s.write(Record{Type: RecordProgress, Progress: &ProgressRecord{Event: e, Percent: e.Percent()}})
+}
+
+// Result writes the result of the command, which exited with exitCode after running for duration
+func (s *Stream) Result(err error, exitCode int, duration time.Duration) {
+	success := err == nil
+	r := Record{Type: RecordResult, Success: &success, ExitCode: &exitCode, DurationSeconds: duration.Seconds()}
+	if err != nil {
+		r.Error = err.Error()
+	}
+	s.write(r)
+}
+
+// Format formats a log line as a log event, or as a warning or error event for those levels. It doesn't write the
+// line itself, since logrus writes formatted lines wherever its output and hooks point.
+func (s *Stream) Format(entry *logrus.Entry) ([]byte, error) {
+	r := Record{Time: entry.Time, Type: RecordLog, Level: entry.Level.String(), Message: entry.Message}
+	switch entry.Level {
+	case logrus.WarnLevel:
+		r.Type = RecordWarning
+	case logrus.ErrorLevel, logrus.FatalLevel, logrus.PanicLevel:
+		r.Type = RecordError
+	}
+	if len(entry.Data) > 0 {
+		r.Fields = make(map[string]interface{}, len(entry.Data))
+		for k, v := range entry.Data {
+			// errors have no exported fields, so they would be written as {}
+			if err, ok := v.(error); ok {
+				v = err.Error()
+			}
+			r.Fields[k] = v
+		}
+	}
+	return s.marshal(r)
+}
+
+func (s *Stream) marshal(r Record) ([]byte, error) {
+	if r.Time.IsZero() {
+		r.Time = time.Now()
+	}
+	r.Command = s.command
+	line, err := json.Marshal(r)
+	if err != nil {
+		return nil, fmt.Errorf("error formatting %s event: %s", r.Type, err)
+	}
+	return append(line, '\n'), nil
+}
+
+func (s *Stream) write(r Record) {
+	line, err := s.marshal(r)
+	if err != nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.w.Write(line)
+}
+
+var (
+	streamMu sync.Mutex
+	stream   *Stream
+)
+
+// SetStream makes every command context report its progress to s rather than in the format asked of it, so that
+// progress is part of the event stream. Nil restores the formats.
+func SetStream(s *Stream) {
+	streamMu.Lock()
+	defer streamMu.Unlock()
+	stream = s
+}
+
+func currentStream() *Stream {
+	streamMu.Lock()
+	defer streamMu.Unlock()
+	return stream
+}
+
+// ParseLogFormat returns the log format named by format, text if it's empty
+func ParseLogFormat(format string) (LogFormat, error) {
+	switch LogFormat(format) {
+	case "", LogFormatText:
+		return LogFormatText, nil
+	case LogFormatJSONLines:
+		return LogFormatJSONLines, nil
+	}
+	return "", fmt.Errorf("invalid log format %q, must be one of: [text, json-lines]", format)
+}
@@ -0,0 +1,112 @@
+package progress
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestStream(t *testing.T) {
+	var out bytes.Buffer
+	s := NewStream(&out, "opm registry add")
+
+	logger := logrus.New()
+	logger.SetOutput(&out)
+	logger.SetFormatter(s)
+
+	s.Start([]string{"database"})
+	logger.WithField("bundle", "quay.io/test/etcd:v0.9.2").Info("adding bundle")
+	s.Report(Event{Phase: "add", Item: "quay.io/test/etcd:v0.9.2", Current: 1, Total: 2})
+	logger.WithError(errors.New("no such host")).Warn("couldn't pull")
+	logger.Error("couldn't add")
+	s.Result(errors.New("couldn't add"), 1, 2*time.Second)
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 6 {
+		t.Fatalf("expected 6 json lines, got %d: %q", len(lines), out.String())
+	}
+	var records []Record
+	for _, line := range lines {
+		var r Record
+		if err := json.Unmarshal([]byte(line), &r); err != nil {
+			t.Fatalf("parsing %q: %s", line, err)
+		}
+		if r.Command != "opm registry add" || r.Time.IsZero() {
+			t.Fatalf("expected every event to have a time and the command, got %q", line)
+		}
+		records = append(records, r)
+	}
+
+	var types []string
+	for _, r := range records {
+		types = append(types, string(r.Type))
+	}
+	if got := strings.Join(types, ","); got != "start,log,progress,warning,error,result" {
+		t.Fatalf("unexpected event types %s", got)
+	}
+	if len(records[0].Flags) != 1 || records[0].Flags[0] != "database" {
+		t.Fatalf("unexpected start event %+v", records[0])
+	}
+	if records[1].Message != "adding bundle" || records[1].Level != "info" || records[1].Fields["bundle"] != "quay.io/test/etcd:v0.9.2" {
+		t.Fatalf("unexpected log event %+v", records[1])
+	}
+	if p := records[2].Progress; p == nil || p.Phase != "add" || p.Current != 1 || p.Total != 2 || p.Percent != 50 {
+		t.Fatalf("unexpected progress event %+v", records[2])
+	}
+	if records[3].Fields["error"] != "no such host" {
+		t.Fatalf("expected the error of a warning to be written as its message, got %+v", records[3])
+	}
+	result := records[5]
+	if result.Success == nil || *result.Success || result.Error != "couldn't add" || result.ExitCode == nil || *result.ExitCode != 1 || result.DurationSeconds != 2 {
+		t.Fatalf("unexpected result event %+v", result)
+	}
+}
+
+func TestContextReportsToStream(t *testing.T) {
+	var out bytes.Buffer
+	SetStream(NewStream(&out, "opm index prune"))
+	defer SetStream(nil)
+
+	ctx, cancel, err := Context(string(FormatBar))
+	if err != nil {
+		t.Fatalf("creating context: %s", err)
+	}
+	defer cancel()
+	Report(ctx, "prune", "etcd", 1, 1)
+
+	var r Record
+	if err := json.Unmarshal(out.Bytes(), &r); err != nil {
+		t.Fatalf("expected the progress event in the stream rather than a bar, got %q: %s", out.String(), err)
+	}
+	if r.Type != RecordProgress || r.Progress == nil || r.Progress.Item != "etcd" {
+		t.Fatalf("unexpected event %+v", r)
+	}
+
+	// without a stream, progress is reported in the format asked for
+	SetStream(nil)
+	ctx, cancel, err = Context(string(FormatNone))
+	if err != nil {
+		t.Fatalf("creating context: %s", err)
+	}
+	defer cancel()
+	if _, ok := FromContext(ctx).(nopReporter); !ok {
+		t.Fatalf("expected progress to be dropped")
+	}
+}
+
+func TestParseLogFormat(t *testing.T) {
+	for format, expected := range map[string]LogFormat{"": LogFormatText, "text": LogFormatText, "json-lines": LogFormatJSONLines} {
+		got, err := ParseLogFormat(format)
+		if err != nil || got != expected {
+			t.Fatalf("parsing %q: expected %s, got %s, %v", format, expected, got, err)
+		}
+	}
+	if _, err := ParseLogFormat("json"); err == nil {
+		t.Fatalf("expected an invalid log format to fail")
+	}
+}
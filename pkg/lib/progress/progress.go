@@ -0,0 +1,166 @@
+package progress
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+)
+
+// Format selects how progress events are surfaced
+type Format string
+
+const (
+	FormatNone Format = "none"
+	FormatBar  Format = "bar"
+	FormatJSON Format = "json"
+)
+
+// barWidth is the number of cells drawn by the terminal progress bar
+const barWidth = 30
+
+// Event describes how far a long running operation has progressed.
+// Current counts the completed items of the phase, out of Total.
+type Event struct {
+	Phase   string `json:"phase"`
+	Item    string `json:"item,omitempty"`
+	Current int    `json:"current"`
+	Total   int    `json:"total"`
+}
+
+// Percent returns the completion of the event's phase, from 0 to 100
+func (e Event) Percent() float64 {
+	if e.Total <= 0 {
+		return 0
+	}
+	return float64(e.Current) * 100 / float64(e.Total)
+}
+
+// Reporter receives the progress events of an operation
+type Reporter interface {
+	Report(Event)
+}
+
+// NewReporter returns a Reporter writing events to w in the given format
+func NewReporter(format Format, w io.Writer) (Reporter, error) {
+	switch format {
+	case "", FormatNone:
+		return nopReporter{}, nil
+	case FormatBar:
+		return &barReporter{w: w}, nil
+	case FormatJSON:
+		return &jsonReporter{enc: json.NewEncoder(w)}, nil
+	}
+	return nil, fmt.Errorf("invalid progress format %q, must be one of: [none, bar, json]", format)
+}
+
+type nopReporter struct{}
+
+func (nopReporter) Report(Event) {}
+
+// barReporter redraws a single line per phase on a terminal
+type barReporter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (r *barReporter) Report(e Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	filled := 0
+	if e.Total > 0 {
+		filled = e.Current * barWidth / e.Total
+	}
+	if filled > barWidth {
+		filled = barWidth
+	}
+	fmt.Fprintf(r.w, "\r\033[K%s [%s%s] %d/%d %s", e.Phase, strings.Repeat("#", filled), strings.Repeat(".", barWidth-filled), e.Current, e.Total, e.Item)
+	if e.Current >= e.Total {
+		fmt.Fprintln(r.w)
+	}
+}
+
+// jsonReporter writes one json object per event
+type jsonReporter struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+type jsonEvent struct {
+	Event
+	Percent float64 `json:"percent"`
+}
+
+func (r *jsonReporter) Report(e Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.enc.Encode(jsonEvent{Event: e, Percent: e.Percent()})
+}
+
+type reporterKey struct{}
+
+// WithReporter returns a copy of ctx that carries the given reporter
+func WithReporter(ctx context.Context, r Reporter) context.Context {
+	return context.WithValue(ctx, reporterKey{}, r)
+}
+
+// FromContext returns the reporter carried by ctx, or one that drops every event
+func FromContext(ctx context.Context) Reporter {
+	if r, ok := ctx.Value(reporterKey{}).(Reporter); ok {
+		return r
+	}
+	return nopReporter{}
+}
+
+// Report sends an event to the reporter carried by ctx
+func Report(ctx context.Context, phase, item string, current, total int) {
+	FromContext(ctx).Report(Event{Phase: phase, Item: item, Current: current, Total: total})
+}
+
+// Canceled returns ctx's error once it has been cancelled, so long operations can stop between items
+func Canceled(ctx context.Context) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+		return nil
+	}
+}
+
+// InterruptContext returns a context that is cancelled on the first interrupt or termination signal.
+// A second signal is not caught, so it terminates the process as usual.
+func InterruptContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(ctx)
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		select {
+		case <-interrupt:
+			signal.Stop(interrupt)
+			cancel()
+		case <-ctx.Done():
+			signal.Stop(interrupt)
+		}
+	}()
+	return ctx, cancel
+}
+
+// Context returns the context to run a command with: it carries a reporter for the given format
+// writing to stderr, or the event stream if one is set, and is cancelled when the command is interrupted
+func Context(format string) (context.Context, context.CancelFunc, error) {
+	reporter, err := NewReporter(Format(format), os.Stderr)
+	if err != nil {
+		return nil, nil, err
+	}
+	if s := currentStream(); s != nil {
+		reporter = s
+	}
+	ctx, cancel := InterruptContext(WithReporter(context.Background(), reporter))
+	return ctx, cancel, nil
+}
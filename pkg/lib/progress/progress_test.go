@@ -0,0 +1,77 @@
+package progress
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestJSONReporter(t *testing.T) {
+	var out bytes.Buffer
+	reporter, err := NewReporter(FormatJSON, &out)
+	if err != nil {
+		t.Fatalf("creating reporter: %s", err)
+	}
+
+	ctx := WithReporter(context.TODO(), reporter)
+	Report(ctx, "prune", "etcd", 1, 4)
+	Report(ctx, "prune", "prometheus", 4, 4)
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 json lines, got %d: %q", len(lines), out.String())
+	}
+
+	var event struct {
+		Phase   string  `json:"phase"`
+		Item    string  `json:"item"`
+		Current int     `json:"current"`
+		Total   int     `json:"total"`
+		Percent float64 `json:"percent"`
+	}
+	if err := json.Unmarshal([]byte(lines[0]), &event); err != nil {
+		t.Fatalf("parsing event: %s", err)
+	}
+	if event.Phase != "prune" || event.Item != "etcd" || event.Current != 1 || event.Total != 4 || event.Percent != 25 {
+		t.Fatalf("unexpected event %+v", event)
+	}
+}
+
+func TestBarReporter(t *testing.T) {
+	var out bytes.Buffer
+	reporter, err := NewReporter(FormatBar, &out)
+	if err != nil {
+		t.Fatalf("creating reporter: %s", err)
+	}
+
+	reporter.Report(Event{Phase: "export", Item: "quay.io/test/etcd:v0.9.0", Current: 1, Total: 2})
+	if strings.HasSuffix(out.String(), "\n") {
+		t.Fatalf("expected an unfinished phase to stay on the same line, got %q", out.String())
+	}
+	reporter.Report(Event{Phase: "export", Item: "quay.io/test/etcd:v0.9.2", Current: 2, Total: 2})
+	if !strings.Contains(out.String(), "export ["+strings.Repeat("#", barWidth)+"] 2/2") || !strings.HasSuffix(out.String(), "\n") {
+		t.Fatalf("expected a full bar ending the line, got %q", out.String())
+	}
+}
+
+func TestNewReporterInvalidFormat(t *testing.T) {
+	if _, err := NewReporter("xml", &bytes.Buffer{}); err == nil {
+		t.Fatalf("expected error for invalid format")
+	}
+}
+
+func TestCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.TODO())
+	if err := Canceled(ctx); err != nil {
+		t.Fatalf("unexpected error before cancel: %s", err)
+	}
+	cancel()
+	if err := Canceled(ctx); err != context.Canceled {
+		t.Fatalf("expected %v after cancel, got %v", context.Canceled, err)
+	}
+
+	// events without a reporter are dropped
+	Report(context.TODO(), "migrate", "", 1, 1)
+}
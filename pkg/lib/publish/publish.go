@@ -0,0 +1,233 @@
+// Package publish publishes catalogs in two phases: a candidate index image is staged under a staging reference once
+// its database passes preflight, and is then promoted to production by pushing that same image, once it passes
+// preflight again and, if required, has been approved by digest.
+package publish
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/operator-framework/operator-registry/pkg/containertools"
+	"github.com/operator-framework/operator-registry/pkg/image"
+	"github.com/operator-framework/operator-registry/pkg/lib/preflight"
+	"github.com/operator-framework/operator-registry/pkg/lib/tmp"
+	"github.com/operator-framework/operator-registry/pkg/registry"
+	"github.com/operator-framework/operator-registry/pkg/sqlite"
+)
+
+// databaseFile is the name the database is added to a staged image as, relative to its build context
+const databaseFile = "index.db"
+
+var (
+	// ErrPreflightFailed is returned when the catalog fails preflight, in which case nothing is built or pushed
+	ErrPreflightFailed = errors.New("catalog failed preflight checks")
+	// ErrNotApproved is returned when the staged image isn't the one that was approved, in which case nothing is pushed
+	ErrNotApproved = errors.New("staged image is not the approved image")
+)
+
+// Registry pulls, unpacks and pushes index images
+type Registry interface {
+	Pull(ctx context.Context, ref image.Reference) error
+	Push(ctx context.Context, ref, to image.Reference) error
+	Unpack(ctx context.Context, ref image.Reference, dir string) error
+	Labels(ctx context.Context, ref image.Reference) (map[string]string, error)
+	// Digest returns the digest of an image as it was pulled
+	Digest(ctx context.Context, ref image.Reference) (string, error)
+	// Resolve returns the digest a reference currently names in its remote registry
+	Resolve(ctx context.Context, ref image.Reference) (string, error)
+}
+
+// Preflight configures the preflight a catalog must pass to be staged or promoted
+type Preflight struct {
+	Checks []preflight.Check
+	// Resolver is used by the images check, which can't be run without one
+	Resolver preflight.ImageResolver
+	// VersionPolicy is what the versions check checks bundle versions against, the strictest policy if unset
+	VersionPolicy *registry.VersionPolicy
+}
+
+// Result is the outcome of staging or promoting a catalog
+type Result struct {
+	// Digest is the digest of the staged or promoted image, empty if it wasn't staged or promoted
+	Digest string
+	// Preflight is the outcome of preflight, empty if it wasn't run
+	Preflight preflight.Result
+}
+
+// StageRequest stages a catalog database
+type StageRequest struct {
+	Database string
+	// StagingImage is the reference the candidate image is pushed to
+	StagingImage string
+	// BinarySourceImage is the image the database is added to, which serves it
+	BinarySourceImage string
+	Preflight         Preflight
+	CommandRunner     containertools.CommandRunner
+	// Registry resolves the digest of the pushed image
+	Registry Registry
+	Logger   *logrus.Entry
+}
+
+// Stage runs preflight against a catalog database and, if it passes, builds it into an index image that is pushed
+// under the staging reference. The result holds the digest of the staged image, which is what approving it for
+// production refers to.
+func Stage(ctx context.Context, request StageRequest) (Result, error) {
+	result, err := runPreflight(ctx, request.Database, request.Preflight)
+	if err != nil {
+		return Result{}, err
+	}
+	if result.Failed() {
+		return Result{Preflight: result}, ErrPreflightFailed
+	}
+
+	workspace, err := tmp.NewWorkspace("stage")
+	if err != nil {
+		return Result{Preflight: result}, err
+	}
+	defer workspace.Cleanup()
+	buildDir, err := workspace.Dir("build-")
+	if err != nil {
+		return Result{Preflight: result}, err
+	}
+	if err := copyFile(request.Database, filepath.Join(buildDir, databaseFile)); err != nil {
+		return Result{Preflight: result}, err
+	}
+	dockerfile := filepath.Join(buildDir, "index.Dockerfile")
+	generator := containertools.NewDockerfileGenerator(request.Logger)
+	if err := writeFile(dockerfile, generator.GenerateIndexDockerfile(request.BinarySourceImage, databaseFile)); err != nil {
+		return Result{Preflight: result}, err
+	}
+
+	request.Logger.Infof("building %s", request.StagingImage)
+	if err := request.CommandRunner.BuildFromContext(dockerfile, request.StagingImage, buildDir); err != nil {
+		return Result{Preflight: result}, err
+	}
+	request.Logger.Infof("pushing %s", request.StagingImage)
+	if err := request.CommandRunner.Push(request.StagingImage); err != nil {
+		return Result{Preflight: result}, err
+	}
+
+	digest, err := request.Registry.Resolve(ctx, image.SimpleReference(request.StagingImage))
+	if err != nil {
+		return Result{Preflight: result}, err
+	}
+	request.Logger.WithField("digest", digest).Infof("staged %s", request.StagingImage)
+	return Result{Digest: digest, Preflight: result}, nil
+}
+
+// PromoteRequest promotes a staged catalog to production
+type PromoteRequest struct {
+	StagingImage    string
+	ProductionImage string
+	// ApprovedDigest, if set, is the digest the staged image must have to be promoted, so that an image staged after
+	// the approval isn't promoted in its place
+	ApprovedDigest string
+	Preflight      Preflight
+	Registry       Registry
+	Logger         *logrus.Entry
+}
+
+// Promote pulls the staged image, runs preflight against the database it holds and, if it passes and the image is
+// the approved one, pushes that same image under the production reference. Production is only updated once every
+// layer of the image is pushed, so it names either the promoted image or the one it named before.
+func Promote(ctx context.Context, request PromoteRequest) (Result, error) {
+	staging := image.SimpleReference(request.StagingImage)
+	if err := request.Registry.Pull(ctx, staging); err != nil {
+		return Result{}, err
+	}
+	digest, err := request.Registry.Digest(ctx, staging)
+	if err != nil {
+		return Result{}, err
+	}
+	if request.ApprovedDigest != "" && digest != request.ApprovedDigest {
+		return Result{}, fmt.Errorf("%s has digest %s, approved %s: %w", request.StagingImage, digest, request.ApprovedDigest, ErrNotApproved)
+	}
+
+	labels, err := request.Registry.Labels(ctx, staging)
+	if err != nil {
+		return Result{}, err
+	}
+	dbLocation, ok := labels[containertools.DbLocationLabel]
+	if !ok {
+		dbLocation = containertools.DefaultDbLocation
+	}
+	workspace, err := tmp.NewWorkspace("promote")
+	if err != nil {
+		return Result{}, err
+	}
+	defer workspace.Cleanup()
+	unpackDir := filepath.Join(workspace.Path(), "image")
+	if err := request.Registry.Unpack(ctx, staging, unpackDir); err != nil {
+		return Result{}, err
+	}
+
+	result, err := runPreflight(ctx, filepath.Join(unpackDir, dbLocation), request.Preflight)
+	if err != nil {
+		return Result{}, err
+	}
+	if result.Failed() {
+		return Result{Preflight: result}, ErrPreflightFailed
+	}
+
+	production := image.SimpleReference(request.ProductionImage)
+	if err := request.Registry.Push(ctx, staging, production); err != nil {
+		return Result{Preflight: result}, err
+	}
+	promoted, err := request.Registry.Resolve(ctx, production)
+	if err != nil {
+		return Result{Preflight: result}, err
+	}
+	if promoted != digest {
+		return Result{Preflight: result}, fmt.Errorf("%s has digest %s after promoting %s, expected %s", request.ProductionImage, promoted, request.StagingImage, digest)
+	}
+	request.Logger.WithField("digest", digest).Infof("promoted %s to %s", request.StagingImage, request.ProductionImage)
+	return Result{Digest: digest, Preflight: result}, nil
+}
+
+func runPreflight(ctx context.Context, database string, config Preflight) (preflight.Result, error) {
+	if _, err := os.Stat(database); err != nil {
+		return preflight.Result{}, err
+	}
+	querier, err := sqlite.NewSQLLiteQuerier(database)
+	if err != nil {
+		return preflight.Result{}, err
+	}
+	defer querier.Close()
+	catalog := &preflight.Catalog{Querier: querier, Resolver: config.Resolver, VersionPolicy: config.VersionPolicy}
+	return preflight.Run(ctx, catalog, config.Checks)
+}
+
+func copyFile(from, to string) error {
+	src, err := tmp.OpenRegularFile(from)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	dst, err := os.Create(to)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(dst, src); err != nil {
+		dst.Close()
+		return err
+	}
+	return dst.Close()
+}
+
+func writeFile(path, content string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if _, err := f.WriteString(content); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,192 @@
+package publish
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+
+	"github.com/operator-framework/operator-registry/pkg/containertools"
+	"github.com/operator-framework/operator-registry/pkg/containertools/containertoolsfakes"
+	"github.com/operator-framework/operator-registry/pkg/image"
+	"github.com/operator-framework/operator-registry/pkg/lib/preflight"
+	"github.com/operator-framework/operator-registry/pkg/sqlite"
+)
+
+// fakeImage is an index image holding a database
+type fakeImage struct {
+	digest   string
+	database string
+}
+
+// fakeRegistry is a remote registry of index images, and the images pulled from it
+type fakeRegistry struct {
+	remote map[string]fakeImage
+	pulled map[string]fakeImage
+	pushed []string
+}
+
+func newFakeRegistry() *fakeRegistry {
+	return &fakeRegistry{remote: map[string]fakeImage{}, pulled: map[string]fakeImage{}}
+}
+
+func (r *fakeRegistry) Pull(ctx context.Context, ref image.Reference) error {
+	img, ok := r.remote[ref.String()]
+	if !ok {
+		return fmt.Errorf("%s not found", ref)
+	}
+	r.pulled[ref.String()] = img
+	return nil
+}
+
+func (r *fakeRegistry) Push(ctx context.Context, ref, to image.Reference) error {
+	img, ok := r.pulled[ref.String()]
+	if !ok {
+		return fmt.Errorf("%s wasn't pulled", ref)
+	}
+	r.remote[to.String()] = img
+	r.pushed = append(r.pushed, to.String())
+	return nil
+}
+
+func (r *fakeRegistry) Unpack(ctx context.Context, ref image.Reference, dir string) error {
+	img, ok := r.pulled[ref.String()]
+	if !ok {
+		return fmt.Errorf("%s wasn't pulled", ref)
+	}
+	path := filepath.Join(dir, containertools.DefaultDbLocation)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return copyFile(img.database, path)
+}
+
+func (r *fakeRegistry) Labels(ctx context.Context, ref image.Reference) (map[string]string, error) {
+	return map[string]string{containertools.DbLocationLabel: containertools.DefaultDbLocation}, nil
+}
+
+func (r *fakeRegistry) Digest(ctx context.Context, ref image.Reference) (string, error) {
+	return r.pulled[ref.String()].digest, nil
+}
+
+func (r *fakeRegistry) Resolve(ctx context.Context, ref image.Reference) (string, error) {
+	img, ok := r.remote[ref.String()]
+	if !ok {
+		return "", fmt.Errorf("%s not found", ref)
+	}
+	return img.digest, nil
+}
+
+func loadCatalog(t *testing.T, dir string) string {
+	path := filepath.Join(dir, "index.db")
+	db, err := sql.Open("sqlite3", path)
+	require.NoError(t, err)
+	defer db.Close()
+	store, err := sqlite.NewSQLLiteLoader(db)
+	require.NoError(t, err)
+	require.NoError(t, store.Migrate(context.TODO()))
+	require.NoError(t, sqlite.NewSQLLoaderForDirectory(store, "../../../manifests").Populate())
+	return path
+}
+
+// checks returns the preflight the sample catalog passes, or, with the schema check, fails
+func checks(t *testing.T, names ...string) Preflight {
+	selected, err := preflight.SelectChecks(names, nil)
+	require.NoError(t, err)
+	return Preflight{Checks: selected}
+}
+
+func TestStage(t *testing.T) {
+	dir, err := ioutil.TempDir("", "stage-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	database := loadCatalog(t, dir)
+	logger := logrus.NewEntry(logrus.New())
+
+	runner := &containertoolsfakes.FakeCommandRunner{}
+	reg := newFakeRegistry()
+	runner.BuildFromContextStub = func(dockerfile, tag, buildContext string) error {
+		content, err := ioutil.ReadFile(dockerfile)
+		require.NoError(t, err)
+		require.Contains(t, string(content), "FROM quay.io/operator-framework/upstream-opm-builder")
+		require.FileExists(t, filepath.Join(buildContext, databaseFile))
+		return nil
+	}
+	runner.PushStub = func(tag string) error {
+		reg.remote[tag] = fakeImage{digest: "sha256:staged", database: database}
+		return nil
+	}
+
+	request := StageRequest{
+		Database:          database,
+		StagingImage:      "quay.io/example/catalog:staging",
+		BinarySourceImage: "quay.io/operator-framework/upstream-opm-builder",
+		Preflight:         checks(t, preflight.CheckGraph),
+		CommandRunner:     runner,
+		Registry:          reg,
+		Logger:            logger,
+	}
+	result, err := Stage(context.TODO(), request)
+	require.NoError(t, err)
+	require.Equal(t, "sha256:staged", result.Digest)
+	require.False(t, result.Preflight.Failed())
+	require.Equal(t, 1, runner.BuildFromContextCallCount())
+	require.Equal(t, "quay.io/example/catalog:staging", runner.PushArgsForCall(0))
+
+	// a catalog that fails preflight isn't built
+	request.Preflight = checks(t, preflight.CheckSchema)
+	result, err = Stage(context.TODO(), request)
+	require.Equal(t, ErrPreflightFailed, err)
+	require.True(t, result.Preflight.Failed())
+	require.Empty(t, result.Digest)
+	require.Equal(t, 1, runner.BuildFromContextCallCount())
+	require.Equal(t, 1, runner.PushCallCount())
+}
+
+func TestPromote(t *testing.T) {
+	dir, err := ioutil.TempDir("", "promote-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	logger := logrus.NewEntry(logrus.New())
+
+	reg := newFakeRegistry()
+	reg.remote["quay.io/example/catalog:staging"] = fakeImage{digest: "sha256:staged", database: loadCatalog(t, dir)}
+	reg.remote["quay.io/example/catalog:latest"] = fakeImage{digest: "sha256:previous"}
+	request := PromoteRequest{
+		StagingImage:    "quay.io/example/catalog:staging",
+		ProductionImage: "quay.io/example/catalog:latest",
+		ApprovedDigest:  "sha256:other",
+		Preflight:       checks(t, preflight.CheckGraph),
+		Registry:        reg,
+		Logger:          logger,
+	}
+
+	// an image other than the approved one isn't promoted
+	_, err = Promote(context.TODO(), request)
+	require.Error(t, err)
+	require.True(t, errors.Is(err, ErrNotApproved))
+	require.Empty(t, reg.pushed)
+
+	// nor is one that fails preflight
+	request.ApprovedDigest = "sha256:staged"
+	request.Preflight = checks(t, preflight.CheckSchema)
+	result, err := Promote(context.TODO(), request)
+	require.Equal(t, ErrPreflightFailed, err)
+	require.True(t, result.Preflight.Failed())
+	require.Empty(t, reg.pushed)
+	require.Equal(t, "sha256:previous", reg.remote["quay.io/example/catalog:latest"].digest)
+
+	request.Preflight = checks(t, preflight.CheckGraph)
+	result, err = Promote(context.TODO(), request)
+	require.NoError(t, err)
+	require.Equal(t, "sha256:staged", result.Digest)
+	require.Equal(t, []string{"quay.io/example/catalog:latest"}, reg.pushed)
+	require.Equal(t, "sha256:staged", reg.remote["quay.io/example/catalog:latest"].digest)
+}
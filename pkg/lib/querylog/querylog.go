@@ -0,0 +1,125 @@
+package querylog
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+)
+
+// registryService prefixes the full method names of the registry API; health checks and reflection aren't recorded
+const registryService = "/api.Registry/"
+
+// Entry is a single recorded RPC
+type Entry struct {
+	// Method is the full gRPC method name, e.g. /api.Registry/GetBundle
+	Method string `json:"method"`
+
+	// Request is the request message as json
+	Request json.RawMessage `json:"request"`
+
+	// Time is when the RPC was received
+	Time time.Time `json:"time"`
+}
+
+// Recorder writes the RPCs a registry server receives to a log, one json entry per line
+type Recorder struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+// NewRecorder returns a Recorder that writes to w
+func NewRecorder(w io.Writer) *Recorder {
+	return &Recorder{enc: json.NewEncoder(w)}
+}
+
+// ServerOptions returns the options that make a grpc server record its registry RPCs. They can be combined with other
+// interceptors.
+func (r *Recorder) ServerOptions() []grpc.ServerOption {
+	return []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(r.unary),
+		grpc.ChainStreamInterceptor(r.stream),
+	}
+}
+
+func (r *Recorder) record(method string, req interface{}) error {
+	// watches are subscriptions rather than queries, and there is nothing to replay them against
+	if !strings.HasPrefix(method, registryService) || method == registryService+"Watch" {
+		return nil
+	}
+	raw, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.enc.Encode(Entry{Method: method, Request: raw, Time: time.Now()})
+}
+
+func (r *Recorder) unary(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	if err := r.record(info.FullMethod, req); err != nil {
+		return nil, fmt.Errorf("error recording query: %s", err)
+	}
+	return handler(ctx, req)
+}
+
+func (r *Recorder) stream(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	return handler(srv, &recordingStream{ServerStream: ss, recorder: r, method: info.FullMethod})
+}
+
+// recordingStream records the request of a server streaming RPC when the handler receives it
+type recordingStream struct {
+	grpc.ServerStream
+	recorder *Recorder
+	method   string
+	recorded bool
+}
+
+func (s *recordingStream) RecvMsg(m interface{}) error {
+	if err := s.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+	if s.recorded {
+		return nil
+	}
+	s.recorded = true
+	if err := s.recorder.record(s.method, m); err != nil {
+		return fmt.Errorf("error recording query: %s", err)
+	}
+	return nil
+}
+
+// ReadLog reads the entries of a log written by a Recorder
+func ReadLog(path string) ([]Entry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var entries []Entry
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(strings.TrimSpace(scanner.Text())) == 0 {
+			continue
+		}
+		var entry Entry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("error parsing %s line %d: %s", path, line, err)
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
@@ -0,0 +1,93 @@
+package querylog
+
+import (
+	"context"
+	"database/sql"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+
+	"github.com/operator-framework/operator-registry/pkg/api"
+	"github.com/operator-framework/operator-registry/pkg/server"
+	"github.com/operator-framework/operator-registry/pkg/sqlite"
+)
+
+// serve starts a registry for the manifests, optionally adjusted by an overlay, and returns a client for it
+func serve(t *testing.T, dir string, overlay *sqlite.Overlay, opts ...grpc.ServerOption) api.RegistryClient {
+	db, err := sql.Open("sqlite3", filepath.Join(dir, "index.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	load, err := sqlite.NewSQLLiteLoader(db)
+	require.NoError(t, err)
+	require.NoError(t, load.Migrate(context.TODO()))
+	require.NoError(t, sqlite.NewSQLLoaderForDirectory(load, "../../../manifests").Populate())
+	if overlay != nil {
+		require.NoError(t, sqlite.ApplyOverlay(context.TODO(), db, overlay))
+	}
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	s := grpc.NewServer(opts...)
+	api.RegisterRegistryServer(s, server.NewRegistryServer(sqlite.NewSQLLiteQuerierFromDb(db)))
+	go s.Serve(lis)
+	t.Cleanup(s.Stop)
+
+	conn, err := grpc.Dial(lis.Addr().String(), grpc.WithInsecure())
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return api.NewRegistryClient(conn)
+}
+
+func TestRecordAndReplay(t *testing.T) {
+	dir, err := ioutil.TempDir("", "querylog-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "baseline"), 0755))
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "candidate"), 0755))
+
+	logPath := filepath.Join(dir, "queries.log")
+	f, err := os.Create(logPath)
+	require.NoError(t, err)
+	defer f.Close()
+
+	baseline := serve(t, filepath.Join(dir, "baseline"), nil, NewRecorder(f).ServerOptions()...)
+	candidate := serve(t, filepath.Join(dir, "candidate"), &sqlite.Overlay{Packages: []sqlite.PackageOverlay{{Name: "etcd", DefaultChannel: "beta"}}})
+
+	// queries made against the baseline are recorded
+	_, err = baseline.GetPackage(context.TODO(), &api.GetPackageRequest{Name: "etcd"})
+	require.NoError(t, err)
+	_, err = baseline.GetBundleForChannel(context.TODO(), &api.GetBundleInChannelRequest{PkgName: "prometheus", ChannelName: "preview"})
+	require.NoError(t, err)
+	_, err = baseline.GetPackage(context.TODO(), &api.GetPackageRequest{Name: "missing"})
+	require.Error(t, err)
+	stream, err := baseline.ListPackages(context.TODO(), &api.ListPackageRequest{})
+	require.NoError(t, err)
+	_, err = recvAll(func() (interface{}, error) { return stream.Recv() })
+	require.NoError(t, err)
+	require.NoError(t, f.Sync())
+
+	entries, err := ReadLog(logPath)
+	require.NoError(t, err)
+	require.Len(t, entries, 4)
+	require.Equal(t, "/api.Registry/GetPackage", entries[0].Method)
+	require.JSONEq(t, `{"name":"etcd"}`, string(entries[0].Request))
+	require.Equal(t, "/api.Registry/ListPackages", entries[3].Method)
+
+	// the baseline agrees with itself
+	differences, err := Replay(context.TODO(), entries, baseline, baseline)
+	require.NoError(t, err)
+	require.Empty(t, differences)
+
+	// only the package whose default channel was changed is answered differently
+	differences, err = Replay(context.TODO(), entries, baseline, candidate)
+	require.NoError(t, err)
+	require.Len(t, differences, 1)
+	require.Equal(t, entries[0], differences[0].Entry)
+	require.Contains(t, differences[0].Baseline, `"defaultChannelName":"alpha"`)
+	require.Contains(t, differences[0].Candidate, `"defaultChannelName":"beta"`)
+}
@@ -0,0 +1,307 @@
+package querylog
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/operator-framework/operator-registry/pkg/api"
+)
+
+// Difference is a recorded query that two catalogs answered differently
+type Difference struct {
+	Entry     Entry
+	Baseline  string
+	Candidate string
+}
+
+// call replays a recorded request against a registry and returns the responses it got
+type call func(ctx context.Context, client api.RegistryClient, request json.RawMessage) ([]interface{}, error)
+
+var calls = map[string]call{
+	registryService + "ListPackages": func(ctx context.Context, client api.RegistryClient, request json.RawMessage) ([]interface{}, error) {
+		req := &api.ListPackageRequest{}
+		if err := json.Unmarshal(request, req); err != nil {
+			return nil, err
+		}
+		stream, err := client.ListPackages(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+		return recvAll(func() (interface{}, error) { return stream.Recv() })
+	},
+	registryService + "ListBundles": func(ctx context.Context, client api.RegistryClient, request json.RawMessage) ([]interface{}, error) {
+		req := &api.ListBundlesRequest{}
+		if err := json.Unmarshal(request, req); err != nil {
+			return nil, err
+		}
+		stream, err := client.ListBundles(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+		return recvAll(func() (interface{}, error) { return stream.Recv() })
+	},
+	registryService + "GetPackage": func(ctx context.Context, client api.RegistryClient, request json.RawMessage) ([]interface{}, error) {
+		req := &api.GetPackageRequest{}
+		if err := json.Unmarshal(request, req); err != nil {
+			return nil, err
+		}
+		res, err := client.GetPackage(ctx, req)
+		return []interface{}{res}, err
+	},
+	registryService + "GetBundle": func(ctx context.Context, client api.RegistryClient, request json.RawMessage) ([]interface{}, error) {
+		req := &api.GetBundleRequest{}
+		if err := json.Unmarshal(request, req); err != nil {
+			return nil, err
+		}
+		res, err := client.GetBundle(ctx, req)
+		return []interface{}{res}, err
+	},
+	registryService + "GetBundleForChannel": func(ctx context.Context, client api.RegistryClient, request json.RawMessage) ([]interface{}, error) {
+		req := &api.GetBundleInChannelRequest{}
+		if err := json.Unmarshal(request, req); err != nil {
+			return nil, err
+		}
+		res, err := client.GetBundleForChannel(ctx, req)
+		return []interface{}{res}, err
+	},
+	registryService + "GetChannelEntriesThatReplace": func(ctx context.Context, client api.RegistryClient, request json.RawMessage) ([]interface{}, error) {
+		req := &api.GetAllReplacementsRequest{}
+		if err := json.Unmarshal(request, req); err != nil {
+			return nil, err
+		}
+		stream, err := client.GetChannelEntriesThatReplace(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+		return recvAll(func() (interface{}, error) { return stream.Recv() })
+	},
+	registryService + "GetBundleThatReplaces": func(ctx context.Context, client api.RegistryClient, request json.RawMessage) ([]interface{}, error) {
+		req := &api.GetReplacementRequest{}
+		if err := json.Unmarshal(request, req); err != nil {
+			return nil, err
+		}
+		res, err := client.GetBundleThatReplaces(ctx, req)
+		return []interface{}{res}, err
+	},
+	registryService + "GetChannelEntriesThatProvide": func(ctx context.Context, client api.RegistryClient, request json.RawMessage) ([]interface{}, error) {
+		req := &api.GetAllProvidersRequest{}
+		if err := json.Unmarshal(request, req); err != nil {
+			return nil, err
+		}
+		stream, err := client.GetChannelEntriesThatProvide(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+		return recvAll(func() (interface{}, error) { return stream.Recv() })
+	},
+	registryService + "GetLatestChannelEntriesThatProvide": func(ctx context.Context, client api.RegistryClient, request json.RawMessage) ([]interface{}, error) {
+		req := &api.GetLatestProvidersRequest{}
+		if err := json.Unmarshal(request, req); err != nil {
+			return nil, err
+		}
+		stream, err := client.GetLatestChannelEntriesThatProvide(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+		return recvAll(func() (interface{}, error) { return stream.Recv() })
+	},
+	registryService + "GetDefaultBundleThatProvides": func(ctx context.Context, client api.RegistryClient, request json.RawMessage) ([]interface{}, error) {
+		req := &api.GetDefaultProviderRequest{}
+		if err := json.Unmarshal(request, req); err != nil {
+			return nil, err
+		}
+		res, err := client.GetDefaultBundleThatProvides(ctx, req)
+		return []interface{}{res}, err
+	},
+	registryService + "BundlesExist": func(ctx context.Context, client api.RegistryClient, request json.RawMessage) ([]interface{}, error) {
+		req := &api.BundlesExistRequest{}
+		if err := json.Unmarshal(request, req); err != nil {
+			return nil, err
+		}
+		res, err := client.BundlesExist(ctx, req)
+		return []interface{}{res}, err
+	},
+	registryService + "GetAPIVersion": func(ctx context.Context, client api.RegistryClient, request json.RawMessage) ([]interface{}, error) {
+		req := &api.GetAPIVersionRequest{}
+		if err := json.Unmarshal(request, req); err != nil {
+			return nil, err
+		}
+		res, err := client.GetAPIVersion(ctx, req)
+		return []interface{}{res}, err
+	},
+	registryService + "GetPackageStats": func(ctx context.Context, client api.RegistryClient, request json.RawMessage) ([]interface{}, error) {
+		req := &api.GetPackageStatsRequest{}
+		if err := json.Unmarshal(request, req); err != nil {
+			return nil, err
+		}
+		stream, err := client.GetPackageStats(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+		return recvAll(func() (interface{}, error) { return stream.Recv() })
+	},
+	registryService + "GetBundleStats": func(ctx context.Context, client api.RegistryClient, request json.RawMessage) ([]interface{}, error) {
+		req := &api.GetBundleStatsRequest{}
+		if err := json.Unmarshal(request, req); err != nil {
+			return nil, err
+		}
+		stream, err := client.GetBundleStats(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+		return recvAll(func() (interface{}, error) { return stream.Recv() })
+	},
+	registryService + "GetDefaultChannelHeads": func(ctx context.Context, client api.RegistryClient, request json.RawMessage) ([]interface{}, error) {
+		req := &api.GetDefaultChannelHeadsRequest{}
+		if err := json.Unmarshal(request, req); err != nil {
+			return nil, err
+		}
+		stream, err := client.GetDefaultChannelHeads(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+		return recvAll(func() (interface{}, error) { return stream.Recv() })
+	},
+	registryService + "GetBundlesForPackage": func(ctx context.Context, client api.RegistryClient, request json.RawMessage) ([]interface{}, error) {
+		req := &api.GetBundlesForPackageRequest{}
+		if err := json.Unmarshal(request, req); err != nil {
+			return nil, err
+		}
+		res, err := client.GetBundlesForPackage(ctx, req)
+		return []interface{}{res}, err
+	},
+	registryService + "SearchBundles": func(ctx context.Context, client api.RegistryClient, request json.RawMessage) ([]interface{}, error) {
+		req := &api.SearchBundlesRequest{}
+		if err := json.Unmarshal(request, req); err != nil {
+			return nil, err
+		}
+		stream, err := client.SearchBundles(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+		return recvAll(func() (interface{}, error) { return stream.Recv() })
+	},
+}
+
+func recvAll(recv func() (interface{}, error)) ([]interface{}, error) {
+	var out []interface{}
+	for {
+		res, err := recv()
+		if err == io.EOF {
+			return out, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, res)
+	}
+}
+
+// Replay runs the recorded queries against the baseline and candidate catalogs and returns the queries they answered
+// differently. Streamed responses and repeated fields are compared regardless of their order, and errors are compared
+// by status code only, since backends are free to word them differently.
+func Replay(ctx context.Context, entries []Entry, baseline, candidate api.RegistryClient) ([]Difference, error) {
+	var differences []Difference
+	for _, entry := range entries {
+		replay, ok := calls[entry.Method]
+		if !ok {
+			return nil, fmt.Errorf("can't replay unknown method %s", entry.Method)
+		}
+
+		expected, err := answer(ctx, replay, baseline, entry.Request)
+		if err != nil {
+			return nil, fmt.Errorf("error replaying %s against baseline: %s", entry.Method, err)
+		}
+		actual, err := answer(ctx, replay, candidate, entry.Request)
+		if err != nil {
+			return nil, fmt.Errorf("error replaying %s against candidate: %s", entry.Method, err)
+		}
+
+		if expected != actual {
+			differences = append(differences, Difference{Entry: entry, Baseline: expected, Candidate: actual})
+		}
+	}
+	return differences, nil
+}
+
+// answer replays a request and normalizes what the registry answered so that answers can be compared
+func answer(ctx context.Context, replay call, client api.RegistryClient, request json.RawMessage) (string, error) {
+	responses, err := replay(ctx, client, request)
+	if code := status.Code(err); code == codes.Unavailable || code == codes.Canceled {
+		// the catalog couldn't be reached, there is nothing to compare
+		return "", err
+	}
+	if err != nil {
+		if _, ok := status.FromError(err); !ok {
+			return "", err
+		}
+		return fmt.Sprintf("error: %s", status.Code(err)), nil
+	}
+
+	lines := make([]string, 0, len(responses))
+	for _, res := range responses {
+		out, err := canonical(res)
+		if err != nil {
+			return "", err
+		}
+		lines = append(lines, out)
+	}
+	sort.Strings(lines)
+	return strings.Join(lines, "\n"), nil
+}
+
+// canonical renders a response as json with the elements of every list sorted, since the order of
+// repeated fields such as properties or provided apis depends on how the catalog was built
+func canonical(res interface{}) (string, error) {
+	raw, err := json.Marshal(res)
+	if err != nil {
+		return "", err
+	}
+	var value interface{}
+	if err := json.Unmarshal(raw, &value); err != nil {
+		return "", err
+	}
+	out, err := json.Marshal(sortLists(value))
+	if err != nil {
+		return "", err
+	}
+	return string(out), nil
+}
+
+func sortLists(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, elem := range v {
+			v[key] = sortLists(elem)
+		}
+	case []interface{}:
+		keys := make([]string, len(v))
+		for i, elem := range v {
+			v[i] = sortLists(elem)
+			// encoding/json sorts map keys, so equal elements always render the same
+			key, _ := json.Marshal(v[i])
+			keys[i] = string(key)
+		}
+		sort.Sort(byKey{elems: v, keys: keys})
+	}
+	return value
+}
+
+type byKey struct {
+	elems []interface{}
+	keys  []string
+}
+
+func (b byKey) Len() int           { return len(b.elems) }
+func (b byKey) Less(i, j int) bool { return b.keys[i] < b.keys[j] }
+func (b byKey) Swap(i, j int) {
+	b.elems[i], b.elems[j] = b.elems[j], b.elems[i]
+	b.keys[i], b.keys[j] = b.keys[j], b.keys[i]
+}
@@ -0,0 +1,87 @@
+package registry
+
+import (
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/operator-framework/operator-registry/pkg/registry"
+	"github.com/operator-framework/operator-registry/pkg/sqlite"
+)
+
+// Formats the api matrix can be exported in
+const (
+	APIMatrixFormatCSV  = "csv"
+	APIMatrixFormatJSON = "json"
+)
+
+type ExportAPIMatrixRequest struct {
+	InputDatabase string
+	// Format is either csv or json
+	Format string
+	// CollisionsOnly limits the matrix to the GVKs provided by more than one package
+	CollisionsOnly bool
+	Output         io.Writer
+}
+
+// ExportAPIMatrix writes which packages provide and require each GVK of a catalog
+func (r RegistryUpdater) ExportAPIMatrix(request ExportAPIMatrixRequest) error {
+	db, err := sql.Open("sqlite3", request.InputDatabase)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	matrix, err := registry.APIMatrix(context.TODO(), sqlite.NewSQLLiteQuerierFromDb(db))
+	if err != nil {
+		return err
+	}
+	if request.CollisionsOnly {
+		collisions := matrix[:0]
+		for _, entry := range matrix {
+			if entry.Collision() {
+				collisions = append(collisions, entry)
+			}
+		}
+		matrix = collisions
+	}
+	r.Logger.Infof("exporting %d apis", len(matrix))
+
+	switch request.Format {
+	case APIMatrixFormatCSV:
+		return writeAPIMatrixCSV(request.Output, matrix)
+	case APIMatrixFormatJSON:
+		enc := json.NewEncoder(request.Output)
+		enc.SetIndent("", "  ")
+		return enc.Encode(matrix)
+	}
+	return fmt.Errorf("unknown api matrix format %q, must be one of [%s, %s]", request.Format, APIMatrixFormatCSV, APIMatrixFormatJSON)
+}
+
+// writeAPIMatrixCSV writes a row per GVK, with the providing and requiring packages separated by semicolons
+func writeAPIMatrixCSV(w io.Writer, matrix []registry.APIMatrixEntry) error {
+	out := csv.NewWriter(w)
+	if err := out.Write([]string{"group", "version", "kind", "providers", "requirers", "collision"}); err != nil {
+		return err
+	}
+	for _, entry := range matrix {
+		row := []string{
+			entry.Group,
+			entry.Version,
+			entry.Kind,
+			strings.Join(entry.Providers, ";"),
+			strings.Join(entry.Requirers, ";"),
+			strconv.FormatBool(entry.Collision()),
+		}
+		if err := out.Write(row); err != nil {
+			return err
+		}
+	}
+	out.Flush()
+	return out.Error()
+}
@@ -2,12 +2,14 @@
 package registry
 
 import (
+	"context"
+
 	"github.com/sirupsen/logrus"
 )
 
 //counterfeiter:generate . RegistryAdder
 type RegistryAdder interface {
-	AddToRegistry(AddToRegistryRequest) error
+	AddToRegistry(context.Context, AddToRegistryRequest) (AddToRegistryResult, error)
 }
 
 func NewRegistryAdder(logger *logrus.Entry) RegistryAdder {
@@ -38,7 +40,7 @@ func NewRegistryStrandedPruner(logger *logrus.Entry) RegistryStrandedPruner {
 }
 
 type RegistryPruner interface {
-	PruneFromRegistry(PruneFromRegistryRequest) error
+	PruneFromRegistry(context.Context, PruneFromRegistryRequest) error
 }
 
 func NewRegistryPruner(logger *logrus.Entry) RegistryPruner {
@@ -56,3 +58,83 @@ func NewRegistryDeprecator(logger *logrus.Entry) RegistryDeprecator {
 		Logger: logger,
 	}
 }
+
+type RegistryRestorer interface {
+	RestoreToRegistry(RestoreToRegistryRequest) error
+}
+
+func NewRegistryRestorer(logger *logrus.Entry) RegistryRestorer {
+	return RegistryUpdater{
+		Logger: logger,
+	}
+}
+
+type RegistryPurger interface {
+	PurgeFromRegistry(PurgeFromRegistryRequest) error
+}
+
+func NewRegistryPurger(logger *logrus.Entry) RegistryPurger {
+	return RegistryUpdater{
+		Logger: logger,
+	}
+}
+
+type RegistrySharder interface {
+	ShardRegistry(ShardRegistryRequest) ([]string, error)
+}
+
+func NewRegistrySharder(logger *logrus.Entry) RegistrySharder {
+	return RegistryUpdater{
+		Logger: logger,
+	}
+}
+
+type RegistryRenamer interface {
+	RenamePackageInRegistry(RenamePackageInRegistryRequest) error
+}
+
+func NewRegistryRenamer(logger *logrus.Entry) RegistryRenamer {
+	return RegistryUpdater{
+		Logger: logger,
+	}
+}
+
+type RegistryAPIMatrixExporter interface {
+	ExportAPIMatrix(ExportAPIMatrixRequest) error
+}
+
+func NewRegistryAPIMatrixExporter(logger *logrus.Entry) RegistryAPIMatrixExporter {
+	return RegistryUpdater{
+		Logger: logger,
+	}
+}
+
+type RegistryMigrator interface {
+	MigrateRegistry(MigrateRegistryRequest) error
+}
+
+func NewRegistryMigrator(logger *logrus.Entry) RegistryMigrator {
+	return RegistryUpdater{
+		Logger: logger,
+	}
+}
+
+type RegistryAnonymizer interface {
+	AnonymizeRegistry(AnonymizeRegistryRequest) error
+}
+
+func NewRegistryAnonymizer(logger *logrus.Entry) RegistryAnonymizer {
+	return RegistryUpdater{
+		Logger: logger,
+	}
+}
+
+type RegistryChannelSelectorUpdater interface {
+	UpdateChannelSelector(ChannelSelectorRequest) error
+}
+
+func NewRegistryChannelSelectorUpdater(logger *logrus.Entry) RegistryChannelSelectorUpdater {
+	return RegistryUpdater{
+		Logger: logger,
+	}
+}
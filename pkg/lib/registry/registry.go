@@ -4,8 +4,10 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
-	"io/ioutil"
 	"os"
+	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/sirupsen/logrus"
 	utilerrors "k8s.io/apimachinery/pkg/util/errors"
@@ -15,6 +17,8 @@ import (
 	"github.com/operator-framework/operator-registry/pkg/image/containerdregistry"
 	"github.com/operator-framework/operator-registry/pkg/image/execregistry"
 	"github.com/operator-framework/operator-registry/pkg/lib/certs"
+	"github.com/operator-framework/operator-registry/pkg/lib/progress"
+	"github.com/operator-framework/operator-registry/pkg/lib/tmp"
 	"github.com/operator-framework/operator-registry/pkg/registry"
 	"github.com/operator-framework/operator-registry/pkg/sqlite"
 )
@@ -31,29 +35,136 @@ type AddToRegistryRequest struct {
 	Bundles       []string
 	Mode          registry.Mode
 	ContainerTool containertools.ContainerTool
+	VerifyCommand string
+	// CredentialProviders name the providers of credentials for registries the docker config has none for, see
+	// containerdregistry.ParseCredentialProviders. They are only used when ContainerTool is none.
+	CredentialProviders []string
+	// State, if set, keeps the unpacked bundles across attempts so a failed add can be resumed
+	State *BuildState
+	// JournalMode and Synchronous, if set, are the SQLite journal mode and synchronous setting the database is
+	// written with. JournalMode defaults to sqlite.DefaultLoaderJournalMode; the database is checkpointed and switched
+	// back to sqlite.DefaultJournalMode once the bundles are added.
+	JournalMode string
+	Synchronous string
+	// InMemory, if set, builds the database in memory and only writes it to InputDatabase once every bundle is added
+	InMemory bool
+	// ChannelOverride, if set, is the channel membership the bundles are added with instead of that of their
+	// annotations. It is recorded in the database for each bundle it is applied to.
+	ChannelOverride registry.ChannelOverride
+	// CSVNameConflicts is what is done with bundles whose CSV name already belongs to another package. They are
+	// rejected by default.
+	CSVNameConflicts registry.ConflictPolicy
+	// VersionPolicy, if set, rejects bundles whose versions break it
+	VersionPolicy *registry.VersionPolicy
 }
 
-func (r RegistryUpdater) AddToRegistry(request AddToRegistryRequest) error {
-	db, err := sql.Open("sqlite3", request.InputDatabase)
-	if err != nil {
+// AddToRegistryResult is what an add did besides loading the bundles
+type AddToRegistryResult struct {
+	// AlreadyPresent are the bundle images that were skipped because the database already had them with identical
+	// content
+	AlreadyPresent []string
+}
+
+// AddToRegistry adds the bundles of the request to its database. ctx carries the progress reporter; cancelling it
+// aborts the add and restores the database.
+func (r RegistryUpdater) AddToRegistry(ctx context.Context, request AddToRegistryRequest) (AddToRegistryResult, error) {
+	var result AddToRegistryResult
+	err := rollbackOnCancel(ctx, request.InputDatabase, func() error {
+		var err error
+		result, err = r.addToRegistry(ctx, request)
 		return err
+	})
+	return result, err
+}
+
+func (r RegistryUpdater) addToRegistry(ctx context.Context, request AddToRegistryRequest) (AddToRegistryResult, error) {
+	if request.InMemory {
+		return r.addInMemory(ctx, request)
+	}
+
+	journalMode := request.JournalMode
+	if journalMode == "" {
+		journalMode = sqlite.DefaultLoaderJournalMode
+	}
+
+	// other adds may be writing to the same database, so transactions wait for them and are retried if they were
+	// still busy
+	dbOpts := []sqlite.DbOption{sqlite.WithJournalMode(journalMode), sqlite.WithSynchronous(request.Synchronous), sqlite.WithBusyTimeout(sqlite.DefaultBusyTimeout)}
+	db, err := sql.Open("sqlite3", sqlite.DSN(request.InputDatabase, dbOpts...))
+	if err != nil {
+		return AddToRegistryResult{}, err
 	}
 	defer db.Close()
 
-	dbLoader, err := sqlite.NewSQLLiteLoader(db)
+	if !strings.EqualFold(journalMode, sqlite.DefaultJournalMode) {
+		// the database is shipped as a single file, without the -wal file a WAL mode database needs. Another add
+		// still writing to it switches it back when it is done.
+		defer func() {
+			if err := sqlite.RestoreJournalMode(db); err != nil {
+				r.Logger.WithError(err).Warnf("couldn't switch database back to journal mode %s", sqlite.DefaultJournalMode)
+			}
+		}()
+	}
+	return r.addToDatabase(ctx, request, db, dbOpts...)
+}
+
+// addInMemory adds the bundles to a copy of the database held in memory, which is only written over the database
+// once every bundle is added
+func (r RegistryUpdater) addInMemory(ctx context.Context, request AddToRegistryRequest) (AddToRegistryResult, error) {
+	if request.JournalMode != "" || request.Synchronous != "" {
+		return AddToRegistryResult{}, fmt.Errorf("the journal mode and synchronous setting can't be set for a database built in memory")
+	}
+
+	var mem *sqlite.MemoryDatabase
+	var err error
+	if _, serr := os.Stat(request.InputDatabase); serr == nil {
+		mem, err = sqlite.LoadMemoryDatabase(ctx, request.InputDatabase)
+	} else {
+		mem, err = sqlite.OpenMemoryDatabase(ctx)
+	}
 	if err != nil {
-		return err
+		return AddToRegistryResult{}, err
 	}
-	if err := dbLoader.Migrate(context.TODO()); err != nil {
-		return err
+	defer mem.Close()
+
+	result, err := r.addToDatabase(ctx, request, mem.DB())
+	if err != nil {
+		return result, err
+	}
+	return result, mem.WriteTo(ctx, request.InputDatabase)
+}
+
+func (r RegistryUpdater) addToDatabase(ctx context.Context, request AddToRegistryRequest, db *sql.DB, dbOpts ...sqlite.DbOption) (AddToRegistryResult, error) {
+	var result AddToRegistryResult
+	dbLoader, err := sqlite.NewSQLLiteLoader(db, dbOpts...)
+	if err != nil {
+		return result, err
+	}
+	if err := dbLoader.Migrate(ctx); err != nil {
+		return result, err
 	}
 
 	graphLoader, err := sqlite.NewSQLGraphLoaderFromDB(db)
 	if err != nil {
-		return err
+		return result, err
 	}
 	dbQuerier := sqlite.NewSQLLiteQuerierFromDb(db)
 
+	// verify the bundle images before anything is pulled into the database
+	var attestations map[string]*registry.Attestation
+	if request.VerifyCommand != "" {
+		attestations, err = verifyBundles(ctx, NewCommandVerifier(request.VerifyCommand, r.Logger), request.Bundles)
+		if err != nil {
+			return result, err
+		}
+	}
+
+	workspace, err := tmp.NewWorkspace("registry-add")
+	if err != nil {
+		return result, err
+	}
+	defer workspace.Cleanup()
+
 	// add custom ca certs to resolver
 
 	var reg image.Registry
@@ -62,16 +173,23 @@ func (r RegistryUpdater) AddToRegistry(request AddToRegistryRequest) error {
 	case containertools.NoneTool:
 		rootCAs, err := certs.RootCAs(request.CaFile)
 		if err != nil {
-			return fmt.Errorf("failed to get RootCAs: %v", err)
+			return result, fmt.Errorf("failed to get RootCAs: %v", err)
+		}
+		providers, err := containerdregistry.ParseCredentialProviders(request.CredentialProviders)
+		if err != nil {
+			return result, err
 		}
-		reg, rerr = containerdregistry.NewRegistry(containerdregistry.SkipTLS(request.SkipTLS), containerdregistry.WithRootCAs(rootCAs))
+		reg, rerr = containerdregistry.NewRegistry(containerdregistry.SkipTLS(request.SkipTLS), containerdregistry.WithRootCAs(rootCAs), containerdregistry.WithCacheDir(filepath.Join(workspace.Path(), "cache")), containerdregistry.WithCredentialProviders(providers...))
 	case containertools.PodmanTool:
 		fallthrough
 	case containertools.DockerTool:
+		if len(request.CredentialProviders) > 0 {
+			return result, fmt.Errorf("credential providers are only supported with the none container tool")
+		}
 		reg, rerr = execregistry.NewRegistry(request.ContainerTool, r.Logger, containertools.SkipTLS(request.SkipTLS))
 	}
 	if rerr != nil {
-		return rerr
+		return result, rerr
 	}
 	defer func() {
 		if err := reg.Destroy(); err != nil {
@@ -84,31 +202,56 @@ func (r RegistryUpdater) AddToRegistry(request AddToRegistryRequest) error {
 		simpleRefs = append(simpleRefs, image.SimpleReference(ref))
 	}
 
-	if err := populate(context.TODO(), dbLoader, graphLoader, dbQuerier, reg, simpleRefs, request.Mode); err != nil {
+	result.AlreadyPresent, err = populate(ctx, dbLoader, graphLoader, dbQuerier, reg, workspace, request.State, simpleRefs, request.Mode, request.ChannelOverride, request.CSVNameConflicts, request.VersionPolicy)
+	if err != nil {
 		r.Logger.Debugf("unable to populate database: %s", err)
 
 		if !request.Permissive {
 			r.Logger.WithError(err).Error("permissive mode disabled")
-			return err
+			return result, err
 		} else {
 			r.Logger.WithError(err).Warn("permissive mode enabled")
 		}
 	}
 
-	return nil
+	if err := recordAttestations(dbLoader, attestations, r.Logger); err != nil {
+		return result, err
+	}
+
+	// channels defined by selectors pick up the bundles that were just added
+	if err := sqlite.ExpandChannelSelectors(ctx, db); err != nil {
+		return result, fmt.Errorf("error expanding channel selectors: %s", err)
+	}
+
+	return result, nil
 }
 
-func populate(ctx context.Context, loader registry.Load, graphLoader registry.GraphLoader, querier registry.Query, reg image.Registry, refs []image.Reference, mode registry.Mode) error {
+// populate adds the bundle images to the database, returning those that were skipped because they were already in it
+func populate(ctx context.Context, loader registry.Load, graphLoader registry.GraphLoader, querier registry.Query, reg image.Registry, workspace *tmp.Workspace, state *BuildState, refs []image.Reference, mode registry.Mode, override registry.ChannelOverride, csvNameConflicts registry.ConflictPolicy, versionPolicy *registry.VersionPolicy) ([]string, error) {
 	var errs []error
 
+	digester, _ := reg.(imageDigester)
+	digests := map[string]string{}
 	unpackedImageMap := make(map[image.Reference]string, 0)
-	for _, ref := range refs {
-		workingDir, err := ioutil.TempDir("./", "bundle_tmp")
+	for i, ref := range refs {
+		if err := progress.Canceled(ctx); err != nil {
+			return nil, err
+		}
+
+		if state != nil {
+			if dir, ok := state.Unpacked(ref.String()); ok {
+				logrus.WithField("img", ref.String()).Info("bundle already unpacked by a previous attempt, resuming")
+				unpackedImageMap[ref] = dir
+				progress.Report(ctx, "pull", ref.String(), i+1, len(refs))
+				continue
+			}
+		}
+
+		workingDir, err := unpackDir(workspace, state)
 		if err != nil {
 			errs = append(errs, err)
 			continue
 		}
-		defer os.RemoveAll(workingDir)
 
 		if err = reg.Pull(ctx, ref); err != nil {
 			errs = append(errs, err)
@@ -120,22 +263,83 @@ func populate(ctx context.Context, loader registry.Load, graphLoader registry.Gr
 			continue
 		}
 
+		if digester != nil {
+			if digest, err := digester.Digest(ctx, ref); err != nil {
+				logrus.WithField("img", ref.String()).WithError(err).Warn("couldn't get the digest of the bundle image")
+			} else {
+				digests[ref.String()] = digest
+			}
+		}
+
+		if state != nil {
+			if err := state.MarkUnpacked(ref.String(), workingDir); err != nil {
+				errs = append(errs, err)
+				continue
+			}
+		}
+
 		unpackedImageMap[ref] = workingDir
+		progress.Report(ctx, "pull", ref.String(), i+1, len(refs))
 	}
 
 	if len(errs) > 0 {
-		return utilerrors.NewAggregate(errs)
+		return nil, utilerrors.NewAggregate(errs)
+	}
+	if err := progress.Canceled(ctx); err != nil {
+		return nil, err
 	}
 
 	populator := registry.NewDirectoryPopulator(loader, graphLoader, querier, unpackedImageMap)
+	populator.OverrideChannels(override)
+	populator.OnCSVNameConflict(csvNameConflicts)
+	if versionPolicy != nil {
+		populator.EnforceVersionPolicy(versionPolicy)
+	}
+
+	progress.Report(ctx, "load", "", 0, 1)
+	if err := populator.Populate(mode); err != nil {
+		return populator.AlreadyPresent(), err
+	}
+	progress.Report(ctx, "load", "", 1, 1)
+	return populator.AlreadyPresent(), recordImageDigests(loader, digests)
+}
 
-	return populator.Populate(mode)
+// imageDigester is implemented by the registries that know the digests of the images they pulled
+type imageDigester interface {
+	Digest(ctx context.Context, ref image.Reference) (string, error)
+}
+
+// recordImageDigests records the digests the bundle images were pulled at. Images that weren't loaded, e.g. because
+// they were already in the database, are skipped.
+func recordImageDigests(loader registry.Load, digests map[string]string) error {
+	for img, digest := range digests {
+		err := loader.AddBundleImageDigest(img, digest)
+		if err == registry.ErrBundleImageNotInDatabase {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// unpackDir returns the directory to unpack a bundle into, which is kept in the build state when there is one
+func unpackDir(workspace *tmp.Workspace, state *BuildState) (string, error) {
+	if state != nil {
+		return state.BundleDir()
+	}
+	return workspace.Dir("bundle_tmp")
 }
 
 type DeleteFromRegistryRequest struct {
 	Permissive    bool
 	InputDatabase string
 	Packages      []string
+	// Soft keeps a tombstone of each removed package so that the removal can be undone
+	Soft bool
+	// UndoWindow is how long tombstones are kept for; older tombstones are purged. Zero keeps them forever.
+	UndoWindow time.Duration
 }
 
 func (r RegistryUpdater) DeleteFromRegistry(request DeleteFromRegistryRequest) error {
@@ -155,6 +359,9 @@ func (r RegistryUpdater) DeleteFromRegistry(request DeleteFromRegistryRequest) e
 
 	for _, pkg := range request.Packages {
 		remover := sqlite.NewSQLRemoverForPackages(dbLoader, pkg)
+		if request.Soft {
+			remover = sqlite.NewSQLSoftRemoverForPackages(dbLoader, pkg)
+		}
 		if err := remover.Remove(); err != nil {
 			err = fmt.Errorf("error deleting packages from database: %s", err)
 			if !request.Permissive {
@@ -165,6 +372,16 @@ func (r RegistryUpdater) DeleteFromRegistry(request DeleteFromRegistryRequest) e
 		}
 	}
 
+	if request.Soft && request.UndoWindow > 0 {
+		purged, err := dbLoader.PurgeTombstones(nil, request.UndoWindow)
+		if err != nil {
+			return fmt.Errorf("error purging expired package tombstones: %s", err)
+		}
+		if len(purged) > 0 {
+			r.Logger.Infof("purged tombstones older than %s: %v", request.UndoWindow, purged)
+		}
+	}
+
 	// remove any stranded bundles from the database
 	// TODO: This is unnecessary if the db schema can prevent this orphaned data from existing
 	remover := sqlite.NewSQLStrandedBundleRemover(dbLoader)
@@ -175,6 +392,249 @@ func (r RegistryUpdater) DeleteFromRegistry(request DeleteFromRegistryRequest) e
 	return nil
 }
 
+type RestoreToRegistryRequest struct {
+	InputDatabase string
+	Packages      []string
+	// UndoWindow is how long after removal a package can be restored. Zero allows any age.
+	UndoWindow time.Duration
+}
+
+// RestoreToRegistry restores soft-deleted packages from their tombstones
+func (r RegistryUpdater) RestoreToRegistry(request RestoreToRegistryRequest) error {
+	db, err := sql.Open("sqlite3", request.InputDatabase)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	dbLoader, err := sqlite.NewSQLLiteLoader(db)
+	if err != nil {
+		return err
+	}
+	if err := dbLoader.Migrate(context.TODO()); err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, pkg := range request.Packages {
+		if err := dbLoader.RestorePackage(pkg, request.UndoWindow); err != nil {
+			errs = append(errs, fmt.Errorf("error restoring package %s: %s", pkg, err))
+			continue
+		}
+		r.Logger.Infof("restored package %s", pkg)
+	}
+
+	return utilerrors.NewAggregate(errs)
+}
+
+type RenamePackageInRegistryRequest struct {
+	InputDatabase string
+	// From is the current name of the package
+	From string
+	// To is the new name of the package; From is kept as an alias of it
+	To string
+}
+
+// RenamePackageInRegistry rewrites a package name across the database, keeping the former name as an alias
+func (r RegistryUpdater) RenamePackageInRegistry(request RenamePackageInRegistryRequest) error {
+	db, err := sql.Open("sqlite3", request.InputDatabase)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	dbLoader, err := sqlite.NewSQLLiteLoader(db)
+	if err != nil {
+		return err
+	}
+	if err := dbLoader.Migrate(context.TODO()); err != nil {
+		return err
+	}
+
+	if err := dbLoader.RenamePackage(request.From, request.To); err != nil {
+		return fmt.Errorf("error renaming package %s to %s: %s", request.From, request.To, err)
+	}
+	r.Logger.Infof("renamed package %s to %s", request.From, request.To)
+
+	return nil
+}
+
+type MigrateRegistryRequest struct {
+	InputDatabase string
+	// Version is the schema version to migrate the database up or down to
+	Version int
+}
+
+// MigrateRegistry migrates the schema of a database in place to the requested version
+func (r RegistryUpdater) MigrateRegistry(request MigrateRegistryRequest) error {
+	// opening a missing database would create it
+	if _, err := os.Stat(request.InputDatabase); err != nil {
+		return err
+	}
+	db, err := sql.Open("sqlite3", request.InputDatabase)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	migrator, err := sqlite.NewSQLLiteMigrator(db)
+	if err != nil {
+		return err
+	}
+	from, err := migrator.Version(context.TODO())
+	if err != nil {
+		return err
+	}
+	if from == request.Version {
+		r.Logger.Infof("database is already at schema version %d", from)
+		return nil
+	}
+	if err := migrator.MigrateTo(context.TODO(), request.Version); err != nil {
+		return fmt.Errorf("error migrating database from schema version %d to %d: %s", from, request.Version, err)
+	}
+	r.Logger.Infof("migrated database from schema version %d to %d", from, request.Version)
+
+	return nil
+}
+
+type AnonymizeRegistryRequest struct {
+	InputDatabase string
+	// OutputDatabase is where the anonymized copy of the database is written, the input database is left alone
+	OutputDatabase string
+	// BlobSize is the size in bytes of the stub csv that replaces the csv of each bundle
+	BlobSize int
+}
+
+// AnonymizeRegistry writes an anonymized copy of a database that can be shared as a fixture, see sqlite.Anonymize
+func (r RegistryUpdater) AnonymizeRegistry(request AnonymizeRegistryRequest) error {
+	// opening a missing database would create it
+	if _, err := os.Stat(request.InputDatabase); err != nil {
+		return err
+	}
+	if err := sqlite.SafeCopyFile(context.TODO(), request.InputDatabase, request.OutputDatabase); err != nil {
+		return err
+	}
+	db, err := sql.Open("sqlite3", request.OutputDatabase)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	dbLoader, err := sqlite.NewSQLLiteLoader(db)
+	if err != nil {
+		return err
+	}
+	if err := dbLoader.Migrate(context.TODO()); err != nil {
+		return err
+	}
+	if err := sqlite.Anonymize(context.TODO(), db, request.BlobSize); err != nil {
+		return fmt.Errorf("error anonymizing database: %s", err)
+	}
+	r.Logger.Infof("wrote anonymized database %s", request.OutputDatabase)
+
+	return nil
+}
+
+type ChannelSelectorRequest struct {
+	InputDatabase string
+	Selector      registry.ChannelSelector
+	// Remove removes the selector of the channel instead of setting it, leaving the entries it was last expanded into
+	Remove bool
+}
+
+// UpdateChannelSelector sets or removes the selector that defines a channel, see registry.ChannelSelector
+func (r RegistryUpdater) UpdateChannelSelector(request ChannelSelectorRequest) error {
+	// opening a missing database would create it
+	if _, err := os.Stat(request.InputDatabase); err != nil {
+		return err
+	}
+	db, err := sql.Open("sqlite3", request.InputDatabase)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	dbLoader, err := sqlite.NewSQLLiteLoader(db)
+	if err != nil {
+		return err
+	}
+	if err := dbLoader.Migrate(context.TODO()); err != nil {
+		return err
+	}
+
+	selector := request.Selector
+	if request.Remove {
+		if err := dbLoader.RemoveChannelSelector(selector.PackageName, selector.ChannelName); err != nil {
+			return err
+		}
+		r.Logger.Infof("removed selector of channel %s of package %s", selector.ChannelName, selector.PackageName)
+		return nil
+	}
+	if err := dbLoader.SetChannelSelector(selector); err != nil {
+		return fmt.Errorf("error setting selector of channel %s of package %s: %s", selector.ChannelName, selector.PackageName, err)
+	}
+	r.Logger.Infof("set selector of channel %s of package %s", selector.ChannelName, selector.PackageName)
+
+	return nil
+}
+
+type ShardRegistryRequest struct {
+	InputDatabase string
+	// Shards is the number of shard databases to split the database into
+	Shards int
+	// OutputDir is the directory the shard databases are written to
+	OutputDir string
+}
+
+// ShardRegistry splits a database into shard databases by package name hash
+func (r RegistryUpdater) ShardRegistry(request ShardRegistryRequest) ([]string, error) {
+	paths, err := sqlite.SplitDatabase(context.TODO(), request.InputDatabase, request.OutputDir, request.Shards)
+	if err != nil {
+		return nil, err
+	}
+	for _, path := range paths {
+		r.Logger.Infof("wrote shard %s", path)
+	}
+	return paths, nil
+}
+
+type PurgeFromRegistryRequest struct {
+	InputDatabase string
+	// Packages limits the purge to the tombstones of these packages, all tombstones are purged if empty
+	Packages []string
+	// OlderThan only purges tombstones that are at least this old
+	OlderThan time.Duration
+}
+
+// PurgeFromRegistry permanently drops the tombstones of soft-deleted packages
+func (r RegistryUpdater) PurgeFromRegistry(request PurgeFromRegistryRequest) error {
+	db, err := sql.Open("sqlite3", request.InputDatabase)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	dbLoader, err := sqlite.NewSQLLiteLoader(db)
+	if err != nil {
+		return err
+	}
+	if err := dbLoader.Migrate(context.TODO()); err != nil {
+		return err
+	}
+
+	purged, err := dbLoader.PurgeTombstones(request.Packages, request.OlderThan)
+	if err != nil {
+		return fmt.Errorf("error purging package tombstones: %s", err)
+	}
+	if len(purged) > 0 {
+		r.Logger.Infof("purged tombstones for packages %v", purged)
+	} else {
+		r.Logger.Info("no package tombstones to purge")
+	}
+
+	return nil
+}
+
 type PruneStrandedFromRegistryRequest struct {
 	InputDatabase string
 }
@@ -205,10 +665,25 @@ func (r RegistryUpdater) PruneStrandedFromRegistry(request PruneStrandedFromRegi
 type PruneFromRegistryRequest struct {
 	Permissive    bool
 	InputDatabase string
-	Packages      []string
+	// Packages are the packages to keep. They may be left empty when HeadsOnly or InstallMode is set, to keep every
+	// package.
+	Packages []string
+	// HeadsOnly also removes every bundle that isn't the head of a channel
+	HeadsOnly bool
+	// InstallMode also removes every bundle that doesn't support this install mode, e.g. AllNamespaces, and the
+	// bundles that could only be upgraded through one
+	InstallMode string
+}
+
+// PruneFromRegistry removes the packages the request doesn't keep from its database. ctx carries the progress
+// reporter; cancelling it aborts the prune and restores the database.
+func (r RegistryUpdater) PruneFromRegistry(ctx context.Context, request PruneFromRegistryRequest) error {
+	return rollbackOnCancel(ctx, request.InputDatabase, func() error {
+		return r.pruneFromRegistry(ctx, request)
+	})
 }
 
-func (r RegistryUpdater) PruneFromRegistry(request PruneFromRegistryRequest) error {
+func (r RegistryUpdater) pruneFromRegistry(ctx context.Context, request PruneFromRegistryRequest) error {
 	db, err := sql.Open("sqlite3", request.InputDatabase)
 	if err != nil {
 		return err
@@ -219,13 +694,13 @@ func (r RegistryUpdater) PruneFromRegistry(request PruneFromRegistryRequest) err
 	if err != nil {
 		return err
 	}
-	if err := dbLoader.Migrate(context.TODO()); err != nil {
+	if err := dbLoader.Migrate(ctx); err != nil {
 		return err
 	}
 
 	// get all the packages
 	lister := sqlite.NewSQLLiteQuerierFromDb(db)
-	packages, err := lister.ListPackages(context.TODO())
+	packages, err := lister.ListPackages(ctx)
 	if err != nil {
 		return err
 	}
@@ -236,8 +711,14 @@ func (r RegistryUpdater) PruneFromRegistry(request PruneFromRegistryRequest) err
 		pkgMap[pkg] = true
 	}
 
-	// prune packages from registry
-	for _, pkg := range packages {
+	// prune packages from registry, unless every package is kept to be pruned to its heads or install mode
+	if len(request.Packages) == 0 && (request.HeadsOnly || request.InstallMode != "") {
+		packages = nil
+	}
+	for i, pkg := range packages {
+		if err := progress.Canceled(ctx); err != nil {
+			return err
+		}
 		if _, found := pkgMap[pkg]; !found {
 			remover := sqlite.NewSQLRemoverForPackages(dbLoader, pkg)
 			if err := remover.Remove(); err != nil {
@@ -249,6 +730,19 @@ func (r RegistryUpdater) PruneFromRegistry(request PruneFromRegistryRequest) err
 				logrus.WithError(err).Warn("permissive mode enabled")
 			}
 		}
+		progress.Report(ctx, "prune", pkg, i+1, len(packages))
+	}
+
+	if request.HeadsOnly {
+		if err := sqlite.NewSQLHeadsOnlyRemover(dbLoader).Remove(); err != nil {
+			return fmt.Errorf("error removing bundles that are not channel heads from database: %s", err)
+		}
+	}
+
+	if request.InstallMode != "" {
+		if err := sqlite.NewSQLInstallModeRemover(dbLoader, request.InstallMode).Remove(); err != nil {
+			return fmt.Errorf("error removing bundles that don't support install mode %s from database: %s", request.InstallMode, err)
+		}
 	}
 
 	return nil
@@ -258,6 +752,9 @@ type DeprecateFromRegistryRequest struct {
 	Permissive    bool
 	InputDatabase string
 	Bundles       []string
+	// KeepPredecessors marks the bundles before the deprecated ones in the upgrade graph deprecated too, instead of
+	// removing them
+	KeepPredecessors bool
 }
 
 func (r RegistryUpdater) DeprecateFromRegistry(request DeprecateFromRegistryRequest) error {
@@ -276,6 +773,9 @@ func (r RegistryUpdater) DeprecateFromRegistry(request DeprecateFromRegistryRequ
 	}
 
 	deprecator := sqlite.NewSQLDeprecatorForBundles(dbLoader, request.Bundles)
+	if request.KeepPredecessors {
+		deprecator = sqlite.NewSQLMarkingDeprecatorForBundles(dbLoader, request.Bundles)
+	}
 	if err := deprecator.Deprecate(); err != nil {
 		r.Logger.Debugf("unable to deprecate bundles from database: %s", err)
 		if !request.Permissive {
@@ -2,43 +2,48 @@
 package registryfakes
 
 import (
+	"context"
 	"sync"
 
 	"github.com/operator-framework/operator-registry/pkg/lib/registry"
 )
 
 type FakeRegistryAdder struct {
-	AddToRegistryStub        func(registry.AddToRegistryRequest) error
+	AddToRegistryStub        func(context.Context, registry.AddToRegistryRequest) (registry.AddToRegistryResult, error)
 	addToRegistryMutex       sync.RWMutex
 	addToRegistryArgsForCall []struct {
-		arg1 registry.AddToRegistryRequest
+		arg1 context.Context
+		arg2 registry.AddToRegistryRequest
 	}
 	addToRegistryReturns struct {
-		result1 error
+		result1 registry.AddToRegistryResult
+		result2 error
 	}
 	addToRegistryReturnsOnCall map[int]struct {
-		result1 error
+		result1 registry.AddToRegistryResult
+		result2 error
 	}
 	invocations      map[string][][]interface{}
 	invocationsMutex sync.RWMutex
 }
 
-func (fake *FakeRegistryAdder) AddToRegistry(arg1 registry.AddToRegistryRequest) error {
+func (fake *FakeRegistryAdder) AddToRegistry(arg1 context.Context, arg2 registry.AddToRegistryRequest) (registry.AddToRegistryResult, error) {
 	fake.addToRegistryMutex.Lock()
 	ret, specificReturn := fake.addToRegistryReturnsOnCall[len(fake.addToRegistryArgsForCall)]
 	fake.addToRegistryArgsForCall = append(fake.addToRegistryArgsForCall, struct {
-		arg1 registry.AddToRegistryRequest
-	}{arg1})
-	fake.recordInvocation("AddToRegistry", []interface{}{arg1})
+		arg1 context.Context
+		arg2 registry.AddToRegistryRequest
+	}{arg1, arg2})
+	fake.recordInvocation("AddToRegistry", []interface{}{arg1, arg2})
 	fake.addToRegistryMutex.Unlock()
 	if fake.AddToRegistryStub != nil {
-		return fake.AddToRegistryStub(arg1)
+		return fake.AddToRegistryStub(arg1, arg2)
 	}
 	if specificReturn {
-		return ret.result1
+		return ret.result1, ret.result2
 	}
 	fakeReturns := fake.addToRegistryReturns
-	return fakeReturns.result1
+	return fakeReturns.result1, fakeReturns.result2
 }
 
 func (fake *FakeRegistryAdder) AddToRegistryCallCount() int {
@@ -47,40 +52,43 @@ func (fake *FakeRegistryAdder) AddToRegistryCallCount() int {
 	return len(fake.addToRegistryArgsForCall)
 }
 
-func (fake *FakeRegistryAdder) AddToRegistryCalls(stub func(registry.AddToRegistryRequest) error) {
+func (fake *FakeRegistryAdder) AddToRegistryCalls(stub func(context.Context, registry.AddToRegistryRequest) (registry.AddToRegistryResult, error)) {
 	fake.addToRegistryMutex.Lock()
 	defer fake.addToRegistryMutex.Unlock()
 	fake.AddToRegistryStub = stub
 }
 
-func (fake *FakeRegistryAdder) AddToRegistryArgsForCall(i int) registry.AddToRegistryRequest {
+func (fake *FakeRegistryAdder) AddToRegistryArgsForCall(i int) (context.Context, registry.AddToRegistryRequest) {
 	fake.addToRegistryMutex.RLock()
 	defer fake.addToRegistryMutex.RUnlock()
 	argsForCall := fake.addToRegistryArgsForCall[i]
-	return argsForCall.arg1
+	return argsForCall.arg1, argsForCall.arg2
 }
 
-func (fake *FakeRegistryAdder) AddToRegistryReturns(result1 error) {
+func (fake *FakeRegistryAdder) AddToRegistryReturns(result1 registry.AddToRegistryResult, result2 error) {
 	fake.addToRegistryMutex.Lock()
 	defer fake.addToRegistryMutex.Unlock()
 	fake.AddToRegistryStub = nil
 	fake.addToRegistryReturns = struct {
-		result1 error
-	}{result1}
+		result1 registry.AddToRegistryResult
+		result2 error
+	}{result1, result2}
 }
 
-func (fake *FakeRegistryAdder) AddToRegistryReturnsOnCall(i int, result1 error) {
+func (fake *FakeRegistryAdder) AddToRegistryReturnsOnCall(i int, result1 registry.AddToRegistryResult, result2 error) {
 	fake.addToRegistryMutex.Lock()
 	defer fake.addToRegistryMutex.Unlock()
 	fake.AddToRegistryStub = nil
 	if fake.addToRegistryReturnsOnCall == nil {
 		fake.addToRegistryReturnsOnCall = make(map[int]struct {
-			result1 error
+			result1 registry.AddToRegistryResult
+			result2 error
 		})
 	}
 	fake.addToRegistryReturnsOnCall[i] = struct {
-		result1 error
-	}{result1}
+		result1 registry.AddToRegistryResult
+		result2 error
+	}{result1, result2}
 }
 
 func (fake *FakeRegistryAdder) Invocations() map[string][][]interface{} {
@@ -0,0 +1,137 @@
+package registry
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/operator-framework/operator-registry/pkg/lib/tmp"
+)
+
+const (
+	buildStateFile    = "state.json"
+	buildStateDB      = "index.db"
+	buildStateBundles = "bundles"
+)
+
+// BuildState persists the progress of a long build, the source database and every bundle unpacked so far, in a
+// directory that outlives the build. Re-running the same build with the same state picks up where it stopped
+// instead of pulling everything again.
+type BuildState struct {
+	dir string
+
+	// Database is set once the source database has been saved
+	Database bool `json:"database"`
+	// Bundles maps the bundle images that were unpacked to their directory, relative to the state directory
+	Bundles map[string]string `json:"bundles"`
+}
+
+// BuildStateDir returns the directory under the work directory that holds the state of the build identified by
+// the given values, e.g. the source index and the bundles being added
+func BuildStateDir(operation string, key ...string) string {
+	sum := sha256.Sum256([]byte(strings.Join(key, "\x00")))
+	return filepath.Join(tmp.WorkDir(), fmt.Sprintf("opm-%s-state-%x", operation, sum[:8]))
+}
+
+// OpenBuildState loads the build state in dir, or starts an empty one if there is none
+func OpenBuildState(dir string) (*BuildState, error) {
+	state := &BuildState{
+		dir:     dir,
+		Bundles: map[string]string{},
+	}
+	if err := os.MkdirAll(filepath.Join(dir, buildStateBundles), 0755); err != nil {
+		return nil, err
+	}
+
+	data, err := ioutil.ReadFile(filepath.Join(dir, buildStateFile))
+	if os.IsNotExist(err) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, fmt.Errorf("invalid build state %s, remove it to start over: %s", dir, err)
+	}
+	if state.Bundles == nil {
+		state.Bundles = map[string]string{}
+	}
+	return state, nil
+}
+
+// Dir returns the directory the state is persisted in
+func (s *BuildState) Dir() string {
+	return s.dir
+}
+
+// Unpacked returns the directory a bundle image was unpacked to, if it was
+func (s *BuildState) Unpacked(ref string) (string, bool) {
+	rel, ok := s.Bundles[ref]
+	if !ok {
+		return "", false
+	}
+	dir := filepath.Join(s.dir, rel)
+	if _, err := os.Stat(dir); err != nil {
+		return "", false
+	}
+	return dir, true
+}
+
+// BundleDir creates a directory in the state to unpack a bundle image into
+func (s *BuildState) BundleDir() (string, error) {
+	return ioutil.TempDir(filepath.Join(s.dir, buildStateBundles), "bundle-")
+}
+
+// MarkUnpacked records that a bundle image was unpacked to dir, which must be in the state
+func (s *BuildState) MarkUnpacked(ref, dir string) error {
+	rel, err := filepath.Rel(s.dir, dir)
+	if err != nil {
+		return err
+	}
+	s.Bundles[ref] = rel
+	return s.save()
+}
+
+// SavedDatabase returns the path of the source database saved in the state, if it was
+func (s *BuildState) SavedDatabase() (string, bool) {
+	if !s.Database {
+		return "", false
+	}
+	return filepath.Join(s.dir, buildStateDB), true
+}
+
+// SaveDatabase copies the source database of the build into the state
+func (s *BuildState) SaveDatabase(databasePath string) error {
+	if _, err := os.Stat(databasePath); os.IsNotExist(err) {
+		// a build from scratch has no source database to save
+		s.Database = false
+		return s.save()
+	}
+	if err := copyFile(databasePath, filepath.Join(s.dir, buildStateDB)); err != nil {
+		return err
+	}
+	s.Database = true
+	return s.save()
+}
+
+// Remove deletes the state, once the build it belongs to is done or is to be started over
+func (s *BuildState) Remove() error {
+	return os.RemoveAll(s.dir)
+}
+
+// save writes the state file through a rename so an interruption never leaves it half written
+func (s *BuildState) save() error {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	tmpFile := filepath.Join(s.dir, buildStateFile+".tmp")
+	if err := ioutil.WriteFile(tmpFile, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmpFile, filepath.Join(s.dir, buildStateFile))
+}
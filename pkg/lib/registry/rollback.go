@@ -0,0 +1,86 @@
+package registry
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/sirupsen/logrus"
+)
+
+// rollbackOnCancel runs fn against the database file and, if ctx is cancelled before fn returns,
+// puts the database back the way it was so that an interrupted operation leaves nothing half applied.
+// Contexts that can't be cancelled skip the backup entirely.
+func rollbackOnCancel(ctx context.Context, databasePath string, fn func() error) error {
+	if ctx.Done() == nil {
+		return fn()
+	}
+
+	backup, existed, err := backupDatabase(databasePath)
+	if err != nil {
+		return err
+	}
+	if backup != "" {
+		defer os.Remove(backup)
+	}
+
+	err = fn()
+	if ctx.Err() == nil {
+		return err
+	}
+
+	logrus.WithField("database", databasePath).Warn("operation cancelled, rolling back database")
+	if !existed {
+		if rerr := os.Remove(databasePath); rerr != nil && !os.IsNotExist(rerr) {
+			return rerr
+		}
+		return ctx.Err()
+	}
+	if rerr := copyFile(backup, databasePath); rerr != nil {
+		return rerr
+	}
+	return ctx.Err()
+}
+
+// backupDatabase copies the database next to itself, reporting whether there was a database to copy
+func backupDatabase(databasePath string) (string, bool, error) {
+	if _, err := os.Stat(databasePath); os.IsNotExist(err) {
+		return "", false, nil
+	} else if err != nil {
+		return "", false, err
+	}
+
+	f, err := ioutil.TempFile(filepath.Dir(databasePath), filepath.Base(databasePath)+".backup")
+	if err != nil {
+		return "", false, err
+	}
+	backup := f.Name()
+	if err := f.Close(); err != nil {
+		return "", false, err
+	}
+	if err := copyFile(databasePath, backup); err != nil {
+		os.Remove(backup)
+		return "", false, err
+	}
+	return backup, true, nil
+}
+
+func copyFile(from, to string) error {
+	in, err := os.Open(from)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(to, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0666)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,92 @@
+package registry
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/operator-framework/operator-registry/pkg/registry"
+)
+
+// BundleVerifier verifies the signature of a bundle image and returns the evidence of that verification
+type BundleVerifier interface {
+	Verify(ctx context.Context, image string) (*registry.Attestation, error)
+}
+
+// CommandVerifier is a BundleVerifier that shells out to an external verification tool
+type CommandVerifier struct {
+	command []string
+	logger  *logrus.Entry
+}
+
+// NewCommandVerifier returns a BundleVerifier that runs the given command with the
+// image reference appended as the last argument. The command must exit non-zero if
+// verification fails, and otherwise print a JSON attestation
+// ({certificateIdentity, certificateIssuer, rekorLogIndex}) to stdout.
+func NewCommandVerifier(command string, logger *logrus.Entry) *CommandVerifier {
+	return &CommandVerifier{
+		command: strings.Fields(command),
+		logger:  logger,
+	}
+}
+
+// Verify runs the verification command against the given image and parses its evidence
+func (v *CommandVerifier) Verify(ctx context.Context, image string) (*registry.Attestation, error) {
+	if len(v.command) == 0 {
+		return nil, fmt.Errorf("no verify command configured")
+	}
+
+	args := append([]string{}, v.command[1:]...)
+	args = append(args, image)
+	cmd := exec.CommandContext(ctx, v.command[0], args...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	v.logger.Infof("running %s", strings.Join(cmd.Args, " "))
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("error verifying %s: %s: %s", image, err, stderr.String())
+	}
+
+	var attestation registry.Attestation
+	if err := json.Unmarshal(stdout.Bytes(), &attestation); err != nil {
+		return nil, fmt.Errorf("error parsing verification output for %s: %s", image, err)
+	}
+	if attestation.CertificateIdentity == "" {
+		return nil, fmt.Errorf("verification output for %s is missing a certificate identity", image)
+	}
+	return &attestation, nil
+}
+
+// verifyBundles verifies every bundle image, returning the evidence keyed by image
+func verifyBundles(ctx context.Context, verifier BundleVerifier, bundles []string) (map[string]*registry.Attestation, error) {
+	attestations := make(map[string]*registry.Attestation, len(bundles))
+	for _, bundle := range bundles {
+		attestation, err := verifier.Verify(ctx, bundle)
+		if err != nil {
+			return nil, err
+		}
+		attestations[bundle] = attestation
+	}
+	return attestations, nil
+}
+
+// recordAttestations stores verification evidence for the bundles that made it into the database
+func recordAttestations(loader registry.Load, attestations map[string]*registry.Attestation, logger *logrus.Entry) error {
+	for bundle, attestation := range attestations {
+		err := loader.AddBundleAttestation(bundle, *attestation)
+		if err == registry.ErrBundleImageNotInDatabase {
+			logger.WithField("bundle", bundle).Warn("bundle not loaded, skipping attestation")
+			continue
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,153 @@
+package remotedb
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/operator-framework/operator-registry/pkg/containertools"
+	"github.com/operator-framework/operator-registry/pkg/image"
+	"github.com/operator-framework/operator-registry/pkg/image/containerdregistry"
+	"github.com/operator-framework/operator-registry/pkg/lib/artifact"
+)
+
+const (
+	// HTTPSScheme prefixes the url of a database served over https, e.g. from object storage
+	HTTPSScheme = "https://"
+	// HTTPScheme prefixes the url of a database served over plain http
+	HTTPScheme = "http://"
+	// OCIScheme prefixes the reference of a catalog artifact or an index image the database is unpacked from, e.g.
+	// oci://quay.io/example/catalog@sha256:...
+	OCIScheme = "oci://"
+)
+
+// databaseFile is the name a downloaded database is saved as
+const databaseFile = "index.db"
+
+var checksumPattern = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+// IsRemote returns true if a database source is a url or an image reference rather than a local path
+func IsRemote(source string) bool {
+	return strings.HasPrefix(source, HTTPSScheme) || strings.HasPrefix(source, HTTPScheme) || strings.HasPrefix(source, OCIScheme)
+}
+
+// Download fetches the database at a remote source into dir and returns its path. The checksum is the hex encoded
+// sha256 of the database, optionally prefixed with sha256:, and is required: the download fails unless the database
+// matches it, so that a catalog never boots from a database that was tampered with or replaced under the same name.
+func Download(ctx context.Context, logger *logrus.Entry, source, checksum, dir string) (string, error) {
+	checksum = strings.ToLower(strings.TrimPrefix(checksum, "sha256:"))
+	if checksum == "" {
+		return "", fmt.Errorf("the sha256 of the database at %s is required to verify it", source)
+	}
+	if !checksumPattern.MatchString(checksum) {
+		return "", fmt.Errorf("invalid sha256 %q", checksum)
+	}
+
+	var path string
+	var err error
+	if strings.HasPrefix(source, OCIScheme) {
+		path, err = unpack(ctx, logger, strings.TrimPrefix(source, OCIScheme), dir)
+	} else {
+		path, err = fetch(ctx, source, dir)
+	}
+	if err != nil {
+		return "", fmt.Errorf("error downloading database %s: %s", source, err)
+	}
+
+	actual, err := fileChecksum(path)
+	if err != nil {
+		return "", err
+	}
+	if actual != checksum {
+		os.Remove(path)
+		return "", fmt.Errorf("database %s has sha256 %s, expected %s", source, actual, checksum)
+	}
+	logger.WithField("sha256", actual).Info("downloaded and verified database")
+	return path, nil
+}
+
+func fetch(ctx context.Context, url, dir string) (string, error) {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := http.DefaultClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("server returned %s", resp.Status)
+	}
+
+	path := filepath.Join(dir, databaseFile)
+	f, err := os.Create(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	if _, err := io.Copy(f, resp.Body); err != nil {
+		return "", err
+	}
+	return path, f.Close()
+}
+
+// unpack pulls a catalog artifact or an index image and returns the path of the database it holds, found in the
+// database layer of an artifact or by the database location label of an index image
+func unpack(ctx context.Context, logger *logrus.Entry, ref, dir string) (string, error) {
+	reg, err := containerdregistry.NewRegistry(containerdregistry.WithLog(logger), containerdregistry.WithCacheDir(filepath.Join(dir, "cache")))
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		if err := reg.Destroy(); err != nil {
+			logger.WithError(err).Warn("error destroying local cache")
+		}
+	}()
+
+	imageRef := image.SimpleReference(ref)
+	if err := reg.Pull(ctx, imageRef); err != nil {
+		return "", err
+	}
+	path, err := artifact.Unpack(ctx, reg, imageRef, filepath.Join(dir, "artifact"))
+	if !errors.Is(err, artifact.ErrNotArtifact) {
+		return path, err
+	}
+	labels, err := reg.Labels(ctx, imageRef)
+	if err != nil {
+		return "", err
+	}
+	dbLocation, ok := labels[containertools.DbLocationLabel]
+	if !ok {
+		dbLocation = containertools.DefaultDbLocation
+	}
+
+	unpackDir := filepath.Join(dir, "image")
+	if err := reg.Unpack(ctx, imageRef, unpackDir); err != nil {
+		return "", err
+	}
+	return filepath.Join(unpackDir, dbLocation), nil
+}
+
+func fileChecksum(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
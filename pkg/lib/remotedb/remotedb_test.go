@@ -0,0 +1,57 @@
+package remotedb
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsRemote(t *testing.T) {
+	require.True(t, IsRemote("https://storage.example.com/catalog/index.db"))
+	require.True(t, IsRemote("http://localhost:8080/index.db"))
+	require.True(t, IsRemote("oci://quay.io/example/catalog:v1"))
+	require.False(t, IsRemote("bundles.db"))
+	require.False(t, IsRemote("/var/lib/catalog/index.db"))
+}
+
+func TestDownload(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/index.db" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte("catalog"))
+	}))
+	defer s.Close()
+	dir, err := ioutil.TempDir("", "remotedb-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	logger := logrus.NewEntry(logrus.New())
+
+	// sha256 of "catalog"
+	const checksum = "652f55016243bf1b9f1bbea46d5749ef892dbe394e46de9d66ab1aacf0b4af57"
+	path, err := Download(context.TODO(), logger, s.URL+"/index.db", "sha256:"+checksum, dir)
+	require.NoError(t, err)
+	content, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, "catalog", string(content))
+
+	// a database that doesn't match its checksum isn't kept
+	_, err = Download(context.TODO(), logger, s.URL+"/index.db", "0000000000000000000000000000000000000000000000000000000000000000", dir)
+	require.Error(t, err)
+	_, err = os.Stat(path)
+	require.True(t, os.IsNotExist(err))
+
+	_, err = Download(context.TODO(), logger, s.URL+"/index.db", "", dir)
+	require.Error(t, err)
+	_, err = Download(context.TODO(), logger, s.URL+"/index.db", "not-a-checksum", dir)
+	require.Error(t, err)
+	_, err = Download(context.TODO(), logger, s.URL+"/missing.db", checksum, dir)
+	require.Error(t, err)
+}
@@ -0,0 +1,86 @@
+package report
+
+import (
+	"encoding/xml"
+	"io"
+	"strings"
+)
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name     string          `xml:"name,attr"`
+	Tests    int             `xml:"tests,attr"`
+	Failures int             `xml:"failures,attr"`
+	Cases    []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Content string `xml:",chardata"`
+}
+
+// writeJUnit writes the report as JUnit XML, with a test suite per rule. A rule with no findings is a single passing
+// test case, otherwise each subject with findings is a test case that fails when its findings are errors and passes
+// with its findings as output when they are warnings.
+func (r Report) writeJUnit(w io.Writer) error {
+	suites := junitTestSuites{Name: r.Tool}
+	for _, rule := range r.Rules {
+		suite := junitTestSuite{Name: rule.ID}
+
+		findings := r.FindingsFor(rule.ID)
+		if len(findings) == 0 {
+			suite.Cases = append(suite.Cases, junitTestCase{Name: rule.Description, ClassName: rule.ID})
+		}
+
+		for start := 0; start < len(findings); {
+			end := start
+			failed := false
+			var messages []string
+			for ; end < len(findings) && findings[end].Subject == findings[start].Subject; end++ {
+				messages = append(messages, findings[end].Text())
+				failed = failed || findings[end].Level == LevelError
+			}
+
+			tc := junitTestCase{Name: findings[start].Subject, ClassName: rule.ID}
+			if failed {
+				tc.Failure = &junitFailure{Message: messages[0], Type: rule.ID, Content: strings.Join(messages, "\n")}
+				suite.Failures++
+			} else {
+				tc.SystemOut = strings.Join(messages, "\n")
+			}
+			suite.Cases = append(suite.Cases, tc)
+			start = end
+		}
+
+		suite.Tests = len(suite.Cases)
+		suites.Tests += suite.Tests
+		suites.Failures += suite.Failures
+		suites.Suites = append(suites.Suites, suite)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(suites); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
@@ -0,0 +1,198 @@
+// Package report writes the results of validation commands in the formats CI systems consume: plain text, JSON,
+// JUnit XML and SARIF.
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Level is how much a finding matters, with the same names SARIF uses
+type Level string
+
+const (
+	LevelError   Level = "error"
+	LevelWarning Level = "warning"
+)
+
+// Format is a format a report can be written in
+type Format string
+
+const (
+	FormatText  Format = "text"
+	FormatJSON  Format = "json"
+	FormatJUnit Format = "junit"
+	FormatSARIF Format = "sarif"
+)
+
+// Formats are the formats a report can be written in
+var Formats = []Format{FormatText, FormatJSON, FormatJUnit, FormatSARIF}
+
+// ParseFormat returns the Format named by s
+func ParseFormat(s string) (Format, error) {
+	for _, f := range Formats {
+		if strings.EqualFold(s, string(f)) {
+			return f, nil
+		}
+	}
+	var names []string
+	for _, f := range Formats {
+		names = append(names, string(f))
+	}
+	return "", fmt.Errorf("unknown output format %q, must be one of [%s]", s, strings.Join(names, ", "))
+}
+
+// Rule is a check that was run, whether or not it found anything
+type Rule struct {
+	ID          string `json:"id"`
+	Description string `json:"description"`
+	Level       Level  `json:"level"`
+}
+
+// Finding is something a rule found wrong with a subject, such as a bundle or a package
+type Finding struct {
+	RuleID string `json:"ruleId"`
+	Level  Level  `json:"level"`
+	// Group, if the subjects of the report are grouped, is the group the subject belongs to, e.g. its package
+	Group   string `json:"group,omitempty"`
+	Subject string `json:"subject"`
+	Message string `json:"message"`
+	// File, if known, is the path of the file the finding is about
+	File string `json:"file,omitempty"`
+	// Suggestion, if one can be made, is a change that would fix what was found
+	Suggestion string `json:"suggestion,omitempty"`
+}
+
+// Text returns the message of the finding followed by its suggested fix, for formats that have no place for the fix
+func (f Finding) Text() string {
+	if f.Suggestion == "" {
+		return f.Message
+	}
+	return fmt.Sprintf("%s (suggested fix: %s)", f.Message, f.Suggestion)
+}
+
+// Group counts the findings about the subjects of a group
+type Group struct {
+	Name     string `json:"name"`
+	Errors   int    `json:"errors"`
+	Warnings int    `json:"warnings"`
+}
+
+// Report is the outcome of running a set of rules. Findings of a rule are expected to be together and sorted by
+// subject.
+type Report struct {
+	// Tool is the name of the command that produced the report
+	Tool     string    `json:"tool"`
+	Rules    []Rule    `json:"rules"`
+	Findings []Finding `json:"findings"`
+	// GroupBy, if the subjects of the report are grouped, is what they are grouped by, e.g. package
+	GroupBy string `json:"groupBy,omitempty"`
+	// Groups summarize the findings of each group, worst first
+	Groups []Group `json:"groups,omitempty"`
+}
+
+// Failed returns true if any finding is an error
+func (r Report) Failed() bool {
+	for _, f := range r.Findings {
+		if f.Level == LevelError {
+			return true
+		}
+	}
+	return false
+}
+
+// FindingsFor returns the findings of a rule
+func (r Report) FindingsFor(ruleID string) []Finding {
+	var findings []Finding
+	for _, f := range r.Findings {
+		if f.RuleID == ruleID {
+			findings = append(findings, f)
+		}
+	}
+	return findings
+}
+
+// Write writes the report in the given format
+func (r Report) Write(w io.Writer, format Format) error {
+	switch format {
+	case FormatText:
+		return r.writeText(w)
+	case FormatJSON:
+		return writeJSON(w, struct {
+			Report
+			Failed bool `json:"failed"`
+		}{r, r.Failed()})
+	case FormatJUnit:
+		return r.writeJUnit(w)
+	case FormatSARIF:
+		return r.writeSARIF(w)
+	}
+	return fmt.Errorf("unknown output format %q", format)
+}
+
+// writeText writes a line per rule saying whether it passed, followed by its findings, and then the groups that have
+// findings
+func (r Report) writeText(w io.Writer) error {
+	for _, rule := range r.Rules {
+		findings := r.FindingsFor(rule.ID)
+		if len(findings) == 0 {
+			if _, err := fmt.Fprintf(w, "PASS %s\n", rule.ID); err != nil {
+				return err
+			}
+			continue
+		}
+
+		status := "FAIL"
+		if rule.Level == LevelWarning {
+			status = "WARN"
+		}
+		if _, err := fmt.Fprintf(w, "%s %s: %d findings\n", status, rule.ID, len(findings)); err != nil {
+			return err
+		}
+		for _, f := range findings {
+			if _, err := fmt.Fprintf(w, "  %s: %s\n", f.Subject, f.Message); err != nil {
+				return err
+			}
+			if f.Suggestion == "" {
+				continue
+			}
+			if _, err := fmt.Fprintf(w, "    suggested fix: %s\n", f.Suggestion); err != nil {
+				return err
+			}
+		}
+	}
+	return r.writeGroups(w)
+}
+
+func (r Report) writeGroups(w io.Writer) error {
+	if len(r.Groups) == 0 {
+		return nil
+	}
+	var found []Group
+	for _, g := range r.Groups {
+		if g.Errors+g.Warnings > 0 {
+			found = append(found, g)
+		}
+	}
+	groupBy := r.GroupBy
+	if groupBy == "" {
+		groupBy = "group"
+	}
+	if _, err := fmt.Fprintf(w, "SUMMARY %d of %d %ss have findings\n", len(found), len(r.Groups), groupBy); err != nil {
+		return err
+	}
+	for _, g := range found {
+		if _, err := fmt.Fprintf(w, "  %s: %d errors, %d warnings\n", g.Name, g.Errors, g.Warnings); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeJSON(w io.Writer, v interface{}) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
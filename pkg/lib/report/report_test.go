@@ -0,0 +1,169 @@
+package report
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func testReport() Report {
+	return Report{
+		Tool: "opm test",
+		Rules: []Rule{
+			{ID: "graph", Description: "graphs are sound", Level: LevelError},
+			{ID: "schema", Description: "manifests are valid", Level: LevelError},
+			{ID: "digests", Description: "images are pinned", Level: LevelWarning},
+		},
+		Findings: []Finding{
+			{RuleID: "schema", Level: LevelError, Subject: "etcdoperator.v0.9.0", Message: "missing CRD"},
+			{RuleID: "schema", Level: LevelError, Subject: "etcdoperator.v0.9.0", Message: "invalid icon"},
+			{RuleID: "schema", Level: LevelError, Subject: "etcdoperator.v0.9.2", Message: "missing CRD", File: "manifests/csv.yaml"},
+			{RuleID: "digests", Level: LevelWarning, Subject: "etcdoperator.v0.9.2", Message: "image quay.io/coreos/etcd:v3 is not pinned"},
+		},
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	for _, name := range []string{"text", "json", "JUnit", "sarif"} {
+		_, err := ParseFormat(name)
+		require.NoError(t, err)
+	}
+	_, err := ParseFormat("xml")
+	require.Error(t, err)
+}
+
+func TestWriteText(t *testing.T) {
+	var out bytes.Buffer
+	require.NoError(t, testReport().Write(&out, FormatText))
+	require.Equal(t, `PASS graph
+FAIL schema: 3 findings
+  etcdoperator.v0.9.0: missing CRD
+  etcdoperator.v0.9.0: invalid icon
+  etcdoperator.v0.9.2: missing CRD
+WARN digests: 1 findings
+  etcdoperator.v0.9.2: image quay.io/coreos/etcd:v3 is not pinned
+`, out.String())
+}
+
+func TestWriteJSON(t *testing.T) {
+	var out bytes.Buffer
+	require.NoError(t, testReport().Write(&out, FormatJSON))
+
+	var decoded struct {
+		Report
+		Failed bool `json:"failed"`
+	}
+	require.NoError(t, json.Unmarshal(out.Bytes(), &decoded))
+	require.True(t, decoded.Failed)
+	require.Equal(t, testReport(), decoded.Report)
+}
+
+func TestWriteJUnit(t *testing.T) {
+	var out bytes.Buffer
+	require.NoError(t, testReport().Write(&out, FormatJUnit))
+
+	var suites junitTestSuites
+	require.NoError(t, xml.Unmarshal(out.Bytes(), &suites))
+	require.Equal(t, "opm test", suites.Name)
+	require.Equal(t, 4, suites.Tests)
+	require.Equal(t, 2, suites.Failures)
+	require.Len(t, suites.Suites, 3)
+
+	// a rule without findings passes
+	require.Equal(t, []junitTestCase{{Name: "graphs are sound", ClassName: "graph"}}, suites.Suites[0].Cases)
+
+	// findings are grouped into a test case per subject
+	schema := suites.Suites[1]
+	require.Equal(t, 2, schema.Failures)
+	require.Equal(t, "etcdoperator.v0.9.0", schema.Cases[0].Name)
+	require.Equal(t, "missing CRD\ninvalid icon", schema.Cases[0].Failure.Content)
+
+	// warnings pass, with the findings as output
+	digests := suites.Suites[2]
+	require.Equal(t, 0, digests.Failures)
+	require.Nil(t, digests.Cases[0].Failure)
+	require.Equal(t, "image quay.io/coreos/etcd:v3 is not pinned", digests.Cases[0].SystemOut)
+}
+
+func TestWriteSARIF(t *testing.T) {
+	var out bytes.Buffer
+	require.NoError(t, testReport().Write(&out, FormatSARIF))
+
+	var log sarifLog
+	require.NoError(t, json.Unmarshal(out.Bytes(), &log))
+	require.Equal(t, "2.1.0", log.Version)
+	require.Len(t, log.Runs, 1)
+
+	run := log.Runs[0]
+	require.Equal(t, "opm test", run.Tool.Driver.Name)
+	require.Len(t, run.Tool.Driver.Rules, 3)
+	require.Equal(t, LevelWarning, run.Tool.Driver.Rules[2].DefaultConfiguration.Level)
+
+	require.Len(t, run.Results, 4)
+	require.Equal(t, sarifResult{
+		RuleID:    "schema",
+		RuleIndex: 1,
+		Level:     LevelError,
+		Message:   sarifMessage{Text: "missing CRD"},
+		Locations: []sarifLocation{{
+			PhysicalLocation: &sarifPhysicalLocation{ArtifactLocation: sarifArtifactLocation{URI: "manifests/csv.yaml"}},
+			LogicalLocations: []sarifLogicalLocation{{FullyQualifiedName: "etcdoperator.v0.9.2"}},
+		}},
+	}, run.Results[2])
+	require.Equal(t, LevelWarning, run.Results[3].Level)
+}
+
+func TestWriteGroups(t *testing.T) {
+	r := testReport()
+	r.GroupBy = "package"
+	r.Groups = []Group{{Name: "etcd", Errors: 3, Warnings: 1}, {Name: "prometheus"}}
+	for i := range r.Findings {
+		r.Findings[i].Group = "etcd"
+	}
+
+	var text bytes.Buffer
+	require.NoError(t, r.Write(&text, FormatText))
+	require.Equal(t, `PASS graph
+FAIL schema: 3 findings
+  etcdoperator.v0.9.0: missing CRD
+  etcdoperator.v0.9.0: invalid icon
+  etcdoperator.v0.9.2: missing CRD
+WARN digests: 1 findings
+  etcdoperator.v0.9.2: image quay.io/coreos/etcd:v3 is not pinned
+SUMMARY 1 of 2 packages have findings
+  etcd: 3 errors, 1 warnings
+`, text.String())
+
+	var out bytes.Buffer
+	require.NoError(t, r.Write(&out, FormatJSON))
+	var decoded Report
+	require.NoError(t, json.Unmarshal(out.Bytes(), &decoded))
+	require.Equal(t, r, decoded)
+}
+
+func TestWriteSuggestions(t *testing.T) {
+	r := Report{
+		Tool:  "opm test",
+		Rules: []Rule{{ID: "graph", Description: "graphs are sound", Level: LevelError}},
+		Findings: []Finding{
+			{RuleID: "graph", Level: LevelError, Subject: "etcd/alpha", Message: "etcdoperator.v0.9.2 replaces etcdoperator.v0.9.1, which is not in the channel", Suggestion: "set the replaces of etcdoperator.v0.9.2 to etcdoperator.v0.9.0"},
+		},
+	}
+
+	var text bytes.Buffer
+	require.NoError(t, r.Write(&text, FormatText))
+	require.Equal(t, `FAIL graph: 1 findings
+  etcd/alpha: etcdoperator.v0.9.2 replaces etcdoperator.v0.9.1, which is not in the channel
+    suggested fix: set the replaces of etcdoperator.v0.9.2 to etcdoperator.v0.9.0
+`, text.String())
+
+	// formats without a place for the fix append it to the message
+	var sarif bytes.Buffer
+	require.NoError(t, r.Write(&sarif, FormatSARIF))
+	var log sarifLog
+	require.NoError(t, json.Unmarshal(sarif.Bytes(), &log))
+	require.Equal(t, "etcdoperator.v0.9.2 replaces etcdoperator.v0.9.1, which is not in the channel (suggested fix: set the replaces of etcdoperator.v0.9.2 to etcdoperator.v0.9.0)", log.Runs[0].Results[0].Message.Text)
+}
@@ -0,0 +1,183 @@
+// Package serve sets up what opm registry serve and registry-server share to serve a registry database over grpc:
+// the copy of each database that is served, and the options of the grpc server, both read from flags that AddFlags
+// adds to either command.
+package serve
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/pflag"
+
+	"github.com/operator-framework/operator-registry/pkg/registry"
+	"github.com/operator-framework/operator-registry/pkg/sqlite"
+)
+
+// DatabaseOptions control how a copy of a database is prepared to be served
+type DatabaseOptions struct {
+	// SkipMigrate serves a database with the schema it was written with rather than migrating it to the latest one
+	SkipMigrate bool
+	// OverlayPath is the path of an overlay applied to every copy served, empty if there is none. It is read again for
+	// every copy, so that an overlay edited between refreshes of a database is applied.
+	OverlayPath string
+	// Key decrypts an encrypted database, nil if none was given
+	Key *sqlite.DatabaseKey
+}
+
+// DatabaseOptionsFromFlags returns the database options set with the flags AddFlags adds
+func DatabaseOptionsFromFlags(fs *pflag.FlagSet) (DatabaseOptions, error) {
+	var opts DatabaseOptions
+	var err error
+	if opts.SkipMigrate, err = fs.GetBool("skip-migrate"); err != nil {
+		return opts, err
+	}
+	if opts.OverlayPath, err = fs.GetString("overlay"); err != nil {
+		return opts, err
+	}
+	keyFile, err := fs.GetString("database-key-file")
+	if err != nil {
+		return opts, err
+	}
+	opts.Key, err = sqlite.LoadDatabaseKey(keyFile)
+	return opts, err
+}
+
+// Open prepares a writable copy of a database, or of one shard out of shards, to be served and returns the querier
+// for it along with the schema features it supports natively, nil if those couldn't be checked. The returned func
+// closes the querier and the copy.
+func (o DatabaseOptions) Open(ctx context.Context, logger *logrus.Entry, dbName string, shard, shards int) (*sqlite.SQLQuerier, *sqlite.FeatureSet, func(), error) {
+	// make a writable copy of the db for migrations, decrypting it into memory if it is encrypted
+	db, closeDB, err := sqlite.OpenWritableCopy(ctx, dbName, o.Key)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	features, err := o.Prepare(ctx, logger, db, shard, shards)
+	if err != nil {
+		closeDB()
+		return nil, nil, nil, err
+	}
+
+	store := sqlite.NewSQLLiteQuerierFromDbWithStmtCache(db)
+	cleanup := func() {
+		store.Close()
+		closeDB()
+	}
+
+	// sanity check that the db is available
+	tables, err := store.ListTables(ctx)
+	if err != nil {
+		logger.WithError(err).Warnf("couldn't list tables in db")
+	}
+	if len(tables) == 0 {
+		logger.Warn("no tables found in db")
+	}
+
+	return store, features, cleanup, nil
+}
+
+// Prepare migrates a writable copy of a database, or of one shard out of shards, fills in what an older schema is
+// missing and applies the overlay, returning the schema features it supports natively, nil if those couldn't be
+// checked
+func (o DatabaseOptions) Prepare(ctx context.Context, logger *logrus.Entry, db *sql.DB, shard, shards int) (*sqlite.FeatureSet, error) {
+	// migrate to the latest version
+	if err := o.migrate(ctx, db); err != nil {
+		logger.WithError(err).Warnf("couldn't migrate db")
+	}
+
+	// fill in anything missing from an older schema so it can still be served
+	features, err := sqlite.EnsureCompatibleSchema(ctx, db)
+	if err != nil {
+		logger.WithError(err).Warnf("couldn't check db schema compatibility")
+		features = nil
+	} else if len(features.Defaulted) > 0 {
+		logger.Warnf("db schema is missing features, serving defaults for: %s", strings.Join(features.Defaulted, ","))
+	}
+
+	// channels defined by selectors are served with the bundles they select now; if they can't be expanded, the
+	// entries they were last expanded into are still a valid catalog
+	if err := sqlite.ExpandChannelSelectors(ctx, db); err != nil {
+		logger.WithError(err).Warnf("couldn't expand channel selectors")
+	}
+
+	// adjust the served catalog without touching the original database
+	if err := o.applyOverlay(ctx, db, shard, shards); err != nil {
+		return nil, err
+	}
+	return features, nil
+}
+
+// Build writes a copy of a database, prepared to be served, to path. The database may be being rewritten while it is
+// copied, so it is copied with SQLite rather than as a file.
+func (o DatabaseOptions) Build(ctx context.Context, logger *logrus.Entry, dbName, path string) (*sqlite.FeatureSet, error) {
+	if encrypted, err := sqlite.IsEncrypted(dbName); err != nil {
+		return nil, err
+	} else if encrypted {
+		return nil, fmt.Errorf("%s: %s", dbName, sqlite.ErrDatabaseEncrypted)
+	}
+	if err := sqlite.SafeCopyFile(ctx, dbName, path); err != nil {
+		return nil, err
+	}
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+	return o.Prepare(ctx, logger, db, 0, 1)
+}
+
+// Load loads a copy of a database, prepared to be served, into memory, decrypting it if it is encrypted. The database
+// is copied with SQLite, like in Build, and an encrypted database, which opm registry encrypt replaces whole, is
+// decrypted as it is.
+func (o DatabaseOptions) Load(ctx context.Context, logger *logrus.Entry, dbName string) (*sqlite.MemoryDatabase, *sqlite.FeatureSet, error) {
+	m, err := sqlite.LoadMemoryDatabaseWithKey(ctx, dbName, o.Key)
+	if err != nil {
+		return nil, nil, err
+	}
+	features, err := o.Prepare(ctx, logger, m.DB(), 0, 1)
+	if err != nil {
+		m.Close()
+		return nil, nil, err
+	}
+	return m, features, nil
+}
+
+func (o DatabaseOptions) migrate(ctx context.Context, db *sql.DB) error {
+	if o.SkipMigrate {
+		return nil
+	}
+
+	migrator, err := sqlite.NewSQLLiteMigrator(db)
+	if err != nil {
+		return err
+	}
+	if migrator == nil {
+		return fmt.Errorf("failed to load migrator")
+	}
+
+	return migrator.Migrate(ctx)
+}
+
+func (o DatabaseOptions) applyOverlay(ctx context.Context, db *sql.DB, shard, shards int) error {
+	if o.OverlayPath == "" {
+		return nil
+	}
+
+	overlay, err := sqlite.LoadOverlay(o.OverlayPath)
+	if err != nil {
+		return err
+	}
+
+	// each shard only holds, and so only gets the overlay of, its own packages
+	packages := overlay.Packages[:0]
+	for _, pkg := range overlay.Packages {
+		if registry.ShardForPackage(pkg.Name, shards) == shard {
+			packages = append(packages, pkg)
+		}
+	}
+	overlay.Packages = packages
+	return sqlite.ApplyOverlay(ctx, db, overlay)
+}
@@ -0,0 +1,68 @@
+package serve
+
+import (
+	"context"
+	"database/sql"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+
+	"github.com/operator-framework/operator-registry/pkg/registry"
+	"github.com/operator-framework/operator-registry/pkg/sqlite"
+)
+
+const testOverlay = `
+packages:
+- name: etcd
+  defaultChannel: beta
+`
+
+func TestOpenAppliesOverlayOfShard(t *testing.T) {
+	dir, err := ioutil.TempDir("", "serve-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	dbName := filepath.Join(dir, "index.db")
+	db, err := sql.Open("sqlite3", dbName)
+	require.NoError(t, err)
+	load, err := sqlite.NewSQLLiteLoader(db)
+	require.NoError(t, err)
+	require.NoError(t, load.Migrate(context.TODO()))
+	require.NoError(t, sqlite.NewSQLLoaderForDirectory(load, "../../../manifests").Populate())
+	require.NoError(t, db.Close())
+
+	overlay := filepath.Join(dir, "overlay.yaml")
+	require.NoError(t, ioutil.WriteFile(overlay, []byte(testOverlay), 0644))
+
+	opts, err := DatabaseOptionsFromFlags(flags(t, "--overlay", overlay))
+	require.NoError(t, err)
+	require.Equal(t, DatabaseOptions{OverlayPath: overlay}, opts)
+
+	defaultChannel := func(shard, shards int) string {
+		store, features, cleanup, err := opts.Open(context.TODO(), logrus.NewEntry(logrus.New()), dbName, shard, shards)
+		require.NoError(t, err)
+		defer cleanup()
+		require.NotNil(t, features)
+		pkg, err := store.GetPackage(context.TODO(), "etcd")
+		require.NoError(t, err)
+		return pkg.DefaultChannelName
+	}
+
+	// the copy served gets the overlay, the database itself doesn't
+	require.Equal(t, "beta", defaultChannel(0, 1))
+	querier, err := sqlite.NewSQLLiteQuerier(dbName)
+	require.NoError(t, err)
+	defer querier.Close()
+	original, err := querier.GetPackage(context.TODO(), "etcd")
+	require.NoError(t, err)
+	require.Equal(t, "alpha", original.DefaultChannelName)
+
+	// a shard only gets the overlay of its own packages
+	shard := registry.ShardForPackage("etcd", 2)
+	require.Equal(t, "beta", defaultChannel(shard, 2))
+	require.Equal(t, "alpha", defaultChannel(1-shard, 2))
+}
@@ -0,0 +1,203 @@
+package serve
+
+import (
+	"crypto/tls"
+	"fmt"
+	"os"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/pflag"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+
+	"github.com/operator-framework/operator-registry/pkg/lib/querylog"
+	"github.com/operator-framework/operator-registry/pkg/registry"
+	"github.com/operator-framework/operator-registry/pkg/server"
+)
+
+// AddFlags adds the flags of the options every command that serves a registry database takes to fs. The CA that
+// client certificates are verified against isn't among them, since commands verify them for different reasons; see
+// TLSOptions.
+func AddFlags(fs *pflag.FlagSet) {
+	fs.Bool("skip-migrate", false, "do  not attempt to migrate to the latest db revision when starting")
+	fs.String("overlay", "", "path to an overlay file (yaml or json) applied to the served catalog to change default channels, drop channels or deprecate bundles")
+	fs.String("database-key-file", "", "path to the key an encrypted --database is decrypted with, written by opm registry encrypt; defaults to the key in $OPM_DATABASE_KEY")
+	fs.String("redaction", "", "path to a redaction config (yaml or json) of CSV content to remove from the bundles served, e.g. alm-examples, maintainer emails or embedded certificates")
+	fs.Bool("log-requests", false, "log every request with its method, status, duration and a request id, which is taken from the x-request-id metadata of the request if set, and sent back to the client")
+	fs.Bool("trace-propagation", false, "log requests that carry a W3C traceparent, as sent by clients instrumented with OpenTelemetry, with their trace id and a span id for the server, and send the trace context of that span back in the response headers; implies --log-requests")
+	fs.String("query-log", "", "path to a file that every registry query is appended to, for replaying with opm registry replay")
+	fs.Int("query-cache-size", 0, "cache up to this many results of the package and bundle lookups clients repeat most, e.g. GetPackage and GetBundleForChannel, in memory in front of each database; 0 disables the cache. Cached results are dropped whenever a database served is reloaded")
+	fs.Duration("query-cache-ttl", 0, "how long a result is served from the query cache before it is looked up again; 0 keeps it until it is evicted or its database is reloaded")
+	fs.Duration("keepalive-min-time", 0, "shortest interval at which clients may send keepalive pings, clients that ping more often are disconnected (default 5m)")
+	fs.Bool("keepalive-permit-without-stream", false, "allow clients to send keepalive pings while they have no calls in flight")
+	fs.Duration("keepalive-time", 0, "how long a connection may go without activity before the server pings the client (default 2h)")
+	fs.Duration("keepalive-timeout", 0, "how long the server waits for the answer to a keepalive ping before closing the connection (default 20s)")
+	fs.Duration("max-connection-idle", 0, "close connections that have had no calls in flight for this long, e.g. to close them before a load balancer silently drops them (default infinite)")
+	fs.Duration("max-connection-age", 0, "close connections that have been open for this long, so that clients reconnect and are spread over new replicas (default infinite)")
+	fs.Duration("max-connection-age-grace", 0, "how long calls in flight are given to finish once a connection reaches its max age (default infinite)")
+	fs.Float64("rate-limit", server.DefaultRateLimit, fmt.Sprintf("requests per second each client host may make on average, beyond which requests fail with ResourceExhausted; 0, the default, disables it. %v with a burst of %d suits catalogs whose clients are spread over many hosts. Health checks aren't limited", server.RecommendedRateLimit, server.RecommendedRateLimitBurst))
+	fs.Int("rate-limit-burst", server.DefaultRateLimitBurst, "requests each client host may make at once on top of --rate-limit, --rate-limit rounded up if 0")
+	fs.Int("max-concurrent-requests", server.DefaultMaxConcurrentRequests, "requests, streams included, each client host may have in flight at once, beyond which requests fail with ResourceExhausted; 0, the default, disables it")
+	fs.Uint32("max-concurrent-streams", server.DefaultMaxConcurrentStreams, "calls a single connection may have open at once, beyond which calls wait for earlier ones to finish; 0 leaves it unlimited")
+	fs.String("tls-cert", "", "path to the serving certificate of the grpc api, clients must connect with tls when it is set; it is reloaded when it and its key are rotated")
+	fs.String("tls-key", "", "path to the key of the serving certificate of the grpc api")
+}
+
+// TLSOptions returns the server options that serve the grpc api over tls, if a serving certificate was given. Client
+// certificates are verified against the CA given with the flag named caFlag, which commands name and register
+// themselves, as clientAuth asks.
+func TLSOptions(fs *pflag.FlagSet, logger *logrus.Entry, caFlag string, clientAuth tls.ClientAuthType) ([]grpc.ServerOption, error) {
+	certFile, err := fs.GetString("tls-cert")
+	if err != nil {
+		return nil, err
+	}
+	keyFile, err := fs.GetString("tls-key")
+	if err != nil {
+		return nil, err
+	}
+	caFile, err := fs.GetString(caFlag)
+	if err != nil {
+		return nil, err
+	}
+	if certFile == "" && keyFile == "" {
+		if caFile != "" {
+			return nil, fmt.Errorf("--%s needs a serving certificate, set with --tls-cert and --tls-key", caFlag)
+		}
+		return nil, nil
+	}
+	if certFile == "" || keyFile == "" {
+		return nil, fmt.Errorf("--tls-cert and --tls-key must be set together")
+	}
+
+	reloader, err := server.NewTLSReloader(logger, certFile, keyFile, caFile, clientAuth)
+	if err != nil {
+		return nil, err
+	}
+	return []grpc.ServerOption{grpc.Creds(credentials.NewTLS(reloader.Config("h2")))}, nil
+}
+
+// RedactionOptions returns the registry server options that remove content from the CSVs of served bundles, if a
+// redaction config was given
+func RedactionOptions(fs *pflag.FlagSet) ([]server.RegistryServerOption, error) {
+	path, err := fs.GetString("redaction")
+	if err != nil || path == "" {
+		return nil, err
+	}
+	redaction, err := server.LoadRedaction(path)
+	if err != nil {
+		return nil, err
+	}
+	return []server.RegistryServerOption{server.WithRedaction(redaction)}, nil
+}
+
+// RequestLogOptions returns the server options that log every request, and propagate trace context, as requested
+func RequestLogOptions(fs *pflag.FlagSet, logger *logrus.Entry) ([]grpc.ServerOption, error) {
+	logRequests, err := fs.GetBool("log-requests")
+	if err != nil {
+		return nil, err
+	}
+	trace, err := fs.GetBool("trace-propagation")
+	if err != nil {
+		return nil, err
+	}
+	if !logRequests && !trace {
+		return nil, nil
+	}
+	return server.NewRequestLog(logger, trace).ServerOptions(), nil
+}
+
+// QueryLogOptions returns the server options that record queries to the query log, if one was requested, and the func
+// that closes it
+func QueryLogOptions(fs *pflag.FlagSet) ([]grpc.ServerOption, func(), error) {
+	queryLogPath, err := fs.GetString("query-log")
+	if err != nil {
+		return nil, nil, err
+	}
+	if queryLogPath == "" {
+		return nil, func() {}, nil
+	}
+
+	f, err := os.OpenFile(queryLogPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, nil, err
+	}
+	return querylog.NewRecorder(f).ServerOptions(), func() { f.Close() }, nil
+}
+
+// QueryCacheOptions returns the options of the query cache put in front of each database, or nil if it is disabled
+func QueryCacheOptions(fs *pflag.FlagSet) (*registry.QueryCacheOptions, error) {
+	var opts registry.QueryCacheOptions
+	var err error
+	if opts.Size, err = fs.GetInt("query-cache-size"); err != nil {
+		return nil, err
+	}
+	if opts.TTL, err = fs.GetDuration("query-cache-ttl"); err != nil {
+		return nil, err
+	}
+	if opts.Size == 0 {
+		return nil, nil
+	}
+	return &opts, nil
+}
+
+// CacheQuery puts a query cache in front of query if one was requested, and returns the func that drops its results
+func CacheQuery(query registry.Query, opts *registry.QueryCacheOptions) (registry.Query, func(), error) {
+	if opts == nil {
+		return query, func() {}, nil
+	}
+	cached, err := registry.NewCachedQuery(query, *opts)
+	if err != nil {
+		return nil, nil, err
+	}
+	return cached, cached.Invalidate, nil
+}
+
+// KeepaliveOptions returns the server options that keep connections alive and close old or idle ones as requested
+func KeepaliveOptions(fs *pflag.FlagSet) ([]grpc.ServerOption, error) {
+	var opts server.KeepaliveOptions
+	var err error
+	if opts.MinTime, err = fs.GetDuration("keepalive-min-time"); err != nil {
+		return nil, err
+	}
+	if opts.PermitWithoutStream, err = fs.GetBool("keepalive-permit-without-stream"); err != nil {
+		return nil, err
+	}
+	if opts.Time, err = fs.GetDuration("keepalive-time"); err != nil {
+		return nil, err
+	}
+	if opts.Timeout, err = fs.GetDuration("keepalive-timeout"); err != nil {
+		return nil, err
+	}
+	if opts.MaxConnectionIdle, err = fs.GetDuration("max-connection-idle"); err != nil {
+		return nil, err
+	}
+	if opts.MaxConnectionAge, err = fs.GetDuration("max-connection-age"); err != nil {
+		return nil, err
+	}
+	if opts.MaxConnectionAgeGrace, err = fs.GetDuration("max-connection-age-grace"); err != nil {
+		return nil, err
+	}
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+	return opts.ServerOptions(), nil
+}
+
+// RateLimitOptions returns the limits on the requests of each client that were asked for
+func RateLimitOptions(fs *pflag.FlagSet) (server.RateLimitOptions, error) {
+	var opts server.RateLimitOptions
+	var err error
+	if opts.RequestsPerSecond, err = fs.GetFloat64("rate-limit"); err != nil {
+		return opts, err
+	}
+	if opts.Burst, err = fs.GetInt("rate-limit-burst"); err != nil {
+		return opts, err
+	}
+	if opts.MaxConcurrentRequests, err = fs.GetInt("max-concurrent-requests"); err != nil {
+		return opts, err
+	}
+	if opts.MaxConcurrentStreams, err = fs.GetUint32("max-concurrent-streams"); err != nil {
+		return opts, err
+	}
+	return opts, opts.Validate()
+}
@@ -0,0 +1,34 @@
+package serve
+
+import (
+	"crypto/tls"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/require"
+)
+
+// flags returns the flags of a command that serves a registry, with the client CA flag of opm registry serve, parsed
+// from args
+func flags(t *testing.T, args ...string) *pflag.FlagSet {
+	fs := pflag.NewFlagSet("serve", pflag.ContinueOnError)
+	AddFlags(fs)
+	fs.String("client-ca", "", "")
+	require.NoError(t, fs.Parse(args))
+	return fs
+}
+
+func TestTLSOptions(t *testing.T) {
+	logger := logrus.NewEntry(logrus.New())
+
+	opts, err := TLSOptions(flags(t), logger, "client-ca", tls.VerifyClientCertIfGiven)
+	require.NoError(t, err)
+	require.Empty(t, opts)
+
+	// errors name the flags of the command
+	_, err = TLSOptions(flags(t, "--client-ca", "ca.crt"), logger, "client-ca", tls.VerifyClientCertIfGiven)
+	require.EqualError(t, err, "--client-ca needs a serving certificate, set with --tls-cert and --tls-key")
+	_, err = TLSOptions(flags(t, "--tls-cert", "tls.crt"), logger, "client-ca", tls.VerifyClientCertIfGiven)
+	require.EqualError(t, err, "--tls-cert and --tls-key must be set together")
+}
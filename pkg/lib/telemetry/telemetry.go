@@ -0,0 +1,133 @@
+package telemetry
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	// register the sqlite driver catalog sizes are read with
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// EndpointEnv is the environment variable that opts in to telemetry. Nothing is recorded or sent unless it is set to
+// the url events are posted to.
+const EndpointEnv = "OPM_TELEMETRY_ENDPOINT"
+
+// sendTimeout bounds how long a command may be held up by sending its event
+const sendTimeout = 2 * time.Second
+
+// Event records a single run of an opm command. It is anonymous by construction: it holds the names of the flags that
+// were set but never their values or the arguments of the command, so image references, paths and hostnames are not
+// recorded, and catalog sizes are rounded up to a power of two.
+type Event struct {
+	// Command is the path of the command that was run, e.g. "opm index add"
+	Command string `json:"command"`
+	// Flags are the names of the flags that were set, sorted
+	Flags           []string     `json:"flags,omitempty"`
+	Success         bool         `json:"success"`
+	DurationSeconds float64      `json:"durationSeconds"`
+	OpmVersion      string       `json:"opmVersion"`
+	GoOs            string       `json:"goOs"`
+	GoArch          string       `json:"goArch"`
+	Catalog         *CatalogSize `json:"catalog,omitempty"`
+}
+
+// CatalogSize is the rounded size of the catalog database a command ran against
+type CatalogSize struct {
+	Packages int `json:"packages"`
+	Bundles  int `json:"bundles"`
+}
+
+// Recorder posts events to a telemetry endpoint. A nil Recorder, which is what FromEnv returns unless telemetry was
+// opted in to, records nothing.
+type Recorder struct {
+	endpoint string
+	client   *http.Client
+}
+
+// NewRecorder returns a recorder that posts events as JSON to endpoint
+func NewRecorder(endpoint string) *Recorder {
+	return &Recorder{endpoint: endpoint, client: &http.Client{Timeout: sendTimeout}}
+}
+
+// FromEnv returns a recorder for the endpoint in EndpointEnv, or nil if it isn't set
+func FromEnv() *Recorder {
+	endpoint := os.Getenv(EndpointEnv)
+	if endpoint == "" {
+		return nil
+	}
+	return NewRecorder(endpoint)
+}
+
+// Enabled returns true if events are sent
+func (r *Recorder) Enabled() bool {
+	return r != nil
+}
+
+// Record posts an event to the endpoint. Telemetry must never get in the way of a command, so callers are expected
+// to ignore the error beyond logging it.
+func (r *Recorder) Record(ctx context.Context, event Event) error {
+	if r == nil {
+		return nil
+	}
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, sendTimeout)
+	defer cancel()
+	req, err := http.NewRequest(http.MethodPost, r.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := r.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("telemetry endpoint returned %s", resp.Status)
+	}
+	return nil
+}
+
+// CatalogSizeOf returns the rounded number of packages and bundles in the catalog database at path
+func CatalogSizeOf(ctx context.Context, path string) (*CatalogSize, error) {
+	if _, err := os.Stat(path); err != nil {
+		return nil, err
+	}
+	db, err := sql.Open("sqlite3", "file:"+path+"?mode=ro")
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	var packages, bundles int
+	if err := db.QueryRowContext(ctx, `SELECT COUNT(*) FROM package`).Scan(&packages); err != nil {
+		return nil, err
+	}
+	if err := db.QueryRowContext(ctx, `SELECT COUNT(*) FROM operatorbundle`).Scan(&bundles); err != nil {
+		return nil, err
+	}
+	return &CatalogSize{Packages: roundUp(packages), Bundles: roundUp(bundles)}, nil
+}
+
+// roundUp rounds n up to a power of two, so that sizes tell the scale of a catalog without identifying it
+func roundUp(n int) int {
+	if n <= 0 {
+		return 0
+	}
+	p := 1
+	for p < n {
+		p *= 2
+	}
+	return p
+}
@@ -0,0 +1,75 @@
+package telemetry
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecord(t *testing.T) {
+	events := make(chan Event, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var e Event
+		if err := json.NewDecoder(r.Body).Decode(&e); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		events <- e
+	}))
+	defer server.Close()
+
+	event := Event{Command: "opm index add", Flags: []string{"bundles", "tag"}, Success: true, OpmVersion: "v1.2.3", Catalog: &CatalogSize{Packages: 4, Bundles: 16}}
+	require.NoError(t, NewRecorder(server.URL).Record(context.TODO(), event))
+	require.Equal(t, event, <-events)
+
+	require.Error(t, NewRecorder(server.URL+"/missing\x00").Record(context.TODO(), event))
+}
+
+func TestFromEnv(t *testing.T) {
+	defer os.Setenv(EndpointEnv, os.Getenv(EndpointEnv))
+
+	// telemetry is off unless opted in to
+	os.Unsetenv(EndpointEnv)
+	recorder := FromEnv()
+	require.False(t, recorder.Enabled())
+	require.NoError(t, recorder.Record(context.TODO(), Event{Command: "opm version"}))
+
+	os.Setenv(EndpointEnv, "https://telemetry.example.com/opm")
+	require.True(t, FromEnv().Enabled())
+}
+
+func TestCatalogSizeOf(t *testing.T) {
+	dir, err := ioutil.TempDir("", "telemetry-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "index.db")
+	db, err := sql.Open("sqlite3", path)
+	require.NoError(t, err)
+	defer db.Close()
+	_, err = db.Exec(`CREATE TABLE package (name TEXT); CREATE TABLE operatorbundle (name TEXT);
+		INSERT INTO package VALUES ('etcd'), ('prometheus'), ('strimzi');
+		INSERT INTO operatorbundle VALUES ('a'), ('b'), ('c'), ('d'), ('e');`)
+	require.NoError(t, err)
+
+	size, err := CatalogSizeOf(context.TODO(), path)
+	require.NoError(t, err)
+	require.Equal(t, &CatalogSize{Packages: 4, Bundles: 8}, size)
+
+	_, err = CatalogSizeOf(context.TODO(), filepath.Join(dir, "missing.db"))
+	require.Error(t, err)
+}
+
+func TestRoundUp(t *testing.T) {
+	for n, expected := range map[int]int{0: 0, 1: 1, 2: 2, 3: 4, 1000: 1024} {
+		require.Equal(t, expected, roundUp(n))
+	}
+}
@@ -7,9 +7,9 @@ import (
 	"os"
 )
 
-// CopyTmpDB reads the file at the given path and copies it to a tmp directory, returning the copied file path or an err
+// CopyTmpDB reads the file at the given path and copies it to the work directory, returning the copied file path or an err
 func CopyTmpDB(original string) (path string, err error) {
-	dst, err := ioutil.TempFile(".", "db-")
+	dst, err := ioutil.TempFile(WorkDir(), "db-")
 	if err != nil {
 		return "", err
 	}
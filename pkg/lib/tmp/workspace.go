@@ -0,0 +1,73 @@
+package tmp
+
+import (
+	"io/ioutil"
+	"os"
+	"sync"
+)
+
+// workspacePrefix prefixes the directories of every operation workspace
+const workspacePrefix = "opm-"
+
+var (
+	workDirMu sync.RWMutex
+	workDir   = "."
+)
+
+// SetWorkDir sets the directory that operation workspaces and temporary databases are created under.
+// An empty dir resets it to the current directory.
+func SetWorkDir(dir string) {
+	workDirMu.Lock()
+	defer workDirMu.Unlock()
+	if dir == "" {
+		dir = "."
+	}
+	workDir = dir
+}
+
+// WorkDir returns the directory that operation workspaces and temporary databases are created under
+func WorkDir() string {
+	workDirMu.RLock()
+	defer workDirMu.RUnlock()
+	return workDir
+}
+
+// Workspace is a uniquely named directory holding the temporary files (unpacked images, build
+// contexts, caches) of a single operation, so concurrent invocations never share paths.
+type Workspace struct {
+	dir string
+}
+
+// NewWorkspace creates a workspace for the named operation under the work directory
+func NewWorkspace(operation string) (*Workspace, error) {
+	root := WorkDir()
+	if err := os.MkdirAll(root, 0755); err != nil {
+		return nil, err
+	}
+	dir, err := ioutil.TempDir(root, workspacePrefix+operation+"-")
+	if err != nil {
+		return nil, err
+	}
+	return &Workspace{dir: dir}, nil
+}
+
+// Path returns the root directory of the workspace
+func (w *Workspace) Path() string {
+	return w.dir
+}
+
+// Dir creates a new uniquely named directory in the workspace
+func (w *Workspace) Dir(prefix string) (string, error) {
+	return ioutil.TempDir(w.dir, prefix)
+}
+
+// File creates a new uniquely named file in the workspace
+func (w *Workspace) File(prefix string) (*os.File, error) {
+	return ioutil.TempFile(w.dir, prefix)
+}
+
+// Cleanup removes the workspace and everything in it. It should be deferred right after the workspace
+// is created so that nothing is left behind when the operation fails.
+func (w *Workspace) Cleanup() error {
+	return os.RemoveAll(w.dir)
+}
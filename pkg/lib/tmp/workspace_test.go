@@ -0,0 +1,52 @@
+package tmp
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWorkspace(t *testing.T) {
+	root := filepath.Join(os.TempDir(), "workspace-test")
+	defer os.RemoveAll(root)
+	SetWorkDir(root)
+	defer SetWorkDir("")
+
+	first, err := NewWorkspace("index-add")
+	if err != nil {
+		t.Fatalf("creating workspace: %s", err)
+	}
+	second, err := NewWorkspace("index-add")
+	if err != nil {
+		t.Fatalf("creating workspace: %s", err)
+	}
+	if first.Path() == second.Path() {
+		t.Fatalf("expected concurrent workspaces to be distinct, both are %s", first.Path())
+	}
+	if filepath.Dir(first.Path()) != root || !strings.HasPrefix(filepath.Base(first.Path()), "opm-index-add-") {
+		t.Fatalf("unexpected workspace path %s", first.Path())
+	}
+
+	dir, err := first.Dir("bundle_tmp")
+	if err != nil {
+		t.Fatalf("creating workspace dir: %s", err)
+	}
+	f, err := first.File("index.Dockerfile")
+	if err != nil {
+		t.Fatalf("creating workspace file: %s", err)
+	}
+	f.Close()
+
+	if err := first.Cleanup(); err != nil {
+		t.Fatalf("cleaning up workspace: %s", err)
+	}
+	for _, path := range []string{first.Path(), dir, f.Name()} {
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Fatalf("expected %s to be removed, got %v", path, err)
+		}
+	}
+	if _, err := os.Stat(second.Path()); err != nil {
+		t.Fatalf("expected other workspace to be left alone: %s", err)
+	}
+}
@@ -0,0 +1,100 @@
+// Package upgradegraph writes the upgrade graph of a package as JSON, for tooling, or as Graphviz DOT, for visualization.
+package upgradegraph
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/operator-framework/operator-registry/pkg/registry"
+)
+
+// Format is a format an upgrade graph can be written in
+type Format string
+
+const (
+	FormatJSON Format = "json"
+	FormatDOT  Format = "dot"
+)
+
+// Formats are the formats an upgrade graph can be written in
+var Formats = []Format{FormatJSON, FormatDOT}
+
+// ParseFormat returns the Format named by s
+func ParseFormat(s string) (Format, error) {
+	for _, f := range Formats {
+		if strings.EqualFold(s, string(f)) {
+			return f, nil
+		}
+	}
+	var names []string
+	for _, f := range Formats {
+		names = append(names, string(f))
+	}
+	return "", fmt.Errorf("unknown output format %q, must be one of [%s]", s, strings.Join(names, ", "))
+}
+
+// Write writes the upgrade graph in the given format
+func Write(w io.Writer, graph *registry.UpgradeGraph, format Format) error {
+	switch format {
+	case FormatJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(graph)
+	case FormatDOT:
+		return writeDOT(w, graph)
+	}
+	return fmt.Errorf("unknown output format %q", format)
+}
+
+// edgeStyles tells the kinds of edges apart in a rendered graph
+var edgeStyles = map[registry.UpgradeEdgeType]string{
+	registry.UpgradeEdgeReplaces:  "solid",
+	registry.UpgradeEdgeSkips:     "dashed",
+	registry.UpgradeEdgeSkipRange: "dotted",
+}
+
+// writeDOT writes a digraph with a cluster per channel. A bundle in several channels is drawn once in each, so node ids
+// are qualified by channel. Heads are drawn with a double border and deprecated bundles in gray.
+func writeDOT(w io.Writer, graph *registry.UpgradeGraph) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "digraph %s {\n", quote(graph.PackageName))
+	fmt.Fprintf(&b, "  label=%s;\n", quote(fmt.Sprintf("%s (default channel %s)", graph.PackageName, graph.DefaultChannel)))
+	b.WriteString("  rankdir=LR;\n  node [shape=box];\n")
+	for _, channel := range graph.Channels {
+		id := func(csvName string) string {
+			return quote(channel.Name + "/" + csvName)
+		}
+		fmt.Fprintf(&b, "  subgraph %s {\n", quote("cluster_"+channel.Name))
+		fmt.Fprintf(&b, "    label=%s;\n", quote(channel.Name))
+		for _, node := range channel.Nodes {
+			label := node.CsvName
+			if node.Version != "" {
+				label += "\n" + node.Version
+			}
+			attrs := []string{"label=" + quote(label)}
+			if node.CsvName == channel.Head {
+				attrs = append(attrs, "peripheries=2")
+			}
+			if node.Deprecated {
+				attrs = append(attrs, "color=gray", "fontcolor=gray")
+			}
+			fmt.Fprintf(&b, "    %s [%s];\n", id(node.CsvName), strings.Join(attrs, ", "))
+		}
+		for _, edge := range channel.Edges {
+			fmt.Fprintf(&b, "    %s -> %s [style=%s, tooltip=%s];\n", id(edge.From), id(edge.To), edgeStyles[edge.Type], quote(string(edge.Type)))
+		}
+		b.WriteString("  }\n")
+	}
+	b.WriteString("}\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// quote returns s as a DOT string, which may hold any character but a quote or a backslash unescaped
+func quote(s string) string {
+	s = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s)
+	return `"` + s + `"`
+}
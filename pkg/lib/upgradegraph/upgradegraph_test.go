@@ -0,0 +1,74 @@
+package upgradegraph
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/operator-framework/operator-registry/pkg/registry"
+)
+
+func testGraph() *registry.UpgradeGraph {
+	return &registry.UpgradeGraph{
+		PackageName:    "etcd",
+		DefaultChannel: "alpha",
+		Channels: []registry.UpgradeGraphChannel{
+			{
+				Name: "alpha",
+				Head: "etcdoperator.v0.9.2",
+				Nodes: []registry.UpgradeGraphNode{
+					{CsvName: "etcdoperator.v0.6.1", Version: "0.6.1", Deprecated: true},
+					{CsvName: "etcdoperator.v0.9.0", Version: "0.9.0"},
+					{CsvName: "etcdoperator.v0.9.2", Version: "0.9.2", SkipRange: "<0.9.2"},
+				},
+				Edges: []registry.UpgradeGraphEdge{
+					{From: "etcdoperator.v0.6.1", To: "etcdoperator.v0.9.0", Type: registry.UpgradeEdgeReplaces},
+					{From: "etcdoperator.v0.9.0", To: "etcdoperator.v0.9.2", Type: registry.UpgradeEdgeReplaces},
+					{From: "etcdoperator.v0.6.1", To: "etcdoperator.v0.9.2", Type: registry.UpgradeEdgeSkipRange},
+				},
+			},
+		},
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	for _, name := range []string{"json", "DOT"} {
+		_, err := ParseFormat(name)
+		require.NoError(t, err)
+	}
+	_, err := ParseFormat("svg")
+	require.Error(t, err)
+}
+
+func TestWriteJSON(t *testing.T) {
+	var out bytes.Buffer
+	require.NoError(t, Write(&out, testGraph(), FormatJSON))
+	var graph registry.UpgradeGraph
+	require.NoError(t, json.Unmarshal(out.Bytes(), &graph))
+	require.Equal(t, testGraph(), &graph)
+	require.Contains(t, out.String(), `"type": "skipRange"`)
+}
+
+func TestWriteDOT(t *testing.T) {
+	var out bytes.Buffer
+	require.NoError(t, Write(&out, testGraph(), FormatDOT))
+	require.Equal(t, `digraph "etcd" {
+  label="etcd (default channel alpha)";
+  rankdir=LR;
+  node [shape=box];
+  subgraph "cluster_alpha" {
+    label="alpha";
+    "alpha/etcdoperator.v0.6.1" [label="etcdoperator.v0.6.1\n0.6.1", color=gray, fontcolor=gray];
+    "alpha/etcdoperator.v0.9.0" [label="etcdoperator.v0.9.0\n0.9.0"];
+    "alpha/etcdoperator.v0.9.2" [label="etcdoperator.v0.9.2\n0.9.2", peripheries=2];
+    "alpha/etcdoperator.v0.6.1" -> "alpha/etcdoperator.v0.9.0" [style=solid, tooltip="replaces"];
+    "alpha/etcdoperator.v0.9.0" -> "alpha/etcdoperator.v0.9.2" [style=solid, tooltip="replaces"];
+    "alpha/etcdoperator.v0.6.1" -> "alpha/etcdoperator.v0.9.2" [style=dotted, tooltip="skipRange"];
+  }
+}
+`, out.String())
+
+	require.Equal(t, `"say \"hi\"\\"`, quote(`say "hi"\`))
+}
@@ -2,6 +2,7 @@ package validation
 
 import (
 	"fmt"
+	"strings"
 
 	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	apiextensionsv1beta1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
@@ -11,6 +12,9 @@ import (
 	"github.com/operator-framework/operator-registry/pkg/registry"
 )
 
+// ownedCRDsField is the path of the owned CRDs of a CSV, which errors about them are reported at
+const ownedCRDsField = "spec.customresourcedefinitions"
+
 var RegistryBundleValidator interfaces.Validator = interfaces.ValidatorFunc(validateBundles)
 
 func validateBundles(objs ...interface{}) (results []errors.ManifestResult) {
@@ -31,6 +35,7 @@ func validateBundle(bundle *registry.Bundle) (result errors.ManifestResult) {
 	}
 
 	result = validateOwnedCRDs(bundle, csv)
+	result.Add(validateUIMetadata(csv).Errors...)
 
 	if result.Name, err = csv.GetVersion(); err != nil {
 		result.Add(errors.ErrInvalidParse("error getting bundle CSV version", err))
@@ -52,10 +57,20 @@ func validateOwnedCRDs(bundle *registry.Bundle, csv *registry.ClusterServiceVers
 		return result
 	}
 
-	// Validate all owned keys, and remove them from the set if seen.
-	for _, ownedKey := range ownedKeys {
+	// Validate all owned keys, and remove them from the set if seen. A key owned more than once is only reported as a
+	// duplicate, so that a copy of an entry for a CRD in the bundle isn't also reported missing.
+	owners := map[registry.DefinitionKey]int{}
+	for i, ownedKey := range ownedKeys {
+		field := fmt.Sprintf("%s.owned[%d]", ownedCRDsField, i)
+		gvk := ownedGVK(*ownedKey)
+		if first, ok := owners[gvk]; ok {
+			detail := fmt.Sprintf("owned CRD %s is declared more than once in CSV %q, first at %s.owned[%d]", keyToString(*ownedKey), csv.GetName(), ownedCRDsField, first)
+			result.Add(errors.NewError(errors.ErrorInvalidCSV, detail, field, *ownedKey))
+			continue
+		}
+		owners[gvk] = i
 		if _, ok := keySet[*ownedKey]; !ok {
-			result.Add(errors.ErrInvalidBundle(fmt.Sprintf("owned CRD %s not found in bundle %q", keyToString(*ownedKey), bundle.Name), *ownedKey))
+			result.Add(errors.NewError(errors.ErrorInvalidBundle, fmt.Sprintf("owned CRD %s not found in bundle %q", keyToString(*ownedKey), bundle.Name), field, *ownedKey))
 		} else {
 			delete(keySet, *ownedKey)
 		}
@@ -67,6 +82,16 @@ func validateOwnedCRDs(bundle *registry.Bundle, csv *registry.ClusterServiceVers
 	return result
 }
 
+// ownedGVK returns the group, version and kind of an owned CRD, which a CSV may declare once. CSVs name the group of a
+// CRD by its name, <plural>.<group>, rather than by the group field.
+func ownedGVK(key registry.DefinitionKey) registry.DefinitionKey {
+	group := key.Group
+	if i := strings.Index(key.Name, "."); i >= 0 && group == "" {
+		group = key.Name[i+1:]
+	}
+	return registry.DefinitionKey{Group: group, Version: key.Version, Kind: key.Kind}
+}
+
 func getBundleCRDKeys(bundle *registry.Bundle) (map[registry.DefinitionKey]struct{}, errors.Error) {
 	crds, err := bundle.CustomResourceDefinitions()
 	if err != nil {
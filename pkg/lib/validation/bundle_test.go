@@ -19,6 +19,7 @@ func TestValidateBundle(t *testing.T) {
 		directory   string
 		hasError    bool
 		errString   string
+		field       string
 	}{
 		{
 			description: "registryv1 bundle/valid bundle",
@@ -30,6 +31,7 @@ func TestValidateBundle(t *testing.T) {
 			directory:   "./testdata/invalid_bundle",
 			hasError:    true,
 			errString:   "owned CRD etcdclusters.etcd.database.coreos.com/v1beta2 not found in bundle",
+			field:       "spec.customresourcedefinitions.owned[0]",
 		},
 		{
 			description: "registryv1 bundle/invalid bundle 2",
@@ -37,6 +39,13 @@ func TestValidateBundle(t *testing.T) {
 			hasError:    true,
 			errString:   `CRD etcdclusters.etcd.database.coreos.com/v1beta2 is present in bundle "test" but not defined in CSV`,
 		},
+		{
+			description: "registryv1 bundle/duplicate owned CRD",
+			directory:   "./testdata/duplicate_owned_crd",
+			hasError:    true,
+			errString:   `owned CRD etcdbackups.etcd.database.coreos.com/v1beta2 is declared more than once in CSV "etcdoperator.v0.9.4", first at spec.customresourcedefinitions.owned[1]`,
+			field:       "spec.customresourcedefinitions.owned[3]",
+		},
 	}
 
 	for _, tt := range table {
@@ -67,6 +76,9 @@ func TestValidateBundle(t *testing.T) {
 			require.Equal(t, tt.hasError, results[0].HasError(), "%s: %s", tt.description, results[0])
 			if results[0].HasError() {
 				require.Contains(t, results[0].Errors[0].Error(), tt.errString)
+				if tt.field != "" {
+					require.Equal(t, tt.field, results[0].Errors[0].Field)
+				}
 			}
 		}
 	}
@@ -0,0 +1,117 @@
+package validation
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"image/png"
+	"net/url"
+	"unicode/utf8"
+
+	"github.com/operator-framework/api/pkg/validation/errors"
+	"github.com/operator-framework/operator-registry/pkg/registry"
+)
+
+// Bounds on the UI metadata of a CSV, past which the console can't render it properly
+const (
+	maxIconSize                = 100 << 10
+	maxDisplayNameLength       = 64
+	maxShortDescriptionLength  = 256
+	maxDescriptionLength       = 64 << 10
+	shortDescriptionAnnotation = "description"
+)
+
+const (
+	pngMediaType = "image/png"
+	svgMediaType = "image/svg+xml"
+)
+
+// validateUIMetadata checks the CSV fields the console displays: icons must be PNG or SVG images under
+// maxIconSize, the display name and descriptions must be within their length bounds, and links must be
+// absolute http(s) urls.
+func validateUIMetadata(csv *registry.ClusterServiceVersion) (result errors.ManifestResult) {
+	name := csv.GetName()
+
+	icons, err := csv.GetIcons()
+	if err != nil {
+		result.Add(errors.ErrInvalidParse("error getting CSV icons", err))
+	}
+	for i, icon := range icons {
+		if err := validateIcon(icon); err != nil {
+			result.Add(errors.ErrInvalidCSV(fmt.Sprintf("icon %d: %s", i, err), name))
+		}
+	}
+
+	displayName, err := csv.GetDisplayName()
+	if err != nil {
+		result.Add(errors.ErrInvalidParse("error getting CSV display name", err))
+	} else if n := utf8.RuneCountInString(displayName); n == 0 || n > maxDisplayNameLength {
+		result.Add(errors.ErrInvalidCSV(fmt.Sprintf("displayName must be between 1 and %d characters, has %d", maxDisplayNameLength, n), name))
+	}
+
+	if n := utf8.RuneCountInString(csv.GetAnnotations()[shortDescriptionAnnotation]); n > maxShortDescriptionLength {
+		result.Add(errors.ErrInvalidCSV(fmt.Sprintf("%s annotation must be at most %d characters, has %d", shortDescriptionAnnotation, maxShortDescriptionLength, n), name))
+	}
+
+	description, err := csv.GetDescription()
+	if err != nil {
+		result.Add(errors.ErrInvalidParse("error getting CSV description", err))
+	} else if n := utf8.RuneCountInString(description); n > maxDescriptionLength {
+		result.Add(errors.ErrInvalidCSV(fmt.Sprintf("description must be at most %d characters, has %d", maxDescriptionLength, n), name))
+	}
+
+	links, err := csv.GetLinks()
+	if err != nil {
+		result.Add(errors.ErrInvalidParse("error getting CSV links", err))
+	}
+	for _, link := range links {
+		u, err := url.Parse(link.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			result.Add(errors.ErrInvalidCSV(fmt.Sprintf("link %q has invalid url %q, must be an absolute http(s) url", link.Name, link.URL), name))
+		}
+	}
+
+	return result
+}
+
+func validateIcon(icon registry.Icon) error {
+	data, err := base64.StdEncoding.DecodeString(icon.Base64Data)
+	if err != nil {
+		return fmt.Errorf("base64data doesn't decode: %s", err)
+	}
+	if len(data) == 0 {
+		return fmt.Errorf("base64data is empty")
+	}
+	if len(data) > maxIconSize {
+		return fmt.Errorf("image is %d bytes, must be at most %d", len(data), maxIconSize)
+	}
+
+	switch icon.MediaType {
+	case pngMediaType:
+		if _, err := png.DecodeConfig(bytes.NewReader(data)); err != nil {
+			return fmt.Errorf("image is not a png: %s", err)
+		}
+	case svgMediaType:
+		if !isSVG(data) {
+			return fmt.Errorf("image is not an svg")
+		}
+	default:
+		return fmt.Errorf("mediatype %q is not supported, must be one of [%s, %s]", icon.MediaType, pngMediaType, svgMediaType)
+	}
+	return nil
+}
+
+// isSVG returns true if the first element of data is an svg element
+func isSVG(data []byte) bool {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return false
+		}
+		if start, ok := token.(xml.StartElement); ok {
+			return start.Name.Local == "svg"
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package validation
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"image"
+	"image/png"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/operator-framework/operator-registry/pkg/registry"
+)
+
+func TestValidateUIMetadata(t *testing.T) {
+	var pngData bytes.Buffer
+	require.NoError(t, png.Encode(&pngData, image.NewRGBA(image.Rect(0, 0, 1, 1))))
+	pngIcon := registry.Icon{Base64Data: base64.StdEncoding.EncodeToString(pngData.Bytes()), MediaType: pngMediaType}
+	svgIcon := registry.Icon{Base64Data: base64.StdEncoding.EncodeToString([]byte(`<?xml version="1.0"?><svg xmlns="http://www.w3.org/2000/svg"/>`)), MediaType: svgMediaType}
+
+	newCSV := func(annotations map[string]string, spec map[string]interface{}) *registry.ClusterServiceVersion {
+		raw, err := json.Marshal(spec)
+		require.NoError(t, err)
+		return &registry.ClusterServiceVersion{
+			ObjectMeta: metav1.ObjectMeta{Name: "etcdoperator.v0.9.4", Annotations: annotations},
+			Spec:       raw,
+		}
+	}
+
+	var table = []struct {
+		description string
+		annotations map[string]string
+		spec        map[string]interface{}
+		errString   string
+	}{
+		{
+			description: "valid metadata",
+			annotations: map[string]string{"description": "Creates and maintains highly-available etcd clusters"},
+			spec: map[string]interface{}{
+				"displayName": "etcd",
+				"icon":        []registry.Icon{pngIcon, svgIcon},
+				"links":       []registry.Link{{Name: "Blog", URL: "https://coreos.com/etcd"}},
+			},
+		},
+		{
+			description: "icon isn't base64",
+			spec:        map[string]interface{}{"displayName": "etcd", "icon": []registry.Icon{{Base64Data: "not base64!", MediaType: pngMediaType}}},
+			errString:   "icon 0: base64data doesn't decode",
+		},
+		{
+			description: "icon doesn't match its media type",
+			spec:        map[string]interface{}{"displayName": "etcd", "icon": []registry.Icon{{Base64Data: svgIcon.Base64Data, MediaType: pngMediaType}}},
+			errString:   "icon 0: image is not a png",
+		},
+		{
+			description: "icon media type isn't supported",
+			spec:        map[string]interface{}{"displayName": "etcd", "icon": []registry.Icon{{Base64Data: pngIcon.Base64Data, MediaType: "image/gif"}}},
+			errString:   `icon 0: mediatype "image/gif" is not supported`,
+		},
+		{
+			description: "icon is too large",
+			spec:        map[string]interface{}{"displayName": "etcd", "icon": []registry.Icon{{Base64Data: base64.StdEncoding.EncodeToString(make([]byte, maxIconSize+1)), MediaType: pngMediaType}}},
+			errString:   "must be at most 102400",
+		},
+		{
+			description: "missing display name",
+			spec:        map[string]interface{}{},
+			errString:   "displayName must be between 1 and 64 characters, has 0",
+		},
+		{
+			description: "long display name",
+			spec:        map[string]interface{}{"displayName": strings.Repeat("e", maxDisplayNameLength+1)},
+			errString:   "displayName must be between 1 and 64 characters, has 65",
+		},
+		{
+			description: "long short description",
+			annotations: map[string]string{"description": strings.Repeat("e", maxShortDescriptionLength+1)},
+			spec:        map[string]interface{}{"displayName": "etcd"},
+			errString:   "description annotation must be at most 256 characters",
+		},
+		{
+			description: "relative link",
+			spec:        map[string]interface{}{"displayName": "etcd", "links": []registry.Link{{Name: "Docs", URL: "docs/index.html"}}},
+			errString:   `link "Docs" has invalid url "docs/index.html"`,
+		},
+	}
+
+	for _, tt := range table {
+		t.Run(tt.description, func(t *testing.T) {
+			result := validateUIMetadata(newCSV(tt.annotations, tt.spec))
+			if tt.errString == "" {
+				require.Empty(t, result.Errors)
+				return
+			}
+			require.Len(t, result.Errors, 1)
+			require.Contains(t, result.Errors[0].Error(), tt.errString)
+		})
+	}
+}
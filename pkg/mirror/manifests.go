@@ -0,0 +1,174 @@
+package mirror
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/docker/distribution/reference"
+	"github.com/ghodss/yaml"
+)
+
+const (
+	// MappingFile lists each image and where it is mirrored to, one source=destination pair per line, in the format
+	// that oc image mirror -f and oc adm catalog mirror read
+	MappingFile = "mapping.txt"
+	// ImageContentSourcePolicyFile redirects pulls by digest to the mirror on clusters older than OpenShift 4.13
+	ImageContentSourcePolicyFile = "imageContentSourcePolicy.yaml"
+	// ImageDigestMirrorSetFile redirects pulls by digest to the mirror, replacing the ImageContentSourcePolicy
+	ImageDigestMirrorSetFile = "imageDigestMirrorSet.yaml"
+	// ImageTagMirrorSetFile redirects pulls by tag to the mirror. It is only written if some images are referenced
+	// by tag, which an ImageContentSourcePolicy can't redirect.
+	ImageTagMirrorSetFile = "imageTagMirrorSet.yaml"
+)
+
+// RepositoryMirror redirects pulls from a source repository to its mirrors
+type RepositoryMirror struct {
+	Source  string   `json:"source"`
+	Mirrors []string `json:"mirrors"`
+}
+
+type objectMeta struct {
+	Name string `json:"name"`
+}
+
+type imageContentSourcePolicy struct {
+	APIVersion string     `json:"apiVersion"`
+	Kind       string     `json:"kind"`
+	Metadata   objectMeta `json:"metadata"`
+	Spec       struct {
+		RepositoryDigestMirrors []RepositoryMirror `json:"repositoryDigestMirrors"`
+	} `json:"spec"`
+}
+
+type imageDigestMirrorSet struct {
+	APIVersion string     `json:"apiVersion"`
+	Kind       string     `json:"kind"`
+	Metadata   objectMeta `json:"metadata"`
+	Spec       struct {
+		ImageDigestMirrors []RepositoryMirror `json:"imageDigestMirrors"`
+	} `json:"spec"`
+}
+
+type imageTagMirrorSet struct {
+	APIVersion string     `json:"apiVersion"`
+	Kind       string     `json:"kind"`
+	Metadata   objectMeta `json:"metadata"`
+	Spec       struct {
+		ImageTagMirrors []RepositoryMirror `json:"imageTagMirrors"`
+	} `json:"spec"`
+}
+
+// WriteManifests writes the mapping file, and the ImageContentSourcePolicy, ImageDigestMirrorSet and
+// ImageTagMirrorSet named name that redirect a cluster to the mirrored images of mapping, to dir
+func WriteManifests(dir, name string, mapping map[string]string) error {
+	byDigest, byTag, err := RepositoryMirrors(mapping)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(dir, MappingFile), MappingFileContent(mapping), 0644); err != nil {
+		return err
+	}
+
+	icsp := imageContentSourcePolicy{
+		APIVersion: "operator.openshift.io/v1alpha1",
+		Kind:       "ImageContentSourcePolicy",
+		Metadata:   objectMeta{Name: name},
+	}
+	icsp.Spec.RepositoryDigestMirrors = byDigest
+	if err := writeManifest(filepath.Join(dir, ImageContentSourcePolicyFile), icsp); err != nil {
+		return err
+	}
+
+	idms := imageDigestMirrorSet{
+		APIVersion: "config.openshift.io/v1",
+		Kind:       "ImageDigestMirrorSet",
+		Metadata:   objectMeta{Name: name},
+	}
+	idms.Spec.ImageDigestMirrors = byDigest
+	if err := writeManifest(filepath.Join(dir, ImageDigestMirrorSetFile), idms); err != nil {
+		return err
+	}
+
+	if len(byTag) == 0 {
+		return nil
+	}
+	itms := imageTagMirrorSet{
+		APIVersion: "config.openshift.io/v1",
+		Kind:       "ImageTagMirrorSet",
+		Metadata:   objectMeta{Name: name},
+	}
+	itms.Spec.ImageTagMirrors = byTag
+	return writeManifest(filepath.Join(dir, ImageTagMirrorSetFile), itms)
+}
+
+// MappingFileContent returns the content of the mapping file of mapping, sorted by source image
+func MappingFileContent(mapping map[string]string) []byte {
+	var sources []string
+	for src := range mapping {
+		sources = append(sources, src)
+	}
+	sort.Strings(sources)
+
+	var buf bytes.Buffer
+	for _, src := range sources {
+		fmt.Fprintf(&buf, "%s=%s\n", src, mapping[src])
+	}
+	return buf.Bytes()
+}
+
+// RepositoryMirrors returns the repositories of the images of mapping with the repositories they are mirrored to,
+// separately for images that are referenced by digest and by tag, sorted by source repository
+func RepositoryMirrors(mapping map[string]string) (byDigest, byTag []RepositoryMirror, err error) {
+	digestMirrors := map[string]map[string]struct{}{}
+	tagMirrors := map[string]map[string]struct{}{}
+	for src, dest := range mapping {
+		srcRef, err := reference.ParseNormalizedNamed(src)
+		if err != nil {
+			return nil, nil, fmt.Errorf("couldn't parse source image %s: %s", src, err)
+		}
+		destRef, err := reference.ParseNormalizedNamed(dest)
+		if err != nil {
+			return nil, nil, fmt.Errorf("couldn't parse mirrored image %s: %s", dest, err)
+		}
+
+		mirrors := tagMirrors
+		if _, ok := srcRef.(reference.Digested); ok {
+			mirrors = digestMirrors
+		}
+		if mirrors[srcRef.Name()] == nil {
+			mirrors[srcRef.Name()] = map[string]struct{}{}
+		}
+		mirrors[srcRef.Name()][destRef.Name()] = struct{}{}
+	}
+	return sortedMirrors(digestMirrors), sortedMirrors(tagMirrors), nil
+}
+
+func sortedMirrors(mirrors map[string]map[string]struct{}) []RepositoryMirror {
+	var out []RepositoryMirror
+	for src, dests := range mirrors {
+		m := RepositoryMirror{Source: src}
+		for dest := range dests {
+			m.Mirrors = append(m.Mirrors, dest)
+		}
+		sort.Strings(m.Mirrors)
+		out = append(out, m)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Source < out[j].Source })
+	return out
+}
+
+func writeManifest(path string, obj interface{}) error {
+	out, err := yaml.Marshal(obj)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, out, 0644)
+}
@@ -39,11 +39,17 @@ func (f ImageMirrorerFunc) Mirror(mapping map[string]string) error {
 }
 
 type IndexImageMirrorer struct {
+	// ImageMirrorer mirrors the images of the index. If it is nil, images aren't mirrored and only the manifests to
+	// mirror them with are written.
 	ImageMirrorer     ImageMirrorer
 	DatabaseExtractor DatabaseExtractor
 
 	// options
 	Source, Dest string
+	// ManifestDir is the directory the mapping file and mirror manifests are written to, none are written if empty
+	ManifestDir string
+	// Name is the name of the mirror manifests
+	Name string
 }
 
 var _ Mirrorer = &IndexImageMirrorer{}
@@ -62,6 +68,8 @@ func NewIndexImageMirror(options ...ImageIndexMirrorOption) (*IndexImageMirrorer
 		DatabaseExtractor: config.DatabaseExtractor,
 		Source:            config.Source,
 		Dest:              config.Dest,
+		ManifestDir:       config.ManifestDir,
+		Name:              config.Name,
 	}, nil
 }
 
@@ -104,8 +112,16 @@ func (b *IndexImageMirrorer) Mirror() (map[string]string, error) {
 		mapping[ref.String()] = b.Dest + strings.TrimPrefix(ref.String(), domain)
 	}
 
-	if err := b.ImageMirrorer.Mirror(mapping); err != nil {
-		errs = append(errs, fmt.Errorf("mirroring failed: %s", err.Error()))
+	if b.ManifestDir != "" {
+		if err := WriteManifests(b.ManifestDir, b.Name, mapping); err != nil {
+			errs = append(errs, fmt.Errorf("writing mirror manifests failed: %s", err.Error()))
+		}
+	}
+
+	if b.ImageMirrorer != nil {
+		if err := b.ImageMirrorer.Mirror(mapping); err != nil {
+			errs = append(errs, fmt.Errorf("mirroring failed: %s", err.Error()))
+		}
 	}
 
 	return mapping, errors.NewAggregate(errs)
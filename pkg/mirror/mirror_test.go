@@ -4,8 +4,10 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"io/ioutil"
 	"math/rand"
 	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -103,3 +105,91 @@ func TestIndexImageMirrorer_Mirror(t *testing.T) {
 		})
 	}
 }
+
+func TestWriteManifests(t *testing.T) {
+	dir, err := ioutil.TempDir("", "mirror-manifests-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	mapping := map[string]string{
+		"quay.io/coreos/etcd-operator@sha256:bd944a211eaf8f31da5e6d69e8541e7cada8f16a9f7a5a570b22478997819943": "registry.example.com/mirror/coreos/etcd-operator@sha256:bd944a211eaf8f31da5e6d69e8541e7cada8f16a9f7a5a570b22478997819943",
+		"quay.io/coreos/etcd-operator@sha256:c0301e4686c3ed4206e370b42de5a3bd2229b9fb4906cf85f3f30650424abec2": "registry.example.com/mirror/coreos/etcd-operator@sha256:c0301e4686c3ed4206e370b42de5a3bd2229b9fb4906cf85f3f30650424abec2",
+		"docker.io/strimzi/operator:0.12.1": "registry.example.com/mirror/strimzi/operator:0.12.1",
+	}
+	require.NoError(t, WriteManifests(dir, "etcd-catalog", mapping))
+
+	out, err := ioutil.ReadFile(filepath.Join(dir, MappingFile))
+	require.NoError(t, err)
+	require.Equal(t, `docker.io/strimzi/operator:0.12.1=registry.example.com/mirror/strimzi/operator:0.12.1
+quay.io/coreos/etcd-operator@sha256:bd944a211eaf8f31da5e6d69e8541e7cada8f16a9f7a5a570b22478997819943=registry.example.com/mirror/coreos/etcd-operator@sha256:bd944a211eaf8f31da5e6d69e8541e7cada8f16a9f7a5a570b22478997819943
+quay.io/coreos/etcd-operator@sha256:c0301e4686c3ed4206e370b42de5a3bd2229b9fb4906cf85f3f30650424abec2=registry.example.com/mirror/coreos/etcd-operator@sha256:c0301e4686c3ed4206e370b42de5a3bd2229b9fb4906cf85f3f30650424abec2
+`, string(out))
+
+	// images of a repository share a mirror, and only images referenced by digest can be redirected by an
+	// ImageContentSourcePolicy
+	out, err = ioutil.ReadFile(filepath.Join(dir, ImageContentSourcePolicyFile))
+	require.NoError(t, err)
+	require.Equal(t, `apiVersion: operator.openshift.io/v1alpha1
+kind: ImageContentSourcePolicy
+metadata:
+  name: etcd-catalog
+spec:
+  repositoryDigestMirrors:
+  - mirrors:
+    - registry.example.com/mirror/coreos/etcd-operator
+    source: quay.io/coreos/etcd-operator
+`, string(out))
+
+	out, err = ioutil.ReadFile(filepath.Join(dir, ImageDigestMirrorSetFile))
+	require.NoError(t, err)
+	require.Contains(t, string(out), "kind: ImageDigestMirrorSet")
+	require.Contains(t, string(out), "source: quay.io/coreos/etcd-operator")
+
+	out, err = ioutil.ReadFile(filepath.Join(dir, ImageTagMirrorSetFile))
+	require.NoError(t, err)
+	require.Equal(t, `apiVersion: config.openshift.io/v1
+kind: ImageTagMirrorSet
+metadata:
+  name: etcd-catalog
+spec:
+  imageTagMirrors:
+  - mirrors:
+    - registry.example.com/mirror/strimzi/operator
+    source: docker.io/strimzi/operator
+`, string(out))
+
+	// without images referenced by tag there is no ImageTagMirrorSet
+	dir2, err := ioutil.TempDir("", "mirror-manifests-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir2)
+	delete(mapping, "docker.io/strimzi/operator:0.12.1")
+	require.NoError(t, WriteManifests(dir2, "etcd-catalog", mapping))
+	_, err = os.Stat(filepath.Join(dir2, ImageTagMirrorSetFile))
+	require.True(t, os.IsNotExist(err))
+}
+
+func TestIndexImageMirrorer_ManifestsOnly(t *testing.T) {
+	_, path, cleanup := CreateTestDb(t)
+	defer cleanup()
+
+	dir, err := ioutil.TempDir("", "mirror-manifests-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	// without an image mirrorer only the manifests are written
+	var extractor DatabaseExtractorFunc = func(from string) (string, error) {
+		return from, nil
+	}
+	mirrorer, err := NewIndexImageMirror(WithExtractor(extractor), WithSource(path), WithDest("localhost"), WithManifestDir(dir))
+	require.NoError(t, err)
+	mapping, err := mirrorer.Mirror()
+	require.NoError(t, err)
+	require.Len(t, mapping, 12)
+
+	out, err := ioutil.ReadFile(filepath.Join(dir, ImageContentSourcePolicyFile))
+	require.NoError(t, err)
+	require.Contains(t, string(out), "name: "+DefaultName)
+	require.Contains(t, string(out), "source: quay.io/coreos/etcd-operator")
+	_, err = os.Stat(filepath.Join(dir, ImageTagMirrorSetFile))
+	require.NoError(t, err)
+}
@@ -10,14 +10,12 @@ type IndexImageMirrorerOptions struct {
 
 	Source, Dest string
 	ManifestDir  string
+	Name         string
 }
 
 func (o *IndexImageMirrorerOptions) Validate() error {
 	// TODO: better validation
 
-	if o.ImageMirrorer == nil {
-		return fmt.Errorf("can't mirror without a mirrorer configured")
-	}
 	if o.DatabaseExtractor == nil {
 		return fmt.Errorf("can't mirror without a database extractor configured")
 	}
@@ -40,6 +38,9 @@ func (o *IndexImageMirrorerOptions) Complete() error {
 	if o.ManifestDir == "" {
 		o.ManifestDir = "./manifests"
 	}
+	if o.Name == "" {
+		o.Name = DefaultName
+	}
 	return nil
 }
 
@@ -69,9 +70,15 @@ func (c *IndexImageMirrorerOptions) ToOption() ImageIndexMirrorOption {
 		if c.ManifestDir != "" {
 			o.ManifestDir = c.ManifestDir
 		}
+		if c.Name != "" {
+			o.Name = c.Name
+		}
 	}
 }
 
+// DefaultName is the name of the mirror manifests if none is set
+const DefaultName = "operator-catalog"
+
 type ImageIndexMirrorOption func(*IndexImageMirrorerOptions)
 
 func DefaultImageIndexMirrorerOptions() *IndexImageMirrorerOptions {
@@ -109,3 +116,9 @@ func WithManifestDir(d string) ImageIndexMirrorOption {
 		o.ManifestDir = d
 	}
 }
+
+func WithName(n string) ImageIndexMirrorOption {
+	return func(o *IndexImageMirrorerOptions) {
+		o.Name = n
+	}
+}
@@ -0,0 +1,80 @@
+package registry
+
+import (
+	"context"
+	"sort"
+)
+
+// APIMatrixEntry lists the packages that provide and the packages that require a GVK
+type APIMatrixEntry struct {
+	Group     string   `json:"group"`
+	Version   string   `json:"version"`
+	Kind      string   `json:"kind"`
+	Providers []string `json:"providers"`
+	Requirers []string `json:"requirers"`
+}
+
+// Collision returns true if more than one package provides the GVK, so that installing one of them can conflict
+// with the others
+func (e APIMatrixEntry) Collision() bool {
+	return len(e.Providers) > 1
+}
+
+// APIMatrix returns, for every GVK provided or required by a bundle of the catalog, the packages that provide and
+// require it. Entries are sorted by group, version and kind, and packages by name.
+func APIMatrix(ctx context.Context, querier Query) ([]APIMatrixEntry, error) {
+	bundles, err := querier.ListBundles(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	type packageSets struct {
+		providers map[string]struct{}
+		requirers map[string]struct{}
+	}
+	gvks := map[GVKDependency]*packageSets{}
+	sets := func(group, version, kind string) *packageSets {
+		key := GVKDependency{Group: group, Version: version, Kind: kind}
+		if gvks[key] == nil {
+			gvks[key] = &packageSets{providers: map[string]struct{}{}, requirers: map[string]struct{}{}}
+		}
+		return gvks[key]
+	}
+	for _, b := range bundles {
+		for _, gvk := range b.GetProvidedApis() {
+			sets(gvk.Group, gvk.Version, gvk.Kind).providers[b.PackageName] = struct{}{}
+		}
+		for _, gvk := range b.GetRequiredApis() {
+			sets(gvk.Group, gvk.Version, gvk.Kind).requirers[b.PackageName] = struct{}{}
+		}
+	}
+
+	sorted := func(set map[string]struct{}) []string {
+		out := []string{}
+		for name := range set {
+			out = append(out, name)
+		}
+		sort.Strings(out)
+		return out
+	}
+	matrix := []APIMatrixEntry{}
+	for key, s := range gvks {
+		matrix = append(matrix, APIMatrixEntry{
+			Group:     key.Group,
+			Version:   key.Version,
+			Kind:      key.Kind,
+			Providers: sorted(s.providers),
+			Requirers: sorted(s.requirers),
+		})
+	}
+	sort.Slice(matrix, func(i, j int) bool {
+		if matrix[i].Group != matrix[j].Group {
+			return matrix[i].Group < matrix[j].Group
+		}
+		if matrix[i].Version != matrix[j].Version {
+			return matrix[i].Version < matrix[j].Version
+		}
+		return matrix[i].Kind < matrix[j].Kind
+	})
+	return matrix, nil
+}
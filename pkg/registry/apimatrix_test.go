@@ -0,0 +1,42 @@
+package registry
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/operator-framework/operator-registry/pkg/api"
+)
+
+type bundlesQuery struct {
+	*EmptyQuery
+	bundles []*api.Bundle
+}
+
+func (q *bundlesQuery) ListBundles(ctx context.Context) ([]*api.Bundle, error) {
+	return q.bundles, nil
+}
+
+func TestAPIMatrix(t *testing.T) {
+	cluster := &api.GroupVersionKind{Group: "etcd.database.coreos.com", Version: "v1beta2", Kind: "EtcdCluster"}
+	backup := &api.GroupVersionKind{Group: "etcd.database.coreos.com", Version: "v1beta2", Kind: "EtcdBackup"}
+	prometheus := &api.GroupVersionKind{Group: "monitoring.coreos.com", Version: "v1", Kind: "Prometheus"}
+	q := &bundlesQuery{EmptyQuery: NewEmptyQuerier(), bundles: []*api.Bundle{
+		// a bundle is listed once per channel it is in
+		{CsvName: "etcdoperator.v0.9.2", PackageName: "etcd", ChannelName: "alpha", ProvidedApis: []*api.GroupVersionKind{cluster, backup}},
+		{CsvName: "etcdoperator.v0.9.2", PackageName: "etcd", ChannelName: "stable", ProvidedApis: []*api.GroupVersionKind{cluster, backup}},
+		{CsvName: "etcd-fork.v1.0.0", PackageName: "etcd-fork", ChannelName: "stable", ProvidedApis: []*api.GroupVersionKind{cluster}},
+		{CsvName: "backup-operator.v1.0.0", PackageName: "backups", ChannelName: "stable", RequiredApis: []*api.GroupVersionKind{backup, prometheus}},
+	}}
+
+	matrix, err := APIMatrix(context.TODO(), q)
+	require.NoError(t, err)
+	require.Equal(t, []APIMatrixEntry{
+		{Group: "etcd.database.coreos.com", Version: "v1beta2", Kind: "EtcdBackup", Providers: []string{"etcd"}, Requirers: []string{"backups"}},
+		{Group: "etcd.database.coreos.com", Version: "v1beta2", Kind: "EtcdCluster", Providers: []string{"etcd", "etcd-fork"}, Requirers: []string{}},
+		{Group: "monitoring.coreos.com", Version: "v1", Kind: "Prometheus", Providers: []string{}, Requirers: []string{"backups"}},
+	}, matrix)
+	require.False(t, matrix[0].Collision())
+	require.True(t, matrix[1].Collision())
+}
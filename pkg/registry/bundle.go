@@ -1,7 +1,9 @@
 package registry
 
 import (
+	"crypto/sha256"
 	"fmt"
+	"sort"
 	"strings"
 
 	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
@@ -74,7 +76,9 @@ func (b *Bundle) Size() int {
 	return len(b.Objects)
 }
 
+// Add adds a manifest to the bundle, normalized with NormalizeManifest
 func (b *Bundle) Add(obj *unstructured.Unstructured) {
+	NormalizeManifest(obj)
 	b.Objects = append(b.Objects, obj)
 	b.cacheStale = true
 }
@@ -252,6 +256,30 @@ func (b *Bundle) Serialize() (csvName, bundleImage string, csvBytes []byte, bund
 	return csvName, b.BundleImage, csvBytes, bundleBytes, nil
 }
 
+// Digest returns a sha256 digest of the bundle content (its package, channels and manifests) that is
+// stable regardless of the order the manifests were read in.
+func (b *Bundle) Digest() (string, error) {
+	objs := make([]string, 0, len(b.Objects))
+	for _, obj := range b.Objects {
+		objBytes, err := runtime.Encode(unstructured.UnstructuredJSONScheme, obj)
+		if err != nil {
+			return "", err
+		}
+		objs = append(objs, string(objBytes))
+	}
+	sort.Strings(objs)
+
+	channels := append([]string{}, b.Channels...)
+	sort.Strings(channels)
+
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\n%s\n", b.Package, strings.Join(channels, ","))
+	for _, obj := range objs {
+		fmt.Fprintf(h, "%s\n", obj)
+	}
+	return fmt.Sprintf("sha256:%x", h.Sum(nil)), nil
+}
+
 func (b *Bundle) Images() (map[string]struct{}, error) {
 	csv, err := b.ClusterServiceVersion()
 	if err != nil {
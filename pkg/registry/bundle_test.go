@@ -106,3 +106,42 @@ func TestV1CRDsInBundle(t *testing.T) {
 		}
 	}
 }
+
+// TestBundleDigest tests that the bundle digest only depends on the bundle content
+func TestBundleDigest(t *testing.T) {
+	csv := `{"apiVersion":"operators.coreos.com/v1alpha1","kind":"ClusterServiceVersion","metadata":{"name":"test.v1.0.0"}}`
+	crd := `{"apiVersion":"apiextensions.k8s.io/v1","kind":"CustomResourceDefinition","metadata":{"name":"tests.example.com"}}`
+
+	bundle, err := NewBundleFromStrings("test.v1.0.0", "test", []string{"alpha", "stable"}, []string{csv, crd})
+	if err != nil {
+		t.Fatalf("creating bundle: %s", err)
+	}
+	reordered, err := NewBundleFromStrings("test.v1.0.0", "test", []string{"stable", "alpha"}, []string{crd, csv})
+	if err != nil {
+		t.Fatalf("creating bundle: %s", err)
+	}
+	otherChannels, err := NewBundleFromStrings("test.v1.0.0", "test", []string{"alpha"}, []string{csv, crd})
+	if err != nil {
+		t.Fatalf("creating bundle: %s", err)
+	}
+
+	digest, err := bundle.Digest()
+	if err != nil {
+		t.Fatalf("computing digest: %s", err)
+	}
+	reorderedDigest, err := reordered.Digest()
+	if err != nil {
+		t.Fatalf("computing digest: %s", err)
+	}
+	otherDigest, err := otherChannels.Digest()
+	if err != nil {
+		t.Fatalf("computing digest: %s", err)
+	}
+
+	if digest != reorderedDigest {
+		t.Fatalf("expected digest to ignore manifest and channel order: %s != %s", digest, reorderedDigest)
+	}
+	if digest == otherDigest {
+		t.Fatalf("expected digest to change with the bundle channels")
+	}
+}
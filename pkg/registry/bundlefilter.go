@@ -0,0 +1,42 @@
+package registry
+
+import (
+	"strings"
+
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// BundleFilter selects the bundles a listing returns, so that clients that only need part of a catalog don't have to
+// read all of it. Fields that aren't set select every bundle.
+type BundleFilter struct {
+	// PackageName selects the bundles of a package
+	PackageName string
+	// ChannelName selects the bundles in the channels with this name
+	ChannelName string
+	// Labels selects the bundles whose labels it matches, see BundleLabels
+	Labels labels.Selector
+	// InstallMode selects the bundles that support this install mode, e.g. AllNamespaces. Bundles that don't record
+	// their install modes are selected by any.
+	InstallMode string
+}
+
+// SelectsLabels returns true if the filter selects bundles by their labels
+func (f BundleFilter) SelectsLabels() bool {
+	return f.Labels != nil && !f.Labels.Empty()
+}
+
+// BundleLabels returns the labels of a bundle given the labels of its olm.label properties. A label of the form
+// key=value is the label key with that value; any other label is a key with an empty value, so that it can be selected
+// by whether a bundle has it, e.g. "lts" or "!beta".
+func BundleLabels(values []string) labels.Set {
+	set := labels.Set{}
+	for _, value := range values {
+		parts := strings.SplitN(value, "=", 2)
+		if len(parts) == 2 {
+			set[parts[0]] = parts[1]
+			continue
+		}
+		set[value] = ""
+	}
+	return set
+}
@@ -0,0 +1,26 @@
+package registry
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+func TestBundleLabels(t *testing.T) {
+	set := BundleLabels([]string{"lts", "tier=gold", "channel=stable=2"})
+	require.Equal(t, labels.Set{"lts": "", "tier": "gold", "channel": "stable=2"}, set)
+
+	for selector, matches := range map[string]bool{
+		"lts":                   true,
+		"!beta":                 true,
+		"tier=gold":             true,
+		"tier in (gold,silver)": true,
+		"tier!=gold":            false,
+		"beta":                  false,
+	} {
+		s, err := labels.Parse(selector)
+		require.NoError(t, err)
+		require.Equal(t, matches, s.Matches(set), selector)
+	}
+}
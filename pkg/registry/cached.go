@@ -0,0 +1,154 @@
+package registry
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	lru "github.com/hashicorp/golang-lru"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/operator-framework/operator-registry/pkg/api"
+)
+
+// QueryCacheOptions size a CachedQuery
+type QueryCacheOptions struct {
+	// Size is how many results are kept, the least recently used are evicted first
+	Size int
+
+	// TTL is how long a result is served from the cache before it is looked up again, or zero to keep it until it is
+	// evicted or the cache is invalidated
+	TTL time.Duration
+}
+
+// CachedQuery serves repeated lookups of packages and bundles, e.g. the GetPackage and GetBundleForChannel calls
+// clients make on every resolution, from an in-memory LRU cache in front of a Query. Every other call is passed
+// through. Lookups that fail aren't cached.
+//
+// The cache doesn't know when the Query it wraps changes, so it must be invalidated whenever it does, e.g. when the
+// database behind it is reloaded.
+type CachedQuery struct {
+	Query
+
+	cache *lru.Cache
+	ttl   time.Duration
+	now   func() time.Time
+
+	// generation is bumped on every invalidation, so that a lookup made before one isn't cached after it
+	mu         sync.Mutex
+	generation uint64
+}
+
+var _ Query = &CachedQuery{}
+
+// cacheKey is the method and arguments of a cached lookup
+type cacheKey struct {
+	method string
+	args   [3]string
+}
+
+type cacheEntry struct {
+	value   interface{}
+	expires time.Time
+}
+
+// NewCachedQuery returns a CachedQuery that caches the lookups made through it to query
+func NewCachedQuery(query Query, options QueryCacheOptions) (*CachedQuery, error) {
+	if options.Size <= 0 {
+		return nil, fmt.Errorf("query cache size must be positive, got %d", options.Size)
+	}
+	if options.TTL < 0 {
+		return nil, fmt.Errorf("query cache ttl must not be negative, got %s", options.TTL)
+	}
+	cache, err := lru.New(options.Size)
+	if err != nil {
+		return nil, err
+	}
+	return &CachedQuery{Query: query, cache: cache, ttl: options.TTL, now: time.Now}, nil
+}
+
+// Invalidate drops every cached result, so that every lookup made from now on is made to the Query again
+func (c *CachedQuery) Invalidate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.generation++
+	c.cache.Purge()
+}
+
+// lookup returns the cached result of a lookup, or makes it with load and caches its result
+func (c *CachedQuery) lookup(key cacheKey, load func() (interface{}, error)) (interface{}, error) {
+	c.mu.Lock()
+	generation := c.generation
+	c.mu.Unlock()
+
+	if v, ok := c.cache.Get(key); ok {
+		entry := v.(cacheEntry)
+		if c.ttl == 0 || c.now().Before(entry.expires) {
+			return entry.value, nil
+		}
+		c.cache.Remove(key)
+	}
+
+	value, err := load()
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.generation == generation {
+		c.cache.Add(key, cacheEntry{value: value, expires: c.now().Add(c.ttl)})
+	}
+	return value, nil
+}
+
+// lookupBundle returns a copy of the cached bundle, since callers such as the registry server change the bundles
+// they're given
+func (c *CachedQuery) lookupBundle(key cacheKey, load func() (*api.Bundle, error)) (*api.Bundle, error) {
+	v, err := c.lookup(key, func() (interface{}, error) {
+		return load()
+	})
+	if err != nil {
+		return nil, err
+	}
+	bundle := v.(*api.Bundle)
+	if bundle == nil {
+		return nil, nil
+	}
+	return proto.Clone(bundle).(*api.Bundle), nil
+}
+
+func (c *CachedQuery) GetPackage(ctx context.Context, name string) (*PackageManifest, error) {
+	v, err := c.lookup(cacheKey{method: "GetPackage", args: [3]string{name}}, func() (interface{}, error) {
+		return c.Query.GetPackage(ctx, name)
+	})
+	if err != nil {
+		return nil, err
+	}
+	manifest := v.(*PackageManifest)
+	if manifest == nil {
+		return nil, nil
+	}
+	copied := *manifest
+	copied.Channels = append([]PackageChannel(nil), manifest.Channels...)
+	return &copied, nil
+}
+
+func (c *CachedQuery) GetBundle(ctx context.Context, pkgName, channelName, csvName string) (*api.Bundle, error) {
+	return c.lookupBundle(cacheKey{method: "GetBundle", args: [3]string{pkgName, channelName, csvName}}, func() (*api.Bundle, error) {
+		return c.Query.GetBundle(ctx, pkgName, channelName, csvName)
+	})
+}
+
+func (c *CachedQuery) GetBundleForChannel(ctx context.Context, pkgName string, channelName string) (*api.Bundle, error) {
+	return c.lookupBundle(cacheKey{method: "GetBundleForChannel", args: [3]string{pkgName, channelName}}, func() (*api.Bundle, error) {
+		return c.Query.GetBundleForChannel(ctx, pkgName, channelName)
+	})
+}
+
+func (c *CachedQuery) GetBundleForChannelIncludingDeprecated(ctx context.Context, pkgName string, channelName string) (*api.Bundle, error) {
+	return c.lookupBundle(cacheKey{method: "GetBundleForChannelIncludingDeprecated", args: [3]string{pkgName, channelName}}, func() (*api.Bundle, error) {
+		return c.Query.GetBundleForChannelIncludingDeprecated(ctx, pkgName, channelName)
+	})
+}
@@ -0,0 +1,96 @@
+package registry
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/operator-framework/operator-registry/pkg/api"
+)
+
+// countingQuery answers package and bundle lookups for any name, and counts the lookups made to it
+type countingQuery struct {
+	*EmptyQuery
+	calls   map[string]int
+	version string
+}
+
+func (q *countingQuery) GetPackage(ctx context.Context, name string) (*PackageManifest, error) {
+	q.calls["GetPackage"]++
+	if name == "missing" {
+		return nil, fmt.Errorf("package %s not found", name)
+	}
+	return &PackageManifest{PackageName: name, Channels: []PackageChannel{{Name: "stable", CurrentCSVName: name + "." + q.version}}}, nil
+}
+
+func (q *countingQuery) GetBundleForChannel(ctx context.Context, pkgName string, channelName string) (*api.Bundle, error) {
+	q.calls["GetBundleForChannel"]++
+	return &api.Bundle{CsvName: pkgName + "." + q.version, PackageName: pkgName, ChannelName: channelName}, nil
+}
+
+func TestCachedQuery(t *testing.T) {
+	q := &countingQuery{EmptyQuery: NewEmptyQuerier(), calls: map[string]int{}, version: "v1"}
+	c, err := NewCachedQuery(q, QueryCacheOptions{Size: 2, TTL: time.Minute})
+	require.NoError(t, err)
+	now := time.Unix(0, 0)
+	c.now = func() time.Time { return now }
+	ctx := context.TODO()
+
+	// repeated lookups are made once, and callers get copies they may change
+	for i := 0; i < 3; i++ {
+		bundle, err := c.GetBundleForChannel(ctx, "etcd", "stable")
+		require.NoError(t, err)
+		require.Equal(t, "etcd.v1", bundle.GetCsvName())
+		bundle.CsvName = "changed"
+
+		pkg, err := c.GetPackage(ctx, "etcd")
+		require.NoError(t, err)
+		require.Equal(t, "etcd.v1", pkg.Channels[0].CurrentCSVName)
+		pkg.Channels[0].CurrentCSVName = "changed"
+	}
+	require.Equal(t, 1, q.calls["GetBundleForChannel"])
+	require.Equal(t, 1, q.calls["GetPackage"])
+
+	// lookups that fail aren't cached
+	for i := 0; i < 2; i++ {
+		_, err = c.GetPackage(ctx, "missing")
+		require.Error(t, err)
+	}
+	require.Equal(t, 3, q.calls["GetPackage"])
+
+	// the least recently used result is evicted
+	_, err = c.GetPackage(ctx, "prometheus")
+	require.NoError(t, err)
+	_, err = c.GetBundleForChannel(ctx, "etcd", "stable")
+	require.NoError(t, err)
+	require.Equal(t, 2, q.calls["GetBundleForChannel"])
+
+	// once invalidated, lookups see the changes to the query
+	q.version = "v2"
+	bundle, err := c.GetBundleForChannel(ctx, "etcd", "stable")
+	require.NoError(t, err)
+	require.Equal(t, "etcd.v1", bundle.GetCsvName())
+	c.Invalidate()
+	bundle, err = c.GetBundleForChannel(ctx, "etcd", "stable")
+	require.NoError(t, err)
+	require.Equal(t, "etcd.v2", bundle.GetCsvName())
+
+	// results expire after the ttl
+	_, err = c.GetPackage(ctx, "prometheus")
+	require.NoError(t, err)
+	_, err = c.GetPackage(ctx, "prometheus")
+	require.NoError(t, err)
+	require.Equal(t, 5, q.calls["GetPackage"])
+	now = now.Add(time.Minute)
+	_, err = c.GetPackage(ctx, "prometheus")
+	require.NoError(t, err)
+	require.Equal(t, 6, q.calls["GetPackage"])
+
+	_, err = NewCachedQuery(q, QueryCacheOptions{})
+	require.Error(t, err)
+	_, err = NewCachedQuery(q, QueryCacheOptions{Size: 1, TTL: -time.Second})
+	require.Error(t, err)
+}
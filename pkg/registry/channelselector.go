@@ -0,0 +1,108 @@
+package registry
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	"github.com/blang/semver"
+)
+
+// ChannelSelector defines a channel by the bundles it selects rather than by its entries, e.g. every 2.x bundle of a
+// package. The channel is expanded into a replaces chain of the selected bundles in version order, with the highest
+// version at its head, so that operators released at a high cadence don't have to add each bundle to it by hand.
+type ChannelSelector struct {
+	PackageName string `json:"packageName"`
+	ChannelName string `json:"channelName"`
+	// Versions is a semver range, e.g. ">=2.0.0 <3.0.0", that the version of each selected bundle must be in. Every
+	// version is in an empty range.
+	Versions string `json:"versions,omitempty"`
+	// Properties must each match a property of each selected bundle. A property matches if it has the same type and
+	// its value has every field of the selector's value, or is equal to it if the selector's value isn't an object.
+	Properties []Property `json:"properties,omitempty"`
+}
+
+// Validate returns an error if the selector can't be matched against bundles
+func (s ChannelSelector) Validate() error {
+	if s.PackageName == "" || s.ChannelName == "" {
+		return fmt.Errorf("channel selector must name a package and a channel")
+	}
+	if s.Versions != "" {
+		if _, err := semver.ParseRange(s.Versions); err != nil {
+			return fmt.Errorf("invalid version range %q: %s", s.Versions, err)
+		}
+	}
+	for _, p := range s.Properties {
+		if p.Type == "" {
+			return fmt.Errorf("selected property must have a type")
+		}
+		var v interface{}
+		if err := json.Unmarshal(p.Value, &v); err != nil {
+			return fmt.Errorf("invalid value of selected property %s: %s", p.Type, err)
+		}
+	}
+	return nil
+}
+
+// Matches returns true if a bundle with the given version and properties is selected. Bundles without a semver
+// version are never selected, as they can't be placed in the channel.
+func (s ChannelSelector) Matches(version string, properties []Property) (bool, error) {
+	v, err := semver.Parse(version)
+	if err != nil {
+		return false, nil
+	}
+	if s.Versions != "" {
+		inRange, err := semver.ParseRange(s.Versions)
+		if err != nil {
+			return false, fmt.Errorf("invalid version range %q: %s", s.Versions, err)
+		}
+		if !inRange(v) {
+			return false, nil
+		}
+	}
+
+	for _, want := range s.Properties {
+		var wantValue interface{}
+		if err := json.Unmarshal(want.Value, &wantValue); err != nil {
+			return false, fmt.Errorf("invalid value of selected property %s: %s", want.Type, err)
+		}
+		found := false
+		for _, have := range properties {
+			if have.Type != want.Type {
+				continue
+			}
+			var haveValue interface{}
+			if err := json.Unmarshal(have.Value, &haveValue); err != nil {
+				continue
+			}
+			if jsonSubset(wantValue, haveValue) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// jsonSubset returns true if every field of want is in have with a matching value, or if want isn't an object and
+// is equal to have
+func jsonSubset(want, have interface{}) bool {
+	wantObject, ok := want.(map[string]interface{})
+	if !ok {
+		return reflect.DeepEqual(want, have)
+	}
+	haveObject, ok := have.(map[string]interface{})
+	if !ok {
+		return false
+	}
+	for k, v := range wantObject {
+		h, ok := haveObject[k]
+		if !ok || !jsonSubset(v, h) {
+			return false
+		}
+	}
+	return true
+}
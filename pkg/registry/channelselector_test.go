@@ -0,0 +1,58 @@
+package registry
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestChannelSelectorMatches(t *testing.T) {
+	properties := []Property{
+		{Type: PackageType, Value: json.RawMessage(`{"packageName":"etcd","version":"2.1.0"}`)},
+		{Type: LabelType, Value: json.RawMessage(`{"label":"lts"}`)},
+	}
+
+	for _, tt := range []struct {
+		name     string
+		selector ChannelSelector
+		version  string
+		matches  bool
+	}{
+		{name: "Everything", selector: ChannelSelector{}, version: "2.1.0", matches: true},
+		{name: "InRange", selector: ChannelSelector{Versions: ">=2.0.0 <3.0.0"}, version: "2.1.0", matches: true},
+		{name: "OutOfRange", selector: ChannelSelector{Versions: ">=2.0.0 <3.0.0"}, version: "3.0.0", matches: false},
+		{name: "NotSemver", selector: ChannelSelector{}, version: "v2.1", matches: false},
+		{
+			name:     "PropertySubset",
+			selector: ChannelSelector{Properties: []Property{{Type: PackageType, Value: json.RawMessage(`{"packageName":"etcd"}`)}}},
+			version:  "2.1.0",
+			matches:  true,
+		},
+		{
+			name:     "PropertyMismatch",
+			selector: ChannelSelector{Properties: []Property{{Type: LabelType, Value: json.RawMessage(`{"label":"beta"}`)}}},
+			version:  "2.1.0",
+			matches:  false,
+		},
+		{
+			name:     "PropertyMissing",
+			selector: ChannelSelector{Properties: []Property{{Type: "olm.maxOpenShiftVersion", Value: json.RawMessage(`"4.8"`)}}},
+			version:  "2.1.0",
+			matches:  false,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			matches, err := tt.selector.Matches(tt.version, properties)
+			require.NoError(t, err)
+			require.Equal(t, tt.matches, matches)
+		})
+	}
+}
+
+func TestChannelSelectorValidate(t *testing.T) {
+	require.NoError(t, ChannelSelector{PackageName: "etcd", ChannelName: "2.x", Versions: ">=2.0.0 <3.0.0"}.Validate())
+	require.Error(t, ChannelSelector{PackageName: "etcd"}.Validate())
+	require.Error(t, ChannelSelector{PackageName: "etcd", ChannelName: "2.x", Versions: "2.x.y"}.Validate())
+	require.Error(t, ChannelSelector{PackageName: "etcd", ChannelName: "2.x", Properties: []Property{{Type: LabelType, Value: json.RawMessage(`{`)}}}.Validate())
+}
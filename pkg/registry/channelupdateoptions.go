@@ -25,3 +25,36 @@ func GetModeFromString(mode string) (Mode, error) {
 		return -1, fmt.Errorf("Invalid channel update mode %s specified", mode)
 	}
 }
+
+// String returns the name a mode is selected by
+func (m Mode) String() string {
+	switch m {
+	case ReplacesMode:
+		return "replaces"
+	case SemVerMode:
+		return "semver"
+	case SkipPatchMode:
+		return "semver-skippatch"
+	default:
+		return fmt.Sprintf("Mode(%d)", int(m))
+	}
+}
+
+// ConflictPolicy is what is done with a bundle that conflicts with the bundles of the catalog
+type ConflictPolicy string
+
+const (
+	// ConflictPolicyReject fails the add
+	ConflictPolicyReject ConflictPolicy = "reject"
+	// ConflictPolicyWarn logs a warning and adds the other bundles without the conflicting one
+	ConflictPolicyWarn ConflictPolicy = "warn"
+)
+
+func GetConflictPolicyFromString(policy string) (ConflictPolicy, error) {
+	switch p := ConflictPolicy(strings.ToLower(policy)); p {
+	case ConflictPolicyReject, ConflictPolicyWarn:
+		return p, nil
+	default:
+		return "", fmt.Errorf("Invalid conflict policy %s specified", policy)
+	}
+}
@@ -3,6 +3,7 @@ package registry
 import (
 	"encoding/json"
 	"fmt"
+	"sort"
 	"strings"
 
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
@@ -30,6 +31,66 @@ func PackageChannelToAPIChannel(channel *PackageChannel) *api.Channel {
 	}
 }
 
+func PackageStatsToAPIPackageStats(stats *PackageStats) *api.PackageStats {
+	return &api.PackageStats{
+		Name:               stats.Name,
+		BundleCount:        int32(stats.BundleCount),
+		Channels:           stats.Channels,
+		DefaultChannelName: stats.DefaultChannelName,
+		LatestVersion:      stats.LatestVersion,
+		TotalBundleSize:    stats.TotalBundleSize,
+		LargestBundle:      stats.LargestBundle,
+		LargestBundleSize:  stats.LargestBundleSize,
+	}
+}
+
+func BundleStatsToAPIBundleStats(stats *BundleStats) *api.BundleStats {
+	return &api.BundleStats{
+		CsvName:     stats.CsvName,
+		PackageName: stats.PackageName,
+		Version:     stats.Version,
+		BundlePath:  stats.BundlePath,
+		Size:        stats.Size,
+		ObjectCount: int32(stats.ObjectCount),
+		CrdCount:    int32(stats.CRDCount),
+	}
+}
+
+func BundleSummaryToAPIBundleSummary(summary *BundleSummary) *api.BundleSummary {
+	return &api.BundleSummary{
+		PackageName: summary.PackageName,
+		ChannelName: summary.ChannelName,
+		CsvName:     summary.CsvName,
+		Version:     summary.Version,
+		BundlePath:  summary.BundlePath,
+		Replaces:    summary.Replaces,
+		Skips:       summary.Skips,
+		SkipRange:   summary.SkipRange,
+	}
+}
+
+// BundleSummaryToAPIBundle returns a bundle holding the fields of a summary, without manifests
+func BundleSummaryToAPIBundle(summary *BundleSummary) *api.Bundle {
+	bundle := &api.Bundle{
+		Id:          summary.ID,
+		CsvName:     summary.CsvName,
+		PackageName: summary.PackageName,
+		ChannelName: summary.ChannelName,
+		BundlePath:  summary.BundlePath,
+		Version:     summary.Version,
+		SkipRange:   summary.SkipRange,
+		Replaces:    summary.Replaces,
+		Skips:       summary.Skips,
+	}
+	for _, key := range summary.ProvidedApis {
+		bundle.ProvidedApis = append(bundle.ProvidedApis, APIKeyToAPIGVK(key))
+	}
+	for _, key := range summary.RequiredApis {
+		bundle.RequiredApis = append(bundle.RequiredApis, APIKeyToAPIGVK(key))
+	}
+	return bundle
+}
+
 func ChannelEntryToAPIChannelEntry(entry *ChannelEntry) *api.ChannelEntry {
 	return &api.ChannelEntry{
 		PackageName: entry.PackageName,
@@ -39,6 +100,109 @@ func ChannelEntryToAPIChannelEntry(entry *ChannelEntry) *api.ChannelEntry {
 	}
 }
 
+// APIKeyToAPIGVK returns the api form of an APIKey
+func APIKeyToAPIGVK(key APIKey) *api.GroupVersionKind {
+	return &api.GroupVersionKind{
+		Group:   key.Group,
+		Version: key.Version,
+		Kind:    key.Kind,
+		Plural:  key.Plural,
+	}
+}
+
+// APIKeysToAPIGVKs returns the api form of a set of APIKeys, sorted by group, version and kind
+func APIKeysToAPIGVKs(keys map[APIKey]struct{}) []*api.GroupVersionKind {
+	gvks := []*api.GroupVersionKind{}
+	for key := range keys {
+		gvks = append(gvks, APIKeyToAPIGVK(key))
+	}
+	sort.Slice(gvks, func(i, j int) bool {
+		if gvks[i].Group != gvks[j].Group {
+			return gvks[i].Group < gvks[j].Group
+		}
+		if gvks[i].Version != gvks[j].Version {
+			return gvks[i].Version < gvks[j].Version
+		}
+		return gvks[i].Kind < gvks[j].Kind
+	})
+	return gvks
+}
+
+func DependencyToAPIDependency(dependency *Dependency) *api.Dependency {
+	return &api.Dependency{
+		Type:  dependency.Type,
+		Value: string(dependency.Value),
+	}
+}
+
+func PropertyToAPIProperty(property *Property) *api.Property {
+	return &api.Property{
+		Type:  property.Type,
+		Value: string(property.Value),
+	}
+}
+
+// BundleToAPIBundle returns the api form of a bundle. A bundle can be in many channels while an api bundle is the
+// entry of a bundle in one channel, so the channel name is left for the caller to set.
+func BundleToAPIBundle(bundle *Bundle) (*api.Bundle, error) {
+	out := &api.Bundle{
+		CsvName:      bundle.Name,
+		PackageName:  bundle.Package,
+		BundlePath:   bundle.BundleImage,
+		Object:       []string{},
+		Dependencies: []*api.Dependency{},
+		Properties:   []*api.Property{},
+	}
+	for _, obj := range bundle.Objects {
+		objBytes, err := json.Marshal(obj.Object)
+		if err != nil {
+			return nil, err
+		}
+		out.Object = append(out.Object, string(objBytes))
+		if obj.GetKind() == "ClusterServiceVersion" {
+			out.CsvName = obj.GetName()
+			out.CsvJson = string(objBytes)
+		}
+	}
+
+	csv, err := bundle.ClusterServiceVersion()
+	if err != nil {
+		return nil, err
+	}
+	if csv == nil {
+		return nil, fmt.Errorf("no csv in bundle")
+	}
+	if out.Version, err = csv.GetVersion(); err != nil {
+		return nil, err
+	}
+	if out.Replaces, err = csv.GetReplaces(); err != nil {
+		return nil, err
+	}
+	if out.Skips, err = csv.GetSkips(); err != nil {
+		return nil, err
+	}
+	out.SkipRange = csv.GetSkipRange()
+
+	provided, err := bundle.ProvidedAPIs()
+	if err != nil {
+		return nil, err
+	}
+	out.ProvidedApis = APIKeysToAPIGVKs(provided)
+	required, err := bundle.RequiredAPIs()
+	if err != nil {
+		return nil, err
+	}
+	out.RequiredApis = APIKeysToAPIGVKs(required)
+
+	for _, d := range bundle.Dependencies {
+		out.Dependencies = append(out.Dependencies, DependencyToAPIDependency(d))
+	}
+	for _, p := range bundle.Properties {
+		out.Properties = append(out.Properties, PropertyToAPIProperty(p))
+	}
+	return out, nil
+}
+
 // Bundle strings are appended json objects, we need to split them apart
 // e.g. {"my":"obj"}{"csv":"data"}{"crd":"too"}
 func BundleStringToObjectStrings(bundleString string) ([]string, error) {
@@ -0,0 +1,60 @@
+package registry
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/operator-framework/operator-registry/pkg/api"
+)
+
+func TestBundleToAPIBundle(t *testing.T) {
+	bundle := NewBundle("lib-bucket-provisioner.v1.0.0", "lib-bucket-provisioner", []string{"alpha"})
+	bundle.BundleImage = "quay.io/example/lib-bucket-provisioner:v1.0.0"
+	for _, name := range []string{
+		"lib-bucket-provisioner.v1.0.0.clusterserviceversion.yaml",
+		"objectbucketclaims.objectbucket.io.crd.yaml",
+		"objectbuckets.objectbucket.io.crd.yaml",
+	} {
+		f, err := os.Open(filepath.Join(manifestDir, name))
+		require.NoError(t, err)
+		obj, err := DecodeUnstructured(f)
+		f.Close()
+		require.NoError(t, err)
+		bundle.Add(obj)
+	}
+	bundle.Dependencies = []*Dependency{{Type: PackageType, Value: json.RawMessage(`{"packageName":"etcd","version":">0.9.0"}`)}}
+	bundle.Properties = []*Property{{Type: LabelType, Value: json.RawMessage(`{"label":"testlabel"}`)}}
+
+	out, err := BundleToAPIBundle(bundle)
+	require.NoError(t, err)
+
+	require.Equal(t, "lib-bucket-provisioner.v1.0.0", out.CsvName)
+	require.Equal(t, "lib-bucket-provisioner", out.PackageName)
+	require.Empty(t, out.ChannelName)
+	require.Equal(t, "quay.io/example/lib-bucket-provisioner:v1.0.0", out.BundlePath)
+	require.Equal(t, "1.0.0", out.Version)
+	require.Len(t, out.Object, 3)
+	require.Contains(t, out.Object, out.CsvJson)
+	require.Equal(t, []*api.GroupVersionKind{
+		{Group: "objectbucket.io", Version: "v1alpha1", Kind: "ObjectBucket", Plural: "objectbuckets"},
+		{Group: "objectbucket.io", Version: "v1alpha1", Kind: "ObjectBucketClaim", Plural: "objectbucketclaims"},
+	}, out.ProvidedApis)
+	require.Equal(t, []*api.GroupVersionKind{}, out.RequiredApis)
+	require.Equal(t, []*api.Dependency{{Type: PackageType, Value: `{"packageName":"etcd","version":">0.9.0"}`}}, out.Dependencies)
+	require.Equal(t, []*api.Property{{Type: LabelType, Value: `{"label":"testlabel"}`}}, out.Properties)
+
+	// the objects of the api bundle decode back to the bundle
+	back, err := BundleStringToAPIBundle(out.Object[0] + out.Object[1] + out.Object[2])
+	require.NoError(t, err)
+	require.Equal(t, out.CsvName, back.CsvName)
+	require.Equal(t, out.CsvJson, back.CsvJson)
+}
+
+func TestBundleToAPIBundleWithoutCSV(t *testing.T) {
+	_, err := BundleToAPIBundle(NewBundle("test", "test", nil))
+	require.Error(t, err)
+}
@@ -3,15 +3,10 @@ package registry
 import (
 	"encoding/json"
 	"fmt"
-	"io/ioutil"
-	"os"
-	"path"
 
 	v1 "k8s.io/api/apps/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
-	"k8s.io/apimachinery/pkg/util/yaml"
 
 	"github.com/operator-framework/api/pkg/operators"
 )
@@ -47,6 +42,27 @@ const (
 
 	// The yaml attribute that specifies the skipRange of the ClusterServiceVersion
 	skipRangeAnnotationKey = "olm.skipRange"
+
+	// The yaml attribute that specifies the provider of the ClusterServiceVersion
+	provider = "provider"
+
+	// The yaml attribute that specifies the maintainers of the ClusterServiceVersion
+	maintainers = "maintainers"
+
+	// The yaml attribute that specifies the links of the ClusterServiceVersion
+	links = "links"
+
+	// The yaml attribute that specifies the icons of the ClusterServiceVersion
+	icon = "icon"
+
+	// The yaml attribute that specifies the display name of the ClusterServiceVersion
+	displayName = "displayName"
+
+	// The yaml attribute that specifies the description of the ClusterServiceVersion
+	description = "description"
+
+	// The yaml attribute that specifies the install modes of the ClusterServiceVersion
+	installModes = "installModes"
 )
 
 // ClusterServiceVersion is a structured representation of cluster service
@@ -65,49 +81,37 @@ type ClusterServiceVersion struct {
 	Spec json.RawMessage `json:"spec"`
 }
 
-// ReadCSVFromBundleDirectory tries to parse every YAML file in the directory without inspecting sub-directories and
+// ReadCSVFromBundleDirectory tries to parse every manifest file in the directory without inspecting sub-directories and
 // returns a CSV. According to the strict one CSV per bundle rule, func returns an error if more than one CSV is found.
 func ReadCSVFromBundleDirectory(bundleDir string) (*ClusterServiceVersion, error) {
-	dirContent, err := ioutil.ReadDir(bundleDir)
+	files, err := ManifestFiles(bundleDir, false)
 	if err != nil {
 		return nil, fmt.Errorf("error reading bundle directory %s, %v", bundleDir, err)
 	}
 
-	files := []string{}
-	for _, f := range dirContent {
-		if !f.IsDir() {
-			files = append(files, f.Name())
-		}
-	}
-
 	csv := ClusterServiceVersion{}
 	foundCSV := false
 	for _, file := range files {
-		yamlReader, err := os.Open(path.Join(bundleDir, file))
+		objs, err := DecodeManifestFile(file)
 		if err != nil {
 			continue
 		}
 
-		unstructuredCSV := unstructured.Unstructured{}
+		for _, unstructuredCSV := range objs {
+			if unstructuredCSV.GetKind() != operators.ClusterServiceVersionKind {
+				continue
+			}
 
-		decoder := yaml.NewYAMLOrJSONDecoder(yamlReader, 30)
-		if err = decoder.Decode(&unstructuredCSV); err != nil {
-			continue
-		}
-
-		if unstructuredCSV.GetKind() != operators.ClusterServiceVersionKind {
-			continue
-		}
+			if foundCSV {
+				return nil, fmt.Errorf("more than one ClusterServiceVersion is found in bundle")
+			}
 
-		if foundCSV {
-			return nil, fmt.Errorf("more than one ClusterServiceVersion is found in bundle")
+			if err := runtime.DefaultUnstructuredConverter.FromUnstructured(unstructuredCSV.UnstructuredContent(),
+				&csv); err != nil {
+				return nil, err
+			}
+			foundCSV = true
 		}
-
-		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(unstructuredCSV.UnstructuredContent(),
-			&csv); err != nil {
-			return nil, err
-		}
-		foundCSV = true
 	}
 
 	if foundCSV {
@@ -196,6 +200,151 @@ func (csv *ClusterServiceVersion) GetSkips() ([]string, error) {
 	return skips, nil
 }
 
+// GetProvider returns the name of the provider of the CSV
+//
+// If not defined, the function returns an empty string.
+func (csv *ClusterServiceVersion) GetProvider() (string, error) {
+	var objmap map[string]*json.RawMessage
+	if err := json.Unmarshal(csv.Spec, &objmap); err != nil {
+		return "", err
+	}
+
+	rawValue, ok := objmap[provider]
+	if !ok || rawValue == nil {
+		return "", nil
+	}
+
+	var p struct {
+		Name string `json:"name"`
+	}
+	if err := json.Unmarshal(*rawValue, &p); err != nil {
+		return "", err
+	}
+
+	return p.Name, nil
+}
+
+// GetDisplayName returns the display name of the CSV
+//
+// If not defined, the function returns an empty string.
+func (csv *ClusterServiceVersion) GetDisplayName() (string, error) {
+	return csv.getSpecString(displayName)
+}
+
+// GetDescription returns the description of the CSV
+//
+// If not defined, the function returns an empty string.
+func (csv *ClusterServiceVersion) GetDescription() (string, error) {
+	return csv.getSpecString(description)
+}
+
+func (csv *ClusterServiceVersion) getSpecString(field string) (string, error) {
+	var objmap map[string]*json.RawMessage
+	if err := json.Unmarshal(csv.Spec, &objmap); err != nil {
+		return "", err
+	}
+
+	rawValue, ok := objmap[field]
+	if !ok || rawValue == nil {
+		return "", nil
+	}
+
+	var value string
+	if err := json.Unmarshal(*rawValue, &value); err != nil {
+		return "", err
+	}
+
+	return value, nil
+}
+
+// GetMaintainers returns the list of maintainers of the CSV
+//
+// If not defined, the function returns an empty list.
+func (csv *ClusterServiceVersion) GetMaintainers() ([]Maintainer, error) {
+	var objmap map[string]*json.RawMessage
+	if err := json.Unmarshal(csv.Spec, &objmap); err != nil {
+		return nil, err
+	}
+
+	rawValue, ok := objmap[maintainers]
+	if !ok || rawValue == nil {
+		return nil, nil
+	}
+
+	var m []Maintainer
+	if err := json.Unmarshal(*rawValue, &m); err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+// GetLinks returns the list of links of the CSV
+//
+// If not defined, the function returns an empty list.
+func (csv *ClusterServiceVersion) GetLinks() ([]Link, error) {
+	var objmap map[string]*json.RawMessage
+	if err := json.Unmarshal(csv.Spec, &objmap); err != nil {
+		return nil, err
+	}
+
+	rawValue, ok := objmap[links]
+	if !ok || rawValue == nil {
+		return nil, nil
+	}
+
+	var l []Link
+	if err := json.Unmarshal(*rawValue, &l); err != nil {
+		return nil, err
+	}
+
+	return l, nil
+}
+
+// GetInstallModes returns the install modes of the CSV, and whether it declares any
+//
+// If not defined, the function returns an empty list and false.
+func (csv *ClusterServiceVersion) GetInstallModes() ([]InstallMode, bool, error) {
+	var objmap map[string]*json.RawMessage
+	if err := json.Unmarshal(csv.Spec, &objmap); err != nil {
+		return nil, false, err
+	}
+
+	rawValue, ok := objmap[installModes]
+	if !ok || rawValue == nil {
+		return nil, false, nil
+	}
+
+	var m []InstallMode
+	if err := json.Unmarshal(*rawValue, &m); err != nil {
+		return nil, false, err
+	}
+
+	return m, true, nil
+}
+
+// GetIcons returns the list of icons of the CSV
+//
+// If not defined, the function returns an empty list.
+func (csv *ClusterServiceVersion) GetIcons() ([]Icon, error) {
+	var objmap map[string]*json.RawMessage
+	if err := json.Unmarshal(csv.Spec, &objmap); err != nil {
+		return nil, err
+	}
+
+	rawValue, ok := objmap[icon]
+	if !ok || rawValue == nil {
+		return nil, nil
+	}
+
+	var i []Icon
+	if err := json.Unmarshal(*rawValue, &i); err != nil {
+		return nil, err
+	}
+
+	return i, nil
+}
+
 // GetCustomResourceDefintions returns a list of owned and required
 // CustomResourceDefinition object(s) specified inside the
 // 'customresourcedefinitions' section of a ClusterServiceVersion 'spec'.
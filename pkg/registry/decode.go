@@ -22,6 +22,39 @@ func DecodeUnstructured(reader io.Reader) (obj *unstructured.Unstructured, err e
 	return
 }
 
+// DecodeUnstructuredList decodes a raw stream of one or more manifests, separated
+// by `---` or appended as json, into unstructured.Unstructured instances.
+// Empty documents are left out, and a document without a kind is invalid.
+func DecodeUnstructuredList(reader io.Reader) (objs []*unstructured.Unstructured, err error) {
+	decoder := yaml.NewYAMLOrJSONDecoder(reader, 30)
+
+	for {
+		m := map[string]interface{}{}
+		if decodeErr := decoder.Decode(&m); decodeErr != nil {
+			if decodeErr == io.EOF {
+				break
+			}
+			err = decodeErr
+			return
+		}
+		if len(m) == 0 {
+			continue
+		}
+
+		t := &unstructured.Unstructured{Object: m}
+		if t.GetKind() == "" {
+			err = fmt.Errorf("manifest %d has no kind", len(objs))
+			return
+		}
+		objs = append(objs, t)
+	}
+
+	if len(objs) == 0 {
+		err = io.EOF
+	}
+	return
+}
+
 // DecodePackageManifest decodes a raw stream into a a PackageManifest instance.
 // If a package name is empty we consider the object invalid!
 func DecodePackageManifest(reader io.Reader) (manifest *PackageManifest, err error) {
@@ -4,7 +4,6 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
-	"path"
 	"path/filepath"
 	"sort"
 
@@ -77,8 +76,14 @@ func (g *DirGraphLoader) loadBundleCsvPathMap() error {
 	if err != nil {
 		return fmt.Errorf("error reading from %s directory, %v", g.PackageDir, err)
 	}
+	ignore := NewIndexIgnore(g.PackageDir)
 	CsvNameAndReplaceMap := make(map[string]csvReplaces)
 	for _, bundlePath := range bundleDirs {
+		if ignored, err := ignore.Ignored(filepath.Join(g.PackageDir, bundlePath.Name()), bundlePath.IsDir()); err != nil {
+			return err
+		} else if ignored {
+			continue
+		}
 		if bundlePath.IsDir() {
 			csvStruct, err := ReadCSVFromBundleDirectory(filepath.Join(g.PackageDir, bundlePath.Name()))
 			if err != nil {
@@ -174,22 +179,14 @@ func (g *DirGraphLoader) getChannelNodes(channelHeadCsv string) *map[BundleKey]m
 // parsePackageYAMLFile parses the *.package.yaml file and fills the information in Package including name,
 // defaultchannel, and head of all Channels. It returns parsing error if any.
 func (g *DirGraphLoader) parsePackageYAMLFile() (*Package, error) {
-	files, err := ioutil.ReadDir(g.PackageDir)
+	ymlFiles, err := ManifestFiles(g.PackageDir, false)
 	if err != nil {
 		return nil, fmt.Errorf("error reading bundle parent directory, %v", err)
 	}
 
-	var ymlFiles []string
-	for _, f := range files {
-		if !f.IsDir() {
-			ymlFiles = append(ymlFiles, f.Name())
-		}
-	}
-
 	errs := errors.AggregateError{}
 
 	for _, ymlFile := range ymlFiles {
-		ymlFile = path.Join(g.PackageDir, ymlFile)
 		ymlReader, err := os.Open(ymlFile)
 		if err != nil {
 			errs = append(errs, fmt.Errorf("error opening %s file, %v", ymlFile, err))
@@ -20,10 +20,18 @@ func (EmptyQuery) ListPackages(ctx context.Context) ([]string, error) {
 	return nil, errors.New("empty querier: cannot list packages")
 }
 
+func (EmptyQuery) ListPackagesPaged(ctx context.Context, after string, limit int) ([]string, error) {
+	return nil, errors.New("empty querier: cannot list packages")
+}
+
 func (EmptyQuery) GetPackage(ctx context.Context, name string) (*PackageManifest, error) {
 	return nil, errors.New("empty querier: cannot get package")
 }
 
+func (EmptyQuery) ListChannels(ctx context.Context, pkgName string) ([]PackageChannel, error) {
+	return nil, errors.New("empty querier: cannot list channels")
+}
+
 func (EmptyQuery) GetDefaultPackage(ctx context.Context, name string) (string, error) {
 	return "", errors.New("empty querier: cannot get default package")
 }
@@ -40,6 +48,22 @@ func (EmptyQuery) GetBundleForChannel(ctx context.Context, pkgName string, chann
 	return nil, errors.New("empty querier: cannot get bundle for channel")
 }
 
+func (EmptyQuery) GetBundleForChannelIncludingDeprecated(ctx context.Context, pkgName string, channelName string) (*api.Bundle, error) {
+	return nil, errors.New("empty querier: cannot get bundle for channel")
+}
+
+func (EmptyQuery) GetChannelHeadBundles(ctx context.Context, pkgName string, includeDeprecated bool) ([]*api.Bundle, error) {
+	return nil, errors.New("empty querier: cannot get channel head bundles")
+}
+
+func (EmptyQuery) GetBundleForVersion(ctx context.Context, pkgName, channelName, version string) (*api.Bundle, error) {
+	return nil, errors.New("empty querier: cannot get bundle for version")
+}
+
+func (EmptyQuery) GetLatestBundleInRange(ctx context.Context, pkgName, channelName, versionRange string) (*api.Bundle, error) {
+	return nil, errors.New("empty querier: cannot get latest bundle in range")
+}
+
 func (EmptyQuery) GetChannelEntriesThatReplace(ctx context.Context, name string) (entries []*ChannelEntry, err error) {
 	return nil, errors.New("empty querier: cannot get channel entries that replace")
 }
@@ -52,6 +76,14 @@ func (EmptyQuery) GetChannelEntriesThatProvide(ctx context.Context, group, versi
 	return nil, errors.New("empty querier: cannot get channel entries that provide")
 }
 
+func (EmptyQuery) GetChannelEntriesThatRequire(ctx context.Context, group, version, kind string) (entries []*ChannelEntry, err error) {
+	return nil, errors.New("empty querier: cannot get channel entries that require")
+}
+
+func (EmptyQuery) GetBundlesRequiring(ctx context.Context, group, version, kind string) ([]*api.Bundle, error) {
+	return nil, errors.New("empty querier: cannot get bundles requiring")
+}
+
 func (EmptyQuery) GetLatestChannelEntriesThatProvide(ctx context.Context, group, version, kind string) (entries []*ChannelEntry, err error) {
 	return nil, errors.New("empty querier: cannot get latest channel entries that provide")
 }
@@ -88,10 +120,6 @@ func (EmptyQuery) GetDefaultChannelForPackage(ctx context.Context, pkgName strin
 	return "", errors.New("empty querier: cannot get default channel")
 }
 
-func (EmptyQuery) ListChannels(ctx context.Context, pkgName string) ([]string, error) {
-	return nil, errors.New("empty querier: cannot list channels")
-}
-
 func (EmptyQuery) GetCurrentCSVNameForChannel(ctx context.Context, pkgName, channel string) (string, error) {
 	return "", errors.New("empty querier: cannot get csv name for package and channel")
 }
@@ -100,10 +128,98 @@ func (EmptyQuery) ListBundles(ctx context.Context) ([]*api.Bundle, error) {
 	return nil, errors.New("empty querier: cannot list bundles")
 }
 
+func (EmptyQuery) ListBundlesFiltered(ctx context.Context, filter BundleFilter) ([]*api.Bundle, error) {
+	return nil, errors.New("empty querier: cannot list bundles")
+}
+
 func (EmptyQuery) GetDependenciesForBundle(ctx context.Context, name, version, path string) (dependencies []*api.Dependency, err error) {
 	return nil, errors.New("empty querier: cannot get dependencies for bundle")
 }
 
+func (EmptyQuery) ListPackagesByProvider(ctx context.Context, provider string) ([]string, error) {
+	return nil, errors.New("empty querier: cannot list packages by provider")
+}
+
+func (EmptyQuery) GetOwnershipForBundle(ctx context.Context, bundleName string) (*Ownership, error) {
+	return nil, errors.New("empty querier: cannot get ownership for bundle")
+}
+
+func (EmptyQuery) GetBundleAttestations(ctx context.Context, bundleName string) ([]*Attestation, error) {
+	return nil, errors.New("empty querier: cannot get bundle attestations")
+}
+
+func (EmptyQuery) GetBundleDigest(ctx context.Context, image string) (string, error) {
+	return "", errors.New("empty querier: cannot get bundle digest")
+}
+
+func (EmptyQuery) GetBundleImage(ctx context.Context, bundleName string) (*BundleImage, error) {
+	return nil, errors.New("empty querier: cannot get bundle image")
+}
+
+func (EmptyQuery) GetPackageForAlias(ctx context.Context, alias string) (string, error) {
+	return "", errors.New("empty querier: cannot get package for alias")
+}
+
+func (EmptyQuery) GetDisplayMetadataForBundle(ctx context.Context, bundleName, locale string) (*DisplayMetadata, error) {
+	return nil, errors.New("empty querier: cannot get display metadata for bundle")
+}
+
+func (EmptyQuery) GetChannelEntriesForPlatformVersion(ctx context.Context, packageName, platformVersion string) ([]ChannelEntryAnnotated, error) {
+	return nil, errors.New("empty querier: cannot get channel entries for platform version")
+}
+
+func (EmptyQuery) GetPackageID(ctx context.Context, name string) (string, error) {
+	return "", errors.New("empty querier: cannot get package id")
+}
+
+func (EmptyQuery) GetBundleID(ctx context.Context, csvName string) (string, error) {
+	return "", errors.New("empty querier: cannot get bundle id")
+}
+
+func (EmptyQuery) GetEntriesByBundleImage(ctx context.Context, imageRefOrDigest string) ([]ChannelEntryAnnotated, error) {
+	return nil, errors.New("empty querier: cannot get entries by bundle image")
+}
+
+func (EmptyQuery) GetPresentBundles(ctx context.Context, bundles []string) ([]string, error) {
+	return nil, errors.New("empty querier: cannot get present bundles")
+}
+
+func (EmptyQuery) GetPackageStats(ctx context.Context, packages []string) ([]*PackageStats, error) {
+	return nil, errors.New("empty querier: cannot get package stats")
+}
+
+func (EmptyQuery) GetBundleStats(ctx context.Context, packages []string, limit int) ([]*BundleStats, error) {
+	return nil, errors.New("empty querier: cannot get bundle stats")
+}
+
+func (EmptyQuery) GetPackagesForBundle(ctx context.Context, csvName string) ([]string, error) {
+	return nil, errors.New("empty querier: cannot get packages for bundle")
+}
+
+func (EmptyQuery) GetDefaultChannelHeads(ctx context.Context, packages []string) ([]*BundleSummary, error) {
+	return nil, errors.New("empty querier: cannot get default channel heads")
+}
+
+func (EmptyQuery) GetBundleSummary(ctx context.Context, pkgName, channelName, csvName string) (*BundleSummary, error) {
+	return nil, errors.New("empty querier: cannot get bundle summary")
+}
+
+func (EmptyQuery) ListBundleSummaries(ctx context.Context) ([]*BundleSummary, error) {
+	return nil, errors.New("empty querier: cannot list bundle summaries")
+}
+
+func (EmptyQuery) ListBundleSummariesFiltered(ctx context.Context, filter BundleFilter) ([]*BundleSummary, error) {
+	return nil, errors.New("empty querier: cannot list bundle summaries")
+}
+
+func (EmptyQuery) SearchBundles(ctx context.Context, query string, packages []string) ([]*BundleSummary, error) {
+	return nil, errors.New("empty querier: cannot search bundles")
+}
+
+func (EmptyQuery) GetUpgradeGraph(ctx context.Context, pkgName string) (*UpgradeGraph, error) {
+	return nil, errors.New("empty querier: cannot get upgrade graph")
+}
+
 var _ Query = &EmptyQuery{}
 
 func NewEmptyQuerier() *EmptyQuery {
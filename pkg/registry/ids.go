@@ -0,0 +1,20 @@
+package registry
+
+import (
+	"github.com/gofrs/uuid"
+)
+
+// idNamespace is the namespace the stable ids of packages and bundles are derived in
+var idNamespace = uuid.NewV5(uuid.NamespaceURL, "https://github.com/operator-framework/operator-registry")
+
+// PackageID returns the id a package is first given, derived from its name. Once stored, a package keeps its id when
+// it is renamed.
+func PackageID(name string) string {
+	return uuid.NewV5(idNamespace, "package/"+name).String()
+}
+
+// BundleID returns the id a bundle is given, derived from its CSV name, so that a bundle that is removed and
+// published again keeps its id.
+func BundleID(csvName string) string {
+	return uuid.NewV5(idNamespace, "bundle/"+csvName).String()
+}
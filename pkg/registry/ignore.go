@@ -0,0 +1,196 @@
+package registry
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// IndexIgnoreFile is the name of the file that lists, with gitignore-style patterns, the paths of a directory that
+// loaders leave out. Its patterns apply to the directory it is in and every directory nested in it.
+const IndexIgnoreFile = ".indexignore"
+
+type ignoreRule struct {
+	// base is the slash separated path, relative to the root, of the directory holding the ignore file
+	base    string
+	pattern *regexp.Regexp
+	negate  bool
+	dirOnly bool
+}
+
+// IndexIgnore matches paths of a directory tree against the .indexignore files of that tree. Ignore files are read
+// the first time a path under their directory is matched.
+type IndexIgnore struct {
+	root   string
+	loaded map[string]bool
+	rules  []ignoreRule
+}
+
+// NewIndexIgnore returns an IndexIgnore for the directory tree at root
+func NewIndexIgnore(root string) *IndexIgnore {
+	return &IndexIgnore{
+		root:   root,
+		loaded: map[string]bool{},
+	}
+}
+
+// Ignored returns true if path, in the tree of the root, is left out by an ignore file. As with gitignore, the last
+// pattern to match a path decides whether it is ignored, patterns of nested ignore files take precedence over those
+// of their parents, and nothing under an ignored directory can be included again.
+func (i *IndexIgnore) Ignored(path string, isDir bool) (bool, error) {
+	rel, err := filepath.Rel(i.root, path)
+	if err != nil {
+		return false, err
+	}
+	rel = filepath.ToSlash(rel)
+	if rel == "." {
+		return false, nil
+	}
+	if rel == ".." || strings.HasPrefix(rel, "../") {
+		return false, fmt.Errorf("%s is not in %s", path, i.root)
+	}
+
+	// ancestors are loaded, and matched, from the root down
+	parts := strings.Split(rel, "/")
+	for n := 0; n < len(parts); n++ {
+		dir := strings.Join(parts[:n], "/")
+		if err := i.load(dir); err != nil {
+			return false, err
+		}
+		if n > 0 && i.match(dir, true) {
+			return true, nil
+		}
+	}
+
+	return i.match(rel, isDir), nil
+}
+
+func (i *IndexIgnore) match(rel string, isDir bool) bool {
+	ignored := false
+	for _, rule := range i.rules {
+		name := rel
+		if rule.base != "" {
+			if !strings.HasPrefix(rel, rule.base+"/") {
+				continue
+			}
+			name = strings.TrimPrefix(rel, rule.base+"/")
+		}
+		if rule.dirOnly && !isDir {
+			continue
+		}
+		if rule.pattern.MatchString(name) {
+			ignored = !rule.negate
+		}
+	}
+	return ignored
+}
+
+// load reads the ignore file of dir, a slash separated path relative to the root
+func (i *IndexIgnore) load(dir string) error {
+	if i.loaded[dir] {
+		return nil
+	}
+	i.loaded[dir] = true
+
+	path := filepath.Join(i.root, filepath.FromSlash(dir), IndexIgnoreFile)
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		rule, ok, err := parseIgnorePattern(scanner.Text())
+		if err != nil {
+			return fmt.Errorf("invalid pattern in %s: %s", path, err)
+		}
+		if !ok {
+			continue
+		}
+		rule.base = dir
+		i.rules = append(i.rules, rule)
+	}
+	return scanner.Err()
+}
+
+// parseIgnorePattern parses a line of an ignore file. Blank lines and comments hold no pattern.
+func parseIgnorePattern(line string) (ignoreRule, bool, error) {
+	var rule ignoreRule
+
+	line = strings.TrimRight(line, " \t\r")
+	if line == "" || strings.HasPrefix(line, "#") {
+		return rule, false, nil
+	}
+	if strings.HasPrefix(line, "!") {
+		rule.negate = true
+		line = line[1:]
+	} else if strings.HasPrefix(line, `\#`) || strings.HasPrefix(line, `\!`) {
+		line = line[1:]
+	}
+	if strings.HasSuffix(line, "/") {
+		rule.dirOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+	if line == "" {
+		return rule, false, nil
+	}
+
+	// a pattern without a slash matches a name at any depth, otherwise it is relative to the ignore file
+	anchored := strings.Contains(line, "/")
+	line = strings.TrimPrefix(line, "/")
+
+	var expr strings.Builder
+	expr.WriteString("^")
+	if !anchored {
+		expr.WriteString("(?:.*/)?")
+	}
+	for n := 0; n < len(line); n++ {
+		switch c := line[n]; c {
+		case '*':
+			if strings.HasPrefix(line[n:], "**/") {
+				expr.WriteString("(?:.*/)?")
+				n += 2
+			} else if strings.HasPrefix(line[n:], "**") {
+				expr.WriteString(".*")
+				n++
+			} else {
+				expr.WriteString("[^/]*")
+			}
+		case '?':
+			expr.WriteString("[^/]")
+		case '[':
+			end := strings.Index(line[n:], "]")
+			if end < 0 {
+				return rule, false, fmt.Errorf("unterminated character class in %q", line)
+			}
+			class := line[n+1 : n+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			expr.WriteString("[" + class + "]")
+			n += end
+		case '\\':
+			if n+1 < len(line) {
+				n++
+			}
+			expr.WriteString(regexp.QuoteMeta(string(line[n])))
+		default:
+			expr.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	expr.WriteString("$")
+
+	pattern, err := regexp.Compile(expr.String())
+	if err != nil {
+		return rule, false, err
+	}
+	rule.pattern = pattern
+	return rule, true, nil
+}
@@ -0,0 +1,93 @@
+package registry
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestIndexIgnore(t *testing.T) {
+	root, err := ioutil.TempDir("", "indexignore-")
+	require.NoError(t, err)
+	defer os.RemoveAll(root)
+
+	write := func(path, content string) {
+		path = filepath.Join(root, filepath.FromSlash(path))
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, ioutil.WriteFile(path, []byte(content), 0644))
+	}
+	write(IndexIgnoreFile, "# editor backups\n*~\n*.swp\n\nOWNERS\n/docs/\n**/drafts/**\n!keep.yaml.swp\n")
+	write("etcd/"+IndexIgnoreFile, "0.9.0/\n")
+
+	tests := []struct {
+		path    string
+		isDir   bool
+		ignored bool
+	}{
+		{path: "package.yaml"},
+		{path: "package.yaml~", ignored: true},
+		{path: "etcd/0.9.2/csv.yaml.swp", ignored: true},
+		{path: "etcd/0.9.2/keep.yaml.swp"},
+		{path: "OWNERS", ignored: true},
+		{path: "etcd/OWNERS", ignored: true},
+		{path: "docs", isDir: true, ignored: true},
+		{path: "docs/index.yaml", ignored: true},
+		{path: "etcd/docs", isDir: true},
+		{path: "etcd/drafts/csv.yaml", ignored: true},
+		{path: "etcd/0.9.0", isDir: true, ignored: true},
+		{path: "etcd/0.9.0/csv.yaml", ignored: true},
+		{path: "0.9.0", isDir: true},
+		{path: "etcd/0.9.2", isDir: true},
+		// directory patterns don't match files
+		{path: "etcd/0.9.0.yaml"},
+	}
+	ignore := NewIndexIgnore(root)
+	for _, tt := range tests {
+		ignored, err := ignore.Ignored(filepath.Join(root, filepath.FromSlash(tt.path)), tt.isDir)
+		require.NoError(t, err)
+		require.Equal(t, tt.ignored, ignored, tt.path)
+	}
+
+	// manifest loaders skip ignored paths
+	write("etcd/package.yaml", "")
+	write("etcd/package.yaml~", "")
+	write("etcd/0.9.0/csv.yaml", "")
+	write("etcd/0.9.2/csv.yaml", "")
+	write("etcd/drafts/csv.yaml", "")
+	files, err := ManifestFiles(root, true)
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		filepath.Join(root, "etcd", "0.9.2", "csv.yaml"),
+		filepath.Join(root, "etcd", "package.yaml"),
+	}, files)
+
+	_, err = NewIndexIgnore(root).Ignored(filepath.Dir(root), true)
+	require.Error(t, err)
+}
+
+func TestParseIgnorePattern(t *testing.T) {
+	_, ok, err := parseIgnorePattern("  # comment")
+	require.NoError(t, err)
+	require.True(t, ok, "only lines starting with # are comments")
+
+	_, ok, err = parseIgnorePattern("# comment")
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	rule, ok, err := parseIgnorePattern(`\#literal`)
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.True(t, rule.pattern.MatchString("#literal"))
+
+	rule, ok, err = parseIgnorePattern("file-[!0-9].yaml")
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.True(t, rule.pattern.MatchString("file-a.yaml"))
+	require.False(t, rule.pattern.MatchString("file-1.yaml"))
+
+	_, _, err = parseIgnorePattern("file-[0-9.yaml")
+	require.Error(t, err)
+}
@@ -19,6 +19,8 @@ type ImageInput struct {
 	annotationsFile  *AnnotationsFile
 	dependenciesFile *DependenciesFile
 	bundle           *Bundle
+	// annotated is the channel membership the annotations declared, if it was overridden
+	annotated ChannelOverride
 }
 
 func NewImageInput(to image.Reference, from string) (*ImageInput, error) {
@@ -126,3 +128,23 @@ func (i *ImageInput) getBundleFromManifests() error {
 
 	return nil
 }
+
+// overrideChannels replaces the channels and default channel of the annotations, and of the bundle, with those of
+// the override
+func (i *ImageInput) overrideChannels(override ChannelOverride) {
+	if override.IsEmpty() {
+		return
+	}
+	i.annotated = ChannelOverride{
+		Channels:       i.annotationsFile.GetChannels(),
+		DefaultChannel: i.annotationsFile.Annotations.DefaultChannelName,
+	}
+	if len(override.Channels) > 0 {
+		i.annotationsFile.Annotations.Channels = strings.Join(override.Channels, ",")
+		i.bundle.Channels = override.Channels
+	}
+	if override.DefaultChannel != "" {
+		i.annotationsFile.Annotations.DefaultChannelName = override.DefaultChannel
+	}
+	logrus.WithFields(logrus.Fields{"bundle": i.bundle.BundleImage, "channels": i.annotationsFile.Annotations.Channels, "default": i.annotationsFile.Annotations.DefaultChannelName}).Info("overriding annotated channels")
+}
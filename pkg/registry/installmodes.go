@@ -0,0 +1,59 @@
+package registry
+
+import (
+	"fmt"
+	"strings"
+)
+
+// The install modes a CSV may support, i.e. the sets of namespaces an OperatorGroup it is installed in may target
+const (
+	InstallModeOwnNamespace    = "OwnNamespace"
+	InstallModeSingleNamespace = "SingleNamespace"
+	InstallModeMultiNamespace  = "MultiNamespace"
+	InstallModeAllNamespaces   = "AllNamespaces"
+)
+
+var installModeTypes = []string{InstallModeOwnNamespace, InstallModeSingleNamespace, InstallModeMultiNamespace, InstallModeAllNamespaces}
+
+// InstallMode is an install mode of a CSV and whether it is supported, as declared in its spec
+type InstallMode struct {
+	Type      string `json:"type" yaml:"type"`
+	Supported bool   `json:"supported" yaml:"supported"`
+}
+
+// InstallModesProperty lists the install modes a bundle supports
+type InstallModesProperty struct {
+	// The supported install modes, e.g. 'AllNamespaces'
+	Supported []string `json:"supported" yaml:"supported"`
+}
+
+// NewInstallModesProperty returns the property of the install modes a CSV declares, listing those it supports
+func NewInstallModesProperty(modes []InstallMode) InstallModesProperty {
+	p := InstallModesProperty{Supported: []string{}}
+	for _, mode := range modes {
+		if mode.Supported {
+			p.Supported = append(p.Supported, mode.Type)
+		}
+	}
+	return p
+}
+
+// Supports returns true if the bundle supports the install mode
+func (p InstallModesProperty) Supports(mode string) bool {
+	for _, supported := range p.Supported {
+		if supported == mode {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateInstallMode returns an error if mode isn't one of the install modes a CSV may support
+func ValidateInstallMode(mode string) error {
+	for _, t := range installModeTypes {
+		if mode == t {
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid install mode %q, must be one of %s", mode, strings.Join(installModeTypes, ", "))
+}
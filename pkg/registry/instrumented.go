@@ -0,0 +1,473 @@
+package registry
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/operator-framework/operator-registry/pkg/api"
+)
+
+// QueryCall describes a call made through an instrumented Query
+type QueryCall struct {
+	// Method is the name of the Query method that was called, e.g. GetBundle
+	Method string
+
+	// Args are the arguments of the call, not including the context
+	Args []interface{}
+}
+
+// QueryHooks observe the calls made through an instrumented Query. Either hook may be nil.
+type QueryHooks struct {
+	// Before is called before the wrapped Query is. The context it returns, e.g. one carrying a tracing span,
+	// is passed on to the wrapped Query and to After.
+	Before func(ctx context.Context, call QueryCall) context.Context
+
+	// After is called once the wrapped Query returns, with the time the call took and the error it returned
+	After func(ctx context.Context, call QueryCall, duration time.Duration, err error)
+}
+
+// InstrumentQuery wraps a Query so that every call made through it is observed by the hooks.
+// Before hooks run in the order given and After hooks in the reverse order, so that hooks nest like middleware.
+func InstrumentQuery(query Query, hooks ...QueryHooks) Query {
+	return &instrumentedQuery{query: query, hooks: hooks}
+}
+
+type instrumentedQuery struct {
+	query Query
+	hooks []QueryHooks
+}
+
+var _ Query = &instrumentedQuery{}
+
+// before runs the Before hooks and returns the context for the call, along with the func that runs the After hooks
+func (i *instrumentedQuery) before(ctx context.Context, method string, args ...interface{}) (context.Context, func(error)) {
+	call := QueryCall{Method: method, Args: args}
+	contexts := make([]context.Context, len(i.hooks))
+	for n, hook := range i.hooks {
+		if hook.Before != nil {
+			ctx = hook.Before(ctx, call)
+		}
+		contexts[n] = ctx
+	}
+
+	start := time.Now()
+	return ctx, func(err error) {
+		duration := time.Since(start)
+		for n := len(i.hooks) - 1; n >= 0; n-- {
+			if i.hooks[n].After != nil {
+				i.hooks[n].After(contexts[n], call, duration, err)
+			}
+		}
+	}
+}
+
+// QueryMethodStats are the totals recorded for one Query method
+type QueryMethodStats struct {
+	Calls    int64
+	Errors   int64
+	Duration time.Duration
+}
+
+// QueryStats counts calls, errors and time spent per Query method. Its Hooks can be passed to InstrumentQuery.
+type QueryStats struct {
+	mu      sync.Mutex
+	methods map[string]QueryMethodStats
+}
+
+// NewQueryStats returns an empty QueryStats
+func NewQueryStats() *QueryStats {
+	return &QueryStats{methods: map[string]QueryMethodStats{}}
+}
+
+// Hooks returns the hooks that record calls into the stats
+func (s *QueryStats) Hooks() QueryHooks {
+	return QueryHooks{
+		After: func(ctx context.Context, call QueryCall, duration time.Duration, err error) {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			stats := s.methods[call.Method]
+			stats.Calls++
+			if err != nil {
+				stats.Errors++
+			}
+			stats.Duration += duration
+			s.methods[call.Method] = stats
+		},
+	}
+}
+
+// Snapshot returns the stats recorded so far, keyed by method name
+func (s *QueryStats) Snapshot() map[string]QueryMethodStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make(map[string]QueryMethodStats, len(s.methods))
+	for method, stats := range s.methods {
+		out[method] = stats
+	}
+	return out
+}
+
+func (i *instrumentedQuery) ListTables(ctx context.Context) ([]string, error) {
+	ctx, done := i.before(ctx, "ListTables")
+	out, err := i.query.ListTables(ctx)
+	done(err)
+	return out, err
+}
+
+func (i *instrumentedQuery) ListPackages(ctx context.Context) ([]string, error) {
+	ctx, done := i.before(ctx, "ListPackages")
+	out, err := i.query.ListPackages(ctx)
+	done(err)
+	return out, err
+}
+
+func (i *instrumentedQuery) ListPackagesPaged(ctx context.Context, after string, limit int) ([]string, error) {
+	ctx, done := i.before(ctx, "ListPackagesPaged", after, limit)
+	out, err := i.query.ListPackagesPaged(ctx, after, limit)
+	done(err)
+	return out, err
+}
+
+func (i *instrumentedQuery) GetPackage(ctx context.Context, name string) (*PackageManifest, error) {
+	ctx, done := i.before(ctx, "GetPackage", name)
+	out, err := i.query.GetPackage(ctx, name)
+	done(err)
+	return out, err
+}
+
+func (i *instrumentedQuery) ListChannels(ctx context.Context, pkgName string) ([]PackageChannel, error) {
+	ctx, done := i.before(ctx, "ListChannels", pkgName)
+	out, err := i.query.ListChannels(ctx, pkgName)
+	done(err)
+	return out, err
+}
+
+func (i *instrumentedQuery) GetDefaultPackage(ctx context.Context, name string) (string, error) {
+	ctx, done := i.before(ctx, "GetDefaultPackage", name)
+	out, err := i.query.GetDefaultPackage(ctx, name)
+	done(err)
+	return out, err
+}
+
+func (i *instrumentedQuery) GetChannelEntriesFromPackage(ctx context.Context, packageName string) ([]ChannelEntryAnnotated, error) {
+	ctx, done := i.before(ctx, "GetChannelEntriesFromPackage", packageName)
+	out, err := i.query.GetChannelEntriesFromPackage(ctx, packageName)
+	done(err)
+	return out, err
+}
+
+func (i *instrumentedQuery) GetBundle(ctx context.Context, pkgName, channelName, csvName string) (*api.Bundle, error) {
+	ctx, done := i.before(ctx, "GetBundle", pkgName, channelName, csvName)
+	out, err := i.query.GetBundle(ctx, pkgName, channelName, csvName)
+	done(err)
+	return out, err
+}
+
+func (i *instrumentedQuery) GetBundleForChannel(ctx context.Context, pkgName string, channelName string) (*api.Bundle, error) {
+	ctx, done := i.before(ctx, "GetBundleForChannel", pkgName, channelName)
+	out, err := i.query.GetBundleForChannel(ctx, pkgName, channelName)
+	done(err)
+	return out, err
+}
+
+func (i *instrumentedQuery) GetBundleForChannelIncludingDeprecated(ctx context.Context, pkgName string, channelName string) (*api.Bundle, error) {
+	ctx, done := i.before(ctx, "GetBundleForChannelIncludingDeprecated", pkgName, channelName)
+	out, err := i.query.GetBundleForChannelIncludingDeprecated(ctx, pkgName, channelName)
+	done(err)
+	return out, err
+}
+
+func (i *instrumentedQuery) GetChannelHeadBundles(ctx context.Context, pkgName string, includeDeprecated bool) ([]*api.Bundle, error) {
+	ctx, done := i.before(ctx, "GetChannelHeadBundles", pkgName, includeDeprecated)
+	out, err := i.query.GetChannelHeadBundles(ctx, pkgName, includeDeprecated)
+	done(err)
+	return out, err
+}
+
+func (i *instrumentedQuery) GetBundleForVersion(ctx context.Context, pkgName, channelName, version string) (*api.Bundle, error) {
+	ctx, done := i.before(ctx, "GetBundleForVersion", pkgName, channelName, version)
+	out, err := i.query.GetBundleForVersion(ctx, pkgName, channelName, version)
+	done(err)
+	return out, err
+}
+
+func (i *instrumentedQuery) GetLatestBundleInRange(ctx context.Context, pkgName, channelName, versionRange string) (*api.Bundle, error) {
+	ctx, done := i.before(ctx, "GetLatestBundleInRange", pkgName, channelName, versionRange)
+	out, err := i.query.GetLatestBundleInRange(ctx, pkgName, channelName, versionRange)
+	done(err)
+	return out, err
+}
+
+func (i *instrumentedQuery) GetChannelEntriesThatReplace(ctx context.Context, name string) ([]*ChannelEntry, error) {
+	ctx, done := i.before(ctx, "GetChannelEntriesThatReplace", name)
+	out, err := i.query.GetChannelEntriesThatReplace(ctx, name)
+	done(err)
+	return out, err
+}
+
+func (i *instrumentedQuery) GetBundleThatReplaces(ctx context.Context, name, pkgName, channelName string) (*api.Bundle, error) {
+	ctx, done := i.before(ctx, "GetBundleThatReplaces", name, pkgName, channelName)
+	out, err := i.query.GetBundleThatReplaces(ctx, name, pkgName, channelName)
+	done(err)
+	return out, err
+}
+
+func (i *instrumentedQuery) GetChannelEntriesThatProvide(ctx context.Context, group, version, kind string) ([]*ChannelEntry, error) {
+	ctx, done := i.before(ctx, "GetChannelEntriesThatProvide", group, version, kind)
+	out, err := i.query.GetChannelEntriesThatProvide(ctx, group, version, kind)
+	done(err)
+	return out, err
+}
+
+func (i *instrumentedQuery) GetChannelEntriesThatRequire(ctx context.Context, group, version, kind string) ([]*ChannelEntry, error) {
+	ctx, done := i.before(ctx, "GetChannelEntriesThatRequire", group, version, kind)
+	out, err := i.query.GetChannelEntriesThatRequire(ctx, group, version, kind)
+	done(err)
+	return out, err
+}
+
+func (i *instrumentedQuery) GetBundlesRequiring(ctx context.Context, group, version, kind string) ([]*api.Bundle, error) {
+	ctx, done := i.before(ctx, "GetBundlesRequiring", group, version, kind)
+	out, err := i.query.GetBundlesRequiring(ctx, group, version, kind)
+	done(err)
+	return out, err
+}
+
+func (i *instrumentedQuery) GetLatestChannelEntriesThatProvide(ctx context.Context, group, version, kind string) ([]*ChannelEntry, error) {
+	ctx, done := i.before(ctx, "GetLatestChannelEntriesThatProvide", group, version, kind)
+	out, err := i.query.GetLatestChannelEntriesThatProvide(ctx, group, version, kind)
+	done(err)
+	return out, err
+}
+
+func (i *instrumentedQuery) GetBundleThatProvides(ctx context.Context, group, version, kind string) (*api.Bundle, error) {
+	ctx, done := i.before(ctx, "GetBundleThatProvides", group, version, kind)
+	out, err := i.query.GetBundleThatProvides(ctx, group, version, kind)
+	done(err)
+	return out, err
+}
+
+func (i *instrumentedQuery) ListImages(ctx context.Context) ([]string, error) {
+	ctx, done := i.before(ctx, "ListImages")
+	out, err := i.query.ListImages(ctx)
+	done(err)
+	return out, err
+}
+
+func (i *instrumentedQuery) GetImagesForBundle(ctx context.Context, bundleName string) ([]string, error) {
+	ctx, done := i.before(ctx, "GetImagesForBundle", bundleName)
+	out, err := i.query.GetImagesForBundle(ctx, bundleName)
+	done(err)
+	return out, err
+}
+
+func (i *instrumentedQuery) GetApisForEntry(ctx context.Context, entryID int64) ([]*api.GroupVersionKind, []*api.GroupVersionKind, error) {
+	ctx, done := i.before(ctx, "GetApisForEntry", entryID)
+	provided, required, err := i.query.GetApisForEntry(ctx, entryID)
+	done(err)
+	return provided, required, err
+}
+
+func (i *instrumentedQuery) GetBundleVersion(ctx context.Context, image string) (string, error) {
+	ctx, done := i.before(ctx, "GetBundleVersion", image)
+	out, err := i.query.GetBundleVersion(ctx, image)
+	done(err)
+	return out, err
+}
+
+func (i *instrumentedQuery) GetBundlePathsForPackage(ctx context.Context, pkgName string) ([]string, error) {
+	ctx, done := i.before(ctx, "GetBundlePathsForPackage", pkgName)
+	out, err := i.query.GetBundlePathsForPackage(ctx, pkgName)
+	done(err)
+	return out, err
+}
+
+func (i *instrumentedQuery) GetBundlesForPackage(ctx context.Context, pkgName string) (map[BundleKey]struct{}, error) {
+	ctx, done := i.before(ctx, "GetBundlesForPackage", pkgName)
+	out, err := i.query.GetBundlesForPackage(ctx, pkgName)
+	done(err)
+	return out, err
+}
+
+func (i *instrumentedQuery) GetDefaultChannelForPackage(ctx context.Context, pkgName string) (string, error) {
+	ctx, done := i.before(ctx, "GetDefaultChannelForPackage", pkgName)
+	out, err := i.query.GetDefaultChannelForPackage(ctx, pkgName)
+	done(err)
+	return out, err
+}
+
+func (i *instrumentedQuery) GetCurrentCSVNameForChannel(ctx context.Context, pkgName, channel string) (string, error) {
+	ctx, done := i.before(ctx, "GetCurrentCSVNameForChannel", pkgName, channel)
+	out, err := i.query.GetCurrentCSVNameForChannel(ctx, pkgName, channel)
+	done(err)
+	return out, err
+}
+
+func (i *instrumentedQuery) ListBundles(ctx context.Context) ([]*api.Bundle, error) {
+	ctx, done := i.before(ctx, "ListBundles")
+	out, err := i.query.ListBundles(ctx)
+	done(err)
+	return out, err
+}
+
+func (i *instrumentedQuery) ListBundlesFiltered(ctx context.Context, filter BundleFilter) ([]*api.Bundle, error) {
+	ctx, done := i.before(ctx, "ListBundlesFiltered", filter)
+	out, err := i.query.ListBundlesFiltered(ctx, filter)
+	done(err)
+	return out, err
+}
+
+func (i *instrumentedQuery) GetDependenciesForBundle(ctx context.Context, name, version, path string) ([]*api.Dependency, error) {
+	ctx, done := i.before(ctx, "GetDependenciesForBundle", name, version, path)
+	out, err := i.query.GetDependenciesForBundle(ctx, name, version, path)
+	done(err)
+	return out, err
+}
+
+func (i *instrumentedQuery) ListPackagesByProvider(ctx context.Context, provider string) ([]string, error) {
+	ctx, done := i.before(ctx, "ListPackagesByProvider", provider)
+	out, err := i.query.ListPackagesByProvider(ctx, provider)
+	done(err)
+	return out, err
+}
+
+func (i *instrumentedQuery) GetOwnershipForBundle(ctx context.Context, bundleName string) (*Ownership, error) {
+	ctx, done := i.before(ctx, "GetOwnershipForBundle", bundleName)
+	out, err := i.query.GetOwnershipForBundle(ctx, bundleName)
+	done(err)
+	return out, err
+}
+
+func (i *instrumentedQuery) GetBundleAttestations(ctx context.Context, bundleName string) ([]*Attestation, error) {
+	ctx, done := i.before(ctx, "GetBundleAttestations", bundleName)
+	out, err := i.query.GetBundleAttestations(ctx, bundleName)
+	done(err)
+	return out, err
+}
+
+func (i *instrumentedQuery) GetBundleDigest(ctx context.Context, image string) (string, error) {
+	ctx, done := i.before(ctx, "GetBundleDigest", image)
+	out, err := i.query.GetBundleDigest(ctx, image)
+	done(err)
+	return out, err
+}
+
+func (i *instrumentedQuery) GetBundleImage(ctx context.Context, bundleName string) (*BundleImage, error) {
+	ctx, done := i.before(ctx, "GetBundleImage", bundleName)
+	out, err := i.query.GetBundleImage(ctx, bundleName)
+	done(err)
+	return out, err
+}
+
+func (i *instrumentedQuery) GetPackageForAlias(ctx context.Context, alias string) (string, error) {
+	ctx, done := i.before(ctx, "GetPackageForAlias", alias)
+	out, err := i.query.GetPackageForAlias(ctx, alias)
+	done(err)
+	return out, err
+}
+
+func (i *instrumentedQuery) GetDisplayMetadataForBundle(ctx context.Context, bundleName, locale string) (*DisplayMetadata, error) {
+	ctx, done := i.before(ctx, "GetDisplayMetadataForBundle", bundleName, locale)
+	out, err := i.query.GetDisplayMetadataForBundle(ctx, bundleName, locale)
+	done(err)
+	return out, err
+}
+
+func (i *instrumentedQuery) GetChannelEntriesForPlatformVersion(ctx context.Context, packageName, platformVersion string) ([]ChannelEntryAnnotated, error) {
+	ctx, done := i.before(ctx, "GetChannelEntriesForPlatformVersion", packageName, platformVersion)
+	out, err := i.query.GetChannelEntriesForPlatformVersion(ctx, packageName, platformVersion)
+	done(err)
+	return out, err
+}
+
+func (i *instrumentedQuery) GetPackageID(ctx context.Context, name string) (string, error) {
+	ctx, done := i.before(ctx, "GetPackageID", name)
+	out, err := i.query.GetPackageID(ctx, name)
+	done(err)
+	return out, err
+}
+
+func (i *instrumentedQuery) GetBundleID(ctx context.Context, csvName string) (string, error) {
+	ctx, done := i.before(ctx, "GetBundleID", csvName)
+	out, err := i.query.GetBundleID(ctx, csvName)
+	done(err)
+	return out, err
+}
+
+func (i *instrumentedQuery) GetEntriesByBundleImage(ctx context.Context, imageRefOrDigest string) ([]ChannelEntryAnnotated, error) {
+	ctx, done := i.before(ctx, "GetEntriesByBundleImage", imageRefOrDigest)
+	out, err := i.query.GetEntriesByBundleImage(ctx, imageRefOrDigest)
+	done(err)
+	return out, err
+}
+
+func (i *instrumentedQuery) GetPresentBundles(ctx context.Context, bundles []string) ([]string, error) {
+	ctx, done := i.before(ctx, "GetPresentBundles", len(bundles))
+	out, err := i.query.GetPresentBundles(ctx, bundles)
+	done(err)
+	return out, err
+}
+
+func (i *instrumentedQuery) GetPackageStats(ctx context.Context, packages []string) ([]*PackageStats, error) {
+	ctx, done := i.before(ctx, "GetPackageStats", packages)
+	out, err := i.query.GetPackageStats(ctx, packages)
+	done(err)
+	return out, err
+}
+
+func (i *instrumentedQuery) GetBundleStats(ctx context.Context, packages []string, limit int) ([]*BundleStats, error) {
+	ctx, done := i.before(ctx, "GetBundleStats", packages, limit)
+	out, err := i.query.GetBundleStats(ctx, packages, limit)
+	done(err)
+	return out, err
+}
+
+func (i *instrumentedQuery) GetPackagesForBundle(ctx context.Context, csvName string) ([]string, error) {
+	ctx, done := i.before(ctx, "GetPackagesForBundle", csvName)
+	out, err := i.query.GetPackagesForBundle(ctx, csvName)
+	done(err)
+	return out, err
+}
+
+func (i *instrumentedQuery) GetDefaultChannelHeads(ctx context.Context, packages []string) ([]*BundleSummary, error) {
+	ctx, done := i.before(ctx, "GetDefaultChannelHeads", len(packages))
+	out, err := i.query.GetDefaultChannelHeads(ctx, packages)
+	done(err)
+	return out, err
+}
+
+func (i *instrumentedQuery) SearchBundles(ctx context.Context, query string, packages []string) ([]*BundleSummary, error) {
+	ctx, done := i.before(ctx, "SearchBundles", query, len(packages))
+	out, err := i.query.SearchBundles(ctx, query, packages)
+	done(err)
+	return out, err
+}
+
+func (i *instrumentedQuery) GetBundleSummary(ctx context.Context, pkgName, channelName, csvName string) (*BundleSummary, error) {
+	ctx, done := i.before(ctx, "GetBundleSummary", pkgName, channelName, csvName)
+	out, err := i.query.GetBundleSummary(ctx, pkgName, channelName, csvName)
+	done(err)
+	return out, err
+}
+
+func (i *instrumentedQuery) ListBundleSummaries(ctx context.Context) ([]*BundleSummary, error) {
+	ctx, done := i.before(ctx, "ListBundleSummaries")
+	out, err := i.query.ListBundleSummaries(ctx)
+	done(err)
+	return out, err
+}
+
+func (i *instrumentedQuery) ListBundleSummariesFiltered(ctx context.Context, filter BundleFilter) ([]*BundleSummary, error) {
+	ctx, done := i.before(ctx, "ListBundleSummariesFiltered", filter)
+	out, err := i.query.ListBundleSummariesFiltered(ctx, filter)
+	done(err)
+	return out, err
+}
+
+func (i *instrumentedQuery) GetUpgradeGraph(ctx context.Context, pkgName string) (*UpgradeGraph, error) {
+	ctx, done := i.before(ctx, "GetUpgradeGraph", pkgName)
+	out, err := i.query.GetUpgradeGraph(ctx, pkgName)
+	done(err)
+	return out, err
+}
@@ -0,0 +1,61 @@
+package registry
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type spanKey struct{}
+
+// packageQuery only knows about the etcd package, and checks that the context passed by the hooks reaches it
+type packageQuery struct {
+	EmptyQuery
+	t *testing.T
+}
+
+func (q packageQuery) GetPackage(ctx context.Context, name string) (*PackageManifest, error) {
+	require.Equal(q.t, "outer/inner", ctx.Value(spanKey{}))
+	if name != "etcd" {
+		return nil, ErrPackageNotInDatabase
+	}
+	return &PackageManifest{PackageName: name}, nil
+}
+
+func TestInstrumentQuery(t *testing.T) {
+	var order []string
+	span := func(name string) QueryHooks {
+		return QueryHooks{
+			Before: func(ctx context.Context, call QueryCall) context.Context {
+				order = append(order, "before "+name)
+				current := name
+				if parent, ok := ctx.Value(spanKey{}).(string); ok {
+					current = parent + "/" + name
+				}
+				return context.WithValue(ctx, spanKey{}, current)
+			},
+			After: func(ctx context.Context, call QueryCall, duration time.Duration, err error) {
+				order = append(order, "after "+ctx.Value(spanKey{}).(string))
+				require.Equal(t, "GetPackage", call.Method)
+				require.Len(t, call.Args, 1)
+			},
+		}
+	}
+	stats := NewQueryStats()
+	query := InstrumentQuery(packageQuery{t: t}, span("outer"), span("inner"), stats.Hooks())
+
+	pkg, err := query.GetPackage(context.Background(), "etcd")
+	require.NoError(t, err)
+	require.Equal(t, "etcd", pkg.PackageName)
+	require.Equal(t, []string{"before outer", "before inner", "after outer/inner", "after outer"}, order)
+
+	_, err = query.GetPackage(context.Background(), "missing")
+	require.Equal(t, ErrPackageNotInDatabase, err)
+
+	snapshot := stats.Snapshot()
+	require.Len(t, snapshot, 1)
+	require.Equal(t, int64(2), snapshot["GetPackage"].Calls)
+	require.Equal(t, int64(1), snapshot["GetPackage"].Errors)
+}
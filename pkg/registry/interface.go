@@ -2,6 +2,7 @@ package registry
 
 import (
 	"context"
+	"time"
 
 	"github.com/operator-framework/operator-registry/pkg/api"
 )
@@ -13,24 +14,74 @@ type Load interface {
 	AddBundlePackageChannels(manifest PackageManifest, bundle *Bundle) error
 	RemovePackage(packageName string) error
 	RemoveStrandedBundles() ([]string, error)
+	// RemoveNonHeadBundles removes every bundle that isn't the head of a channel, and returns their names
+	RemoveNonHeadBundles() ([]string, error)
+	// RemoveBundlesWithoutInstallMode removes every bundle that doesn't support an install mode, and those that could
+	// only be upgraded through one, and returns their names
+	RemoveBundlesWithoutInstallMode(mode string) ([]string, error)
 	DeprecateBundle(path string) error
+	// MarkBundleDeprecated marks the bundle loaded from an image, and every bundle it replaces or skips, deprecated
+	// without removing any of them
+	MarkBundleDeprecated(path string) error
+	AddBundleAttestation(path string, attestation Attestation) error
+	AddChannelOverride(path string, annotated, override ChannelOverride) error
+	AddBundleImageDigest(path, digest string) error
 	ClearNonHeadBundles() error
+	// SetChannelSelector defines a channel by the bundles a selector selects, and expands it into their entries
+	SetChannelSelector(selector ChannelSelector) error
+	// RemoveChannelSelector removes the selector of a channel, leaving the entries it was last expanded into
+	RemoveChannelSelector(packageName, channel string) error
+}
+
+// Tombstoner soft-deletes packages so that their removal can be undone until the tombstone is purged
+type Tombstoner interface {
+	SoftRemovePackage(packageName string) error
+	RestorePackage(packageName string, window time.Duration) error
+	PurgeTombstones(packages []string, olderThan time.Duration) ([]string, error)
+	ListTombstones() ([]string, error)
+}
+
+// Renamer renames packages, keeping each former name as an alias of the new one
+type Renamer interface {
+	RenamePackage(from, to string) error
 }
 
 type Query interface {
 	ListTables(ctx context.Context) ([]string, error)
 	ListPackages(ctx context.Context) ([]string, error)
+	// ListPackagesPaged returns, in order, up to limit names of the packages that sort after the given name, or every
+	// one of them if limit isn't positive. Passing the last name of each page as the next after pages through all
+	// packages.
+	ListPackagesPaged(ctx context.Context, after string, limit int) ([]string, error)
 	GetPackage(ctx context.Context, name string) (*PackageManifest, error)
+	// ListChannels returns every channel of a package, ordered by name, with the name of the bundle at its head.
+	// Channels whose head is deprecated are listed too, marked Deprecated.
+	ListChannels(ctx context.Context, pkgName string) ([]PackageChannel, error)
 	GetDefaultPackage(ctx context.Context, name string) (string, error)
 	GetChannelEntriesFromPackage(ctx context.Context, packageName string) ([]ChannelEntryAnnotated, error)
 	GetBundle(ctx context.Context, pkgName, channelName, csvName string) (*api.Bundle, error)
+	// Get the bundle at the head of a package/channel, unless it is deprecated
 	GetBundleForChannel(ctx context.Context, pkgName string, channelName string) (*api.Bundle, error)
-	// Get all channel entries that say they replace this one
+	// Get the bundle at the head of a package/channel, even if it is deprecated
+	GetBundleForChannelIncludingDeprecated(ctx context.Context, pkgName string, channelName string) (*api.Bundle, error)
+	// Get the bundle at the head of every channel of a package, ordered by channel. Channels whose head is deprecated
+	// are left out unless includeDeprecated is set.
+	GetChannelHeadBundles(ctx context.Context, pkgName string, includeDeprecated bool) ([]*api.Bundle, error)
+	// Get the bundle of a version in a package/channel
+	GetBundleForVersion(ctx context.Context, pkgName, channelName, version string) (*api.Bundle, error)
+	// Get the bundle with the highest version in a package/channel that satisfies a semver range
+	GetLatestBundleInRange(ctx context.Context, pkgName, channelName, versionRange string) (*api.Bundle, error)
+	// Get all channel entries that replace or skip this one, or whose skipRange contains its version in a channel
+	// they share with it
 	GetChannelEntriesThatReplace(ctx context.Context, name string) (entries []*ChannelEntry, err error)
-	// Get the bundle in a package/channel that replace this one
+	// Get the bundle in a package/channel that replaces or skips this one, or whose skipRange contains its version
 	GetBundleThatReplaces(ctx context.Context, name, pkgName, channelName string) (*api.Bundle, error)
 	// Get all channel entries that provide an api
 	GetChannelEntriesThatProvide(ctx context.Context, group, version, kind string) (entries []*ChannelEntry, err error)
+	// Get all channel entries that require an api
+	GetChannelEntriesThatRequire(ctx context.Context, group, version, kind string) (entries []*ChannelEntry, err error)
+	// Get the bundles that require an api, sorted by name
+	GetBundlesRequiring(ctx context.Context, group, version, kind string) ([]*api.Bundle, error)
 	// Get latest channel entries that provide an api
 	GetLatestChannelEntriesThatProvide(ctx context.Context, group, version, kind string) (entries []*ChannelEntry, err error)
 	// Get the the latest bundle that provides the API in a default channel
@@ -49,14 +100,58 @@ type Query interface {
 	GetBundlesForPackage(ctx context.Context, pkgName string) (map[BundleKey]struct{}, error)
 	// Get DefaultChannel for Package
 	GetDefaultChannelForPackage(ctx context.Context, pkgName string) (string, error)
-	// List channels for package
-	ListChannels(ctx context.Context, pkgName string) ([]string, error)
 	// Get CurrentCSV name for channel and package
 	GetCurrentCSVNameForChannel(ctx context.Context, pkgName, channel string) (string, error)
 	// List all available bundles in the database
 	ListBundles(ctx context.Context) (bundles []*api.Bundle, err error)
+	// List the bundles in the database that the filter selects
+	ListBundlesFiltered(ctx context.Context, filter BundleFilter) ([]*api.Bundle, error)
 	// Get the list of dependencies for a bundle
 	GetDependenciesForBundle(ctx context.Context, name, version, path string) (dependencies []*api.Dependency, err error)
+	// List all packages that ship a bundle from the given provider
+	ListPackagesByProvider(ctx context.Context, provider string) ([]string, error)
+	// Get the provider, maintainers and links for a bundle
+	GetOwnershipForBundle(ctx context.Context, bundleName string) (*Ownership, error)
+	// Get the verification evidence recorded for a bundle
+	GetBundleAttestations(ctx context.Context, bundleName string) ([]*Attestation, error)
+	// Get the content digest of the bundle loaded from an image, empty if it was loaded without one
+	GetBundleDigest(ctx context.Context, image string) (string, error)
+	// Get the image a bundle was loaded from, with the digest it had when it was pulled
+	GetBundleImage(ctx context.Context, bundleName string) (*BundleImage, error)
+	// Get the current name of a package that was renamed from the given name
+	GetPackageForAlias(ctx context.Context, alias string) (string, error)
+	// Get the display name and description of a bundle in the given locale, falling back to the untranslated values
+	GetDisplayMetadataForBundle(ctx context.Context, bundleName, locale string) (*DisplayMetadata, error)
+	// Get the channel entries of a package whose bundles support the given platform version
+	GetChannelEntriesForPlatformVersion(ctx context.Context, packageName, platformVersion string) ([]ChannelEntryAnnotated, error)
+	// Get the stable id of a package, which it keeps across renames
+	GetPackageID(ctx context.Context, name string) (string, error)
+	// Get the stable id of a bundle, which it keeps across re-publishes
+	GetBundleID(ctx context.Context, csvName string) (string, error)
+	// Get the channel entries of the bundles loaded from an image, given by reference or by digest alone
+	GetEntriesByBundleImage(ctx context.Context, imageRefOrDigest string) ([]ChannelEntryAnnotated, error)
+	// Get the bundles, named by CSV name, image reference or image digest, that are in the database
+	GetPresentBundles(ctx context.Context, bundles []string) ([]string, error)
+	// Get the bundle count, channels and latest version of the given packages, or of every package if none are given
+	GetPackageStats(ctx context.Context, packages []string) ([]*PackageStats, error)
+	// Get the size and object counts of the bundles of the given packages, or of every bundle if none are given, largest
+	// first; at most limit bundles are returned unless it is zero
+	GetBundleStats(ctx context.Context, packages []string, limit int) ([]*BundleStats, error)
+	// Get the packages that have a bundle with the given CSV name in one of their channels, sorted by name
+	GetPackagesForBundle(ctx context.Context, csvName string) ([]string, error)
+	// Get the head of the default channel of the given packages, or of every package if none are given, sorted by package
+	GetDefaultChannelHeads(ctx context.Context, packages []string) ([]*BundleSummary, error)
+	// Get the bundles of the given packages, or of every package if none are given, whose name, display name,
+	// description or keywords contain every word of the query, best matches first
+	SearchBundles(ctx context.Context, query string, packages []string) ([]*BundleSummary, error)
+	// Get the name, version, upgrade graph and apis of a bundle in a channel, without reading its manifests
+	GetBundleSummary(ctx context.Context, pkgName, channelName, csvName string) (*BundleSummary, error)
+	// Get the summary of every bundle in every channel it is in, without reading their manifests
+	ListBundleSummaries(ctx context.Context) ([]*BundleSummary, error)
+	// Get the summary of every bundle the filter selects in every channel it is in, without reading their manifests
+	ListBundleSummariesFiltered(ctx context.Context, filter BundleFilter) ([]*BundleSummary, error)
+	// Get the upgrade graph of every channel of a package, with the replaces, skips and skip range edges between its bundles
+	GetUpgradeGraph(ctx context.Context, pkgName string) (*UpgradeGraph, error)
 }
 
 // GraphLoader generates a graph
@@ -0,0 +1,69 @@
+package registry
+
+import (
+	"strings"
+)
+
+// NormalizeLocale brings a locale into the form used to match translations: lower case, with
+// subtags separated by dashes, so that 'pt_BR' and 'pt-br' both match a 'pt-BR' translation
+func NormalizeLocale(locale string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(locale), "_", "-"))
+}
+
+// localeFallbacks lists the locales to try for a requested locale, most specific first,
+// e.g. 'zh-hant-tw', 'zh-hant', 'zh'
+func localeFallbacks(locale string) []string {
+	locale = NormalizeLocale(locale)
+	if locale == "" {
+		return nil
+	}
+
+	var fallbacks []string
+	for {
+		fallbacks = append(fallbacks, locale)
+		i := strings.LastIndex(locale, "-")
+		if i <= 0 {
+			return fallbacks
+		}
+		locale = locale[:i]
+	}
+}
+
+// LocalizeDisplayMetadata picks the display data to show for the requested locale. Each field is resolved
+// on its own: the exact locale is tried first, then each less specific locale, and the untranslated
+// value from defaults is used when no translation provides the field.
+func LocalizeDisplayMetadata(defaults DisplayMetadata, translations []LocalizedProperty, locale string) DisplayMetadata {
+	byLocale := make(map[string]LocalizedProperty, len(translations))
+	for _, t := range translations {
+		key := NormalizeLocale(t.Locale)
+		if _, ok := byLocale[key]; ok || key == "" {
+			// the first translation of a locale wins
+			continue
+		}
+		byLocale[key] = t
+	}
+
+	out := DisplayMetadata{DisplayName: defaults.DisplayName, Description: defaults.Description}
+	var displayNameFound, descriptionFound bool
+	for _, candidate := range localeFallbacks(locale) {
+		t, ok := byLocale[candidate]
+		if !ok {
+			continue
+		}
+		if !displayNameFound && t.DisplayName != "" {
+			out.DisplayName = t.DisplayName
+			displayNameFound = true
+			if out.Locale == "" {
+				out.Locale = t.Locale
+			}
+		}
+		if !descriptionFound && t.Description != "" {
+			out.Description = t.Description
+			descriptionFound = true
+			if out.Locale == "" {
+				out.Locale = t.Locale
+			}
+		}
+	}
+	return out
+}
@@ -0,0 +1,54 @@
+package registry
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLocalizeDisplayMetadata(t *testing.T) {
+	defaults := DisplayMetadata{DisplayName: "etcd", Description: "A distributed key value store"}
+	translations := []LocalizedProperty{
+		{Locale: "de", DisplayName: "etcd (de)", Description: "Ein verteilter Schlüssel-Wert-Speicher"},
+		{Locale: "pt", DisplayName: "etcd (pt)", Description: "Um armazenamento chave-valor distribuído"},
+		{Locale: "pt-BR", DisplayName: "etcd (pt-BR)"},
+		{Locale: "de", DisplayName: "ignored"},
+	}
+
+	tests := []struct {
+		name     string
+		locale   string
+		expected DisplayMetadata
+	}{
+		{
+			name:     "NoLocale",
+			locale:   "",
+			expected: defaults,
+		},
+		{
+			name:     "Exact",
+			locale:   "de",
+			expected: DisplayMetadata{Locale: "de", DisplayName: "etcd (de)", Description: "Ein verteilter Schlüssel-Wert-Speicher"},
+		},
+		{
+			name:     "ParentLocale",
+			locale:   "de-AT",
+			expected: DisplayMetadata{Locale: "de", DisplayName: "etcd (de)", Description: "Ein verteilter Schlüssel-Wert-Speicher"},
+		},
+		{
+			name:     "FieldsFallBackIndependently",
+			locale:   "pt_br",
+			expected: DisplayMetadata{Locale: "pt-BR", DisplayName: "etcd (pt-BR)", Description: "Um armazenamento chave-valor distribuído"},
+		},
+		{
+			name:     "Untranslated",
+			locale:   "fr-FR",
+			expected: defaults,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.expected, LocalizeDisplayMetadata(defaults, translations, tt.locale))
+		})
+	}
+}
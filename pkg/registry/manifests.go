@@ -0,0 +1,119 @@
+package registry
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+)
+
+// manifestExtensions are the extensions of the files manifests are read from, in either format
+var manifestExtensions = map[string]struct{}{
+	".yaml": {},
+	".yml":  {},
+	".json": {},
+}
+
+// IsManifestFile returns true if a file, by its name, holds manifests. Manifests are read from .yaml, .yml and .json
+// files, whatever the format of their content, and hidden files are left out.
+func IsManifestFile(name string) bool {
+	if strings.HasPrefix(name, ".") {
+		return false
+	}
+	_, ok := manifestExtensions[strings.ToLower(filepath.Ext(name))]
+	return ok
+}
+
+// ManifestFiles returns the paths of the manifest files in dir in lexical order. If recursive is set, the manifest
+// files of nested directories are included, except for those of hidden directories. Paths left out by the
+// .indexignore files of dir are skipped.
+func ManifestFiles(dir string, recursive bool) ([]string, error) {
+	return NewIndexIgnore(dir).ManifestFiles(dir, recursive)
+}
+
+// ManifestFiles returns the paths of the manifest files in dir, which is in the tree of the root, that aren't ignored
+func (i *IndexIgnore) ManifestFiles(dir string, recursive bool) ([]string, error) {
+	var files []string
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() && path != dir && (!recursive || strings.HasPrefix(info.Name(), ".")) {
+			return filepath.SkipDir
+		}
+
+		ignored, err := i.Ignored(path, info.IsDir())
+		if err != nil {
+			return err
+		}
+		if ignored {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if !info.IsDir() && IsManifestFile(info.Name()) {
+			files = append(files, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// filepath.Walk already visits files in lexical order, this keeps the order explicit
+	sort.Strings(files)
+	return files, nil
+}
+
+// DecodeManifestFile decodes every manifest in a file, which may be a multi-document yaml stream or appended json
+// objects. An empty file holds no manifests.
+func DecodeManifestFile(path string) ([]*unstructured.Unstructured, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read file %s: %s", path, err)
+	}
+	defer f.Close()
+
+	objs, err := DecodeUnstructuredList(f)
+	if err == io.EOF {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not decode contents of file %s: %s", path, err)
+	}
+	return objs, nil
+}
+
+// DecodeManifestDir decodes the manifests of every manifest file in dir, in the order of ManifestFiles. The manifests
+// of files that could be decoded are returned along with the errors of those that couldn't.
+func DecodeManifestDir(dir string, recursive bool) ([]*unstructured.Unstructured, error) {
+	return NewIndexIgnore(dir).DecodeManifestDir(dir, recursive)
+}
+
+// DecodeManifestDir decodes the manifests of every manifest file in dir, which is in the tree of the root, that isn't
+// ignored
+func (i *IndexIgnore) DecodeManifestDir(dir string, recursive bool) ([]*unstructured.Unstructured, error) {
+	files, err := i.ManifestFiles(dir, recursive)
+	if err != nil {
+		return nil, err
+	}
+
+	var objs []*unstructured.Unstructured
+	var errs []error
+	for _, file := range files {
+		fileObjs, err := DecodeManifestFile(file)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		objs = append(objs, fileObjs...)
+	}
+	return objs, utilerrors.NewAggregate(errs)
+}
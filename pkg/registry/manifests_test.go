@@ -0,0 +1,57 @@
+package registry
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsManifestFile(t *testing.T) {
+	for name, expected := range map[string]bool{
+		"csv.yaml":     true,
+		"crd.yml":      true,
+		"package.json": true,
+		"CSV.YAML":     true,
+		".hidden.yaml": false,
+		"README.md":    false,
+		"yaml":         false,
+	} {
+		require.Equal(t, expected, IsManifestFile(name), name)
+	}
+}
+
+func TestManifestFiles(t *testing.T) {
+	const dir = "./testdata/mixed-manifests"
+
+	files, err := ManifestFiles(dir, false)
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		filepath.Join(dir, "configmap.yml"),
+		filepath.Join(dir, "empty.yaml"),
+		filepath.Join(dir, "service.JSON"),
+	}, files)
+
+	files, err = ManifestFiles(dir, true)
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		filepath.Join(dir, "configmap.yml"),
+		filepath.Join(dir, "empty.yaml"),
+		filepath.Join(dir, "nested", "account.yaml"),
+		filepath.Join(dir, "service.JSON"),
+	}, files)
+
+	_, err = ManifestFiles("./testdata/missing", true)
+	require.Error(t, err)
+}
+
+func TestDecodeManifestDir(t *testing.T) {
+	objs, err := DecodeManifestDir("./testdata/mixed-manifests", true)
+	require.NoError(t, err)
+
+	var names []string
+	for _, obj := range objs {
+		names = append(names, obj.GetKind()+"/"+obj.GetName())
+	}
+	require.Equal(t, []string{"ConfigMap/first", "ConfigMap/second", "ServiceAccount/nested", "Service/service"}, names)
+}
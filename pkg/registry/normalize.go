@@ -0,0 +1,47 @@
+package registry
+
+import (
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// serverSetMetadataFields are the metadata fields the API server populates, which manifests exported from a cluster
+// carry but which mean nothing in a bundle
+var serverSetMetadataFields = []string{
+	"creationTimestamp",
+	"deletionGracePeriodSeconds",
+	"deletionTimestamp",
+	"generation",
+	"managedFields",
+	"resourceVersion",
+	"selfLink",
+	"uid",
+}
+
+// lastAppliedConfigAnnotation is the copy of a manifest kubectl apply records on the object, which repeats the
+// manifest it is in
+const lastAppliedConfigAnnotation = "kubectl.kubernetes.io/last-applied-configuration"
+
+// NormalizeManifest drops the status of a manifest and the fields the API server sets on it, so that manifests with
+// the same content are stored, hashed and diffed the same way however they were produced. Keys need no reordering,
+// as manifests are always written as JSON with their keys sorted.
+func NormalizeManifest(obj *unstructured.Unstructured) {
+	if obj == nil || obj.Object == nil {
+		return
+	}
+	unstructured.RemoveNestedField(obj.Object, "status")
+
+	for _, field := range serverSetMetadataFields {
+		unstructured.RemoveNestedField(obj.Object, "metadata", field)
+	}
+
+	annotations, found, err := unstructured.NestedMap(obj.Object, "metadata", "annotations")
+	if err != nil || !found {
+		return
+	}
+	delete(annotations, lastAppliedConfigAnnotation)
+	if len(annotations) == 0 {
+		unstructured.RemoveNestedField(obj.Object, "metadata", "annotations")
+		return
+	}
+	unstructured.SetNestedMap(obj.Object, annotations, "metadata", "annotations")
+}
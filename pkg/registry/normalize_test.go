@@ -0,0 +1,81 @@
+package registry
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestNormalizeManifest(t *testing.T) {
+	exported := `{
+	"apiVersion": "v1",
+	"kind": "ConfigMap",
+	"metadata": {
+		"name": "settings",
+		"namespace": "default",
+		"creationTimestamp": "2020-06-01T00:00:00Z",
+		"generation": 3,
+		"resourceVersion": "12345",
+		"selfLink": "/api/v1/namespaces/default/configmaps/settings",
+		"uid": "5f1e6c1a-0000-0000-0000-000000000000",
+		"managedFields": [{"manager": "kubectl", "operation": "Apply"}],
+		"annotations": {
+			"kubectl.kubernetes.io/last-applied-configuration": "{}"
+		},
+		"labels": {"app": "settings"}
+	},
+	"data": {"key": "value"},
+	"status": {"phase": "Active"}
+}`
+	authored := `{
+	"kind": "ConfigMap",
+	"apiVersion": "v1",
+	"metadata": {
+		"labels": {"app": "settings"},
+		"namespace": "default",
+		"name": "settings",
+		"creationTimestamp": null
+	},
+	"data": {"key": "value"}
+}`
+
+	a, err := NewBundleFromStrings("settings", "pkg", []string{"stable"}, []string{exported})
+	require.NoError(t, err)
+	b, err := NewBundleFromStrings("settings", "pkg", []string{"stable"}, []string{authored})
+	require.NoError(t, err)
+
+	expected := map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "ConfigMap",
+		"metadata": map[string]interface{}{
+			"name":      "settings",
+			"namespace": "default",
+			"labels":    map[string]interface{}{"app": "settings"},
+		},
+		"data": map[string]interface{}{"key": "value"},
+	}
+	require.Equal(t, expected, a.Objects[0].Object)
+	require.Equal(t, expected, b.Objects[0].Object)
+
+	aDigest, err := a.Digest()
+	require.NoError(t, err)
+	bDigest, err := b.Digest()
+	require.NoError(t, err)
+	require.Equal(t, aDigest, bDigest)
+
+	// annotations other than the last applied configuration are kept
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": map[string]interface{}{
+				"kubectl.kubernetes.io/last-applied-configuration": "{}",
+				"olm.skipRange": "<1.0.0",
+			},
+		},
+	}}
+	NormalizeManifest(obj)
+	require.Equal(t, map[string]string{"olm.skipRange": "<1.0.0"}, obj.GetAnnotations())
+
+	NormalizeManifest(nil)
+	NormalizeManifest(&unstructured.Unstructured{})
+}
@@ -0,0 +1,137 @@
+package registry
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/blang/semver"
+)
+
+const (
+	// OpenShiftVersionsAnnotation lists the OpenShift versions a bundle supports, e.g. 'v4.5', '=v4.6' or 'v4.5-v4.7'
+	OpenShiftVersionsAnnotation = "com.redhat.openshift.versions"
+)
+
+// PlatformVersionsProperty is the range of platform versions a bundle supports
+type PlatformVersionsProperty struct {
+	// The supported platform versions in semver range format, e.g. '>=4.5.0 <4.8.0'
+	Range string `json:"range" yaml:"range"`
+}
+
+// Validate will validate the platform versions property and return error(s)
+func (p *PlatformVersionsProperty) Validate() []error {
+	errs := []error{}
+	if p.Range == "" {
+		errs = append(errs, fmt.Errorf("Platform version range is empty"))
+	} else if _, err := semver.ParseRange(p.Range); err != nil {
+		errs = append(errs, fmt.Errorf("Invalid semver format range: %s", err))
+	}
+	return errs
+}
+
+// Supports returns true if the platform version is within the range
+func (p *PlatformVersionsProperty) Supports(platformVersion semver.Version) (bool, error) {
+	r, err := semver.ParseRange(p.Range)
+	if err != nil {
+		return false, err
+	}
+	return r(platformVersion), nil
+}
+
+// ParsePlatformVersion parses a platform version, allowing the short forms that platforms report such as 'v4.6' or '4.6'
+func ParsePlatformVersion(version string) (semver.Version, error) {
+	v, err := semver.ParseTolerant(version)
+	if err != nil {
+		return semver.Version{}, fmt.Errorf("invalid platform version %s: %s", version, err)
+	}
+	return v, nil
+}
+
+// OpenShiftVersionsToRange converts the value of the OpenShift versions annotation to a semver range.
+// A single version ('v4.5') is a lower bound, a version prefixed with '=' ('=v4.6') is that minor version only,
+// a span ('v4.5-v4.7') includes both ends, and a comma separated list of versions is their union.
+func OpenShiftVersionsToRange(versions string) (string, error) {
+	versions = strings.TrimSpace(versions)
+	if versions == "" {
+		return "", fmt.Errorf("no openshift versions given")
+	}
+
+	if strings.Contains(versions, ",") {
+		var ranges []string
+		for _, v := range strings.Split(versions, ",") {
+			min, err := minorVersion(v)
+			if err != nil {
+				return "", err
+			}
+			ranges = append(ranges, minorRange(min, min))
+		}
+		return strings.Join(ranges, " || "), nil
+	}
+
+	if strings.HasPrefix(versions, "=") {
+		min, err := minorVersion(strings.TrimPrefix(versions, "="))
+		if err != nil {
+			return "", err
+		}
+		return minorRange(min, min), nil
+	}
+
+	if parts := strings.SplitN(versions, "-", 2); len(parts) == 2 {
+		min, err := minorVersion(parts[0])
+		if err != nil {
+			return "", err
+		}
+		max, err := minorVersion(parts[1])
+		if err != nil {
+			return "", err
+		}
+		if max.LT(min) {
+			return "", fmt.Errorf("invalid openshift versions %s: upper bound is below lower bound", versions)
+		}
+		return minorRange(min, max), nil
+	}
+
+	min, err := minorVersion(versions)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf(">=%s", min), nil
+}
+
+// minorVersion parses a version such as 'v4.6' down to its minor version, 4.6.0
+func minorVersion(version string) (semver.Version, error) {
+	v, err := ParsePlatformVersion(strings.TrimSpace(version))
+	if err != nil {
+		return semver.Version{}, err
+	}
+	return semver.Version{Major: v.Major, Minor: v.Minor}, nil
+}
+
+// minorRange is the range that covers every release of the minor versions from min to max
+func minorRange(min, max semver.Version) string {
+	return fmt.Sprintf(">=%s <%s", min, semver.Version{Major: max.Major, Minor: max.Minor + 1})
+}
+
+// ChannelHeads returns the head of each channel among the given entries, keyed by channel name. The head is the
+// entry that no other entry in its channel replaces, so when entries that don't support a platform version are
+// left out, the head is the newest bundle that does.
+func ChannelHeads(entries []ChannelEntryAnnotated) map[string]string {
+	replaced := map[string]map[string]struct{}{}
+	for _, entry := range entries {
+		if _, ok := replaced[entry.ChannelName]; !ok {
+			replaced[entry.ChannelName] = map[string]struct{}{}
+		}
+		if entry.Replaces != "" {
+			replaced[entry.ChannelName][entry.Replaces] = struct{}{}
+		}
+	}
+
+	heads := map[string]string{}
+	for _, entry := range entries {
+		if _, ok := replaced[entry.ChannelName][entry.BundleName]; ok {
+			continue
+		}
+		heads[entry.ChannelName] = entry.BundleName
+	}
+	return heads
+}
@@ -0,0 +1,78 @@
+package registry
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpenShiftVersionsToRange(t *testing.T) {
+	tests := []struct {
+		versions  string
+		expected  string
+		supported []string
+		rejected  []string
+		wantErr   bool
+	}{
+		{
+			versions:  "v4.5",
+			expected:  ">=4.5.0",
+			supported: []string{"4.5", "4.5.13", "v4.9"},
+			rejected:  []string{"4.4.30"},
+		},
+		{
+			versions:  "=v4.6",
+			expected:  ">=4.6.0 <4.7.0",
+			supported: []string{"4.6.0", "4.6.42"},
+			rejected:  []string{"4.5", "4.7"},
+		},
+		{
+			versions:  "v4.5-v4.7",
+			expected:  ">=4.5.0 <4.8.0",
+			supported: []string{"4.5", "4.7.9"},
+			rejected:  []string{"4.4", "4.8.0"},
+		},
+		{
+			versions:  "v4.5,v4.7",
+			expected:  ">=4.5.0 <4.6.0 || >=4.7.0 <4.8.0",
+			supported: []string{"4.5.1", "4.7"},
+			rejected:  []string{"4.6"},
+		},
+		{
+			versions: "v4.7-v4.5",
+			wantErr:  true,
+		},
+		{
+			versions: "latest",
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.versions, func(t *testing.T) {
+			r, err := OpenShiftVersionsToRange(tt.versions)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, r)
+
+			prop := PlatformVersionsProperty{Range: r}
+			require.Empty(t, prop.Validate())
+			for _, v := range tt.supported {
+				version, err := ParsePlatformVersion(v)
+				require.NoError(t, err)
+				ok, err := prop.Supports(version)
+				require.NoError(t, err)
+				require.True(t, ok, "expected %s to support %s", r, v)
+			}
+			for _, v := range tt.rejected {
+				version, err := ParsePlatformVersion(v)
+				require.NoError(t, err)
+				ok, err := prop.Supports(version)
+				require.NoError(t, err)
+				require.False(t, ok, "expected %s to reject %s", r, v)
+			}
+		})
+	}
+}
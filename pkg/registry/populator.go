@@ -4,9 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"io/ioutil"
 	"os"
-	"path/filepath"
 	"strings"
 
 	"github.com/sirupsen/logrus"
@@ -27,6 +25,13 @@ type DirectoryPopulator struct {
 	graphLoader GraphLoader
 	querier     Query
 	imageDirMap map[image.Reference]string
+	override    ChannelOverride
+	// csvNameConflicts is what is done with bundles whose CSV name belongs to another package
+	csvNameConflicts ConflictPolicy
+	// versionPolicy, if set, is what the versions of the bundles are checked against
+	versionPolicy *VersionPolicy
+	// skipped are the bundle images the last Populate skipped because they were already loaded
+	skipped []string
 }
 
 func NewDirectoryPopulator(loader Load, graphLoader GraphLoader, querier Query, imageDirMap map[image.Reference]string) *DirectoryPopulator {
@@ -38,7 +43,31 @@ func NewDirectoryPopulator(loader Load, graphLoader GraphLoader, querier Query,
 	}
 }
 
+// OverrideChannels makes the bundles be added with the channels of the override rather than those of their
+// annotations. Each bundle added with an override has it recorded along with the channels of its annotations.
+func (i *DirectoryPopulator) OverrideChannels(override ChannelOverride) {
+	i.override = override
+}
+
+// OnCSVNameConflict sets what is done with bundles whose CSV name already belongs to a bundle of another package,
+// which would make the bundle ambiguous when looked up by name. They are rejected by default.
+func (i *DirectoryPopulator) OnCSVNameConflict(policy ConflictPolicy) {
+	i.csvNameConflicts = policy
+}
+
+// EnforceVersionPolicy makes bundles whose versions break the policy be rejected, along with the bundles added with them
+func (i *DirectoryPopulator) EnforceVersionPolicy(policy *VersionPolicy) {
+	i.versionPolicy = policy
+}
+
+// AlreadyPresent returns the bundle images the last call to Populate skipped because they were already in the
+// database with identical content
+func (i *DirectoryPopulator) AlreadyPresent() []string {
+	return i.skipped
+}
+
 func (i *DirectoryPopulator) Populate(mode Mode) error {
+	i.skipped = nil
 	var errs []error
 	imagesToAdd := make([]*ImageInput, 0)
 	for to, from := range i.imageDirMap {
@@ -47,6 +76,7 @@ func (i *DirectoryPopulator) Populate(mode Mode) error {
 			errs = append(errs, err)
 			continue
 		}
+		imageInput.overrideChannels(i.override)
 
 		imagesToAdd = append(imagesToAdd, imageInput)
 	}
@@ -63,36 +93,105 @@ func (i *DirectoryPopulator) Populate(mode Mode) error {
 	return nil
 }
 
-func (i *DirectoryPopulator) globalSanityCheck(imagesToAdd []*ImageInput) error {
+// checkCSVNameConflicts returns the images whose CSV name doesn't belong to another package, either in the database
+// or among the images being added. Conflicting images are an error, unless the policy is to warn about them.
+func (i *DirectoryPopulator) checkCSVNameConflicts(imagesToAdd []*ImageInput) ([]*ImageInput, error) {
 	var errs []error
-	images := make(map[string]struct{})
+	var remaining []*ImageInput
+	adding := map[string]string{}
 	for _, image := range imagesToAdd {
-		images[image.bundle.BundleImage] = struct{}{}
+		packages, err := i.querier.GetPackagesForBundle(context.TODO(), image.bundle.Name)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if pkg, ok := adding[image.bundle.Name]; ok {
+			packages = append(packages, pkg)
+		}
+
+		var others []string
+		for _, pkg := range packages {
+			if pkg != image.bundle.Package {
+				others = append(others, pkg)
+			}
+		}
+		if len(others) == 0 {
+			adding[image.bundle.Name] = image.bundle.Package
+			remaining = append(remaining, image)
+			continue
+		}
+
+		conflict := CSVNameConflictErr{ErrorString: fmt.Sprintf("Bundle %s of package %s has CSV name %s, which already belongs to package %s", image.bundle.BundleImage, image.bundle.Package, image.bundle.Name, strings.Join(others, ", "))}
+		if i.csvNameConflicts == ConflictPolicyWarn {
+			logrus.WithField("bundle", image.bundle.BundleImage).Warnf("%s, skipping", conflict)
+			continue
+		}
+		errs = append(errs, conflict)
+	}
+
+	return remaining, utilerrors.NewAggregate(errs)
+}
+
+// checkVersions returns an error for every image whose version breaks the version policy, on its own or against the
+// versions of the bundles it replaces and skips, either in the database or among the images being added. Skip ranges
+// aren't checked here, since the bundles they cover are only known once the image is loaded.
+func (i *DirectoryPopulator) checkVersions(imagesToAdd []*ImageInput) error {
+	if i.versionPolicy == nil {
+		return nil
 	}
 
+	var errs []error
+	versions := map[string]map[string]string{}
+	packageVersions := func(pkg string) map[string]string {
+		if v, ok := versions[pkg]; ok {
+			return v
+		}
+		versions[pkg] = map[string]string{}
+		// packages that aren't in the database yet have no bundles to upgrade from
+		bundles, _ := i.querier.GetBundlesForPackage(context.TODO(), pkg)
+		for key := range bundles {
+			versions[pkg][key.CsvName] = key.Version
+		}
+		return versions[pkg]
+	}
 	for _, image := range imagesToAdd {
-		bundlePaths, err := i.querier.GetBundlePathsForPackage(context.TODO(), image.bundle.Package)
+		version, err := image.bundle.Version()
 		if err != nil {
-			// Assume that this means that the bundle is empty
-			// Or that this is the first time the package is loaded.
-			return nil
+			errs = append(errs, err)
+			continue
 		}
-		for _, bundlePath := range bundlePaths {
-			if _, ok := images[bundlePath]; ok {
-				errs = append(errs, BundleImageAlreadyAddedErr{ErrorString: fmt.Sprintf("Bundle %s already exists", image.bundle.BundleImage)})
-				continue
-			}
+		packageVersions(image.bundle.Package)[image.bundle.Name] = version
+	}
+	if len(errs) > 0 {
+		return utilerrors.NewAggregate(errs)
+	}
+
+	for _, image := range imagesToAdd {
+		rejected := func(err error) {
+			errs = append(errs, VersionPolicyErr{ErrorString: fmt.Sprintf("Bundle %s breaks the version policy: %s", image.bundle.BundleImage, err)})
 		}
-		for _, channel := range image.bundle.Channels {
-			bundle, err := i.querier.GetBundle(context.TODO(), image.bundle.Package, channel, image.bundle.csv.GetName())
-			if err != nil {
-				// Assume that if we can not find a bundle for the package, channel and or CSV Name that this is safe to add
+		version := packageVersions(image.bundle.Package)[image.bundle.Name]
+		for _, err := range i.versionPolicy.ValidateVersion(version, image.bundle.Channels...) {
+			rejected(err)
+		}
+
+		replaces, err := image.bundle.Replaces()
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		skips, err := image.bundle.Skips()
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		for _, from := range append([]string{replaces}, skips...) {
+			fromVersion, ok := packageVersions(image.bundle.Package)[from]
+			if from == "" || !ok {
 				continue
 			}
-			if bundle != nil {
-				// raise error that this package + channel + csv combo is already in the db
-				errs = append(errs, PackageVersionAlreadyAddedErr{ErrorString: "Bundle already added that provides package and csv"})
-				break
+			if err := i.versionPolicy.ValidateUpgrade(fromVersion, version); err != nil {
+				rejected(fmt.Errorf("%s: %s", from, err))
 			}
 		}
 	}
@@ -100,12 +199,88 @@ func (i *DirectoryPopulator) globalSanityCheck(imagesToAdd []*ImageInput) error
 	return utilerrors.NewAggregate(errs)
 }
 
+// globalSanityCheck returns the subset of imagesToAdd that still needs to be loaded. Images whose content
+// is identical to a bundle already in the database are dropped, making repeated adds of the same content a no-op,
+// and are listed by AlreadyPresent.
+func (i *DirectoryPopulator) globalSanityCheck(imagesToAdd []*ImageInput) ([]*ImageInput, error) {
+	var errs []error
+	var remaining []*ImageInput
+	for _, image := range imagesToAdd {
+		bundlePaths, err := i.querier.GetBundlePathsForPackage(context.TODO(), image.bundle.Package)
+		if err != nil {
+			// Assume that this means that the bundle is empty
+			// Or that this is the first time the package is loaded.
+			remaining = append(remaining, image)
+			continue
+		}
+
+		present, err := i.alreadyPresent(image, bundlePaths)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if present {
+			logrus.WithField("bundle", image.bundle.BundleImage).Info("bundle already present, skipping")
+			i.skipped = append(i.skipped, image.bundle.BundleImage)
+			continue
+		}
+		remaining = append(remaining, image)
+	}
+
+	return remaining, utilerrors.NewAggregate(errs)
+}
+
+// alreadyPresent returns true if the image is already loaded with identical content, and an error if
+// the image is already loaded with different content or another image provides its package version.
+func (i *DirectoryPopulator) alreadyPresent(image *ImageInput, bundlePaths []string) (bool, error) {
+	for _, bundlePath := range bundlePaths {
+		if bundlePath == image.bundle.BundleImage {
+			if i.sameDigest(image, bundlePath) {
+				return true, nil
+			}
+			return false, BundleImageAlreadyAddedErr{ErrorString: fmt.Sprintf("Bundle %s already exists", image.bundle.BundleImage)}
+		}
+	}
+	for _, channel := range image.bundle.Channels {
+		bundle, err := i.querier.GetBundle(context.TODO(), image.bundle.Package, channel, image.bundle.csv.GetName())
+		if err != nil {
+			// Assume that if we can not find a bundle for the package, channel and or CSV Name that this is safe to add
+			continue
+		}
+		if bundle != nil {
+			// raise error that this package + channel + csv combo is already in the db
+			return false, PackageVersionAlreadyAddedErr{ErrorString: "Bundle already added that provides package and csv"}
+		}
+	}
+	return false, nil
+}
+
+// sameDigest returns true if the bundle loaded from bundlePath has the same content digest as image
+func (i *DirectoryPopulator) sameDigest(image *ImageInput, bundlePath string) bool {
+	existing, err := i.querier.GetBundleDigest(context.TODO(), bundlePath)
+	if err != nil || existing == "" {
+		return false
+	}
+	digest, err := image.bundle.Digest()
+	if err != nil {
+		return false
+	}
+	return digest == existing
+}
+
 func (i *DirectoryPopulator) loadManifests(imagesToAdd []*ImageInput, mode Mode) error {
 	// global sanity checks before insertion
-	err := i.globalSanityCheck(imagesToAdd)
+	imagesToAdd, err := i.checkCSVNameConflicts(imagesToAdd)
 	if err != nil {
 		return err
 	}
+	imagesToAdd, err = i.globalSanityCheck(imagesToAdd)
+	if err != nil {
+		return err
+	}
+	if err := i.checkVersions(imagesToAdd); err != nil {
+		return err
+	}
 
 	switch mode {
 	case ReplacesMode:
@@ -129,6 +304,9 @@ func (i *DirectoryPopulator) loadManifests(imagesToAdd []*ImageInput, mode Mode)
 				if err != nil {
 					return err
 				}
+				if err := i.recordOverride(image); err != nil {
+					return err
+				}
 			}
 		}
 	case SemVerMode:
@@ -137,6 +315,9 @@ func (i *DirectoryPopulator) loadManifests(imagesToAdd []*ImageInput, mode Mode)
 			if err != nil {
 				return err
 			}
+			if err := i.recordOverride(image); err != nil {
+				return err
+			}
 		}
 	case SkipPatchMode:
 		for _, image := range imagesToAdd {
@@ -144,6 +325,9 @@ func (i *DirectoryPopulator) loadManifests(imagesToAdd []*ImageInput, mode Mode)
 			if err != nil {
 				return err
 			}
+			if err := i.recordOverride(image); err != nil {
+				return err
+			}
 		}
 	default:
 		err := fmt.Errorf("Unsupported update mode")
@@ -160,15 +344,19 @@ func (i *DirectoryPopulator) loadManifests(imagesToAdd []*ImageInput, mode Mode)
 	return nil
 }
 
+// recordOverride records the channel override an image was added with, if it was added with one
+func (i *DirectoryPopulator) recordOverride(image *ImageInput) error {
+	if i.override.IsEmpty() {
+		return nil
+	}
+	return i.loader.AddChannelOverride(image.bundle.BundleImage, image.annotated, i.override)
+}
+
 func (i *DirectoryPopulator) loadManifestsReplaces(bundle *Bundle, annotationsFile *AnnotationsFile) error {
 	channels, err := i.querier.ListChannels(context.TODO(), annotationsFile.GetName())
 	existingPackageChannels := map[string]string{}
 	for _, c := range channels {
-		current, err := i.querier.GetCurrentCSVNameForChannel(context.TODO(), annotationsFile.GetName(), c)
-		if err != nil {
-			return err
-		}
-		existingPackageChannels[c] = current
+		existingPackageChannels[c.Name] = c.CurrentCSVName
 	}
 
 	bcsv, err := bundle.ClusterServiceVersion()
@@ -271,48 +459,28 @@ func (i *DirectoryPopulator) loadManifestsSemver(bundle *Bundle, annotations *An
 	return nil
 }
 
-// loadBundle takes the directory that a CSV is in and assumes the rest of the objects in that directory
-// are part of the bundle.
+// loadBundle takes the directory that a CSV is in and assumes the rest of the objects in that directory,
+// including those in nested directories, are part of the bundle.
 func loadBundle(csvName string, dir string) (*Bundle, error) {
 	log := logrus.WithFields(logrus.Fields{"dir": dir, "load": "bundle"})
-	files, err := ioutil.ReadDir(dir)
-	if err != nil {
+	if _, err := os.Stat(dir); err != nil {
 		return nil, err
 	}
+	objs, err := DecodeManifestDir(dir, true)
+	if err != nil {
+		log.WithError(err).Debug("could not decode file contents")
+	}
 
 	bundle := &Bundle{
 		Name: csvName,
 	}
-	for _, f := range files {
-		log = log.WithField("file", f.Name())
-		if f.IsDir() {
-			log.Info("skipping directory")
-			continue
-		}
-
-		if strings.HasPrefix(f.Name(), ".") {
-			log.Info("skipping hidden file")
-			continue
-		}
-
-		log.Info("loading bundle file")
-		var (
-			obj  = &unstructured.Unstructured{}
-			path = filepath.Join(dir, f.Name())
-		)
-		if err = DecodeFile(path, obj); err != nil {
-			log.WithError(err).Debugf("could not decode file contents for %s", path)
-			continue
-		}
-
+	for _, obj := range objs {
 		// Don't include other CSVs in the bundle
 		if obj.GetKind() == "ClusterServiceVersion" && obj.GetName() != csvName {
 			continue
 		}
 
-		if obj.Object != nil {
-			bundle.Add(obj)
-		}
+		bundle.Add(obj)
 	}
 
 	return bundle, nil
@@ -322,37 +490,18 @@ func loadBundle(csvName string, dir string) (*Bundle, error) {
 func (i *ImageInput) findCSV(manifests string) (*unstructured.Unstructured, error) {
 	log := logrus.WithFields(logrus.Fields{"dir": i.from, "find": "csv"})
 
-	files, err := ioutil.ReadDir(manifests)
-	if err != nil {
+	if _, err := os.Stat(manifests); err != nil {
 		return nil, fmt.Errorf("unable to read directory %s: %s", manifests, err)
 	}
+	objs, err := DecodeManifestDir(manifests, true)
+	if err != nil {
+		log.WithError(err).Debug("could not decode file contents")
+	}
 
-	for _, f := range files {
-		log = log.WithField("file", f.Name())
-		if f.IsDir() {
-			log.Info("skipping directory")
-			continue
-		}
-
-		if strings.HasPrefix(f.Name(), ".") {
-			log.Info("skipping hidden file")
-			continue
-		}
-
-		var (
-			obj  = &unstructured.Unstructured{}
-			path = filepath.Join(manifests, f.Name())
-		)
-		if err = DecodeFile(path, obj); err != nil {
-			log.WithError(err).Debugf("could not decode file contents for %s", path)
-			continue
-		}
-
-		if obj.GetKind() != clusterServiceVersionKind {
-			continue
+	for _, obj := range objs {
+		if obj.GetKind() == clusterServiceVersionKind {
+			return obj, nil
 		}
-
-		return obj, nil
 	}
 
 	return nil, fmt.Errorf("no csv found in bundle")
@@ -5,8 +5,10 @@ import (
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"io/ioutil"
 	"math/rand"
 	"os"
+	"path/filepath"
 	"reflect"
 	"strings"
 	"testing"
@@ -133,6 +135,8 @@ func TestQuerierForImage(t *testing.T) {
 		Object:      []string{"{\"apiVersion\":\"apiextensions.k8s.io/v1beta1\",\"kind\":\"CustomResourceDefinition\",\"metadata\":{\"name\":\"etcdbackups.etcd.database.coreos.com\"},\"spec\":{\"group\":\"etcd.database.coreos.com\",\"names\":{\"kind\":\"EtcdBackup\",\"listKind\":\"EtcdBackupList\",\"plural\":\"etcdbackups\",\"singular\":\"etcdbackup\"},\"scope\":\"Namespaced\",\"version\":\"v1beta2\"}}", "{\"apiVersion\":\"apiextensions.k8s.io/v1beta1\",\"kind\":\"CustomResourceDefinition\",\"metadata\":{\"name\":\"etcdclusters.etcd.database.coreos.com\"},\"spec\":{\"group\":\"etcd.database.coreos.com\",\"names\":{\"kind\":\"EtcdCluster\",\"listKind\":\"EtcdClusterList\",\"plural\":\"etcdclusters\",\"shortNames\":[\"etcdclus\",\"etcd\"],\"singular\":\"etcdcluster\"},\"scope\":\"Namespaced\",\"version\":\"v1beta2\"}}", "{\"apiVersion\":\"operators.coreos.com/v1alpha1\",\"kind\":\"ClusterServiceVersion\",\"metadata\":{\"annotations\":{\"alm-examples\":\"[{\\\"apiVersion\\\":\\\"etcd.database.coreos.com/v1beta2\\\",\\\"kind\\\":\\\"EtcdCluster\\\",\\\"metadata\\\":{\\\"name\\\":\\\"example\\\",\\\"namespace\\\":\\\"default\\\"},\\\"spec\\\":{\\\"size\\\":3,\\\"version\\\":\\\"3.2.13\\\"}},{\\\"apiVersion\\\":\\\"etcd.database.coreos.com/v1beta2\\\",\\\"kind\\\":\\\"EtcdRestore\\\",\\\"metadata\\\":{\\\"name\\\":\\\"example-etcd-cluster\\\"},\\\"spec\\\":{\\\"etcdCluster\\\":{\\\"name\\\":\\\"example-etcd-cluster\\\"},\\\"backupStorageType\\\":\\\"S3\\\",\\\"s3\\\":{\\\"path\\\":\\\"\\u003cfull-s3-path\\u003e\\\",\\\"awsSecret\\\":\\\"\\u003caws-secret\\u003e\\\"}}},{\\\"apiVersion\\\":\\\"etcd.database.coreos.com/v1beta2\\\",\\\"kind\\\":\\\"EtcdBackup\\\",\\\"metadata\\\":{\\\"name\\\":\\\"example-etcd-cluster-backup\\\"},\\\"spec\\\":{\\\"etcdEndpoints\\\":[\\\"\\u003cetcd-cluster-endpoints\\u003e\\\"],\\\"storageType\\\":\\\"S3\\\",\\\"s3\\\":{\\\"path\\\":\\\"\\u003cfull-s3-path\\u003e\\\",\\\"awsSecret\\\":\\\"\\u003caws-secret\\u003e\\\"}}}]\",\"tectonic-visibility\":\"ocs\"},\"name\":\"etcdoperator.v0.9.2\",\"namespace\":\"placeholder\"},\"spec\":{\"customresourcedefinitions\":{\"owned\":[{\"description\":\"Represents a cluster of etcd nodes.\",\"displayName\":\"etcd Cluster\",\"kind\":\"EtcdCluster\",\"name\":\"etcdclusters.etcd.database.coreos.com\",\"resources\":[{\"kind\":\"Service\",\"version\":\"v1\"},{\"kind\":\"Pod\",\"version\":\"v1\"}],\"specDescriptors\":[{\"description\":\"The desired number of member Pods for the etcd cluster.\",\"displayName\":\"Size\",\"path\":\"size\",\"x-descriptors\":[\"urn:alm:descriptor:com.tectonic.ui:podCount\"]},{\"description\":\"Limits describes the minimum/maximum amount of compute resources required/allowed\",\"displayName\":\"Resource Requirements\",\"path\":\"pod.resources\",\"x-descriptors\":[\"urn:alm:descriptor:com.tectonic.ui:resourceRequirements\"]}],\"statusDescriptors\":[{\"description\":\"The status of each of the member Pods for the etcd cluster.\",\"displayName\":\"Member Status\",\"path\":\"members\",\"x-descriptors\":[\"urn:alm:descriptor:com.tectonic.ui:podStatuses\"]},{\"description\":\"The service at which the running etcd cluster can be accessed.\",\"displayName\":\"Service\",\"path\":\"serviceName\",\"x-descriptors\":[\"urn:alm:descriptor:io.kubernetes:Service\"]},{\"description\":\"The current size of the etcd cluster.\",\"displayName\":\"Cluster Size\",\"path\":\"size\"},{\"description\":\"The current version of the etcd cluster.\",\"displayName\":\"Current Version\",\"path\":\"currentVersion\"},{\"description\":\"The target version of the etcd cluster, after upgrading.\",\"displayName\":\"Target Version\",\"path\":\"targetVersion\"},{\"description\":\"The current status of the etcd cluster.\",\"displayName\":\"Status\",\"path\":\"phase\",\"x-descriptors\":[\"urn:alm:descriptor:io.kubernetes.phase\"]},{\"description\":\"Explanation for the current status of the cluster.\",\"displayName\":\"Status Details\",\"path\":\"reason\",\"x-descriptors\":[\"urn:alm:descriptor:io.kubernetes.phase:reason\"]}],\"version\":\"v1beta2\"},{\"description\":\"Represents the intent to backup an etcd cluster.\",\"displayName\":\"etcd Backup\",\"kind\":\"EtcdBackup\",\"name\":\"etcdbackups.etcd.database.coreos.com\",\"specDescriptors\":[{\"description\":\"Specifies the endpoints of an etcd cluster.\",\"displayName\":\"etcd Endpoint(s)\",\"path\":\"etcdEndpoints\",\"x-descriptors\":[\"urn:alm:descriptor:etcd:endpoint\"]},{\"description\":\"The full AWS S3 path where the backup is saved.\",\"displayName\":\"S3 Path\",\"path\":\"s3.path\",\"x-descriptors\":[\"urn:alm:descriptor:aws:s3:path\"]},{\"description\":\"The name of the secret object that stores the AWS credential and config files.\",\"displayName\":\"AWS Secret\",\"path\":\"s3.awsSecret\",\"x-descriptors\":[\"urn:alm:descriptor:io.kubernetes:Secret\"]}],\"statusDescriptors\":[{\"description\":\"Indicates if the backup was successful.\",\"displayName\":\"Succeeded\",\"path\":\"succeeded\",\"x-descriptors\":[\"urn:alm:descriptor:text\"]},{\"description\":\"Indicates the reason for any backup related failures.\",\"displayName\":\"Reason\",\"path\":\"reason\",\"x-descriptors\":[\"urn:alm:descriptor:io.kubernetes.phase:reason\"]}],\"version\":\"v1beta2\"},{\"description\":\"Represents the intent to restore an etcd cluster from a backup.\",\"displayName\":\"etcd Restore\",\"kind\":\"EtcdRestore\",\"name\":\"etcdrestores.etcd.database.coreos.com\",\"specDescriptors\":[{\"description\":\"References the EtcdCluster which should be restored,\",\"displayName\":\"etcd Cluster\",\"path\":\"etcdCluster.name\",\"x-descriptors\":[\"urn:alm:descriptor:io.kubernetes:EtcdCluster\",\"urn:alm:descriptor:text\"]},{\"description\":\"The full AWS S3 path where the backup is saved.\",\"displayName\":\"S3 Path\",\"path\":\"s3.path\",\"x-descriptors\":[\"urn:alm:descriptor:aws:s3:path\"]},{\"description\":\"The name of the secret object that stores the AWS credential and config files.\",\"displayName\":\"AWS Secret\",\"path\":\"s3.awsSecret\",\"x-descriptors\":[\"urn:alm:descriptor:io.kubernetes:Secret\"]}],\"statusDescriptors\":[{\"description\":\"Indicates if the restore was successful.\",\"displayName\":\"Succeeded\",\"path\":\"succeeded\",\"x-descriptors\":[\"urn:alm:descriptor:text\"]},{\"description\":\"Indicates the reason for any restore related failures.\",\"displayName\":\"Reason\",\"path\":\"reason\",\"x-descriptors\":[\"urn:alm:descriptor:io.kubernetes.phase:reason\"]}],\"version\":\"v1beta2\"}],\"required\":[{\"description\":\"Represents a cluster of etcd nodes.\",\"displayName\":\"etcd Cluster\",\"kind\":\"EtcdCluster\",\"name\":\"etcdclusters.etcd.database.coreos.com\",\"resources\":[{\"kind\":\"Service\",\"version\":\"v1\"},{\"kind\":\"Pod\",\"version\":\"v1\"}],\"specDescriptors\":[{\"description\":\"The desired number of member Pods for the etcd cluster.\",\"displayName\":\"Size\",\"path\":\"size\",\"x-descriptors\":[\"urn:alm:descriptor:com.tectonic.ui:podCount\"]}],\"version\":\"v1beta2\"}]},\"description\":\"etcd is a distributed key value store that provides a reliable way to store data across a cluster of machines. It’s open-source and available on GitHub. etcd gracefully handles leader elections during network partitions and will tolerate machine failure, including the leader. Your applications can read and write data into etcd.\\nA simple use-case is to store database connection details or feature flags within etcd as key value pairs. These values can be watched, allowing your app to reconfigure itself when they change. Advanced uses take advantage of the consistency guarantees to implement database leader elections or do distributed locking across a cluster of workers.\\n\\n_The etcd Open Cloud Service is Public Alpha. The goal before Beta is to fully implement backup features._\\n\\n### Reading and writing to etcd\\n\\nCommunicate with etcd though its command line utility `etcdctl` or with the API using the automatically generated Kubernetes Service.\\n\\n[Read the complete guide to using the etcd Open Cloud Service](https://coreos.com/tectonic/docs/latest/alm/etcd-ocs.html)\\n\\n### Supported Features\\n\\n\\n**High availability**\\n\\n\\nMultiple instances of etcd are networked together and secured. Individual failures or networking issues are transparently handled to keep your cluster up and running.\\n\\n\\n**Automated updates**\\n\\n\\nRolling out a new etcd version works like all Kubernetes rolling updates. Simply declare the desired version, and the etcd service starts a safe rolling update to the new version automatically.\\n\\n\\n**Backups included**\\n\\n\\nComing soon, the ability to schedule backups to happen on or off cluster.\\n\",\"displayName\":\"etcd\",\"icon\":[{\"base64data\":\"iVBORw0KGgoAAAANSUhEUgAAAOEAAADZCAYAAADWmle6AAAACXBIWXMAAAsTAAALEwEAmpwYAAAAGXRFWHRTb2Z0d2FyZQBBZG9iZSBJbWFnZVJlYWR5ccllPAAAEKlJREFUeNrsndt1GzkShmEev4sTgeiHfRYdgVqbgOgITEVgOgLTEQydwIiKwFQCayoCU6+7DyYjsBiBFyVVz7RkXvqCSxXw/+f04XjGQ6IL+FBVuL769euXgZ7r39f/G9iP0X+u/jWDNZzZdGI/Ftama1jjuV4BwmcNpbAf1Fgu+V/9YRvNAyzT2a59+/GT/3hnn5m16wKWedJrmOCxkYztx9Q+py/+E0GJxtJdReWfz+mxNt+QzS2Mc0AI+HbBBwj9QViKbH5t64DsP2fvmGXUkWU4WgO+Uve2YQzBUGd7r+zH2ZG/tiUQc4QxKwgbwFfVGwwmdLL5wH78aPC/ZBem9jJpCAX3xtcNASSNgJLzUPSQyjB1zQNl8IQJ9MIU4lx2+Jo72ysXYKl1HSzN02BMa/vbZ5xyNJIshJzwf3L0dQhJw4Sih/SFw9Tk8sVeghVPoefaIYCkMZCKbrcP9lnZuk0uPUjGE/KE8JQry7W2tgfuC3vXgvNV+qSQbyFtAtyWk7zWiYevvuUQ9QEQCvJ+5mmu6dTjz1zFHLFj8Eb87MtxaZh/IQFIHom+9vgTWwZxAQjT9X4vtbEVPojwjiV471s00mhAckpwGuCn1HtFtRDaSh6y9zsL+LNBvCG/24ThcxHObdlWc1v+VQJe8LcO0jwtuF8BwnAAUgP9M8JPU2Me+Oh12auPGT6fHuTePE3bLDy+x9pTLnhMn+07TQGh//Bz1iI0c6kvtqInjvPZcYR3KsPVmUsPYt9nFig9SCY8VQNhpPBzn952bbgcsk2EvM89wzh3UEffBbyPqvBUBYQ8ODGPFOLsa7RF096WJ69L+E4EmnpjWu5o4ChlKaRTKT39RMMaVPEQRsz/nIWlDN80chjdJlSd1l0pJCAMVZsniobQVuxceMM9OFoaMd9zqZtjMEYYDW38Drb8Y0DYPLShxn0pvIFuOSxd7YCPet9zk452wsh54FJoeN05hcgSQoG5RR0Qh9Q4E4VvL4wcZq8UACgaRFEQKgSwWrkr5WFnGxiHSutqJGlXjBgIOayhwYBTA0ER0oisIVSUV0AAMT0IASCUO4hRIQSAEECMCCEPwqyQA0JCQBzEGjWNAqHiUVAoXUWbvggOIQCEAOJzxTjoaQ4AIaE64/aZridUsBYUgkhB15oGg1DBIl8IqirYwV6hPSGBSFteMCUBSVXwfYixBmamRubeMyjzMJQBDDowE3OesDD+zwqFoDqiEwXoXJpljB+PvWJGy75BKF1FPxhKygJuqUdYQGlLxNEXkrYyjQ0GbaAwEnUIlLRNvVjQDYUAsJB0HKLE4y0AIpQNgCIhBIhQTgCKhZBBpAN/v6LtQI50JfUgYOnnjmLUFHKhjxbAmdTCaTiBm3ovLPqG2urWAij6im0Nd9aTN9ygLUEt9LgSRnohxUPIKxlGaE+/6Y7znFf0yX+GnkvFFWmarkab2o9PmTeq8sbd2a7DaysXz7i64VeznN4jCQhN9gdDbRiuWrfrsq0mHIrlaq+hlotCtd3Um9u0BYWY8y5D67wccJoZjFca7iUs9VqZcfsZwTd1sbWGG+OcYaTnPAP7rTQVVlM4Sg3oGvB1tmNh0t/HKXZ1jFoIMwCQjtqbhNxUmkGYqgZEDZP11HN/S3gAYRozf0l8C5kKEKUvW0t1IfeWG/5MwgheZTT1E0AEhDkAePQO+Ig2H3DncAkQM4cwUQCD530dU4B5Yvmi2LlDqXfWrxMCcMth51RToRMNUXFnfc2KJ0+Ryl0VNOUwlhh6NoxK5gnViTgQpUG4SqSyt5z3zRJpuKmt3Q1614QaCBPaN6je+2XiFcWAKOXcUfIYKRyL/1lb7pe5VxSxxjQ6hImshqGRt5GWZVKO6q2wHwujfwDtIvaIdexj8Cm8+a68EqMfox6x/voMouZF4dHnEGNeCDMwT6vdNfekH1MafMk4PI06YtqLVGl95aEM9Z5vAeCTOA++YLtoVJRrsqNCaJ6WRmkdYaNec5BT/lcTRMqrhmwfjbpkj55+OKp8IEbU/JLgPJE6Wa3TTe9sHS+ShVD5QIyqIxMEwKh12olC6mHIed5ewEop80CNlfIOADYOT2nd6ZXCop+Ebqchc0JqxKcKASxChycJgUh1rnHA5ow9eTrhqNI7JWiAYYwBGGdpyNLoGw0Pkh96h1BpHihyywtATDM/7Hk2fN9EnH8BgKJCU4ooBkbXFMZJiPbrOyecGl3zgQDQL4hk10IZiOe+5w99Q/gBAEIJgPhJM4QAEEoFREAIAAEiIASAkD8Qt4AQAEIAERAGFlX4CACKAXGVM4ivMwWwCLFAlyeoaa70QePKm5Dlp+/n+ye/5dYgva6YsUaVeMa+tzNFeJtWwc+udbJ0Fg399kLielQJ5Ze61c2+7ytA6EZetiPxZC6tj22yJCv6jUwOyj/zcbqAxOMyAKEbfeHtNa7DtYXptjsk2kJxR+eIeim/tHNofUKYy8DMrQcAKWz6brpvzyIAlpwPhQ49l6b7skJf5Z+YTOYQc4FwLDxvoTDwaygQK+U/kVr+ytSFBG01Q3gnJJR4cNiAhx4HDub8/b5DULXlj6SVZghFiE+LdvE9vo/o8Lp1RmH5hzm0T6wdbZ6n+D6i44zDRc3ln6CpAEJfXiRU45oqLz8gFAThWsh7ughrRibc0QynHgZpNJa/ENJ+loCwu/qOGnFIjYR/n7TfgycULhcQhu6VC+HfF+L3BoAQ4WiZTw1M+FPCnA2gKC6/FAhXgDC+ojQGh3NuWsvfF1L/D5ohlCKtl1j2ldu9a/nPAKFwN56Bst10zCG0CPleXN/zXPgHQZXaZaBgrbzyY5V/mUA+6F0hwtGN9rwu5DVZPuwWqfxdFz1LWbJ2lwKEa+0Qsm4Dl3fp+Pu0lV97PgwIPfSsS+UQhj5Oo+vvFULazRIQyvGEcxPuNLCth2MvFsrKn8UOilAQShkh7TTczYNMoS6OdP47msrPi82lXKGWhCdMZYS0bFy+vcnGAjP1CIfvgbKNA9glecEH9RD6Ol4wRuWyN/G9MHnksS6o/GPf5XcwNSUlHzQhDuAKtWJmkwKElU7lylP5rgIcsquh/FI8YZCDpkJBuE4FQm7Icw8N+SrUGaQKyi8FwiDt1ve5o+Vu7qYHy/psgK8cvh+FTYuO77bhEC7GuaPiys/L1X4IgXDL+e3M5+ovLxBy5VLuIebw1oqcHoPfoaMJUsHays878r8KbDc3xtPx/84gZPBG/JwaufrsY/SRG/OY3//8QMNdsvdZCFtbW6f8pFuf5bflILAlX7O+4fdfugKyFYS8T2zAsXthdG0VurPGKwI06oF5vkBgHWkNp6ry29+lsPZMU3vijnXFNmoclr+6+Ou/FIb8yb30sS8YGjmTqCLyQsi5N/6ZwKs0Yenj68pfPjF6N782Dp2FzV9CTyoSeY8mLK16qGxIkLI8oa1n8tz9juP40DlK0epxYEbojbq+9QfurBeVIlCO9D2396bxiV4lkYQ3hOAFw2pbhqMGISkkQOMcQ9EqhDmGZZdo92JC0YHRNTfoSg+5e0IT+opqCKHoIU+4ztQIgBD1EFNrQAgIpYSil9lDmPHqkROPt+JC6AgPquSuumJmg0YARVCuneDfvPVeJokZ6pIXDkNxQtGzTF9/BQjRG0tQznfb74RwCQghpALBtIQnfK4zhxdyQvVCUeknMIT3hLyY+T5jo0yABqKPQNpUNw/09tGZod5jgCaYFxyYvJcNPkv9eof+I3pnCFEHIETjSM8L9tHZHYCQT9PaZGycU6yg8S4akDnJ+P03L0+t23XGzCLzRgII/Wqa+fv/xlfvmKvMUOcOrlCDdoei1MGdZm6G5VEIfRzzjd4aQs69n699Rx7ewhvCGzr2gmTPs8zNsJOrXt24FbkhhOjCfT4ICA/rPbyhUy94Dks0gJCX1NzCZui9YUd3oei+c257TalFbgg19ILHrlrL2gvWgXAL26EX76gZTNASQnad8Ibwhl284NhgXpB0c+jKhWO3Ms1hP9ihJYB9eMF6qd1BCPk0qA1s+LimFIu7m4nsdQIzPK4VbQ8hYvrnuSH2G9b2ggP78QmWqBdF9Vx8SSY6QYdUW7BTA1schZATyhvY8lHvcRbNUS9YGFy2U+qmzh2YPVc0I7yAOFyHfRpyUwtCSzOdPXMHmz7qDIM0e0V2wZTEk+6Ym6N63eBLp/b5Bts+2cKCSJ/LuoZO3ANSiE5hKAZjnvNSS4931jcw9jpwT0feV/qSJ1pVtCyfHKDkvK8Ejx7pUxGh2xFNSwx8QTi2H9ceC0/nni64MS/5N5dG39pDqvRV+WgGk71c9VFXF9b+xYvOw/d61iv7m3MvEHryhvecwC52jSSx4VIIgwnMNT/UsTxIgpPt3K/ARj15CptwL3Zd/ceDSATj2DGQjbxgWwhdeMMte7zpy5On9vymRm/YxBYljGVjKWF9VJf7I1+sex3wY8w/V1QPTborW/72gkdsRDaZMJBdbdHIC7aCkAu9atlLbtnrzerMnyToDaGwelOnk3/hHSem/ZK7e/t7jeeR20LYBgqa8J80gS8jbwi5F02Uj1u2NYJxap8PLkJfLxA2hIJyvnHX/AfeEPLpBfe0uSFHbnXaea3Qd5d6HcpYZ8L6M7lnFwMQ3MNg+RxUR1+6AshtbsVgfXTEg1sIGax9UND2p7f270wdG3eK9gXVGHdw2k5sOyZv+Nbs39Z308XR9DqWb2J+PwKDhuKHPobfuXf7gnYGHdCs7bhDDadD4entDug7LWNsnRNW4mYqwJ9dk+GGSTPBiA2j0G8RWNM5upZtcG4/3vMfP7KnbK2egx6CCnDPhRn7NgD3cghLIad5WcM2SO38iqHvvMOosyeMpQ5zlVCaaj06GVs9xUbHdiKoqrHWgquFEFMWUEWfXUxJAML23hAHFOctmjZQffKD2pywkhtSGHKNtpitLroscAeE7kCkSsC60vxEl6yMtL9EL5HKGCMszU5bk8gdkklAyEn5FO0yK419rIxBOIqwFMooDE0tHEVYijAUECIshRCGIhxFWIowFJ5QkEYIS5PTJrUwNGlPyN6QQPyKtpuM1E/K5+YJDV/MiA3AaehzqgAm7QnZG9IGYKo8bHnSK7VblLL3hOwNHziPuEGOqE5brrdR6i+atCfckyeWD47HkAkepRGLY/e8A8J0gCwYSNypF08bBm+e6zVz2UL4AshhBUjML/rXLefqC82bcQFhGC9JDwZ1uuu+At0S5gCETYHsV4DUeD9fDN2Zfy5OXaW2zAwQygCzBLJ8cvaW5OXKC1FxfTggFAHmoAJnSiOw2wps9KwRWgJCLaEswaj5NqkLwAYIU4BxqTSXbHXpJdRMPZgAOiAMqABCNGYIEEJutEK5IUAIwYMDQgiCACEEAcJs1Vda7gGqDhCmoiEghAAhBAHCrKXVo2C1DCBMRlp37uMIEECoX7xrX3P5C9QiINSuIcoPAUI0YkAICLNWgfJDh4T9hH7zqYH9+JHAq7zBqWjwhPAicTVCVQJCNF50JghHocahKK0X/ZnQKyEkhSdUpzG8OgQI42qC94EQjsYLRSmH+pbgq73L6bYkeEJ4DYTYmeg1TOBFc/usTTp3V9DdEuXJ2xDCUbXhaXk0/kAYmBvuMB4qkC35E5e5AMKkwSQgyxufyuPy6fMMgAFCSI73LFXU/N8AmEL9X4ABACNSKMHAgb34AAAAAElFTkSuQmCC\",\"mediatype\":\"image/png\"}],\"install\":{\"spec\":{\"deployments\":[{\"name\":\"etcd-operator\",\"spec\":{\"replicas\":1,\"selector\":{\"matchLabels\":{\"name\":\"etcd-operator-alm-owned\"}},\"template\":{\"metadata\":{\"labels\":{\"name\":\"etcd-operator-alm-owned\"},\"name\":\"etcd-operator-alm-owned\"},\"spec\":{\"containers\":[{\"command\":[\"etcd-operator\",\"--create-crd=false\"],\"env\":[{\"name\":\"MY_POD_NAMESPACE\",\"valueFrom\":{\"fieldRef\":{\"fieldPath\":\"metadata.namespace\"}}},{\"name\":\"MY_POD_NAME\",\"valueFrom\":{\"fieldRef\":{\"fieldPath\":\"metadata.name\"}}}],\"image\":\"quay.io/coreos/etcd-operator@sha256:c0301e4686c3ed4206e370b42de5a3bd2229b9fb4906cf85f3f30650424abec2\",\"name\":\"etcd-operator\"},{\"command\":[\"etcd-backup-operator\",\"--create-crd=false\"],\"env\":[{\"name\":\"MY_POD_NAMESPACE\",\"valueFrom\":{\"fieldRef\":{\"fieldPath\":\"metadata.namespace\"}}},{\"name\":\"MY_POD_NAME\",\"valueFrom\":{\"fieldRef\":{\"fieldPath\":\"metadata.name\"}}}],\"image\":\"quay.io/coreos/etcd-operator@sha256:c0301e4686c3ed4206e370b42de5a3bd2229b9fb4906cf85f3f30650424abec2\",\"name\":\"etcd-backup-operator\"},{\"command\":[\"etcd-restore-operator\",\"--create-crd=false\"],\"env\":[{\"name\":\"MY_POD_NAMESPACE\",\"valueFrom\":{\"fieldRef\":{\"fieldPath\":\"metadata.namespace\"}}},{\"name\":\"MY_POD_NAME\",\"valueFrom\":{\"fieldRef\":{\"fieldPath\":\"metadata.name\"}}}],\"image\":\"quay.io/coreos/etcd-operator@sha256:c0301e4686c3ed4206e370b42de5a3bd2229b9fb4906cf85f3f30650424abec2\",\"name\":\"etcd-restore-operator\"}],\"serviceAccountName\":\"etcd-operator\"}}}}],\"permissions\":[{\"rules\":[{\"apiGroups\":[\"etcd.database.coreos.com\"],\"resources\":[\"etcdclusters\",\"etcdbackups\",\"etcdrestores\"],\"verbs\":[\"*\"]},{\"apiGroups\":[\"\"],\"resources\":[\"pods\",\"services\",\"endpoints\",\"persistentvolumeclaims\",\"events\"],\"verbs\":[\"*\"]},{\"apiGroups\":[\"apps\"],\"resources\":[\"deployments\"],\"verbs\":[\"*\"]},{\"apiGroups\":[\"\"],\"resources\":[\"secrets\"],\"verbs\":[\"get\"]}],\"serviceAccountName\":\"etcd-operator\"}]},\"strategy\":\"deployment\"},\"keywords\":[\"etcd\",\"key value\",\"database\",\"coreos\",\"open source\"],\"labels\":{\"alm-owner-etcd\":\"etcdoperator\",\"operated-by\":\"etcdoperator\"},\"links\":[{\"name\":\"Blog\",\"url\":\"https://coreos.com/etcd\"},{\"name\":\"Documentation\",\"url\":\"https://coreos.com/operators/etcd/docs/latest/\"},{\"name\":\"etcd Operator Source Code\",\"url\":\"https://github.com/coreos/etcd-operator\"}],\"maintainers\":[{\"email\":\"support@coreos.com\",\"name\":\"CoreOS, Inc\"}],\"maturity\":\"alpha\",\"provider\":{\"name\":\"CoreOS, Inc\"},\"replaces\":\"etcdoperator.v0.9.0\",\"selector\":{\"matchLabels\":{\"alm-owner-etcd\":\"etcdoperator\",\"operated-by\":\"etcdoperator\"}},\"skips\":[\"etcdoperator.v0.9.1\"],\"version\":\"0.9.2\"}}", "{\"apiVersion\":\"apiextensions.k8s.io/v1beta1\",\"kind\":\"CustomResourceDefinition\",\"metadata\":{\"name\":\"etcdrestores.etcd.database.coreos.com\"},\"spec\":{\"group\":\"etcd.database.coreos.com\",\"names\":{\"kind\":\"EtcdRestore\",\"listKind\":\"EtcdRestoreList\",\"plural\":\"etcdrestores\",\"singular\":\"etcdrestore\"},\"scope\":\"Namespaced\",\"version\":\"v1beta2\"}}"}, BundlePath: "quay.io/test/etcd.0.9.2",
 		Version:   "0.9.2",
 		SkipRange: "",
+		Replaces:  "etcdoperator.v0.9.0",
+		Skips:     []string{"etcdoperator.v0.9.1"},
 		Dependencies: []*api.Dependency{
 			{
 				Type:  "olm.gvk",
@@ -205,6 +209,22 @@ func TestQuerierForImage(t *testing.T) {
 	require.NoError(t, err)
 	EqualBundles(t, *expectedBundle, *etcdBundleByProvides)
 
+	etcdChannelEntriesThatRequire, err := store.GetChannelEntriesThatRequire(context.TODO(), "etcd.database.coreos.com", "v1beta2", "EtcdCluster")
+	require.NoError(t, err)
+	require.ElementsMatch(t, []*registry.ChannelEntry{
+		{"etcd", "alpha", "etcdoperator.v0.9.2", "etcdoperator.v0.9.1"},
+		{"etcd", "alpha", "etcdoperator.v0.9.2", "etcdoperator.v0.9.0"},
+		{"etcd", "stable", "etcdoperator.v0.9.2", "etcdoperator.v0.9.1"},
+		{"etcd", "stable", "etcdoperator.v0.9.2", "etcdoperator.v0.9.0"}}, etcdChannelEntriesThatRequire)
+	_, err = store.GetChannelEntriesThatRequire(context.TODO(), "etcd.database.coreos.com", "v1beta2", "EtcdBackup")
+	require.Error(t, err)
+
+	etcdBundlesRequiring, err := store.GetBundlesRequiring(context.TODO(), "etcd.database.coreos.com", "v1beta2", "EtcdCluster")
+	require.NoError(t, err)
+	require.Len(t, etcdBundlesRequiring, 1)
+	require.Equal(t, "etcdoperator.v0.9.2", etcdBundlesRequiring[0].CsvName)
+	require.Equal(t, "alpha", etcdBundlesRequiring[0].ChannelName)
+
 	expectedEtcdImages := []string{
 		"quay.io/coreos/etcd-operator@sha256:c0301e4686c3ed4206e370b42de5a3bd2229b9fb4906cf85f3f30650424abec2",
 	}
@@ -238,8 +258,12 @@ func TestQuerierForImage(t *testing.T) {
 
 	listChannels, err := store.ListChannels(context.TODO(), "etcd")
 	require.NoError(t, err)
-	expectedListChannels := []string{"alpha", "stable", "beta"}
-	require.ElementsMatch(t, expectedListChannels, listChannels)
+	expectedListChannels := []registry.PackageChannel{
+		{Name: "alpha", CurrentCSVName: "etcdoperator.v0.9.2"},
+		{Name: "beta", CurrentCSVName: "etcdoperator.v0.9.0"},
+		{Name: "stable", CurrentCSVName: "etcdoperator.v0.9.2"},
+	}
+	require.Equal(t, expectedListChannels, listChannels)
 
 	currentCSVName, err := store.GetCurrentCSVNameForChannel(context.TODO(), "etcd", "alpha")
 	require.NoError(t, err)
@@ -257,8 +281,10 @@ func TestImageLoading(t *testing.T) {
 		initImages   []img
 		addImage     img
 		wantPackages []*registry.Package
-		wantErr      bool
-		err          error
+		// wantSkipped are the images that are already present
+		wantSkipped []string
+		wantErr     bool
+		err         error
 	}{
 		{
 			name: "OneChannel/AddBundleToTwoChannels",
@@ -444,10 +470,45 @@ func TestImageLoading(t *testing.T) {
 				},
 			},
 			addImage: img{
-				// Add the same package
+				// Re-adding identical content is a no-op
 				ref: image.SimpleReference("quay.io/prometheus/operator:0.14.0"),
 				dir: "../../bundles/prometheus.0.14.0",
 			},
+			wantPackages: []*registry.Package{
+				{
+					Name:           "prometheus",
+					DefaultChannel: "preview",
+					Channels: map[string]registry.Channel{
+						"preview": {
+							Head: registry.BundleKey{
+								BundlePath: "quay.io/prometheus/operator:0.14.0",
+								Version:    "0.14.0",
+								CsvName:    "prometheusoperator.0.14.0",
+							},
+							Nodes: map[registry.BundleKey]map[registry.BundleKey]struct{}{
+								{BundlePath: "quay.io/prometheus/operator:0.14.0", Version: "0.14.0", CsvName: "prometheusoperator.0.14.0"}: {},
+							},
+						},
+					},
+				},
+			},
+			wantSkipped: []string{"quay.io/prometheus/operator:0.14.0"},
+			wantErr:     false,
+		},
+		{
+			name: "AddChangedBundleAlreadyExists",
+			initImages: []img{
+				{
+					// this is in the "preview" channel
+					ref: image.SimpleReference("quay.io/prometheus/operator:0.14.0"),
+					dir: "../../bundles/prometheus.0.14.0",
+				},
+			},
+			addImage: img{
+				// Add different content under the same image reference
+				ref: image.SimpleReference("quay.io/prometheus/operator:0.14.0"),
+				dir: "../../bundles/prometheus.0.15.0",
+			},
 			wantPackages: []*registry.Package{
 				{
 					Name:           "prometheus",
@@ -500,6 +561,7 @@ func TestImageLoading(t *testing.T) {
 				return
 			}
 			require.NoError(t, err)
+			require.Equal(t, tt.wantSkipped, add.AlreadyPresent())
 
 			for _, p := range tt.wantPackages {
 				graphLoader, err := sqlite.NewSQLGraphLoaderFromDB(db)
@@ -514,6 +576,47 @@ func TestImageLoading(t *testing.T) {
 	}
 }
 
+// unlistedBundlePaths is a querier that can't list the bundle paths of a package, as with bundles loaded without images
+type unlistedBundlePaths struct {
+	registry.Query
+	pkg string
+}
+
+func (q unlistedBundlePaths) GetBundlePathsForPackage(ctx context.Context, pkgName string) ([]string, error) {
+	if pkgName == q.pkg {
+		return nil, fmt.Errorf("Index malformed: cannot find paths to bundle images")
+	}
+	return q.Query.GetBundlePathsForPackage(ctx, pkgName)
+}
+
+func TestAlreadyPresentWithUnlistedPackage(t *testing.T) {
+	db, cleanup := CreateTestDb(t)
+	defer cleanup()
+	load, err := sqlite.NewSQLLiteLoader(db)
+	require.NoError(t, err)
+	require.NoError(t, load.Migrate(context.TODO()))
+	query := sqlite.NewSQLLiteQuerierFromDb(db)
+	graphLoader, err := sqlite.NewSQLGraphLoaderFromDB(db)
+	require.NoError(t, err)
+
+	require.NoError(t, registry.NewDirectoryPopulator(load, graphLoader, query, map[image.Reference]string{
+		image.SimpleReference("quay.io/prometheus/operator:0.14.0"): "../../bundles/prometheus.0.14.0",
+	}).Populate(registry.ReplacesMode))
+
+	// a package whose bundle paths can't be listed doesn't make the bundle already present be added again
+	add := registry.NewDirectoryPopulator(load, graphLoader, unlistedBundlePaths{Query: query, pkg: "etcd"}, map[image.Reference]string{
+		image.SimpleReference("quay.io/prometheus/operator:0.14.0"): "../../bundles/prometheus.0.14.0",
+		image.SimpleReference("quay.io/test/etcd.0.9.0"):            "../../bundles/etcd.0.9.0",
+	})
+	require.NoError(t, add.Populate(registry.ReplacesMode))
+	require.Equal(t, []string{"quay.io/prometheus/operator:0.14.0"}, add.AlreadyPresent())
+
+	packages, err := query.ListPackages(context.TODO())
+	require.NoError(t, err)
+	require.ElementsMatch(t, []string{"etcd", "prometheus"}, packages)
+	CheckInvariants(t, db)
+}
+
 func checkAggErr(aggErr, wantErr error) bool {
 	if a, ok := aggErr.(utilerrors.Aggregate); ok {
 		for _, e := range a.Errors() {
@@ -630,6 +733,181 @@ func TestListBundles(t *testing.T) {
 	require.ElementsMatch(t, expectedDependencies, dependencies)
 }
 
+func TestOverrideChannels(t *testing.T) {
+	logrus.SetLevel(logrus.DebugLevel)
+	db, cleanup := CreateTestDb(t)
+	defer cleanup()
+
+	load, err := sqlite.NewSQLLiteLoader(db)
+	require.NoError(t, err)
+	require.NoError(t, load.Migrate(context.TODO()))
+	query := sqlite.NewSQLLiteQuerierFromDb(db)
+	graphLoader, err := sqlite.NewSQLGraphLoaderFromDB(db)
+	require.NoError(t, err)
+
+	// the annotations of the bundle put it in alpha, stable and beta, with stable as the default
+	populator := registry.NewDirectoryPopulator(load, graphLoader, query, map[image.Reference]string{
+		image.SimpleReference("quay.io/test/etcd.0.9.0"): "../../bundles/etcd.0.9.0",
+	})
+	populator.OverrideChannels(registry.ChannelOverride{Channels: []string{"alpha", "candidate"}, DefaultChannel: "candidate"})
+	require.NoError(t, populator.Populate(registry.ReplacesMode))
+
+	etcdPackage, err := query.GetPackage(context.TODO(), "etcd")
+	require.NoError(t, err)
+	require.Equal(t, "candidate", etcdPackage.DefaultChannelName)
+	require.ElementsMatch(t, []registry.PackageChannel{
+		{Name: "alpha", CurrentCSVName: "etcdoperator.v0.9.0"},
+		{Name: "candidate", CurrentCSVName: "etcdoperator.v0.9.0"},
+	}, etcdPackage.Channels)
+
+	var annotatedChannels, annotatedDefault, channels, defaultChannel string
+	require.NoError(t, db.QueryRow(`SELECT annotated_channels, annotated_default_channel, channels, default_channel FROM channel_override WHERE operatorbundle_path = ?`, "quay.io/test/etcd.0.9.0").Scan(&annotatedChannels, &annotatedDefault, &channels, &defaultChannel))
+	require.Equal(t, "alpha,stable,beta", annotatedChannels)
+	require.Equal(t, "stable", annotatedDefault)
+	require.Equal(t, "alpha,candidate", channels)
+	require.Equal(t, "candidate", defaultChannel)
+}
+
+func TestCSVNameConflicts(t *testing.T) {
+	// a copy of the etcd 0.9.0 bundle, with the same CSV name, in another package
+	fork, err := ioutil.TempDir("", "etcd-fork-")
+	require.NoError(t, err)
+	defer os.RemoveAll(fork)
+	for _, dir := range []string{"manifests", "metadata"} {
+		require.NoError(t, os.Mkdir(filepath.Join(fork, dir), 0755))
+	}
+	manifests, err := ioutil.ReadDir("../../bundles/etcd.0.9.0/manifests")
+	require.NoError(t, err)
+	for _, f := range manifests {
+		content, err := ioutil.ReadFile(filepath.Join("../../bundles/etcd.0.9.0/manifests", f.Name()))
+		require.NoError(t, err)
+		require.NoError(t, ioutil.WriteFile(filepath.Join(fork, "manifests", f.Name()), content, 0644))
+	}
+	annotations, err := ioutil.ReadFile("../../bundles/etcd.0.9.0/metadata/annotations.yaml")
+	require.NoError(t, err)
+	annotations = []byte(strings.Replace(string(annotations), `package.v1: "etcd"`, `package.v1: "etcd-fork"`, 1))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(fork, "metadata", "annotations.yaml"), annotations, 0644))
+
+	tests := []struct {
+		name    string
+		policy  registry.ConflictPolicy
+		wantErr bool
+	}{
+		{name: "RejectedByDefault", wantErr: true},
+		{name: "Rejected", policy: registry.ConflictPolicyReject, wantErr: true},
+		{name: "SkippedWithWarning", policy: registry.ConflictPolicyWarn},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, cleanup := CreateTestDb(t)
+			defer cleanup()
+			load, err := sqlite.NewSQLLiteLoader(db)
+			require.NoError(t, err)
+			require.NoError(t, load.Migrate(context.TODO()))
+			query := sqlite.NewSQLLiteQuerierFromDb(db)
+			graphLoader, err := sqlite.NewSQLGraphLoaderFromDB(db)
+			require.NoError(t, err)
+
+			require.NoError(t, registry.NewDirectoryPopulator(load, graphLoader, query, map[image.Reference]string{
+				image.SimpleReference("quay.io/test/etcd.0.9.0"): "../../bundles/etcd.0.9.0",
+			}).Populate(registry.ReplacesMode))
+
+			// the fork is added along with a bundle that doesn't conflict
+			populator := registry.NewDirectoryPopulator(load, graphLoader, query, map[image.Reference]string{
+				image.SimpleReference("quay.io/test/etcd-fork.0.9.0"): fork,
+				image.SimpleReference("quay.io/test/etcd.0.9.2"):      "../../bundles/etcd.0.9.2",
+			})
+			populator.OnCSVNameConflict(tt.policy)
+			err = populator.Populate(registry.ReplacesMode)
+			if tt.wantErr {
+				require.True(t, checkAggErr(err, registry.CSVNameConflictErr{}))
+				return
+			}
+			require.NoError(t, err)
+
+			packages, err := query.GetPackagesForBundle(context.TODO(), "etcdoperator.v0.9.0")
+			require.NoError(t, err)
+			require.Equal(t, []string{"etcd"}, packages)
+			packages, err = query.GetPackagesForBundle(context.TODO(), "etcdoperator.v0.9.2")
+			require.NoError(t, err)
+			require.Equal(t, []string{"etcd"}, packages)
+		})
+	}
+}
+
+func TestVersionPolicy(t *testing.T) {
+	// copies of the etcd 0.9.2 bundle, which replaces 0.9.0, with another version
+	withVersion := func(t *testing.T, version string) (string, func()) {
+		dir, err := ioutil.TempDir("", "etcd-version-")
+		require.NoError(t, err)
+		for _, sub := range []string{"manifests", "metadata"} {
+			require.NoError(t, os.Mkdir(filepath.Join(dir, sub), 0755))
+			files, err := ioutil.ReadDir(filepath.Join("../../bundles/etcd.0.9.2", sub))
+			require.NoError(t, err)
+			for _, f := range files {
+				content, err := ioutil.ReadFile(filepath.Join("../../bundles/etcd.0.9.2", sub, f.Name()))
+				require.NoError(t, err)
+				content = []byte(strings.Replace(string(content), "version: 0.9.2", "version: "+version, 1))
+				require.NoError(t, ioutil.WriteFile(filepath.Join(dir, sub, f.Name()), content, 0644))
+			}
+		}
+		return dir, func() { os.RemoveAll(dir) }
+	}
+
+	tests := []struct {
+		name    string
+		version string
+		policy  registry.VersionPolicy
+		wantErr bool
+	}{
+		{name: "Valid", version: "0.9.2"},
+		{name: "NotSemver", version: "v0.9.2", wantErr: true},
+		{name: "BuildMetadata", version: "0.9.2+build.1", wantErr: true},
+		{name: "BuildMetadataAllowed", version: "0.9.2+build.1", policy: registry.VersionPolicy{AllowBuildMetadata: true}},
+		{name: "PrereleaseInStableChannel", version: "0.9.2-rc.1", policy: registry.VersionPolicy{PrereleaseChannels: []string{"alpha"}}, wantErr: true},
+		{name: "PrereleaseAllowedEverywhere", version: "0.9.2-rc.1"},
+		{name: "Downgrade", version: "0.8.0", wantErr: true},
+		{name: "DowngradeAllowed", version: "0.8.0", policy: registry.VersionPolicy{AllowDowngrades: true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bundle, remove := withVersion(t, tt.version)
+			defer remove()
+			db, cleanup := CreateTestDb(t)
+			defer cleanup()
+			load, err := sqlite.NewSQLLiteLoader(db)
+			require.NoError(t, err)
+			require.NoError(t, load.Migrate(context.TODO()))
+			query := sqlite.NewSQLLiteQuerierFromDb(db)
+			graphLoader, err := sqlite.NewSQLGraphLoaderFromDB(db)
+			require.NoError(t, err)
+
+			require.NoError(t, registry.NewDirectoryPopulator(load, graphLoader, query, map[image.Reference]string{
+				image.SimpleReference("quay.io/test/etcd.0.9.0"): "../../bundles/etcd.0.9.0",
+			}).Populate(registry.ReplacesMode))
+
+			policy, err := registry.NewVersionPolicy(tt.policy)
+			require.NoError(t, err)
+			populator := registry.NewDirectoryPopulator(load, graphLoader, query, map[image.Reference]string{
+				image.SimpleReference("quay.io/test/etcd.0.9.2"): bundle,
+			})
+			populator.EnforceVersionPolicy(policy)
+			err = populator.Populate(registry.ReplacesMode)
+			if tt.wantErr {
+				require.True(t, checkAggErr(err, registry.VersionPolicyErr{}), "%v", err)
+				version, err := query.GetBundleVersion(context.TODO(), "quay.io/test/etcd.0.9.2")
+				require.NoError(t, err)
+				require.Empty(t, version)
+				return
+			}
+			require.NoError(t, err)
+			version, err := query.GetBundleVersion(context.TODO(), "quay.io/test/etcd.0.9.2")
+			require.NoError(t, err)
+			require.Equal(t, tt.version, version)
+		})
+	}
+}
+
 func EqualBundles(t *testing.T, expected, actual api.Bundle) {
 	require.ElementsMatch(t, expected.ProvidedApis, actual.ProvidedApis, "provided apis don't match: %#v\n%#v", expected.ProvidedApis, actual.ProvidedApis)
 	require.ElementsMatch(t, expected.RequiredApis, actual.RequiredApis, "required apis don't match: %#v\n%#v", expected.RequiredApis, actual.RequiredApis)
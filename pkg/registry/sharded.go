@@ -0,0 +1,639 @@
+package registry
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"sync"
+
+	"github.com/operator-framework/operator-registry/pkg/api"
+)
+
+// ShardForPackage returns the shard, out of the given number of shards, that holds a package
+func ShardForPackage(packageName string, shards int) int {
+	if shards <= 1 {
+		return 0
+	}
+	h := fnv.New32a()
+	h.Write([]byte(packageName))
+	return int(h.Sum32() % uint32(shards))
+}
+
+// NewShardedQuery returns a Query over a catalog that was split into shards by ShardForPackage. Queries about a
+// package are routed to the shard that holds it, and all other queries are fanned out to every shard in parallel.
+// Shards that hold no match for a fanned out query may answer it with an error, so such a query only fails when
+// every shard fails it.
+func NewShardedQuery(shards []Query) Query {
+	if len(shards) == 0 {
+		return EmptyQuery{}
+	}
+	return &shardedQuery{shards: shards}
+}
+
+type shardedQuery struct {
+	shards []Query
+}
+
+var _ Query = &shardedQuery{}
+
+func (s *shardedQuery) shard(packageName string) Query {
+	return s.shards[ShardForPackage(packageName, len(s.shards))]
+}
+
+// fanOut calls fn on every shard in parallel. It returns nil if any shard succeeded and the first error otherwise.
+func (s *shardedQuery) fanOut(fn func(i int, q Query) error) error {
+	errs := make([]error, len(s.shards))
+	var wg sync.WaitGroup
+	for i, q := range s.shards {
+		wg.Add(1)
+		go func(i int, q Query) {
+			defer wg.Done()
+			errs[i] = fn(i, q)
+		}(i, q)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err == nil {
+			return nil
+		}
+	}
+	return errs[0]
+}
+
+// first returns the result of the first shard, in shard order, that answered without an error
+func (s *shardedQuery) first(fn func(q Query) (interface{}, error)) (interface{}, error) {
+	results := make([]interface{}, len(s.shards))
+	errs := make([]error, len(s.shards))
+	s.fanOut(func(i int, q Query) error {
+		results[i], errs[i] = fn(q)
+		return errs[i]
+	})
+	for i := range s.shards {
+		if errs[i] == nil {
+			return results[i], nil
+		}
+	}
+	return nil, errs[0]
+}
+
+// strings concatenates the string lists returned by the shards, leaving out duplicates
+func (s *shardedQuery) strings(fn func(q Query) ([]string, error)) ([]string, error) {
+	results := make([][]string, len(s.shards))
+	err := s.fanOut(func(i int, q Query) (err error) {
+		results[i], err = fn(q)
+		return
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var out []string
+	seen := map[string]struct{}{}
+	for _, result := range results {
+		for _, r := range result {
+			if _, ok := seen[r]; ok {
+				continue
+			}
+			seen[r] = struct{}{}
+			out = append(out, r)
+		}
+	}
+	return out, nil
+}
+
+// channelEntries concatenates the channel entries returned by the shards
+func (s *shardedQuery) channelEntries(fn func(q Query) ([]*ChannelEntry, error)) ([]*ChannelEntry, error) {
+	results := make([][]*ChannelEntry, len(s.shards))
+	err := s.fanOut(func(i int, q Query) (err error) {
+		results[i], err = fn(q)
+		return
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var out []*ChannelEntry
+	for _, result := range results {
+		out = append(out, result...)
+	}
+	return out, nil
+}
+
+func (s *shardedQuery) ListTables(ctx context.Context) ([]string, error) {
+	// every shard has the same schema
+	return s.shards[0].ListTables(ctx)
+}
+
+func (s *shardedQuery) ListPackages(ctx context.Context) ([]string, error) {
+	return s.strings(func(q Query) ([]string, error) { return q.ListPackages(ctx) })
+}
+
+func (s *shardedQuery) ListPackagesPaged(ctx context.Context, after string, limit int) ([]string, error) {
+	// every shard returns a full page, as any of them may hold all of the packages of the merged page
+	packages, err := s.strings(func(q Query) ([]string, error) { return q.ListPackagesPaged(ctx, after, limit) })
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(packages)
+	if limit > 0 && len(packages) > limit {
+		packages = packages[:limit]
+	}
+	return packages, nil
+}
+
+func (s *shardedQuery) GetPackage(ctx context.Context, name string) (*PackageManifest, error) {
+	return s.shard(name).GetPackage(ctx, name)
+}
+
+func (s *shardedQuery) ListChannels(ctx context.Context, pkgName string) ([]PackageChannel, error) {
+	return s.shard(pkgName).ListChannels(ctx, pkgName)
+}
+
+func (s *shardedQuery) GetDefaultPackage(ctx context.Context, name string) (string, error) {
+	return s.shard(name).GetDefaultPackage(ctx, name)
+}
+
+func (s *shardedQuery) GetChannelEntriesFromPackage(ctx context.Context, packageName string) ([]ChannelEntryAnnotated, error) {
+	return s.shard(packageName).GetChannelEntriesFromPackage(ctx, packageName)
+}
+
+func (s *shardedQuery) GetBundle(ctx context.Context, pkgName, channelName, csvName string) (*api.Bundle, error) {
+	return s.shard(pkgName).GetBundle(ctx, pkgName, channelName, csvName)
+}
+
+func (s *shardedQuery) GetBundleForChannel(ctx context.Context, pkgName string, channelName string) (*api.Bundle, error) {
+	return s.shard(pkgName).GetBundleForChannel(ctx, pkgName, channelName)
+}
+
+func (s *shardedQuery) GetBundleForChannelIncludingDeprecated(ctx context.Context, pkgName string, channelName string) (*api.Bundle, error) {
+	return s.shard(pkgName).GetBundleForChannelIncludingDeprecated(ctx, pkgName, channelName)
+}
+
+func (s *shardedQuery) GetChannelHeadBundles(ctx context.Context, pkgName string, includeDeprecated bool) ([]*api.Bundle, error) {
+	return s.shard(pkgName).GetChannelHeadBundles(ctx, pkgName, includeDeprecated)
+}
+
+func (s *shardedQuery) GetBundleForVersion(ctx context.Context, pkgName, channelName, version string) (*api.Bundle, error) {
+	return s.shard(pkgName).GetBundleForVersion(ctx, pkgName, channelName, version)
+}
+
+func (s *shardedQuery) GetLatestBundleInRange(ctx context.Context, pkgName, channelName, versionRange string) (*api.Bundle, error) {
+	return s.shard(pkgName).GetLatestBundleInRange(ctx, pkgName, channelName, versionRange)
+}
+
+func (s *shardedQuery) GetChannelEntriesThatReplace(ctx context.Context, name string) ([]*ChannelEntry, error) {
+	return s.channelEntries(func(q Query) ([]*ChannelEntry, error) { return q.GetChannelEntriesThatReplace(ctx, name) })
+}
+
+func (s *shardedQuery) GetBundleThatReplaces(ctx context.Context, name, pkgName, channelName string) (*api.Bundle, error) {
+	return s.shard(pkgName).GetBundleThatReplaces(ctx, name, pkgName, channelName)
+}
+
+func (s *shardedQuery) GetChannelEntriesThatProvide(ctx context.Context, group, version, kind string) ([]*ChannelEntry, error) {
+	return s.channelEntries(func(q Query) ([]*ChannelEntry, error) {
+		return q.GetChannelEntriesThatProvide(ctx, group, version, kind)
+	})
+}
+
+func (s *shardedQuery) GetChannelEntriesThatRequire(ctx context.Context, group, version, kind string) ([]*ChannelEntry, error) {
+	return s.channelEntries(func(q Query) ([]*ChannelEntry, error) {
+		return q.GetChannelEntriesThatRequire(ctx, group, version, kind)
+	})
+}
+
+func (s *shardedQuery) GetBundlesRequiring(ctx context.Context, group, version, kind string) ([]*api.Bundle, error) {
+	results := make([][]*api.Bundle, len(s.shards))
+	err := s.fanOut(func(i int, q Query) (err error) {
+		results[i], err = q.GetBundlesRequiring(ctx, group, version, kind)
+		return
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// every package is in a single shard
+	var out []*api.Bundle
+	for _, result := range results {
+		out = append(out, result...)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].CsvName < out[j].CsvName
+	})
+	return out, nil
+}
+
+func (s *shardedQuery) GetLatestChannelEntriesThatProvide(ctx context.Context, group, version, kind string) ([]*ChannelEntry, error) {
+	return s.channelEntries(func(q Query) ([]*ChannelEntry, error) {
+		return q.GetLatestChannelEntriesThatProvide(ctx, group, version, kind)
+	})
+}
+
+func (s *shardedQuery) GetBundleThatProvides(ctx context.Context, group, version, kind string) (*api.Bundle, error) {
+	// when packages in several shards provide the api, the lowest shard wins
+	out, err := s.first(func(q Query) (interface{}, error) { return q.GetBundleThatProvides(ctx, group, version, kind) })
+	if err != nil {
+		return nil, err
+	}
+	return out.(*api.Bundle), nil
+}
+
+func (s *shardedQuery) ListImages(ctx context.Context) ([]string, error) {
+	return s.strings(func(q Query) ([]string, error) { return q.ListImages(ctx) })
+}
+
+func (s *shardedQuery) GetImagesForBundle(ctx context.Context, bundleName string) ([]string, error) {
+	return s.strings(func(q Query) ([]string, error) { return q.GetImagesForBundle(ctx, bundleName) })
+}
+
+func (s *shardedQuery) GetApisForEntry(ctx context.Context, entryID int64) ([]*api.GroupVersionKind, []*api.GroupVersionKind, error) {
+	return nil, nil, fmt.Errorf("channel entry ids are local to a shard, can't get apis for entry %d", entryID)
+}
+
+func (s *shardedQuery) GetBundleVersion(ctx context.Context, image string) (string, error) {
+	out, err := s.first(func(q Query) (interface{}, error) {
+		version, err := q.GetBundleVersion(ctx, image)
+		if err == nil && version == "" {
+			return nil, ErrBundleImageNotInDatabase
+		}
+		return version, err
+	})
+	if err == ErrBundleImageNotInDatabase {
+		// bundles that aren't found have no version
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return out.(string), nil
+}
+
+func (s *shardedQuery) GetBundlePathsForPackage(ctx context.Context, pkgName string) ([]string, error) {
+	return s.shard(pkgName).GetBundlePathsForPackage(ctx, pkgName)
+}
+
+func (s *shardedQuery) GetBundlesForPackage(ctx context.Context, pkgName string) (map[BundleKey]struct{}, error) {
+	return s.shard(pkgName).GetBundlesForPackage(ctx, pkgName)
+}
+
+func (s *shardedQuery) GetDefaultChannelForPackage(ctx context.Context, pkgName string) (string, error) {
+	return s.shard(pkgName).GetDefaultChannelForPackage(ctx, pkgName)
+}
+
+func (s *shardedQuery) GetCurrentCSVNameForChannel(ctx context.Context, pkgName, channel string) (string, error) {
+	return s.shard(pkgName).GetCurrentCSVNameForChannel(ctx, pkgName, channel)
+}
+
+func (s *shardedQuery) ListBundles(ctx context.Context) ([]*api.Bundle, error) {
+	results := make([][]*api.Bundle, len(s.shards))
+	err := s.fanOut(func(i int, q Query) (err error) {
+		results[i], err = q.ListBundles(ctx)
+		return
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var out []*api.Bundle
+	for _, result := range results {
+		out = append(out, result...)
+	}
+	return out, nil
+}
+
+// ListBundlesFiltered only asks the shard of the package if the filter selects one
+func (s *shardedQuery) ListBundlesFiltered(ctx context.Context, filter BundleFilter) ([]*api.Bundle, error) {
+	if filter.PackageName != "" {
+		return s.shard(filter.PackageName).ListBundlesFiltered(ctx, filter)
+	}
+	results := make([][]*api.Bundle, len(s.shards))
+	err := s.fanOut(func(i int, q Query) (err error) {
+		results[i], err = q.ListBundlesFiltered(ctx, filter)
+		return
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var out []*api.Bundle
+	for _, result := range results {
+		out = append(out, result...)
+	}
+	return out, nil
+}
+
+func (s *shardedQuery) GetDependenciesForBundle(ctx context.Context, name, version, path string) ([]*api.Dependency, error) {
+	results := make([][]*api.Dependency, len(s.shards))
+	err := s.fanOut(func(i int, q Query) (err error) {
+		results[i], err = q.GetDependenciesForBundle(ctx, name, version, path)
+		return
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var out []*api.Dependency
+	for _, result := range results {
+		out = append(out, result...)
+	}
+	return out, nil
+}
+
+func (s *shardedQuery) ListPackagesByProvider(ctx context.Context, provider string) ([]string, error) {
+	return s.strings(func(q Query) ([]string, error) { return q.ListPackagesByProvider(ctx, provider) })
+}
+
+func (s *shardedQuery) GetOwnershipForBundle(ctx context.Context, bundleName string) (*Ownership, error) {
+	out, err := s.first(func(q Query) (interface{}, error) { return q.GetOwnershipForBundle(ctx, bundleName) })
+	if err != nil {
+		return nil, err
+	}
+	return out.(*Ownership), nil
+}
+
+func (s *shardedQuery) GetBundleAttestations(ctx context.Context, bundleName string) ([]*Attestation, error) {
+	results := make([][]*Attestation, len(s.shards))
+	err := s.fanOut(func(i int, q Query) (err error) {
+		results[i], err = q.GetBundleAttestations(ctx, bundleName)
+		return
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var out []*Attestation
+	for _, result := range results {
+		out = append(out, result...)
+	}
+	return out, nil
+}
+
+func (s *shardedQuery) GetBundleDigest(ctx context.Context, image string) (string, error) {
+	out, err := s.first(func(q Query) (interface{}, error) { return q.GetBundleDigest(ctx, image) })
+	if err != nil {
+		return "", err
+	}
+	return out.(string), nil
+}
+
+func (s *shardedQuery) GetBundleImage(ctx context.Context, bundleName string) (*BundleImage, error) {
+	out, err := s.first(func(q Query) (interface{}, error) { return q.GetBundleImage(ctx, bundleName) })
+	if err != nil {
+		return nil, err
+	}
+	return out.(*BundleImage), nil
+}
+
+func (s *shardedQuery) GetPackageForAlias(ctx context.Context, alias string) (string, error) {
+	// the alias is kept in the shard of the package it was renamed to, not in the shard its own name hashes to
+	out, err := s.first(func(q Query) (interface{}, error) { return q.GetPackageForAlias(ctx, alias) })
+	if err != nil {
+		return "", err
+	}
+	return out.(string), nil
+}
+
+func (s *shardedQuery) GetDisplayMetadataForBundle(ctx context.Context, bundleName, locale string) (*DisplayMetadata, error) {
+	out, err := s.first(func(q Query) (interface{}, error) { return q.GetDisplayMetadataForBundle(ctx, bundleName, locale) })
+	if err != nil {
+		return nil, err
+	}
+	return out.(*DisplayMetadata), nil
+}
+
+func (s *shardedQuery) GetChannelEntriesForPlatformVersion(ctx context.Context, packageName, platformVersion string) ([]ChannelEntryAnnotated, error) {
+	return s.shard(packageName).GetChannelEntriesForPlatformVersion(ctx, packageName, platformVersion)
+}
+
+func (s *shardedQuery) GetPackageID(ctx context.Context, name string) (string, error) {
+	return s.shard(name).GetPackageID(ctx, name)
+}
+
+func (s *shardedQuery) GetBundleID(ctx context.Context, csvName string) (string, error) {
+	out, err := s.first(func(q Query) (interface{}, error) { return q.GetBundleID(ctx, csvName) })
+	if err != nil {
+		return "", err
+	}
+	return out.(string), nil
+}
+
+func (s *shardedQuery) GetEntriesByBundleImage(ctx context.Context, imageRefOrDigest string) ([]ChannelEntryAnnotated, error) {
+	// the same image may have been loaded into several shards
+	results := make([][]ChannelEntryAnnotated, len(s.shards))
+	err := s.fanOut(func(i int, q Query) (err error) {
+		results[i], err = q.GetEntriesByBundleImage(ctx, imageRefOrDigest)
+		return
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var out []ChannelEntryAnnotated
+	for _, result := range results {
+		out = append(out, result...)
+	}
+	return out, nil
+}
+
+func (s *shardedQuery) GetPresentBundles(ctx context.Context, bundles []string) ([]string, error) {
+	results := make([][]string, len(s.shards))
+	err := s.fanOut(func(i int, q Query) (err error) {
+		results[i], err = q.GetPresentBundles(ctx, bundles)
+		return
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// keep the order of the request, each bundle once
+	present := map[string]bool{}
+	for _, result := range results {
+		for _, b := range result {
+			present[b] = true
+		}
+	}
+	var out []string
+	for _, b := range bundles {
+		if present[b] {
+			out = append(out, b)
+			delete(present, b)
+		}
+	}
+	return out, nil
+}
+
+func (s *shardedQuery) GetPackageStats(ctx context.Context, packages []string) ([]*PackageStats, error) {
+	results := make([][]*PackageStats, len(s.shards))
+	err := s.fanOut(func(i int, q Query) (err error) {
+		results[i], err = q.GetPackageStats(ctx, packages)
+		return
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// every package is in a single shard
+	var out []*PackageStats
+	for _, result := range results {
+		out = append(out, result...)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].Name < out[j].Name
+	})
+	return out, nil
+}
+
+func (s *shardedQuery) GetBundleStats(ctx context.Context, packages []string, limit int) ([]*BundleStats, error) {
+	results := make([][]*BundleStats, len(s.shards))
+	err := s.fanOut(func(i int, q Query) (err error) {
+		results[i], err = q.GetBundleStats(ctx, packages, limit)
+		return
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// the largest bundles of the catalog are among the largest of each shard
+	var out []*BundleStats
+	for _, result := range results {
+		out = append(out, result...)
+	}
+	sortBundleStats(out)
+	if limit > 0 && len(out) > limit {
+		out = out[:limit]
+	}
+	return out, nil
+}
+
+func (s *shardedQuery) GetPackagesForBundle(ctx context.Context, csvName string) ([]string, error) {
+	results := make([][]string, len(s.shards))
+	err := s.fanOut(func(i int, q Query) (err error) {
+		results[i], err = q.GetPackagesForBundle(ctx, csvName)
+		return
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	seen := map[string]struct{}{}
+	var out []string
+	for _, result := range results {
+		for _, p := range result {
+			if _, ok := seen[p]; ok {
+				continue
+			}
+			seen[p] = struct{}{}
+			out = append(out, p)
+		}
+	}
+	sort.Strings(out)
+	return out, nil
+}
+
+func (s *shardedQuery) GetDefaultChannelHeads(ctx context.Context, packages []string) ([]*BundleSummary, error) {
+	results := make([][]*BundleSummary, len(s.shards))
+	err := s.fanOut(func(i int, q Query) (err error) {
+		results[i], err = q.GetDefaultChannelHeads(ctx, packages)
+		return
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// every package is in a single shard
+	var out []*BundleSummary
+	for _, result := range results {
+		out = append(out, result...)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].PackageName < out[j].PackageName
+	})
+	return out, nil
+}
+
+func (s *shardedQuery) SearchBundles(ctx context.Context, query string, packages []string) ([]*BundleSummary, error) {
+	results := make([][]*BundleSummary, len(s.shards))
+	err := s.fanOut(func(i int, q Query) (err error) {
+		results[i], err = q.SearchBundles(ctx, query, packages)
+		return
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// every package is in a single shard, but the relevance of matches in different shards can't be compared, so the
+	// best matches of each shard are interleaved
+	out := []*BundleSummary{}
+	for n := 0; ; n++ {
+		added := false
+		for _, result := range results {
+			if n < len(result) {
+				out = append(out, result[n])
+				added = true
+			}
+		}
+		if !added {
+			return out, nil
+		}
+	}
+}
+
+func (s *shardedQuery) GetBundleSummary(ctx context.Context, pkgName, channelName, csvName string) (*BundleSummary, error) {
+	return s.shard(pkgName).GetBundleSummary(ctx, pkgName, channelName, csvName)
+}
+
+func (s *shardedQuery) ListBundleSummaries(ctx context.Context) ([]*BundleSummary, error) {
+	results := make([][]*BundleSummary, len(s.shards))
+	err := s.fanOut(func(i int, q Query) (err error) {
+		results[i], err = q.ListBundleSummaries(ctx)
+		return
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var out []*BundleSummary
+	for _, result := range results {
+		out = append(out, result...)
+	}
+	return out, nil
+}
+
+// ListBundleSummariesFiltered only asks the shard of the package if the filter selects one
+func (s *shardedQuery) ListBundleSummariesFiltered(ctx context.Context, filter BundleFilter) ([]*BundleSummary, error) {
+	if filter.PackageName != "" {
+		return s.shard(filter.PackageName).ListBundleSummariesFiltered(ctx, filter)
+	}
+	results := make([][]*BundleSummary, len(s.shards))
+	err := s.fanOut(func(i int, q Query) (err error) {
+		results[i], err = q.ListBundleSummariesFiltered(ctx, filter)
+		return
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var out []*BundleSummary
+	for _, result := range results {
+		out = append(out, result...)
+	}
+	return out, nil
+}
+
+func (s *shardedQuery) GetUpgradeGraph(ctx context.Context, pkgName string) (*UpgradeGraph, error) {
+	return s.shard(pkgName).GetUpgradeGraph(ctx, pkgName)
+}
+
+// sortBundleStats sorts bundle stats largest first, as the stats of a single database are
+func sortBundleStats(stats []*BundleStats) {
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Size != stats[j].Size {
+			return stats[i].Size > stats[j].Size
+		}
+		if stats[i].CsvName != stats[j].CsvName {
+			return stats[i].CsvName < stats[j].CsvName
+		}
+		return stats[i].PackageName < stats[j].PackageName
+	})
+}
@@ -0,0 +1,360 @@
+package registry
+
+import (
+	"context"
+	"sync"
+
+	"github.com/operator-framework/operator-registry/pkg/api"
+)
+
+// SwappableQuery serves queries from a Query that can be replaced while it is being queried, e.g. by a freshly built
+// database. Calls made before a swap finish against the Query they started on.
+type SwappableQuery struct {
+	mu      sync.RWMutex
+	current *swappableTarget
+}
+
+type swappableTarget struct {
+	query    Query
+	inflight sync.WaitGroup
+}
+
+var _ Query = &SwappableQuery{}
+
+// NewSwappableQuery returns a SwappableQuery that serves from query until it is swapped
+func NewSwappableQuery(query Query) *SwappableQuery {
+	return &SwappableQuery{current: &swappableTarget{query: query}}
+}
+
+// Swap serves every call made from now on from query. It returns the Query that was being served once the calls
+// made to it have finished, so that it can be closed.
+func (s *SwappableQuery) Swap(query Query) Query {
+	s.mu.Lock()
+	old := s.current
+	s.current = &swappableTarget{query: query}
+	s.mu.Unlock()
+
+	old.inflight.Wait()
+	return old.query
+}
+
+// acquire returns the Query to make a call on, along with the func to call once the call has returned
+func (s *SwappableQuery) acquire() (Query, func()) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	target := s.current
+	target.inflight.Add(1)
+	return target.query, target.inflight.Done
+}
+
+func (s *SwappableQuery) ListTables(ctx context.Context) ([]string, error) {
+	query, done := s.acquire()
+	defer done()
+	return query.ListTables(ctx)
+}
+
+func (s *SwappableQuery) ListPackages(ctx context.Context) ([]string, error) {
+	query, done := s.acquire()
+	defer done()
+	return query.ListPackages(ctx)
+}
+
+func (s *SwappableQuery) ListPackagesPaged(ctx context.Context, after string, limit int) ([]string, error) {
+	query, done := s.acquire()
+	defer done()
+	return query.ListPackagesPaged(ctx, after, limit)
+}
+
+func (s *SwappableQuery) GetPackage(ctx context.Context, name string) (*PackageManifest, error) {
+	query, done := s.acquire()
+	defer done()
+	return query.GetPackage(ctx, name)
+}
+
+func (s *SwappableQuery) ListChannels(ctx context.Context, pkgName string) ([]PackageChannel, error) {
+	query, done := s.acquire()
+	defer done()
+	return query.ListChannels(ctx, pkgName)
+}
+
+func (s *SwappableQuery) GetDefaultPackage(ctx context.Context, name string) (string, error) {
+	query, done := s.acquire()
+	defer done()
+	return query.GetDefaultPackage(ctx, name)
+}
+
+func (s *SwappableQuery) GetChannelEntriesFromPackage(ctx context.Context, packageName string) ([]ChannelEntryAnnotated, error) {
+	query, done := s.acquire()
+	defer done()
+	return query.GetChannelEntriesFromPackage(ctx, packageName)
+}
+
+func (s *SwappableQuery) GetBundle(ctx context.Context, pkgName, channelName, csvName string) (*api.Bundle, error) {
+	query, done := s.acquire()
+	defer done()
+	return query.GetBundle(ctx, pkgName, channelName, csvName)
+}
+
+func (s *SwappableQuery) GetBundleForChannel(ctx context.Context, pkgName string, channelName string) (*api.Bundle, error) {
+	query, done := s.acquire()
+	defer done()
+	return query.GetBundleForChannel(ctx, pkgName, channelName)
+}
+
+func (s *SwappableQuery) GetBundleForChannelIncludingDeprecated(ctx context.Context, pkgName string, channelName string) (*api.Bundle, error) {
+	query, done := s.acquire()
+	defer done()
+	return query.GetBundleForChannelIncludingDeprecated(ctx, pkgName, channelName)
+}
+
+func (s *SwappableQuery) GetChannelHeadBundles(ctx context.Context, pkgName string, includeDeprecated bool) ([]*api.Bundle, error) {
+	query, done := s.acquire()
+	defer done()
+	return query.GetChannelHeadBundles(ctx, pkgName, includeDeprecated)
+}
+
+func (s *SwappableQuery) GetBundleForVersion(ctx context.Context, pkgName, channelName, version string) (*api.Bundle, error) {
+	query, done := s.acquire()
+	defer done()
+	return query.GetBundleForVersion(ctx, pkgName, channelName, version)
+}
+
+func (s *SwappableQuery) GetLatestBundleInRange(ctx context.Context, pkgName, channelName, versionRange string) (*api.Bundle, error) {
+	query, done := s.acquire()
+	defer done()
+	return query.GetLatestBundleInRange(ctx, pkgName, channelName, versionRange)
+}
+
+func (s *SwappableQuery) GetChannelEntriesThatReplace(ctx context.Context, name string) (entries []*ChannelEntry, err error) {
+	query, done := s.acquire()
+	defer done()
+	return query.GetChannelEntriesThatReplace(ctx, name)
+}
+
+func (s *SwappableQuery) GetBundleThatReplaces(ctx context.Context, name, pkgName, channelName string) (*api.Bundle, error) {
+	query, done := s.acquire()
+	defer done()
+	return query.GetBundleThatReplaces(ctx, name, pkgName, channelName)
+}
+
+func (s *SwappableQuery) GetChannelEntriesThatProvide(ctx context.Context, group, version, kind string) (entries []*ChannelEntry, err error) {
+	query, done := s.acquire()
+	defer done()
+	return query.GetChannelEntriesThatProvide(ctx, group, version, kind)
+}
+
+func (s *SwappableQuery) GetChannelEntriesThatRequire(ctx context.Context, group, version, kind string) (entries []*ChannelEntry, err error) {
+	query, done := s.acquire()
+	defer done()
+	return query.GetChannelEntriesThatRequire(ctx, group, version, kind)
+}
+
+func (s *SwappableQuery) GetBundlesRequiring(ctx context.Context, group, version, kind string) ([]*api.Bundle, error) {
+	query, done := s.acquire()
+	defer done()
+	return query.GetBundlesRequiring(ctx, group, version, kind)
+}
+
+func (s *SwappableQuery) GetLatestChannelEntriesThatProvide(ctx context.Context, group, version, kind string) (entries []*ChannelEntry, err error) {
+	query, done := s.acquire()
+	defer done()
+	return query.GetLatestChannelEntriesThatProvide(ctx, group, version, kind)
+}
+
+func (s *SwappableQuery) GetBundleThatProvides(ctx context.Context, group, version, kind string) (*api.Bundle, error) {
+	query, done := s.acquire()
+	defer done()
+	return query.GetBundleThatProvides(ctx, group, version, kind)
+}
+
+func (s *SwappableQuery) ListImages(ctx context.Context) ([]string, error) {
+	query, done := s.acquire()
+	defer done()
+	return query.ListImages(ctx)
+}
+
+func (s *SwappableQuery) GetImagesForBundle(ctx context.Context, bundleName string) ([]string, error) {
+	query, done := s.acquire()
+	defer done()
+	return query.GetImagesForBundle(ctx, bundleName)
+}
+
+func (s *SwappableQuery) GetApisForEntry(ctx context.Context, entryID int64) (provided []*api.GroupVersionKind, required []*api.GroupVersionKind, err error) {
+	query, done := s.acquire()
+	defer done()
+	return query.GetApisForEntry(ctx, entryID)
+}
+
+func (s *SwappableQuery) GetBundleVersion(ctx context.Context, image string) (string, error) {
+	query, done := s.acquire()
+	defer done()
+	return query.GetBundleVersion(ctx, image)
+}
+
+func (s *SwappableQuery) GetBundlePathsForPackage(ctx context.Context, pkgName string) ([]string, error) {
+	query, done := s.acquire()
+	defer done()
+	return query.GetBundlePathsForPackage(ctx, pkgName)
+}
+
+func (s *SwappableQuery) GetBundlesForPackage(ctx context.Context, pkgName string) (map[BundleKey]struct{}, error) {
+	query, done := s.acquire()
+	defer done()
+	return query.GetBundlesForPackage(ctx, pkgName)
+}
+
+func (s *SwappableQuery) GetDefaultChannelForPackage(ctx context.Context, pkgName string) (string, error) {
+	query, done := s.acquire()
+	defer done()
+	return query.GetDefaultChannelForPackage(ctx, pkgName)
+}
+
+func (s *SwappableQuery) GetCurrentCSVNameForChannel(ctx context.Context, pkgName, channel string) (string, error) {
+	query, done := s.acquire()
+	defer done()
+	return query.GetCurrentCSVNameForChannel(ctx, pkgName, channel)
+}
+
+func (s *SwappableQuery) ListBundles(ctx context.Context) (bundles []*api.Bundle, err error) {
+	query, done := s.acquire()
+	defer done()
+	return query.ListBundles(ctx)
+}
+
+func (s *SwappableQuery) ListBundlesFiltered(ctx context.Context, filter BundleFilter) ([]*api.Bundle, error) {
+	query, done := s.acquire()
+	defer done()
+	return query.ListBundlesFiltered(ctx, filter)
+}
+
+func (s *SwappableQuery) GetDependenciesForBundle(ctx context.Context, name, version, path string) (dependencies []*api.Dependency, err error) {
+	query, done := s.acquire()
+	defer done()
+	return query.GetDependenciesForBundle(ctx, name, version, path)
+}
+
+func (s *SwappableQuery) ListPackagesByProvider(ctx context.Context, provider string) ([]string, error) {
+	query, done := s.acquire()
+	defer done()
+	return query.ListPackagesByProvider(ctx, provider)
+}
+
+func (s *SwappableQuery) GetOwnershipForBundle(ctx context.Context, bundleName string) (*Ownership, error) {
+	query, done := s.acquire()
+	defer done()
+	return query.GetOwnershipForBundle(ctx, bundleName)
+}
+
+func (s *SwappableQuery) GetBundleAttestations(ctx context.Context, bundleName string) ([]*Attestation, error) {
+	query, done := s.acquire()
+	defer done()
+	return query.GetBundleAttestations(ctx, bundleName)
+}
+
+func (s *SwappableQuery) GetBundleDigest(ctx context.Context, image string) (string, error) {
+	query, done := s.acquire()
+	defer done()
+	return query.GetBundleDigest(ctx, image)
+}
+
+func (s *SwappableQuery) GetBundleImage(ctx context.Context, bundleName string) (*BundleImage, error) {
+	query, done := s.acquire()
+	defer done()
+	return query.GetBundleImage(ctx, bundleName)
+}
+
+func (s *SwappableQuery) GetPackageForAlias(ctx context.Context, alias string) (string, error) {
+	query, done := s.acquire()
+	defer done()
+	return query.GetPackageForAlias(ctx, alias)
+}
+
+func (s *SwappableQuery) GetDisplayMetadataForBundle(ctx context.Context, bundleName, locale string) (*DisplayMetadata, error) {
+	query, done := s.acquire()
+	defer done()
+	return query.GetDisplayMetadataForBundle(ctx, bundleName, locale)
+}
+
+func (s *SwappableQuery) GetChannelEntriesForPlatformVersion(ctx context.Context, packageName, platformVersion string) ([]ChannelEntryAnnotated, error) {
+	query, done := s.acquire()
+	defer done()
+	return query.GetChannelEntriesForPlatformVersion(ctx, packageName, platformVersion)
+}
+
+func (s *SwappableQuery) GetPackageID(ctx context.Context, name string) (string, error) {
+	query, done := s.acquire()
+	defer done()
+	return query.GetPackageID(ctx, name)
+}
+
+func (s *SwappableQuery) GetBundleID(ctx context.Context, csvName string) (string, error) {
+	query, done := s.acquire()
+	defer done()
+	return query.GetBundleID(ctx, csvName)
+}
+
+func (s *SwappableQuery) GetEntriesByBundleImage(ctx context.Context, imageRefOrDigest string) ([]ChannelEntryAnnotated, error) {
+	query, done := s.acquire()
+	defer done()
+	return query.GetEntriesByBundleImage(ctx, imageRefOrDigest)
+}
+
+func (s *SwappableQuery) GetPresentBundles(ctx context.Context, bundles []string) ([]string, error) {
+	query, done := s.acquire()
+	defer done()
+	return query.GetPresentBundles(ctx, bundles)
+}
+
+func (s *SwappableQuery) GetPackageStats(ctx context.Context, packages []string) ([]*PackageStats, error) {
+	query, done := s.acquire()
+	defer done()
+	return query.GetPackageStats(ctx, packages)
+}
+
+func (s *SwappableQuery) GetBundleStats(ctx context.Context, packages []string, limit int) ([]*BundleStats, error) {
+	query, done := s.acquire()
+	defer done()
+	return query.GetBundleStats(ctx, packages, limit)
+}
+
+func (s *SwappableQuery) GetPackagesForBundle(ctx context.Context, csvName string) ([]string, error) {
+	query, done := s.acquire()
+	defer done()
+	return query.GetPackagesForBundle(ctx, csvName)
+}
+
+func (s *SwappableQuery) GetDefaultChannelHeads(ctx context.Context, packages []string) ([]*BundleSummary, error) {
+	query, done := s.acquire()
+	defer done()
+	return query.GetDefaultChannelHeads(ctx, packages)
+}
+
+func (s *SwappableQuery) SearchBundles(ctx context.Context, search string, packages []string) ([]*BundleSummary, error) {
+	query, done := s.acquire()
+	defer done()
+	return query.SearchBundles(ctx, search, packages)
+}
+
+func (s *SwappableQuery) GetBundleSummary(ctx context.Context, pkgName, channelName, csvName string) (*BundleSummary, error) {
+	query, done := s.acquire()
+	defer done()
+	return query.GetBundleSummary(ctx, pkgName, channelName, csvName)
+}
+
+func (s *SwappableQuery) ListBundleSummaries(ctx context.Context) ([]*BundleSummary, error) {
+	query, done := s.acquire()
+	defer done()
+	return query.ListBundleSummaries(ctx)
+}
+
+func (s *SwappableQuery) ListBundleSummariesFiltered(ctx context.Context, filter BundleFilter) ([]*BundleSummary, error) {
+	query, done := s.acquire()
+	defer done()
+	return query.ListBundleSummariesFiltered(ctx, filter)
+}
+
+func (s *SwappableQuery) GetUpgradeGraph(ctx context.Context, pkgName string) (*UpgradeGraph, error) {
+	query, done := s.acquire()
+	defer done()
+	return query.GetUpgradeGraph(ctx, pkgName)
+}
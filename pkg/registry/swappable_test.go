@@ -0,0 +1,60 @@
+package registry
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// blockingQuery answers ListPackages with its packages once it is released
+type blockingQuery struct {
+	*EmptyQuery
+	packages []string
+	started  chan struct{}
+	release  chan struct{}
+}
+
+func (q *blockingQuery) ListPackages(ctx context.Context) ([]string, error) {
+	if q.started != nil {
+		close(q.started)
+		<-q.release
+	}
+	return q.packages, nil
+}
+
+func TestSwappableQuery(t *testing.T) {
+	old := &blockingQuery{EmptyQuery: NewEmptyQuerier(), packages: []string{"old"}, started: make(chan struct{}), release: make(chan struct{})}
+	new := &blockingQuery{EmptyQuery: NewEmptyQuerier(), packages: []string{"new"}}
+	q := NewSwappableQuery(old)
+
+	// a call is in flight against the old query when it is swapped
+	inflight := make(chan []string)
+	go func() {
+		packages, _ := q.ListPackages(context.TODO())
+		inflight <- packages
+	}()
+	<-old.started
+
+	swapped := make(chan Query)
+	go func() {
+		swapped <- q.Swap(new)
+	}()
+
+	// calls made after the swap are served by the new query, even before the old one is released
+	require.Eventually(t, func() bool {
+		packages, err := q.ListPackages(context.TODO())
+		return err == nil && len(packages) == 1 && packages[0] == "new"
+	}, 5*time.Second, 10*time.Millisecond)
+
+	select {
+	case <-swapped:
+		t.Fatal("swap returned while a call to the old query was in flight")
+	default:
+	}
+
+	close(old.release)
+	require.Equal(t, []string{"old"}, <-inflight)
+	require.Equal(t, old, <-swapped)
+}
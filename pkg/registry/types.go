@@ -19,8 +19,38 @@ var (
 	// ErrRemovingDefaultChannelDuringDeprecation is an error that describes a bundle deprecation causing the deletion
 	// of the default channel
 	ErrRemovingDefaultChannelDuringDeprecation = errors.New("Bundle deprecation causing default channel removal")
+
+	// ErrPackageNotFound is what the errors of looking up a package that isn't in the registry are, by errors.Is
+	ErrPackageNotFound = errors.New("package not found")
+
+	// ErrBundleNotFound is what the errors of looking up a bundle that isn't in the registry are, by errors.Is
+	ErrBundleNotFound = errors.New("bundle not found")
 )
 
+// notFoundError describes what a lookup didn't find, and wraps the sentinel error of what it looked up
+type notFoundError struct {
+	message string
+	err     error
+}
+
+func (e *notFoundError) Error() string {
+	return e.message
+}
+
+func (e *notFoundError) Unwrap() error {
+	return e.err
+}
+
+// PackageNotFoundf returns an error with the formatted message that is ErrPackageNotFound
+func PackageNotFoundf(format string, args ...interface{}) error {
+	return &notFoundError{message: fmt.Sprintf(format, args...), err: ErrPackageNotFound}
+}
+
+// BundleNotFoundf returns an error with the formatted message that is ErrBundleNotFound
+func BundleNotFoundf(format string, args ...interface{}) error {
+	return &notFoundError{message: fmt.Sprintf(format, args...), err: ErrBundleNotFound}
+}
+
 // BundleImageAlreadyAddedErr is an error that describes a bundle is already added
 type BundleImageAlreadyAddedErr struct {
 	ErrorString string
@@ -39,12 +69,33 @@ func (e PackageVersionAlreadyAddedErr) Error() string {
 	return e.ErrorString
 }
 
+// CSVNameConflictErr is an error that describes a bundle whose CSV name already belongs to a bundle of another package
+type CSVNameConflictErr struct {
+	ErrorString string
+}
+
+func (e CSVNameConflictErr) Error() string {
+	return e.ErrorString
+}
+
+// VersionPolicyErr is an error that describes a bundle whose version breaks the version policy of the catalog
+type VersionPolicyErr struct {
+	ErrorString string
+}
+
+func (e VersionPolicyErr) Error() string {
+	return e.ErrorString
+}
+
 const (
-	GVKType        = "olm.gvk"
-	PackageType    = "olm.package"
-	DeprecatedType = "olm.deprecated"
-	LabelType      = "olm.label"
-	PropertyKey    = "olm.properties"
+	GVKType          = "olm.gvk"
+	PackageType      = "olm.package"
+	DeprecatedType   = "olm.deprecated"
+	LabelType        = "olm.label"
+	LocalizedType    = "olm.localized"
+	PlatformType     = "olm.platformVersions"
+	InstallModesType = "olm.installModes"
+	PropertyKey      = "olm.properties"
 )
 
 // APIKey stores GroupVersionKind for use as map keys
@@ -103,6 +154,10 @@ type PackageChannel struct {
 	// CurrentCSVName defines a reference to the CSV holding the version of this package currently
 	// for the channel.
 	CurrentCSVName string `json:"currentCSV" yaml:"currentCSV"`
+
+	// Deprecated is set by queries when the bundle at the head of the channel is deprecated. It is not part of
+	// the package manifest format.
+	Deprecated bool `json:"-" yaml:"-"`
 }
 
 // IsDefaultChannel returns true if the PackageChennel is the default for the PackageManifest
@@ -130,6 +185,175 @@ type ChannelEntryAnnotated struct {
 	ReplacesBundlePath string
 }
 
+// PackageStats are aggregates over the bundles and channels of a package
+type PackageStats struct {
+	Name               string
+	BundleCount        int
+	Channels           []string
+	DefaultChannelName string
+	// LatestVersion is the highest semver version of the bundles in the package, empty if none has one
+	LatestVersion string
+	// TotalBundleSize is the size in bytes of the serialized manifests of the bundles in the package, and
+	// LargestBundle the bundle with the largest of them, of LargestBundleSize bytes
+	TotalBundleSize   int64
+	LargestBundle     string
+	LargestBundleSize int64
+}
+
+// BundleStats is the size of the serialized manifests of a bundle, recorded when it was loaded, and the number of
+// objects and CRDs among them
+type BundleStats struct {
+	CsvName     string
+	PackageName string
+	Version     string
+	BundlePath  string
+	Size        int64
+	ObjectCount int
+	CRDCount    int
+}
+
+// BundleSummary identifies a bundle and its place in the upgrade graph of a channel, without its manifests
+type BundleSummary struct {
+	// ID is the stable id of the bundle
+	ID          string
+	PackageName string
+	ChannelName string
+	CsvName     string
+	Version     string
+	BundlePath  string
+	Replaces    string
+	Skips       []string
+	SkipRange   string
+	// ProvidedApis and RequiredApis are only filled in by GetBundleSummary and ListBundleSummaries
+	ProvidedApis []APIKey
+	RequiredApis []APIKey
+}
+
+// UpgradeGraph is the upgrade graph of a package: the bundles in each of its channels and the upgrades between them
+type UpgradeGraph struct {
+	PackageName    string                `json:"packageName"`
+	DefaultChannel string                `json:"defaultChannel"`
+	Channels       []UpgradeGraphChannel `json:"channels"`
+}
+
+// UpgradeGraphChannel is the upgrade graph of a channel, a DAG of its bundles with edges from each bundle to the bundles
+// that upgrade it
+type UpgradeGraphChannel struct {
+	Name string `json:"name"`
+	// Head is the bundle at the head of the channel, even if it is deprecated
+	Head  string             `json:"head"`
+	Nodes []UpgradeGraphNode `json:"nodes"`
+	Edges []UpgradeGraphEdge `json:"edges"`
+}
+
+// UpgradeGraphNode is a bundle in the upgrade graph of a channel
+type UpgradeGraphNode struct {
+	CsvName    string `json:"csvName"`
+	Version    string `json:"version,omitempty"`
+	BundlePath string `json:"bundlePath,omitempty"`
+	SkipRange  string `json:"skipRange,omitempty"`
+	Deprecated bool   `json:"deprecated,omitempty"`
+}
+
+// UpgradeEdgeType is how a bundle declares that it upgrades another
+type UpgradeEdgeType string
+
+const (
+	UpgradeEdgeReplaces  UpgradeEdgeType = "replaces"
+	UpgradeEdgeSkips     UpgradeEdgeType = "skips"
+	UpgradeEdgeSkipRange UpgradeEdgeType = "skipRange"
+)
+
+// UpgradeGraphEdge is an upgrade from one bundle of a channel to another
+type UpgradeGraphEdge struct {
+	From string          `json:"from"`
+	To   string          `json:"to"`
+	Type UpgradeEdgeType `json:"type"`
+}
+
+// Maintainer identifies a maintainer of an operator, as declared in a CSV
+type Maintainer struct {
+	Name  string `json:"name,omitempty" yaml:"name,omitempty"`
+	Email string `json:"email,omitempty" yaml:"email,omitempty"`
+}
+
+// Link is a named url associated with an operator, as declared in a CSV
+type Link struct {
+	Name string `json:"name,omitempty" yaml:"name,omitempty"`
+	URL  string `json:"url,omitempty" yaml:"url,omitempty"`
+}
+
+// Icon is an image displayed for an operator, as declared in a CSV
+type Icon struct {
+	Base64Data string `json:"base64data" yaml:"base64data"`
+	MediaType  string `json:"mediatype" yaml:"mediatype"`
+}
+
+// Ownership describes who publishes and maintains a bundle
+type Ownership struct {
+	Provider    string       `json:"provider,omitempty" yaml:"provider,omitempty"`
+	Maintainers []Maintainer `json:"maintainers,omitempty" yaml:"maintainers,omitempty"`
+	Links       []Link       `json:"links,omitempty" yaml:"links,omitempty"`
+}
+
+// DisplayMetadata is the display data of a bundle in a given locale
+type DisplayMetadata struct {
+	// Locale is the most specific locale that any of the fields were taken from, empty if none were translated
+	Locale      string `json:"locale,omitempty" yaml:"locale,omitempty"`
+	DisplayName string `json:"displayName,omitempty" yaml:"displayName,omitempty"`
+	Description string `json:"description,omitempty" yaml:"description,omitempty"`
+}
+
+// Attestation is the keyless verification evidence recorded for a bundle image
+type Attestation struct {
+	// CertificateIdentity is the subject (e.g. email or workflow URI) of the signing certificate
+	CertificateIdentity string `json:"certificateIdentity"`
+
+	// CertificateIssuer is the OIDC issuer that vouched for the certificate identity
+	CertificateIssuer string `json:"certificateIssuer,omitempty"`
+
+	// RekorLogIndex is the index of the signature entry in the transparency log
+	RekorLogIndex int64 `json:"rekorLogIndex"`
+}
+
+// BundleImage is the image a bundle was loaded from
+type BundleImage struct {
+	// Image is the reference the bundle was pulled by, its bundle path
+	Image string `json:"image"`
+
+	// Digest is the digest of the manifest of the image when the bundle was pulled, empty if it is unknown
+	Digest string `json:"digest,omitempty"`
+}
+
+// Pinned returns a reference to the image by digest, which pulls the bundle as it was when it was loaded even if the
+// tag it was pulled by has moved since. It is the image itself if its digest is unknown.
+func (i BundleImage) Pinned() string {
+	if i.Digest == "" || strings.Contains(i.Image, "@") {
+		return i.Image
+	}
+	name := i.Image
+	// drop the tag, but not the port of the registry
+	if colon := strings.LastIndex(name, ":"); colon > strings.LastIndex(name, "/") {
+		name = name[:colon]
+	}
+	return name + "@" + i.Digest
+}
+
+// ChannelOverride is the channel membership of a bundle given when it is added, which replaces the one its
+// annotations declare
+type ChannelOverride struct {
+	// Channels, if set, are the channels the bundle is added to instead of those of its annotations
+	Channels []string `json:"channels,omitempty"`
+
+	// DefaultChannel, if set, is the default channel of the package instead of that of the annotations
+	DefaultChannel string `json:"defaultChannel,omitempty"`
+}
+
+// IsEmpty returns true if the override replaces nothing
+func (o ChannelOverride) IsEmpty() bool {
+	return len(o.Channels) == 0 && o.DefaultChannel == ""
+}
+
 // AnnotationsFile holds annotation information about a bundle
 type AnnotationsFile struct {
 	// annotations is a list of annotations for a given bundle
@@ -228,6 +452,18 @@ type LabelProperty struct {
 	Label string `json:"label" yaml:"label"`
 }
 
+// LocalizedProperty carries a translation of the display data of a bundle
+type LocalizedProperty struct {
+	// The locale of the translation, as a BCP 47 language tag such as 'de' or 'pt-BR'
+	Locale string `json:"locale" yaml:"locale"`
+
+	// The translated display name, falls back to a less specific locale when empty
+	DisplayName string `json:"displayName,omitempty" yaml:"displayName,omitempty"`
+
+	// The translated description, falls back to a less specific locale when empty
+	Description string `json:"description,omitempty" yaml:"description,omitempty"`
+}
+
 // Validate will validate GVK dependency type and return error(s)
 func (gd *GVKDependency) Validate() []error {
 	errs := []error{}
@@ -0,0 +1,136 @@
+package registry
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/blang/semver"
+)
+
+// VersionPolicy is a catalog's rules for the versions of its bundles. OLM and the tooling downstream of catalogs read
+// bundle versions as semver, and silently skip or misorder the bundles whose versions they can't, so a catalog can
+// refuse such versions when they are added rather than find out from its users. Every version must be valid semver,
+// e.g. 1.2.0 and not v1.2 or 1.2.0.1; the other rules can be relaxed.
+//
+// A policy is read from a YAML or JSON rule file, in which an empty file is the strictest policy:
+//
+//	allowBuildMetadata: false
+//	prereleaseChannels:
+//	- (candidate|fast)(-v\d+\.\d+)?
+//	allowDowngrades: false
+type VersionPolicy struct {
+	// AllowBuildMetadata accepts versions with build metadata, e.g. 1.2.0+build.5. Semver precedence ignores build
+	// metadata, so bundles whose versions only differ in it can't be ordered.
+	AllowBuildMetadata bool `json:"allowBuildMetadata"`
+	// PrereleaseChannels are regular expressions, one of which a channel name must match in full for the channel to hold
+	// pre-release versions, e.g. 1.2.0-rc.1. Pre-releases are allowed in every channel if there are none.
+	PrereleaseChannels []string `json:"prereleaseChannels"`
+	// AllowDowngrades accepts bundles that upgrade from a bundle of their channel with a version as high or higher.
+	// Versions must otherwise increase along every replaces, skips and skip range edge of the upgrade graph.
+	AllowDowngrades bool `json:"allowDowngrades"`
+
+	compiled []*regexp.Regexp
+}
+
+// NewVersionPolicy returns the policy with its channel patterns compiled
+func NewVersionPolicy(policy VersionPolicy) (*VersionPolicy, error) {
+	p := &VersionPolicy{
+		AllowBuildMetadata: policy.AllowBuildMetadata,
+		PrereleaseChannels: policy.PrereleaseChannels,
+		AllowDowngrades:    policy.AllowDowngrades,
+	}
+	for _, pattern := range p.PrereleaseChannels {
+		re, err := regexp.Compile(`^(?:` + pattern + `)$`)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pre-release channel pattern %q: %s", pattern, err)
+		}
+		p.compiled = append(p.compiled, re)
+	}
+	return p, nil
+}
+
+// LoadVersionPolicy reads a version policy from a rule file
+func LoadVersionPolicy(path string) (*VersionPolicy, error) {
+	var p VersionPolicy
+	if err := DecodeFile(path, &p); err != nil {
+		return nil, fmt.Errorf("error reading version policy %s: %s", path, err)
+	}
+	return NewVersionPolicy(p)
+}
+
+// ValidateVersion returns an error for every rule of the policy that the version of a bundle in the given channels
+// breaks
+func (p *VersionPolicy) ValidateVersion(version string, channels ...string) []error {
+	if version == "" {
+		return []error{fmt.Errorf("bundle has no version")}
+	}
+	v, err := semver.Parse(version)
+	if err != nil {
+		return []error{fmt.Errorf("version %q is not valid semver: %s", version, err)}
+	}
+
+	var errs []error
+	if len(v.Build) > 0 && !p.AllowBuildMetadata {
+		errs = append(errs, fmt.Errorf("version %q has build metadata, which semver precedence ignores", version))
+	}
+	if len(v.Pre) > 0 && len(p.compiled) > 0 {
+		for _, channel := range channels {
+			if !p.allowsPrerelease(channel) {
+				errs = append(errs, fmt.Errorf("version %q is a pre-release, which channel %q can't hold", version, channel))
+			}
+		}
+	}
+	return errs
+}
+
+// ValidateUpgrade returns an error if the policy doesn't allow a bundle to upgrade from a bundle of the given version.
+// Versions that aren't valid semver are left to ValidateVersion.
+func (p *VersionPolicy) ValidateUpgrade(from, to string) error {
+	if p.AllowDowngrades {
+		return nil
+	}
+	fromVersion, err := semver.Parse(from)
+	if err != nil {
+		return nil
+	}
+	toVersion, err := semver.Parse(to)
+	if err != nil {
+		return nil
+	}
+	if !fromVersion.LT(toVersion) {
+		return fmt.Errorf("version %q upgrades from version %q, which isn't lower", to, from)
+	}
+	return nil
+}
+
+// ValidateGraph returns an error for every edge of the upgrade graph of a package along which the version doesn't
+// increase, unless the policy allows downgrades. Edges found in several channels are reported once.
+func (p *VersionPolicy) ValidateGraph(graph *UpgradeGraph) []error {
+	var errs []error
+	seen := map[UpgradeGraphEdge]struct{}{}
+	for _, channel := range graph.Channels {
+		versions := map[string]string{}
+		for _, node := range channel.Nodes {
+			versions[node.CsvName] = node.Version
+		}
+		for _, edge := range channel.Edges {
+			if _, ok := seen[edge]; ok {
+				continue
+			}
+			seen[edge] = struct{}{}
+			if err := p.ValidateUpgrade(versions[edge.From], versions[edge.To]); err != nil {
+				errs = append(errs, fmt.Errorf("%s %s %s: %s", edge.To, edge.Type, edge.From, err))
+			}
+		}
+	}
+	return errs
+}
+
+func (p *VersionPolicy) allowsPrerelease(channel string) bool {
+	for _, re := range p.compiled {
+		if re.MatchString(channel) {
+			return true
+		}
+	}
+	return false
+}
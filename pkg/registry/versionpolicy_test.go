@@ -0,0 +1,96 @@
+package registry
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateVersion(t *testing.T) {
+	strict, err := NewVersionPolicy(VersionPolicy{})
+	require.NoError(t, err)
+	relaxed, err := NewVersionPolicy(VersionPolicy{AllowBuildMetadata: true, PrereleaseChannels: []string{`candidate(-v\d+)?`}})
+	require.NoError(t, err)
+
+	tests := []struct {
+		version  string
+		channels []string
+		strict   int
+		relaxed  int
+	}{
+		{version: "1.2.0", channels: []string{"stable"}},
+		{version: "", strict: 1, relaxed: 1},
+		{version: "v1.2.0", strict: 1, relaxed: 1},
+		{version: "1.2", strict: 1, relaxed: 1},
+		{version: "01.2.0", strict: 1, relaxed: 1},
+		{version: "1.2.0+build.5", strict: 1},
+		{version: "1.2.0-rc.1", channels: []string{"candidate-v1"}},
+		{version: "1.2.0-rc.1", channels: []string{"stable", "fast", "candidate"}, relaxed: 2},
+	}
+	for _, tt := range tests {
+		require.Len(t, strict.ValidateVersion(tt.version, tt.channels...), tt.strict, tt.version)
+		require.Len(t, relaxed.ValidateVersion(tt.version, tt.channels...), tt.relaxed, tt.version)
+	}
+
+	_, err = NewVersionPolicy(VersionPolicy{PrereleaseChannels: []string{"("}})
+	require.Error(t, err)
+}
+
+func TestValidateGraph(t *testing.T) {
+	graph := &UpgradeGraph{
+		PackageName: "etcd",
+		Channels: []UpgradeGraphChannel{
+			{
+				Name: "alpha",
+				Nodes: []UpgradeGraphNode{
+					{CsvName: "etcd.v1", Version: "1.0.0"},
+					{CsvName: "etcd.v2", Version: "2.0.0"},
+					{CsvName: "etcd.v1.5", Version: "1.5.0"},
+					{CsvName: "etcd.broken", Version: "latest"},
+				},
+				Edges: []UpgradeGraphEdge{
+					{From: "etcd.v1", To: "etcd.v2", Type: UpgradeEdgeReplaces},
+					{From: "etcd.v2", To: "etcd.v1.5", Type: UpgradeEdgeReplaces},
+					{From: "etcd.v2", To: "etcd.broken", Type: UpgradeEdgeReplaces},
+				},
+			},
+			{
+				Name: "stable",
+				Nodes: []UpgradeGraphNode{
+					{CsvName: "etcd.v2", Version: "2.0.0"},
+					{CsvName: "etcd.v1.5", Version: "1.5.0"},
+				},
+				Edges: []UpgradeGraphEdge{
+					{From: "etcd.v2", To: "etcd.v1.5", Type: UpgradeEdgeReplaces},
+				},
+			},
+		},
+	}
+
+	// the downgrade is in both channels but reported once, and invalid versions are left to ValidateVersion
+	errs := (&VersionPolicy{}).ValidateGraph(graph)
+	require.Len(t, errs, 1)
+	require.EqualError(t, errs[0], `etcd.v1.5 replaces etcd.v2: version "1.5.0" upgrades from version "2.0.0", which isn't lower`)
+	require.Empty(t, (&VersionPolicy{AllowDowngrades: true}).ValidateGraph(graph))
+}
+
+func TestLoadVersionPolicy(t *testing.T) {
+	dir, err := ioutil.TempDir("", "version-policy-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "versions.yaml")
+	require.NoError(t, ioutil.WriteFile(path, []byte("allowBuildMetadata: true\nprereleaseChannels:\n- candidate\n"), 0644))
+	policy, err := LoadVersionPolicy(path)
+	require.NoError(t, err)
+	require.True(t, policy.AllowBuildMetadata)
+	require.False(t, policy.AllowDowngrades)
+	require.Empty(t, policy.ValidateVersion("1.0.0-rc.1", "candidate"))
+	require.Len(t, policy.ValidateVersion("1.0.0-rc.1", "stable"), 1)
+
+	_, err = LoadVersionPolicy(filepath.Join(dir, "missing.yaml"))
+	require.Error(t, err)
+}
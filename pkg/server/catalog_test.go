@@ -0,0 +1,100 @@
+package server
+
+import (
+	"database/sql"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/operator-framework/operator-registry/pkg/api"
+	"github.com/operator-framework/operator-registry/pkg/sqlite"
+)
+
+func newCatalogStore(t *testing.T, path, manifests string) *sqlite.SQLQuerier {
+	db, err := sql.Open("sqlite3", path)
+	require.NoError(t, err)
+	load, err := sqlite.NewSQLLiteLoader(db)
+	require.NoError(t, err)
+	require.NoError(t, load.Migrate(context.TODO()))
+	if manifests != "" {
+		require.NoError(t, sqlite.NewSQLLoaderForDirectory(load, manifests).Populate())
+	}
+	require.NoError(t, db.Close())
+	store, err := sqlite.NewSQLLiteQuerier(path)
+	require.NoError(t, err)
+	return store
+}
+
+func listPackageNames(t *testing.T, c api.RegistryClient) ([]string, error) {
+	stream, err := c.ListPackages(context.TODO(), &api.ListPackageRequest{})
+	require.NoError(t, err)
+	var names []string
+	for {
+		name, err := stream.Recv()
+		if err == io.EOF {
+			return names, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		names = append(names, name.GetName())
+	}
+}
+
+func TestCatalogRegistryServer(t *testing.T) {
+	dir, err := ioutil.TempDir("", "catalogs-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	community := NewRegistryServer(newCatalogStore(t, filepath.Join(dir, "community.db"), "../../manifests"))
+	empty := NewRegistryServer(newCatalogStore(t, filepath.Join(dir, "empty.db"), ""))
+
+	s := grpc.NewServer()
+	api.RegisterRegistryServer(s, community)
+	api.RegisterCatalogRegistryServer(s, "community", community)
+	api.RegisterCatalogRegistryServer(s, "empty", empty)
+	lis, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+	go s.Serve(lis)
+	defer s.Stop()
+	conn, err := grpc.Dial(lis.Addr().String(), grpc.WithInsecure())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	// each catalog is served by its own service, and the default one by the registry service
+	names, err := listPackageNames(t, api.NewRegistryClient(conn))
+	require.NoError(t, err)
+	require.Equal(t, []string{"etcd", "prometheus", "strimzi-kafka-operator"}, names)
+	names, err = listPackageNames(t, api.NewCatalogRegistryClient(conn, "community"))
+	require.NoError(t, err)
+	require.Equal(t, []string{"etcd", "prometheus", "strimzi-kafka-operator"}, names)
+	names, err = listPackageNames(t, api.NewCatalogRegistryClient(conn, "empty"))
+	require.NoError(t, err)
+	require.Empty(t, names)
+
+	pkg, err := api.NewCatalogRegistryClient(conn, "community").GetPackage(context.TODO(), &api.GetPackageRequest{Name: "etcd"})
+	require.NoError(t, err)
+	require.Equal(t, "etcd", pkg.GetName())
+	_, err = api.NewCatalogRegistryClient(conn, "empty").GetPackage(context.TODO(), &api.GetPackageRequest{Name: "etcd"})
+	require.Error(t, err)
+
+	_, err = listPackageNames(t, api.NewCatalogRegistryClient(conn, "missing"))
+	require.Equal(t, codes.Unimplemented, status.Code(err))
+}
+
+func TestValidateCatalogName(t *testing.T) {
+	for _, name := range []string{"community", "redhat-operators", "v4"} {
+		require.NoError(t, api.ValidateCatalogName(name), name)
+	}
+	for _, name := range []string{"", "Community", "index_v2", "-community", "community.api", "a/b"} {
+		require.Error(t, api.ValidateCatalogName(name), name)
+	}
+}
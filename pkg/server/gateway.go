@@ -0,0 +1,485 @@
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+
+	"github.com/operator-framework/operator-registry/pkg/api"
+)
+
+// GatewayPrefix prefixes the path of every method served by the gateway, e.g. /api/v1/GetPackage
+const GatewayPrefix = "/api/v1/"
+
+// The gateway passes the address and verified client certificate of each http request on to its grpc server with
+// these metadata keys, which only its own calls can set
+const (
+	gatewayAddrKey = "x-gateway-remote-addr"
+	gatewayCertKey = "x-gateway-client-cert-bin"
+)
+
+// Gateway serves the registry api as json over http, for clients that can't speak grpc such as curl and web consoles.
+// Each method of the api is served at GatewayPrefix followed by its name. Its request is read from a json body, from
+// the query parameters of the url, or from both, e.g.
+//
+//	curl 'localhost:8080/api/v1/GetBundleForChannel?pkgName=etcd&channelName=alpha&fields=csvName&fields=version'
+//
+// Unary methods respond with a json object, and streaming methods with a json object per line, each written as soon
+// as it is received. Errors are written as {"code": ..., "message": ...} with the http status closest to their grpc
+// code; an error that ends a stream that has already responded is written as its last line, as {"error": {...}}.
+//
+// Requests are made of the registry through a grpc server of the gateway's own, which has the interceptors given to
+// NewGateway, such as those of the query log and the rate limits. Each request is made as the http client that sent
+// it: its address, the certificate it verified with and its bearer token are passed on, so that rate limits and
+// --visibility apply to it as they do to grpc clients.
+type Gateway struct {
+	logger  logrus.FieldLogger
+	methods map[string]protoreflect.MethodDescriptor
+	server  *grpc.Server
+	conn    *grpc.ClientConn
+}
+
+// NewGateway returns a gateway to srv. opts are the options of the grpc server the gateway calls srv through, e.g.
+// the interceptors of a query log or rate limits; transport options such as credentials and limits on the streams of
+// a connection don't apply to it, since all of its calls share one in-process connection.
+func NewGateway(srv api.RegistryServer, logger logrus.FieldLogger, opts ...grpc.ServerOption) (*Gateway, error) {
+	service := api.File_registry_proto.Services().ByName("Registry")
+	if service == nil {
+		return nil, fmt.Errorf("registry service not found in the api descriptor")
+	}
+	g := &Gateway{logger: logger, methods: map[string]protoreflect.MethodDescriptor{}}
+	for i := 0; i < service.Methods().Len(); i++ {
+		method := service.Methods().Get(i)
+		if method.IsStreamingClient() {
+			continue
+		}
+		g.methods[string(method.Name())] = method
+	}
+
+	lis := newPipeListener()
+	// the client is restored before any other interceptor runs
+	opts = append([]grpc.ServerOption{grpc.ChainUnaryInterceptor(gatewayUnary), grpc.ChainStreamInterceptor(gatewayStream)}, opts...)
+	g.server = grpc.NewServer(opts...)
+	api.RegisterRegistryServer(g.server, srv)
+	go g.server.Serve(lis)
+	conn, err := grpc.Dial("gateway", grpc.WithInsecure(), grpc.WithContextDialer(lis.dial))
+	if err != nil {
+		g.server.Stop()
+		return nil, err
+	}
+	g.conn = conn
+	return g, nil
+}
+
+// Close stops the gateway; requests still in flight fail
+func (g *Gateway) Close() error {
+	err := g.conn.Close()
+	g.server.Stop()
+	return err
+}
+
+func (g *Gateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		w.Header().Set("Allow", "GET, POST")
+		g.writeError(w, http.StatusMethodNotAllowed, status.Errorf(codes.Unimplemented, "method %s not allowed", r.Method))
+		return
+	}
+	if strings.TrimSuffix(r.URL.Path, "/")+"/" == GatewayPrefix {
+		g.writeMethods(w)
+		return
+	}
+	method, ok := g.methods[strings.TrimPrefix(r.URL.Path, GatewayPrefix)]
+	if !ok || !strings.HasPrefix(r.URL.Path, GatewayPrefix) {
+		g.writeError(w, http.StatusNotFound, status.Errorf(codes.NotFound, "no method is served at %s", r.URL.Path))
+		return
+	}
+	in, err := gatewayRequest(r, method.Input())
+	if err != nil {
+		g.writeError(w, http.StatusBadRequest, status.Errorf(codes.InvalidArgument, "%s", err))
+		return
+	}
+
+	ctx := metadata.AppendToOutgoingContext(r.Context(), gatewayAddrKey, r.RemoteAddr)
+	if r.TLS != nil && len(r.TLS.VerifiedChains) > 0 && len(r.TLS.VerifiedChains[0]) > 0 {
+		ctx = metadata.AppendToOutgoingContext(ctx, gatewayCertKey, string(r.TLS.VerifiedChains[0][0].Raw))
+	}
+	if auth := r.Header.Get("Authorization"); auth != "" {
+		ctx = metadata.AppendToOutgoingContext(ctx, "authorization", auth)
+	}
+	fullMethod := "/" + string(method.Parent().FullName()) + "/" + string(method.Name())
+	if method.IsStreamingServer() {
+		g.stream(ctx, w, fullMethod, in, method.Output())
+		return
+	}
+	out, err := newMessage(method.Output())
+	if err == nil {
+		err = g.conn.Invoke(ctx, fullMethod, in.Interface(), out.Interface())
+	}
+	if err != nil {
+		g.writeError(w, httpStatus(status.Code(err)), err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	g.encode(w, out.Interface())
+}
+
+// stream writes each message of a server stream as a line of json, flushing each one so that long lived streams such
+// as Watch are written as they go
+func (g *Gateway) stream(ctx context.Context, w http.ResponseWriter, fullMethod string, in protoreflect.Message, output protoreflect.MessageDescriptor) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	stream, err := g.conn.NewStream(ctx, &grpc.StreamDesc{ServerStreams: true}, fullMethod)
+	if err == nil {
+		if err = stream.SendMsg(in.Interface()); err == nil {
+			err = stream.CloseSend()
+		}
+	}
+	if err != nil {
+		g.writeError(w, httpStatus(status.Code(err)), err)
+		return
+	}
+
+	flusher, _ := w.(http.Flusher)
+	started := false
+	for {
+		out, err := newMessage(output)
+		if err == nil {
+			err = stream.RecvMsg(out.Interface())
+		}
+		if err == io.EOF {
+			if !started {
+				w.Header().Set("Content-Type", "application/x-ndjson")
+				w.WriteHeader(http.StatusOK)
+			}
+			return
+		}
+		if err != nil {
+			if !started {
+				g.writeError(w, httpStatus(status.Code(err)), err)
+				return
+			}
+			g.encode(w, struct {
+				Error gatewayError `json:"error"`
+			}{newGatewayError(err)})
+			return
+		}
+		if !started {
+			w.Header().Set("Content-Type", "application/x-ndjson")
+			started = true
+		}
+		if !g.encode(w, out.Interface()) {
+			return
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+}
+
+// gatewayClient makes the http client a call of the gateway was made for the peer of the call, in place of the
+// in-process connection, so that it is limited and identified by its own address and verified certificate
+func gatewayClient(ctx context.Context) context.Context {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ctx
+	}
+	p := &peer.Peer{Addr: pipeAddr{}}
+	if addrs := md.Get(gatewayAddrKey); len(addrs) > 0 {
+		p.Addr = remoteAddr(addrs[0])
+	}
+	if certs := md.Get(gatewayCertKey); len(certs) > 0 {
+		// the http server verified the certificate against the client CA before the request was made
+		if cert, err := x509.ParseCertificate([]byte(certs[0])); err == nil {
+			p.AuthInfo = credentials.TLSInfo{State: tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{cert}}}}
+		}
+	}
+	md = md.Copy()
+	delete(md, gatewayAddrKey)
+	delete(md, gatewayCertKey)
+	return peer.NewContext(metadata.NewIncomingContext(ctx, md), p)
+}
+
+func gatewayUnary(ctx context.Context, req interface{}, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	return handler(gatewayClient(ctx), req)
+}
+
+func gatewayStream(srv interface{}, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	return handler(srv, &gatewayServerStream{ServerStream: ss, ctx: gatewayClient(ss.Context())})
+}
+
+// gatewayServerStream is a stream made as the http client of the gateway
+type gatewayServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *gatewayServerStream) Context() context.Context {
+	return s.ctx
+}
+
+// writeMethods lists the methods the gateway serves
+func (g *Gateway) writeMethods(w http.ResponseWriter) {
+	type method struct {
+		Name      string `json:"name"`
+		Streaming bool   `json:"streaming,omitempty"`
+	}
+	methods := []method{}
+	for name, m := range g.methods {
+		methods = append(methods, method{Name: name, Streaming: m.IsStreamingServer()})
+	}
+	sort.Slice(methods, func(i, j int) bool {
+		return methods[i].Name < methods[j].Name
+	})
+	w.Header().Set("Content-Type", "application/json")
+	g.encode(w, struct {
+		Methods []method `json:"methods"`
+	}{methods})
+}
+
+func (g *Gateway) writeError(w http.ResponseWriter, code int, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	g.encode(w, newGatewayError(err))
+}
+
+// encode writes v as a line of json, and returns false if it couldn't be written
+func (g *Gateway) encode(w io.Writer, v interface{}) bool {
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		g.logger.WithError(err).Debug("error writing gateway response")
+		return false
+	}
+	return true
+}
+
+type gatewayError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func newGatewayError(err error) gatewayError {
+	s := status.Convert(err)
+	return gatewayError{Code: s.Code().String(), Message: s.Message()}
+}
+
+// gatewayRequest reads the request of a method from the json body of r, if there is one, and then from the query
+// parameters of its url, which are named by the json names of the fields of the request. A repeated field is set by
+// repeating its parameter.
+func gatewayRequest(r *http.Request, input protoreflect.MessageDescriptor) (protoreflect.Message, error) {
+	in, err := newMessage(input)
+	if err != nil {
+		return nil, err
+	}
+	if r.Method == http.MethodPost && r.Body != nil {
+		dec := json.NewDecoder(r.Body)
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(in.Interface()); err != nil && err != io.EOF {
+			return nil, fmt.Errorf("error reading request body: %s", err)
+		}
+	}
+
+	fields := input.Fields()
+	for name, values := range r.URL.Query() {
+		field := fields.ByJSONName(name)
+		if field == nil {
+			field = fields.ByName(protoreflect.Name(name))
+		}
+		if field == nil {
+			return nil, fmt.Errorf("%s has no field %s", input.Name(), name)
+		}
+		if field.IsMap() || field.Kind() == protoreflect.MessageKind || field.Kind() == protoreflect.GroupKind {
+			return nil, fmt.Errorf("field %s can only be set in the request body", name)
+		}
+		if !field.IsList() {
+			if len(values) > 1 {
+				return nil, fmt.Errorf("field %s can't be set more than once", name)
+			}
+			v, err := parseScalar(field, values[0])
+			if err != nil {
+				return nil, err
+			}
+			in.Set(field, v)
+			continue
+		}
+		list := in.Mutable(field).List()
+		for _, value := range values {
+			v, err := parseScalar(field, value)
+			if err != nil {
+				return nil, err
+			}
+			list.Append(v)
+		}
+	}
+	return in, nil
+}
+
+func parseScalar(field protoreflect.FieldDescriptor, value string) (protoreflect.Value, error) {
+	invalid := func(err error) (protoreflect.Value, error) {
+		return protoreflect.Value{}, fmt.Errorf("invalid value %q for field %s: %s", value, field.JSONName(), err)
+	}
+	switch field.Kind() {
+	case protoreflect.StringKind:
+		return protoreflect.ValueOfString(value), nil
+	case protoreflect.BytesKind:
+		return protoreflect.ValueOfBytes([]byte(value)), nil
+	case protoreflect.BoolKind:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return invalid(err)
+		}
+		return protoreflect.ValueOfBool(b), nil
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		i, err := strconv.ParseInt(value, 10, 32)
+		if err != nil {
+			return invalid(err)
+		}
+		return protoreflect.ValueOfInt32(int32(i)), nil
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		i, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return invalid(err)
+		}
+		return protoreflect.ValueOfInt64(i), nil
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		i, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			return invalid(err)
+		}
+		return protoreflect.ValueOfUint32(uint32(i)), nil
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		i, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			return invalid(err)
+		}
+		return protoreflect.ValueOfUint64(i), nil
+	case protoreflect.FloatKind, protoreflect.DoubleKind:
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return invalid(err)
+		}
+		if field.Kind() == protoreflect.FloatKind {
+			return protoreflect.ValueOfFloat32(float32(f)), nil
+		}
+		return protoreflect.ValueOfFloat64(f), nil
+	case protoreflect.EnumKind:
+		// enums are named, as in the proto, or numbered, as they are encoded in json
+		if v := field.Enum().Values().ByName(protoreflect.Name(value)); v != nil {
+			return protoreflect.ValueOfEnum(v.Number()), nil
+		}
+		i, err := strconv.ParseInt(value, 10, 32)
+		if err != nil {
+			return invalid(fmt.Errorf("not a value of %s", field.Enum().Name()))
+		}
+		return protoreflect.ValueOfEnum(protoreflect.EnumNumber(i)), nil
+	}
+	return invalid(fmt.Errorf("fields of kind %s can't be set in the url", field.Kind()))
+}
+
+func newMessage(desc protoreflect.MessageDescriptor) (protoreflect.Message, error) {
+	t, err := protoregistry.GlobalTypes.FindMessageByName(desc.FullName())
+	if err != nil {
+		return nil, err
+	}
+	return t.New(), nil
+}
+
+// httpStatus returns the http status closest to a grpc code, as grpc-gateway maps them
+func httpStatus(code codes.Code) int {
+	switch code {
+	case codes.OK:
+		return http.StatusOK
+	case codes.Canceled:
+		return 499
+	case codes.InvalidArgument, codes.FailedPrecondition, codes.OutOfRange:
+		return http.StatusBadRequest
+	case codes.DeadlineExceeded:
+		return http.StatusGatewayTimeout
+	case codes.NotFound:
+		return http.StatusNotFound
+	case codes.AlreadyExists, codes.Aborted:
+		return http.StatusConflict
+	case codes.PermissionDenied:
+		return http.StatusForbidden
+	case codes.Unauthenticated:
+		return http.StatusUnauthorized
+	case codes.ResourceExhausted:
+		return http.StatusTooManyRequests
+	case codes.Unimplemented:
+		return http.StatusNotImplemented
+	case codes.Unavailable:
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusInternalServerError
+}
+
+// pipeListener is a listener that is only dialed in process, over in-memory connections
+type pipeListener struct {
+	conns  chan net.Conn
+	closed chan struct{}
+	once   sync.Once
+}
+
+func newPipeListener() *pipeListener {
+	return &pipeListener{conns: make(chan net.Conn), closed: make(chan struct{})}
+}
+
+func (l *pipeListener) dial(ctx context.Context, _ string) (net.Conn, error) {
+	client, server := net.Pipe()
+	select {
+	case l.conns <- server:
+		return client, nil
+	case <-l.closed:
+		return nil, fmt.Errorf("gateway closed")
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (l *pipeListener) Accept() (net.Conn, error) {
+	select {
+	case conn := <-l.conns:
+		return conn, nil
+	case <-l.closed:
+		return nil, fmt.Errorf("gateway closed")
+	}
+}
+
+func (l *pipeListener) Close() error {
+	l.once.Do(func() {
+		close(l.closed)
+	})
+	return nil
+}
+
+func (l *pipeListener) Addr() net.Addr {
+	return pipeAddr{}
+}
+
+type pipeAddr struct{}
+
+func (pipeAddr) Network() string { return "pipe" }
+func (pipeAddr) String() string  { return "gateway" }
+
+// remoteAddr is the address of an http client of the gateway, as its server reported it
+type remoteAddr string
+
+func (remoteAddr) Network() string  { return "tcp" }
+func (a remoteAddr) String() string { return string(a) }
@@ -0,0 +1,223 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"database/sql"
+	"encoding/json"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+
+	"github.com/operator-framework/operator-registry/pkg/api"
+	"github.com/operator-framework/operator-registry/pkg/sqlite"
+)
+
+func newGatewayServer(t *testing.T, opts ...grpc.ServerOption) (*httptest.Server, func()) {
+	gateway, cleanup := newGateway(t, nil, opts...)
+	s := httptest.NewServer(gateway)
+	return s, func() {
+		s.Close()
+		cleanup()
+	}
+}
+
+// newGateway returns a gateway to the catalog of the manifests directory, limited by visibility if it is set
+func newGateway(t *testing.T, visibility *Visibility, opts ...grpc.ServerOption) (*Gateway, func()) {
+	dir, err := ioutil.TempDir("", "gateway-")
+	require.NoError(t, err)
+
+	dbPath := filepath.Join(dir, "index.db")
+	db, err := sql.Open("sqlite3", dbPath)
+	require.NoError(t, err)
+	load, err := sqlite.NewSQLLiteLoader(db)
+	require.NoError(t, err)
+	require.NoError(t, load.Migrate(context.TODO()))
+	require.NoError(t, sqlite.NewSQLLoaderForDirectory(load, "../../manifests").Populate())
+	require.NoError(t, db.Close())
+	store, err := sqlite.NewSQLLiteQuerier(dbPath)
+	require.NoError(t, err)
+
+	var serverOpts []RegistryServerOption
+	if visibility != nil {
+		serverOpts = append(serverOpts, WithVisibility(visibility))
+	}
+	gateway, err := NewGateway(NewRegistryServer(store, serverOpts...), logrus.NewEntry(logrus.New()), opts...)
+	require.NoError(t, err)
+	return gateway, func() {
+		gateway.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+func gatewayCall(t *testing.T, s *httptest.Server, method, path, body string, code int) *http.Response {
+	req, err := http.NewRequest(method, s.URL+path, strings.NewReader(body))
+	require.NoError(t, err)
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	require.Equal(t, code, resp.StatusCode, path)
+	return resp
+}
+
+func TestGateway(t *testing.T) {
+	s, cleanup := newGatewayServer(t)
+	defer cleanup()
+
+	resp := gatewayCall(t, s, http.MethodGet, "/api/v1/GetPackage?name=etcd", "", http.StatusOK)
+	var pkg api.Package
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&pkg))
+	resp.Body.Close()
+	require.Equal(t, "etcd", pkg.GetName())
+	require.Equal(t, "alpha", pkg.GetDefaultChannelName())
+	require.Len(t, pkg.GetChannels(), 3)
+
+	// the request can be given as a json body, and repeated fields by repeating their parameter
+	resp = gatewayCall(t, s, http.MethodPost, "/api/v1/GetBundleForChannel?fields=csvName&fields=version", `{"pkgName":"etcd","channelName":"alpha"}`, http.StatusOK)
+	var bundle api.Bundle
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&bundle))
+	resp.Body.Close()
+	require.Equal(t, "etcdoperator.v0.9.2", bundle.GetCsvName())
+	require.Equal(t, "0.9.2", bundle.GetVersion())
+	require.Empty(t, bundle.GetCsvJson())
+
+	// streams are written a message per line
+	resp = gatewayCall(t, s, http.MethodGet, "/api/v1/ListPackages?limit=2", "", http.StatusOK)
+	require.Equal(t, "application/x-ndjson", resp.Header.Get("Content-Type"))
+	var names []string
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		var name api.PackageName
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &name))
+		names = append(names, name.GetName())
+	}
+	resp.Body.Close()
+	require.Equal(t, []string{"etcd", "prometheus"}, names)
+
+	resp = gatewayCall(t, s, http.MethodGet, "/api/v1/", "", http.StatusOK)
+	var methods struct {
+		Methods []struct {
+			Name      string `json:"name"`
+			Streaming bool   `json:"streaming"`
+		} `json:"methods"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&methods))
+	resp.Body.Close()
+	served := map[string]bool{}
+	for _, m := range methods.Methods {
+		served[m.Name] = m.Streaming
+	}
+	require.Contains(t, served, "GetPackage")
+	require.False(t, served["GetPackage"])
+	require.True(t, served["ListBundles"])
+}
+
+func TestGatewayErrors(t *testing.T) {
+	s, cleanup := newGatewayServer(t)
+	defer cleanup()
+
+	for _, tt := range []struct {
+		method, path, body string
+		code               int
+		grpcCode           string
+	}{
+		{method: http.MethodGet, path: "/api/v1/GetPackage?name=missing", code: http.StatusNotFound, grpcCode: "NotFound"},
+		{method: http.MethodGet, path: "/api/v1/Missing", code: http.StatusNotFound, grpcCode: "NotFound"},
+		{method: http.MethodGet, path: "/GetPackage", code: http.StatusNotFound, grpcCode: "NotFound"},
+		{method: http.MethodGet, path: "/api/v1/GetPackage?missing=etcd", code: http.StatusBadRequest, grpcCode: "InvalidArgument"},
+		{method: http.MethodGet, path: "/api/v1/GetPackage?name=etcd&name=prometheus", code: http.StatusBadRequest, grpcCode: "InvalidArgument"},
+		{method: http.MethodGet, path: "/api/v1/ListPackages?limit=many", code: http.StatusBadRequest, grpcCode: "InvalidArgument"},
+		{method: http.MethodPost, path: "/api/v1/GetPackage", body: `{"nmae":"etcd"}`, code: http.StatusBadRequest, grpcCode: "InvalidArgument"},
+		{method: http.MethodDelete, path: "/api/v1/GetPackage?name=etcd", code: http.StatusMethodNotAllowed, grpcCode: "Unimplemented"},
+		// the gateway isn't watching the catalog
+		{method: http.MethodGet, path: "/api/v1/Watch", code: http.StatusBadRequest, grpcCode: "FailedPrecondition"},
+	} {
+		resp := gatewayCall(t, s, tt.method, tt.path, tt.body, tt.code)
+		var gerr gatewayError
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&gerr), tt.path)
+		resp.Body.Close()
+		require.Equal(t, tt.grpcCode, gerr.Code, tt.path)
+		require.NotEmpty(t, gerr.Message, tt.path)
+	}
+}
+
+// clientCertificate returns a certificate for a client with the given common name
+func clientCertificate(t *testing.T, commonName string) *x509.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return cert
+}
+
+func TestGatewayClientCertificate(t *testing.T) {
+	visibility, err := NewVisibility(VisibilityConfig{
+		Rules: []VisibilityRule{{Clients: []string{"team-a"}, Packages: []string{"etcd"}}},
+	})
+	require.NoError(t, err)
+	gateway, cleanup := newGateway(t, visibility)
+	defer cleanup()
+
+	get := func(state *tls.ConnectionState) int {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/GetPackage?name=etcd", nil)
+		req.TLS = state
+		w := httptest.NewRecorder()
+		gateway.ServeHTTP(w, req)
+		return w.Code
+	}
+	cert := clientCertificate(t, "team-a")
+
+	// the common name of a verified certificate identifies the http client, as it does a grpc client
+	require.Equal(t, http.StatusOK, get(&tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{cert}}}))
+	require.Equal(t, http.StatusNotFound, get(&tls.ConnectionState{PeerCertificates: []*x509.Certificate{cert}}))
+	require.Equal(t, http.StatusNotFound, get(nil))
+
+	// clients can't name themselves with the metadata the gateway passes the client on with
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/GetPackage?name=etcd", nil)
+	req.Header.Set(gatewayCertKey, string(cert.Raw))
+	w := httptest.NewRecorder()
+	gateway.ServeHTTP(w, req)
+	require.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestGatewayRateLimit(t *testing.T) {
+	_, gatewayOpts := RateLimitOptions{RequestsPerSecond: 0.001, Burst: 1}.ServerOptionsWithGateway()
+	gateway, cleanup := newGateway(t, nil, gatewayOpts...)
+	defer cleanup()
+
+	get := func(remoteAddr string) int {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/GetPackage?name=etcd", nil)
+		req.RemoteAddr = remoteAddr
+		w := httptest.NewRecorder()
+		gateway.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	// http clients are limited by the address they connect from, not by the connection the gateway calls over
+	require.Equal(t, http.StatusOK, get("10.0.0.1:40000"))
+	require.Equal(t, http.StatusTooManyRequests, get("10.0.0.1:40001"))
+	require.Equal(t, http.StatusOK, get("10.0.0.2:40000"))
+}
@@ -2,20 +2,75 @@ package server
 
 import (
 	"context"
+	"strings"
+	"sync"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
 
 	health "github.com/operator-framework/operator-registry/pkg/api/grpc_health_v1"
 )
 
+const (
+	// FeaturesHeader is the Check response header listing the schema features the served database supports
+	FeaturesHeader = "x-registry-features"
+	// DefaultedFeaturesHeader is the Check response header listing the schema features that were missing
+	// from the served database and are answered with empty defaults
+	DefaultedFeaturesHeader = "x-registry-defaulted-features"
+)
+
 type HealthServer struct {
 	health.UnimplementedHealthServer
+
+	mu                sync.RWMutex
+	notServing        bool
+	features          []string
+	defaultedFeatures []string
 }
 
 var _ health.HealthServer = &HealthServer{}
 
 func NewHealthServer() *HealthServer {
-	return &HealthServer{health.UnimplementedHealthServer{}}
+	return &HealthServer{UnimplementedHealthServer: health.UnimplementedHealthServer{}}
+}
+
+// NewHealthServerWithFeatures returns a HealthServer that reports the effective feature set of the served database
+func NewHealthServerWithFeatures(features, defaultedFeatures []string) *HealthServer {
+	return &HealthServer{
+		UnimplementedHealthServer: health.UnimplementedHealthServer{},
+		features:                  features,
+		defaultedFeatures:         defaultedFeatures,
+	}
+}
+
+// SetServing sets whether Check reports the server as serving, e.g. to report it as not serving while the database it
+// serves is being reloaded
+func (s *HealthServer) SetServing(serving bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.notServing = !serving
+}
+
+// SetFeatures replaces the feature set that Check reports, e.g. with that of a reloaded database
+func (s *HealthServer) SetFeatures(features, defaultedFeatures []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.features = features
+	s.defaultedFeatures = defaultedFeatures
 }
 
 func (s *HealthServer) Check(ctx context.Context, req *health.HealthCheckRequest) (*health.HealthCheckResponse, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.features != nil || s.defaultedFeatures != nil {
+		// the feature set is informational, so failing to send it does not fail the check
+		_ = grpc.SetHeader(ctx, metadata.Pairs(
+			FeaturesHeader, strings.Join(s.features, ","),
+			DefaultedFeaturesHeader, strings.Join(s.defaultedFeatures, ","),
+		))
+	}
+	if s.notServing {
+		return &health.HealthCheckResponse{Status: health.HealthCheckResponse_NOT_SERVING}, nil
+	}
 	return &health.HealthCheckResponse{Status: health.HealthCheckResponse_SERVING}, nil
 }
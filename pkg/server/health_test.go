@@ -0,0 +1,28 @@
+package server
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	health "github.com/operator-framework/operator-registry/pkg/api/grpc_health_v1"
+)
+
+func TestHealthServerServing(t *testing.T) {
+	s := NewHealthServer()
+	res, err := s.Check(context.TODO(), &health.HealthCheckRequest{})
+	require.NoError(t, err)
+	require.Equal(t, health.HealthCheckResponse_SERVING, res.GetStatus())
+
+	// e.g. while the served database is being reloaded
+	s.SetServing(false)
+	res, err = s.Check(context.TODO(), &health.HealthCheckRequest{})
+	require.NoError(t, err)
+	require.Equal(t, health.HealthCheckResponse_NOT_SERVING, res.GetStatus())
+
+	s.SetServing(true)
+	res, err = s.Check(context.TODO(), &health.HealthCheckRequest{})
+	require.NoError(t, err)
+	require.Equal(t, health.HealthCheckResponse_SERVING, res.GetStatus())
+}
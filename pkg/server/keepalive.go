@@ -0,0 +1,74 @@
+package server
+
+import (
+	"fmt"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/keepalive"
+)
+
+// KeepaliveOptions tunes how long the server keeps connections open and how it checks that they are still alive.
+// Load balancers and proxies in front of a catalog may silently drop connections that they think are idle, which
+// clients only notice when their next query times out; pinging clients, or closing connections before the load
+// balancer does, avoids that. Zero values keep the grpc defaults.
+type KeepaliveOptions struct {
+	// MinTime is the shortest interval at which clients may ping; clients that ping more often are disconnected
+	MinTime time.Duration
+	// PermitWithoutStream allows clients to ping while they have no calls in flight
+	PermitWithoutStream bool
+	// Time is how long a connection may go without activity before the server pings the client
+	Time time.Duration
+	// Timeout is how long the server waits for the answer to a ping before closing the connection
+	Timeout time.Duration
+	// MaxConnectionIdle is how long a connection may have no calls in flight before it is closed
+	MaxConnectionIdle time.Duration
+	// MaxConnectionAge is how long a connection may stay open before it is closed, so that clients reconnect and are
+	// spread over new replicas
+	MaxConnectionAge time.Duration
+	// MaxConnectionAgeGrace is how long calls in flight are given to finish once a connection reaches its max age
+	MaxConnectionAgeGrace time.Duration
+}
+
+// Validate returns an error if any of the durations is negative
+func (o KeepaliveOptions) Validate() error {
+	durations := []struct {
+		name  string
+		value time.Duration
+	}{
+		{"keepalive min time", o.MinTime},
+		{"keepalive time", o.Time},
+		{"keepalive timeout", o.Timeout},
+		{"max connection idle", o.MaxConnectionIdle},
+		{"max connection age", o.MaxConnectionAge},
+		{"max connection age grace", o.MaxConnectionAgeGrace},
+	}
+	for _, d := range durations {
+		if d.value < 0 {
+			return fmt.Errorf("%s must not be negative, got %s", d.name, d.value)
+		}
+	}
+	return nil
+}
+
+// ServerOptions returns the grpc server options that apply the keepalive options, none if they are all unset
+func (o KeepaliveOptions) ServerOptions() []grpc.ServerOption {
+	var opts []grpc.ServerOption
+	if o.MinTime != 0 || o.PermitWithoutStream {
+		opts = append(opts, grpc.KeepaliveEnforcementPolicy(keepalive.EnforcementPolicy{
+			MinTime:             o.MinTime,
+			PermitWithoutStream: o.PermitWithoutStream,
+		}))
+	}
+	params := keepalive.ServerParameters{
+		MaxConnectionIdle:     o.MaxConnectionIdle,
+		MaxConnectionAge:      o.MaxConnectionAge,
+		MaxConnectionAgeGrace: o.MaxConnectionAgeGrace,
+		Time:                  o.Time,
+		Timeout:               o.Timeout,
+	}
+	if params != (keepalive.ServerParameters{}) {
+		opts = append(opts, grpc.KeepaliveParams(params))
+	}
+	return opts
+}
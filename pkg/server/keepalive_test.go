@@ -0,0 +1,50 @@
+package server
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/connectivity"
+
+	health "github.com/operator-framework/operator-registry/pkg/api/grpc_health_v1"
+	registryclient "github.com/operator-framework/operator-registry/pkg/client"
+)
+
+func TestKeepaliveOptions(t *testing.T) {
+	require.Empty(t, KeepaliveOptions{}.ServerOptions())
+	require.Len(t, KeepaliveOptions{MinTime: time.Minute}.ServerOptions(), 1)
+	require.Len(t, KeepaliveOptions{PermitWithoutStream: true, MaxConnectionAge: time.Hour}.ServerOptions(), 2)
+
+	require.NoError(t, KeepaliveOptions{}.Validate())
+	require.Error(t, KeepaliveOptions{MaxConnectionIdle: -time.Second}.Validate())
+}
+
+func TestKeepaliveMaxConnectionIdle(t *testing.T) {
+	lis, err := Listen(TCPScheme + "127.0.0.1:0")
+	require.NoError(t, err)
+
+	s := grpc.NewServer(KeepaliveOptions{MaxConnectionIdle: 100 * time.Millisecond}.ServerOptions()...)
+	health.RegisterHealthServer(s, NewHealthServer())
+	go s.Serve(lis)
+	defer s.Stop()
+
+	c, err := registryclient.NewClient(lis.Addr().String())
+	require.NoError(t, err)
+	defer c.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	serving, err := c.HealthCheck(ctx, time.Second)
+	require.NoError(t, err)
+	require.True(t, serving)
+
+	// the server closes the connection once it has been idle for too long. The client may reconnect right away, so
+	// rather than polling for a state other than Ready, which could miss it, wait for the state to change, with a
+	// deadline far beyond the idle timeout.
+	waitCtx, waitCancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer waitCancel()
+	require.True(t, c.Conn.WaitForStateChange(waitCtx, connectivity.Ready), "connection still ready after 30s")
+}
@@ -0,0 +1,96 @@
+package server
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strings"
+
+	"google.golang.org/grpc"
+)
+
+const (
+	// UnixScheme prefixes the path of a unix domain socket in a listen address, e.g. unix:///var/run/registry.sock
+	UnixScheme = "unix://"
+	// TCPScheme optionally prefixes a host:port listen address
+	TCPScheme = "tcp://"
+	// TCP4Scheme prefixes a host:port listen address that is bound to IPv4 only
+	TCP4Scheme = "tcp4://"
+	// TCP6Scheme prefixes a host:port listen address that is bound to IPv6 only, e.g. tcp6://[::]:50051
+	TCP6Scheme = "tcp6://"
+)
+
+// Listen listens on a tcp host:port, or on a unix domain socket when the address starts with unix://. A socket
+// left behind by a server that didn't shut down cleanly is replaced. IPv6 hosts are bracketed, e.g. [::1]:50051, and
+// tcp4:// or tcp6:// bind a single stack where the default would bind both.
+func Listen(address string) (net.Listener, error) {
+	if strings.HasPrefix(address, UnixScheme) {
+		path := strings.TrimPrefix(address, UnixScheme)
+		if path == "" {
+			return nil, fmt.Errorf("no socket path in listen address %q", address)
+		}
+		if info, err := os.Stat(path); err == nil && info.Mode()&os.ModeSocket != 0 {
+			if err := os.Remove(path); err != nil {
+				return nil, err
+			}
+		}
+		return net.Listen("unix", path)
+	}
+	if strings.HasPrefix(address, TCP4Scheme) {
+		return net.Listen("tcp4", strings.TrimPrefix(address, TCP4Scheme))
+	}
+	if strings.HasPrefix(address, TCP6Scheme) {
+		return net.Listen("tcp6", strings.TrimPrefix(address, TCP6Scheme))
+	}
+	return net.Listen("tcp", strings.TrimPrefix(address, TCPScheme))
+}
+
+// ListenAll listens on every address, closing the listeners already opened if one of them fails
+func ListenAll(addresses ...string) ([]net.Listener, error) {
+	var listeners []net.Listener
+	for _, address := range addresses {
+		lis, err := Listen(address)
+		if err != nil {
+			for _, l := range listeners {
+				l.Close()
+			}
+			return nil, fmt.Errorf("error listening on %s: %s", address, err)
+		}
+		listeners = append(listeners, lis)
+	}
+	return listeners, nil
+}
+
+// Serve serves s on every listener until it is stopped, or until serving on one of them fails
+func Serve(s *grpc.Server, listeners []net.Listener) error {
+	errs := make(chan error, len(listeners))
+	for _, lis := range listeners {
+		go func(lis net.Listener) {
+			errs <- s.Serve(lis)
+		}(lis)
+	}
+	for range listeners {
+		if err := <-errs; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ListenAddress returns the address to listen on: the listen flag if it was set, or any interface on the port
+// otherwise
+func ListenAddress(listen, port string) string {
+	if listen != "" {
+		return listen
+	}
+	return ":" + port
+}
+
+// ListenAddresses returns the addresses to listen on: those given with repeated listen flags, or any interface on the
+// port if there were none
+func ListenAddresses(listen []string, port string) []string {
+	if len(listen) > 0 {
+		return listen
+	}
+	return []string{ListenAddress("", port)}
+}
@@ -0,0 +1,108 @@
+package server
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+
+	health "github.com/operator-framework/operator-registry/pkg/api/grpc_health_v1"
+	registryclient "github.com/operator-framework/operator-registry/pkg/client"
+)
+
+func TestListenUnix(t *testing.T) {
+	dir, err := ioutil.TempDir("", "listen-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	socket := filepath.Join(dir, "registry.sock")
+
+	// a socket left behind by an earlier server is replaced
+	stale, err := net.Listen("unix", socket)
+	require.NoError(t, err)
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	require.NoError(t, stale.Close())
+
+	lis, err := Listen(UnixScheme + socket)
+	require.NoError(t, err)
+	require.Equal(t, "unix", lis.Addr().Network())
+
+	s := grpc.NewServer()
+	health.RegisterHealthServer(s, NewHealthServer())
+	go s.Serve(lis)
+	defer s.Stop()
+
+	c, err := registryclient.NewClient(UnixScheme + socket)
+	require.NoError(t, err)
+	defer c.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	serving, err := c.HealthCheck(ctx, time.Second)
+	require.NoError(t, err)
+	require.True(t, serving)
+}
+
+func TestListenAddress(t *testing.T) {
+	require.Equal(t, ":50051", ListenAddress("", "50051"))
+	require.Equal(t, "unix:///var/run/registry.sock", ListenAddress("unix:///var/run/registry.sock", "50051"))
+
+	lis, err := Listen(TCPScheme + "127.0.0.1:0")
+	require.NoError(t, err)
+	require.Equal(t, "tcp", lis.Addr().Network())
+	require.NoError(t, lis.Close())
+
+	_, err = Listen(UnixScheme)
+	require.Error(t, err)
+}
+
+func TestListenAll(t *testing.T) {
+	dir, err := ioutil.TempDir("", "listen-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	socket := UnixScheme + filepath.Join(dir, "registry.sock")
+
+	require.Equal(t, []string{":50051"}, ListenAddresses(nil, "50051"))
+	require.Equal(t, []string{"[::]:50051", socket}, ListenAddresses([]string{"[::]:50051", socket}, "50051"))
+
+	listeners, err := ListenAll(TCP4Scheme+"127.0.0.1:0", TCP6Scheme+"[::1]:0", socket)
+	if err != nil {
+		t.Skipf("dual-stack loopback unavailable: %s", err)
+	}
+
+	s := grpc.NewServer()
+	health.RegisterHealthServer(s, NewHealthServer())
+	served := make(chan error, 1)
+	go func() {
+		served <- Serve(s, listeners)
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	// the server answers on every address it listens on
+	for _, address := range []string{listeners[0].Addr().String(), listeners[1].Addr().String(), socket} {
+		c, err := registryclient.NewClient(address)
+		require.NoError(t, err)
+		serving, err := c.HealthCheck(ctx, time.Second)
+		require.NoError(t, err, address)
+		require.True(t, serving, address)
+		c.Close()
+	}
+
+	s.GracefulStop()
+	require.NoError(t, <-served)
+
+	// a failed listen doesn't leave the others open
+	lis, err := Listen(TCPScheme + "127.0.0.1:0")
+	require.NoError(t, err)
+	defer lis.Close()
+	_, err = ListenAll(socket, lis.Addr().String())
+	require.Error(t, err)
+	_, err = os.Stat(filepath.Join(dir, "registry.sock"))
+	require.True(t, os.IsNotExist(err))
+}
@@ -0,0 +1,236 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/time/rate"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/stats"
+	"google.golang.org/grpc/status"
+)
+
+// Default limits of the registry server commands. Requests aren't limited per client by default, since a single host
+// may make the requests of many clients, such as a catalog operator resolving many subscriptions or the clients
+// behind a NAT, and would see them fail; only the streams of a connection are.
+const (
+	DefaultRateLimit             = 0
+	DefaultRateLimitBurst        = 0
+	DefaultMaxConcurrentRequests = 0
+	DefaultMaxConcurrentStreams  = 100
+)
+
+// Recommended rate limits for catalogs whose clients are spread over many hosts, which let a client load a whole
+// catalog at once while keeping a client that repeats expensive queries, such as ListBundles, in a loop from starving
+// the others
+const (
+	RecommendedRateLimit      = 50
+	RecommendedRateLimitBurst = 100
+)
+
+// watchMethod ends the method of Watch streams, which stay open as long as their clients want updates and so don't
+// count towards the requests a client has in flight
+const watchMethod = "/Watch"
+
+// clientIdleTimeout is how long the state of a client that makes no requests is kept
+const clientIdleTimeout = 5 * time.Minute
+
+// RateLimitOptions limits the requests each client of the server may make, so that a misbehaving client can't starve
+// the others. Clients are told apart by the host they connect from, so a client's connections share its limits.
+// Clients that don't connect from an IP address, such as those of a unix socket, are limited per connection. Health
+// checks aren't limited, so that probes never fail because of the clients being served. Zero values leave the
+// corresponding limit off.
+type RateLimitOptions struct {
+	// RequestsPerSecond is how many requests a client may make per second on average. Requests beyond it fail with
+	// ResourceExhausted.
+	RequestsPerSecond float64
+	// Burst is how many requests a client may make at once on top of its rate, RequestsPerSecond rounded up if unset
+	Burst int
+	// MaxConcurrentRequests is how many requests, streams other than Watch included, a client may have in flight at
+	// once. Requests beyond it fail with ResourceExhausted rather than wait.
+	MaxConcurrentRequests int
+	// MaxConcurrentStreams is how many calls a single connection may have open at once; calls beyond it wait for
+	// earlier ones to finish
+	MaxConcurrentStreams uint32
+}
+
+// Validate returns an error if any of the limits is negative
+func (o RateLimitOptions) Validate() error {
+	if o.RequestsPerSecond < 0 {
+		return fmt.Errorf("rate limit must not be negative, got %v", o.RequestsPerSecond)
+	}
+	if o.Burst < 0 {
+		return fmt.Errorf("rate limit burst must not be negative, got %d", o.Burst)
+	}
+	if o.MaxConcurrentRequests < 0 {
+		return fmt.Errorf("max concurrent requests must not be negative, got %d", o.MaxConcurrentRequests)
+	}
+	return nil
+}
+
+// ServerOptions returns the grpc server options that apply the limits, none if they are all unset. Their interceptors
+// can be combined with others, which run within them; they come with a stats handler, which replaces any other.
+func (o RateLimitOptions) ServerOptions() []grpc.ServerOption {
+	opts, _ := o.ServerOptionsWithGateway()
+	return opts
+}
+
+// ServerOptionsWithGateway returns the options of a grpc server, as ServerOptions does, and those of the Gateway to
+// it, which share the limits of each client so that a client has the same budget over http as over grpc. The
+// gateway's options leave out MaxConcurrentStreams, which its http server has to apply to its connections instead.
+func (o RateLimitOptions) ServerOptionsWithGateway() (serverOpts, gatewayOpts []grpc.ServerOption) {
+	if o.MaxConcurrentStreams != 0 {
+		serverOpts = append(serverOpts, grpc.MaxConcurrentStreams(o.MaxConcurrentStreams))
+	}
+	if o.RequestsPerSecond == 0 && o.MaxConcurrentRequests == 0 {
+		return serverOpts, nil
+	}
+	l := newClientLimiter(o)
+	gatewayOpts = []grpc.ServerOption{grpc.StatsHandler(connectionTagger{}), grpc.ChainUnaryInterceptor(l.unary), grpc.ChainStreamInterceptor(l.stream)}
+	return append(serverOpts, gatewayOpts...), gatewayOpts
+}
+
+// connectionKey is the context key of the number of the connection a request was made on
+type connectionKey struct{}
+
+// connections numbers the connections of the process
+var connections uint64
+
+// connectionTagger numbers each connection, so that clients without a host can be told apart by their connection
+type connectionTagger struct{}
+
+func (connectionTagger) TagConn(ctx context.Context, _ *stats.ConnTagInfo) context.Context {
+	return context.WithValue(ctx, connectionKey{}, atomic.AddUint64(&connections, 1))
+}
+
+func (connectionTagger) HandleConn(context.Context, stats.ConnStats) {}
+
+func (connectionTagger) TagRPC(ctx context.Context, _ *stats.RPCTagInfo) context.Context {
+	return ctx
+}
+
+func (connectionTagger) HandleRPC(context.Context, stats.RPCStats) {}
+
+// clientLimiter tracks the requests of each client
+type clientLimiter struct {
+	options RateLimitOptions
+	now     func() time.Time
+
+	mu        sync.Mutex
+	clients   map[string]*clientState
+	lastSweep time.Time
+}
+
+type clientState struct {
+	limiter  *rate.Limiter
+	inFlight int
+	lastSeen time.Time
+}
+
+func newClientLimiter(options RateLimitOptions) *clientLimiter {
+	if options.Burst == 0 {
+		options.Burst = int(options.RequestsPerSecond)
+		if float64(options.Burst) < options.RequestsPerSecond {
+			options.Burst++
+		}
+	}
+	return &clientLimiter{options: options, now: time.Now, clients: map[string]*clientState{}}
+}
+
+func (l *clientLimiter) unary(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	if strings.HasPrefix(info.FullMethod, healthService) {
+		return handler(ctx, req)
+	}
+	done, err := l.start(ctx, true)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+	return handler(ctx, req)
+}
+
+func (l *clientLimiter) stream(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if strings.HasPrefix(info.FullMethod, healthService) {
+		return handler(srv, ss)
+	}
+	done, err := l.start(ss.Context(), !strings.HasSuffix(info.FullMethod, watchMethod))
+	if err != nil {
+		return err
+	}
+	defer done()
+	return handler(srv, ss)
+}
+
+// start admits a request of the client of ctx, returning a function to call once it is done, or rejects it with
+// ResourceExhausted if the client is over one of its limits. Requests that aren't counted are only rate limited.
+func (l *clientLimiter) start(ctx context.Context, counted bool) (func(), error) {
+	client := clientKey(ctx)
+	now := l.now()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.sweep(now)
+	state, ok := l.clients[client]
+	if !ok {
+		limit := rate.Inf
+		if l.options.RequestsPerSecond != 0 {
+			limit = rate.Limit(l.options.RequestsPerSecond)
+		}
+		state = &clientState{limiter: rate.NewLimiter(limit, l.options.Burst)}
+		l.clients[client] = state
+	}
+	state.lastSeen = now
+
+	if counted && l.options.MaxConcurrentRequests != 0 && state.inFlight >= l.options.MaxConcurrentRequests {
+		return nil, status.Errorf(codes.ResourceExhausted, "too many concurrent requests from %s, at most %d are allowed", client, l.options.MaxConcurrentRequests)
+	}
+	if !state.limiter.AllowN(now, 1) {
+		return nil, status.Errorf(codes.ResourceExhausted, "rate limit of %v requests per second exceeded by %s", l.options.RequestsPerSecond, client)
+	}
+	if !counted {
+		return func() {}, nil
+	}
+	state.inFlight++
+	return func() {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		state.inFlight--
+		state.lastSeen = l.now()
+	}, nil
+}
+
+// sweep forgets the clients that have been idle for a while, so that the state kept doesn't grow with every host
+// that ever connected. A forgotten client starts over with a full burst, which it would have regained by then anyway.
+func (l *clientLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < clientIdleTimeout {
+		return
+	}
+	l.lastSweep = now
+	for client, state := range l.clients {
+		if state.inFlight == 0 && now.Sub(state.lastSeen) >= clientIdleTimeout {
+			delete(l.clients, client)
+		}
+	}
+}
+
+// clientKey identifies the client of a request by the IP address it connects from, without its port, so that the
+// connections of a client share its limits. Clients without one, such as those of a unix socket, which all share the
+// same address, are identified by their connection instead.
+func clientKey(ctx context.Context) string {
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		if host, _, err := net.SplitHostPort(p.Addr.String()); err == nil && net.ParseIP(host) != nil {
+			return host
+		}
+	}
+	if conn, ok := ctx.Value(connectionKey{}).(uint64); ok {
+		return fmt.Sprintf("connection %d", conn)
+	}
+	return ""
+}
@@ -0,0 +1,143 @@
+package server
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	"github.com/operator-framework/operator-registry/pkg/api"
+	health "github.com/operator-framework/operator-registry/pkg/api/grpc_health_v1"
+)
+
+func TestRateLimit(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ratelimit-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	// a rate so low that the burst isn't refilled during the test
+	opts := RateLimitOptions{RequestsPerSecond: 0.001, Burst: 2}
+	require.NoError(t, opts.Validate())
+	s := grpc.NewServer(opts.ServerOptions()...)
+	api.RegisterRegistryServer(s, NewRegistryServer(newCatalogStore(t, filepath.Join(dir, "bundles.db"), "../../manifests")))
+	health.RegisterHealthServer(s, NewHealthServer())
+	lis, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+	go s.Serve(lis)
+	defer s.Stop()
+	conn, err := grpc.Dial(lis.Addr().String(), grpc.WithInsecure())
+	require.NoError(t, err)
+	defer conn.Close()
+	c := api.NewRegistryClient(conn)
+
+	_, err = c.GetPackage(context.TODO(), &api.GetPackageRequest{Name: "etcd"})
+	require.NoError(t, err)
+	stream, err := c.ListPackages(context.TODO(), &api.ListPackageRequest{})
+	require.NoError(t, err)
+	_, err = stream.Recv()
+	require.NoError(t, err)
+
+	// the burst is spent, by the stream as by the unary request
+	_, err = c.GetPackage(context.TODO(), &api.GetPackageRequest{Name: "etcd"})
+	require.Equal(t, codes.ResourceExhausted, status.Code(err))
+
+	// health checks aren't limited
+	for i := 0; i < 3; i++ {
+		_, err = health.NewHealthClient(conn).Check(context.TODO(), &health.HealthCheckRequest{})
+		require.NoError(t, err)
+	}
+
+	// the connections of a unix socket are limited on their own, since they share its address
+	unixLis, err := net.Listen("unix", filepath.Join(dir, "registry.sock"))
+	require.NoError(t, err)
+	go s.Serve(unixLis)
+	for i := 0; i < 2; i++ {
+		unixConn, err := grpc.Dial("unix://"+filepath.Join(dir, "registry.sock"), grpc.WithInsecure())
+		require.NoError(t, err)
+		uc := api.NewRegistryClient(unixConn)
+		for j := 0; j < 2; j++ {
+			_, err = uc.GetPackage(context.TODO(), &api.GetPackageRequest{Name: "etcd"})
+			require.NoError(t, err)
+		}
+		_, err = uc.GetPackage(context.TODO(), &api.GetPackageRequest{Name: "etcd"})
+		require.Equal(t, codes.ResourceExhausted, status.Code(err))
+		unixConn.Close()
+	}
+
+	require.Error(t, RateLimitOptions{RequestsPerSecond: -1}.Validate())
+	require.Error(t, RateLimitOptions{MaxConcurrentRequests: -1}.Validate())
+	require.Empty(t, RateLimitOptions{}.ServerOptions())
+}
+
+func TestClientLimiter(t *testing.T) {
+	now := time.Unix(0, 0)
+	l := newClientLimiter(RateLimitOptions{RequestsPerSecond: 1.5, MaxConcurrentRequests: 2})
+	l.now = func() time.Time { return now }
+	require.Equal(t, 2, l.options.Burst)
+
+	client := func(host string) context.Context {
+		return peer.NewContext(context.TODO(), &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP(host), Port: 40000}})
+	}
+	a, b := client("10.0.0.1"), client("10.0.0.2")
+
+	// a client may have no more than two requests in flight, whatever connections they come from
+	doneFirst, err := l.start(a, true)
+	require.NoError(t, err)
+	doneSecond, err := l.start(peer.NewContext(context.TODO(), &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP("10.0.0.1"), Port: 40001}}), true)
+	require.NoError(t, err)
+	_, err = l.start(a, true)
+	require.Equal(t, codes.ResourceExhausted, status.Code(err))
+
+	// watch streams aren't counted, but spend the rate like any other request
+	now = now.Add(time.Second)
+	_, err = l.start(a, false)
+	require.NoError(t, err)
+
+	// other clients are served meanwhile
+	doneOther, err := l.start(b, true)
+	require.NoError(t, err)
+	doneOther()
+
+	// once a request is done, the client is limited by its rate until it regains tokens
+	doneFirst()
+	doneSecond()
+	_, err = l.start(a, true)
+	require.Equal(t, codes.ResourceExhausted, status.Code(err))
+	now = now.Add(2 * time.Second)
+	done, err := l.start(a, true)
+	require.NoError(t, err)
+	done()
+
+	// idle clients are forgotten
+	now = now.Add(clientIdleTimeout)
+	done, err = l.start(b, true)
+	require.NoError(t, err)
+	done()
+	require.Len(t, l.clients, 1)
+
+	// clients of a unix socket share its address, so they are limited per connection
+	unix := func(conn uint64) context.Context {
+		ctx := context.WithValue(context.TODO(), connectionKey{}, conn)
+		return peer.NewContext(ctx, &peer.Peer{Addr: &net.UnixAddr{Name: "@", Net: "unix"}})
+	}
+	doneFirst, err = l.start(unix(1), true)
+	require.NoError(t, err)
+	doneSecond, err = l.start(unix(1), true)
+	require.NoError(t, err)
+	_, err = l.start(unix(1), true)
+	require.Equal(t, codes.ResourceExhausted, status.Code(err))
+	done, err = l.start(unix(2), true)
+	require.NoError(t, err)
+	done()
+	doneFirst()
+	doneSecond()
+}
@@ -0,0 +1,180 @@
+package server
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/operator-framework/operator-registry/pkg/api"
+	"github.com/operator-framework/operator-registry/pkg/registry"
+)
+
+// pemBlockRegexp matches a PEM encoded block, such as a certificate or a key, embedded in a string
+var pemBlockRegexp = regexp.MustCompile(`(?s)-----BEGIN [A-Z0-9 ]+-----.*?-----END [A-Z0-9 ]+-----\n?`)
+
+// RedactionConfig removes content from the CSVs of the bundles a catalog serves, for catalogs whose consumers may not
+// all see everything their authors put in them. It is read from a YAML or JSON file:
+//
+//	annotations: [alm-examples]
+//	maintainerEmails: true
+//	certificates: true
+//	fields: [spec.links]
+type RedactionConfig struct {
+	// Annotations are the annotations of the CSV to remove, e.g. alm-examples
+	Annotations []string `json:"annotations,omitempty"`
+	// MaintainerEmails removes the email of every maintainer of the CSV
+	MaintainerEmails bool `json:"maintainerEmails,omitempty"`
+	// Certificates removes every PEM encoded certificate or key embedded in a string of the CSV, along with strings
+	// that are a base64 encoded one, such as a caBundle
+	Certificates bool `json:"certificates,omitempty"`
+	// Fields are the dot separated paths of fields of the CSV to remove, e.g. spec.links
+	Fields []string `json:"fields,omitempty"`
+}
+
+// Redaction removes content from the CSVs of served bundles. A nil Redaction serves them whole.
+type Redaction struct {
+	annotations      []string
+	maintainerEmails bool
+	certificates     bool
+	fields           [][]string
+}
+
+// NewRedaction returns the redaction described by config
+func NewRedaction(config RedactionConfig) (*Redaction, error) {
+	r := &Redaction{
+		annotations:      config.Annotations,
+		maintainerEmails: config.MaintainerEmails,
+		certificates:     config.Certificates,
+	}
+	for _, field := range config.Fields {
+		path := strings.Split(field, ".")
+		for _, name := range path {
+			if name == "" {
+				return nil, fmt.Errorf("invalid redacted field %q", field)
+			}
+		}
+		r.fields = append(r.fields, path)
+	}
+	return r, nil
+}
+
+// LoadRedaction reads a redaction config file
+func LoadRedaction(path string) (*Redaction, error) {
+	var config RedactionConfig
+	if err := registry.DecodeFile(path, &config); err != nil {
+		return nil, fmt.Errorf("error reading redaction config %s: %s", path, err)
+	}
+	return NewRedaction(config)
+}
+
+// redactBundle removes the redacted content of the CSV of a bundle, both from its csvJson and from its objects. A
+// bundle whose CSV can't be read is withheld rather than served whole.
+func (r *Redaction) redactBundle(bundle *api.Bundle) error {
+	if r == nil || bundle == nil {
+		return nil
+	}
+	if bundle.CsvJson != "" {
+		csv, err := r.redactCSV(bundle.CsvJson)
+		if err != nil {
+			return status.Errorf(codes.Internal, "couldn't redact bundle %s: %s", bundle.GetCsvName(), err)
+		}
+		bundle.CsvJson = csv
+	}
+	for i, obj := range bundle.Object {
+		csv, err := r.redactCSV(obj)
+		if err != nil {
+			return status.Errorf(codes.Internal, "couldn't redact bundle %s: %s", bundle.GetCsvName(), err)
+		}
+		bundle.Object[i] = csv
+	}
+	return nil
+}
+
+// redactCSV returns obj, a json encoded object, with its redacted content removed if it is a CSV
+func (r *Redaction) redactCSV(obj string) (string, error) {
+	dec := json.NewDecoder(strings.NewReader(obj))
+	// numbers are kept as they were written rather than rounded through a float
+	dec.UseNumber()
+	var csv map[string]interface{}
+	if err := dec.Decode(&csv); err != nil {
+		return "", err
+	}
+	if kind, _ := csv["kind"].(string); kind != "ClusterServiceVersion" {
+		return obj, nil
+	}
+
+	if metadata, ok := csv["metadata"].(map[string]interface{}); ok {
+		if annotations, ok := metadata["annotations"].(map[string]interface{}); ok {
+			for _, annotation := range r.annotations {
+				delete(annotations, annotation)
+			}
+		}
+	}
+	if r.maintainerEmails {
+		if spec, ok := csv["spec"].(map[string]interface{}); ok {
+			maintainers, _ := spec["maintainers"].([]interface{})
+			for _, maintainer := range maintainers {
+				if maintainer, ok := maintainer.(map[string]interface{}); ok {
+					delete(maintainer, "email")
+				}
+			}
+		}
+	}
+	for _, path := range r.fields {
+		removeField(csv, path)
+	}
+	var redacted interface{} = csv
+	if r.certificates {
+		redacted = removeCertificates(csv)
+	}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(redacted); err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(buf.String(), "\n"), nil
+}
+
+// removeField removes the field at path from obj, if it is there
+func removeField(obj map[string]interface{}, path []string) {
+	for _, name := range path[:len(path)-1] {
+		next, ok := obj[name].(map[string]interface{})
+		if !ok {
+			return
+		}
+		obj = next
+	}
+	delete(obj, path[len(path)-1])
+}
+
+// removeCertificates returns value with PEM encoded blocks removed from each of its strings, and with strings that
+// are base64 encoded PEM emptied
+func removeCertificates(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, field := range v {
+			v[key] = removeCertificates(field)
+		}
+		return v
+	case []interface{}:
+		for i, item := range v {
+			v[i] = removeCertificates(item)
+		}
+		return v
+	case string:
+		if decoded, err := base64.StdEncoding.DecodeString(v); err == nil && pemBlockRegexp.Match(decoded) {
+			return ""
+		}
+		return pemBlockRegexp.ReplaceAllString(v, "")
+	default:
+		return value
+	}
+}
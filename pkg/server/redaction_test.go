@@ -0,0 +1,113 @@
+package server
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+
+	"github.com/operator-framework/operator-registry/pkg/api"
+)
+
+const testPEM = "-----BEGIN CERTIFICATE-----\nMIIBdTCCARugAwIBAgIUV2hhdGV2ZXI=\n-----END CERTIFICATE-----\n"
+
+func TestRedactBundle(t *testing.T) {
+	csv := map[string]interface{}{
+		"apiVersion": "operators.coreos.com/v1alpha1",
+		"kind":       "ClusterServiceVersion",
+		"metadata": map[string]interface{}{
+			"name": "etcdoperator.v0.9.2",
+			"annotations": map[string]interface{}{
+				"alm-examples":   "[]",
+				"containerImage": "quay.io/coreos/etcd-operator@sha256:c0301e4686c3ed4206e370b42de5a3bd2229b9fb4906cf85f3f30650424abec2",
+			},
+		},
+		"spec": map[string]interface{}{
+			"replicas":    json.Number("12345678901234567890"),
+			"links":       []interface{}{map[string]interface{}{"name": "Blog", "url": "https://coreos.com/etcd"}},
+			"maintainers": []interface{}{map[string]interface{}{"name": "CoreOS, Inc", "email": "support@coreos.com"}},
+			"description": "Trust this CA:\n" + testPEM + "and nothing else",
+			"webhookdefinitions": []interface{}{map[string]interface{}{
+				"caBundle": base64.StdEncoding.EncodeToString([]byte(testPEM)),
+			}},
+		},
+	}
+	csvJSON, err := json.Marshal(csv)
+	require.NoError(t, err)
+	crdJSON := `{"apiVersion":"apiextensions.k8s.io/v1beta1","kind":"CustomResourceDefinition","metadata":{"annotations":{"alm-examples":"[]"}}}`
+
+	redaction, err := NewRedaction(RedactionConfig{
+		Annotations:      []string{"alm-examples"},
+		MaintainerEmails: true,
+		Certificates:     true,
+		Fields:           []string{"spec.links", "spec.missing.field"},
+	})
+	require.NoError(t, err)
+	bundle := &api.Bundle{CsvName: "etcdoperator.v0.9.2", CsvJson: string(csvJSON), Object: []string{crdJSON, string(csvJSON)}}
+	require.NoError(t, redaction.redactBundle(bundle))
+
+	// the CSV is redacted both as csvJson and among the objects, which are otherwise left as they are
+	require.Equal(t, bundle.CsvJson, bundle.Object[1])
+	require.Equal(t, crdJSON, bundle.Object[0])
+	require.JSONEq(t, `{
+		"apiVersion": "operators.coreos.com/v1alpha1",
+		"kind": "ClusterServiceVersion",
+		"metadata": {
+			"name": "etcdoperator.v0.9.2",
+			"annotations": {
+				"containerImage": "quay.io/coreos/etcd-operator@sha256:c0301e4686c3ed4206e370b42de5a3bd2229b9fb4906cf85f3f30650424abec2"
+			}
+		},
+		"spec": {
+			"replicas": 12345678901234567890,
+			"maintainers": [{"name": "CoreOS, Inc"}],
+			"description": "Trust this CA:\nand nothing else",
+			"webhookdefinitions": [{"caBundle": ""}]
+		}
+	}`, bundle.CsvJson)
+	require.Contains(t, bundle.CsvJson, "12345678901234567890")
+
+	// a nil redaction serves bundles whole
+	var none *Redaction
+	whole := &api.Bundle{CsvJson: string(csvJSON)}
+	require.NoError(t, none.redactBundle(whole))
+	require.Equal(t, string(csvJSON), whole.CsvJson)
+
+	// a CSV that can't be read is withheld
+	require.Error(t, redaction.redactBundle(&api.Bundle{CsvJson: "{"}))
+
+	_, err = NewRedaction(RedactionConfig{Fields: []string{"spec..links"}})
+	require.Error(t, err)
+}
+
+func TestRegistryServerRedaction(t *testing.T) {
+	dir, err := ioutil.TempDir("", "redaction-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	configPath := filepath.Join(dir, "redaction.yaml")
+	require.NoError(t, ioutil.WriteFile(configPath, []byte("annotations: [alm-examples]\nmaintainerEmails: true\n"), 0644))
+	redaction, err := LoadRedaction(configPath)
+	require.NoError(t, err)
+
+	s := NewRegistryServer(newCatalogStore(t, filepath.Join(dir, "bundles.db"), "../../manifests"), WithRedaction(redaction))
+	bundle, err := s.GetBundleForChannel(context.TODO(), &api.GetBundleInChannelRequest{PkgName: "etcd", ChannelName: "alpha"})
+	require.NoError(t, err)
+	require.Equal(t, "etcdoperator.v0.9.2", bundle.GetCsvName())
+	require.NotEmpty(t, bundle.GetCsvJson())
+	for _, obj := range append(bundle.GetObject(), bundle.GetCsvJson()) {
+		require.NotContains(t, obj, "alm-examples")
+		require.NotContains(t, obj, "support@coreos.com")
+	}
+	require.Contains(t, bundle.GetCsvJson(), "CoreOS, Inc")
+
+	res, err := s.GetBundlesForPackage(context.TODO(), &api.GetBundlesForPackageRequest{PkgName: "etcd"})
+	require.NoError(t, err)
+	for _, head := range res.GetHeads() {
+		require.NotContains(t, head.GetCsvJson(), "support@coreos.com")
+	}
+}
@@ -0,0 +1,187 @@
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// RequestIDHeader is the metadata key of the id a request is logged with. Clients may set it to correlate their
+	// own logs with the server's, and are otherwise assigned one. Either way it is sent back in the response headers.
+	RequestIDHeader = "x-request-id"
+	// TraceparentHeader is the metadata key of the W3C trace context of a request, which OpenTelemetry propagates
+	TraceparentHeader = "traceparent"
+)
+
+// maxRequestIDLength bounds the ids clients may choose, since they are logged as they are sent
+const maxRequestIDLength = 128
+
+// healthService prefixes the methods of health checks, which probes make so often that they are only logged at
+// debug level
+const healthService = "/grpc.health.v1.Health/"
+
+// traceparentRegexp matches a W3C trace context: its version, trace id, parent span id and flags
+var traceparentRegexp = regexp.MustCompile(`^([0-9a-f]{2})-([0-9a-f]{32})-([0-9a-f]{16})-([0-9a-f]{2})(-.*)?$`)
+
+// RequestLog logs every request a grpc server handles, with its method, status, duration and an id that is sent back
+// to the client. With trace propagation, requests that carry a W3C trace context, as sent by clients instrumented
+// with OpenTelemetry, are logged with their trace id and the id of a span for the server, and the trace context of
+// that span is sent back in the response headers.
+type RequestLog struct {
+	logger logrus.FieldLogger
+	trace  bool
+}
+
+// NewRequestLog returns a RequestLog that logs to logger, and propagates trace context if trace is set
+func NewRequestLog(logger logrus.FieldLogger, trace bool) *RequestLog {
+	return &RequestLog{logger: logger, trace: trace}
+}
+
+// ServerOptions returns the options that make a grpc server log its requests. They can be combined with other
+// interceptors, which run within them.
+func (l *RequestLog) ServerOptions() []grpc.ServerOption {
+	return []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(l.unary),
+		grpc.ChainStreamInterceptor(l.stream),
+	}
+}
+
+func (l *RequestLog) unary(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	fields, header := l.start(ctx)
+	// the headers are informational, so failing to send them doesn't fail the request
+	_ = grpc.SetHeader(ctx, header)
+	start := time.Now()
+	resp, err := handler(ctx, req)
+	l.log(info.FullMethod, fields, start, err)
+	return resp, err
+}
+
+func (l *RequestLog) stream(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	fields, header := l.start(ss.Context())
+	_ = ss.SetHeader(header)
+	counting := &countingStream{ServerStream: ss}
+	start := time.Now()
+	err := handler(srv, counting)
+	fields["sent"] = counting.sent
+	l.log(info.FullMethod, fields, start, err)
+	return err
+}
+
+// start returns the fields to log a request with and the headers to send back to its client
+func (l *RequestLog) start(ctx context.Context) (logrus.Fields, metadata.MD) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	id := firstValue(md, RequestIDHeader)
+	if !validRequestID(id) {
+		id = randomHex(16)
+	}
+	fields := logrus.Fields{"request_id": id}
+	header := metadata.Pairs(RequestIDHeader, id)
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		fields["peer"] = p.Addr.String()
+	}
+
+	if !l.trace {
+		return fields, header
+	}
+	traceID, parentID, flags, ok := parseTraceparent(firstValue(md, TraceparentHeader))
+	if !ok {
+		return fields, header
+	}
+	spanID := randomHex(8)
+	fields["trace_id"] = traceID
+	fields["parent_span_id"] = parentID
+	fields["span_id"] = spanID
+	header.Set(TraceparentHeader, strings.Join([]string{"00", traceID, spanID, flags}, "-"))
+	return fields, header
+}
+
+func (l *RequestLog) log(method string, fields logrus.Fields, start time.Time, err error) {
+	code := status.Code(err)
+	fields["method"] = method
+	fields["code"] = code.String()
+	fields["duration_ms"] = float64(time.Since(start)) / float64(time.Millisecond)
+	entry := l.logger.WithFields(fields)
+	if err != nil {
+		entry = entry.WithError(err)
+	}
+
+	switch {
+	case code == codes.Internal || code == codes.Unknown || code == codes.DataLoss:
+		entry.Warn("handled request")
+	case strings.HasPrefix(method, healthService):
+		entry.Debug("handled request")
+	default:
+		entry.Info("handled request")
+	}
+}
+
+// countingStream counts the messages a streaming handler sends
+type countingStream struct {
+	grpc.ServerStream
+	sent int
+}
+
+func (s *countingStream) SendMsg(m interface{}) error {
+	if err := s.ServerStream.SendMsg(m); err != nil {
+		return err
+	}
+	s.sent++
+	return nil
+}
+
+// parseTraceparent returns the trace id, parent span id and flags of a W3C trace context, and false if it isn't a
+// valid one
+func parseTraceparent(traceparent string) (traceID, parentID, flags string, ok bool) {
+	m := traceparentRegexp.FindStringSubmatch(traceparent)
+	if m == nil {
+		return "", "", "", false
+	}
+	version, traceID, parentID, flags := m[1], m[2], m[3], m[4]
+	// version ff is invalid, and only later versions may carry more fields
+	if version == "ff" || (version == "00" && m[5] != "") {
+		return "", "", "", false
+	}
+	if strings.Trim(traceID, "0") == "" || strings.Trim(parentID, "0") == "" {
+		return "", "", "", false
+	}
+	return traceID, parentID, flags, true
+}
+
+// validRequestID returns true if a request id chosen by a client is short and printable enough to log
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, r := range id {
+		if r < '!' || r > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+func firstValue(md metadata.MD, key string) string {
+	if values := md.Get(key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// randomHex returns n random bytes, hex encoded
+func randomHex(n int) string {
+	b := make([]byte, n)
+	// the ids only need to be unique enough to tell requests apart in logs
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,144 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/operator-framework/operator-registry/pkg/api"
+	health "github.com/operator-framework/operator-registry/pkg/api/grpc_health_v1"
+)
+
+// syncBuffer is a buffer that the server's goroutines can log to while the test reads it
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) entries(t *testing.T) []map[string]interface{} {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var entries []map[string]interface{}
+	scanner := bufio.NewScanner(bytes.NewReader(b.buf.Bytes()))
+	for scanner.Scan() {
+		var entry map[string]interface{}
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &entry))
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+func TestRequestLog(t *testing.T) {
+	dir, err := ioutil.TempDir("", "requestlog-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	var out syncBuffer
+	logger := logrus.New()
+	logger.Out = &out
+	logger.Formatter = &logrus.JSONFormatter{}
+
+	s := grpc.NewServer(NewRequestLog(logger, true).ServerOptions()...)
+	api.RegisterRegistryServer(s, NewRegistryServer(newCatalogStore(t, filepath.Join(dir, "bundles.db"), "../../manifests")))
+	health.RegisterHealthServer(s, NewHealthServer())
+	lis, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+	go s.Serve(lis)
+	defer s.Stop()
+	conn, err := grpc.Dial(lis.Addr().String(), grpc.WithInsecure())
+	require.NoError(t, err)
+	defer conn.Close()
+	c := api.NewRegistryClient(conn)
+
+	// a request id and trace context sent by the client are kept, and sent back
+	ctx := metadata.AppendToOutgoingContext(context.TODO(),
+		RequestIDHeader, "client-chosen-id",
+		TraceparentHeader, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	var header metadata.MD
+	_, err = c.GetPackage(ctx, &api.GetPackageRequest{Name: "etcd"}, grpc.Header(&header))
+	require.NoError(t, err)
+	require.Equal(t, []string{"client-chosen-id"}, header.Get(RequestIDHeader))
+	traceparent := strings.Split(header.Get(TraceparentHeader)[0], "-")
+	require.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", traceparent[1])
+	require.NotEqual(t, "00f067aa0ba902b7", traceparent[2])
+	require.Equal(t, "01", traceparent[3])
+
+	// the server assigns an id to requests that have none, and counts the messages of streams
+	stream, err := c.ListPackages(context.TODO(), &api.ListPackageRequest{})
+	require.NoError(t, err)
+	for err == nil {
+		_, err = stream.Recv()
+	}
+	header, err = stream.Header()
+	require.NoError(t, err)
+	require.Len(t, header.Get(RequestIDHeader)[0], 32)
+	require.Empty(t, header.Get(TraceparentHeader))
+
+	_, err = c.GetPackage(context.TODO(), &api.GetPackageRequest{Name: "missing"})
+	require.Error(t, err)
+
+	// health checks are only logged at debug level
+	_, err = health.NewHealthClient(conn).Check(context.TODO(), &health.HealthCheckRequest{})
+	require.NoError(t, err)
+
+	entries := out.entries(t)
+	require.Len(t, entries, 3)
+	require.Equal(t, "/api.Registry/GetPackage", entries[0]["method"])
+	require.Equal(t, "OK", entries[0]["code"])
+	require.Equal(t, "client-chosen-id", entries[0]["request_id"])
+	require.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", entries[0]["trace_id"])
+	require.Equal(t, "00f067aa0ba902b7", entries[0]["parent_span_id"])
+	require.Equal(t, traceparent[2], entries[0]["span_id"])
+	require.NotEmpty(t, entries[0]["peer"])
+
+	require.Equal(t, "/api.Registry/ListPackages", entries[1]["method"])
+	require.Equal(t, float64(3), entries[1]["sent"])
+	require.Equal(t, header.Get(RequestIDHeader)[0], entries[1]["request_id"])
+
+	require.Equal(t, "/api.Registry/GetPackage", entries[2]["method"])
+	require.NotEqual(t, "OK", entries[2]["code"])
+	require.NotEmpty(t, entries[2]["error"])
+}
+
+func TestParseTraceparent(t *testing.T) {
+	traceID, parentID, flags, ok := parseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	require.True(t, ok)
+	require.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", traceID)
+	require.Equal(t, "00f067aa0ba902b7", parentID)
+	require.Equal(t, "01", flags)
+
+	// later versions may carry more fields
+	_, _, _, ok = parseTraceparent("01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra")
+	require.True(t, ok)
+
+	for _, invalid := range []string{
+		"",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+		"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01",
+	} {
+		_, _, _, ok := parseTraceparent(invalid)
+		require.False(t, ok, invalid)
+	}
+}
@@ -1,29 +1,73 @@
 package server
 
 import (
+	"errors"
+	"fmt"
+	"sort"
+
 	"github.com/operator-framework/operator-registry/pkg/api"
 	"github.com/operator-framework/operator-registry/pkg/registry"
 
 	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"k8s.io/apimachinery/pkg/labels"
 )
 
 type RegistryServer struct {
 	api.UnimplementedRegistryServer
-	store registry.Query
+	store      registry.Query
+	visibility *Visibility
+	redaction  *Redaction
+	events     *CatalogEvents
 }
 
 var _ api.RegistryServer = &RegistryServer{}
 
-func NewRegistryServer(store registry.Query) *RegistryServer {
-	return &RegistryServer{UnimplementedRegistryServer: api.UnimplementedRegistryServer{},  store: store}
+// RegistryServerOption configures a RegistryServer
+type RegistryServerOption func(*RegistryServer)
+
+// WithVisibility limits the packages each client sees to those visibility allows it
+func WithVisibility(visibility *Visibility) RegistryServerOption {
+	return func(s *RegistryServer) {
+		s.visibility = visibility
+	}
+}
+
+// WithRedaction removes the content redaction withholds from the CSVs of the bundles served
+func WithRedaction(redaction *Redaction) RegistryServerOption {
+	return func(s *RegistryServer) {
+		s.redaction = redaction
+	}
+}
+
+func NewRegistryServer(store registry.Query, opts ...RegistryServerOption) *RegistryServer {
+	s := &RegistryServer{UnimplementedRegistryServer: api.UnimplementedRegistryServer{}, store: store}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
 }
 
 func (s *RegistryServer) ListPackages(req *api.ListPackageRequest, stream api.Registry_ListPackagesServer) error {
-	packageNames, err := s.store.ListPackages(stream.Context())
+	visible, err := s.visibility.filter(stream.Context())
+	if err != nil {
+		return err
+	}
+	var packageNames []string
+	if req.GetAfter() == "" && req.GetLimit() <= 0 {
+		packageNames, err = s.store.ListPackages(stream.Context())
+	} else {
+		packageNames, err = s.listPackagesPage(stream.Context(), visible, req.GetAfter(), int(req.GetLimit()))
+	}
 	if err != nil {
 		return err
 	}
 	for _, p := range packageNames {
+		if !visible.allows(p) {
+			continue
+		}
 		if err := stream.Send(&api.PackageName{Name: p}); err != nil {
 			return err
 		}
@@ -32,12 +76,58 @@ func (s *RegistryServer) ListPackages(req *api.ListPackageRequest, stream api.Re
 	return nil
 }
 
+// listPackagesPage returns a page of the packages a client may see. Packages it may not see would otherwise leave
+// pages short, and an empty page would end paging early, so further pages are read from the store to fill it.
+func (s *RegistryServer) listPackagesPage(ctx context.Context, visible packageFilter, after string, limit int) ([]string, error) {
+	var page []string
+	for {
+		names, err := s.store.ListPackagesPaged(ctx, after, limit)
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range names {
+			if !visible.allows(name) {
+				continue
+			}
+			page = append(page, name)
+			if len(page) == limit {
+				return page, nil
+			}
+		}
+		if limit <= 0 || len(names) < limit {
+			return page, nil
+		}
+		after = names[len(names)-1]
+	}
+}
+
 func (s *RegistryServer) ListBundles(req *api.ListBundlesRequest, stream api.Registry_ListBundlesServer) error {
-	bundles, err := s.store.ListBundles(stream.Context())
+	visible, err := s.visibility.filter(stream.Context())
+	if err != nil {
+		return err
+	}
+	filter, err := s.bundleFilter(stream.Context(), req)
+	if err != nil {
+		return err
+	}
+	if summaryOnly(req.GetFields()) {
+		return s.listBundleSummaries(req, stream, visible, filter)
+	}
+
+	bundles, err := s.store.ListBundlesFiltered(stream.Context(), filter)
 	if err != nil {
 		return err
 	}
 	for _, b := range bundles {
+		if !visible.allows(b.GetPackageName()) {
+			continue
+		}
+		if err := maskBundle(b, req.GetFields()); err != nil {
+			return err
+		}
+		if err := s.redaction.redactBundle(b); err != nil {
+			return err
+		}
 		if err := stream.Send(b); err != nil {
 			return err
 		}
@@ -46,28 +136,210 @@ func (s *RegistryServer) ListBundles(req *api.ListBundlesRequest, stream api.Reg
 	return nil
 }
 
+// listBundleSummaries lists bundles from their summaries, which skips reading their manifests from the database
+func (s *RegistryServer) listBundleSummaries(req *api.ListBundlesRequest, stream api.Registry_ListBundlesServer, visible packageFilter, filter registry.BundleFilter) error {
+	summaries, err := s.store.ListBundleSummariesFiltered(stream.Context(), filter)
+	if err != nil {
+		return err
+	}
+	for _, summary := range summaries {
+		if !visible.allows(summary.PackageName) {
+			continue
+		}
+		b := registry.BundleSummaryToAPIBundle(summary)
+		if err := maskBundle(b, req.GetFields()); err != nil {
+			return err
+		}
+		if err := stream.Send(b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// bundleFilter returns the filter of the bundles a ListBundles request selects. A package is selected by its former
+// name if it was renamed.
+func (s *RegistryServer) bundleFilter(ctx context.Context, req *api.ListBundlesRequest) (registry.BundleFilter, error) {
+	filter := registry.BundleFilter{PackageName: req.GetPkgName(), ChannelName: req.GetChannelName()}
+	if filter.PackageName != "" {
+		if _, err := s.store.GetPackage(ctx, filter.PackageName); err != nil {
+			if current, aliasErr := s.store.GetPackageForAlias(ctx, filter.PackageName); aliasErr == nil {
+				filter.PackageName = current
+			}
+		}
+	}
+	if req.GetLabelSelector() != "" {
+		selector, err := labels.Parse(req.GetLabelSelector())
+		if err != nil {
+			return filter, status.Errorf(codes.InvalidArgument, "invalid label selector %q: %s", req.GetLabelSelector(), err)
+		}
+		filter.Labels = selector
+	}
+	if mode := req.GetInstallMode(); mode != "" {
+		if err := registry.ValidateInstallMode(mode); err != nil {
+			return filter, status.Error(codes.InvalidArgument, err.Error())
+		}
+		filter.InstallMode = mode
+	}
+	return filter, nil
+}
+
+// summaryFields are the fields of a bundle that its summary holds
+var summaryFields = map[string]bool{
+	"csvName":      true,
+	"packageName":  true,
+	"channelName":  true,
+	"bundlePath":   true,
+	"providedApis": true,
+	"requiredApis": true,
+	"version":      true,
+	"skipRange":    true,
+	"replaces":     true,
+	"skips":        true,
+	"id":           true,
+}
+
+// summaryOnly returns true if fields name only fields of a bundle that its summary holds
+func summaryOnly(fields []string) bool {
+	if len(fields) == 0 {
+		return false
+	}
+	for _, field := range fields {
+		if !summaryFields[field] {
+			return false
+		}
+	}
+	return true
+}
+
 func (s *RegistryServer) GetPackage(ctx context.Context, req *api.GetPackageRequest) (*api.Package, error) {
+	visible, err := s.visibility.filter(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var deprecationNotice string
 	packageManifest, err := s.store.GetPackage(ctx, req.GetName())
 	if err != nil {
+		// the package may have been looked up by a name it was renamed from
+		current, aliasErr := s.store.GetPackageForAlias(ctx, req.GetName())
+		if aliasErr != nil {
+			return nil, storeError(err)
+		}
+		packageManifest, err = s.store.GetPackage(ctx, current)
+		if err != nil {
+			return nil, storeError(err)
+		}
+		deprecationNotice = fmt.Sprintf("package %s has been renamed to %s", req.GetName(), current)
+	}
+	if !visible.allows(packageManifest.PackageName) {
+		return nil, status.Errorf(codes.NotFound, "package %s not found", req.GetName())
+	}
+
+	pkg := registry.PackageManifestToAPIPackage(packageManifest)
+	pkg.DeprecationNotice = deprecationNotice
+	if pkg.Id, err = s.store.GetPackageID(ctx, pkg.GetName()); err != nil {
 		return nil, err
 	}
-	return registry.PackageManifestToAPIPackage(packageManifest), nil
+	if req.GetLocale() != "" {
+		if err := s.localizePackage(ctx, pkg, packageManifest, req.GetLocale()); err != nil {
+			return nil, err
+		}
+	}
+	return pkg, nil
+}
+
+// localizePackage fills in the display data of a package from the head of its default channel.
+// It is only done on request so that clients that don't show display data don't pay for the descriptions.
+func (s *RegistryServer) localizePackage(ctx context.Context, pkg *api.Package, manifest *registry.PackageManifest, locale string) error {
+	defaultChannel := manifest.GetDefaultChannel()
+	for _, channel := range manifest.Channels {
+		if channel.Name != defaultChannel {
+			continue
+		}
+		metadata, err := s.store.GetDisplayMetadataForBundle(ctx, channel.CurrentCSVName, locale)
+		if err != nil {
+			return err
+		}
+		pkg.DisplayName = metadata.DisplayName
+		pkg.Description = metadata.Description
+		pkg.Locale = metadata.Locale
+	}
+	return nil
 }
 
 func (s *RegistryServer) GetBundle(ctx context.Context, req *api.GetBundleRequest) (*api.Bundle, error) {
-	return s.store.GetBundle(ctx, req.GetPkgName(), req.GetChannelName(), req.GetCsvName())
+	visible, err := s.visibility.filter(ctx)
+	if err != nil {
+		return nil, err
+	}
+	bundle, err := s.store.GetBundle(ctx, req.GetPkgName(), req.GetChannelName(), req.GetCsvName())
+	if err != nil {
+		current, aliasErr := s.store.GetPackageForAlias(ctx, req.GetPkgName())
+		if aliasErr != nil {
+			return nil, storeError(err)
+		}
+		bundle, err = s.store.GetBundle(ctx, current, req.GetChannelName(), req.GetCsvName())
+	}
+	bundle, err = visibleBundle(bundle, visible, err)
+	bundle, err = s.withBundleID(ctx, bundle, err)
+	return s.redacted(withFields(bundle, req.GetFields(), err))
 }
 
 func (s *RegistryServer) GetBundleForChannel(ctx context.Context, req *api.GetBundleInChannelRequest) (*api.Bundle, error) {
-	return s.store.GetBundleForChannel(ctx, req.GetPkgName(), req.GetChannelName())
+	visible, err := s.visibility.filter(ctx)
+	if err != nil {
+		return nil, err
+	}
+	getBundleForChannel := s.store.GetBundleForChannel
+	if req.GetIncludeDeprecated() {
+		getBundleForChannel = s.store.GetBundleForChannelIncludingDeprecated
+	}
+	bundle, err := getBundleForChannel(ctx, req.GetPkgName(), req.GetChannelName())
+	if err != nil {
+		current, aliasErr := s.store.GetPackageForAlias(ctx, req.GetPkgName())
+		if aliasErr != nil {
+			return nil, storeError(err)
+		}
+		bundle, err = getBundleForChannel(ctx, current, req.GetChannelName())
+	}
+	bundle, err = visibleBundle(bundle, visible, err)
+	bundle, err = s.withBundleID(ctx, bundle, err)
+	return s.redacted(withFields(bundle, req.GetFields(), err))
+}
+
+// GetBundlesForPackage returns a package along with the bundle at the head of each of its channels, so that a client
+// can assemble a package in one call rather than one per channel. The heads carry the stable ids of their bundles,
+// which the store joins in as it lists them, rather than passing through withBundleID one by one.
+func (s *RegistryServer) GetBundlesForPackage(ctx context.Context, req *api.GetBundlesForPackageRequest) (*api.PackageBundles, error) {
+	pkg, err := s.GetPackage(ctx, &api.GetPackageRequest{Name: req.GetPkgName()})
+	if err != nil {
+		return nil, err
+	}
+	heads, err := s.store.GetChannelHeadBundles(ctx, pkg.GetName(), req.GetIncludeDeprecated())
+	if err != nil {
+		return nil, err
+	}
+	for i, head := range heads {
+		if heads[i], err = s.redacted(withFields(head, req.GetFields(), nil)); err != nil {
+			return nil, err
+		}
+	}
+	return &api.PackageBundles{Package: pkg, Heads: heads}, nil
 }
 
 func (s *RegistryServer) GetChannelEntriesThatReplace(req *api.GetAllReplacementsRequest, stream api.Registry_GetChannelEntriesThatReplaceServer) error {
+	visible, err := s.visibility.filter(stream.Context())
+	if err != nil {
+		return err
+	}
 	channelEntries, err := s.store.GetChannelEntriesThatReplace(stream.Context(), req.GetCsvName())
 	if err != nil {
 		return err
 	}
 	for _, e := range channelEntries {
+		if !visible.allows(e.PackageName) {
+			continue
+		}
 		if err := stream.Send(registry.ChannelEntryToAPIChannelEntry(e)); err != nil {
 			return err
 		}
@@ -76,15 +348,36 @@ func (s *RegistryServer) GetChannelEntriesThatReplace(req *api.GetAllReplacement
 }
 
 func (s *RegistryServer) GetBundleThatReplaces(ctx context.Context, req *api.GetReplacementRequest) (*api.Bundle, error) {
-	return s.store.GetBundleThatReplaces(ctx, req.GetCsvName(), req.GetPkgName(), req.GetChannelName())
+	visible, err := s.visibility.filter(ctx)
+	if err != nil {
+		return nil, err
+	}
+	bundle, err := s.store.GetBundleThatReplaces(ctx, req.GetCsvName(), req.GetPkgName(), req.GetChannelName())
+	if err != nil {
+		current, aliasErr := s.store.GetPackageForAlias(ctx, req.GetPkgName())
+		if aliasErr != nil {
+			return nil, storeError(err)
+		}
+		bundle, err = s.store.GetBundleThatReplaces(ctx, req.GetCsvName(), current, req.GetChannelName())
+	}
+	bundle, err = visibleBundle(bundle, visible, err)
+	bundle, err = s.withBundleID(ctx, bundle, err)
+	return s.redacted(withFields(bundle, req.GetFields(), err))
 }
 
 func (s *RegistryServer) GetChannelEntriesThatProvide(req *api.GetAllProvidersRequest, stream api.Registry_GetChannelEntriesThatProvideServer) error {
+	visible, err := s.visibility.filter(stream.Context())
+	if err != nil {
+		return err
+	}
 	channelEntries, err := s.store.GetChannelEntriesThatProvide(stream.Context(), req.GetGroup(), req.GetVersion(), req.GetKind())
 	if err != nil {
 		return err
 	}
 	for _, e := range channelEntries {
+		if !visible.allows(e.PackageName) {
+			continue
+		}
 		if err := stream.Send(registry.ChannelEntryToAPIChannelEntry(e)); err != nil {
 			return err
 		}
@@ -93,11 +386,18 @@ func (s *RegistryServer) GetChannelEntriesThatProvide(req *api.GetAllProvidersRe
 }
 
 func (s *RegistryServer) GetLatestChannelEntriesThatProvide(req *api.GetLatestProvidersRequest, stream api.Registry_GetLatestChannelEntriesThatProvideServer) error {
+	visible, err := s.visibility.filter(stream.Context())
+	if err != nil {
+		return err
+	}
 	channelEntries, err := s.store.GetLatestChannelEntriesThatProvide(stream.Context(), req.GetGroup(), req.GetVersion(), req.GetKind())
 	if err != nil {
 		return err
 	}
 	for _, e := range channelEntries {
+		if !visible.allows(e.PackageName) {
+			continue
+		}
 		if err := stream.Send(registry.ChannelEntryToAPIChannelEntry(e)); err != nil {
 			return err
 		}
@@ -106,5 +406,280 @@ func (s *RegistryServer) GetLatestChannelEntriesThatProvide(req *api.GetLatestPr
 }
 
 func (s *RegistryServer) GetDefaultBundleThatProvides(ctx context.Context, req *api.GetDefaultProviderRequest) (*api.Bundle, error) {
-	return s.store.GetBundleThatProvides(ctx, req.GetGroup(), req.GetVersion(), req.GetKind())
+	visible, err := s.visibility.filter(ctx)
+	if err != nil {
+		return nil, err
+	}
+	bundle, err := s.store.GetBundleThatProvides(ctx, req.GetGroup(), req.GetVersion(), req.GetKind())
+	bundle, err = visibleBundle(bundle, visible, err)
+	bundle, err = s.withBundleID(ctx, bundle, err)
+	return s.redacted(withFields(bundle, req.GetFields(), err))
+}
+
+func (s *RegistryServer) BundlesExist(ctx context.Context, req *api.BundlesExistRequest) (*api.BundlesExistResponse, error) {
+	visible, err := s.visibility.filter(ctx)
+	if err != nil {
+		return nil, err
+	}
+	present, err := s.store.GetPresentBundles(ctx, req.GetBundles())
+	if err != nil {
+		return nil, err
+	}
+
+	found := make(map[string]bool, len(present))
+	for _, b := range present {
+		if found[b], err = s.bundleVisible(ctx, b, visible); err != nil {
+			return nil, err
+		}
+	}
+	res := &api.BundlesExistResponse{}
+	for _, b := range req.GetBundles() {
+		if found[b] {
+			res.Present = append(res.Present, b)
+		} else {
+			res.Missing = append(res.Missing, b)
+		}
+	}
+	return res, nil
+}
+
+func (s *RegistryServer) GetPackageStats(req *api.GetPackageStatsRequest, stream api.Registry_GetPackageStatsServer) error {
+	visible, err := s.visibility.filter(stream.Context())
+	if err != nil {
+		return err
+	}
+	b := budget{method: "GetPackageStats", millis: req.GetTimeBudgetMillis(), continuation: req.GetContinuation()}
+	return s.readPackages(stream, visible, req.GetPackages(), b, func(packages []string) error {
+		stats, err := s.store.GetPackageStats(stream.Context(), packages)
+		if err != nil {
+			return err
+		}
+		for _, pkg := range stats {
+			if !visible.allows(pkg.Name) {
+				continue
+			}
+			if err := stream.Send(registry.PackageStatsToAPIPackageStats(pkg)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// GetBundleStats streams the size and object counts of bundles, largest first, so that the bundles that inflate a
+// catalog can be found. With a time budget, the bundles of the packages read within it are streamed largest first.
+func (s *RegistryServer) GetBundleStats(req *api.GetBundleStatsRequest, stream api.Registry_GetBundleStatsServer) error {
+	visible, err := s.visibility.filter(stream.Context())
+	if err != nil {
+		return err
+	}
+	// bundles the client may not see would leave a limited result short, so those are only limited once filtered
+	limit := int(req.GetLimit())
+	storeLimit := limit
+	if visible != nil {
+		storeLimit = 0
+	}
+	var stats []*registry.BundleStats
+	b := budget{method: "GetBundleStats", millis: req.GetTimeBudgetMillis(), continuation: req.GetContinuation()}
+	err = s.readPackages(stream, visible, req.GetPackages(), b, func(packages []string) error {
+		window, err := s.store.GetBundleStats(stream.Context(), packages, storeLimit)
+		stats = append(stats, window...)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	// the packages of a request with a time budget are read a window at a time, each of them largest first
+	sort.SliceStable(stats, func(i, j int) bool {
+		return stats[i].Size > stats[j].Size
+	})
+	sent := 0
+	for _, bundle := range stats {
+		if !visible.allows(bundle.PackageName) {
+			continue
+		}
+		if limit > 0 && sent == limit {
+			return nil
+		}
+		if err := stream.Send(registry.BundleStatsToAPIBundleStats(bundle)); err != nil {
+			return err
+		}
+		sent++
+	}
+	return nil
+}
+
+func (s *RegistryServer) GetDefaultChannelHeads(req *api.GetDefaultChannelHeadsRequest, stream api.Registry_GetDefaultChannelHeadsServer) error {
+	visible, err := s.visibility.filter(stream.Context())
+	if err != nil {
+		return err
+	}
+	heads, err := s.store.GetDefaultChannelHeads(stream.Context(), req.GetPackages())
+	if err != nil {
+		return err
+	}
+	for _, head := range heads {
+		if !visible.allows(head.PackageName) {
+			continue
+		}
+		if err := stream.Send(registry.BundleSummaryToAPIBundleSummary(head)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *RegistryServer) SearchBundles(req *api.SearchBundlesRequest, stream api.Registry_SearchBundlesServer) error {
+	visible, err := s.visibility.filter(stream.Context())
+	if err != nil {
+		return err
+	}
+	b := budget{method: "SearchBundles", millis: req.GetTimeBudgetMillis(), continuation: req.GetContinuation()}
+	return s.readPackages(stream, visible, nil, b, func(packages []string) error {
+		bundles, err := s.store.SearchBundles(stream.Context(), req.GetQuery(), packages)
+		if err != nil {
+			return err
+		}
+		for _, bundle := range bundles {
+			if !visible.allows(bundle.PackageName) {
+				continue
+			}
+			if err := stream.Send(registry.BundleSummaryToAPIBundleSummary(bundle)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// GetAPIVersion returns the version of the api this server implements and the optional features it supports
+func (s *RegistryServer) GetAPIVersion(ctx context.Context, req *api.GetAPIVersionRequest) (*api.APIVersion, error) {
+	version := &api.APIVersion{
+		Version: api.Version,
+		Features: []string{
+			api.FeatureStreaming,
+			api.FeatureDeprecations,
+			api.FeatureProperties,
+			api.FeatureLocales,
+			api.FeatureBundlesExist,
+			api.FeaturePackageStats,
+			api.FeatureDefaultChannelHeads,
+			api.FeatureBundleFields,
+			api.FeaturePackagePages,
+			api.FeatureBundleSearch,
+			api.FeatureDeprecatedHeads,
+			api.FeatureBundleFilters,
+			api.FeatureBundlesForPackage,
+			api.FeatureBundleStats,
+			api.FeatureTimeBudgets,
+			api.FeatureInstallModes,
+		},
+	}
+	if s.events != nil {
+		version.Features = append(version.Features, api.FeatureWatch)
+	}
+	return version, nil
+}
+
+// visibleBundle hides a bundle looked up from the store from clients that may not see its package
+func visibleBundle(bundle *api.Bundle, visible packageFilter, err error) (*api.Bundle, error) {
+	if err != nil {
+		return nil, storeError(err)
+	}
+	if !visible.allows(bundle.GetPackageName()) {
+		return nil, status.Errorf(codes.NotFound, "bundle %s not found", bundle.GetCsvName())
+	}
+	return bundle, nil
+}
+
+// storeError gives the errors of store lookups of packages and bundles that aren't in it the NotFound code, so that
+// clients can tell them apart from the store failing
+func storeError(err error) error {
+	if errors.Is(err, registry.ErrPackageNotFound) || errors.Is(err, registry.ErrBundleNotFound) {
+		return status.Error(codes.NotFound, err.Error())
+	}
+	return err
+}
+
+// bundleVisible returns true if a client may see a package with a bundle of the given CSV name, image reference or
+// image digest
+func (s *RegistryServer) bundleVisible(ctx context.Context, bundle string, visible packageFilter) (bool, error) {
+	if visible == nil {
+		return true, nil
+	}
+	packages, err := s.store.GetPackagesForBundle(ctx, bundle)
+	if err != nil {
+		return false, err
+	}
+	entries, err := s.store.GetEntriesByBundleImage(ctx, bundle)
+	if err != nil {
+		return false, err
+	}
+	for _, e := range entries {
+		packages = append(packages, e.PackageName)
+	}
+	for _, pkg := range packages {
+		if visible.allows(pkg) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// withBundleID fills in the stable id of a bundle looked up from the store
+func (s *RegistryServer) withBundleID(ctx context.Context, bundle *api.Bundle, err error) (*api.Bundle, error) {
+	if err != nil {
+		return nil, err
+	}
+	if bundle.Id, err = s.store.GetBundleID(ctx, bundle.GetCsvName()); err != nil {
+		return nil, err
+	}
+	return bundle, nil
+}
+
+// withFields masks a bundle looked up from the store down to the fields a client asked for
+func withFields(bundle *api.Bundle, fields []string, err error) (*api.Bundle, error) {
+	if err != nil {
+		return nil, err
+	}
+	if err := maskBundle(bundle, fields); err != nil {
+		return nil, err
+	}
+	return bundle, nil
+}
+
+// redacted removes the content redaction withholds from a bundle looked up from the store
+func (s *RegistryServer) redacted(bundle *api.Bundle, err error) (*api.Bundle, error) {
+	if err != nil {
+		return nil, err
+	}
+	if err := s.redaction.redactBundle(bundle); err != nil {
+		return nil, err
+	}
+	return bundle, nil
+}
+
+// maskBundle clears every field of a bundle that isn't named in fields, so that clients that only need a few fields
+// of many bundles don't have to receive their manifests. A bundle is left whole if no fields are named.
+func maskBundle(bundle *api.Bundle, fields []string) error {
+	if len(fields) == 0 {
+		return nil
+	}
+
+	m := bundle.ProtoReflect()
+	descriptors := m.Descriptor().Fields()
+	keep := make(map[protoreflect.FieldNumber]bool, len(fields))
+	for _, name := range fields {
+		fd := descriptors.ByName(protoreflect.Name(name))
+		if fd == nil {
+			return status.Errorf(codes.InvalidArgument, "bundles have no field %q", name)
+		}
+		keep[fd.Number()] = true
+	}
+	for i := 0; i < descriptors.Len(); i++ {
+		if fd := descriptors.Get(i); !keep[fd.Number()] {
+			m.Clear(fd)
+		}
+	}
+	return nil
 }
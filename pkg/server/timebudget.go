@@ -0,0 +1,119 @@
+package server
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"sort"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/operator-framework/operator-registry/pkg/api"
+)
+
+// budgetWindow is how many packages a request with a time budget reads at a time. The budget is checked between
+// windows, so a request runs over its budget by at most the time it takes to read one.
+var budgetWindow = 8
+
+// continuation is where a request with a time budget left off: the method it was made to, so that it can't be used to
+// continue another, and the last package it read
+type continuation struct {
+	Method string `json:"method"`
+	After  string `json:"after"`
+}
+
+func (c continuation) encode() string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeContinuation(method, token string) (continuation, error) {
+	var c continuation
+	b, err := base64.RawURLEncoding.DecodeString(token)
+	if err == nil {
+		err = json.Unmarshal(b, &c)
+	}
+	if err != nil || c.Method != method {
+		return continuation{}, status.Errorf(codes.InvalidArgument, "invalid continuation %q", token)
+	}
+	return c, nil
+}
+
+// budget is the time budget and continuation of a request to an expensive aggregate rpc
+type budget struct {
+	method       string
+	millis       int32
+	continuation string
+}
+
+// readPackages calls read with the packages of a request, or with nil for every package if it names none. Requests
+// with a time budget or a continuation read the packages the client may see in order of name, a window at a time,
+// and once the budget is spent stop with a continuation in the trailer of the stream that the client can make the
+// same request with to read the rest. At least one window is read, so that continuing always makes progress.
+func (s *RegistryServer) readPackages(stream grpc.ServerStream, visible packageFilter, packages []string, b budget, read func(packages []string) error) error {
+	if b.millis < 0 {
+		return status.Errorf(codes.InvalidArgument, "time budget must not be negative, got %d", b.millis)
+	}
+	if b.millis == 0 && b.continuation == "" {
+		return read(packages)
+	}
+	start := time.Now()
+	var after string
+	if b.continuation != "" {
+		c, err := decodeContinuation(b.method, b.continuation)
+		if err != nil {
+			return err
+		}
+		after = c.After
+	}
+
+	remaining, err := s.budgetedPackages(stream.Context(), visible, packages, after)
+	if err != nil {
+		return err
+	}
+	deadline := start.Add(time.Duration(b.millis) * time.Millisecond)
+	for len(remaining) > 0 {
+		n := budgetWindow
+		if n > len(remaining) {
+			n = len(remaining)
+		}
+		if err := read(remaining[:n]); err != nil {
+			return err
+		}
+		last := remaining[n-1]
+		remaining = remaining[n:]
+		if len(remaining) > 0 && b.millis > 0 && !time.Now().Before(deadline) {
+			next := continuation{Method: b.method, After: last}
+			stream.SetTrailer(metadata.Pairs(api.ContinuationTrailer, next.encode()))
+			return nil
+		}
+	}
+	return nil
+}
+
+// budgetedPackages returns the packages a request with a time budget reads, in order of name: the packages it names,
+// or every package, that the client may see and that come after the package it left off at. Packages the client may
+// not see are left out so that a continuation never names one.
+func (s *RegistryServer) budgetedPackages(ctx context.Context, visible packageFilter, packages []string, after string) ([]string, error) {
+	if len(packages) == 0 {
+		var err error
+		if packages, err = s.store.ListPackages(ctx); err != nil {
+			return nil, err
+		}
+	}
+	seen := map[string]bool{}
+	var out []string
+	for _, pkg := range packages {
+		if seen[pkg] || pkg <= after || !visible.allows(pkg) {
+			continue
+		}
+		seen[pkg] = true
+		out = append(out, pkg)
+	}
+	sort.Strings(out)
+	return out, nil
+}
@@ -0,0 +1,145 @@
+package server
+
+import (
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/operator-framework/operator-registry/pkg/api"
+	"github.com/operator-framework/operator-registry/pkg/registry"
+)
+
+// slowQuery takes longer than any time budget of the tests to read the stats of any package
+type slowQuery struct {
+	registry.Query
+}
+
+func (q slowQuery) GetPackageStats(ctx context.Context, packages []string) ([]*registry.PackageStats, error) {
+	time.Sleep(5 * time.Millisecond)
+	return q.Query.GetPackageStats(ctx, packages)
+}
+
+func (q slowQuery) GetBundleStats(ctx context.Context, packages []string, limit int) ([]*registry.BundleStats, error) {
+	time.Sleep(5 * time.Millisecond)
+	return q.Query.GetBundleStats(ctx, packages, limit)
+}
+
+func TestTimeBudget(t *testing.T) {
+	dir, err := ioutil.TempDir("", "timebudget-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	defer func(window int) { budgetWindow = window }(budgetWindow)
+	budgetWindow = 1
+
+	s := grpc.NewServer()
+	api.RegisterRegistryServer(s, NewRegistryServer(slowQuery{newCatalogStore(t, filepath.Join(dir, "bundles.db"), "../../manifests")}))
+	lis, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+	go s.Serve(lis)
+	defer s.Stop()
+	conn, err := grpc.Dial(lis.Addr().String(), grpc.WithInsecure())
+	require.NoError(t, err)
+	defer conn.Close()
+	c := api.NewRegistryClient(conn)
+
+	// each request reads a single package before its budget is spent, and continues where the last left off
+	var names []string
+	var continuations int
+	req := &api.GetPackageStatsRequest{TimeBudgetMillis: 1}
+	for {
+		stream, err := c.GetPackageStats(context.TODO(), req)
+		require.NoError(t, err)
+		var page []string
+		for {
+			stats, err := stream.Recv()
+			if err == io.EOF {
+				break
+			}
+			require.NoError(t, err)
+			page = append(page, stats.GetName())
+		}
+		require.Len(t, page, 1)
+		names = append(names, page...)
+		next := stream.Trailer().Get(api.ContinuationTrailer)
+		if len(next) == 0 {
+			break
+		}
+		continuations++
+		req.Continuation = next[0]
+	}
+	require.Equal(t, []string{"etcd", "prometheus", "strimzi-kafka-operator"}, names)
+	require.Equal(t, 2, continuations)
+
+	// without a budget, a continuation reads the rest at once
+	stream, err := c.GetPackageStats(context.TODO(), &api.GetPackageStatsRequest{Continuation: continuation{Method: "GetPackageStats", After: "etcd"}.encode()})
+	require.NoError(t, err)
+	var rest []string
+	for {
+		stats, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		rest = append(rest, stats.GetName())
+	}
+	require.Equal(t, []string{"prometheus", "strimzi-kafka-operator"}, rest)
+	require.Empty(t, stream.Trailer().Get(api.ContinuationTrailer))
+
+	// the bundles of the packages read within the budget are streamed largest first
+	bundleStream, err := c.GetBundleStats(context.TODO(), &api.GetBundleStatsRequest{Packages: []string{"strimzi-kafka-operator", "etcd"}, TimeBudgetMillis: 1})
+	require.NoError(t, err)
+	var sizes []int64
+	for {
+		stats, err := bundleStream.Recv()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		require.Equal(t, "etcd", stats.GetPackageName())
+		sizes = append(sizes, stats.GetSize())
+	}
+	require.Len(t, sizes, 3)
+	require.True(t, sizes[0] >= sizes[1] && sizes[1] >= sizes[2])
+	require.Len(t, bundleStream.Trailer().Get(api.ContinuationTrailer), 1)
+
+	// a search with a budget that is never spent finds what a search without one does
+	search := func(req *api.SearchBundlesRequest) []string {
+		stream, err := c.SearchBundles(context.TODO(), req)
+		require.NoError(t, err)
+		var found []string
+		for {
+			bundle, err := stream.Recv()
+			if err == io.EOF {
+				return found
+			}
+			require.NoError(t, err)
+			found = append(found, bundle.GetCsvName())
+		}
+	}
+	found := search(&api.SearchBundlesRequest{Query: "operator"})
+	require.NotEmpty(t, found)
+	require.ElementsMatch(t, found, search(&api.SearchBundlesRequest{Query: "operator", TimeBudgetMillis: 60000}))
+
+	// continuations are only accepted by the rpc that returned them
+	for _, invalid := range []*api.GetBundleStatsRequest{
+		{Continuation: req.GetContinuation()},
+		{Continuation: "not a continuation"},
+		{TimeBudgetMillis: -1},
+	} {
+		stream, err := c.GetBundleStats(context.TODO(), invalid)
+		require.NoError(t, err)
+		_, err = stream.Recv()
+		require.Equal(t, codes.InvalidArgument, status.Code(err))
+	}
+}
@@ -0,0 +1,169 @@
+package server
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// tlsReloadInterval is how often the files of a TLSReloader are checked for changes, at most once per handshake
+const tlsReloadInterval = 5 * time.Second
+
+// TLSConfig returns the tls config of a server with the given serving certificate. When a client CA is given, the
+// client certificates signed by it are verified and identify their clients, but clients may still connect without
+// one, e.g. to identify themselves with a bearer token instead.
+func TLSConfig(certFile, keyFile, clientCAFile string) (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("error loading serving certificate: %s", err)
+	}
+	config := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+	if clientCAFile == "" {
+		return config, nil
+	}
+
+	pem, err := ioutil.ReadFile(clientCAFile)
+	if err != nil {
+		return nil, fmt.Errorf("error reading client CA: %s", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no certificates found in client CA %s", clientCAFile)
+	}
+	config.ClientCAs = pool
+	config.ClientAuth = tls.VerifyClientCertIfGiven
+	return config, nil
+}
+
+// TLSReloader serves the certificate and client CA in its files, reading them again once they change, so that they can
+// be rotated, e.g. by cert-manager or a Kubernetes secret volume, without restarting the server. Files are checked for
+// changes on handshakes, at most every few seconds, rather than watched, since a secret volume swaps a symlink to the
+// directory of its files rather than the files themselves. A rotation that can't be loaded, e.g. because the key has
+// been written but not yet the certificate that goes with it, is logged and the certificate in use is kept.
+type TLSReloader struct {
+	logger                          logrus.FieldLogger
+	certFile, keyFile, clientCAFile string
+	clientAuth                      tls.ClientAuthType
+
+	mu      sync.Mutex
+	config  *tls.Config
+	stamps  []fileStamp
+	checked time.Time
+}
+
+// fileStamp is what tells a version of a file from the next
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+// NewTLSReloader loads the certificate in certFile and keyFile and, unless clientCAFile is empty, the client CA that
+// client certificates are verified against as clientAuth requires
+func NewTLSReloader(logger logrus.FieldLogger, certFile, keyFile, clientCAFile string, clientAuth tls.ClientAuthType) (*TLSReloader, error) {
+	r := &TLSReloader{
+		logger:       logger,
+		certFile:     certFile,
+		keyFile:      keyFile,
+		clientCAFile: clientCAFile,
+		clientAuth:   clientAuth,
+	}
+	stamps, err := r.stamp()
+	if err != nil {
+		return nil, err
+	}
+	if r.config, err = r.load(); err != nil {
+		return nil, err
+	}
+	r.stamps = stamps
+	r.checked = time.Now()
+	return r, nil
+}
+
+// Config returns the tls config of a server that speaks the given application protocols, e.g. h2 for grpc, with
+// the current certificate and client CA of the reloader
+func (r *TLSReloader) Config(nextProtos ...string) *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		NextProtos: nextProtos,
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			config := r.current().Clone()
+			config.NextProtos = nextProtos
+			return config, nil
+		},
+	}
+}
+
+// current returns the config of the current certificate and client CA, loading them again if their files changed
+func (r *TLSReloader) current() *tls.Config {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if time.Since(r.checked) < tlsReloadInterval {
+		return r.config
+	}
+	r.checked = time.Now()
+
+	stamps, err := r.stamp()
+	if err != nil {
+		r.logger.WithError(err).Warn("couldn't check serving certificate for changes")
+		return r.config
+	}
+	if stampsEqual(stamps, r.stamps) {
+		return r.config
+	}
+	config, err := r.load()
+	if err != nil {
+		r.logger.WithError(err).Warn("couldn't reload serving certificate, still serving the previous one")
+		return r.config
+	}
+	r.config = config
+	r.stamps = stamps
+	r.logger.Info("reloaded serving certificate")
+	return r.config
+}
+
+func (r *TLSReloader) load() (*tls.Config, error) {
+	config, err := TLSConfig(r.certFile, r.keyFile, r.clientCAFile)
+	if err != nil {
+		return nil, err
+	}
+	if config.ClientCAs != nil {
+		config.ClientAuth = r.clientAuth
+	}
+	return config, nil
+}
+
+func (r *TLSReloader) stamp() ([]fileStamp, error) {
+	var stamps []fileStamp
+	for _, file := range []string{r.certFile, r.keyFile, r.clientCAFile} {
+		if file == "" {
+			continue
+		}
+		info, err := os.Stat(file)
+		if err != nil {
+			return nil, err
+		}
+		stamps = append(stamps, fileStamp{modTime: info.ModTime(), size: info.Size()})
+	}
+	return stamps, nil
+}
+
+func stampsEqual(a, b []fileStamp) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !a[i].modTime.Equal(b[i].modTime) || a[i].size != b[i].size {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,150 @@
+package server
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+)
+
+type testCert struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+// newTestCert returns a certificate for commonName signed by parent, or self-signed CA if parent is nil
+func newTestCert(t *testing.T, commonName string, parent *testCert) *testCert {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		DNSNames:     []string{commonName},
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	signer, signerKey := template, key
+	if parent == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+		template.KeyUsage = x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature
+	} else {
+		signer, signerKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, signer, &key.PublicKey, signerKey)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return &testCert{cert: cert, key: key}
+}
+
+func (c *testCert) write(t *testing.T, certFile, keyFile string) {
+	require.NoError(t, ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c.cert.Raw}), 0600))
+	if keyFile == "" {
+		return
+	}
+	der, err := x509.MarshalECPrivateKey(c.key)
+	require.NoError(t, err)
+	require.NoError(t, ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}), 0600))
+}
+
+func (c *testCert) tlsCertificate() tls.Certificate {
+	return tls.Certificate{Certificate: [][]byte{c.cert.Raw}, PrivateKey: c.key, Leaf: c.cert}
+}
+
+func TestTLSReloader(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tls-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	certFile, keyFile, caFile := filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key"), filepath.Join(dir, "ca.crt")
+
+	ca := newTestCert(t, "ca", nil)
+	ca.write(t, caFile, "")
+	newTestCert(t, "registry-1", ca).write(t, certFile, keyFile)
+
+	reloader, err := NewTLSReloader(logrus.New(), certFile, keyFile, caFile, tls.RequireAndVerifyClientCert)
+	require.NoError(t, err)
+	lis, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+	lis = tls.NewListener(lis, reloader.Config("h2"))
+	defer lis.Close()
+	go func() {
+		for {
+			conn, err := lis.Accept()
+			if err != nil {
+				return
+			}
+			conn.(*tls.Conn).Handshake()
+			conn.Close()
+		}
+	}()
+
+	client := newTestCert(t, "client", ca)
+	dial := func(certs ...tls.Certificate) (*tls.ConnectionState, error) {
+		conn, err := tls.Dial("tcp", lis.Addr().String(), &tls.Config{
+			Certificates: certs,
+			NextProtos:   []string{"h2"},
+			// the served certificate is checked by name below
+			InsecureSkipVerify: true,
+		})
+		if err != nil {
+			return nil, err
+		}
+		defer conn.Close()
+		// the server only rejects a client certificate once the client reads
+		if _, err := conn.Read(make([]byte, 1)); err != nil && err != io.EOF {
+			return nil, err
+		}
+		state := conn.ConnectionState()
+		return &state, nil
+	}
+
+	state, err := dial(client.tlsCertificate())
+	require.NoError(t, err)
+	require.Equal(t, "registry-1", state.PeerCertificates[0].Subject.CommonName)
+	require.Equal(t, "h2", state.NegotiatedProtocol)
+
+	// clients have to present a certificate signed by the client CA
+	_, err = dial()
+	require.Error(t, err)
+	_, err = dial(newTestCert(t, "client", newTestCert(t, "other-ca", nil)).tlsCertificate())
+	require.Error(t, err)
+
+	// a rotated certificate is served once the files are checked again
+	newTestCert(t, "registry-2", ca).write(t, certFile, keyFile)
+	later := time.Now().Add(time.Minute)
+	require.NoError(t, os.Chtimes(certFile, later, later))
+	state, err = dial(client.tlsCertificate())
+	require.NoError(t, err)
+	require.Equal(t, "registry-1", state.PeerCertificates[0].Subject.CommonName)
+	reloader.mu.Lock()
+	reloader.checked = time.Time{}
+	reloader.mu.Unlock()
+	state, err = dial(client.tlsCertificate())
+	require.NoError(t, err)
+	require.Equal(t, "registry-2", state.PeerCertificates[0].Subject.CommonName)
+
+	// a rotation that can't be loaded keeps the previous certificate
+	require.NoError(t, ioutil.WriteFile(keyFile, []byte("not a key"), 0600))
+	reloader.mu.Lock()
+	reloader.checked = time.Time{}
+	reloader.mu.Unlock()
+	state, err = dial(client.tlsCertificate())
+	require.NoError(t, err)
+	require.Equal(t, "registry-2", state.PeerCertificates[0].Subject.CommonName)
+}
@@ -0,0 +1,293 @@
+package server
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"time"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	jose "gopkg.in/square/go-jose.v2"
+	"gopkg.in/square/go-jose.v2/jwt"
+
+	"github.com/operator-framework/operator-registry/pkg/registry"
+)
+
+// AnyClient in the clients of a rule matches every client, including clients that present no identity, and
+// AnyPackage in its packages allows every package
+const (
+	AnyClient  = "*"
+	AnyPackage = "*"
+)
+
+// defaultTokenClaim names the client a bearer token was issued to
+const defaultTokenClaim = "sub"
+
+// VisibilityConfig limits the packages each client of a catalog sees, so that one catalog deployment can serve
+// different audiences. Clients are identified by the common name of their verified client certificate and by a claim
+// of a verified bearer token. A client sees the packages of every rule that names one of its identities, and nothing
+// if no rule does. It is read from a YAML or JSON file:
+//
+//	tokenPublicKey: /etc/catalog/issuer.pem
+//	tokenAudience: catalog.example.com
+//	tokenIssuer: https://issuer.example.com
+//	tokenClaim: groups
+//	rules:
+//	- clients: [team-a, team-a-ci]
+//	  packages: [etcd, prometheus]
+//	- clients: ["*"]
+//	  packages: [etcd]
+type VisibilityConfig struct {
+	// TokenPublicKey is the path of the PEM encoded RSA or ECDSA public key bearer tokens are signed with. Bearer
+	// tokens are ignored unless it is set.
+	TokenPublicKey string `json:"tokenPublicKey,omitempty"`
+	// TokenAudience is the audience bearer tokens must be issued for, in their aud claim. It must be set along with
+	// TokenPublicKey, so that tokens the issuer signed for other services aren't accepted.
+	TokenAudience string `json:"tokenAudience,omitempty"`
+	// TokenIssuer is the issuer bearer tokens must name in their iss claim, if set
+	TokenIssuer string `json:"tokenIssuer,omitempty"`
+	// TokenClaim is the claim of a bearer token that identifies the client, sub by default. A claim that holds a list,
+	// such as groups, identifies the client by each of its values.
+	TokenClaim string `json:"tokenClaim,omitempty"`
+	// Rules are the packages each client may see
+	Rules []VisibilityRule `json:"rules"`
+}
+
+// VisibilityRule allows clients to see packages
+type VisibilityRule struct {
+	// Clients are the identities the rule applies to
+	Clients []string `json:"clients"`
+	// Packages are the names the packages are currently served under
+	Packages []string `json:"packages"`
+}
+
+// Visibility decides which packages the client of a request may see. A nil Visibility lets every client see every
+// package.
+type Visibility struct {
+	rules    []VisibilityRule
+	claim    string
+	key      crypto.PublicKey
+	audience string
+	issuer   string
+}
+
+// NewVisibility returns the visibility described by config
+func NewVisibility(config VisibilityConfig) (*Visibility, error) {
+	v := &Visibility{rules: config.Rules, claim: config.TokenClaim, audience: config.TokenAudience, issuer: config.TokenIssuer}
+	if v.claim == "" {
+		v.claim = defaultTokenClaim
+	}
+	for i, rule := range config.Rules {
+		if len(rule.Clients) == 0 {
+			return nil, fmt.Errorf("visibility rule %d names no clients", i)
+		}
+	}
+	if config.TokenPublicKey == "" {
+		return v, nil
+	}
+	if config.TokenAudience == "" {
+		return nil, fmt.Errorf("tokenAudience must be set to verify bearer tokens")
+	}
+
+	key, err := loadPublicKey(config.TokenPublicKey)
+	if err != nil {
+		return nil, fmt.Errorf("error reading token public key %s: %s", config.TokenPublicKey, err)
+	}
+	if _, err := keyAlgorithms(key); err != nil {
+		return nil, fmt.Errorf("error reading token public key %s: %s", config.TokenPublicKey, err)
+	}
+	v.key = key
+	return v, nil
+}
+
+// LoadVisibility reads a visibility config file
+func LoadVisibility(path string) (*Visibility, error) {
+	var config VisibilityConfig
+	if err := registry.DecodeFile(path, &config); err != nil {
+		return nil, fmt.Errorf("error reading visibility config %s: %s", path, err)
+	}
+	return NewVisibility(config)
+}
+
+// packageFilter is the set of packages a client may see; a nil filter allows every package
+type packageFilter map[string]bool
+
+func (f packageFilter) allows(pkg string) bool {
+	return f == nil || f[pkg]
+}
+
+// filter returns the packages the client of a request may see. Requests with a bearer token that can't be verified
+// are rejected rather than served as if they had none.
+func (v *Visibility) filter(ctx context.Context) (packageFilter, error) {
+	if v == nil {
+		return nil, nil
+	}
+	identities, err := v.identities(ctx)
+	if err != nil {
+		return nil, status.Errorf(codes.Unauthenticated, "%s", err)
+	}
+
+	filter := packageFilter{}
+	for _, rule := range v.rules {
+		if !rule.matches(identities) {
+			continue
+		}
+		for _, pkg := range rule.Packages {
+			if pkg == AnyPackage {
+				return nil, nil
+			}
+			filter[pkg] = true
+		}
+	}
+	return filter, nil
+}
+
+func (r VisibilityRule) matches(identities []string) bool {
+	for _, client := range r.Clients {
+		if client == AnyClient {
+			return true
+		}
+		for _, identity := range identities {
+			if client == identity {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// identities returns the common name of the verified client certificate of a request and the values of the
+// identifying claim of its bearer token
+func (v *Visibility) identities(ctx context.Context) ([]string, error) {
+	var identities []string
+	if p, ok := peer.FromContext(ctx); ok {
+		if info, ok := p.AuthInfo.(credentials.TLSInfo); ok {
+			// only chains that were verified against the client CA identify a client
+			for _, chain := range info.State.VerifiedChains {
+				if len(chain) > 0 && chain[0].Subject.CommonName != "" {
+					identities = append(identities, chain[0].Subject.CommonName)
+					break
+				}
+			}
+		}
+	}
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	for _, auth := range md.Get("authorization") {
+		if !strings.HasPrefix(auth, "Bearer ") || v.key == nil {
+			continue
+		}
+		claims, err := verifyToken(strings.TrimPrefix(auth, "Bearer "), v.key, v.audience, v.issuer, time.Now())
+		if err != nil {
+			return nil, fmt.Errorf("invalid bearer token: %s", err)
+		}
+		identities = append(identities, claimValues(claims[v.claim])...)
+	}
+	return identities, nil
+}
+
+// claimValues returns the strings a claim holds, whether it holds one or a list
+func claimValues(claim interface{}) []string {
+	switch c := claim.(type) {
+	case string:
+		return []string{c}
+	case []interface{}:
+		var values []string
+		for _, value := range c {
+			if s, ok := value.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	}
+	return nil
+}
+
+// keyAlgorithms returns the JWT signing algorithms tokens verified with key may be signed with: RS256, RS384 and RS512
+// for an RSA key, and for an ECDSA key only the algorithm of its curve
+func keyAlgorithms(key crypto.PublicKey) ([]jose.SignatureAlgorithm, error) {
+	switch k := key.(type) {
+	case *rsa.PublicKey:
+		return []jose.SignatureAlgorithm{jose.RS256, jose.RS384, jose.RS512}, nil
+	case *ecdsa.PublicKey:
+		switch k.Curve {
+		case elliptic.P256():
+			return []jose.SignatureAlgorithm{jose.ES256}, nil
+		case elliptic.P384():
+			return []jose.SignatureAlgorithm{jose.ES384}, nil
+		case elliptic.P521():
+			return []jose.SignatureAlgorithm{jose.ES512}, nil
+		}
+		return nil, fmt.Errorf("unsupported ECDSA curve %s", k.Curve.Params().Name)
+	}
+	return nil, fmt.Errorf("unsupported public key type %T", key)
+}
+
+// verifyToken checks the signature, validity period, audience and, if one is given, issuer of a JWT and returns its
+// claims. The token must be signed with an algorithm of the key, see keyAlgorithms, and must expire, so that a leaked
+// token can't be used forever.
+func verifyToken(token string, key crypto.PublicKey, audience, issuer string, now time.Time) (map[string]interface{}, error) {
+	algorithms, err := keyAlgorithms(key)
+	if err != nil {
+		return nil, err
+	}
+	parsed, err := jwt.ParseSigned(token)
+	if err != nil {
+		return nil, fmt.Errorf("not a JWT: %s", err)
+	}
+	if len(parsed.Headers) != 1 || !containsAlgorithm(algorithms, parsed.Headers[0].Algorithm) {
+		return nil, fmt.Errorf("token not signed with an algorithm of the key, one of %v", algorithms)
+	}
+
+	var standard jwt.Claims
+	var claims map[string]interface{}
+	if err := parsed.Claims(key, &standard, &claims); err != nil {
+		return nil, fmt.Errorf("invalid signature: %s", err)
+	}
+	if standard.Expiry == nil {
+		return nil, fmt.Errorf("token has no expiry")
+	}
+	if err := standard.ValidateWithLeeway(jwt.Expected{Audience: jwt.Audience{audience}, Issuer: issuer, Time: now}, 0); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+func containsAlgorithm(algorithms []jose.SignatureAlgorithm, alg string) bool {
+	for _, a := range algorithms {
+		if string(a) == alg {
+			return true
+		}
+	}
+	return false
+}
+
+func loadPublicKey(path string) (crypto.PublicKey, error) {
+	raw, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(raw)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM data found")
+	}
+	if block.Type == "CERTIFICATE" {
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		return cert.PublicKey, nil
+	}
+	return x509.ParsePKIXPublicKey(block.Bytes)
+}
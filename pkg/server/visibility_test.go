@@ -0,0 +1,293 @@
+package server
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	"github.com/operator-framework/operator-registry/pkg/api"
+	"github.com/operator-framework/operator-registry/pkg/sqlite"
+)
+
+// signToken returns an ES256 JWT with the given claims
+func signToken(t *testing.T, key *ecdsa.PrivateKey, claims map[string]interface{}) string {
+	return signTokenWith(t, key, "ES256", 32, claims)
+}
+
+// signTokenWith returns a JWT with the given claims that names alg in its header and is signed with key, with each
+// half of the signature made size bytes long
+func signTokenWith(t *testing.T, key *ecdsa.PrivateKey, alg string, size int, claims map[string]interface{}) string {
+	header, err := json.Marshal(map[string]string{"alg": alg, "typ": "JWT"})
+	require.NoError(t, err)
+	payload, err := json.Marshal(claims)
+	require.NoError(t, err)
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+
+	hash := map[string]crypto.Hash{"ES256": crypto.SHA256, "ES384": crypto.SHA384, "ES512": crypto.SHA512}[alg]
+	h := hash.New()
+	h.Write([]byte(signed))
+	r, s, err := ecdsa.Sign(rand.Reader, key, h.Sum(nil))
+	require.NoError(t, err)
+	// halves longer than size lose their leading bytes
+	fit := func(b []byte) []byte {
+		if len(b) > size {
+			b = b[len(b)-size:]
+		}
+		return append(make([]byte, size-len(b)), b...)
+	}
+	signature := append(fit(r.Bytes()), fit(s.Bytes())...)
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+// newVisibilityServer serves the catalog of the manifests directory to clients limited by a visibility config that
+// trusts tokens signed by the returned key. The returned func stops the server and removes the catalog.
+func newVisibilityServer(t *testing.T) (api.RegistryClient, *ecdsa.PrivateKey, func()) {
+	dir, err := ioutil.TempDir("", "visibility-")
+	require.NoError(t, err)
+
+	dbPath := filepath.Join(dir, "index.db")
+	db, err := sql.Open("sqlite3", dbPath)
+	require.NoError(t, err)
+	load, err := sqlite.NewSQLLiteLoader(db)
+	require.NoError(t, err)
+	require.NoError(t, load.Migrate(context.TODO()))
+	require.NoError(t, sqlite.NewSQLLoaderForDirectory(load, "../../manifests").Populate())
+	require.NoError(t, db.Close())
+	store, err := sqlite.NewSQLLiteQuerier(dbPath)
+	require.NoError(t, err)
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	require.NoError(t, err)
+	keyPath := filepath.Join(dir, "issuer.pem")
+	require.NoError(t, ioutil.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0644))
+	configPath := filepath.Join(dir, "visibility.yaml")
+	config := "tokenPublicKey: " + keyPath + `
+tokenAudience: catalog
+tokenIssuer: issuer
+rules:
+- clients: [team-a]
+  packages: [etcd]
+- clients: ["*"]
+  packages: [strimzi-kafka-operator]
+`
+	require.NoError(t, ioutil.WriteFile(configPath, []byte(config), 0644))
+	visibility, err := LoadVisibility(configPath)
+	require.NoError(t, err)
+
+	lis, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+	s := grpc.NewServer()
+	api.RegisterRegistryServer(s, NewRegistryServer(store, WithVisibility(visibility)))
+	go s.Serve(lis)
+
+	conn, err := grpc.Dial(lis.Addr().String(), grpc.WithInsecure())
+	require.NoError(t, err)
+	return api.NewRegistryClient(conn), key, func() {
+		conn.Close()
+		s.Stop()
+		os.RemoveAll(dir)
+	}
+}
+
+func withToken(token string) context.Context {
+	return metadata.AppendToOutgoingContext(context.TODO(), "authorization", "Bearer "+token)
+}
+
+func listPackages(t *testing.T, ctx context.Context, c api.RegistryClient, req *api.ListPackageRequest) []string {
+	stream, err := c.ListPackages(ctx, req)
+	require.NoError(t, err)
+	var packages []string
+	for {
+		pkg, err := stream.Recv()
+		if err == io.EOF {
+			return packages
+		}
+		require.NoError(t, err)
+		packages = append(packages, pkg.GetName())
+	}
+}
+
+func TestVisibility(t *testing.T) {
+	c, key, cleanup := newVisibilityServer(t)
+	defer cleanup()
+	valid := func(claims map[string]interface{}) map[string]interface{} {
+		token := map[string]interface{}{"sub": "team-a", "aud": "catalog", "iss": "issuer", "exp": time.Now().Add(time.Hour).Unix()}
+		for claim, value := range claims {
+			if value == nil {
+				delete(token, claim)
+			} else {
+				token[claim] = value
+			}
+		}
+		return token
+	}
+	teamA := withToken(signToken(t, key, valid(nil)))
+
+	require.Equal(t, []string{"etcd", "strimzi-kafka-operator"}, listPackages(t, teamA, c, &api.ListPackageRequest{}))
+	require.Equal(t, []string{"strimzi-kafka-operator"}, listPackages(t, context.TODO(), c, &api.ListPackageRequest{}))
+	// pages are filled past the packages a client may not see
+	require.Equal(t, []string{"strimzi-kafka-operator"}, listPackages(t, context.TODO(), c, &api.ListPackageRequest{Limit: 1}))
+	require.Equal(t, []string{"strimzi-kafka-operator"}, listPackages(t, teamA, c, &api.ListPackageRequest{After: "etcd", Limit: 1}))
+
+	_, err := c.GetPackage(teamA, &api.GetPackageRequest{Name: "etcd"})
+	require.NoError(t, err)
+	_, err = c.GetPackage(context.TODO(), &api.GetPackageRequest{Name: "etcd"})
+	require.Equal(t, codes.NotFound, status.Code(err))
+	// the package is hidden whatever fields are asked for
+	_, err = c.GetBundleForChannel(context.TODO(), &api.GetBundleInChannelRequest{PkgName: "etcd", ChannelName: "alpha", Fields: []string{"csvName"}})
+	require.Equal(t, codes.NotFound, status.Code(err))
+
+	stream, err := c.ListBundles(context.TODO(), &api.ListBundlesRequest{Fields: []string{"csvName"}})
+	require.NoError(t, err)
+	for {
+		bundle, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		require.Contains(t, bundle.GetCsvName(), "strimzi")
+	}
+
+	res, err := c.BundlesExist(context.TODO(), &api.BundlesExistRequest{Bundles: []string{"etcdoperator.v0.9.2"}})
+	require.NoError(t, err)
+	require.Equal(t, []string{"etcdoperator.v0.9.2"}, res.GetMissing())
+	res, err = c.BundlesExist(teamA, &api.BundlesExistRequest{Bundles: []string{"etcdoperator.v0.9.2"}})
+	require.NoError(t, err)
+	require.Equal(t, []string{"etcdoperator.v0.9.2"}, res.GetPresent())
+
+	// tokens that can't be verified are rejected rather than ignored
+	other, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	forged := withToken(signToken(t, other, valid(nil)))
+	_, err = c.GetPackage(forged, &api.GetPackageRequest{Name: "strimzi-kafka-operator"})
+	require.Equal(t, codes.Unauthenticated, status.Code(err))
+	for name, claims := range map[string]map[string]interface{}{
+		"expired":        {"exp": time.Now().Add(-time.Hour).Unix()},
+		"missing exp":    {"exp": nil},
+		"wrong audience": {"aud": "other-service"},
+		"missing aud":    {"aud": nil},
+		"wrong issuer":   {"iss": "other-issuer"},
+	} {
+		_, err = c.GetPackage(withToken(signToken(t, key, valid(claims))), &api.GetPackageRequest{Name: "strimzi-kafka-operator"})
+		require.Equal(t, codes.Unauthenticated, status.Code(err), name)
+	}
+	// the audience may be one of several
+	audiences := withToken(signToken(t, key, valid(map[string]interface{}{"aud": []string{"other-service", "catalog"}})))
+	_, err = c.GetPackage(audiences, &api.GetPackageRequest{Name: "etcd"})
+	require.NoError(t, err)
+}
+
+func TestVisibilityClientCertificate(t *testing.T) {
+	visibility, err := NewVisibility(VisibilityConfig{
+		TokenClaim: "groups",
+		Rules:      []VisibilityRule{{Clients: []string{"team-b"}, Packages: []string{AnyPackage}}},
+	})
+	require.NoError(t, err)
+
+	verified := peer.NewContext(context.TODO(), &peer.Peer{AuthInfo: credentials.TLSInfo{State: tls.ConnectionState{
+		VerifiedChains: [][]*x509.Certificate{{{Subject: pkix.Name{CommonName: "team-b"}}}},
+	}}})
+	filter, err := visibility.filter(verified)
+	require.NoError(t, err)
+	require.True(t, filter.allows("etcd"))
+
+	// certificates that weren't verified don't identify their clients
+	unverified := peer.NewContext(context.TODO(), &peer.Peer{AuthInfo: credentials.TLSInfo{State: tls.ConnectionState{
+		PeerCertificates: []*x509.Certificate{{Subject: pkix.Name{CommonName: "team-b"}}},
+	}}})
+	filter, err = visibility.filter(unverified)
+	require.NoError(t, err)
+	require.False(t, filter.allows("etcd"))
+
+	// without a token public key bearer tokens are ignored
+	filter, err = visibility.filter(metadata.NewIncomingContext(context.TODO(), metadata.Pairs("authorization", "Bearer not-a-token")))
+	require.NoError(t, err)
+	require.False(t, filter.allows("etcd"))
+
+	require.Equal(t, []string{"team-a", "team-b"}, claimValues([]interface{}{"team-a", "team-b"}))
+
+	_, err = NewVisibility(VisibilityConfig{Rules: []VisibilityRule{{Packages: []string{"etcd"}}}})
+	require.Error(t, err)
+	// tokens are only verified for a given audience
+	_, err = NewVisibility(VisibilityConfig{TokenPublicKey: "issuer.pem", Rules: []VisibilityRule{{Clients: []string{"team-b"}}}})
+	require.Error(t, err)
+}
+
+func TestVerifyToken(t *testing.T) {
+	now := time.Now()
+	claims := map[string]interface{}{"sub": "team-a", "aud": "catalog", "exp": now.Add(time.Hour).Unix()}
+	p256, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	p384, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	require.NoError(t, err)
+	p521, err := ecdsa.GenerateKey(elliptic.P521(), rand.Reader)
+	require.NoError(t, err)
+
+	for _, tt := range []struct {
+		key  *ecdsa.PrivateKey
+		alg  string
+		size int
+	}{
+		{key: p256, alg: "ES256", size: 32},
+		{key: p384, alg: "ES384", size: 48},
+		{key: p521, alg: "ES512", size: 66},
+	} {
+		verified, err := verifyToken(signTokenWith(t, tt.key, tt.alg, tt.size, claims), &tt.key.PublicKey, "catalog", "", now)
+		require.NoError(t, err, tt.alg)
+		require.Equal(t, "team-a", verified["sub"], tt.alg)
+	}
+
+	for name, tt := range map[string]struct {
+		token string
+		key   crypto.PublicKey
+	}{
+		// the algorithm must be the one of the curve of the key, even if the signature would verify
+		"ES256 with a P-384 key": {token: signTokenWith(t, p384, "ES256", 48, claims), key: &p384.PublicKey},
+		"ES384 with a P-256 key": {token: signTokenWith(t, p256, "ES384", 32, claims), key: &p256.PublicKey},
+		"ES512 with a P-384 key": {token: signTokenWith(t, p384, "ES512", 48, claims), key: &p384.PublicKey},
+		"RS256 with an ECDSA key": {
+			token: strings.Replace(signToken(t, p256, claims), base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"ES256","typ":"JWT"}`)), base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"RS256","typ":"JWT"}`)), 1),
+			key:   &p256.PublicKey,
+		},
+		"none": {
+			token: base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none"}`)) + "." + strings.Split(signToken(t, p256, claims), ".")[1] + ".",
+			key:   &p256.PublicKey,
+		},
+		// each half of the signature must be exactly as long as the coordinates of the curve
+		"short signature":   {token: signTokenWith(t, p256, "ES256", 31, claims), key: &p256.PublicKey},
+		"padded signature":  {token: signTokenWith(t, p256, "ES256", 33, claims), key: &p256.PublicKey},
+		"P-521 signature":   {token: signTokenWith(t, p521, "ES512", 64, claims), key: &p521.PublicKey},
+		"unsupported curve": {token: signToken(t, p256, claims), key: &ecdsa.PublicKey{Curve: elliptic.P224()}},
+		"not a JWT":         {token: "not-a-token", key: &p256.PublicKey},
+	} {
+		_, err := verifyToken(tt.token, tt.key, "catalog", "", now)
+		require.Error(t, err, name)
+	}
+}
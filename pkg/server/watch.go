@@ -0,0 +1,250 @@
+package server
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/operator-framework/operator-registry/pkg/api"
+	"github.com/operator-framework/operator-registry/pkg/registry"
+)
+
+// watchBuffer is how many changes a watching client may fall behind by before its watch is ended
+const watchBuffer = 16
+
+// CatalogEvents tracks the content of a served catalog and tells the clients watching it what changed each time it
+// is checked, e.g. after the served database is refreshed
+type CatalogEvents struct {
+	store registry.Query
+
+	mu          sync.Mutex
+	snapshot    *catalogSnapshot
+	subscribers map[*subscriber]struct{}
+}
+
+type subscriber struct {
+	events chan []*api.CatalogEvent
+	// behind is closed when the subscriber falls too far behind to be sent more events
+	behind chan struct{}
+}
+
+// NewCatalogEvents takes a snapshot of the content of store, which later checks are compared to
+func NewCatalogEvents(ctx context.Context, store registry.Query) (*CatalogEvents, error) {
+	snapshot, err := takeSnapshot(ctx, store)
+	if err != nil {
+		return nil, err
+	}
+	return &CatalogEvents{store: store, snapshot: snapshot, subscribers: map[*subscriber]struct{}{}}, nil
+}
+
+// Hash returns the content hash of the catalog as of the last check
+func (c *CatalogEvents) Hash() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.snapshot.hash
+}
+
+// Check compares the content of the catalog to the last check and sends what changed to the clients watching it. It
+// returns false if nothing changed.
+func (c *CatalogEvents) Check(ctx context.Context) (bool, error) {
+	snapshot, err := takeSnapshot(ctx, c.store)
+	if err != nil {
+		return false, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if snapshot.hash == c.snapshot.hash {
+		return false, nil
+	}
+	events := diffSnapshots(c.snapshot, snapshot)
+	c.snapshot = snapshot
+	for sub := range c.subscribers {
+		select {
+		case sub.events <- events:
+		default:
+			close(sub.behind)
+			delete(c.subscribers, sub)
+		}
+	}
+	return true, nil
+}
+
+// subscribe returns the hash of the catalog along with a subscriber that is sent every change after it
+func (c *CatalogEvents) subscribe() (string, *subscriber) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	sub := &subscriber{events: make(chan []*api.CatalogEvent, watchBuffer), behind: make(chan struct{})}
+	c.subscribers[sub] = struct{}{}
+	return c.snapshot.hash, sub
+}
+
+func (c *CatalogEvents) unsubscribe(sub *subscriber) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.subscribers, sub)
+}
+
+// WithCatalogEvents lets clients watch the catalog for the changes events finds
+func WithCatalogEvents(events *CatalogEvents) RegistryServerOption {
+	return func(s *RegistryServer) {
+		s.events = events
+	}
+}
+
+// Watch streams the changes to the catalog, or to the named packages of it, starting with a SYNCED event with the
+// current hash. Each change is streamed as an event per package and bundle it added, removed or updated, followed by
+// a SYNCED event with the new hash. A client that falls too far behind has its watch ended with Aborted, and should
+// list the catalog again before watching it again.
+func (s *RegistryServer) Watch(req *api.WatchRequest, stream api.Registry_WatchServer) error {
+	if s.events == nil {
+		return status.Errorf(codes.FailedPrecondition, "catalog changes aren't watched by this server")
+	}
+	visible, err := s.visibility.filter(stream.Context())
+	if err != nil {
+		return err
+	}
+	watched := map[string]struct{}{}
+	for _, pkg := range req.GetPackages() {
+		watched[pkg] = struct{}{}
+	}
+
+	hash, sub := s.events.subscribe()
+	defer s.events.unsubscribe(sub)
+	if err := stream.Send(&api.CatalogEvent{Type: api.CatalogEvent_SYNCED, Hash: hash}); err != nil {
+		return err
+	}
+	for {
+		select {
+		case events := <-sub.events:
+			for _, event := range events {
+				if event.Type != api.CatalogEvent_SYNCED {
+					if _, ok := watched[event.PackageName]; len(watched) > 0 && !ok {
+						continue
+					}
+					if !visible.allows(event.PackageName) {
+						continue
+					}
+				}
+				if err := stream.Send(event); err != nil {
+					return err
+				}
+			}
+		case <-sub.behind:
+			return status.Errorf(codes.Aborted, "fell more than %d changes behind the catalog", watchBuffer)
+		case <-stream.Context().Done():
+			return nil
+		}
+	}
+}
+
+// catalogSnapshot is the content of a catalog that changes are reported on, and its hash
+type catalogSnapshot struct {
+	// packages are the default channel and channels of each package
+	packages map[string]string
+	// bundles are the version, image and upgrade edges of each bundle, by package/channel/csv
+	bundles map[bundleKey]string
+	hash    string
+}
+
+type bundleKey struct {
+	pkg, channel, csv string
+}
+
+func takeSnapshot(ctx context.Context, store registry.Query) (*catalogSnapshot, error) {
+	stats, err := store.GetPackageStats(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	summaries, err := store.ListBundleSummariesFiltered(ctx, registry.BundleFilter{})
+	if err != nil {
+		return nil, err
+	}
+
+	snapshot := &catalogSnapshot{packages: map[string]string{}, bundles: map[bundleKey]string{}}
+	var lines []string
+	for _, pkg := range stats {
+		channels := append([]string(nil), pkg.Channels...)
+		sort.Strings(channels)
+		state := fmt.Sprintf("%s %s", pkg.DefaultChannelName, strings.Join(channels, ","))
+		snapshot.packages[pkg.Name] = state
+		lines = append(lines, fmt.Sprintf("package %s %s", pkg.Name, state))
+	}
+	for _, b := range summaries {
+		key := bundleKey{pkg: b.PackageName, channel: b.ChannelName, csv: b.CsvName}
+		state := fmt.Sprintf("%s %s %s %s %s", b.Version, b.BundlePath, b.Replaces, strings.Join(b.Skips, ","), b.SkipRange)
+		snapshot.bundles[key] = state
+		lines = append(lines, fmt.Sprintf("bundle %s/%s/%s %s", key.pkg, key.channel, key.csv, state))
+	}
+	sort.Strings(lines)
+	sum := sha256.Sum256([]byte(strings.Join(lines, "\n")))
+	snapshot.hash = hex.EncodeToString(sum[:])
+	return snapshot, nil
+}
+
+// diffSnapshots returns the events that turn from into to, packages first, followed by a SYNCED event with the hash
+// of to
+func diffSnapshots(from, to *catalogSnapshot) []*api.CatalogEvent {
+	var events []*api.CatalogEvent
+	for name, state := range to.packages {
+		old, ok := from.packages[name]
+		switch {
+		case !ok:
+			events = append(events, &api.CatalogEvent{Type: api.CatalogEvent_PACKAGE_ADDED, PackageName: name})
+		case old != state:
+			events = append(events, &api.CatalogEvent{Type: api.CatalogEvent_PACKAGE_UPDATED, PackageName: name})
+		}
+	}
+	for name := range from.packages {
+		if _, ok := to.packages[name]; !ok {
+			events = append(events, &api.CatalogEvent{Type: api.CatalogEvent_PACKAGE_REMOVED, PackageName: name})
+		}
+	}
+	for key, state := range to.bundles {
+		old, ok := from.bundles[key]
+		switch {
+		case !ok:
+			events = append(events, bundleEvent(api.CatalogEvent_BUNDLE_ADDED, key))
+		case old != state:
+			events = append(events, bundleEvent(api.CatalogEvent_BUNDLE_UPDATED, key))
+		}
+	}
+	for key := range from.bundles {
+		if _, ok := to.bundles[key]; !ok {
+			events = append(events, bundleEvent(api.CatalogEvent_BUNDLE_REMOVED, key))
+		}
+	}
+
+	sort.SliceStable(events, func(i, j int) bool {
+		a, b := events[i], events[j]
+		if packageEvent(a) != packageEvent(b) {
+			return packageEvent(a)
+		}
+		if a.PackageName != b.PackageName {
+			return a.PackageName < b.PackageName
+		}
+		if a.ChannelName != b.ChannelName {
+			return a.ChannelName < b.ChannelName
+		}
+		if a.CsvName != b.CsvName {
+			return a.CsvName < b.CsvName
+		}
+		return a.Type < b.Type
+	})
+	return append(events, &api.CatalogEvent{Type: api.CatalogEvent_SYNCED, Hash: to.hash})
+}
+
+func bundleEvent(t api.CatalogEvent_Type, key bundleKey) *api.CatalogEvent {
+	return &api.CatalogEvent{Type: t, PackageName: key.pkg, ChannelName: key.channel, CsvName: key.csv}
+}
+
+func packageEvent(e *api.CatalogEvent) bool {
+	return e.Type == api.CatalogEvent_PACKAGE_ADDED || e.Type == api.CatalogEvent_PACKAGE_REMOVED || e.Type == api.CatalogEvent_PACKAGE_UPDATED
+}
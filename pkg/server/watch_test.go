@@ -0,0 +1,140 @@
+package server
+
+import (
+	"database/sql"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/operator-framework/operator-registry/pkg/api"
+	"github.com/operator-framework/operator-registry/pkg/sqlite"
+)
+
+func TestWatch(t *testing.T) {
+	dir, err := ioutil.TempDir("", "watch-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	db, err := sql.Open("sqlite3", filepath.Join(dir, "index.db"))
+	require.NoError(t, err)
+	defer db.Close()
+	load, err := sqlite.NewSQLLiteLoader(db)
+	require.NoError(t, err)
+	require.NoError(t, load.Migrate(context.TODO()))
+	require.NoError(t, sqlite.NewSQLLoaderForDirectory(load, "../../manifests").Populate())
+
+	store := sqlite.NewSQLLiteQuerierFromDb(db)
+	events, err := NewCatalogEvents(context.TODO(), store)
+	require.NoError(t, err)
+	changed, err := events.Check(context.TODO())
+	require.NoError(t, err)
+	require.False(t, changed)
+
+	lis, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+	s := grpc.NewServer()
+	api.RegisterRegistryServer(s, NewRegistryServer(store, WithCatalogEvents(events)))
+	go s.Serve(lis)
+	defer s.Stop()
+	conn, err := grpc.Dial(lis.Addr().String(), grpc.WithInsecure())
+	require.NoError(t, err)
+	defer conn.Close()
+	c := api.NewRegistryClient(conn)
+
+	version, err := c.GetAPIVersion(context.TODO(), &api.GetAPIVersionRequest{})
+	require.NoError(t, err)
+	require.True(t, version.HasFeature(api.FeatureWatch))
+
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	all, err := c.Watch(ctx, &api.WatchRequest{})
+	require.NoError(t, err)
+	etcd, err := c.Watch(ctx, &api.WatchRequest{Packages: []string{"etcd"}})
+	require.NoError(t, err)
+	initial := events.Hash()
+	for _, stream := range []api.Registry_WatchClient{all, etcd} {
+		event, err := stream.Recv()
+		require.NoError(t, err)
+		require.Equal(t, api.CatalogEvent_SYNCED, event.GetType())
+		require.Equal(t, initial, event.GetHash())
+	}
+
+	_, err = db.Exec(`UPDATE package SET default_channel = 'stable' WHERE name = 'etcd'`)
+	require.NoError(t, err)
+	_, err = db.Exec(`UPDATE operatorbundle SET skiprange = '< 0.7.0' WHERE name = 'etcdoperator.v0.9.2'`)
+	require.NoError(t, err)
+	require.NoError(t, load.RemovePackage("prometheus"))
+	changed, err = events.Check(context.TODO())
+	require.NoError(t, err)
+	require.True(t, changed)
+	require.NotEqual(t, initial, events.Hash())
+
+	recv := func(stream api.Registry_WatchClient) []string {
+		var received []string
+		for {
+			event, err := stream.Recv()
+			require.NoError(t, err)
+			if event.GetType() == api.CatalogEvent_SYNCED {
+				require.Equal(t, events.Hash(), event.GetHash())
+				return received
+			}
+			received = append(received, event.GetType().String()+" "+event.GetPackageName()+"/"+event.GetChannelName()+"/"+event.GetCsvName())
+		}
+	}
+	require.Equal(t, []string{
+		"PACKAGE_UPDATED etcd//",
+		"BUNDLE_UPDATED etcd/alpha/etcdoperator.v0.9.2",
+		"BUNDLE_UPDATED etcd/stable/etcdoperator.v0.9.2",
+	}, recv(etcd))
+	received := recv(all)
+	require.Equal(t, []string{
+		"PACKAGE_UPDATED etcd//",
+		"PACKAGE_REMOVED prometheus//",
+		"BUNDLE_UPDATED etcd/alpha/etcdoperator.v0.9.2",
+		"BUNDLE_UPDATED etcd/stable/etcdoperator.v0.9.2",
+	}, received[:4])
+	for _, event := range received[4:] {
+		require.Regexp(t, "^BUNDLE_REMOVED prometheus/", event)
+	}
+
+	// a watcher that falls too far behind is cut off
+	_, sub := events.subscribe()
+	for i := 0; i <= watchBuffer; i++ {
+		channel := "stable"
+		if i%2 == 0 {
+			channel = "alpha"
+		}
+		_, err = db.Exec(`UPDATE package SET default_channel = ? WHERE name = 'etcd'`, channel)
+		require.NoError(t, err)
+		changed, err = events.Check(context.TODO())
+		require.NoError(t, err)
+		require.True(t, changed)
+	}
+	select {
+	case <-sub.behind:
+	default:
+		t.Fatal("expected watcher to be cut off")
+	}
+}
+
+func TestWatchUnsupported(t *testing.T) {
+	c, conn := client(t)
+	defer conn.Close()
+
+	version, err := c.GetAPIVersion(context.TODO(), &api.GetAPIVersionRequest{})
+	require.NoError(t, err)
+	require.False(t, version.HasFeature(api.FeatureWatch))
+
+	stream, err := c.Watch(context.TODO(), &api.WatchRequest{})
+	require.NoError(t, err)
+	_, err = stream.Recv()
+	require.Equal(t, codes.FailedPrecondition, status.Code(err))
+}
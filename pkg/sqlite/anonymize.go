@@ -0,0 +1,448 @@
+package sqlite
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/operator-framework/operator-registry/pkg/registry"
+	"github.com/operator-framework/operator-registry/pkg/sqlite/migrations"
+)
+
+// paddingAnnotation holds the filler that brings the stub csv of a bundle up to the requested blob size
+const paddingAnnotation = "operators.operatorframework.io/fixture-padding"
+
+// Anonymize rewrites a catalog into a fixture that can be shared, e.g. attached to a bug about the performance of a
+// production catalog. The structure of the catalog is kept: its packages, channels, upgrade graph, versions, skip
+// ranges, apis and dependencies. Everything that names or describes an operator is replaced by a stand-in, e.g.
+// package-1 or bundle-12, consistently across tables. The csv and bundle of each bundle are replaced by a stub csv
+// padded to blobSize bytes, so that the fixture weighs roughly what the catalog did. Properties other than packages,
+// apis, labels and deprecations are dropped, as are attestations, tombstones and channel overrides.
+//
+// The database is vacuumed afterwards so that nothing of the original is left in its free pages. It must be migrated
+// to the latest schema first.
+func Anonymize(ctx context.Context, db *sql.DB, blobSize int) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// names are keys that tables reference each other by, so they're only consistent again once all are rewritten
+	if _, err := tx.Exec(`PRAGMA defer_foreign_keys = ON`); err != nil {
+		return err
+	}
+
+	a := &anonymizer{
+		tx:       tx,
+		packages: newNamer("package-%d"),
+		channels: newNamer("channel-%d"),
+		bundles:  newNamer("bundle-%d"),
+		images:   newNamer("registry.example.com/image-%d"),
+		groups:   newNamer("group-%d.example.com"),
+		kinds:    newNamer("Kind%d"),
+		labels:   newNamer("label-%d"),
+		provider: newNamer("Provider %d"),
+	}
+	// names are handed out in a stable order, so the same catalog always gives the same fixture
+	for _, seed := range []struct {
+		namer *namer
+		query string
+	}{
+		{a.packages, `SELECT name FROM package ORDER BY name`},
+		{a.channels, `SELECT DISTINCT name FROM channel ORDER BY name`},
+		{a.bundles, `SELECT name FROM operatorbundle ORDER BY name`},
+	} {
+		if err := a.seed(seed.namer, seed.query); err != nil {
+			return err
+		}
+	}
+
+	for _, table := range []string{"bundle_attestation", "package_tombstone", "channel_override", "channel_selector"} {
+		if _, err := tx.Exec(`DELETE FROM ` + table); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.Exec(`DELETE FROM properties WHERE type NOT IN (?, ?, ?, ?)`, registry.PackageType, registry.GVKType, registry.LabelType, registry.DeprecatedType); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM dependencies WHERE type NOT IN (?, ?, ?)`, registry.PackageType, registry.GVKType, registry.LabelType); err != nil {
+		return err
+	}
+
+	if err := a.rewriteBundles(blobSize); err != nil {
+		return err
+	}
+	if err := a.rewriteAPIs(); err != nil {
+		return err
+	}
+
+	bundleRefs := map[string]func(string) string{"operatorbundle_name": a.bundles.name, "operatorbundle_path": a.images.name}
+	for _, c := range []struct {
+		table   string
+		columns map[string]func(string) string
+	}{
+		{"package", map[string]func(string) string{"name": a.packages.name, "default_channel": a.channels.name}},
+		{"channel", map[string]func(string) string{"name": a.channels.name, "package_name": a.packages.name, "head_operatorbundle_name": a.bundles.name}},
+		{"channel_entry", map[string]func(string) string{"channel_name": a.channels.name, "package_name": a.packages.name, "operatorbundle_name": a.bundles.name}},
+		{"package_alias", map[string]func(string) string{"alias": a.packages.name, "package_name": a.packages.name}},
+		{"api_provider", withRefs(bundleRefs, map[string]func(string) string{"group_name": a.groups.name, "kind": a.kinds.name})},
+		{"api_requirer", withRefs(bundleRefs, map[string]func(string) string{"group_name": a.groups.name, "kind": a.kinds.name})},
+		{"properties", bundleRefs},
+		{"dependencies", bundleRefs},
+		{"bundle_image", withRefs(bundleRefs, map[string]func(string) string{"digest": stubDigest})},
+		{"related_image", map[string]func(string) string{"image": a.images.name, "operatorbundle_name": a.bundles.name}},
+		{"deprecated", map[string]func(string) string{"operatorbundle_name": a.bundles.name}},
+		{"bundle_stats", map[string]func(string) string{"operatorbundle_name": a.bundles.name}},
+	} {
+		for column, rewrite := range c.columns {
+			if err := a.rewriteColumn(c.table, column, rewrite); err != nil {
+				return fmt.Errorf("error rewriting %s.%s: %s", c.table, column, err)
+			}
+		}
+	}
+
+	for _, table := range []string{"properties", "dependencies"} {
+		if err := a.rewriteValues(table); err != nil {
+			return fmt.Errorf("error rewriting %s.value: %s", table, err)
+		}
+	}
+	if err := a.rewriteStableIDs(); err != nil {
+		return err
+	}
+
+	// the search index kept the text of csvs that were cleared, so it is rebuilt from the stand-in names alone. A
+	// database built without FTS5 has no index.
+	var indexed bool
+	if err := tx.QueryRow(`SELECT EXISTS(SELECT 1 FROM sqlite_master WHERE type='table' AND name='bundle_search')`).Scan(&indexed); err != nil {
+		return err
+	}
+	if indexed {
+		if _, err := tx.Exec(`DELETE FROM bundle_search`); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(`INSERT INTO bundle_search(name, display_name, description, keywords) SELECT name, name, '', '' FROM operatorbundle`); err != nil {
+			return err
+		}
+		// deleted text stays in the index until its segments are merged
+		if _, err := tx.Exec(`INSERT INTO bundle_search(bundle_search) VALUES ('optimize')`); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	_, err = db.ExecContext(ctx, `VACUUM`)
+	return err
+}
+
+// namer hands out a stand-in for each distinct name it is given
+type namer struct {
+	format string
+	names  map[string]string
+}
+
+func newNamer(format string) *namer {
+	return &namer{format: format, names: map[string]string{}}
+}
+
+// name returns the stand-in for a name; empty names stay empty
+func (n *namer) name(name string) string {
+	if name == "" {
+		return ""
+	}
+	if standIn, ok := n.names[name]; ok {
+		return standIn
+	}
+	standIn := fmt.Sprintf(n.format, len(n.names)+1)
+	n.names[name] = standIn
+	return standIn
+}
+
+func withRefs(refs, columns map[string]func(string) string) map[string]func(string) string {
+	for column, rewrite := range refs {
+		columns[column] = rewrite
+	}
+	return columns
+}
+
+func stubDigest(value string) string {
+	return fmt.Sprintf("sha256:%x", sha256.Sum256([]byte(value)))
+}
+
+type anonymizer struct {
+	tx                                                                   *sql.Tx
+	packages, channels, bundles, images, groups, kinds, labels, provider *namer
+}
+
+// seed hands out stand-ins for the names a query selects, in the order it selects them
+func (a *anonymizer) seed(n *namer, query string) error {
+	names, err := a.list(query)
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		n.name(name)
+	}
+	return nil
+}
+
+func (a *anonymizer) list(query string) ([]string, error) {
+	rows, err := a.tx.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var names []string
+	for rows.Next() {
+		var name sql.NullString
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		names = append(names, name.String)
+	}
+	return names, rows.Err()
+}
+
+// rewriteColumn replaces every value of a column that isn't null with the value rewrite returns for it
+func (a *anonymizer) rewriteColumn(table, column string, rewrite func(string) string) error {
+	rows, err := a.tx.Query(fmt.Sprintf(`SELECT rowid, %s FROM %s WHERE %s IS NOT NULL`, column, table, column))
+	if err != nil {
+		return err
+	}
+	rewritten := map[int64]string{}
+	for rows.Next() {
+		var rowid int64
+		var value string
+		if err := rows.Scan(&rowid, &value); err != nil {
+			rows.Close()
+			return err
+		}
+		rewritten[rowid] = rewrite(value)
+	}
+	if err := rows.Close(); err != nil {
+		return err
+	}
+	return a.update(table, column, rewritten)
+}
+
+// rewriteValues replaces the names in the json values of the properties or dependencies table
+func (a *anonymizer) rewriteValues(table string) error {
+	rows, err := a.tx.Query(fmt.Sprintf(`SELECT rowid, type, value FROM %s WHERE value IS NOT NULL`, table))
+	if err != nil {
+		return err
+	}
+	rewritten := map[int64]string{}
+	for rows.Next() {
+		var rowid int64
+		var kind, value string
+		if err := rows.Scan(&rowid, &kind, &value); err != nil {
+			rows.Close()
+			return err
+		}
+		if rewritten[rowid], err = a.rewriteValue(kind, value); err != nil {
+			rows.Close()
+			return err
+		}
+	}
+	if err := rows.Close(); err != nil {
+		return err
+	}
+	return a.update(table, "value", rewritten)
+}
+
+func (a *anonymizer) update(table, column string, values map[int64]string) error {
+	update, err := a.tx.Prepare(fmt.Sprintf(`UPDATE %s SET %s = ? WHERE rowid = ?`, table, column))
+	if err != nil {
+		return err
+	}
+	defer update.Close()
+	for rowid, value := range values {
+		if _, err := update.Exec(value, rowid); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rewriteValue replaces the names in the json value of a property or dependency of the given type
+func (a *anonymizer) rewriteValue(kind, value string) (string, error) {
+	fields := map[string]interface{}{}
+	if err := json.Unmarshal([]byte(value), &fields); err != nil {
+		return "", fmt.Errorf("error parsing %s value: %s", kind, err)
+	}
+	rename := map[string]func(string) string{}
+	switch kind {
+	case registry.PackageType:
+		rename["packageName"] = a.packages.name
+	case registry.GVKType:
+		rename["group"] = a.groups.name
+		rename["kind"] = a.kinds.name
+	case registry.LabelType:
+		rename["label"] = a.labels.name
+	}
+	for field, rewrite := range rename {
+		if s, ok := fields[field].(string); ok {
+			fields[field] = rewrite(s)
+		}
+	}
+	out, err := json.Marshal(fields)
+	return string(out), err
+}
+
+// rewriteBundles replaces the names, images and manifests of every bundle
+func (a *anonymizer) rewriteBundles(blobSize int) error {
+	rows, err := a.tx.Query(`SELECT rowid, name, version, replaces, skips, skiprange, bundlepath, provider, csv IS NOT NULL, bundle IS NOT NULL FROM operatorbundle ORDER BY name`)
+	if err != nil {
+		return err
+	}
+	type stub struct {
+		name, version, replaces, skips, skipRange, path, provider string
+		csv, bundle                                               bool
+	}
+	stubs := map[int64]*stub{}
+	for rows.Next() {
+		var rowid int64
+		var name, version, replaces, skips, skipRange, path, provider sql.NullString
+		s := &stub{}
+		if err := rows.Scan(&rowid, &name, &version, &replaces, &skips, &skipRange, &path, &provider, &s.csv, &s.bundle); err != nil {
+			rows.Close()
+			return err
+		}
+		s.name, s.version, s.replaces, s.skipRange = a.bundles.name(name.String), version.String, a.bundles.name(replaces.String), skipRange.String
+		s.path, s.provider = a.images.name(path.String), a.provider.name(provider.String)
+		var standIns []string
+		for _, skip := range strings.Split(skips.String, ",") {
+			if skip != "" {
+				standIns = append(standIns, a.bundles.name(skip))
+			}
+		}
+		s.skips = strings.Join(standIns, ",")
+		stubs[rowid] = s
+	}
+	if err := rows.Close(); err != nil {
+		return err
+	}
+
+	update, err := a.tx.Prepare(`UPDATE operatorbundle SET name = ?, csv = ?, bundle = ?, bundlepath = ?, replaces = ?, skips = ?, provider = ?, maintainers = '[]', links = '[]', digest = ? WHERE rowid = ?`)
+	if err != nil {
+		return err
+	}
+	defer update.Close()
+	for rowid, s := range stubs {
+		csv, err := stubCSV(s.name, s.version, s.replaces, s.skips, s.skipRange, blobSize)
+		if err != nil {
+			return err
+		}
+		var csvValue, bundleValue interface{}
+		if s.csv {
+			csvValue = csv
+		}
+		if s.bundle {
+			bundleValue = csv
+		}
+		if _, err := update.Exec(s.name, csvValue, bundleValue, s.path, s.replaces, s.skips, s.provider, stubDigest(s.name), rowid); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// stubCSV returns a csv with just the fields that place a bundle in the upgrade graph, padded to size bytes
+func stubCSV(name, version, replaces, skips, skipRange string, size int) ([]byte, error) {
+	annotations := map[string]string{}
+	if skipRange != "" {
+		annotations[migrations.SkipRangeAnnotationKey] = skipRange
+	}
+	spec := map[string]interface{}{"displayName": name}
+	if version != "" {
+		spec["version"] = version
+	}
+	if replaces != "" {
+		spec["replaces"] = replaces
+	}
+	if skips != "" {
+		spec["skips"] = strings.Split(skips, ",")
+	}
+	csv := map[string]interface{}{
+		"apiVersion": "operators.coreos.com/v1alpha1",
+		"kind":       "ClusterServiceVersion",
+		"metadata":   map[string]interface{}{"name": name, "annotations": annotations},
+		"spec":       spec,
+	}
+	out, err := json.Marshal(csv)
+	if err != nil {
+		return nil, err
+	}
+	// the padding annotation adds its key and quotes on top of its value
+	if padding := size - len(out) - len(paddingAnnotation) - 6; padding > 0 {
+		annotations[paddingAnnotation] = strings.Repeat("x", padding)
+		return json.Marshal(csv)
+	}
+	return out, nil
+}
+
+// rewriteAPIs replaces the group and kind of every api, and its plural with one that matches the new kind
+func (a *anonymizer) rewriteAPIs() error {
+	rows, err := a.tx.Query(`SELECT rowid, group_name, kind, plural FROM api ORDER BY rowid`)
+	if err != nil {
+		return err
+	}
+	type api struct {
+		rowid               int64
+		group, kind, plural string
+	}
+	var apis []api
+	for rows.Next() {
+		var rowid int64
+		var group, kind, plural sql.NullString
+		if err := rows.Scan(&rowid, &group, &kind, &plural); err != nil {
+			rows.Close()
+			return err
+		}
+		apis = append(apis, api{rowid: rowid, group: a.groups.name(group.String), kind: a.kinds.name(kind.String), plural: plural.String})
+	}
+	if err := rows.Close(); err != nil {
+		return err
+	}
+
+	for _, api := range apis {
+		if api.plural != "" {
+			api.plural = strings.ToLower(api.kind) + "s"
+		}
+		if _, err := a.tx.Exec(`UPDATE api SET group_name = ?, kind = ?, plural = ? WHERE rowid = ?`, api.group, api.kind, api.plural, api.rowid); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rewriteStableIDs gives every package and bundle the id derived from its stand-in, since ids derived from the
+// original names would give them away
+func (a *anonymizer) rewriteStableIDs() error {
+	if _, err := a.tx.Exec(`DELETE FROM stable_id`); err != nil {
+		return err
+	}
+	for _, kind := range []struct {
+		query string
+		add   func(tx *sql.Tx, name string) error
+	}{
+		{`SELECT name FROM package ORDER BY name`, addPackageID},
+		{`SELECT name FROM operatorbundle ORDER BY name`, addBundleID},
+	} {
+		names, err := a.list(kind.query)
+		if err != nil {
+			return err
+		}
+		for _, name := range names {
+			if err := kind.add(a.tx, name); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
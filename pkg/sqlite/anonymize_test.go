@@ -0,0 +1,74 @@
+package sqlite
+
+import (
+	"context"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/operator-framework/operator-registry/pkg/sqlite/migrations"
+)
+
+func TestAnonymize(t *testing.T) {
+	db, cleanup := CreateTestDb(t)
+	defer cleanup()
+	store, err := NewSQLLiteLoader(db)
+	require.NoError(t, err)
+	require.NoError(t, store.Migrate(context.TODO()))
+	require.NoError(t, NewSQLLoaderForDirectory(store, "../../manifests").Populate())
+	require.NoError(t, store.(*sqlLoader).RenamePackage("strimzi-kafka-operator", "strimzi"))
+
+	tables := []string{"package", "channel", "channel_entry", "operatorbundle", "api", "api_provider", "api_requirer", "dependencies", "related_image", "package_alias"}
+	if fullTextSearch, err := migrations.SupportsFullTextSearch(context.TODO(), db); err == nil && fullTextSearch {
+		tables = append(tables, "bundle_search")
+	}
+	counts := func() map[string]int {
+		c := map[string]int{}
+		for _, table := range tables {
+			var n int
+			require.NoError(t, db.QueryRow(`SELECT COUNT(*) FROM `+table).Scan(&n))
+			c[table] = n
+		}
+		return c
+	}
+	before := counts()
+	querier := NewSQLLiteQuerierFromDb(db)
+	heads, err := querier.GetDefaultChannelHeads(context.TODO(), nil)
+	require.NoError(t, err)
+
+	require.NoError(t, Anonymize(context.TODO(), db, 4096))
+
+	// the structure of the catalog is kept
+	require.Equal(t, before, counts())
+	require.NoError(t, ValidateServable(context.TODO(), querier))
+	packages, err := querier.ListPackages(context.TODO())
+	require.NoError(t, err)
+	require.Equal(t, []string{"package-1", "package-2", "package-3"}, packages)
+	anonymizedHeads, err := querier.GetDefaultChannelHeads(context.TODO(), nil)
+	require.NoError(t, err)
+	require.Len(t, anonymizedHeads, len(heads))
+
+	// etcdoperator.v0.9.2 replaces etcdoperator.v0.9.0, the second and third bundles by name
+	bundle, err := querier.GetBundleThatReplaces(context.TODO(), "bundle-2", "package-1", "channel-1")
+	require.NoError(t, err)
+	require.Equal(t, "bundle-3", bundle.CsvName)
+	require.Equal(t, "0.9.2", bundle.Version)
+	require.Equal(t, "< 0.6.0", bundle.SkipRange)
+	require.True(t, len(bundle.CsvJson) >= 4096)
+	require.Contains(t, bundle.CsvJson, `"name":"bundle-3"`)
+	for _, api := range bundle.ProvidedApis {
+		require.True(t, strings.HasSuffix(api.Group, ".example.com"), api.Group)
+	}
+
+	// nothing of the original catalog is left in the file
+	var seq int
+	var name, path string
+	require.NoError(t, db.QueryRow(`PRAGMA database_list`).Scan(&seq, &name, &path))
+	raw, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	for _, original := range []string{"etcd", "prometheus", "strimzi", "kafka", "support@coreos.com", "quay.io"} {
+		require.NotContains(t, strings.ToLower(string(raw)), original)
+	}
+}
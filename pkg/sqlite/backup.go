@@ -0,0 +1,107 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+
+	"github.com/operator-framework/operator-registry/pkg/lib/tmp"
+)
+
+// backupRetryInterval is how long a backup waits before retrying when the database it copies is busy
+const backupRetryInterval = 10 * time.Millisecond
+
+// SafeCopy writes a consistent copy of the database db is open on to a file at path, replacing any database already
+// there. Unlike copying the file, it can be used while the database is being written to: the copy is taken between
+// transactions, and writers are only held off while it is taken.
+func SafeCopy(ctx context.Context, db *sql.DB, path string) error {
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	return writeTo(ctx, conn, path)
+}
+
+// SafeCopyFile writes a consistent copy of the database at from to a file at to, see SafeCopy
+func SafeCopyFile(ctx context.Context, from, to string) error {
+	// only copy regular files, which doesn't create the database if it doesn't exist
+	f, err := tmp.OpenRegularFile(from)
+	if err != nil {
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	db, err := sql.Open("sqlite3", from)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	return SafeCopy(ctx, db, to)
+}
+
+// writeTo copies the database of the src connection to a file at path
+func writeTo(ctx context.Context, src *sql.Conn, path string) error {
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	dst, err := db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer dst.Close()
+
+	if err := backup(ctx, dst, src); err != nil {
+		return fmt.Errorf("error copying database to %s: %s", path, err)
+	}
+	return nil
+}
+
+// backup copies the main database of the src connection over the main database of the dst connection, with the
+// online backup API SQLite provides for copying databases between connections
+func backup(ctx context.Context, dst, src *sql.Conn) error {
+	return dst.Raw(func(dstDriverConn interface{}) error {
+		return src.Raw(func(srcDriverConn interface{}) error {
+			dstConn, ok := dstDriverConn.(*sqlite3.SQLiteConn)
+			if !ok {
+				return fmt.Errorf("unexpected driver connection %T", dstDriverConn)
+			}
+			srcConn, ok := srcDriverConn.(*sqlite3.SQLiteConn)
+			if !ok {
+				return fmt.Errorf("unexpected driver connection %T", srcDriverConn)
+			}
+
+			b, err := dstConn.Backup("main", srcConn, "main")
+			if err != nil {
+				return err
+			}
+			for {
+				// copy everything in one step, so that writes in between steps can't restart the copy. The step is
+				// retried while the source is locked by a writer.
+				done, err := b.Step(-1)
+				if err != nil {
+					b.Finish()
+					return err
+				}
+				if done {
+					break
+				}
+				select {
+				case <-ctx.Done():
+					b.Finish()
+					return ctx.Err()
+				case <-time.After(backupRetryInterval):
+				}
+			}
+			return b.Finish()
+		})
+	})
+}
@@ -0,0 +1,74 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math/rand"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSafeCopy(t *testing.T) {
+	ctx := context.TODO()
+	db, cleanup := createLoadedTestDb(t)
+	defer cleanup()
+
+	// keep writing to the database while it is copied
+	_, err := db.Exec("CREATE TABLE writes (n INTEGER)")
+	require.NoError(t, err)
+	stop := make(chan struct{})
+	written := make(chan error)
+	go func() {
+		for n := 0; ; n++ {
+			select {
+			case <-stop:
+				written <- nil
+				return
+			default:
+			}
+			if _, err := db.Exec("INSERT INTO writes (n) VALUES (?)", n); err != nil {
+				written <- err
+				return
+			}
+		}
+	}()
+
+	path := fmt.Sprintf("test-%d.db", rand.Int())
+	defer os.Remove(path)
+	err = SafeCopy(ctx, db, path)
+	close(stop)
+	require.NoError(t, <-written)
+	require.NoError(t, err)
+
+	copied, err := sql.Open("sqlite3", path)
+	require.NoError(t, err)
+	defer copied.Close()
+
+	var integrity string
+	require.NoError(t, copied.QueryRow("PRAGMA integrity_check").Scan(&integrity))
+	require.Equal(t, "ok", integrity)
+
+	// the copy has every write up to the point it was taken
+	var count, max int
+	require.NoError(t, copied.QueryRow("SELECT COUNT(*), IFNULL(MAX(n), -1) FROM writes").Scan(&count, &max))
+	require.Equal(t, max+1, count)
+
+	bundles, err := NewSQLLiteQuerierFromDb(db).ListBundles(ctx)
+	require.NoError(t, err)
+	copiedBundles, err := NewSQLLiteQuerierFromDb(copied).ListBundles(ctx)
+	require.NoError(t, err)
+	require.ElementsMatch(t, bundles, copiedBundles)
+}
+
+func TestSafeCopyFile(t *testing.T) {
+	ctx := context.TODO()
+	path := fmt.Sprintf("test-%d.db", rand.Int())
+	defer os.Remove(path)
+
+	require.Error(t, SafeCopyFile(ctx, "does-not-exist.db", path))
+	_, err := os.Stat("does-not-exist.db")
+	require.True(t, os.IsNotExist(err), "the missing database shouldn't be created")
+}
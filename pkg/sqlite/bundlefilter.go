@@ -0,0 +1,89 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"strings"
+
+	"github.com/operator-framework/operator-registry/pkg/registry"
+)
+
+// bundleFilterClause returns the WHERE clause, on the channel_entry table, that selects the bundles a filter selects
+func (s *SQLQuerier) bundleFilterClause(ctx context.Context, filter registry.BundleFilter) (string, []interface{}, error) {
+	var conditions []string
+	var args []interface{}
+	if filter.PackageName != "" {
+		conditions = append(conditions, `channel_entry.package_name = ?`)
+		args = append(args, filter.PackageName)
+	}
+	if filter.ChannelName != "" {
+		conditions = append(conditions, `channel_entry.channel_name = ?`)
+		args = append(args, filter.ChannelName)
+	}
+
+	if filter.SelectsLabels() {
+		names, err := s.bundlesMatchingLabels(ctx, filter, conditions, args)
+		if err != nil {
+			return "", nil, err
+		}
+		// the names are passed as a single json array, however many bundles are selected
+		encoded, err := json.Marshal(names)
+		if err != nil {
+			return "", nil, err
+		}
+		conditions = append(conditions, `channel_entry.operatorbundle_name IN (SELECT value FROM json_each(?))`)
+		args = append(args, string(encoded))
+	}
+
+	if filter.InstallMode != "" {
+		conditions = append(conditions, `NOT EXISTS (SELECT 1 FROM properties
+			WHERE properties.operatorbundle_name = channel_entry.operatorbundle_name AND properties.type = ?
+			AND NOT EXISTS (SELECT 1 FROM json_each(properties.value, '$.supported') WHERE json_each.value = ?))`)
+		args = append(args, registry.InstallModesType, filter.InstallMode)
+	}
+
+	if len(conditions) == 0 {
+		return "", nil, nil
+	}
+	return "WHERE " + strings.Join(conditions, " AND "), args, nil
+}
+
+// bundlesMatchingLabels returns the names of the bundles, in the channels selected by conditions, whose labels match
+// the label selector of the filter
+func (s *SQLQuerier) bundlesMatchingLabels(ctx context.Context, filter registry.BundleFilter, conditions []string, args []interface{}) ([]string, error) {
+	query := `SELECT DISTINCT channel_entry.operatorbundle_name,
+		CASE WHEN json_valid(properties.value) THEN json_extract(properties.value, '$.label') END
+		FROM channel_entry
+		LEFT OUTER JOIN properties ON properties.operatorbundle_name = channel_entry.operatorbundle_name AND properties.type = ?`
+	if len(conditions) > 0 {
+		query += ` WHERE ` + strings.Join(conditions, " AND ")
+	}
+	var order []string
+	bundleLabels := map[string][]string{}
+	err := s.forEachRow(ctx, "bundle labels", query, append([]interface{}{registry.LabelType}, args...), func(row scanner) error {
+		var name, label sql.NullString
+		if err := row.Scan(&name, &label); err != nil {
+			return err
+		}
+		if _, ok := bundleLabels[name.String]; !ok {
+			order = append(order, name.String)
+			bundleLabels[name.String] = nil
+		}
+		if label.Valid {
+			bundleLabels[name.String] = append(bundleLabels[name.String], label.String)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	names := []string{}
+	for _, name := range order {
+		if filter.Labels.Matches(registry.BundleLabels(bundleLabels[name])) {
+			names = append(names, name)
+		}
+	}
+	return names, nil
+}
@@ -0,0 +1,229 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/blang/semver"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+
+	"github.com/operator-framework/operator-registry/pkg/registry"
+)
+
+// SetChannelSelector defines a channel of a package by a selector, replacing the selector it had before, and expands
+// it. It is an error for a selector to select no bundles.
+func (s *sqlLoader) SetChannelSelector(selector registry.ChannelSelector) error {
+	if err := selector.Validate(); err != nil {
+		return err
+	}
+	properties, err := json.Marshal(selector.Properties)
+	if err != nil {
+		return err
+	}
+
+	return s.transaction(func(tx *sql.Tx) error {
+		if err := packageExists(tx, selector.PackageName); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(`INSERT OR REPLACE INTO channel_selector(package_name, channel_name, versions, properties) VALUES (?, ?, ?, ?)`,
+			selector.PackageName, selector.ChannelName, selector.Versions, string(properties)); err != nil {
+			return err
+		}
+		selected, err := expandChannelSelector(tx, selector)
+		if err != nil {
+			return err
+		}
+		if selected == 0 {
+			return fmt.Errorf("selector of channel %s selects no bundles of package %s", selector.ChannelName, selector.PackageName)
+		}
+		return nil
+	})
+}
+
+// RemoveChannelSelector removes the selector of a channel. The channel keeps the entries it was last expanded into,
+// and is updated the way any other channel is from then on.
+func (s *sqlLoader) RemoveChannelSelector(packageName, channel string) error {
+	return s.transaction(func(tx *sql.Tx) error {
+		res, err := tx.Exec(`DELETE FROM channel_selector WHERE package_name = ? AND channel_name = ?`, packageName, channel)
+		if err != nil {
+			return err
+		}
+		if removed, err := res.RowsAffected(); err != nil {
+			return err
+		} else if removed == 0 {
+			return fmt.Errorf("channel %s of package %s has no selector", channel, packageName)
+		}
+		return nil
+	})
+}
+
+// ExpandChannelSelectors rewrites the entries of every channel defined by a selector from the bundles it currently
+// selects, so that bundles added since the channel was last expanded are picked up. A channel that no longer selects
+// any bundles is removed, unless it is the default channel of its package. Selectors of packages that aren't in the
+// database are skipped.
+func ExpandChannelSelectors(ctx context.Context, db *sql.DB) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		tx.Rollback()
+	}()
+
+	selectors, err := listChannelSelectors(tx)
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for _, selector := range selectors {
+		if err := packageExists(tx, selector.PackageName); err != nil {
+			// the selectors of a removed package are kept until it is added again
+			continue
+		}
+		if _, err := expandChannelSelector(tx, selector); err != nil {
+			errs = append(errs, fmt.Errorf("error expanding channel %s of package %s: %s", selector.ChannelName, selector.PackageName, err))
+		}
+	}
+	if len(errs) > 0 {
+		return utilerrors.NewAggregate(errs)
+	}
+
+	return tx.Commit()
+}
+
+func listChannelSelectors(tx *sql.Tx) ([]registry.ChannelSelector, error) {
+	rows, err := tx.Query(`SELECT package_name, channel_name, versions, properties FROM channel_selector ORDER BY package_name, channel_name`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var selectors []registry.ChannelSelector
+	for rows.Next() {
+		var packageName, channel string
+		var versions, properties sql.NullString
+		if err := rows.Scan(&packageName, &channel, &versions, &properties); err != nil {
+			return nil, err
+		}
+		selector := registry.ChannelSelector{PackageName: packageName, ChannelName: channel, Versions: versions.String}
+		if properties.Valid && properties.String != "" {
+			if err := json.Unmarshal([]byte(properties.String), &selector.Properties); err != nil {
+				return nil, fmt.Errorf("invalid selector of channel %s of package %s: %s", channel, packageName, err)
+			}
+		}
+		selectors = append(selectors, selector)
+	}
+	return selectors, rows.Err()
+}
+
+// selectedBundle is a bundle of a package that a channel selector has selected
+type selectedBundle struct {
+	key     registry.BundleKey
+	version semver.Version
+}
+
+// expandChannelSelector rewrites the entries of a channel into a replaces chain of the bundles its selector selects,
+// and returns how many were selected
+func expandChannelSelector(tx *sql.Tx, selector registry.ChannelSelector) (int, error) {
+	// the candidates are every bundle in a channel of the package, including the channel being expanded
+	rows, err := tx.Query(`SELECT DISTINCT operatorbundle.name, operatorbundle.version, operatorbundle.bundlepath
+		FROM channel_entry INNER JOIN operatorbundle ON operatorbundle.name = channel_entry.operatorbundle_name
+		WHERE channel_entry.package_name = ?`, selector.PackageName)
+	if err != nil {
+		return 0, err
+	}
+	var candidates []registry.BundleKey
+	for rows.Next() {
+		var name string
+		var version, bundlePath sql.NullString
+		if err := rows.Scan(&name, &version, &bundlePath); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		candidates = append(candidates, registry.BundleKey{CsvName: name, Version: version.String, BundlePath: bundlePath.String})
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	var selected []selectedBundle
+	for _, candidate := range candidates {
+		properties, err := bundleProperties(tx, candidate.CsvName)
+		if err != nil {
+			return 0, err
+		}
+		matches, err := selector.Matches(candidate.Version, properties)
+		if err != nil {
+			return 0, err
+		}
+		if !matches {
+			continue
+		}
+		// Matches only selects bundles with semver versions
+		version, _ := semver.Parse(candidate.Version)
+		selected = append(selected, selectedBundle{key: candidate, version: version})
+	}
+
+	if len(selected) == 0 {
+		var defaultChannel sql.NullString
+		if err := tx.QueryRow(`SELECT default_channel FROM package WHERE name = ?`, selector.PackageName).Scan(&defaultChannel); err != nil {
+			return 0, err
+		}
+		if defaultChannel.String == selector.ChannelName {
+			return 0, fmt.Errorf("default channel %s selects no bundles", selector.ChannelName)
+		}
+		if err := truncChannelGraph(tx, selector.ChannelName, selector.PackageName); err != nil {
+			return 0, err
+		}
+		_, err := tx.Exec(`DELETE FROM channel WHERE package_name = ? AND name = ?`, selector.PackageName, selector.ChannelName)
+		return 0, err
+	}
+
+	sort.Slice(selected, func(i, j int) bool {
+		if c := selected[i].version.Compare(selected[j].version); c != 0 {
+			return c < 0
+		}
+		return selected[i].key.CsvName < selected[j].key.CsvName
+	})
+	channel := registry.Channel{
+		Head:  selected[len(selected)-1].key,
+		Nodes: make(map[registry.BundleKey]map[registry.BundleKey]struct{}, len(selected)),
+	}
+	for i, bundle := range selected {
+		replaces := map[registry.BundleKey]struct{}{}
+		if i > 0 {
+			replaces[selected[i-1].key] = struct{}{}
+		}
+		channel.Nodes[bundle.key] = replaces
+	}
+
+	if err := addOrUpdateChannel(tx, selector.ChannelName, selector.PackageName, channel.Head.CsvName); err != nil {
+		return 0, err
+	}
+	if err := utilerrors.NewAggregate(addChannelGraph(tx, selector.PackageName, selector.ChannelName, channel)); err != nil {
+		return 0, err
+	}
+	return len(selected), nil
+}
+
+func bundleProperties(tx *sql.Tx, bundleName string) ([]registry.Property, error) {
+	rows, err := tx.Query(`SELECT type, value FROM properties WHERE operatorbundle_name = ?`, bundleName)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var properties []registry.Property
+	for rows.Next() {
+		var typ, value sql.NullString
+		if err := rows.Scan(&typ, &value); err != nil {
+			return nil, err
+		}
+		properties = append(properties, registry.Property{Type: typ.String, Value: json.RawMessage(value.String)})
+	}
+	return properties, rows.Err()
+}
@@ -0,0 +1,86 @@
+package sqlite
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/operator-framework/operator-registry/pkg/registry"
+)
+
+func TestChannelSelector(t *testing.T) {
+	db, cleanup := CreateTestDb(t)
+	defer cleanup()
+	store, err := NewSQLLiteLoader(db)
+	require.NoError(t, err)
+	require.NoError(t, store.Migrate(context.TODO()))
+
+	channels := []string{"stable"}
+	for _, b := range []*registry.Bundle{
+		newBundle(t, "csv-a", "pkg", channels, newUnstructuredCSVWithSkipRange(t, "csv-a", "1.0.0", "", "")),
+		newBundle(t, "csv-b", "pkg", channels, newUnstructuredCSVWithSkipRange(t, "csv-b", "2.0.0", "csv-a", "")),
+		newBundle(t, "csv-c", "pkg", channels, newUnstructuredCSVWithSkipRange(t, "csv-c", "2.1.0", "csv-b", "")),
+	} {
+		require.NoError(t, store.AddOperatorBundle(b))
+	}
+	require.NoError(t, store.AddPackageChannels(registry.PackageManifest{
+		PackageName:        "pkg",
+		Channels:           []registry.PackageChannel{{Name: "stable", CurrentCSVName: "csv-c"}},
+		DefaultChannelName: "stable",
+	}))
+
+	querier := NewSQLLiteQuerierFromDb(db)
+	head := func(channel string) string {
+		bundle, err := querier.GetBundleForChannel(context.TODO(), "pkg", channel)
+		require.NoError(t, err)
+		return bundle.CsvName
+	}
+
+	selector := registry.ChannelSelector{PackageName: "pkg", ChannelName: "2.x", Versions: ">=2.0.0 <3.0.0"}
+	require.NoError(t, store.SetChannelSelector(selector))
+	require.Equal(t, "csv-c", head("2.x"))
+	replacement, err := querier.GetBundleThatReplaces(context.TODO(), "csv-b", "pkg", "2.x")
+	require.NoError(t, err)
+	require.Equal(t, "csv-c", replacement.CsvName)
+	_, err = querier.GetBundleThatReplaces(context.TODO(), "csv-a", "pkg", "2.x")
+	require.Error(t, err)
+
+	// a selector that selects nothing isn't set
+	require.Error(t, store.SetChannelSelector(registry.ChannelSelector{PackageName: "pkg", ChannelName: "5.x", Versions: ">=5.0.0"}))
+	require.Error(t, store.SetChannelSelector(registry.ChannelSelector{PackageName: "missing", ChannelName: "2.x"}))
+	require.Error(t, store.SetChannelSelector(registry.ChannelSelector{PackageName: "pkg", ChannelName: "2.x", Versions: "2.x.y"}))
+
+	// bundles are selected by their properties
+	require.NoError(t, store.SetChannelSelector(registry.ChannelSelector{
+		PackageName: "pkg",
+		ChannelName: "2.0",
+		Properties:  []registry.Property{{Type: registry.PackageType, Value: json.RawMessage(`{"version":"2.0.0"}`)}},
+	}))
+	require.Equal(t, "csv-b", head("2.0"))
+
+	// bundles added later join the channel once it is expanded again, even if the package was recreated
+	require.NoError(t, store.AddBundlePackageChannels(registry.PackageManifest{
+		PackageName:        "pkg",
+		Channels:           []registry.PackageChannel{{Name: "stable", CurrentCSVName: "csv-d"}},
+		DefaultChannelName: "stable",
+	}, newBundle(t, "csv-d", "pkg", channels, newUnstructuredCSVWithSkipRange(t, "csv-d", "2.2.0", "csv-c", ""))))
+	_, err = querier.GetBundleForChannel(context.TODO(), "pkg", "2.x")
+	require.Error(t, err)
+	require.NoError(t, ExpandChannelSelectors(context.TODO(), db))
+	require.Equal(t, "csv-d", head("2.x"))
+	require.Equal(t, "csv-b", head("2.0"))
+
+	// a channel that no longer selects anything is removed
+	_, err = db.Exec(`UPDATE channel_selector SET versions = ? WHERE channel_name = ?`, ">=5.0.0", "2.x")
+	require.NoError(t, err)
+	require.NoError(t, ExpandChannelSelectors(context.TODO(), db))
+	_, err = querier.GetBundleForChannel(context.TODO(), "pkg", "2.x")
+	require.Error(t, err)
+
+	// removing a selector leaves the channel as it was last expanded
+	require.NoError(t, store.RemoveChannelSelector("pkg", "2.0"))
+	require.Error(t, store.RemoveChannelSelector("pkg", "2.0"))
+	require.Equal(t, "csv-b", head("2.0"))
+}
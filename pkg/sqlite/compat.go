@@ -0,0 +1,233 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/operator-framework/operator-registry/pkg/sqlite/migrations"
+)
+
+// Feature names an optional part of the database schema that was introduced by a migration
+type Feature string
+
+const (
+	FeatureRelatedImages    Feature = "related-images"
+	FeatureBundlePath       Feature = "bundle-path"
+	FeatureVersion          Feature = "version-skiprange"
+	FeatureReplacesSkips    Feature = "replaces-skips"
+	FeatureDependencies     Feature = "dependencies"
+	FeatureProperties       Feature = "properties"
+	FeatureOwnership        Feature = "ownership"
+	FeatureAttestations     Feature = "attestations"
+	FeatureBundleDigest     Feature = "bundle-digest"
+	FeaturePackageAlias     Feature = "package-aliases"
+	FeatureStableIDs        Feature = "stable-ids"
+	FeatureChannelOverrides Feature = "channel-overrides"
+	FeatureBundleSearch     Feature = "bundle-search"
+	FeatureBundleImages     Feature = "bundle-images"
+	FeatureDeprecated       Feature = "deprecated"
+	FeatureChannelSelectors Feature = "channel-selectors"
+	FeatureBundleStats      Feature = "bundle-stats"
+)
+
+// compatTable is the table that column based features are added to
+const compatTable = "operatorbundle"
+
+// compatFeature describes how to detect a feature and how to fill it in with defaults when it is missing
+type compatFeature struct {
+	feature Feature
+	// columns that must exist on the operatorbundle table
+	columns []string
+	// table that must exist, and the statement that creates it empty
+	table       string
+	createTable string
+	// fullTextSearch is set if the table can only be created if SQLite was built with FTS5
+	fullTextSearch bool
+}
+
+var compatFeatures = []compatFeature{
+	{
+		feature:     FeatureRelatedImages,
+		table:       "related_image",
+		createTable: `CREATE TABLE IF NOT EXISTS related_image (image TEXT, operatorbundle_name TEXT, FOREIGN KEY(operatorbundle_name) REFERENCES operatorbundle(name))`,
+	},
+	{feature: FeatureBundlePath, columns: []string{"bundlepath"}},
+	{feature: FeatureVersion, columns: []string{"version", "skiprange"}},
+	{feature: FeatureReplacesSkips, columns: []string{"replaces", "skips"}},
+	{
+		feature:     FeatureDependencies,
+		table:       "dependencies",
+		createTable: `CREATE TABLE IF NOT EXISTS dependencies (type TEXT, value TEXT, operatorbundle_name TEXT, operatorbundle_version TEXT, operatorbundle_path TEXT)`,
+	},
+	{
+		feature:     FeatureProperties,
+		table:       "properties",
+		createTable: `CREATE TABLE IF NOT EXISTS properties (type TEXT, value TEXT, operatorbundle_name TEXT, operatorbundle_version TEXT, operatorbundle_path TEXT)`,
+	},
+	{feature: FeatureOwnership, columns: []string{"provider", "maintainers", "links"}},
+	{
+		feature:     FeatureAttestations,
+		table:       "bundle_attestation",
+		createTable: `CREATE TABLE IF NOT EXISTS bundle_attestation (certificate_identity TEXT, certificate_issuer TEXT, rekor_log_index INTEGER, operatorbundle_name TEXT, operatorbundle_version TEXT, operatorbundle_path TEXT)`,
+	},
+	{feature: FeatureBundleDigest, columns: []string{"digest"}},
+	{
+		feature:     FeaturePackageAlias,
+		table:       "package_alias",
+		createTable: `CREATE TABLE IF NOT EXISTS package_alias (alias TEXT PRIMARY KEY, package_name TEXT NOT NULL, renamed_at INTEGER)`,
+	},
+	{
+		feature:     FeatureStableIDs,
+		table:       "stable_id",
+		createTable: `CREATE TABLE IF NOT EXISTS stable_id (kind TEXT NOT NULL, name TEXT NOT NULL, id TEXT NOT NULL, PRIMARY KEY(kind, name))`,
+	},
+	{
+		feature:     FeatureChannelOverrides,
+		table:       "channel_override",
+		createTable: `CREATE TABLE IF NOT EXISTS channel_override (annotated_channels TEXT, annotated_default_channel TEXT, channels TEXT, default_channel TEXT, operatorbundle_name TEXT, operatorbundle_version TEXT, operatorbundle_path TEXT)`,
+	},
+	{
+		feature:        FeatureBundleSearch,
+		table:          "bundle_search",
+		createTable:    `CREATE VIRTUAL TABLE IF NOT EXISTS bundle_search USING fts5(name, display_name, description, keywords)`,
+		fullTextSearch: true,
+	},
+	{
+		feature:     FeatureBundleImages,
+		table:       "bundle_image",
+		createTable: `CREATE TABLE IF NOT EXISTS bundle_image (digest TEXT NOT NULL, operatorbundle_name TEXT, operatorbundle_version TEXT, operatorbundle_path TEXT)`,
+	},
+	{
+		feature:     FeatureDeprecated,
+		table:       "deprecated",
+		createTable: `CREATE TABLE IF NOT EXISTS deprecated (operatorbundle_name TEXT PRIMARY KEY)`,
+	},
+	{
+		feature:     FeatureChannelSelectors,
+		table:       "channel_selector",
+		createTable: `CREATE TABLE IF NOT EXISTS channel_selector (package_name TEXT NOT NULL, channel_name TEXT NOT NULL, versions TEXT, properties TEXT, PRIMARY KEY(package_name, channel_name))`,
+	},
+	{
+		feature:     FeatureBundleStats,
+		table:       "bundle_stats",
+		createTable: `CREATE TABLE IF NOT EXISTS bundle_stats (operatorbundle_name TEXT PRIMARY KEY, size INTEGER NOT NULL, object_count INTEGER NOT NULL, crd_count INTEGER NOT NULL)`,
+	},
+}
+
+// FeatureSet reports which optional schema features a database supports natively, and which
+// were missing and have been filled in with empty defaults so that the database can still be served
+type FeatureSet struct {
+	Available []string
+	Defaulted []string
+}
+
+// String returns a compact description of the feature set
+func (f FeatureSet) String() string {
+	return fmt.Sprintf("available=%s defaulted=%s", strings.Join(f.Available, ","), strings.Join(f.Defaulted, ","))
+}
+
+// EnsureCompatibleSchema lets a database with an older schema be served by this version of the querier.
+// Missing columns are added as NULL (read back as empty values) and missing tables are created empty;
+// no data is backfilled. The database must be writable, so this is meant to be run against a temporary copy.
+func EnsureCompatibleSchema(ctx context.Context, db *sql.DB) (*FeatureSet, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		tx.Rollback()
+	}()
+
+	columns, err := tableColumns(ctx, tx, compatTable)
+	if err != nil {
+		return nil, err
+	}
+	if len(columns) == 0 {
+		return nil, fmt.Errorf("database has no %s table", compatTable)
+	}
+
+	set := &FeatureSet{}
+	for _, f := range compatFeatures {
+		missing, err := missingFor(ctx, tx, f, columns)
+		if err != nil {
+			return nil, err
+		}
+		if !missing {
+			set.Available = append(set.Available, string(f.feature))
+			continue
+		}
+
+		if f.fullTextSearch {
+			supported, err := migrations.SupportsFullTextSearch(ctx, tx)
+			if err != nil {
+				return nil, err
+			}
+			if !supported {
+				// served without the table, e.g. bundles are searched without an index
+				set.Defaulted = append(set.Defaulted, string(f.feature))
+				continue
+			}
+		}
+		if f.createTable != "" {
+			if _, err := tx.ExecContext(ctx, f.createTable); err != nil {
+				return nil, err
+			}
+		}
+		for _, column := range f.columns {
+			if _, ok := columns[column]; ok {
+				continue
+			}
+			if _, err := tx.ExecContext(ctx, fmt.Sprintf(`ALTER TABLE %s ADD COLUMN %s TEXT`, compatTable, column)); err != nil {
+				return nil, err
+			}
+			columns[column] = struct{}{}
+		}
+		set.Defaulted = append(set.Defaulted, string(f.feature))
+	}
+
+	return set, tx.Commit()
+}
+
+func missingFor(ctx context.Context, tx *sql.Tx, f compatFeature, columns map[string]struct{}) (bool, error) {
+	for _, column := range f.columns {
+		if _, ok := columns[column]; !ok {
+			return true, nil
+		}
+	}
+	if f.table == "" {
+		return false, nil
+	}
+	var name sql.NullString
+	err := tx.QueryRowContext(ctx, `SELECT name FROM sqlite_master WHERE type='table' AND name=?`, f.table).Scan(&name)
+	if err == sql.ErrNoRows {
+		return true, nil
+	}
+	return false, err
+}
+
+func tableColumns(ctx context.Context, tx *sql.Tx, table string) (map[string]struct{}, error) {
+	rows, err := tx.QueryContext(ctx, fmt.Sprintf(`PRAGMA table_info(%s)`, table))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	columns := map[string]struct{}{}
+	for rows.Next() {
+		var (
+			cid       int
+			name      string
+			colType   string
+			notNull   int
+			dfltValue sql.NullString
+			pk        int
+		)
+		if err := rows.Scan(&cid, &name, &colType, &notNull, &dfltValue, &pk); err != nil {
+			return nil, err
+		}
+		columns[name] = struct{}{}
+	}
+	return columns, rows.Err()
+}
@@ -0,0 +1,56 @@
+package sqlite
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/operator-framework/operator-registry/pkg/registry"
+	"github.com/operator-framework/operator-registry/pkg/sqlite/migrations"
+)
+
+func TestEnsureCompatibleSchema(t *testing.T) {
+	db, cleanup := CreateTestDb(t)
+	defer cleanup()
+
+	// migrate to a schema that predates properties
+	migrator, err := NewSQLLiteMigrator(db)
+	require.NoError(t, err)
+	require.NoError(t, migrator.Up(context.TODO(), migrations.To(migrations.PropertiesMigrationKey-1)))
+
+	insert := "insert into operatorbundle(name, csv, bundle, bundlepath, version, skiprange, replaces, skips) values(?, ?, ?, ?, ?, ?, ?, ?)"
+	_, err = db.Exec(insert, "etcdoperator.v0.6.1", "testCSV", "testBundle", "quay.io/image", "0.6.1", "", "", "")
+	require.NoError(t, err)
+
+	features, err := EnsureCompatibleSchema(context.TODO(), db)
+	require.NoError(t, err)
+	require.ElementsMatch(t, []string{"related-images", "bundle-path", "version-skiprange", "replaces-skips", "dependencies"}, features.Available)
+	require.ElementsMatch(t, []string{"properties", "ownership", "attestations", "bundle-digest", "package-aliases", "stable-ids", "channel-overrides", "bundle-search", "bundle-images", "deprecated", "channel-selectors", "bundle-stats"}, features.Defaulted)
+
+	// queries against the defaulted features return empty values
+	querier := NewSQLLiteQuerierFromDb(db)
+	digest, err := querier.GetBundleDigest(context.TODO(), "quay.io/image")
+	require.NoError(t, err)
+	require.Empty(t, digest)
+	ownership, err := querier.GetOwnershipForBundle(context.TODO(), "etcdoperator.v0.6.1")
+	require.NoError(t, err)
+	require.Empty(t, ownership.Provider)
+	attestations, err := querier.GetBundleAttestations(context.TODO(), "etcdoperator.v0.6.1")
+	require.NoError(t, err)
+	require.Empty(t, attestations)
+	_, err = querier.GetPackageForAlias(context.TODO(), "etcd")
+	require.Equal(t, registry.ErrPackageNotInDatabase, err)
+	id, err := querier.GetBundleID(context.TODO(), "etcdoperator.v0.6.1")
+	require.NoError(t, err)
+	require.Equal(t, registry.BundleID("etcdoperator.v0.6.1"), id)
+
+	// once filled in, every feature is reported as available, except for the search index without FTS5
+	features, err = EnsureCompatibleSchema(context.TODO(), db)
+	require.NoError(t, err)
+	if fullTextSearch, err := migrations.SupportsFullTextSearch(context.TODO(), db); err == nil && !fullTextSearch {
+		require.Equal(t, []string{"bundle-search"}, features.Defaulted)
+	} else {
+		require.Empty(t, features.Defaulted)
+	}
+}
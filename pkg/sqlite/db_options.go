@@ -2,18 +2,68 @@ package sqlite
 
 import (
 	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
 )
 
+// Journal modes and synchronous settings SQLite accepts, see https://www.sqlite.org/pragma.html
+var (
+	JournalModes        = []string{"DELETE", "TRUNCATE", "PERSIST", "MEMORY", "WAL", "OFF"}
+	SynchronousSettings = []string{"OFF", "NORMAL", "FULL", "EXTRA"}
+)
+
+// DefaultJournalMode is the journal mode databases are created with, and that a database built in another mode should
+// be switched back to before it is shipped, so that it can be read without its -wal and -shm files
+const DefaultJournalMode = "DELETE"
+
+// DefaultLoaderJournalMode is the journal mode commands that add to a database on disk write it with unless told
+// otherwise. WAL syncs far less than DELETE; the database is checkpointed and switched back to DefaultJournalMode
+// with RestoreJournalMode once it is written.
+const DefaultLoaderJournalMode = "WAL"
+
 type DbOptions struct {
 	// MigratorBuilder is a function that returns a migrator instance
 	MigratorBuilder func(*sql.DB) (Migrator, error)
+
+	// JournalMode, if set, is the journal mode the database is switched to, e.g. WAL. It is recorded in the
+	// database file and outlives the loader.
+	JournalMode string
+
+	// Synchronous, if set, is how often SQLite waits for writes to reach the disk, e.g. NORMAL
+	Synchronous string
+
+	// BusyTimeout, if set, is how long a connection waits for another writer to release the database before its
+	// statement fails as busy. Connections opened with the DSN of the options also take the write lock as soon as a
+	// transaction begins, so that two writers can't both read the database and then fail to upgrade to a write.
+	BusyTimeout time.Duration
+
+	// Retry is how the loader retries a transaction that failed because another writer held the database
+	Retry RetryPolicy
+}
+
+// RetryPolicy is how often, and how far apart, a transaction that failed because the database was busy is retried
+type RetryPolicy struct {
+	// Attempts is how many times a transaction is run at most, including the first time
+	Attempts int
+	// Backoff is how long to wait before the first retry, doubled for every retry after it
+	Backoff time.Duration
 }
 
+// DefaultBusyTimeout is how long commands that write a database shared with other processes, such as
+// opm registry add, wait for the other writers
+const DefaultBusyTimeout = 30 * time.Second
+
+// DefaultRetryPolicy retries a busy transaction a few times over about a second and a half
+var DefaultRetryPolicy = RetryPolicy{Attempts: 5, Backoff: 100 * time.Millisecond}
+
 type DbOption func(*DbOptions)
 
 func defaultDBOptions() *DbOptions {
 	return &DbOptions{
 		MigratorBuilder: NewSQLLiteMigrator,
+		Retry:           DefaultRetryPolicy,
 	}
 }
 
@@ -22,3 +72,121 @@ func WithMigratorBuilder(m func(loader *sql.DB) (Migrator, error)) DbOption {
 		o.MigratorBuilder = m
 	}
 }
+
+// WithJournalMode switches the database to a journal mode, e.g. WAL to speed up large builds
+func WithJournalMode(mode string) DbOption {
+	return func(o *DbOptions) {
+		o.JournalMode = mode
+	}
+}
+
+// WithSynchronous sets how often SQLite waits for writes to reach the disk, e.g. NORMAL, which is safe in WAL mode
+func WithSynchronous(synchronous string) DbOption {
+	return func(o *DbOptions) {
+		o.Synchronous = synchronous
+	}
+}
+
+// WithBusyTimeout sets how long to wait for other writers of the database, so that several processes can add to the
+// same database at once
+func WithBusyTimeout(timeout time.Duration) DbOption {
+	return func(o *DbOptions) {
+		o.BusyTimeout = timeout
+	}
+}
+
+// WithRetryPolicy sets how a transaction that failed because the database was busy is retried
+func WithRetryPolicy(policy RetryPolicy) DbOption {
+	return func(o *DbOptions) {
+		o.Retry = policy
+	}
+}
+
+// apply sets the pragmas the options ask for on the database
+func (o *DbOptions) apply(db *sql.DB) error {
+	if o.JournalMode != "" {
+		if err := SetJournalMode(db, o.JournalMode); err != nil {
+			return err
+		}
+	}
+	if o.Synchronous != "" {
+		synchronous, err := oneOf("synchronous setting", o.Synchronous, SynchronousSettings)
+		if err != nil {
+			return err
+		}
+		if _, err := db.Exec("PRAGMA synchronous = " + synchronous); err != nil {
+			return err
+		}
+	}
+	if o.BusyTimeout > 0 {
+		if _, err := db.Exec("PRAGMA busy_timeout = " + strconv.FormatInt(o.BusyTimeout.Milliseconds(), 10)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SetJournalMode switches the database to a journal mode. SQLite keeps the current mode when the requested one
+// can't be used, e.g. WAL on a filesystem without shared memory, which is returned as an error.
+func SetJournalMode(db *sql.DB, mode string) error {
+	mode, err := oneOf("journal mode", mode, JournalModes)
+	if err != nil {
+		return err
+	}
+	var current string
+	if err := db.QueryRow("PRAGMA journal_mode = " + mode).Scan(&current); err != nil {
+		return err
+	}
+	if !strings.EqualFold(current, mode) {
+		return fmt.Errorf("couldn't switch database to journal mode %s, it is still in %s", mode, current)
+	}
+	return nil
+}
+
+// RestoreJournalMode checkpoints everything in the -wal file of the database into the database file and switches it
+// back to DefaultJournalMode, so that it is a single file again. It fails if another connection still has the
+// database open in WAL mode.
+func RestoreJournalMode(db *sql.DB) error {
+	var busy, logged, checkpointed int
+	if err := db.QueryRow("PRAGMA wal_checkpoint(TRUNCATE)").Scan(&busy, &logged, &checkpointed); err != nil {
+		return err
+	}
+	if busy != 0 {
+		return fmt.Errorf("couldn't checkpoint database, it is in use by another connection")
+	}
+	return SetJournalMode(db, DefaultJournalMode)
+}
+
+// oneOf returns the allowed value that matches value, ignoring case. Pragma values can't be passed as query
+// arguments, so only known values are let through.
+func oneOf(name, value string, allowed []string) (string, error) {
+	for _, a := range allowed {
+		if strings.EqualFold(value, a) {
+			return a, nil
+		}
+	}
+	return "", fmt.Errorf("unknown %s %q, must be one of [%s]", name, value, strings.Join(allowed, ", "))
+}
+
+// DSN returns the data source name to open the database at path with, so that every connection to it, not only
+// the one the loader sets its pragmas on, uses the journal mode, synchronous setting and busy timeout of the options
+func DSN(path string, opts ...DbOption) string {
+	options := defaultDBOptions()
+	for _, o := range opts {
+		o(options)
+	}
+	var params []string
+	if options.JournalMode != "" {
+		params = append(params, "_journal_mode="+options.JournalMode)
+	}
+	if options.Synchronous != "" {
+		params = append(params, "_sync="+options.Synchronous)
+	}
+	if options.BusyTimeout > 0 {
+		params = append(params, "_busy_timeout="+strconv.FormatInt(options.BusyTimeout.Milliseconds(), 10), "_txlock=immediate")
+	}
+	if len(params) == 0 {
+		return path
+	}
+	return path + "?" + strings.Join(params, "&")
+}
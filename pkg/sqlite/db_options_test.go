@@ -0,0 +1,57 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestJournalOptions(t *testing.T) {
+	dir, err := ioutil.TempDir("", "journal-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "index.db")
+
+	opts := []DbOption{WithJournalMode("wal"), WithSynchronous("normal")}
+	require.Equal(t, path+"?_journal_mode=wal&_sync=normal", DSN(path, opts...))
+	require.Equal(t, path, DSN(path))
+
+	db, err := sql.Open("sqlite3", DSN(path, opts...))
+	require.NoError(t, err)
+	defer db.Close()
+
+	loader, err := NewSQLLiteLoader(db, opts...)
+	require.NoError(t, err)
+	require.NoError(t, loader.Migrate(context.TODO()))
+	require.NoError(t, NewSQLLoaderForDirectory(loader, "../../manifests").Populate())
+
+	var mode string
+	require.NoError(t, db.QueryRow("PRAGMA journal_mode").Scan(&mode))
+	require.Equal(t, "wal", mode)
+	var synchronous int
+	require.NoError(t, db.QueryRow("PRAGMA synchronous").Scan(&synchronous))
+	require.Equal(t, 1, synchronous)
+
+	// a database built in WAL mode is checkpointed and switched back before it is shipped
+	_, err = os.Stat(path + "-wal")
+	require.NoError(t, err)
+	require.NoError(t, RestoreJournalMode(db))
+	require.NoError(t, db.QueryRow("PRAGMA journal_mode").Scan(&mode))
+	require.Equal(t, "delete", mode)
+	_, err = os.Stat(path + "-wal")
+	require.True(t, os.IsNotExist(err))
+	packages, err := NewSQLLiteQuerierFromDb(db).ListPackages(context.TODO())
+	require.NoError(t, err)
+	require.Len(t, packages, 3)
+
+	// only known values can be set
+	_, err = NewSQLLiteLoader(db, WithJournalMode("wal; DROP TABLE package"))
+	require.Error(t, err)
+	_, err = NewSQLLiteLoader(db, WithSynchronous("sometimes"))
+	require.Error(t, err)
+}
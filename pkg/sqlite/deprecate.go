@@ -18,6 +18,8 @@ type SQLDeprecator interface {
 type BundleDeprecator struct {
 	store   registry.Load
 	bundles []string
+	// mark keeps the bundles before the deprecated ones in the upgrade graph, marked deprecated too
+	mark bool
 }
 
 var _ SQLDeprecator = &BundleDeprecator{}
@@ -29,15 +31,30 @@ func NewSQLDeprecatorForBundles(store registry.Load, bundles []string) *BundleDe
 	}
 }
 
+// NewSQLMarkingDeprecatorForBundles returns a deprecator that marks bundles, and every bundle before them in the
+// upgrade graph, deprecated instead of removing the bundles before them
+func NewSQLMarkingDeprecatorForBundles(store registry.Load, bundles []string) *BundleDeprecator {
+	return &BundleDeprecator{
+		store:   store,
+		bundles: bundles,
+		mark:    true,
+	}
+}
+
 func (d *BundleDeprecator) Deprecate() error {
 	log := logrus.WithField("bundles", d.bundles)
 
 	log.Info("deprecating bundles")
 
+	deprecate := d.store.DeprecateBundle
+	if d.mark {
+		deprecate = d.store.MarkBundleDeprecated
+	}
+
 	var errs []error
 
 	for _, bundlePath := range d.bundles {
-		if err := d.store.DeprecateBundle(bundlePath); err != nil {
+		if err := deprecate(bundlePath); err != nil {
 			if !errors.Is(err, registry.ErrBundleImageNotInDatabase) && !errors.Is(err, registry.ErrRemovingDefaultChannelDuringDeprecation) {
 				return utilerrors.NewAggregate(append(errs, fmt.Errorf("error deprecating bundle %s: %s", bundlePath, err)))
 			}
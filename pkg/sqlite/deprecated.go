@@ -0,0 +1,106 @@
+package sqlite
+
+import (
+	"database/sql"
+	"encoding/json"
+	"strings"
+
+	"github.com/operator-framework/operator-registry/pkg/registry"
+)
+
+// notDeprecated is a condition that holds for the bundle named by column unless it has been marked deprecated
+func notDeprecated(column string) string {
+	return column + ` NOT IN (SELECT operatorbundle_name FROM deprecated)`
+}
+
+// isDeprecated is a boolean expression that is true if the bundle named by column has been marked deprecated
+func isDeprecated(column string) string {
+	return `EXISTS (SELECT 1 FROM deprecated WHERE deprecated.operatorbundle_name = ` + column + `)`
+}
+
+// MarkBundleDeprecated marks the bundle loaded from the given image deprecated, along with every bundle it replaces or
+// skips, directly or through other bundles. Unlike DeprecateBundle nothing is removed: the bundles stay in the upgrade
+// graph, so that installed operators can still upgrade past them, but are no longer the head of any channel.
+func (s *sqlLoader) MarkBundleDeprecated(path string) error {
+	return s.transaction(func(tx *sql.Tx) error {
+		name, _, err := getBundleNameAndVersionForImage(tx, path)
+		if err != nil {
+			return err
+		}
+		bundles, err := getPredecessors(tx, name)
+		if err != nil {
+			return err
+		}
+
+		deprecatedValue, err := json.Marshal(registry.DeprecatedProperty{})
+		if err != nil {
+			return err
+		}
+		for _, bundle := range append([]string{name}, bundles...) {
+			var version, bundlePath sql.NullString
+			err := tx.QueryRow(`SELECT version, bundlepath FROM operatorbundle WHERE name = ?`, bundle).Scan(&version, &bundlePath)
+			if err == sql.ErrNoRows {
+				// bundles can skip bundles that were never added
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			res, err := tx.Exec(`INSERT OR IGNORE INTO deprecated(operatorbundle_name) VALUES (?)`, bundle)
+			if err != nil {
+				return err
+			}
+			if marked, err := res.RowsAffected(); err != nil {
+				return err
+			} else if marked == 0 {
+				continue
+			}
+
+			var hasProperty bool
+			if err := tx.QueryRow(`SELECT EXISTS (SELECT 1 FROM properties WHERE type = ? AND operatorbundle_name = ?)`, registry.DeprecatedType, bundle).Scan(&hasProperty); err != nil {
+				return err
+			}
+			if hasProperty {
+				continue
+			}
+			if err := s.addProperty(tx, registry.DeprecatedType, string(deprecatedValue), bundle, version.String, bundlePath.String); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// getPredecessors returns the bundles that a bundle replaces or skips, directly or through other bundles
+func getPredecessors(tx *sql.Tx, name string) ([]string, error) {
+	seen := map[string]struct{}{name: {}}
+	var predecessors []string
+	queue := []string{name}
+	for len(queue) > 0 {
+		next := queue[0]
+		queue = queue[1:]
+
+		var replaces, skips sql.NullString
+		err := tx.QueryRow(`SELECT replaces, skips FROM operatorbundle WHERE name = ?`, next).Scan(&replaces, &skips)
+		if err == sql.ErrNoRows {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		edges := []string{replaces.String}
+		if skips.String != "" {
+			edges = append(edges, strings.Split(skips.String, ",")...)
+		}
+		for _, edge := range edges {
+			if _, ok := seen[edge]; ok || edge == "" {
+				continue
+			}
+			seen[edge] = struct{}{}
+			predecessors = append(predecessors, edge)
+			queue = append(queue, edge)
+		}
+	}
+	return predecessors, nil
+}
@@ -0,0 +1,93 @@
+package sqlite
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/operator-framework/operator-registry/pkg/registry"
+)
+
+func TestMarkBundleDeprecated(t *testing.T) {
+	db, cleanup := CreateTestDb(t)
+	defer cleanup()
+	store, err := NewSQLLiteLoader(db)
+	require.NoError(t, err)
+	require.NoError(t, store.Migrate(context.TODO()))
+
+	channels := []string{"stable", "beta"}
+	for _, b := range []*registry.Bundle{
+		newBundle(t, "csv-a", "pkg", channels, newUnstructuredCSVWithSkipRange(t, "csv-a", "1.0.0", "", "")),
+		newBundle(t, "csv-b", "pkg", channels, newUnstructuredCSVWithSkipRange(t, "csv-b", "1.1.0", "csv-a", "")),
+		newBundle(t, "csv-c", "pkg", channels, newUnstructuredCSVWithSkipRange(t, "csv-c", "1.2.0", "csv-b", "")),
+	} {
+		b.BundleImage = "quay.io/example/" + b.Name
+		require.NoError(t, store.AddOperatorBundle(b))
+	}
+	require.NoError(t, store.AddPackageChannels(registry.PackageManifest{
+		PackageName: "pkg",
+		Channels: []registry.PackageChannel{
+			{Name: "stable", CurrentCSVName: "csv-c"},
+			{Name: "beta", CurrentCSVName: "csv-b"},
+		},
+		DefaultChannelName: "beta",
+	}))
+
+	require.NoError(t, store.MarkBundleDeprecated("quay.io/example/csv-b"))
+	// marking again changes nothing
+	require.NoError(t, store.MarkBundleDeprecated("quay.io/example/csv-b"))
+	require.Equal(t, registry.ErrBundleImageNotInDatabase, store.MarkBundleDeprecated("quay.io/example/missing"))
+
+	var deprecated []string
+	rows, err := db.Query(`SELECT operatorbundle_name FROM deprecated ORDER BY operatorbundle_name`)
+	require.NoError(t, err)
+	for rows.Next() {
+		var name string
+		require.NoError(t, rows.Scan(&name))
+		deprecated = append(deprecated, name)
+	}
+	require.NoError(t, rows.Close())
+	require.Equal(t, []string{"csv-a", "csv-b"}, deprecated)
+
+	var properties int
+	require.NoError(t, db.QueryRow(`SELECT COUNT(*) FROM properties WHERE type = ?`, registry.DeprecatedType).Scan(&properties))
+	require.Equal(t, 2, properties)
+
+	// nothing is removed from the upgrade graph
+	querier := NewSQLLiteQuerierFromDb(db)
+	bundle, err := querier.GetBundleThatReplaces(context.TODO(), "csv-a", "pkg", "stable")
+	require.NoError(t, err)
+	require.Equal(t, "csv-b", bundle.CsvName)
+
+	// but deprecated bundles are no channel's head unless asked for
+	bundle, err = querier.GetBundleForChannel(context.TODO(), "pkg", "stable")
+	require.NoError(t, err)
+	require.Equal(t, "csv-c", bundle.CsvName)
+	_, err = querier.GetBundleForChannel(context.TODO(), "pkg", "beta")
+	require.Error(t, err)
+	bundle, err = querier.GetBundleForChannelIncludingDeprecated(context.TODO(), "pkg", "beta")
+	require.NoError(t, err)
+	require.Equal(t, "csv-b", bundle.CsvName)
+
+	heads, err := querier.ListChannels(context.TODO(), "pkg")
+	require.NoError(t, err)
+	require.Equal(t, []registry.PackageChannel{
+		{Name: "beta", CurrentCSVName: "csv-b", Deprecated: true},
+		{Name: "stable", CurrentCSVName: "csv-c"},
+	}, heads)
+	defaultHeads, err := querier.GetDefaultChannelHeads(context.TODO(), []string{"pkg"})
+	require.NoError(t, err)
+	require.Empty(t, defaultHeads)
+
+	headBundles, err := querier.GetChannelHeadBundles(context.TODO(), "pkg", false)
+	require.NoError(t, err)
+	require.Len(t, headBundles, 1)
+	require.Equal(t, "stable", headBundles[0].ChannelName)
+	require.Equal(t, "csv-c", headBundles[0].CsvName)
+	headBundles, err = querier.GetChannelHeadBundles(context.TODO(), "pkg", true)
+	require.NoError(t, err)
+	require.Len(t, headBundles, 2)
+	require.Equal(t, "beta", headBundles[0].ChannelName)
+	require.Equal(t, "csv-b", headBundles[0].CsvName)
+}
@@ -2,13 +2,12 @@ package sqlite
 
 import (
 	"fmt"
-	"io/ioutil"
+	"io"
 	"os"
 	"path/filepath"
 	"strings"
 
 	"github.com/sirupsen/logrus"
-	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	utilerrors "k8s.io/apimachinery/pkg/util/errors"
 	"k8s.io/apimachinery/pkg/util/yaml"
 
@@ -25,6 +24,7 @@ type SQLPopulator interface {
 type DirectoryLoader struct {
 	store     registry.Load
 	directory string
+	ignore    *registry.IndexIgnore
 }
 
 var _ SQLPopulator = &DirectoryLoader{}
@@ -33,6 +33,7 @@ func NewSQLLoaderForDirectory(store registry.Load, directory string) *DirectoryL
 	return &DirectoryLoader{
 		store:     store,
 		directory: directory,
+		ignore:    registry.NewIndexIgnore(directory),
 	}
 }
 
@@ -65,7 +66,7 @@ func collectWalkErrs(walk filepath.WalkFunc, errs *[]error) filepath.WalkFunc {
 	}
 }
 
-// LoadBundleWalkFunc walks the directory. When it sees a `.clusterserviceversion.yaml` file, it
+// LoadBundleWalkFunc walks the directory. When it sees a manifest file holding a CSV, it
 // attempts to load the surrounding files in the same directory as a bundle, and stores them in the
 // db for querying
 func (d *DirectoryLoader) LoadBundleWalkFunc(path string, f os.FileInfo, err error) error {
@@ -74,6 +75,16 @@ func (d *DirectoryLoader) LoadBundleWalkFunc(path string, f os.FileInfo, err err
 	}
 
 	log := logrus.WithFields(logrus.Fields{"dir": d.directory, "file": f.Name(), "load": "bundles"})
+	if ignored, err := d.ignore.Ignored(path, f.IsDir()); err != nil {
+		return err
+	} else if ignored {
+		log.Info("skipping ignored path")
+		if f.IsDir() {
+			return filepath.SkipDir
+		}
+		return nil
+	}
+
 	if f.IsDir() {
 		if strings.HasPrefix(f.Name(), ".") {
 			log.Info("skipping hidden directory")
@@ -88,33 +99,39 @@ func (d *DirectoryLoader) LoadBundleWalkFunc(path string, f os.FileInfo, err err
 		return nil
 	}
 
-	fileReader, err := os.Open(path)
-	if err != nil {
-		return fmt.Errorf("unable to load file %s: %s", path, err)
+	if !registry.IsManifestFile(f.Name()) {
+		log.Debug("skipping file that doesn't hold manifests")
+		return nil
 	}
 
-	decoder := yaml.NewYAMLOrJSONDecoder(fileReader, 30)
-	csv := unstructured.Unstructured{}
-
-	if err = decoder.Decode(&csv); err != nil {
+	objs, err := registry.DecodeManifestFile(path)
+	if err != nil {
 		return nil
 	}
 
-	if csv.GetKind() != ClusterServiceVersionKind {
-		return nil
+	var errs []error
+	for _, csv := range objs {
+		if csv.GetKind() != ClusterServiceVersionKind {
+			continue
+		}
+
+		log.Info("found csv, loading bundle")
+		errs = append(errs, d.loadBundle(csv.GetName(), filepath.Dir(path))...)
 	}
 
-	log.Info("found csv, loading bundle")
+	return utilerrors.NewAggregate(errs)
+}
 
+func (d *DirectoryLoader) loadBundle(csvName, dir string) []error {
 	var errs []error
-	bundle, err := loadBundle(csv.GetName(), filepath.Dir(path))
+	bundle, err := loadBundle(csvName, dir, d.ignore)
 	if err != nil {
 		errs = append(errs, fmt.Errorf("error loading objs in directory: %s", err))
 	}
 
 	if bundle == nil || bundle.Size() == 0 {
 		errs = append(errs, fmt.Errorf("no bundle objects found"))
-		return utilerrors.NewAggregate(errs)
+		return errs
 	}
 
 	if err := bundle.AllProvidedAPIsInBundle(); err != nil {
@@ -123,10 +140,10 @@ func (d *DirectoryLoader) LoadBundleWalkFunc(path string, f os.FileInfo, err err
 
 	if err := d.store.AddOperatorBundle(bundle); err != nil {
 		version, _ := bundle.Version()
-		errs = append(errs, fmt.Errorf("error adding operator bundle %s/%s/%s: %s", csv.GetName(), version, bundle.BundleImage, err))
+		errs = append(errs, fmt.Errorf("error adding operator bundle %s/%s/%s: %s", csvName, version, bundle.BundleImage, err))
 	}
 
-	return utilerrors.NewAggregate(errs)
+	return errs
 }
 
 // LoadPackagesWalkFunc attempts to unmarshal the file at the given path into a PackageManifest resource.
@@ -137,6 +154,16 @@ func (d *DirectoryLoader) LoadPackagesWalkFunc(path string, f os.FileInfo, err e
 	}
 
 	log := logrus.WithFields(logrus.Fields{"dir": d.directory, "file": f.Name(), "load": "package"})
+	if ignored, err := d.ignore.Ignored(path, f.IsDir()); err != nil {
+		return err
+	} else if ignored {
+		log.Info("skipping ignored path")
+		if f.IsDir() {
+			return filepath.SkipDir
+		}
+		return nil
+	}
+
 	if f.IsDir() {
 		if strings.HasPrefix(f.Name(), ".") {
 			log.Info("skipping hidden directory")
@@ -151,79 +178,61 @@ func (d *DirectoryLoader) LoadPackagesWalkFunc(path string, f os.FileInfo, err e
 		return nil
 	}
 
+	if !registry.IsManifestFile(f.Name()) {
+		log.Debug("skipping file that doesn't hold manifests")
+		return nil
+	}
+
 	fileReader, err := os.Open(path)
 	if err != nil {
 		return fmt.Errorf("unable to load package from file %s: %s", path, err)
 	}
+	defer fileReader.Close()
 
+	// a package manifest may share a multi-document file with other manifests
 	decoder := yaml.NewYAMLOrJSONDecoder(fileReader, 30)
-	manifest := registry.PackageManifest{}
-	if err = decoder.Decode(&manifest); err != nil {
-		if err != nil {
+	for {
+		manifest := registry.PackageManifest{}
+		if err := decoder.Decode(&manifest); err != nil {
+			if err == io.EOF {
+				return nil
+			}
 			return fmt.Errorf("could not decode contents of file %s into package: %s", path, err)
 		}
+		if manifest.PackageName == "" {
+			continue
+		}
 
+		if err := d.store.AddPackageChannels(manifest); err != nil {
+			return fmt.Errorf("error loading package into db: %s", err)
+		}
 	}
-	if manifest.PackageName == "" {
-		return nil
-	}
-
-	if err := d.store.AddPackageChannels(manifest); err != nil {
-		return fmt.Errorf("error loading package into db: %s", err)
-	}
-
-	return nil
 }
 
 // loadBundle takes the directory that a CSV is in and assumes the rest of the objects in that directory
-// are part of the bundle.
-func loadBundle(csvName string, dir string) (*registry.Bundle, error) {
-	log := logrus.WithFields(logrus.Fields{"dir": dir, "load": "bundle", "name": csvName})
-	files, err := ioutil.ReadDir(dir)
-	if err != nil {
+// are part of the bundle. Files left out by the loader's ignore files are skipped.
+func loadBundle(csvName string, dir string, ignore *registry.IndexIgnore) (*registry.Bundle, error) {
+	if _, err := os.Stat(dir); err != nil {
 		return nil, err
 	}
 
-	var errs []error
+	// nested directories hold other bundles
+	objs, err := ignore.DecodeManifestDir(dir, false)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{"dir": dir, "load": "bundle", "name": csvName}).WithError(err).Debug("could not decode file contents")
+	}
+
 	bundle := &registry.Bundle{
 		Name: csvName,
 	}
-	for _, f := range files {
-		log = log.WithField("file", f.Name())
-		if f.IsDir() {
-			log.Info("skipping directory")
-			continue
-		}
-
-		if strings.HasPrefix(f.Name(), ".") {
-			log.Info("skipping hidden file")
-			continue
-		}
-
-		log.Info("loading bundle file")
-		path := filepath.Join(dir, f.Name())
-		fileReader, err := os.Open(path)
-		if err != nil {
-			errs = append(errs, fmt.Errorf("unable to load file %s: %s", path, err))
-			continue
-		}
-
-		decoder := yaml.NewYAMLOrJSONDecoder(fileReader, 30)
-		obj := &unstructured.Unstructured{}
-		if err = decoder.Decode(obj); err != nil {
-			logrus.WithError(err).Debugf("could not decode file contents for %s", path)
-			continue
-		}
-
+	for _, obj := range objs {
 		// Don't include other CSVs in the bundle
 		if obj.GetKind() == "ClusterServiceVersion" && obj.GetName() != csvName {
 			continue
 		}
 
-		if obj.Object != nil {
-			bundle.Add(obj)
-		}
+		bundle.Add(obj)
 	}
 
-	return bundle, utilerrors.NewAggregate(errs)
+	return bundle, nil
 }
@@ -0,0 +1,366 @@
+package sqlite
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"golang.org/x/crypto/hkdf"
+
+	"github.com/operator-framework/operator-registry/pkg/lib/tmp"
+)
+
+// EnvDatabaseKey holds the key encrypted databases are opened with when no key file is given
+const EnvDatabaseKey = "OPM_DATABASE_KEY"
+
+const (
+	// encryptedMagic starts every encrypted database, which can't be mistaken for an SQLite database, whose files
+	// start with "SQLite format 3"
+	encryptedMagic   = "OPMDBENC"
+	encryptedVersion = 1
+	// an encrypted database is a header followed by chunks of the database, each sealed on its own so that databases
+	// of any size are encrypted and decrypted as streams
+	encryptedChunkSize = 64 * 1024
+	saltSize           = 16
+	noncePrefixSize    = 7
+	headerSize         = len(encryptedMagic) + 1 + saltSize + noncePrefixSize
+	// minKeySize is the shortest key accepted, keys should be random rather than passphrases
+	minKeySize = 16
+)
+
+// ErrDatabaseEncrypted is returned for an encrypted database that is opened without a key
+var ErrDatabaseEncrypted = errors.New("database is encrypted, a key is needed to open it")
+
+// DatabaseKey is the key catalog databases are encrypted at rest with. Each database is encrypted with AES-256-GCM
+// under a key derived from it and a random salt of the database, so the same key can encrypt any number of databases.
+type DatabaseKey struct {
+	material []byte
+}
+
+// NewDatabaseKey returns a key made of material, which should be random bytes, e.g. from openssl rand 32
+func NewDatabaseKey(material []byte) (*DatabaseKey, error) {
+	if len(material) < minKeySize {
+		return nil, fmt.Errorf("database key is %d bytes, it must be at least %d", len(material), minKeySize)
+	}
+	return &DatabaseKey{material: append([]byte(nil), material...)}, nil
+}
+
+// LoadDatabaseKey reads the key in file, or in the OPM_DATABASE_KEY environment variable if file is empty. A trailing
+// newline is not part of the key. It returns nil if neither is set.
+func LoadDatabaseKey(file string) (*DatabaseKey, error) {
+	var material []byte
+	if file != "" {
+		b, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("error reading database key: %s", err)
+		}
+		material = bytes.TrimRight(b, "\r\n")
+	} else if env := os.Getenv(EnvDatabaseKey); env != "" {
+		material = []byte(env)
+	} else {
+		return nil, nil
+	}
+	return NewDatabaseKey(material)
+}
+
+// IsEncrypted returns true if the file at path is an encrypted database
+func IsEncrypted(path string) (bool, error) {
+	f, err := tmp.OpenRegularFile(path)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+	magic := make([]byte, len(encryptedMagic))
+	if _, err := io.ReadFull(f, magic); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return false, nil
+		}
+		return false, err
+	}
+	return string(magic) == encryptedMagic, nil
+}
+
+// EncryptDatabase writes an encrypted copy of the database at from to to. The database is copied with SQLite first,
+// so it can be encrypted while it is being written to.
+func EncryptDatabase(ctx context.Context, key *DatabaseKey, from, to string) error {
+	if encrypted, err := IsEncrypted(from); err != nil {
+		return err
+	} else if encrypted {
+		return fmt.Errorf("%s is already encrypted", from)
+	}
+	plain, err := ioutil.TempFile(tmp.WorkDir(), "db-")
+	if err != nil {
+		return err
+	}
+	plain.Close()
+	defer os.Remove(plain.Name())
+	if err := SafeCopyFile(ctx, from, plain.Name()); err != nil {
+		return err
+	}
+
+	src, err := os.Open(plain.Name())
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	return writeFileAtomic(to, func(w io.Writer) error {
+		return encrypt(key, src, w)
+	})
+}
+
+// DecryptDatabase writes the database encrypted at from to to, failing if it was encrypted with another key or has
+// been tampered with
+func DecryptDatabase(key *DatabaseKey, from, to string) error {
+	src, err := tmp.OpenRegularFile(from)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	return writeFileAtomic(to, func(w io.Writer) error {
+		if err := decrypt(key, src, w); err != nil {
+			return fmt.Errorf("error decrypting %s: %s", from, err)
+		}
+		return nil
+	})
+}
+
+// LoadMemoryDatabaseWithKey loads the database at path into memory, decrypting it with key if it is encrypted. An
+// encrypted database is only ever decrypted into memory, it is never written to disk in the clear, which is only
+// supported on linux. It returns ErrDatabaseEncrypted for an encrypted database if key is nil.
+func LoadMemoryDatabaseWithKey(ctx context.Context, path string, key *DatabaseKey) (*MemoryDatabase, error) {
+	encrypted, err := IsEncrypted(path)
+	if err != nil {
+		return nil, err
+	}
+	if !encrypted {
+		return LoadMemoryDatabase(ctx, path)
+	}
+	if key == nil {
+		return nil, fmt.Errorf("%s: %s", path, ErrDatabaseEncrypted)
+	}
+
+	src, err := tmp.OpenRegularFile(path)
+	if err != nil {
+		return nil, err
+	}
+	defer src.Close()
+	// the database is decrypted into memory to be copied into the in-memory database, SQLite can only copy a file
+	plain, err := memoryFile("opm-decrypted-db")
+	if err != nil {
+		return nil, fmt.Errorf("error decrypting %s into memory: %s", path, err)
+	}
+	defer os.Remove(plain.Name())
+	defer plain.Close()
+	bw := bufio.NewWriter(plain)
+	if err := decrypt(key, src, bw); err != nil {
+		return nil, fmt.Errorf("error decrypting %s: %s", path, err)
+	}
+	if err := bw.Flush(); err != nil {
+		return nil, err
+	}
+	m, err := loadMemoryDatabase(ctx, "file:"+plain.Name()+"?immutable=true")
+	if err != nil {
+		return nil, fmt.Errorf("error loading %s into memory: %s", path, err)
+	}
+	return m, nil
+}
+
+// OpenWritableCopy opens a writable copy of the database at path, to be migrated and served without touching the
+// original. Without a key the database is copied to the work directory. With one it is loaded into memory instead,
+// and decrypted if it is encrypted, so that it is never written to disk in the clear. close drops the copy.
+func OpenWritableCopy(ctx context.Context, path string, key *DatabaseKey) (db *sql.DB, close func() error, err error) {
+	if key != nil {
+		m, err := LoadMemoryDatabaseWithKey(ctx, path, key)
+		if err != nil {
+			return nil, nil, err
+		}
+		return m.DB(), m.Close, nil
+	}
+
+	if encrypted, err := IsEncrypted(path); err != nil {
+		return nil, nil, err
+	} else if encrypted {
+		return nil, nil, fmt.Errorf("%s: %s", path, ErrDatabaseEncrypted)
+	}
+	copied, err := tmp.CopyTmpDB(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	db, err = sql.Open("sqlite3", copied)
+	if err != nil {
+		os.Remove(copied)
+		return nil, nil, err
+	}
+	return db, func() error {
+		err := db.Close()
+		if rerr := os.Remove(copied); err == nil {
+			err = rerr
+		}
+		return err
+	}, nil
+}
+
+// NewEncryptedSQLLiteQuerier returns a querier for the database at path, which is decrypted with key if it is
+// encrypted. The database is queried from memory, which close drops along with closing the querier.
+func NewEncryptedSQLLiteQuerier(path string, key *DatabaseKey) (q *SQLQuerier, close func() error, err error) {
+	m, err := LoadMemoryDatabaseWithKey(context.TODO(), path, key)
+	if err != nil {
+		return nil, nil, err
+	}
+	q = NewSQLLiteQuerierFromDb(m.DB())
+	return q, func() error {
+		err := q.Close()
+		if cerr := m.Close(); err == nil {
+			err = cerr
+		}
+		return err
+	}, nil
+}
+
+func encrypt(key *DatabaseKey, r io.Reader, w io.Writer) error {
+	header := make([]byte, headerSize)
+	copy(header, encryptedMagic)
+	header[len(encryptedMagic)] = encryptedVersion
+	if _, err := io.ReadFull(rand.Reader, header[len(encryptedMagic)+1:]); err != nil {
+		return err
+	}
+	aead, noncePrefix, err := key.aead(header)
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+
+	// a chunk is only sealed once the next one is read, so that the last chunk, which may be empty, is marked as such
+	// and a truncated database can't be decrypted
+	br := bufio.NewReaderSize(r, encryptedChunkSize)
+	chunk := make([]byte, encryptedChunkSize)
+	sealed := make([]byte, 0, encryptedChunkSize+aead.Overhead())
+	for counter := uint32(0); ; counter++ {
+		n, err := io.ReadFull(br, chunk)
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			return err
+		}
+		_, peekErr := br.Peek(1)
+		last := peekErr == io.EOF
+		if counter == ^uint32(0) && !last {
+			return fmt.Errorf("database is too large to encrypt")
+		}
+		sealed = aead.Seal(sealed[:0], chunkNonce(noncePrefix, counter, last), chunk[:n], header)
+		if _, err := w.Write(sealed); err != nil {
+			return err
+		}
+		if last {
+			return nil
+		}
+	}
+}
+
+func decrypt(key *DatabaseKey, r io.Reader, w io.Writer) error {
+	header := make([]byte, headerSize)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return fmt.Errorf("not an encrypted database")
+	}
+	if string(header[:len(encryptedMagic)]) != encryptedMagic {
+		return fmt.Errorf("not an encrypted database")
+	}
+	if v := header[len(encryptedMagic)]; v != encryptedVersion {
+		return fmt.Errorf("unsupported encryption version %d", v)
+	}
+	aead, noncePrefix, err := key.aead(header)
+	if err != nil {
+		return err
+	}
+
+	br := bufio.NewReaderSize(r, encryptedChunkSize+aead.Overhead())
+	chunk := make([]byte, encryptedChunkSize+aead.Overhead())
+	plain := make([]byte, 0, encryptedChunkSize)
+	for counter := uint32(0); ; counter++ {
+		n, err := io.ReadFull(br, chunk)
+		if err != nil && err != io.ErrUnexpectedEOF {
+			if err == io.EOF {
+				return fmt.Errorf("database is truncated")
+			}
+			return err
+		}
+		_, peekErr := br.Peek(1)
+		last := peekErr == io.EOF
+		plain, err = aead.Open(plain[:0], chunkNonce(noncePrefix, counter, last), chunk[:n], header)
+		if err != nil {
+			return fmt.Errorf("wrong key, or the database was modified or truncated")
+		}
+		if _, err := w.Write(plain); err != nil {
+			return err
+		}
+		if last {
+			return nil
+		}
+	}
+}
+
+// aead returns the cipher of a database with the given header, whose salt the key is derived with, along with the
+// prefix of the nonces of its chunks
+func (k *DatabaseKey) aead(header []byte) (cipher.AEAD, []byte, error) {
+	salt := header[len(encryptedMagic)+1 : len(encryptedMagic)+1+saltSize]
+	derived := make([]byte, 32)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, k.material, salt, []byte("opm database encryption v1")), derived); err != nil {
+		return nil, nil, err
+	}
+	block, err := aes.NewCipher(derived)
+	if err != nil {
+		return nil, nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, nil, err
+	}
+	return aead, header[len(encryptedMagic)+1+saltSize:], nil
+}
+
+// chunkNonce returns the nonce of a chunk: the prefix of the database, the index of the chunk, and whether it is the
+// last one, so that chunks can't be reordered, dropped or appended
+func chunkNonce(prefix []byte, counter uint32, last bool) []byte {
+	nonce := make([]byte, noncePrefixSize+5)
+	copy(nonce, prefix)
+	binary.BigEndian.PutUint32(nonce[noncePrefixSize:], counter)
+	if last {
+		nonce[len(nonce)-1] = 1
+	}
+	return nonce
+}
+
+// writeFileAtomic writes a file that only the current user can read to path with write, replacing any file there once
+// it has been written in full
+func writeFileAtomic(path string, write func(w io.Writer) error) error {
+	f, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".tmp-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	bw := bufio.NewWriter(f)
+	if err := write(bw); err != nil {
+		f.Close()
+		return err
+	}
+	if err := bw.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
@@ -0,0 +1,152 @@
+package sqlite
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/operator-framework/operator-registry/pkg/lib/tmp"
+)
+
+func TestEncryptDatabase(t *testing.T) {
+	ctx := context.TODO()
+	dir, err := ioutil.TempDir("", "encrypt-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	plain := filepath.Join(dir, "index.db")
+	db, err := sql.Open("sqlite3", plain)
+	require.NoError(t, err)
+	store, err := NewSQLLiteLoader(db)
+	require.NoError(t, err)
+	require.NoError(t, store.Migrate(ctx))
+	require.NoError(t, NewSQLLoaderForDirectory(store, "./testdata/loader_data").Populate())
+	require.NoError(t, db.Close())
+
+	key, err := NewDatabaseKey([]byte("0123456789abcdef0123456789abcdef"))
+	require.NoError(t, err)
+	encrypted := filepath.Join(dir, "index.db.enc")
+	require.NoError(t, EncryptDatabase(ctx, key, plain, encrypted))
+	isEncrypted, err := IsEncrypted(encrypted)
+	require.NoError(t, err)
+	require.True(t, isEncrypted)
+	isEncrypted, err = IsEncrypted(plain)
+	require.NoError(t, err)
+	require.False(t, isEncrypted)
+	info, err := os.Stat(encrypted)
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0600), info.Mode().Perm())
+	ciphertext, err := ioutil.ReadFile(encrypted)
+	require.NoError(t, err)
+	require.False(t, bytes.Contains(ciphertext, []byte("etcdoperator")))
+	// the database spans several chunks
+	require.True(t, len(ciphertext) > 2*encryptedChunkSize)
+
+	// the encrypted database is queried like the one it was encrypted from
+	q, closeQuerier, err := NewEncryptedSQLLiteQuerier(encrypted, key)
+	require.NoError(t, err)
+	packages, err := q.ListPackages(ctx)
+	require.NoError(t, err)
+	expected, err := NewSQLLiteQuerier(plain)
+	require.NoError(t, err)
+	expectedPackages, err := expected.ListPackages(ctx)
+	require.NoError(t, err)
+	require.ElementsMatch(t, expectedPackages, packages)
+	require.NoError(t, closeQuerier())
+	// databases that aren't encrypted are opened as they are
+	_, closeQuerier, err = NewEncryptedSQLLiteQuerier(plain, key)
+	require.NoError(t, err)
+	require.NoError(t, closeQuerier())
+
+	decrypted := filepath.Join(dir, "decrypted.db")
+	require.NoError(t, DecryptDatabase(key, encrypted, decrypted))
+	copied, err := sql.Open("sqlite3", decrypted)
+	require.NoError(t, err)
+	defer copied.Close()
+	var integrity string
+	require.NoError(t, copied.QueryRow("PRAGMA integrity_check").Scan(&integrity))
+	require.Equal(t, "ok", integrity)
+
+	_, _, err = OpenWritableCopy(ctx, encrypted, nil)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), ErrDatabaseEncrypted.Error())
+	// a writable copy of an encrypted database is only decrypted into memory
+	workDir := filepath.Join(dir, "work")
+	require.NoError(t, os.Mkdir(workDir, 0700))
+	tmp.SetWorkDir(workDir)
+	defer tmp.SetWorkDir("")
+	writable, closeWritable, err := OpenWritableCopy(ctx, encrypted, key)
+	require.NoError(t, err)
+	_, err = writable.Exec("DELETE FROM package")
+	require.NoError(t, err)
+	files, err := ioutil.ReadDir(workDir)
+	require.NoError(t, err)
+	require.Empty(t, files)
+	require.NoError(t, closeWritable())
+	require.Error(t, EncryptDatabase(ctx, key, encrypted, filepath.Join(dir, "twice.db")))
+
+	// a database can't be decrypted with another key, or once it has been modified or truncated
+	other, err := NewDatabaseKey([]byte("fedcba9876543210fedcba9876543210"))
+	require.NoError(t, err)
+	require.Error(t, DecryptDatabase(other, encrypted, decrypted))
+	for name, modified := range map[string][]byte{
+		"flipped":   append(append([]byte(nil), ciphertext[:headerSize+10]...), append([]byte{ciphertext[headerSize+10] ^ 1}, ciphertext[headerSize+11:]...)...),
+		"truncated": ciphertext[:headerSize+encryptedChunkSize+16],
+		"appended":  append(append([]byte(nil), ciphertext...), ciphertext[headerSize:headerSize+100]...),
+	} {
+		path := filepath.Join(dir, name+".db")
+		require.NoError(t, ioutil.WriteFile(path, modified, 0600))
+		require.Error(t, DecryptDatabase(key, path, decrypted), name)
+	}
+	// a failed decryption leaves the previous file alone
+	require.NoError(t, copied.QueryRow("PRAGMA integrity_check").Scan(&integrity))
+	require.Equal(t, "ok", integrity)
+}
+
+func TestEncryptSizes(t *testing.T) {
+	key, err := NewDatabaseKey([]byte("0123456789abcdef"))
+	require.NoError(t, err)
+	for _, size := range []int{0, 1, encryptedChunkSize, encryptedChunkSize + 1, 3 * encryptedChunkSize} {
+		plain := bytes.Repeat([]byte{7}, size)
+		var encrypted, decrypted bytes.Buffer
+		require.NoError(t, encrypt(key, bytes.NewReader(plain), &encrypted))
+		require.NoError(t, decrypt(key, &encrypted, &decrypted))
+		require.True(t, bytes.Equal(plain, decrypted.Bytes()), "size %d", size)
+	}
+}
+
+func TestLoadDatabaseKey(t *testing.T) {
+	os.Unsetenv(EnvDatabaseKey)
+	key, err := LoadDatabaseKey("")
+	require.NoError(t, err)
+	require.Nil(t, key)
+
+	os.Setenv(EnvDatabaseKey, "0123456789abcdef")
+	defer os.Unsetenv(EnvDatabaseKey)
+	key, err = LoadDatabaseKey("")
+	require.NoError(t, err)
+	require.Equal(t, []byte("0123456789abcdef"), key.material)
+
+	// a key file takes precedence, without its trailing newline
+	f, err := ioutil.TempFile("", "key-")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+	_, err = f.WriteString("fedcba9876543210fedcba\n")
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+	key, err = LoadDatabaseKey(f.Name())
+	require.NoError(t, err)
+	require.Equal(t, []byte("fedcba9876543210fedcba"), key.material)
+
+	os.Setenv(EnvDatabaseKey, "short")
+	_, err = LoadDatabaseKey("")
+	require.Error(t, err)
+	_, err = LoadDatabaseKey(filepath.Join(os.TempDir(), "missing-key"))
+	require.Error(t, err)
+}
@@ -0,0 +1,34 @@
+package sqlite
+
+import (
+	"github.com/sirupsen/logrus"
+
+	"github.com/operator-framework/operator-registry/pkg/registry"
+)
+
+// HeadsOnlyRemover removes every bundle but the channel heads from the database, for catalogs that are only ever
+// installed from and never upgraded through
+type HeadsOnlyRemover struct {
+	store registry.Load
+}
+
+func NewSQLHeadsOnlyRemover(store registry.Load) *HeadsOnlyRemover {
+	return &HeadsOnlyRemover{
+		store: store,
+	}
+}
+
+func (d *HeadsOnlyRemover) Remove() error {
+	bundles, err := d.store.RemoveNonHeadBundles()
+	if err != nil {
+		return err
+	}
+
+	if len(bundles) > 0 {
+		logrus.WithField("bundles", bundles).Info("removed bundles that are not channel heads")
+	} else {
+		logrus.Info("no bundles found that are not channel heads")
+	}
+
+	return nil
+}
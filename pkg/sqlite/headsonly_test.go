@@ -0,0 +1,45 @@
+package sqlite
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRemoveNonHeadBundles(t *testing.T) {
+	db, cleanup := CreateTestDb(t)
+	defer cleanup()
+	store, err := NewSQLLiteLoader(db)
+	require.NoError(t, err)
+	require.NoError(t, store.Migrate(context.TODO()))
+	require.NoError(t, NewSQLLoaderForDirectory(store, "../../manifests").Populate())
+
+	removed, err := store.RemoveNonHeadBundles()
+	require.NoError(t, err)
+	require.Equal(t, []string{"etcdoperator.v0.6.1", "prometheusoperator.0.14.0", "prometheusoperator.0.15.0", "strimzi-cluster-operator.v0.11.0"}, removed)
+
+	// every channel is left with its head and nothing leading to it
+	querier := NewSQLLiteQuerierFromDb(db)
+	var entries int
+	require.NoError(t, db.QueryRow(`SELECT COUNT(*) FROM channel_entry`).Scan(&entries))
+	require.Equal(t, 7, entries)
+	for _, pkg := range []string{"etcd", "prometheus", "strimzi-kafka-operator"} {
+		p, err := querier.GetPackage(context.TODO(), pkg)
+		require.NoError(t, err)
+		for _, channel := range p.Channels {
+			bundle, err := querier.GetBundleForChannel(context.TODO(), pkg, channel.Name)
+			require.NoError(t, err)
+			require.Equal(t, channel.CurrentCSVName, bundle.CsvName)
+		}
+	}
+	_, err = querier.GetBundleThatReplaces(context.TODO(), "etcdoperator.v0.9.0", "etcd", "alpha")
+	require.Error(t, err)
+	var orphaned int
+	require.NoError(t, db.QueryRow(`SELECT COUNT(*) FROM api_provider WHERE operatorbundle_name NOT IN (SELECT name FROM operatorbundle)`).Scan(&orphaned))
+	require.Zero(t, orphaned)
+
+	removed, err = store.RemoveNonHeadBundles()
+	require.NoError(t, err)
+	require.Empty(t, removed)
+}
@@ -0,0 +1,40 @@
+package sqlite
+
+import (
+	"github.com/sirupsen/logrus"
+
+	"github.com/operator-framework/operator-registry/pkg/registry"
+)
+
+// InstallModeRemover removes every bundle that doesn't support an install mode from the database, for catalogs of
+// operators that must all be installable the same way, e.g. cluster-wide with AllNamespaces
+type InstallModeRemover struct {
+	store registry.Load
+	mode  string
+}
+
+func NewSQLInstallModeRemover(store registry.Load, mode string) *InstallModeRemover {
+	return &InstallModeRemover{
+		store: store,
+		mode:  mode,
+	}
+}
+
+func (d *InstallModeRemover) Remove() error {
+	if err := registry.ValidateInstallMode(d.mode); err != nil {
+		return err
+	}
+	bundles, err := d.store.RemoveBundlesWithoutInstallMode(d.mode)
+	if err != nil {
+		return err
+	}
+
+	log := logrus.WithField("installMode", d.mode)
+	if len(bundles) > 0 {
+		log.WithField("bundles", bundles).Info("removed bundles that don't support the install mode")
+	} else {
+		log.Info("no bundles found that don't support the install mode")
+	}
+
+	return nil
+}
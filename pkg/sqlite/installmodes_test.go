@@ -0,0 +1,79 @@
+package sqlite
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"github.com/operator-framework/operator-registry/pkg/registry"
+)
+
+// newUnstructuredCSVWithInstallModes returns a CSV that supports the given install modes
+func newUnstructuredCSVWithInstallModes(t *testing.T, name, replaces string, supported ...string) *unstructured.Unstructured {
+	csv := newUnstructuredCSV(t, name, replaces)
+	var modes []interface{}
+	for _, mode := range supported {
+		modes = append(modes, map[string]interface{}{"type": mode, "supported": true})
+	}
+	modes = append(modes, map[string]interface{}{"type": registry.InstallModeMultiNamespace, "supported": false})
+	require.NoError(t, unstructured.SetNestedSlice(csv.Object, modes, "spec", "installModes"))
+	return csv
+}
+
+func TestRemoveBundlesWithoutInstallMode(t *testing.T) {
+	db, cleanup := CreateTestDb(t)
+	defer cleanup()
+	store, err := NewSQLLiteLoader(db)
+	require.NoError(t, err)
+	require.NoError(t, store.Migrate(context.TODO()))
+
+	// b doesn't support AllNamespaces and c doesn't record its install modes. d heads the fast channel and supports
+	// only its own namespace, as does the head of the default channel of other.
+	all, own := registry.InstallModeAllNamespaces, registry.InstallModeOwnNamespace
+	both := []string{"stable", "fast"}
+	bundles := []*registry.Bundle{
+		newBundle(t, "a", "pkg", both, newUnstructuredCSVWithInstallModes(t, "a", "", all, own)),
+		newBundle(t, "b", "pkg", both, newUnstructuredCSVWithInstallModes(t, "b", "a", own)),
+		newBundle(t, "c", "pkg", both, newUnstructuredCSV(t, "c", "b")),
+		newBundle(t, "d", "pkg", []string{"fast"}, newUnstructuredCSVWithInstallModes(t, "d", "c", own)),
+		newBundle(t, "x", "other", []string{"stable"}, newUnstructuredCSVWithInstallModes(t, "x", "", all)),
+		newBundle(t, "y", "other", []string{"stable"}, newUnstructuredCSVWithInstallModes(t, "y", "x", own)),
+	}
+	for _, bundle := range bundles {
+		require.NoError(t, store.AddOperatorBundle(bundle))
+	}
+	require.NoError(t, store.AddPackageChannels(registry.PackageManifest{
+		PackageName:        "pkg",
+		Channels:           []registry.PackageChannel{{Name: "stable", CurrentCSVName: "c"}, {Name: "fast", CurrentCSVName: "d"}},
+		DefaultChannelName: "stable",
+	}))
+	require.NoError(t, store.AddPackageChannels(registry.PackageManifest{
+		PackageName:        "other",
+		Channels:           []registry.PackageChannel{{Name: "stable", CurrentCSVName: "y"}},
+		DefaultChannelName: "stable",
+	}))
+
+	removed, err := store.RemoveBundlesWithoutInstallMode(all)
+	require.NoError(t, err)
+	require.Equal(t, []string{"a", "b", "d", "x", "y"}, removed)
+
+	// the default channel keeps the head it can be installed from, and the fast channel is gone
+	querier := NewSQLLiteQuerierFromDb(db)
+	packages, err := querier.ListPackages(context.TODO())
+	require.NoError(t, err)
+	require.Equal(t, []string{"pkg"}, packages)
+	p, err := querier.GetPackage(context.TODO(), "pkg")
+	require.NoError(t, err)
+	require.Equal(t, []registry.PackageChannel{{Name: "stable", CurrentCSVName: "c"}}, p.Channels)
+	entries, err := querier.GetChannelEntriesFromPackage(context.TODO(), "pkg")
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	require.Equal(t, "c", entries[0].BundleName)
+	require.Empty(t, entries[0].Replaces)
+
+	removed, err = store.RemoveBundlesWithoutInstallMode(all)
+	require.NoError(t, err)
+	require.Empty(t, removed)
+}
@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"sort"
 	"strings"
 
 	_ "github.com/mattn/go-sqlite3"
@@ -16,10 +17,13 @@ import (
 type sqlLoader struct {
 	db       *sql.DB
 	migrator Migrator
+	retry    RetryPolicy
 }
 
 type MigratableLoader interface {
 	registry.Load
+	registry.Tombstoner
+	registry.Renamer
 	Migrate(context.Context) error
 }
 
@@ -35,12 +39,16 @@ func NewSQLLiteLoader(db *sql.DB, opts ...DbOption) (MigratableLoader, error) {
 		return nil, err
 	}
 
+	if err := options.apply(db); err != nil {
+		return nil, err
+	}
+
 	migrator, err := options.MigratorBuilder(db)
 	if err != nil {
 		return nil, err
 	}
 
-	return &sqlLoader{db: db, migrator: migrator}, nil
+	return &sqlLoader{db: db, migrator: migrator, retry: options.Retry}, nil
 }
 
 func (s *sqlLoader) Migrate(ctx context.Context) error {
@@ -51,23 +59,13 @@ func (s *sqlLoader) Migrate(ctx context.Context) error {
 }
 
 func (s *sqlLoader) AddOperatorBundle(bundle *registry.Bundle) error {
-	tx, err := s.db.Begin()
-	if err != nil {
-		return err
-	}
-	defer func() {
-		tx.Rollback()
-	}()
-
-	if err := s.addOperatorBundle(tx, bundle); err != nil {
-		return err
-	}
-
-	return tx.Commit()
+	return s.transaction(func(tx *sql.Tx) error {
+		return s.addOperatorBundle(tx, bundle)
+	})
 }
 
 func (s *sqlLoader) addOperatorBundle(tx *sql.Tx, bundle *registry.Bundle) error {
-	addBundle, err := tx.Prepare("insert into operatorbundle(name, csv, bundle, bundlepath, version, skiprange, replaces, skips) values(?, ?, ?, ?, ?, ?, ?, ?)")
+	addBundle, err := tx.Prepare("insert into operatorbundle(name, csv, bundle, bundlepath, version, skiprange, replaces, skips, provider, maintainers, links, digest) values(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)")
 	if err != nil {
 		return err
 	}
@@ -105,7 +103,22 @@ func (s *sqlLoader) addOperatorBundle(tx *sql.Tx, bundle *registry.Bundle) error
 		return err
 	}
 
-	if _, err := addBundle.Exec(csvName, csvBytes, bundleBytes, bundleImage, version, skiprange, replaces, strings.Join(skips, ",")); err != nil {
+	provider, maintainers, links, err := bundleOwnership(bundle)
+	if err != nil {
+		return err
+	}
+	digest, err := bundle.Digest()
+	if err != nil {
+		return err
+	}
+
+	if _, err := addBundle.Exec(csvName, csvBytes, bundleBytes, bundleImage, version, skiprange, replaces, strings.Join(skips, ","), provider, maintainers, links, digest); err != nil {
+		return err
+	}
+	if err := addBundleID(tx, csvName); err != nil {
+		return err
+	}
+	if err := addBundleStats(tx, csvName, bundle, bundleBytes); err != nil {
 		return err
 	}
 
@@ -133,15 +146,63 @@ func (s *sqlLoader) addOperatorBundle(tx *sql.Tx, bundle *registry.Bundle) error
 	return s.addAPIs(tx, bundle)
 }
 
+// addBundleStats records the size of the serialized manifests of a bundle and the number of objects and CRDs among
+// them, which outlive the manifests if they are cleared to save space
+func addBundleStats(tx *sql.Tx, csvName string, bundle *registry.Bundle, manifests []byte) error {
+	crds := 0
+	for _, obj := range bundle.Objects {
+		if obj.GetKind() == "CustomResourceDefinition" {
+			crds++
+		}
+	}
+	_, err := tx.Exec(`INSERT OR REPLACE INTO bundle_stats(operatorbundle_name, size, object_count, crd_count) VALUES (?, ?, ?, ?)`, csvName, len(manifests), len(bundle.Objects), crds)
+	return err
+}
+
+// bundleOwnership extracts the provider name along with the serialized maintainers and links from a bundle's CSV
+func bundleOwnership(bundle *registry.Bundle) (provider, maintainers, links string, err error) {
+	csv, err := bundle.ClusterServiceVersion()
+	if err != nil {
+		return
+	}
+	if provider, err = csv.GetProvider(); err != nil {
+		return
+	}
+	m, err := csv.GetMaintainers()
+	if err != nil {
+		return
+	}
+	mBytes, err := json.Marshal(m)
+	if err != nil {
+		return
+	}
+	l, err := csv.GetLinks()
+	if err != nil {
+		return
+	}
+	lBytes, err := json.Marshal(l)
+	if err != nil {
+		return
+	}
+	return provider, string(mBytes), string(lBytes), nil
+}
+
 func (s *sqlLoader) AddPackageChannelsFromGraph(graph *registry.Package) error {
-	tx, err := s.db.Begin()
+	var graphErr error
+	err := s.transaction(func(tx *sql.Tx) error {
+		// the graph is committed even if some of it couldn't be written, and those errors are returned
+		graphErr = addPackageChannelsFromGraph(tx, graph)
+		return nil
+	})
 	if err != nil {
 		return err
 	}
-	defer func() {
-		tx.Rollback()
-	}()
+	return graphErr
+}
 
+// addPackageChannelsFromGraph writes the package, channels and channel entries of a graph, returning every error it
+// ran into
+func addPackageChannelsFromGraph(tx *sql.Tx, graph *registry.Package) error {
 	var errs []error
 
 	if err := addPackageIfNotExists(tx, graph.Name); err != nil {
@@ -159,115 +220,113 @@ func (s *sqlLoader) AddPackageChannelsFromGraph(graph *registry.Package) error {
 		errs = append(errs, err)
 	}
 
-	// update each channel's graph
+	// update each channel's graph. A channel whose graph can't be written is left as it was, rather than half
+	// written, while the others are still updated.
 	for channelName, channel := range graph.Channels {
-		currentNode := channel.Head
-		depth := 1
+		err := savepoint(tx, "channel_graph", func() error {
+			return utilerrors.NewAggregate(addChannelGraph(tx, graph.Name, channelName, channel))
+		})
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
 
-		var previousNodeID int64
+	return utilerrors.NewAggregate(errs)
+}
 
-		// first clear the current channel graph
-		err := truncChannelGraph(tx, channelName, graph.Name)
+// addChannelGraph replaces the entries of a channel with those of its graph, returning every error it ran into
+func addChannelGraph(tx *sql.Tx, pkgName, channelName string, channel registry.Channel) []error {
+	var errs []error
+	currentNode := channel.Head
+	depth := 1
+
+	var previousNodeID int64
+
+	// first clear the current channel graph
+	err := truncChannelGraph(tx, channelName, pkgName)
+	if err != nil {
+		return []error{err}
+	}
+
+	// iterate into the replacement chain of the channel to insert or update all entries
+	for {
+		// create real channel entry for node
+		id, err := addChannelEntry(tx, channelName, pkgName, currentNode.CsvName, depth)
 		if err != nil {
 			errs = append(errs, err)
 			break
 		}
 
-		// iterate into the replacement chain of the channel to insert or update all entries
-		for {
-			// create real channel entry for node
-			id, err := addChannelEntry(tx, channelName, graph.Name, currentNode.CsvName, depth)
+		// If the previous node was created, use the entryId of the current node to update
+		// the replaces for the previous node
+		if previousNodeID != 0 {
+			err := addReplaces(tx, id, previousNodeID)
 			if err != nil {
 				errs = append(errs, err)
-				break
-			}
-
-			// If the previous node was created, use the entryId of the current node to update
-			// the replaces for the previous node
-			if previousNodeID != 0 {
-				err := addReplaces(tx, id, previousNodeID)
-				if err != nil {
-					errs = append(errs, err)
-				}
 			}
+		}
 
-			syntheticReplaces := make([]registry.BundleKey, 0) // for CSV skips
-			nextNode := registry.BundleKey{}
+		syntheticReplaces := make([]registry.BundleKey, 0) // for CSV skips
+		nextNode := registry.BundleKey{}
 
-			currentNodeReplaces := channel.Nodes[currentNode]
+		currentNodeReplaces := channel.Nodes[currentNode]
 
-			// Iterate over all replaces for the node in the graph
-			// It should only contain one real replacement, so let's find it and
-			// follow the chain. For the rest, they are fake entries and should be
-			// generated as synthetic replacements
-			for replace := range currentNodeReplaces {
-				if _, ok := channel.Nodes[replace]; !ok {
-					syntheticReplaces = append(syntheticReplaces, replace)
-				} else {
-					nextNode = replace
-				}
+		// Iterate over all replaces for the node in the graph
+		// It should only contain one real replacement, so let's find it and
+		// follow the chain. For the rest, they are fake entries and should be
+		// generated as synthetic replacements
+		for replace := range currentNodeReplaces {
+			if _, ok := channel.Nodes[replace]; !ok {
+				syntheticReplaces = append(syntheticReplaces, replace)
+			} else {
+				nextNode = replace
 			}
+		}
 
-			// create synthetic channel entries for nodes
-			// also create channel entry to replace that node
-			syntheticDepth := depth + 1
-			for _, synthetic := range syntheticReplaces {
-				syntheticReplacesID, err := addChannelEntry(tx, channelName, graph.Name, synthetic.CsvName, syntheticDepth)
-				if err != nil {
-					errs = append(errs, err)
-					break
-				}
-
-				syntheticNodeID, err := addChannelEntry(tx, channelName, graph.Name, currentNode.CsvName, syntheticDepth)
-				if err != nil {
-					errs = append(errs, err)
-					break
-				}
-
-				err = addReplaces(tx, syntheticReplacesID, syntheticNodeID)
-				if err != nil {
-					errs = append(errs, err)
-				}
-				syntheticDepth++
+		// create synthetic channel entries for nodes
+		// also create channel entry to replace that node
+		syntheticDepth := depth + 1
+		for _, synthetic := range syntheticReplaces {
+			syntheticReplacesID, err := addChannelEntry(tx, channelName, pkgName, synthetic.CsvName, syntheticDepth)
+			if err != nil {
+				errs = append(errs, err)
+				break
 			}
 
-			// we got to the end of the channel graph
-			if nextNode.IsEmpty() {
-				if len(channel.Nodes) != depth {
-					err := fmt.Errorf("Invalid graph: some (non-bottom) nodes defined in the graph were not mentioned as replacements of any node")
-					errs = append(errs, err)
-				}
+			syntheticNodeID, err := addChannelEntry(tx, channelName, pkgName, currentNode.CsvName, syntheticDepth)
+			if err != nil {
+				errs = append(errs, err)
 				break
 			}
 
-			// increase depth and continue
-			currentNode = nextNode
-			previousNodeID = id
-			depth++
+			err = addReplaces(tx, syntheticReplacesID, syntheticNodeID)
+			if err != nil {
+				errs = append(errs, err)
+			}
+			syntheticDepth++
 		}
-	}
 
-	if err := tx.Commit(); err != nil {
-		errs = append(errs, err)
-	}
+		// we got to the end of the channel graph
+		if nextNode.IsEmpty() {
+			if len(channel.Nodes) != depth {
+				err := fmt.Errorf("Invalid graph: some (non-bottom) nodes defined in the graph were not mentioned as replacements of any node")
+				errs = append(errs, err)
+			}
+			break
+		}
 
-	return utilerrors.NewAggregate(errs)
+		// increase depth and continue
+		currentNode = nextNode
+		previousNodeID = id
+		depth++
+	}
+	return errs
 }
 
 func (s *sqlLoader) AddPackageChannels(manifest registry.PackageManifest) error {
-	tx, err := s.db.Begin()
-	if err != nil {
-		return err
-	}
-	defer func() {
-		tx.Rollback()
-	}()
-
-	if err := s.addPackageChannels(tx, manifest); err != nil {
-		return err
-	}
-
-	return tx.Commit()
+	return s.transaction(func(tx *sql.Tx) error {
+		return s.addPackageChannels(tx, manifest)
+	})
 }
 
 func (s *sqlLoader) addPackageChannels(tx *sql.Tx, manifest registry.PackageManifest) error {
@@ -316,6 +375,10 @@ func (s *sqlLoader) addPackageChannels(tx *sql.Tx, manifest registry.PackageMani
 		errs = append(errs, err)
 		return utilerrors.NewAggregate(errs)
 	}
+	if err := addPackageID(tx, manifest.PackageName); err != nil {
+		errs = append(errs, err)
+		return utilerrors.NewAggregate(errs)
+	}
 
 	hasDefault := false
 	for _, c := range manifest.Channels {
@@ -449,32 +512,26 @@ func (s *sqlLoader) addPackageChannels(tx *sql.Tx, manifest registry.PackageMani
 }
 
 func (s *sqlLoader) ClearNonHeadBundles() error {
-	tx, err := s.db.Begin()
-	if err != nil {
-		return err
-	}
-	defer func() {
-		tx.Rollback()
-	}()
-
-	removeNonHeadBundles, err := tx.Prepare(`
-		update operatorbundle set bundle = null, csv = null
-		where (bundlepath != null or bundlepath != "")
-		and name not in (
-			select operatorbundle.name from operatorbundle
-			join channel on channel.head_operatorbundle_name = operatorbundle.name
-		)
-	`)
-	if err != nil {
-		return err
-	}
-	defer removeNonHeadBundles.Close()
+	return s.transaction(func(tx *sql.Tx) error {
+		removeNonHeadBundles, err := tx.Prepare(`
+			update operatorbundle set bundle = null, csv = null
+			where (bundlepath != null or bundlepath != "")
+			and name not in (
+				select operatorbundle.name from operatorbundle
+				join channel on channel.head_operatorbundle_name = operatorbundle.name
+			)
+		`)
+		if err != nil {
+			return err
+		}
+		defer removeNonHeadBundles.Close()
 
-	_, err = removeNonHeadBundles.Exec()
-	if err != nil {
-		return err
-	}
-	return tx.Commit()
+		_, err = removeNonHeadBundles.Exec()
+		if err != nil {
+			return err
+		}
+		return nil
+	})
 }
 
 func (s *sqlLoader) getBundleSkipsReplacesVersion(tx *sql.Tx, bundleName string) (replaces string, skips []string, version string, err error) {
@@ -643,29 +700,31 @@ func (s *sqlLoader) getCSVNames(tx *sql.Tx, packageName string) ([]string, error
 }
 
 func (s *sqlLoader) RemovePackage(packageName string) error {
-	tx, err := s.db.Begin()
-	if err != nil {
-		return err
-	}
-	defer func() {
-		tx.Rollback()
-	}()
-
-	csvNames, err := s.getCSVNames(tx, packageName)
-	if err != nil {
-		return err
-	}
-	for _, csvName := range csvNames {
-		err = s.rmBundle(tx, csvName)
+	return s.transaction(func(tx *sql.Tx) error {
+		csvNames, err := s.getCSVNames(tx, packageName)
 		if err != nil {
 			return err
 		}
-	}
+		for _, csvName := range csvNames {
+			err = s.rmBundle(tx, csvName)
+			if err != nil {
+				return err
+			}
+		}
 
-	return tx.Commit()
+		return nil
+	})
 }
 
 func (s *sqlLoader) rmBundle(tx *sql.Tx, csvName string) error {
+	// The apis of bundles loaded without a bundle path are associated with a null path, which the foreign key to the
+	// bundle doesn't cascade to, so they are removed by name
+	for _, table := range []string{"api_provider", "api_requirer"} {
+		if _, err := tx.Exec("DELETE FROM "+table+" WHERE operatorbundle_name=?", csvName); err != nil {
+			return err
+		}
+	}
+
 	stmt, err := tx.Prepare("DELETE FROM operatorbundle WHERE operatorbundle.name=?")
 	if err != nil {
 		return err
@@ -679,58 +738,45 @@ func (s *sqlLoader) rmBundle(tx *sql.Tx, csvName string) error {
 	return nil
 }
 
+// AddBundleSemver adds a bundle and the graph of its package it was added to in a single transaction, so that a
+// bundle whose graph can't be written isn't left in the database
 func (s *sqlLoader) AddBundleSemver(graph *registry.Package, bundle *registry.Bundle) error {
-	err := s.AddOperatorBundle(bundle)
-	if err != nil {
-		return err
-	}
-
-	err = s.AddPackageChannelsFromGraph(graph)
-	if err != nil {
-		return err
-	}
-
-	return nil
+	return s.transaction(func(tx *sql.Tx) error {
+		if err := s.addOperatorBundle(tx, bundle); err != nil {
+			return err
+		}
+		return addPackageChannelsFromGraph(tx, graph)
+	})
 }
 
 func (s *sqlLoader) AddBundlePackageChannels(manifest registry.PackageManifest, bundle *registry.Bundle) error {
-	tx, err := s.db.Begin()
-	if err != nil {
-		return err
-	}
-	defer func() {
-		tx.Rollback()
-	}()
-
-	if err := s.addOperatorBundle(tx, bundle); err != nil {
-		return err
-	}
-
-	// Delete package and channels (entries will cascade) - they will be recalculated
-	deletePkg, err := tx.Prepare("delete from package where name = ?")
-	if err != nil {
-		return err
-	}
-	defer deletePkg.Close()
-	_, err = deletePkg.Exec(manifest.PackageName)
-	if err != nil {
-		return err
-	}
-	deleteChan, err := tx.Prepare("delete from channel where package_name = ?")
-	if err != nil {
-		return err
-	}
-	defer deleteChan.Close()
-	_, err = deleteChan.Exec(manifest.PackageName)
-	if err != nil {
-		return err
-	}
+	return s.transaction(func(tx *sql.Tx) error {
+		if err := s.addOperatorBundle(tx, bundle); err != nil {
+			return err
+		}
 
-	if err := s.addPackageChannels(tx, manifest); err != nil {
-		return err
-	}
+		// Delete package and channels (entries will cascade) - they will be recalculated
+		deletePkg, err := tx.Prepare("delete from package where name = ?")
+		if err != nil {
+			return err
+		}
+		defer deletePkg.Close()
+		_, err = deletePkg.Exec(manifest.PackageName)
+		if err != nil {
+			return err
+		}
+		deleteChan, err := tx.Prepare("delete from channel where package_name = ?")
+		if err != nil {
+			return err
+		}
+		defer deleteChan.Close()
+		_, err = deleteChan.Exec(manifest.PackageName)
+		if err != nil {
+			return err
+		}
 
-	return tx.Commit()
+		return s.addPackageChannels(tx, manifest)
+	})
 }
 
 func (s *sqlLoader) addDependencies(tx *sql.Tx, bundle *registry.Bundle) error {
@@ -850,8 +896,34 @@ func (s *sqlLoader) addBundleProperties(tx *sql.Tx, bundle *registry.Bundle) err
 			var props []registry.Property
 			if err := json.Unmarshal([]byte(v), &props); err == nil {
 				for _, prop := range props {
-					// Only add label type from the list
+					// Only add label, localized and platform version types from the list
 					// TODO: Support more types such as GVK and package
+					if prop.Type == registry.PlatformType {
+						var platform registry.PlatformVersionsProperty
+						if err := json.Unmarshal(prop.Value, &platform); err != nil || len(platform.Validate()) > 0 {
+							continue
+						}
+						value, err := json.Marshal(platform)
+						if err != nil {
+							continue
+						}
+						if err := s.addProperty(tx, registry.PlatformType, string(value), bundle.Name, bundleVersion, bundle.BundleImage); err != nil {
+							continue
+						}
+					}
+					if prop.Type == registry.LocalizedType {
+						var localized registry.LocalizedProperty
+						if err := json.Unmarshal(prop.Value, &localized); err != nil || localized.Locale == "" {
+							continue
+						}
+						value, err := json.Marshal(localized)
+						if err != nil {
+							continue
+						}
+						if err := s.addProperty(tx, registry.LocalizedType, string(value), bundle.Name, bundleVersion, bundle.BundleImage); err != nil {
+							continue
+						}
+					}
 					if prop.Type == registry.LabelType {
 						var label registry.LabelProperty
 						err := json.Unmarshal(prop.Value, &label)
@@ -869,6 +941,36 @@ func (s *sqlLoader) addBundleProperties(tx *sql.Tx, bundle *registry.Bundle) err
 				}
 			}
 		}
+
+		// Supported openshift versions are recorded as a platform version range
+		if v, ok := annotations[registry.OpenShiftVersionsAnnotation]; ok {
+			r, err := registry.OpenShiftVersionsToRange(v)
+			if err != nil {
+				return fmt.Errorf("invalid %s annotation on %s: %s", registry.OpenShiftVersionsAnnotation, bundle.Name, err)
+			}
+			value, err := json.Marshal(registry.PlatformVersionsProperty{Range: r})
+			if err != nil {
+				return err
+			}
+			if err := s.addProperty(tx, registry.PlatformType, string(value), bundle.Name, bundleVersion, bundle.BundleImage); err != nil {
+				return err
+			}
+		}
+
+		// The install modes the CSV supports are recorded so that catalogs can be filtered by them
+		modes, declared, err := csv.GetInstallModes()
+		if err != nil {
+			return fmt.Errorf("invalid install modes of %s: %s", bundle.Name, err)
+		}
+		if declared {
+			value, err := json.Marshal(registry.NewInstallModesProperty(modes))
+			if err != nil {
+				return err
+			}
+			if err := s.addProperty(tx, registry.InstallModesType, string(value), bundle.Name, bundleVersion, bundle.BundleImage); err != nil {
+				return err
+			}
+		}
 	}
 
 	return nil
@@ -1001,61 +1103,278 @@ func getBundleNameAndVersionForImage(tx *sql.Tx, path string) (string, string, e
 }
 
 func (s *sqlLoader) DeprecateBundle(path string) error {
-	tx, err := s.db.Begin()
-	if err != nil {
-		return err
-	}
-	defer func() {
-		tx.Rollback()
-	}()
+	return s.transaction(func(tx *sql.Tx) error {
+		name, version, err := getBundleNameAndVersionForImage(tx, path)
+		if err != nil {
+			return err
+		}
+		tailBundles, err := getTailFromBundle(tx, name)
+		if err != nil {
+			return err
+		}
 
-	name, version, err := getBundleNameAndVersionForImage(tx, path)
-	if err != nil {
-		return err
-	}
-	tailBundles, err := getTailFromBundle(tx, name)
-	if err != nil {
+		for _, bundle := range tailBundles {
+			err := s.rmBundle(tx, bundle)
+			if err != nil {
+				return err
+			}
+			err = s.rmChannelEntry(tx, bundle)
+			if err != nil {
+				return err
+			}
+		}
+
+		deprecatedValue, err := json.Marshal(registry.DeprecatedProperty{})
+		if err != nil {
+			return err
+		}
+		err = s.addProperty(tx, registry.DeprecatedType, string(deprecatedValue), name, version, path)
+		if err != nil {
+			return err
+		}
+
+		return nil
+	})
+}
+
+// AddBundleAttestation records verification evidence for the bundle loaded from the given image
+func (s *sqlLoader) AddBundleAttestation(path string, attestation registry.Attestation) error {
+	return s.transaction(func(tx *sql.Tx) error {
+		name, version, err := getBundleNameAndVersionForImage(tx, path)
+		if err != nil {
+			return err
+		}
+
+		insert, err := tx.Prepare(`INSERT INTO bundle_attestation(certificate_identity, certificate_issuer, rekor_log_index, operatorbundle_name, operatorbundle_version, operatorbundle_path) VALUES (?, ?, ?, ?, ?, ?)`)
+		if err != nil {
+			return err
+		}
+		defer insert.Close()
+
+		if _, err := insert.Exec(attestation.CertificateIdentity, attestation.CertificateIssuer, attestation.RekorLogIndex, name, version, path); err != nil {
+			return err
+		}
+
+		return nil
+	})
+}
+
+// AddBundleImageDigest records the digest of the manifest of the image the bundle at path was pulled from
+func (s *sqlLoader) AddBundleImageDigest(path, digest string) error {
+	return s.transaction(func(tx *sql.Tx) error {
+		name, version, err := getBundleNameAndVersionForImage(tx, path)
+		if err != nil {
+			return err
+		}
+
+		if _, err := tx.Exec(`DELETE FROM bundle_image WHERE operatorbundle_name = ? AND operatorbundle_path = ?`, name, path); err != nil {
+			return err
+		}
+		_, err = tx.Exec(`INSERT INTO bundle_image(digest, operatorbundle_name, operatorbundle_version, operatorbundle_path) VALUES (?, ?, ?, ?)`, digest, name, version, path)
 		return err
-	}
+	})
+}
 
-	for _, bundle := range tailBundles {
-		err := s.rmBundle(tx, bundle)
+// AddChannelOverride records that the bundle loaded from the given image was added with the channels of the override
+// rather than those its annotations declared
+func (s *sqlLoader) AddChannelOverride(path string, annotated, override registry.ChannelOverride) error {
+	return s.transaction(func(tx *sql.Tx) error {
+		name, version, err := getBundleNameAndVersionForImage(tx, path)
 		if err != nil {
 			return err
 		}
-		err = s.rmChannelEntry(tx, bundle)
+
+		insert, err := tx.Prepare(`INSERT INTO channel_override(annotated_channels, annotated_default_channel, channels, default_channel, operatorbundle_name, operatorbundle_version, operatorbundle_path) VALUES (?, ?, ?, ?, ?, ?, ?)`)
 		if err != nil {
 			return err
 		}
-	}
+		defer insert.Close()
+
+		if _, err := insert.Exec(strings.Join(annotated.Channels, ","), annotated.DefaultChannel, strings.Join(override.Channels, ","), override.DefaultChannel, name, version, path); err != nil {
+			return err
+		}
+
+		return nil
+	})
+}
 
-	deprecatedValue, err := json.Marshal(registry.DeprecatedProperty{})
+func (s *sqlLoader) RemoveStrandedBundles() ([]string, error) {
+	var bundles []string
+	err := s.transaction(func(tx *sql.Tx) error {
+		var err error
+		bundles, err = s.rmStrandedBundles(tx)
+		return err
+	})
 	if err != nil {
+		return nil, err
+	}
+	return bundles, nil
+}
+
+// RemoveNonHeadBundles removes every bundle that isn't the head of one of its channels, along with the upgrade graph
+// that led to the heads, and returns the names of the bundles it removed
+func (s *sqlLoader) RemoveNonHeadBundles() ([]string, error) {
+	var bundles []string
+	err := s.transaction(func(tx *sql.Tx) error {
+		var err error
+		bundles, err = s.rmNonHeadBundles(tx)
 		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return bundles, nil
+}
+
+func (s *sqlLoader) rmNonHeadBundles(tx *sql.Tx) ([]string, error) {
+	// nothing is left for the heads to replace or skip, and each head keeps a single entry in the channel it heads
+	for _, stmt := range []string{
+		`UPDATE channel_entry SET replaces = NULL`,
+		`DELETE FROM channel_entry WHERE NOT EXISTS (
+			SELECT 1 FROM channel
+			WHERE channel.name = channel_entry.channel_name
+			AND channel.package_name = channel_entry.package_name
+			AND channel.head_operatorbundle_name = channel_entry.operatorbundle_name)`,
+		`DELETE FROM channel_entry WHERE entry_id NOT IN (
+			SELECT MIN(entry_id) FROM channel_entry GROUP BY package_name, channel_name, operatorbundle_name)`,
+	} {
+		if _, err := tx.Exec(stmt); err != nil {
+			return nil, err
+		}
 	}
-	err = s.addProperty(tx, registry.DeprecatedType, string(deprecatedValue), name, version, path)
+
+	// the bundles that are left without a channel entry are the ones to remove, including those that were already
+	// stranded
+	rows, err := tx.Query(`SELECT name FROM operatorbundle WHERE name NOT IN (SELECT operatorbundle_name FROM channel_entry) ORDER BY name`)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	var bundles []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		bundles = append(bundles, name)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
 	}
 
-	return tx.Commit()
+	for _, name := range bundles {
+		if err := s.rmBundle(tx, name); err != nil {
+			return nil, err
+		}
+	}
+	return bundles, nil
 }
 
-func (s *sqlLoader) RemoveStrandedBundles() ([]string, error) {
-	tx, err := s.db.Begin()
+// RemoveBundlesWithoutInstallMode removes every bundle that doesn't support an install mode, along with the bundles
+// that could only be upgraded through one, and returns the names of the bundles it removed. Channels whose head doesn't
+// support the mode are removed, and packages whose default channel head doesn't are removed entirely. Bundles that
+// don't record the install modes they support are kept.
+func (s *sqlLoader) RemoveBundlesWithoutInstallMode(mode string) ([]string, error) {
+	var bundles []string
+	err := s.transaction(func(tx *sql.Tx) error {
+		var err error
+		bundles, err = s.rmBundlesWithoutInstallMode(tx, mode)
+		return err
+	})
 	if err != nil {
 		return nil, err
 	}
-	defer func() {
-		tx.Rollback()
-	}()
+	return bundles, nil
+}
+
+func (s *sqlLoader) rmBundlesWithoutInstallMode(tx *sql.Tx, mode string) ([]string, error) {
+	unsupported := `SELECT operatorbundle_name FROM properties WHERE type = ?
+		AND NOT EXISTS (SELECT 1 FROM json_each(properties.value, '$.supported') WHERE json_each.value = ?)`
+	args := []interface{}{registry.InstallModesType, mode}
 
-	bundles, err := s.rmStrandedBundles(tx)
+	queryNames := func(query string, args ...interface{}) ([]string, error) {
+		rows, err := tx.Query(query, args...)
+		if err != nil {
+			return nil, err
+		}
+		defer rows.Close()
+		var names []string
+		for rows.Next() {
+			var name string
+			if err := rows.Scan(&name); err != nil {
+				return nil, err
+			}
+			names = append(names, name)
+		}
+		return names, rows.Err()
+	}
+
+	// a package can't be left without its default channel, so it is removed entirely
+	packages, err := queryNames(`SELECT package.name FROM package
+		INNER JOIN channel ON channel.package_name = package.name AND channel.name = package.default_channel
+		WHERE channel.head_operatorbundle_name IN (`+unsupported+`)`, args...)
 	if err != nil {
 		return nil, err
 	}
+	removed := map[string]bool{}
+	for _, pkg := range packages {
+		names, err := s.getCSVNames(tx, pkg)
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range names {
+			if err := s.rmBundle(tx, name); err != nil {
+				return nil, err
+			}
+			removed[name] = true
+		}
+	}
+
+	// an installed bundle can only be upgraded to a head through the bundles between them, so each channel keeps the
+	// entries closer to its head than the closest one that doesn't support the mode, if any
+	truncated, err := queryNames(`SELECT DISTINCT operatorbundle_name FROM channel_entry AS e WHERE depth >= (
+		SELECT MIN(depth) FROM channel_entry
+		WHERE channel_name = e.channel_name AND package_name = e.package_name AND operatorbundle_name IN (`+unsupported+`))`, args...)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := tx.Exec(`DELETE FROM channel_entry WHERE entry_id IN (SELECT entry_id FROM channel_entry AS e WHERE depth >= (
+		SELECT MIN(depth) FROM channel_entry
+		WHERE channel_name = e.channel_name AND package_name = e.package_name AND operatorbundle_name IN (`+unsupported+`)))`, args...); err != nil {
+		return nil, err
+	}
+	for _, stmt := range []string{
+		`UPDATE channel_entry SET replaces = NULL WHERE replaces NOT IN (SELECT entry_id FROM channel_entry)`,
+		`DELETE FROM channel WHERE NOT EXISTS (
+			SELECT 1 FROM channel_entry
+			WHERE channel_entry.channel_name = channel.name AND channel_entry.package_name = channel.package_name)`,
+	} {
+		if _, err := tx.Exec(stmt); err != nil {
+			return nil, err
+		}
+	}
+
+	// the truncated bundles that are left without a channel entry are removed
+	for _, name := range truncated {
+		var entries int
+		if err := tx.QueryRow(`SELECT COUNT(*) FROM channel_entry WHERE operatorbundle_name = ?`, name).Scan(&entries); err != nil {
+			return nil, err
+		}
+		if entries > 0 {
+			continue
+		}
+		if err := s.rmBundle(tx, name); err != nil {
+			return nil, err
+		}
+		removed[name] = true
+	}
 
-	return bundles, tx.Commit()
+	var names []string
+	for name := range removed {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
 }
 
 func (s *sqlLoader) rmStrandedBundles(tx *sql.Tx) ([]string, error) {
@@ -212,6 +212,232 @@ func TestClearNonHeadBundles(t *testing.T) {
 	require.NotEmpty(t, bundle.CsvJson)
 }
 
+func TestAddBundleAttestation(t *testing.T) {
+	db, cleanup := CreateTestDb(t)
+	defer cleanup()
+	store, err := NewSQLLiteLoader(db)
+	require.NoError(t, err)
+	err = store.Migrate(context.TODO())
+	require.NoError(t, err)
+
+	signed := newBundle(t, "signed", "pkg", []string{"stable"}, newUnstructuredCSV(t, "signed", ""))
+	signed.BundleImage = "this.is/signed@sha256:path"
+	require.NoError(t, store.AddOperatorBundle(signed))
+
+	attestation := registry.Attestation{
+		CertificateIdentity: "releng@example.com",
+		CertificateIssuer:   "https://accounts.example.com",
+		RekorLogIndex:       1234,
+	}
+	require.NoError(t, store.AddBundleAttestation(signed.BundleImage, attestation))
+
+	// Bundles that were never loaded can't be attested
+	require.Equal(t, registry.ErrBundleImageNotInDatabase, store.AddBundleAttestation("this.is/missing@sha256:path", attestation))
+
+	querier := NewSQLLiteQuerierFromDb(db)
+	attestations, err := querier.GetBundleAttestations(context.Background(), signed.Name)
+	require.NoError(t, err)
+	require.Equal(t, []*registry.Attestation{&attestation}, attestations)
+
+	attestations, err = querier.GetBundleAttestations(context.Background(), "unsigned")
+	require.NoError(t, err)
+	require.Empty(t, attestations)
+}
+
+func TestAddBundleImageDigest(t *testing.T) {
+	db, cleanup := CreateTestDb(t)
+	defer cleanup()
+	store, err := NewSQLLiteLoader(db)
+	require.NoError(t, err)
+	require.NoError(t, store.Migrate(context.TODO()))
+
+	tagged := newBundle(t, "tagged", "pkg", []string{"stable"}, newUnstructuredCSV(t, "tagged", ""))
+	tagged.BundleImage = "registry.example.com:5000/tagged:v1"
+	require.NoError(t, store.AddOperatorBundle(tagged))
+	pinned := newBundle(t, "pinned", "pkg", []string{"stable"}, newUnstructuredCSV(t, "pinned", ""))
+	pinned.BundleImage = "this.is/pinned@sha256:pinned"
+	require.NoError(t, store.AddOperatorBundle(pinned))
+	unrecorded := newBundle(t, "unrecorded", "pkg", []string{"stable"}, newUnstructuredCSV(t, "unrecorded", ""))
+	unrecorded.BundleImage = "this.is/unrecorded:v1"
+	require.NoError(t, store.AddOperatorBundle(unrecorded))
+	require.NoError(t, store.AddOperatorBundle(newBundle(t, "local", "pkg", []string{"stable"}, newUnstructuredCSV(t, "local", ""))))
+
+	require.NoError(t, store.AddBundleImageDigest(tagged.BundleImage, "sha256:old"))
+	// a later pull replaces the digest
+	require.NoError(t, store.AddBundleImageDigest(tagged.BundleImage, "sha256:tagged"))
+	require.Equal(t, registry.ErrBundleImageNotInDatabase, store.AddBundleImageDigest("this.is/missing:v1", "sha256:missing"))
+
+	querier := NewSQLLiteQuerierFromDb(db)
+	image, err := querier.GetBundleImage(context.TODO(), "tagged")
+	require.NoError(t, err)
+	require.Equal(t, &registry.BundleImage{Image: "registry.example.com:5000/tagged:v1", Digest: "sha256:tagged"}, image)
+	require.Equal(t, "registry.example.com:5000/tagged@sha256:tagged", image.Pinned())
+
+	// images pulled by digest carry their own
+	image, err = querier.GetBundleImage(context.TODO(), "pinned")
+	require.NoError(t, err)
+	require.Equal(t, "sha256:pinned", image.Digest)
+	require.Equal(t, pinned.BundleImage, image.Pinned())
+
+	image, err = querier.GetBundleImage(context.TODO(), "unrecorded")
+	require.NoError(t, err)
+	require.Empty(t, image.Digest)
+	require.Equal(t, unrecorded.BundleImage, image.Pinned())
+
+	_, err = querier.GetBundleImage(context.TODO(), "local")
+	require.Error(t, err)
+	_, err = querier.GetBundleImage(context.TODO(), "missing")
+	require.Error(t, err)
+}
+
+func TestAddLocalizedDisplayMetadata(t *testing.T) {
+	db, cleanup := CreateTestDb(t)
+	defer cleanup()
+	store, err := NewSQLLiteLoader(db)
+	require.NoError(t, err)
+	err = store.Migrate(context.TODO())
+	require.NoError(t, err)
+
+	csv := &registry.ClusterServiceVersion{}
+	csv.TypeMeta.Kind = "ClusterServiceVersion"
+	csv.SetName("translated")
+	csv.SetAnnotations(map[string]string{
+		registry.PropertyKey: `[{"type":"olm.localized","value":{"locale":"de","displayName":"Übersetzt","description":"Eine Beschreibung"}},` +
+			`{"type":"olm.localized","value":{"locale":"de-CH","displayName":"Übersetzt (CH)"}},` +
+			`{"type":"olm.localized","value":{"displayName":"missing locale"}}]`,
+	})
+	csv.Spec = json.RawMessage(`{"displayName": "Translated", "description": "A description"}`)
+	out, err := runtime.DefaultUnstructuredConverter.ToUnstructured(csv)
+	require.NoError(t, err)
+
+	translated := newBundle(t, "translated", "pkg", []string{"stable"}, &unstructured.Unstructured{Object: out})
+	require.NoError(t, store.AddOperatorBundle(translated))
+
+	querier := NewSQLLiteQuerierFromDb(db)
+	metadata, err := querier.GetDisplayMetadataForBundle(context.Background(), "translated", "de_CH")
+	require.NoError(t, err)
+	require.Equal(t, &registry.DisplayMetadata{Locale: "de-CH", DisplayName: "Übersetzt (CH)", Description: "Eine Beschreibung"}, metadata)
+
+	metadata, err = querier.GetDisplayMetadataForBundle(context.Background(), "translated", "fr")
+	require.NoError(t, err)
+	require.Equal(t, &registry.DisplayMetadata{DisplayName: "Translated", Description: "A description"}, metadata)
+
+	_, err = querier.GetDisplayMetadataForBundle(context.Background(), "missing", "de")
+	require.Error(t, err)
+}
+
+func TestGetChannelEntriesForPlatformVersion(t *testing.T) {
+	db, cleanup := CreateTestDb(t)
+	defer cleanup()
+	store, err := NewSQLLiteLoader(db)
+	require.NoError(t, err)
+	err = store.Migrate(context.TODO())
+	require.NoError(t, err)
+
+	// a supports every version, b declares a range as a property and c declares the openshift versions it supports
+	pkg, channel := "pkg", "stable"
+	channels := []string{channel}
+	a := newBundle(t, "a", pkg, channels, newUnstructuredCSV(t, "a", ""))
+	bCSV := newUnstructuredCSV(t, "b", "a")
+	bCSV.SetAnnotations(map[string]string{registry.PropertyKey: `[{"type":"olm.platformVersions","value":{"range":">=4.6.0"}}]`})
+	b := newBundle(t, "b", pkg, channels, bCSV)
+	cCSV := newUnstructuredCSV(t, "c", "b")
+	cCSV.SetAnnotations(map[string]string{registry.OpenShiftVersionsAnnotation: "=v4.8"})
+	c := newBundle(t, "c", pkg, channels, cCSV)
+
+	for _, bundle := range []*registry.Bundle{a, b, c} {
+		require.NoError(t, store.AddOperatorBundle(bundle))
+	}
+	err = store.AddPackageChannels(registry.PackageManifest{
+		PackageName:        pkg,
+		Channels:           []registry.PackageChannel{{Name: channel, CurrentCSVName: c.Name}},
+		DefaultChannelName: channel,
+	})
+	require.NoError(t, err)
+
+	querier := NewSQLLiteQuerierFromDb(db)
+	for version, expected := range map[string]struct {
+		bundles []string
+		head    string
+	}{
+		"4.5":    {bundles: []string{"a"}, head: "a"},
+		"v4.6":   {bundles: []string{"a", "b"}, head: "b"},
+		"4.8.12": {bundles: []string{"a", "b", "c"}, head: "c"},
+		"4.9":    {bundles: []string{"a", "b"}, head: "b"},
+	} {
+		entries, err := querier.GetChannelEntriesForPlatformVersion(context.Background(), pkg, version)
+		require.NoError(t, err)
+		var bundles []string
+		for _, entry := range entries {
+			bundles = append(bundles, entry.BundleName)
+		}
+		require.ElementsMatch(t, expected.bundles, bundles, "bundles supported on %s", version)
+		require.Equal(t, map[string]string{channel: expected.head}, registry.ChannelHeads(entries), "channel heads on %s", version)
+	}
+
+	_, err = querier.GetChannelEntriesForPlatformVersion(context.Background(), pkg, "latest")
+	require.Error(t, err)
+}
+
+func TestGetEntriesByBundleImage(t *testing.T) {
+	db, cleanup := CreateTestDb(t)
+	defer cleanup()
+	store, err := NewSQLLiteLoader(db)
+	require.NoError(t, err)
+	err = store.Migrate(context.TODO())
+	require.NoError(t, err)
+
+	const (
+		digestA = "sha256:6e1b8c25a5e8bfd2a8c30d14a93a8b5c04b5ec4d3ed6b1c6d1b70c0f4f3a4f21"
+		digestB = "sha256:0d0e1e8e3f2c0a6a8bd1b0d9a7a3fd3b8f0f39b4f54d0c5d5f0e1b3d2a3c7e9b"
+	)
+	pkg := "pkg"
+	channels := []string{"stable", "fast"}
+	a := newBundle(t, "a", pkg, channels, newUnstructuredCSV(t, "a", ""))
+	a.BundleImage = "quay.io/test/pkg-bundle@" + digestA
+	b := newBundle(t, "b", pkg, channels, newUnstructuredCSV(t, "b", "a"))
+	b.BundleImage = "quay.io/test/pkg-bundle:v2"
+	for _, bundle := range []*registry.Bundle{a, b} {
+		require.NoError(t, store.AddOperatorBundle(bundle))
+	}
+	err = store.AddPackageChannels(registry.PackageManifest{
+		PackageName:        pkg,
+		Channels:           []registry.PackageChannel{{Name: "stable", CurrentCSVName: "b"}, {Name: "fast", CurrentCSVName: "b"}},
+		DefaultChannelName: "stable",
+	})
+	require.NoError(t, err)
+
+	querier := NewSQLLiteQuerierFromDb(db)
+	for ref, expected := range map[string][]string{
+		// by reference, by digest alone and by the same digest pulled from a mirror
+		"quay.io/test/pkg-bundle@" + digestA: {"fast/a", "stable/a"},
+		digestA:                              {"fast/a", "stable/a"},
+		"mirror.example.com/pkg-bundle@" + digestA: {"fast/a", "stable/a"},
+		"quay.io/test/pkg-bundle:v2":               {"fast/b", "stable/b"},
+		digestB:                                    nil,
+		"quay.io/test/pkg-bundle:v1":               nil,
+	} {
+		entries, err := querier.GetEntriesByBundleImage(context.Background(), ref)
+		require.NoError(t, err)
+		var found []string
+		for _, entry := range entries {
+			require.Equal(t, pkg, entry.PackageName)
+			found = append(found, entry.ChannelName+"/"+entry.BundleName)
+		}
+		require.Equal(t, expected, found, ref)
+	}
+
+	_, err = querier.GetEntriesByBundleImage(context.Background(), "")
+	require.Error(t, err)
+
+	// bundles can be checked for in bulk by any of the same names, or by CSV name
+	present, err := querier.GetPresentBundles(context.Background(), []string{
+		"b", "c", digestA, digestB, "mirror.example.com/pkg-bundle@" + digestA, "quay.io/test/pkg-bundle:v1", "quay.io/test/pkg-bundle:v2", "",
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{"b", digestA, "mirror.example.com/pkg-bundle@" + digestA, "quay.io/test/pkg-bundle:v2"}, present)
+}
+
 func newUnstructuredCSV(t *testing.T, name, replaces string) *unstructured.Unstructured {
 	csv := &registry.ClusterServiceVersion{}
 	csv.TypeMeta.Kind = "ClusterServiceVersion"
@@ -2,6 +2,9 @@ package sqlite
 
 import (
 	"database/sql"
+
+	"github.com/operator-framework/operator-registry/pkg/registry"
+	"github.com/operator-framework/operator-registry/pkg/sqlite/migrations"
 )
 
 // TODO: Finish separating procedures from loader layer: make this a type to make
@@ -52,7 +55,7 @@ func addPackage(tx *sql.Tx, packageName string) error {
 		return err
 	}
 
-	return nil
+	return addPackageID(tx, packageName)
 }
 
 func addPackageIfNotExists(tx *sql.Tx, packageName string) error {
@@ -67,7 +70,7 @@ func addPackageIfNotExists(tx *sql.Tx, packageName string) error {
 		return err
 	}
 
-	return nil
+	return addPackageID(tx, packageName)
 }
 
 func addChannel(tx *sql.Tx, channelName, packageName, headCsvName string) error {
@@ -144,3 +147,15 @@ func truncChannelGraph(tx *sql.Tx, channelName, packageName string) error {
 
 	return nil
 }
+
+// addPackageID gives a package the id derived from its name, unless it already has one
+func addPackageID(tx *sql.Tx, packageName string) error {
+	_, err := tx.Exec("insert or ignore into stable_id(kind, name, id) values(?, ?, ?)", migrations.StableIDKindPackage, packageName, registry.PackageID(packageName))
+	return err
+}
+
+// addBundleID gives a bundle the id derived from its name, unless it already has one
+func addBundleID(tx *sql.Tx, csvName string) error {
+	_, err := tx.Exec("insert or ignore into stable_id(kind, name, id) values(?, ?, ?)", migrations.StableIDKindBundle, csvName, registry.BundleID(csvName))
+	return err
+}
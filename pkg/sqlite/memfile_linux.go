@@ -0,0 +1,23 @@
+package sqlite
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// sharedMemoryDir is a tmpfs, whose files are held in memory and never written to disk
+const sharedMemoryDir = "/dev/shm"
+
+// memoryFile creates a file that is held in memory and never written to disk, which only the current user can read.
+// SQLite can't open an anonymous memfd, since it resolves the symlinks in /proc/self/fd, so the file is created in
+// a tmpfs and has to be removed once it is no longer needed.
+func memoryFile(name string) (*os.File, error) {
+	var fs unix.Statfs_t
+	if err := unix.Statfs(sharedMemoryDir, &fs); err != nil || fs.Type != unix.TMPFS_MAGIC {
+		return nil, fmt.Errorf("%s is not an in-memory filesystem", sharedMemoryDir)
+	}
+	return ioutil.TempFile(sharedMemoryDir, name+"-")
+}
@@ -0,0 +1,15 @@
+//go:build !linux
+// +build !linux
+
+package sqlite
+
+import (
+	"fmt"
+	"os"
+)
+
+// memoryFile creates a file that is held in memory and never written to disk. Encrypted databases are only decrypted
+// on linux, whose /dev/shm holds them in memory.
+func memoryFile(name string) (*os.File, error) {
+	return nil, fmt.Errorf("encrypted databases can only be decrypted on linux")
+}
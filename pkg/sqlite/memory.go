@@ -0,0 +1,96 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"sync/atomic"
+)
+
+// memoryDatabases numbers the in-memory databases of the process, which are shared by name between connections
+var memoryDatabases uint64
+
+// MemoryDatabase is a database held entirely in memory, for catalogs that are built to be validated or served and
+// then thrown away, or that are only written to disk once they are complete.
+//
+// Every connection of the pool opens the same shared in-memory database, which SQLite drops as soon as its last
+// connection is closed, so one connection is held open until the MemoryDatabase is closed.
+type MemoryDatabase struct {
+	db   *sql.DB
+	conn *sql.Conn
+}
+
+// OpenMemoryDatabase opens a new, empty in-memory database
+func OpenMemoryDatabase(ctx context.Context) (*MemoryDatabase, error) {
+	name := fmt.Sprintf("file:opm-%d-%d?mode=memory&cache=shared", os.Getpid(), atomic.AddUint64(&memoryDatabases, 1))
+	db, err := sql.Open("sqlite3", name)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &MemoryDatabase{db: db, conn: conn}, nil
+}
+
+// LoadMemoryDatabase opens an in-memory database with a copy of the database at path
+func LoadMemoryDatabase(ctx context.Context, path string) (*MemoryDatabase, error) {
+	if _, err := os.Stat(path); err != nil {
+		return nil, err
+	}
+	m, err := loadMemoryDatabase(ctx, path)
+	if err != nil {
+		return nil, fmt.Errorf("error loading %s into memory: %s", path, err)
+	}
+	return m, nil
+}
+
+// loadMemoryDatabase opens an in-memory database with a copy of the database at dsn
+func loadMemoryDatabase(ctx context.Context, dsn string) (*MemoryDatabase, error) {
+	m, err := OpenMemoryDatabase(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	src, err := sql.Open("sqlite3", dsn)
+	if err != nil {
+		m.Close()
+		return nil, err
+	}
+	defer src.Close()
+
+	conn, err := src.Conn(ctx)
+	if err != nil {
+		m.Close()
+		return nil, err
+	}
+	defer conn.Close()
+
+	if err := backup(ctx, m.conn, conn); err != nil {
+		m.Close()
+		return nil, err
+	}
+	return m, nil
+}
+
+// DB returns the pool of connections to the database, to build or query it with
+func (m *MemoryDatabase) DB() *sql.DB {
+	return m.db
+}
+
+// WriteTo writes the database to a file at path, replacing any database already there
+func (m *MemoryDatabase) WriteTo(ctx context.Context, path string) error {
+	return writeTo(ctx, m.conn, path)
+}
+
+// Close drops the database
+func (m *MemoryDatabase) Close() error {
+	err := m.conn.Close()
+	if cerr := m.db.Close(); cerr != nil && err == nil {
+		err = cerr
+	}
+	return err
+}
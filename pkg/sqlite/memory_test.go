@@ -0,0 +1,59 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryDatabase(t *testing.T) {
+	ctx := context.TODO()
+
+	m, err := OpenMemoryDatabase(ctx)
+	require.NoError(t, err)
+	defer m.Close()
+
+	store, err := NewSQLLiteLoader(m.DB())
+	require.NoError(t, err)
+	require.NoError(t, store.Migrate(ctx))
+	require.NoError(t, NewSQLLoaderForDirectory(store, "./testdata/loader_data").Populate())
+
+	// served straight from memory
+	bundles, err := NewSQLLiteQuerierFromDb(m.DB()).ListBundles(ctx)
+	require.NoError(t, err)
+	require.NotEmpty(t, bundles)
+
+	// a second in-memory database doesn't share the first one's tables
+	other, err := OpenMemoryDatabase(ctx)
+	require.NoError(t, err)
+	defer other.Close()
+	tables, err := NewSQLLiteQuerierFromDb(other.DB()).ListTables(ctx)
+	require.NoError(t, err)
+	require.Empty(t, tables)
+
+	// written to disk
+	path := fmt.Sprintf("test-%d.db", rand.Int())
+	defer os.Remove(path)
+	require.NoError(t, m.WriteTo(ctx, path))
+
+	querier, err := NewSQLLiteQuerier(path)
+	require.NoError(t, err)
+	written, err := querier.ListBundles(ctx)
+	require.NoError(t, err)
+	require.ElementsMatch(t, bundles, written)
+
+	// and loaded back into memory
+	loaded, err := LoadMemoryDatabase(ctx, path)
+	require.NoError(t, err)
+	defer loaded.Close()
+	reloaded, err := NewSQLLiteQuerierFromDb(loaded.DB()).ListBundles(ctx)
+	require.NoError(t, err)
+	require.ElementsMatch(t, bundles, reloaded)
+
+	_, err = LoadMemoryDatabase(ctx, "does-not-exist.db")
+	require.Error(t, err)
+}
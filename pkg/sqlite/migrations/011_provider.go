@@ -0,0 +1,111 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+
+	"github.com/sirupsen/logrus"
+)
+
+const ProviderMigrationKey = 11
+
+// Register this migration
+func init() {
+	registerMigration(ProviderMigrationKey, providerMigration)
+}
+
+// This migration adds provider, maintainers and links fields to the operatorbundle table
+// so that ownership metadata from the CSV can be queried without decoding the CSV itself
+var providerMigration = &Migration{
+	Id: ProviderMigrationKey,
+	Up: func(ctx context.Context, tx *sql.Tx) error {
+		sql := `
+		ALTER TABLE operatorbundle
+		ADD COLUMN provider TEXT;
+
+		ALTER TABLE operatorbundle
+		ADD COLUMN maintainers TEXT;
+
+		ALTER TABLE operatorbundle
+		ADD COLUMN links TEXT;
+		`
+		_, err := tx.ExecContext(ctx, sql)
+		if err != nil {
+			return err
+		}
+
+		bundles, err := listBundles(ctx, tx)
+		if err != nil {
+			return err
+		}
+		for _, bundle := range bundles {
+			if err := extractProvider(ctx, tx, bundle); err != nil {
+				logrus.Warnf("error backfilling provider: %v", err)
+				continue
+			}
+		}
+		return nil
+	},
+	Down: func(ctx context.Context, tx *sql.Tx) error {
+		foreignKeyOff := `PRAGMA foreign_keys = 0`
+		createTempTable := `CREATE TABLE operatorbundle_backup (name TEXT, csv TEXT, bundle TEXT, bundlepath TEXT, version TEXT, skiprange TEXT, replaces TEXT, skips TEXT)`
+		backupTargetTable := `INSERT INTO operatorbundle_backup SELECT name, csv, bundle, bundlepath, version, skiprange, replaces, skips FROM operatorbundle`
+		dropTargetTable := `DROP TABLE operatorbundle`
+		renameBackUpTable := `ALTER TABLE operatorbundle_backup RENAME TO operatorbundle;`
+		foreignKeyOn := `PRAGMA foreign_keys = 1`
+		_, err := tx.ExecContext(ctx, foreignKeyOff)
+		if err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, createTempTable)
+		if err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, backupTargetTable)
+		if err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, dropTargetTable)
+		if err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, renameBackUpTable)
+		if err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, foreignKeyOn)
+		return err
+	},
+}
+
+func extractProvider(ctx context.Context, tx *sql.Tx, name string) error {
+	csv, err := getCSV(ctx, tx, name)
+	if err != nil {
+		return err
+	}
+	provider, err := csv.GetProvider()
+	if err != nil {
+		return err
+	}
+	maintainers, err := csv.GetMaintainers()
+	if err != nil {
+		return err
+	}
+	links, err := csv.GetLinks()
+	if err != nil {
+		return err
+	}
+	maintainersJson, err := json.Marshal(maintainers)
+	if err != nil {
+		return err
+	}
+	linksJson, err := json.Marshal(links)
+	if err != nil {
+		return err
+	}
+
+	updateSql := `update operatorbundle SET provider = ?, maintainers = ?, links = ? WHERE name = ?;`
+	_, err = tx.ExecContext(ctx, updateSql, provider, string(maintainersJson), string(linksJson), name)
+	return err
+}
@@ -0,0 +1,52 @@
+package migrations_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/operator-framework/operator-registry/pkg/sqlite/migrations"
+)
+
+func TestProviderUp(t *testing.T) {
+	db, migrator, cleanup := CreateTestDbAt(t, migrations.ProviderMigrationKey-1)
+	defer cleanup()
+
+	testCSV := `{"apiVersion":"operators.coreos.com/v1alpha1","kind":"ClusterServiceVersion","metadata":{"name":"etcdoperator.v0.6.1"},"spec":{"links":[{"name":"Blog","url":"https://coreos.com/etcd"}],"maintainers":[{"email":"support@coreos.com","name":"CoreOS, Inc"}],"provider":{"name":"CoreOS, Inc"},"version":"0.6.1"}}`
+	insert := "insert into operatorbundle(name, csv, bundle, bundlepath, version, skiprange, replaces, skips) values(?, ?, ?, ?, ?, ?, ?, ?)"
+	_, err := db.Exec(insert, "etcdoperator.v0.6.1", testCSV, testCSV, "quay.io/image", "0.6.1", "", "", "")
+	require.NoError(t, err)
+
+	err = migrator.Up(context.TODO(), migrations.Only(migrations.ProviderMigrationKey))
+	require.NoError(t, err)
+
+	var provider, maintainers, links sql.NullString
+	row := db.QueryRow(`SELECT provider, maintainers, links FROM operatorbundle WHERE name = ?`, "etcdoperator.v0.6.1")
+	require.NoError(t, row.Scan(&provider, &maintainers, &links))
+	require.Equal(t, "CoreOS, Inc", provider.String)
+	require.Equal(t, `[{"name":"CoreOS, Inc","email":"support@coreos.com"}]`, maintainers.String)
+	require.Equal(t, `[{"name":"Blog","url":"https://coreos.com/etcd"}]`, links.String)
+}
+
+func TestProviderDown(t *testing.T) {
+	db, migrator, cleanup := CreateTestDbAt(t, migrations.ProviderMigrationKey)
+	defer cleanup()
+
+	insert := "insert into operatorbundle(name, csv, bundle, bundlepath, version, skiprange, replaces, skips, provider) values(?, ?, ?, ?, ?, ?, ?, ?, ?)"
+	_, err := db.Exec(insert, "etcdoperator.v0.6.1", "testCSV", "testBundle", "quay.io/image", "0.6.1", "", "", "", "CoreOS, Inc")
+	require.NoError(t, err)
+
+	err = migrator.Down(context.TODO(), migrations.Only(migrations.ProviderMigrationKey))
+	require.NoError(t, err)
+
+	// the provider column should be gone after migrating down
+	_, err = db.Exec(`SELECT provider FROM operatorbundle`)
+	require.Error(t, err)
+
+	var name sql.NullString
+	row := db.QueryRow(`SELECT name FROM operatorbundle`)
+	require.NoError(t, row.Scan(&name))
+	require.Equal(t, "etcdoperator.v0.6.1", name.String)
+}
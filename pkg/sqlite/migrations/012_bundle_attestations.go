@@ -0,0 +1,38 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+)
+
+const BundleAttestationsMigrationKey = 12
+
+// Register this migration
+func init() {
+	registerMigration(BundleAttestationsMigrationKey, bundleAttestationsMigration)
+}
+
+// This migration adds a table recording the keyless verification evidence (signing certificate identity
+// and transparency log entry) captured for each bundle image when it was added with verification enabled
+var bundleAttestationsMigration = &Migration{
+	Id: BundleAttestationsMigrationKey,
+	Up: func(ctx context.Context, tx *sql.Tx) error {
+		sql := `
+		CREATE TABLE IF NOT EXISTS bundle_attestation (
+			certificate_identity TEXT,
+			certificate_issuer TEXT,
+			rekor_log_index INTEGER,
+			operatorbundle_name TEXT,
+			operatorbundle_version TEXT,
+			operatorbundle_path TEXT,
+			FOREIGN KEY(operatorbundle_name, operatorbundle_version, operatorbundle_path) REFERENCES operatorbundle(name, version, bundlepath) ON DELETE CASCADE
+		);
+		`
+		_, err := tx.ExecContext(ctx, sql)
+		return err
+	},
+	Down: func(ctx context.Context, tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, `DROP TABLE bundle_attestation`)
+		return err
+	},
+}
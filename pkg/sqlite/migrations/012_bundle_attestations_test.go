@@ -0,0 +1,41 @@
+package migrations_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/operator-framework/operator-registry/pkg/sqlite/migrations"
+)
+
+func TestBundleAttestationsUp(t *testing.T) {
+	db, migrator, cleanup := CreateTestDbAt(t, migrations.BundleAttestationsMigrationKey-1)
+	defer cleanup()
+
+	_, err := db.Exec("insert into operatorbundle(name, csv, bundle, bundlepath, version, skiprange, replaces, skips) values(?, ?, ?, ?, ?, ?, ?, ?)", "etcdoperator.v0.6.1", "testCSV", "testBundle", "quay.io/image", "0.6.1", "", "", "")
+	require.NoError(t, err)
+
+	err = migrator.Up(context.TODO(), migrations.Only(migrations.BundleAttestationsMigrationKey))
+	require.NoError(t, err)
+
+	insert := "insert into bundle_attestation(certificate_identity, certificate_issuer, rekor_log_index, operatorbundle_name, operatorbundle_version, operatorbundle_path) values(?, ?, ?, ?, ?, ?)"
+	_, err = db.Exec(insert, "releng@example.com", "https://accounts.example.com", 42, "etcdoperator.v0.6.1", "0.6.1", "quay.io/image")
+	require.NoError(t, err)
+
+	var count int
+	require.NoError(t, db.QueryRow(`SELECT COUNT(*) FROM bundle_attestation WHERE rekor_log_index = 42`).Scan(&count))
+	require.Equal(t, 1, count)
+}
+
+func TestBundleAttestationsDown(t *testing.T) {
+	db, migrator, cleanup := CreateTestDbAt(t, migrations.BundleAttestationsMigrationKey)
+	defer cleanup()
+
+	err := migrator.Down(context.TODO(), migrations.Only(migrations.BundleAttestationsMigrationKey))
+	require.NoError(t, err)
+
+	// the table should be gone after migrating down
+	_, err = db.Exec(`SELECT * FROM bundle_attestation`)
+	require.Error(t, err)
+}
@@ -0,0 +1,58 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+)
+
+const BundleDigestMigrationKey = 13
+
+// Register this migration
+func init() {
+	registerMigration(BundleDigestMigrationKey, bundleDigestMigration)
+}
+
+// This migration adds a digest of the bundle content to the operatorbundle table so that re-adding
+// identical content can be detected and treated as a no-op. Bundles loaded before this migration
+// have no digest and are not backfilled, since their manifests may already have been cleared.
+var bundleDigestMigration = &Migration{
+	Id: BundleDigestMigrationKey,
+	Up: func(ctx context.Context, tx *sql.Tx) error {
+		sql := `
+		ALTER TABLE operatorbundle
+		ADD COLUMN digest TEXT;
+		`
+		_, err := tx.ExecContext(ctx, sql)
+		return err
+	},
+	Down: func(ctx context.Context, tx *sql.Tx) error {
+		foreignKeyOff := `PRAGMA foreign_keys = 0`
+		createTempTable := `CREATE TABLE operatorbundle_backup (name TEXT, csv TEXT, bundle TEXT, bundlepath TEXT, version TEXT, skiprange TEXT, replaces TEXT, skips TEXT, provider TEXT, maintainers TEXT, links TEXT)`
+		backupTargetTable := `INSERT INTO operatorbundle_backup SELECT name, csv, bundle, bundlepath, version, skiprange, replaces, skips, provider, maintainers, links FROM operatorbundle`
+		dropTargetTable := `DROP TABLE operatorbundle`
+		renameBackUpTable := `ALTER TABLE operatorbundle_backup RENAME TO operatorbundle;`
+		foreignKeyOn := `PRAGMA foreign_keys = 1`
+		_, err := tx.ExecContext(ctx, foreignKeyOff)
+		if err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, createTempTable)
+		if err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, backupTargetTable)
+		if err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, dropTargetTable)
+		if err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, renameBackUpTable)
+		if err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, foreignKeyOn)
+		return err
+	},
+}
@@ -0,0 +1,47 @@
+package migrations_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/operator-framework/operator-registry/pkg/sqlite/migrations"
+)
+
+func TestBundleDigestUp(t *testing.T) {
+	db, migrator, cleanup := CreateTestDbAt(t, migrations.BundleDigestMigrationKey-1)
+	defer cleanup()
+
+	err := migrator.Up(context.TODO(), migrations.Only(migrations.BundleDigestMigrationKey))
+	require.NoError(t, err)
+
+	insert := "insert into operatorbundle(name, csv, bundle, bundlepath, version, skiprange, replaces, skips, digest) values(?, ?, ?, ?, ?, ?, ?, ?, ?)"
+	_, err = db.Exec(insert, "etcdoperator.v0.6.1", "testCSV", "testBundle", "quay.io/image", "0.6.1", "", "", "", "sha256:abc")
+	require.NoError(t, err)
+
+	var digest sql.NullString
+	require.NoError(t, db.QueryRow(`SELECT digest FROM operatorbundle WHERE name = ?`, "etcdoperator.v0.6.1").Scan(&digest))
+	require.Equal(t, "sha256:abc", digest.String)
+}
+
+func TestBundleDigestDown(t *testing.T) {
+	db, migrator, cleanup := CreateTestDbAt(t, migrations.BundleDigestMigrationKey)
+	defer cleanup()
+
+	insert := "insert into operatorbundle(name, csv, bundle, bundlepath, version, skiprange, replaces, skips, provider, digest) values(?, ?, ?, ?, ?, ?, ?, ?, ?, ?)"
+	_, err := db.Exec(insert, "etcdoperator.v0.6.1", "testCSV", "testBundle", "quay.io/image", "0.6.1", "", "", "", "CoreOS, Inc", "sha256:abc")
+	require.NoError(t, err)
+
+	err = migrator.Down(context.TODO(), migrations.Only(migrations.BundleDigestMigrationKey))
+	require.NoError(t, err)
+
+	// the digest column should be gone after migrating down
+	_, err = db.Exec(`SELECT digest FROM operatorbundle`)
+	require.Error(t, err)
+
+	var provider sql.NullString
+	require.NoError(t, db.QueryRow(`SELECT provider FROM operatorbundle WHERE name = ?`, "etcdoperator.v0.6.1").Scan(&provider))
+	require.Equal(t, "CoreOS, Inc", provider.String)
+}
@@ -0,0 +1,34 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+)
+
+const PackageTombstoneMigrationKey = 14
+
+// Register this migration
+func init() {
+	registerMigration(PackageTombstoneMigrationKey, packageTombstoneMigration)
+}
+
+// This migration adds a table holding a snapshot of each soft-deleted package, so that
+// removing a package can be undone until its tombstone is purged
+var packageTombstoneMigration = &Migration{
+	Id: PackageTombstoneMigrationKey,
+	Up: func(ctx context.Context, tx *sql.Tx) error {
+		sql := `
+		CREATE TABLE IF NOT EXISTS package_tombstone (
+			package_name TEXT PRIMARY KEY,
+			deleted_at INTEGER,
+			snapshot TEXT
+		);
+		`
+		_, err := tx.ExecContext(ctx, sql)
+		return err
+	},
+	Down: func(ctx context.Context, tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, `DROP TABLE package_tombstone`)
+		return err
+	},
+}
@@ -0,0 +1,33 @@
+package migrations_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/operator-framework/operator-registry/pkg/sqlite/migrations"
+)
+
+func TestPackageTombstoneUp(t *testing.T) {
+	db, migrator, cleanup := CreateTestDbAt(t, migrations.PackageTombstoneMigrationKey-1)
+	defer cleanup()
+
+	err := migrator.Up(context.TODO(), migrations.Only(migrations.PackageTombstoneMigrationKey))
+	require.NoError(t, err)
+
+	_, err = db.Exec("insert into package_tombstone(package_name, deleted_at, snapshot) values(?, ?, ?)", "etcd", 1600000000, "{}")
+	require.NoError(t, err)
+}
+
+func TestPackageTombstoneDown(t *testing.T) {
+	db, migrator, cleanup := CreateTestDbAt(t, migrations.PackageTombstoneMigrationKey)
+	defer cleanup()
+
+	err := migrator.Down(context.TODO(), migrations.Only(migrations.PackageTombstoneMigrationKey))
+	require.NoError(t, err)
+
+	// the table should be gone after migrating down
+	_, err = db.Exec(`SELECT * FROM package_tombstone`)
+	require.Error(t, err)
+}
@@ -0,0 +1,34 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+)
+
+const PackageAliasMigrationKey = 15
+
+// Register this migration
+func init() {
+	registerMigration(PackageAliasMigrationKey, packageAliasMigration)
+}
+
+// This migration adds a table mapping the former names of renamed packages to their current name,
+// so that lookups by an old name can still be resolved
+var packageAliasMigration = &Migration{
+	Id: PackageAliasMigrationKey,
+	Up: func(ctx context.Context, tx *sql.Tx) error {
+		sql := `
+		CREATE TABLE IF NOT EXISTS package_alias (
+			alias TEXT PRIMARY KEY,
+			package_name TEXT NOT NULL,
+			renamed_at INTEGER
+		);
+		`
+		_, err := tx.ExecContext(ctx, sql)
+		return err
+	},
+	Down: func(ctx context.Context, tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, `DROP TABLE package_alias`)
+		return err
+	},
+}
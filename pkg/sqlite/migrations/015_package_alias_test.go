@@ -0,0 +1,37 @@
+package migrations_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/operator-framework/operator-registry/pkg/sqlite/migrations"
+)
+
+func TestPackageAliasUp(t *testing.T) {
+	db, migrator, cleanup := CreateTestDbAt(t, migrations.PackageAliasMigrationKey-1)
+	defer cleanup()
+
+	err := migrator.Up(context.TODO(), migrations.Only(migrations.PackageAliasMigrationKey))
+	require.NoError(t, err)
+
+	_, err = db.Exec("insert into package_alias(alias, package_name, renamed_at) values(?, ?, ?)", "etcd", "etcd-operator", 1600000000)
+	require.NoError(t, err)
+
+	// an alias can only point at one package
+	_, err = db.Exec("insert into package_alias(alias, package_name, renamed_at) values(?, ?, ?)", "etcd", "other", 1600000000)
+	require.Error(t, err)
+}
+
+func TestPackageAliasDown(t *testing.T) {
+	db, migrator, cleanup := CreateTestDbAt(t, migrations.PackageAliasMigrationKey)
+	defer cleanup()
+
+	err := migrator.Down(context.TODO(), migrations.Only(migrations.PackageAliasMigrationKey))
+	require.NoError(t, err)
+
+	// the table should be gone after migrating down
+	_, err = db.Exec(`SELECT * FROM package_alias`)
+	require.Error(t, err)
+}
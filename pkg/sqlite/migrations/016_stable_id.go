@@ -0,0 +1,86 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/operator-framework/operator-registry/pkg/registry"
+)
+
+const StableIDMigrationKey = 16
+
+// Kinds of the entities that have a stable id
+const (
+	StableIDKindPackage = "package"
+	StableIDKindBundle  = "bundle"
+)
+
+// Register this migration
+func init() {
+	registerMigration(StableIDMigrationKey, stableIDMigration)
+}
+
+// This migration adds a table of stable ids for packages and bundles, so that clients can track them across renames
+// and re-publishes. Ids aren't removed with their package or bundle, and existing packages and bundles are given the
+// ids derived from their names.
+var stableIDMigration = &Migration{
+	Id: StableIDMigrationKey,
+	Up: func(ctx context.Context, tx *sql.Tx) error {
+		sql := `
+		CREATE TABLE IF NOT EXISTS stable_id (
+			kind TEXT NOT NULL,
+			name TEXT NOT NULL,
+			id TEXT NOT NULL,
+			PRIMARY KEY(kind, name)
+		);
+		`
+		if _, err := tx.ExecContext(ctx, sql); err != nil {
+			return err
+		}
+
+		packages, err := selectNames(ctx, tx, `SELECT name FROM package`)
+		if err != nil {
+			return err
+		}
+		for _, name := range packages {
+			if _, err := tx.ExecContext(ctx, `INSERT OR IGNORE INTO stable_id(kind, name, id) VALUES (?, ?, ?)`, StableIDKindPackage, name, registry.PackageID(name)); err != nil {
+				return err
+			}
+		}
+
+		bundles, err := selectNames(ctx, tx, `SELECT name FROM operatorbundle`)
+		if err != nil {
+			return err
+		}
+		for _, name := range bundles {
+			if _, err := tx.ExecContext(ctx, `INSERT OR IGNORE INTO stable_id(kind, name, id) VALUES (?, ?, ?)`, StableIDKindBundle, name, registry.BundleID(name)); err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+	Down: func(ctx context.Context, tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, `DROP TABLE stable_id`)
+		return err
+	},
+}
+
+func selectNames(ctx context.Context, tx *sql.Tx, query string) ([]string, error) {
+	rows, err := tx.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var names []string
+	for rows.Next() {
+		var name sql.NullString
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		if name.Valid {
+			names = append(names, name.String)
+		}
+	}
+	return names, rows.Err()
+}
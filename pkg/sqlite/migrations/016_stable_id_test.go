@@ -0,0 +1,49 @@
+package migrations_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/operator-framework/operator-registry/pkg/registry"
+	"github.com/operator-framework/operator-registry/pkg/sqlite/migrations"
+)
+
+func TestStableIDUp(t *testing.T) {
+	db, migrator, cleanup := CreateTestDbAt(t, migrations.StableIDMigrationKey-1)
+	defer cleanup()
+
+	_, err := db.Exec(`PRAGMA foreign_keys = 0`)
+	require.NoError(t, err)
+	_, err = db.Exec("insert into package(name, default_channel) values(?, ?)", "etcd", "alpha")
+	require.NoError(t, err)
+	_, err = db.Exec("insert into operatorbundle(name, csv, bundle) values(?, ?, ?)", "etcdoperator.v0.6.1", "testCSV", "testBundle")
+	require.NoError(t, err)
+
+	err = migrator.Up(context.TODO(), migrations.Only(migrations.StableIDMigrationKey))
+	require.NoError(t, err)
+
+	// existing packages and bundles get the ids derived from their names
+	var id string
+	require.NoError(t, db.QueryRow(`SELECT id FROM stable_id WHERE kind = ? AND name = ?`, migrations.StableIDKindPackage, "etcd").Scan(&id))
+	require.Equal(t, registry.PackageID("etcd"), id)
+	require.NoError(t, db.QueryRow(`SELECT id FROM stable_id WHERE kind = ? AND name = ?`, migrations.StableIDKindBundle, "etcdoperator.v0.6.1").Scan(&id))
+	require.Equal(t, registry.BundleID("etcdoperator.v0.6.1"), id)
+
+	// a name has a single id for each kind
+	_, err = db.Exec("insert into stable_id(kind, name, id) values(?, ?, ?)", migrations.StableIDKindPackage, "etcd", "other")
+	require.Error(t, err)
+}
+
+func TestStableIDDown(t *testing.T) {
+	db, migrator, cleanup := CreateTestDbAt(t, migrations.StableIDMigrationKey)
+	defer cleanup()
+
+	err := migrator.Down(context.TODO(), migrations.Only(migrations.StableIDMigrationKey))
+	require.NoError(t, err)
+
+	// the table should be gone after migrating down
+	_, err = db.Exec(`SELECT * FROM stable_id`)
+	require.Error(t, err)
+}
@@ -0,0 +1,39 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+)
+
+const ChannelOverrideMigrationKey = 17
+
+// Register this migration
+func init() {
+	registerMigration(ChannelOverrideMigrationKey, channelOverrideMigration)
+}
+
+// This migration adds a table recording, for each bundle image added with its channels given on the command line
+// rather than taken from its annotations, the channels its annotations declared and those it was added with
+var channelOverrideMigration = &Migration{
+	Id: ChannelOverrideMigrationKey,
+	Up: func(ctx context.Context, tx *sql.Tx) error {
+		sql := `
+		CREATE TABLE IF NOT EXISTS channel_override (
+			annotated_channels TEXT,
+			annotated_default_channel TEXT,
+			channels TEXT,
+			default_channel TEXT,
+			operatorbundle_name TEXT,
+			operatorbundle_version TEXT,
+			operatorbundle_path TEXT,
+			FOREIGN KEY(operatorbundle_name, operatorbundle_version, operatorbundle_path) REFERENCES operatorbundle(name, version, bundlepath) ON DELETE CASCADE
+		);
+		`
+		_, err := tx.ExecContext(ctx, sql)
+		return err
+	},
+	Down: func(ctx context.Context, tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, `DROP TABLE channel_override`)
+		return err
+	},
+}
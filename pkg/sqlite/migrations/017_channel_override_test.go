@@ -0,0 +1,50 @@
+package migrations_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/operator-framework/operator-registry/pkg/sqlite/migrations"
+)
+
+func TestChannelOverrideUp(t *testing.T) {
+	db, migrator, cleanup := CreateTestDbAt(t, migrations.ChannelOverrideMigrationKey-1)
+	defer cleanup()
+
+	_, err := db.Exec("insert into operatorbundle(name, csv, bundle, bundlepath, version, skiprange, replaces, skips) values(?, ?, ?, ?, ?, ?, ?, ?)", "etcdoperator.v0.6.1", "testCSV", "testBundle", "quay.io/image", "0.6.1", "", "", "")
+	require.NoError(t, err)
+
+	err = migrator.Up(context.TODO(), migrations.Only(migrations.ChannelOverrideMigrationKey))
+	require.NoError(t, err)
+
+	insert := "insert into channel_override(annotated_channels, annotated_default_channel, channels, default_channel, operatorbundle_name, operatorbundle_version, operatorbundle_path) values(?, ?, ?, ?, ?, ?, ?)"
+	_, err = db.Exec(insert, "alpha", "alpha", "alpha,stable", "stable", "etcdoperator.v0.6.1", "0.6.1", "quay.io/image")
+	require.NoError(t, err)
+
+	var channels string
+	require.NoError(t, db.QueryRow(`SELECT channels FROM channel_override WHERE operatorbundle_name = ?`, "etcdoperator.v0.6.1").Scan(&channels))
+	require.Equal(t, "alpha,stable", channels)
+
+	// the override goes with its bundle
+	_, err = db.Exec("PRAGMA foreign_keys = ON")
+	require.NoError(t, err)
+	_, err = db.Exec("delete from operatorbundle where name = ?", "etcdoperator.v0.6.1")
+	require.NoError(t, err)
+	var count int
+	require.NoError(t, db.QueryRow(`SELECT COUNT(*) FROM channel_override`).Scan(&count))
+	require.Equal(t, 0, count)
+}
+
+func TestChannelOverrideDown(t *testing.T) {
+	db, migrator, cleanup := CreateTestDbAt(t, migrations.ChannelOverrideMigrationKey)
+	defer cleanup()
+
+	err := migrator.Down(context.TODO(), migrations.Only(migrations.ChannelOverrideMigrationKey))
+	require.NoError(t, err)
+
+	// the table should be gone after migrating down
+	_, err = db.Exec(`SELECT * FROM channel_override`)
+	require.Error(t, err)
+}
@@ -0,0 +1,71 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+const BundleSearchMigrationKey = 18
+
+// Register this migration
+func init() {
+	registerMigration(BundleSearchMigrationKey, bundleSearchMigration)
+}
+
+// bundleSearchColumns selects the searchable text of a bundle from its csv, which isn't always valid json
+const bundleSearchColumns = `
+	CASE WHEN json_valid(%[1]s.csv) THEN json_extract(%[1]s.csv, '$.spec.displayName') END,
+	CASE WHEN json_valid(%[1]s.csv) THEN coalesce(json_extract(%[1]s.csv, '$.metadata.annotations.description'), '') || ' ' || coalesce(json_extract(%[1]s.csv, '$.spec.description'), '') END,
+	CASE WHEN json_valid(%[1]s.csv) THEN json_extract(%[1]s.csv, '$.spec.keywords') END`
+
+// SupportsFullTextSearch returns true if SQLite was built with FTS5, which opm is with the sqlite_fts5 build tag
+func SupportsFullTextSearch(ctx context.Context, q interface {
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}) (bool, error) {
+	var supported bool
+	err := q.QueryRowContext(ctx, `SELECT EXISTS(SELECT 1 FROM pragma_compile_options WHERE compile_options = 'ENABLE_FTS5')`).Scan(&supported)
+	return supported, err
+}
+
+// This migration adds a full-text index over the names, display names, descriptions and keywords of bundles. The
+// index is kept up to date by triggers on the operatorbundle table; csvs that are cleared to save space keep their
+// text in the index. The index is left out if SQLite was built without FTS5, bundles are then searched without it.
+var bundleSearchMigration = &Migration{
+	Id: BundleSearchMigrationKey,
+	Up: func(ctx context.Context, tx *sql.Tx) error {
+		if supported, err := SupportsFullTextSearch(ctx, tx); err != nil || !supported {
+			return err
+		}
+		newColumns := fmt.Sprintf(bundleSearchColumns, "new")
+		sql := `
+		CREATE VIRTUAL TABLE IF NOT EXISTS bundle_search USING fts5(name, display_name, description, keywords);
+		CREATE TRIGGER IF NOT EXISTS bundle_search_insert AFTER INSERT ON operatorbundle
+		BEGIN
+			INSERT INTO bundle_search(name, display_name, description, keywords) VALUES (new.name, ` + newColumns + `);
+		END;
+		CREATE TRIGGER IF NOT EXISTS bundle_search_update AFTER UPDATE OF csv ON operatorbundle WHEN new.csv IS NOT NULL
+		BEGIN
+			DELETE FROM bundle_search WHERE name = old.name;
+			INSERT INTO bundle_search(name, display_name, description, keywords) VALUES (new.name, ` + newColumns + `);
+		END;
+		CREATE TRIGGER IF NOT EXISTS bundle_search_delete AFTER DELETE ON operatorbundle
+		BEGIN
+			DELETE FROM bundle_search WHERE name = old.name;
+		END;
+		INSERT INTO bundle_search(name, display_name, description, keywords) SELECT operatorbundle.name, ` + fmt.Sprintf(bundleSearchColumns, "operatorbundle") + ` FROM operatorbundle;
+		`
+		_, err := tx.ExecContext(ctx, sql)
+		return err
+	},
+	Down: func(ctx context.Context, tx *sql.Tx) error {
+		sql := `
+		DROP TRIGGER IF EXISTS bundle_search_insert;
+		DROP TRIGGER IF EXISTS bundle_search_update;
+		DROP TRIGGER IF EXISTS bundle_search_delete;
+		DROP TABLE IF EXISTS bundle_search;
+		`
+		_, err := tx.ExecContext(ctx, sql)
+		return err
+	},
+}
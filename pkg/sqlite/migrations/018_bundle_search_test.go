@@ -0,0 +1,66 @@
+package migrations_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/operator-framework/operator-registry/pkg/sqlite/migrations"
+)
+
+func TestBundleSearchUp(t *testing.T) {
+	db, migrator, cleanup := CreateTestDbAt(t, migrations.BundleSearchMigrationKey-1)
+	defer cleanup()
+
+	insert := "insert into operatorbundle(name, csv, bundle, bundlepath, version, skiprange, replaces, skips) values(?, ?, ?, ?, ?, ?, ?, ?)"
+	_, err := db.Exec(insert, "etcdoperator.v0.6.1", `{"spec":{"displayName":"etcd","description":"A distributed key value store","keywords":["database"]}}`, "testBundle", "quay.io/image", "0.6.1", "", "", "")
+	require.NoError(t, err)
+
+	err = migrator.Up(context.TODO(), migrations.Only(migrations.BundleSearchMigrationKey))
+	require.NoError(t, err)
+
+	search := func(query string) []string {
+		rows, err := db.Query(`SELECT name FROM bundle_search WHERE bundle_search MATCH ? ORDER BY name`, query)
+		require.NoError(t, err)
+		defer rows.Close()
+		var names []string
+		for rows.Next() {
+			var name string
+			require.NoError(t, rows.Scan(&name))
+			names = append(names, name)
+		}
+		return names
+	}
+
+	// existing bundles are indexed
+	require.Equal(t, []string{"etcdoperator.v0.6.1"}, search("distributed"))
+
+	// new bundles are indexed as they are added, even if their csv isn't json
+	_, err = db.Exec(insert, "prometheusoperator.v0.22.2", "testCSV", "testBundle", "quay.io/other", "0.22.2", "", "", "")
+	require.NoError(t, err)
+	require.Equal(t, []string{"prometheusoperator.v0.22.2"}, search("prometheusoperator*"))
+
+	// clearing the csv keeps the bundle searchable
+	_, err = db.Exec("update operatorbundle set csv = null where name = ?", "etcdoperator.v0.6.1")
+	require.NoError(t, err)
+	require.Equal(t, []string{"etcdoperator.v0.6.1"}, search("database"))
+
+	_, err = db.Exec("delete from operatorbundle where name = ?", "etcdoperator.v0.6.1")
+	require.NoError(t, err)
+	require.Empty(t, search("database"))
+}
+
+func TestBundleSearchDown(t *testing.T) {
+	db, migrator, cleanup := CreateTestDbAt(t, migrations.BundleSearchMigrationKey)
+	defer cleanup()
+
+	err := migrator.Down(context.TODO(), migrations.Only(migrations.BundleSearchMigrationKey))
+	require.NoError(t, err)
+
+	// the index should be gone after migrating down, and bundles can still be added without it
+	_, err = db.Exec(`SELECT * FROM bundle_search`)
+	require.Error(t, err)
+	_, err = db.Exec("insert into operatorbundle(name, csv, bundle, bundlepath, version, skiprange, replaces, skips) values(?, ?, ?, ?, ?, ?, ?, ?)", "etcdoperator.v0.6.1", "testCSV", "testBundle", "quay.io/image", "0.6.1", "", "", "")
+	require.NoError(t, err)
+}
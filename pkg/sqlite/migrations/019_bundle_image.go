@@ -0,0 +1,37 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+)
+
+const BundleImageMigrationKey = 19
+
+// Register this migration
+func init() {
+	registerMigration(BundleImageMigrationKey, bundleImageMigration)
+}
+
+// This migration adds a table recording the digest of the manifest of the image each bundle was pulled from, so that
+// bundles added by tag can still be pulled as they were when they were added. Bundles loaded before this migration,
+// or from images whose digest couldn't be resolved, have no row.
+var bundleImageMigration = &Migration{
+	Id: BundleImageMigrationKey,
+	Up: func(ctx context.Context, tx *sql.Tx) error {
+		sql := `
+		CREATE TABLE IF NOT EXISTS bundle_image (
+			digest TEXT NOT NULL,
+			operatorbundle_name TEXT,
+			operatorbundle_version TEXT,
+			operatorbundle_path TEXT,
+			FOREIGN KEY(operatorbundle_name, operatorbundle_version, operatorbundle_path) REFERENCES operatorbundle(name, version, bundlepath) ON DELETE CASCADE
+		);
+		`
+		_, err := tx.ExecContext(ctx, sql)
+		return err
+	},
+	Down: func(ctx context.Context, tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, `DROP TABLE bundle_image`)
+		return err
+	},
+}
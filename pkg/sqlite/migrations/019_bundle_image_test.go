@@ -0,0 +1,50 @@
+package migrations_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/operator-framework/operator-registry/pkg/sqlite/migrations"
+)
+
+func TestBundleImageUp(t *testing.T) {
+	db, migrator, cleanup := CreateTestDbAt(t, migrations.BundleImageMigrationKey-1)
+	defer cleanup()
+
+	_, err := db.Exec("insert into operatorbundle(name, csv, bundle, bundlepath, version, skiprange, replaces, skips) values(?, ?, ?, ?, ?, ?, ?, ?)", "etcdoperator.v0.6.1", "testCSV", "testBundle", "quay.io/image:v0.6.1", "0.6.1", "", "", "")
+	require.NoError(t, err)
+
+	err = migrator.Up(context.TODO(), migrations.Only(migrations.BundleImageMigrationKey))
+	require.NoError(t, err)
+
+	insert := "insert into bundle_image(digest, operatorbundle_name, operatorbundle_version, operatorbundle_path) values(?, ?, ?, ?)"
+	_, err = db.Exec(insert, "sha256:abc", "etcdoperator.v0.6.1", "0.6.1", "quay.io/image:v0.6.1")
+	require.NoError(t, err)
+
+	var digest string
+	require.NoError(t, db.QueryRow(`SELECT digest FROM bundle_image WHERE operatorbundle_name = ?`, "etcdoperator.v0.6.1").Scan(&digest))
+	require.Equal(t, "sha256:abc", digest)
+
+	// the digest goes with its bundle
+	_, err = db.Exec("PRAGMA foreign_keys = ON")
+	require.NoError(t, err)
+	_, err = db.Exec("delete from operatorbundle where name = ?", "etcdoperator.v0.6.1")
+	require.NoError(t, err)
+	var count int
+	require.NoError(t, db.QueryRow(`SELECT COUNT(*) FROM bundle_image`).Scan(&count))
+	require.Equal(t, 0, count)
+}
+
+func TestBundleImageDown(t *testing.T) {
+	db, migrator, cleanup := CreateTestDbAt(t, migrations.BundleImageMigrationKey)
+	defer cleanup()
+
+	err := migrator.Down(context.TODO(), migrations.Only(migrations.BundleImageMigrationKey))
+	require.NoError(t, err)
+
+	// the table should be gone after migrating down
+	_, err = db.Exec(`SELECT * FROM bundle_image`)
+	require.Error(t, err)
+}
@@ -0,0 +1,33 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+)
+
+const DeprecatedMigrationKey = 20
+
+// Register this migration
+func init() {
+	registerMigration(DeprecatedMigrationKey, deprecatedMigration)
+}
+
+// This migration adds a table of the bundles that have been marked deprecated. Deprecated bundles stay in the upgrade
+// graph, so that installed operators can still upgrade past them, but are never the head of a channel.
+var deprecatedMigration = &Migration{
+	Id: DeprecatedMigrationKey,
+	Up: func(ctx context.Context, tx *sql.Tx) error {
+		sql := `
+		CREATE TABLE IF NOT EXISTS deprecated (
+			operatorbundle_name TEXT PRIMARY KEY,
+			FOREIGN KEY(operatorbundle_name) REFERENCES operatorbundle(name) ON DELETE CASCADE
+		);
+		`
+		_, err := tx.ExecContext(ctx, sql)
+		return err
+	},
+	Down: func(ctx context.Context, tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, `DROP TABLE deprecated`)
+		return err
+	},
+}
@@ -0,0 +1,48 @@
+package migrations_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/operator-framework/operator-registry/pkg/sqlite/migrations"
+)
+
+func TestDeprecatedUp(t *testing.T) {
+	db, migrator, cleanup := CreateTestDbAt(t, migrations.DeprecatedMigrationKey-1)
+	defer cleanup()
+
+	_, err := db.Exec("insert into operatorbundle(name, csv, bundle, bundlepath, version, skiprange, replaces, skips) values(?, ?, ?, ?, ?, ?, ?, ?)", "etcdoperator.v0.6.1", "testCSV", "testBundle", "quay.io/image:v0.6.1", "0.6.1", "", "", "")
+	require.NoError(t, err)
+
+	err = migrator.Up(context.TODO(), migrations.Only(migrations.DeprecatedMigrationKey))
+	require.NoError(t, err)
+
+	_, err = db.Exec("insert into deprecated(operatorbundle_name) values(?)", "etcdoperator.v0.6.1")
+	require.NoError(t, err)
+	// a bundle is only marked once
+	_, err = db.Exec("insert into deprecated(operatorbundle_name) values(?)", "etcdoperator.v0.6.1")
+	require.Error(t, err)
+
+	// the mark goes with its bundle
+	_, err = db.Exec("PRAGMA foreign_keys = ON")
+	require.NoError(t, err)
+	_, err = db.Exec("delete from operatorbundle where name = ?", "etcdoperator.v0.6.1")
+	require.NoError(t, err)
+	var count int
+	require.NoError(t, db.QueryRow(`SELECT COUNT(*) FROM deprecated`).Scan(&count))
+	require.Equal(t, 0, count)
+}
+
+func TestDeprecatedDown(t *testing.T) {
+	db, migrator, cleanup := CreateTestDbAt(t, migrations.DeprecatedMigrationKey)
+	defer cleanup()
+
+	err := migrator.Down(context.TODO(), migrations.Only(migrations.DeprecatedMigrationKey))
+	require.NoError(t, err)
+
+	// the table should be gone after migrating down
+	_, err = db.Exec(`SELECT * FROM deprecated`)
+	require.Error(t, err)
+}
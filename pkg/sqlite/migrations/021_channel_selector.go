@@ -0,0 +1,38 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+)
+
+const ChannelSelectorMigrationKey = 21
+
+// Register this migration
+func init() {
+	registerMigration(ChannelSelectorMigrationKey, channelSelectorMigration)
+}
+
+// This migration adds a table of channel selectors, which define the entries of a channel as every bundle of its
+// package whose version is in a range and that has the given properties, instead of by listing them. Selected
+// channels are expanded into entries whenever the database is built or served. Packages are deleted and recreated
+// when bundles are added to them in replaces mode, so selectors aren't removed with their package.
+var channelSelectorMigration = &Migration{
+	Id: ChannelSelectorMigrationKey,
+	Up: func(ctx context.Context, tx *sql.Tx) error {
+		sql := `
+		CREATE TABLE IF NOT EXISTS channel_selector (
+			package_name TEXT NOT NULL,
+			channel_name TEXT NOT NULL,
+			versions TEXT,
+			properties TEXT,
+			PRIMARY KEY(package_name, channel_name)
+		);
+		`
+		_, err := tx.ExecContext(ctx, sql)
+		return err
+	},
+	Down: func(ctx context.Context, tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, `DROP TABLE channel_selector`)
+		return err
+	},
+}
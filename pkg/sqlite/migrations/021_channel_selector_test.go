@@ -0,0 +1,48 @@
+package migrations_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/operator-framework/operator-registry/pkg/sqlite/migrations"
+)
+
+func TestChannelSelectorUp(t *testing.T) {
+	db, migrator, cleanup := CreateTestDbAt(t, migrations.ChannelSelectorMigrationKey-1)
+	defer cleanup()
+
+	_, err := db.Exec("insert into package(name) values(?)", "etcd")
+	require.NoError(t, err)
+
+	err = migrator.Up(context.TODO(), migrations.Only(migrations.ChannelSelectorMigrationKey))
+	require.NoError(t, err)
+
+	_, err = db.Exec("insert into channel_selector(package_name, channel_name, versions, properties) values(?, ?, ?, ?)", "etcd", "2.x", ">=2.0.0 <3.0.0", "[]")
+	require.NoError(t, err)
+	// a channel has one selector
+	_, err = db.Exec("insert into channel_selector(package_name, channel_name, versions, properties) values(?, ?, ?, ?)", "etcd", "2.x", ">=2.1.0", "[]")
+	require.Error(t, err)
+
+	// the selector outlives its package, which is recreated when bundles are added in replaces mode
+	_, err = db.Exec("PRAGMA foreign_keys = ON")
+	require.NoError(t, err)
+	_, err = db.Exec("delete from package where name = ?", "etcd")
+	require.NoError(t, err)
+	var count int
+	require.NoError(t, db.QueryRow(`SELECT COUNT(*) FROM channel_selector`).Scan(&count))
+	require.Equal(t, 1, count)
+}
+
+func TestChannelSelectorDown(t *testing.T) {
+	db, migrator, cleanup := CreateTestDbAt(t, migrations.ChannelSelectorMigrationKey)
+	defer cleanup()
+
+	err := migrator.Down(context.TODO(), migrations.Only(migrations.ChannelSelectorMigrationKey))
+	require.NoError(t, err)
+
+	// the table should be gone after migrating down
+	_, err = db.Exec(`SELECT * FROM channel_selector`)
+	require.Error(t, err)
+}
@@ -0,0 +1,91 @@
+package migrations
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+)
+
+const BundleStatsMigrationKey = 22
+
+// Register this migration
+func init() {
+	registerMigration(BundleStatsMigrationKey, bundleStatsMigration)
+}
+
+// This migration adds a table of the size of the serialized manifests of each bundle and of the number of objects and
+// CRDs among them, so that bundles that inflate a catalog can be found without reading every manifest. Stats are
+// recorded when a bundle is loaded, and kept when its manifests are cleared to save space; bundles whose manifests
+// were cleared before this migration have none.
+var bundleStatsMigration = &Migration{
+	Id: BundleStatsMigrationKey,
+	Up: func(ctx context.Context, tx *sql.Tx) error {
+		sql := `
+		CREATE TABLE IF NOT EXISTS bundle_stats (
+			operatorbundle_name TEXT PRIMARY KEY,
+			size INTEGER NOT NULL,
+			object_count INTEGER NOT NULL,
+			crd_count INTEGER NOT NULL,
+			FOREIGN KEY(operatorbundle_name) REFERENCES operatorbundle(name) ON DELETE CASCADE
+		);
+		`
+		if _, err := tx.ExecContext(ctx, sql); err != nil {
+			return err
+		}
+
+		rows, err := tx.QueryContext(ctx, `SELECT name, bundle FROM operatorbundle WHERE bundle IS NOT NULL`)
+		if err != nil {
+			return err
+		}
+		type bundleStats struct {
+			size, objects, crds int
+		}
+		stats := map[string]bundleStats{}
+		for rows.Next() {
+			var name string
+			var manifests []byte
+			if err := rows.Scan(&name, &manifests); err != nil {
+				rows.Close()
+				return err
+			}
+			objects, crds := countObjects(manifests)
+			stats[name] = bundleStats{size: len(manifests), objects: objects, crds: crds}
+		}
+		if err := rows.Err(); err != nil {
+			rows.Close()
+			return err
+		}
+		if err := rows.Close(); err != nil {
+			return err
+		}
+		for name, s := range stats {
+			if _, err := tx.ExecContext(ctx, `INSERT INTO bundle_stats(operatorbundle_name, size, object_count, crd_count) VALUES (?, ?, ?, ?)`, name, s.size, s.objects, s.crds); err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+	Down: func(ctx context.Context, tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, `DROP TABLE bundle_stats`)
+		return err
+	},
+}
+
+// countObjects counts the objects, and the CRDs among them, in the manifests of a bundle, which are stored as
+// concatenated json objects. Objects after one that can't be decoded aren't counted.
+func countObjects(manifests []byte) (objects, crds int) {
+	dec := json.NewDecoder(bytes.NewReader(manifests))
+	for {
+		var obj struct {
+			Kind string `json:"kind"`
+		}
+		if err := dec.Decode(&obj); err != nil {
+			return
+		}
+		objects++
+		if obj.Kind == "CustomResourceDefinition" {
+			crds++
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package migrations_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/operator-framework/operator-registry/pkg/sqlite/migrations"
+)
+
+func TestBundleStatsUp(t *testing.T) {
+	db, migrator, cleanup := CreateTestDbAt(t, migrations.BundleStatsMigrationKey-1)
+	defer cleanup()
+
+	manifests := `{"kind":"CustomResourceDefinition","metadata":{"name":"etcdclusters.etcd.database.coreos.com"}}{"kind":"ClusterServiceVersion","metadata":{"name":"etcdoperator.v0.6.1"}}{"kind":"CustomResourceDefinition","metadata":{"name":"etcdbackups.etcd.database.coreos.com"}}`
+	_, err := db.Exec("insert into operatorbundle(name, csv, bundle, bundlepath, version, skiprange, replaces, skips) values(?, ?, ?, ?, ?, ?, ?, ?)", "etcdoperator.v0.6.1", "testCSV", manifests, "quay.io/image:v0.6.1", "0.6.1", "", "", "")
+	require.NoError(t, err)
+	// a bundle whose manifests were cleared has no stats to backfill
+	_, err = db.Exec("insert into operatorbundle(name, csv, bundle, bundlepath, version, skiprange, replaces, skips) values(?, ?, ?, ?, ?, ?, ?, ?)", "etcdoperator.v0.6.0", nil, nil, "quay.io/image:v0.6.0", "0.6.0", "", "", "")
+	require.NoError(t, err)
+
+	err = migrator.Up(context.TODO(), migrations.Only(migrations.BundleStatsMigrationKey))
+	require.NoError(t, err)
+
+	var name string
+	var size, objects, crds int
+	require.NoError(t, db.QueryRow(`SELECT operatorbundle_name, size, object_count, crd_count FROM bundle_stats`).Scan(&name, &size, &objects, &crds))
+	require.Equal(t, "etcdoperator.v0.6.1", name)
+	require.Equal(t, len(manifests), size)
+	require.Equal(t, 3, objects)
+	require.Equal(t, 2, crds)
+	var count int
+	require.NoError(t, db.QueryRow(`SELECT COUNT(*) FROM bundle_stats`).Scan(&count))
+	require.Equal(t, 1, count)
+
+	// the stats go with their bundle
+	_, err = db.Exec("PRAGMA foreign_keys = ON")
+	require.NoError(t, err)
+	_, err = db.Exec("delete from operatorbundle where name = ?", "etcdoperator.v0.6.1")
+	require.NoError(t, err)
+	require.NoError(t, db.QueryRow(`SELECT COUNT(*) FROM bundle_stats`).Scan(&count))
+	require.Equal(t, 0, count)
+}
+
+func TestBundleStatsDown(t *testing.T) {
+	db, migrator, cleanup := CreateTestDbAt(t, migrations.BundleStatsMigrationKey)
+	defer cleanup()
+
+	err := migrator.Down(context.TODO(), migrations.Only(migrations.BundleStatsMigrationKey))
+	require.NoError(t, err)
+
+	// the table should be gone after migrating down
+	_, err = db.Exec(`SELECT * FROM bundle_stats`)
+	require.Error(t, err)
+}
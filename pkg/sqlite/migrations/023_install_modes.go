@@ -0,0 +1,76 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+
+	"github.com/operator-framework/operator-registry/pkg/registry"
+)
+
+const InstallModesMigrationKey = 23
+
+// Register this migration
+func init() {
+	registerMigration(InstallModesMigrationKey, installModesMigration)
+}
+
+// This migration records the install modes each bundle supports as an olm.installModes property, as they are recorded
+// when a bundle is loaded, so that catalogs can be filtered by them. Bundles whose CSV was cleared, or declares no
+// install modes, are left without one.
+var installModesMigration = &Migration{
+	Id: InstallModesMigrationKey,
+	Up: func(ctx context.Context, tx *sql.Tx) error {
+		rows, err := tx.QueryContext(ctx, `SELECT name, version, bundlepath, csv FROM operatorbundle
+			WHERE csv IS NOT NULL
+			AND name NOT IN (SELECT operatorbundle_name FROM properties WHERE type = ?)`, registry.InstallModesType)
+		if err != nil {
+			return err
+		}
+		type bundleModes struct {
+			name, version, path sql.NullString
+			value               string
+		}
+		var bundles []bundleModes
+		for rows.Next() {
+			var b bundleModes
+			var csvJSON string
+			if err := rows.Scan(&b.name, &b.version, &b.path, &csvJSON); err != nil {
+				rows.Close()
+				return err
+			}
+			csv := registry.ClusterServiceVersion{}
+			if err := json.Unmarshal([]byte(csvJSON), &csv); err != nil {
+				continue
+			}
+			modes, declared, err := csv.GetInstallModes()
+			if err != nil || !declared {
+				continue
+			}
+			value, err := json.Marshal(registry.NewInstallModesProperty(modes))
+			if err != nil {
+				rows.Close()
+				return err
+			}
+			b.value = string(value)
+			bundles = append(bundles, b)
+		}
+		if err := rows.Err(); err != nil {
+			rows.Close()
+			return err
+		}
+		if err := rows.Close(); err != nil {
+			return err
+		}
+		for _, b := range bundles {
+			if _, err := tx.ExecContext(ctx, `INSERT INTO properties(type, value, operatorbundle_name, operatorbundle_version, operatorbundle_path) VALUES (?, ?, ?, ?, ?)`, registry.InstallModesType, b.value, b.name, b.version, b.path); err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+	Down: func(ctx context.Context, tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, `DELETE FROM properties WHERE type = ?`, registry.InstallModesType)
+		return err
+	},
+}
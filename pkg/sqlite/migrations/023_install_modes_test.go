@@ -0,0 +1,65 @@
+package migrations_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/operator-framework/operator-registry/pkg/registry"
+	"github.com/operator-framework/operator-registry/pkg/sqlite/migrations"
+)
+
+func TestInstallModesUp(t *testing.T) {
+	db, migrator, cleanup := CreateTestDbAt(t, migrations.InstallModesMigrationKey-1)
+	defer cleanup()
+
+	insertBundle := "insert into operatorbundle(name, csv, bundle, bundlepath, version, skiprange, replaces, skips) values(?, ?, ?, ?, ?, ?, ?, ?)"
+	csv := `{"kind":"ClusterServiceVersion","metadata":{"name":"etcdoperator.v0.6.1"},"spec":{"installModes":[{"type":"OwnNamespace","supported":true},{"type":"AllNamespaces","supported":true},{"type":"MultiNamespace","supported":false}]}}`
+	_, err := db.Exec(insertBundle, "etcdoperator.v0.6.1", csv, "", "quay.io/image:v0.6.1", "0.6.1", "", "", "")
+	require.NoError(t, err)
+	// bundles whose CSV declares no install modes, or was cleared, have none to backfill
+	_, err = db.Exec(insertBundle, "etcdoperator.v0.6.0", `{"kind":"ClusterServiceVersion","metadata":{"name":"etcdoperator.v0.6.0"},"spec":{}}`, "", "quay.io/image:v0.6.0", "0.6.0", "", "", "")
+	require.NoError(t, err)
+	_, err = db.Exec(insertBundle, "etcdoperator.v0.5.0", nil, nil, "quay.io/image:v0.5.0", "0.5.0", "", "", "")
+	require.NoError(t, err)
+
+	err = migrator.Up(context.TODO(), migrations.Only(migrations.InstallModesMigrationKey))
+	require.NoError(t, err)
+
+	rows, err := db.Query(`SELECT operatorbundle_name, operatorbundle_version, operatorbundle_path, value FROM properties WHERE type = ?`, registry.InstallModesType)
+	require.NoError(t, err)
+	defer rows.Close()
+	var found [][]string
+	for rows.Next() {
+		var name, version, path, value string
+		require.NoError(t, rows.Scan(&name, &version, &path, &value))
+		found = append(found, []string{name, version, path, value})
+	}
+	require.Equal(t, [][]string{{"etcdoperator.v0.6.1", "0.6.1", "quay.io/image:v0.6.1", `{"supported":["OwnNamespace","AllNamespaces"]}`}}, found)
+}
+
+func TestInstallModesDown(t *testing.T) {
+	db, migrator, cleanup := CreateTestDbAt(t, migrations.InstallModesMigrationKey)
+	defer cleanup()
+
+	_, err := db.Exec(`INSERT INTO properties(type, value, operatorbundle_name) VALUES (?, ?, ?), (?, ?, ?)`,
+		registry.InstallModesType, `{"supported":["AllNamespaces"]}`, "etcdoperator.v0.6.1",
+		registry.LabelType, `{"label":"lts"}`, "etcdoperator.v0.6.1")
+	require.NoError(t, err)
+
+	err = migrator.Down(context.TODO(), migrations.Only(migrations.InstallModesMigrationKey))
+	require.NoError(t, err)
+
+	// only the install modes are gone after migrating down
+	var types []string
+	rows, err := db.Query(`SELECT type FROM properties`)
+	require.NoError(t, err)
+	defer rows.Close()
+	for rows.Next() {
+		var typ string
+		require.NoError(t, rows.Scan(&typ))
+		types = append(types, typ)
+	}
+	require.Equal(t, []string{registry.LabelType}, types)
+}
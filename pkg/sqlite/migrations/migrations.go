@@ -82,6 +82,22 @@ func All() MigrationSet {
 	return migrations
 }
 
+// Latest returns the id of the last migration of the set, the version of the schema it migrates up to
+func (m MigrationSet) Latest() int {
+	latest := -1
+	for k := range m {
+		if k > latest {
+			latest = k
+		}
+	}
+	return latest
+}
+
+// Latest returns the version of the schema the full set migrates up to
+func Latest() int {
+	return migrations.Latest()
+}
+
 func registerMigration(key int, m *Migration) {
 	if _, ok := migrations[key]; ok {
 		panic(fmt.Sprintf("already have a migration registered with id %d", key))
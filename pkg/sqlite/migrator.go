@@ -9,6 +9,7 @@ import (
 	_ "github.com/golang-migrate/migrate/v4/source/file" // indirect import required by golang-migrate package
 	"github.com/sirupsen/logrus"
 
+	"github.com/operator-framework/operator-registry/pkg/lib/progress"
 	"github.com/operator-framework/operator-registry/pkg/sqlite/migrations"
 )
 
@@ -16,6 +17,8 @@ type Migrator interface {
 	Migrate(ctx context.Context) error
 	Up(ctx context.Context, migrations migrations.Migrations) error
 	Down(ctx context.Context, migrations migrations.Migrations) error
+	Version(ctx context.Context) (int, error)
+	MigrateTo(ctx context.Context, version int) error
 }
 
 type SQLLiteMigrator struct {
@@ -64,6 +67,49 @@ func (m *SQLLiteMigrator) Migrate(ctx context.Context) error {
 	return m.Up(ctx, m.migrations.From(version+1))
 }
 
+// Version returns the schema version of the database, NilVersion if it hasn't been migrated
+func (m *SQLLiteMigrator) Version(ctx context.Context) (int, error) {
+	tx, err := m.db.Begin()
+	if err != nil {
+		return NilVersion, err
+	}
+	defer tx.Rollback()
+	return m.version(ctx, tx)
+}
+
+// MigrateTo migrates the database up or down to a specific version. Migrating down drops the data that
+// the later migrations added, so it's only meant for handing a database to an older opm.
+func (m *SQLLiteMigrator) MigrateTo(ctx context.Context, version int) error {
+	if version < NilVersion || version > m.migrations.Latest() {
+		return fmt.Errorf("unknown schema version %d, the latest is %d", version, m.migrations.Latest())
+	}
+	current, err := m.Version(ctx)
+	if err != nil {
+		return err
+	}
+	switch {
+	case current > m.migrations.Latest():
+		return fmt.Errorf("database schema version %d is newer than the latest version %d known to this opm", current, m.migrations.Latest())
+	case version > current:
+		var up migrations.Migrations
+		for _, migration := range m.migrations.From(current + 1) {
+			if migration.Id <= version {
+				up = append(up, migration)
+			}
+		}
+		return m.Up(ctx, up)
+	case version < current:
+		var down migrations.Migrations
+		for _, migration := range m.migrations.To(current) {
+			if migration.Id > version {
+				down = append(migrations.Migrations{migration}, down...)
+			}
+		}
+		return m.Down(ctx, down)
+	}
+	return nil
+}
+
 // Up runs a specific set of migrations.
 func (m *SQLLiteMigrator) Up(ctx context.Context, migrations migrations.Migrations) error {
 	tx, err := m.db.Begin()
@@ -82,26 +128,32 @@ func (m *SQLLiteMigrator) Up(ctx context.Context, migrations migrations.Migratio
 	}()
 
 	if err := m.ensureMigrationTable(ctx, tx); err != nil {
-		return err
+		return rollback(tx, err)
 	}
 
-	for _, migration := range migrations {
+	for i, migration := range migrations {
+		// stop between migrations; nothing is committed until all of them have run
+		if err := progress.Canceled(ctx); err != nil {
+			return rollback(tx, err)
+		}
+
 		current_version, err := m.version(ctx, tx)
 		if err != nil {
-			return err
+			return rollback(tx, err)
 		}
 
 		if migration.Id != current_version+1 {
-			return fmt.Errorf("migration applied out of order")
+			return rollback(tx, fmt.Errorf("migration applied out of order"))
 		}
 
 		if err := migration.Up(ctx, tx); err != nil {
-			return err
+			return rollback(tx, err)
 		}
 
 		if err := m.setVersion(ctx, tx, migration.Id); err != nil {
-			return err
+			return rollback(tx, err)
 		}
+		progress.Report(ctx, "migrate", fmt.Sprintf("migration %d", migration.Id), i+1, len(migrations))
 	}
 	commitErr = tx.Commit()
 	return commitErr
@@ -207,3 +259,11 @@ func (m *SQLLiteMigrator) setVersion(ctx context.Context, tx *sql.Tx, version in
 	_, err = tx.ExecContext(ctx, "INSERT INTO "+m.migrationsTable+"(version) values(?)", version)
 	return err
 }
+
+// rollback aborts tx and returns the error that caused it to be abandoned
+func rollback(tx *sql.Tx, err error) error {
+	if rerr := tx.Rollback(); rerr != nil {
+		logrus.WithError(rerr).Warningf("couldn't rollback")
+	}
+	return err
+}
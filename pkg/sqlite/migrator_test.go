@@ -158,6 +158,9 @@ func TestSQLLiteMigrator_Down(t *testing.T) {
 }
 
 func TestSQLLiteMigrator_Up(t *testing.T) {
+	cancelCtx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+
 	var up int
 	var down bool
 
@@ -275,6 +278,39 @@ func TestSQLLiteMigrator_Up(t *testing.T) {
 			wantDown:    false,
 			wantVersion: -1,
 		},
+		{
+			name:   "cancel between migrations",
+			fields: fields{migrationsTable: DefaultMigrationsTable},
+			args: args{ctx: cancelCtx, migrations: migrations.Migrations{
+				{
+					Id: 0,
+					Up: func(ctx context.Context, tx *sql.Tx) error {
+						up++
+						cancel()
+						return nil
+					},
+					Down: func(ctx context.Context, tx *sql.Tx) error {
+						down = true
+						return nil
+					},
+				},
+				{
+					Id: 1,
+					Up: func(ctx context.Context, tx *sql.Tx) error {
+						up++
+						return nil
+					},
+					Down: func(ctx context.Context, tx *sql.Tx) error {
+						down = true
+						return nil
+					},
+				},
+			}},
+			wantErr:     true,
+			wantUp:      1,
+			wantDown:    false,
+			wantVersion: -1,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -431,3 +467,96 @@ func TestSQLLiteMigrator_Migrate(t *testing.T) {
 		})
 	}
 }
+
+func TestSQLLiteMigrator_MigrateTo(t *testing.T) {
+	var applied []int
+	migs := migrations.MigrationSet{}
+	for id := 0; id < 3; id++ {
+		id := id
+		migs[id] = &migrations.Migration{
+			Id: id,
+			Up: func(ctx context.Context, tx *sql.Tx) error {
+				applied = append(applied, id)
+				return nil
+			},
+			Down: func(ctx context.Context, tx *sql.Tx) error {
+				applied = append(applied, -id)
+				return nil
+			},
+		}
+	}
+
+	tests := []struct {
+		name         string
+		startVersion int
+		version      int
+		wantErr      bool
+		wantApplied  []int
+		wantVersion  int
+	}{
+		{
+			name:         "up from nothing to a version",
+			startVersion: -1,
+			version:      1,
+			wantApplied:  []int{0, 1},
+			wantVersion:  1,
+		},
+		{
+			name:         "down in reverse order",
+			startVersion: 2,
+			version:      0,
+			wantApplied:  []int{-2, -1},
+			wantVersion:  0,
+		},
+		{
+			name:         "at version",
+			startVersion: 1,
+			version:      1,
+			wantVersion:  1,
+		},
+		{
+			name:         "unknown version",
+			startVersion: 1,
+			version:      3,
+			wantErr:      true,
+			wantVersion:  1,
+		},
+		{
+			name:         "database newer than the migrations",
+			startVersion: 5,
+			version:      2,
+			wantErr:      true,
+			wantVersion:  5,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			applied = nil
+			db, cleanup := CreateTestDb(t)
+			defer cleanup()
+			m := &SQLLiteMigrator{
+				db:              db,
+				migrationsTable: DefaultMigrationsTable,
+				migrations:      migs,
+			}
+			if tt.startVersion != NilVersion {
+				tx, err := db.Begin()
+				require.NoError(t, err)
+				require.NoError(t, m.setVersion(context.TODO(), tx, tt.startVersion))
+				require.NoError(t, tx.Commit())
+			}
+
+			err := m.MigrateTo(context.TODO(), tt.version)
+			if tt.wantErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+			require.Equal(t, tt.wantApplied, applied)
+
+			version, err := m.Version(context.TODO())
+			require.NoError(t, err)
+			require.Equal(t, tt.wantVersion, version)
+		})
+	}
+}
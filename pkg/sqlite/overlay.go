@@ -0,0 +1,188 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"k8s.io/apimachinery/pkg/util/yaml"
+
+	"github.com/operator-framework/operator-registry/pkg/registry"
+)
+
+// Overlay describes adjustments made to an upstream catalog when it is served, so that
+// downstream distributors don't have to fork the catalog to change it
+type Overlay struct {
+	Packages []PackageOverlay `json:"packages"`
+}
+
+// PackageOverlay holds the adjustments made to a single package
+type PackageOverlay struct {
+	Name string `json:"name"`
+	// DefaultChannel replaces the package's default channel
+	DefaultChannel string `json:"defaultChannel,omitempty"`
+	// DropChannels removes channels from the package, along with bundles that are left in no channel
+	DropChannels []string `json:"dropChannels,omitempty"`
+	// DeprecateBundles marks the named bundles (csv names) as deprecated
+	DeprecateBundles []string `json:"deprecateBundles,omitempty"`
+}
+
+// LoadOverlay reads an overlay from a yaml or json file
+func LoadOverlay(path string) (*Overlay, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	overlay := &Overlay{}
+	if err := yaml.NewYAMLOrJSONDecoder(f, 30).Decode(overlay); err != nil {
+		return nil, fmt.Errorf("error parsing overlay %s: %s", path, err)
+	}
+	return overlay, nil
+}
+
+// ApplyOverlay patches the database with the overlay. Either every adjustment applies or none does.
+// The database is modified in place, so this is meant to be run against the temporary copy being served.
+func ApplyOverlay(ctx context.Context, db *sql.DB, overlay *Overlay) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		tx.Rollback()
+	}()
+
+	s := &sqlLoader{db: db}
+	for _, pkg := range overlay.Packages {
+		if err := s.applyPackageOverlay(tx, pkg); err != nil {
+			return fmt.Errorf("error applying overlay to package %s: %s", pkg.Name, err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (s *sqlLoader) applyPackageOverlay(tx *sql.Tx, pkg PackageOverlay) error {
+	var defaultChannel sql.NullString
+	err := tx.QueryRow(`SELECT default_channel FROM package WHERE name = ?`, pkg.Name).Scan(&defaultChannel)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("package not found")
+	}
+	if err != nil {
+		return err
+	}
+
+	dropped := make(map[string]struct{}, len(pkg.DropChannels))
+	for _, channel := range pkg.DropChannels {
+		dropped[channel] = struct{}{}
+	}
+
+	if pkg.DefaultChannel != "" {
+		if _, ok := dropped[pkg.DefaultChannel]; ok {
+			return fmt.Errorf("default channel %s is also dropped", pkg.DefaultChannel)
+		}
+		if err := channelExists(tx, pkg.Name, pkg.DefaultChannel); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(`UPDATE package SET default_channel = ? WHERE name = ?`, pkg.DefaultChannel, pkg.Name); err != nil {
+			return err
+		}
+		defaultChannel.String = pkg.DefaultChannel
+	}
+
+	for _, channel := range pkg.DropChannels {
+		if channel == defaultChannel.String {
+			return fmt.Errorf("can't drop default channel %s without setting a new default channel", channel)
+		}
+		if err := s.dropChannel(tx, pkg.Name, channel); err != nil {
+			return err
+		}
+	}
+
+	for _, csvName := range pkg.DeprecateBundles {
+		if err := s.deprecateBundleByName(tx, pkg.Name, csvName); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func channelExists(tx *sql.Tx, packageName, channel string) error {
+	var count int
+	if err := tx.QueryRow(`SELECT COUNT(*) FROM channel WHERE package_name = ? AND name = ?`, packageName, channel).Scan(&count); err != nil {
+		return err
+	}
+	if count == 0 {
+		return fmt.Errorf("channel %s not found", channel)
+	}
+	return nil
+}
+
+// dropChannel removes a channel and its entries, and then removes the bundles that were only in that channel
+func (s *sqlLoader) dropChannel(tx *sql.Tx, packageName, channel string) error {
+	if err := channelExists(tx, packageName, channel); err != nil {
+		return err
+	}
+
+	rows, err := tx.Query(`SELECT DISTINCT operatorbundle_name FROM channel_entry WHERE package_name = ? AND channel_name = ?`, packageName, channel)
+	if err != nil {
+		return err
+	}
+	var bundles []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			rows.Close()
+			return err
+		}
+		bundles = append(bundles, name)
+	}
+	if err := rows.Close(); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(`DELETE FROM channel_entry WHERE package_name = ? AND channel_name = ?`, packageName, channel); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM channel WHERE package_name = ? AND name = ?`, packageName, channel); err != nil {
+		return err
+	}
+
+	for _, bundle := range bundles {
+		var remaining int
+		if err := tx.QueryRow(`SELECT COUNT(*) FROM channel_entry WHERE operatorbundle_name = ?`, bundle).Scan(&remaining); err != nil {
+			return err
+		}
+		if remaining > 0 {
+			continue
+		}
+		if err := s.rmBundle(tx, bundle); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deprecateBundleByName marks a bundle of the package as deprecated without truncating its upgrade graph
+func (s *sqlLoader) deprecateBundleByName(tx *sql.Tx, packageName, csvName string) error {
+	var version, path sql.NullString
+	err := tx.QueryRow(`SELECT DISTINCT operatorbundle.version, operatorbundle.bundlepath FROM operatorbundle
+		INNER JOIN channel_entry ON channel_entry.operatorbundle_name = operatorbundle.name
+		WHERE operatorbundle.name = ? AND channel_entry.package_name = ?`, csvName, packageName).Scan(&version, &path)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("bundle %s not found", csvName)
+	}
+	if err != nil {
+		return err
+	}
+
+	deprecatedValue, err := json.Marshal(registry.DeprecatedProperty{})
+	if err != nil {
+		return err
+	}
+	return s.addProperty(tx, registry.DeprecatedType, string(deprecatedValue), csvName, version.String, path.String)
+}
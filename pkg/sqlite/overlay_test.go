@@ -0,0 +1,75 @@
+package sqlite
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/operator-framework/operator-registry/pkg/registry"
+)
+
+const testOverlay = `
+packages:
+- name: etcd
+  defaultChannel: beta
+  dropChannels:
+  - alpha
+  - stable
+  deprecateBundles:
+  - etcdoperator.v0.6.1
+`
+
+func TestApplyOverlay(t *testing.T) {
+	db, cleanup := CreateTestDb(t)
+	defer cleanup()
+	load, err := NewSQLLiteLoader(db)
+	require.NoError(t, err)
+	require.NoError(t, load.Migrate(context.TODO()))
+	require.NoError(t, NewSQLLoaderForDirectory(load, "../../manifests").Populate())
+
+	f, err := ioutil.TempFile("", "overlay-*.yaml")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+	_, err = f.WriteString(testOverlay)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	overlay, err := LoadOverlay(f.Name())
+	require.NoError(t, err)
+	require.Len(t, overlay.Packages, 1)
+
+	// dropping the default channel without replacing it leaves the database untouched
+	err = ApplyOverlay(context.TODO(), db, &Overlay{Packages: []PackageOverlay{{Name: "etcd", DropChannels: []string{"alpha"}}}})
+	require.Error(t, err)
+
+	store := NewSQLLiteQuerierFromDb(db)
+	pkg, err := store.GetPackage(context.TODO(), "etcd")
+	require.NoError(t, err)
+	require.Equal(t, "alpha", pkg.DefaultChannelName)
+	require.Len(t, pkg.Channels, 3)
+
+	require.NoError(t, ApplyOverlay(context.TODO(), db, overlay))
+
+	pkg, err = store.GetPackage(context.TODO(), "etcd")
+	require.NoError(t, err)
+	require.Equal(t, "beta", pkg.DefaultChannelName)
+	require.Equal(t, []registry.PackageChannel{{Name: "beta", CurrentCSVName: "etcdoperator.v0.9.0"}}, pkg.Channels)
+
+	// etcdoperator.v0.9.2 was only in the dropped channels
+	_, err = store.GetBundle(context.TODO(), "etcd", "stable", "etcdoperator.v0.9.2")
+	require.Error(t, err)
+	bundles, err := store.GetBundlesForPackage(context.TODO(), "etcd")
+	require.NoError(t, err)
+	require.Len(t, bundles, 2)
+
+	var deprecated int
+	require.NoError(t, db.QueryRow(`SELECT COUNT(*) FROM properties WHERE type = ? AND operatorbundle_name = ?`, registry.DeprecatedType, "etcdoperator.v0.6.1").Scan(&deprecated))
+	require.Equal(t, 1, deprecated)
+
+	// unknown packages and channels are rejected
+	require.Error(t, ApplyOverlay(context.TODO(), db, &Overlay{Packages: []PackageOverlay{{Name: "missing"}}}))
+	require.Error(t, ApplyOverlay(context.TODO(), db, &Overlay{Packages: []PackageOverlay{{Name: "etcd", DefaultChannel: "alpha"}}}))
+}
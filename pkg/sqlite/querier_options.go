@@ -0,0 +1,114 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// QuerierOptions tunes the pool of read-only connections NewSQLLiteQuerier reads a database with. Unset options keep
+// the defaults of database/sql and SQLite.
+type QuerierOptions struct {
+	// MaxOpenConns, if set, bounds how many connections are open at once. Concurrent queries each run on a connection
+	// of their own, so a bound lower than the number of concurrent queries makes the others wait for one.
+	MaxOpenConns int
+
+	// MaxIdleConns, if set, is how many connections are kept open between queries, 2 otherwise. Connections beyond it
+	// are closed once their query is done, and opened again, with their statements prepared again, for the next one.
+	MaxIdleConns int
+
+	// BusyTimeout, if set, is how long a query waits for a lock on the database before it fails as busy
+	BusyTimeout time.Duration
+
+	// MmapSize, if set, is how many bytes of the database each connection maps into memory and reads from there
+	// rather than with read calls, see https://www.sqlite.org/mmap.html
+	MmapSize int64
+}
+
+type QuerierOption func(*QuerierOptions)
+
+// WithMaxOpenConns bounds how many connections the querier has open at once
+func WithMaxOpenConns(n int) QuerierOption {
+	return func(o *QuerierOptions) {
+		o.MaxOpenConns = n
+	}
+}
+
+// WithMaxIdleConns sets how many connections the querier keeps open between queries, e.g. as many as the queries it
+// is expected to serve at once
+func WithMaxIdleConns(n int) QuerierOption {
+	return func(o *QuerierOptions) {
+		o.MaxIdleConns = n
+	}
+}
+
+// WithQuerierBusyTimeout sets how long the queries of the querier wait for a lock on the database
+func WithQuerierBusyTimeout(timeout time.Duration) QuerierOption {
+	return func(o *QuerierOptions) {
+		o.BusyTimeout = timeout
+	}
+}
+
+// WithMmapSize sets how many bytes of the database each connection of the querier maps into memory, e.g. the size of
+// the database for a large catalog read by many connections at once
+func WithMmapSize(size int64) QuerierOption {
+	return func(o *QuerierOptions) {
+		o.MmapSize = size
+	}
+}
+
+func (o *QuerierOptions) validate() error {
+	switch {
+	case o.MaxOpenConns < 0:
+		return fmt.Errorf("invalid max open connections %d, must not be negative", o.MaxOpenConns)
+	case o.MaxIdleConns < 0:
+		return fmt.Errorf("invalid max idle connections %d, must not be negative", o.MaxIdleConns)
+	case o.BusyTimeout < 0:
+		return fmt.Errorf("invalid busy timeout %s, must not be negative", o.BusyTimeout)
+	case o.MmapSize < 0:
+		return fmt.Errorf("invalid mmap size %d, must not be negative", o.MmapSize)
+	}
+	return nil
+}
+
+// connector returns the connector of the read-only connections to a database the options ask for
+func (o *QuerierOptions) connector(dbFilename string) driver.Connector {
+	dsn := "file:" + dbFilename + "?immutable=true"
+	if o.BusyTimeout > 0 {
+		dsn += "&_busy_timeout=" + strconv.FormatInt(o.BusyTimeout.Milliseconds(), 10)
+	}
+	c := &pragmaConnector{dsn: dsn}
+	if o.MmapSize > 0 {
+		c.pragmas = append(c.pragmas, "PRAGMA mmap_size = "+strconv.FormatInt(o.MmapSize, 10))
+	}
+	return c
+}
+
+// pragmaConnector opens connections to a database and runs pragmas on each of them, for the settings SQLite keeps
+// per connection that can't be set in the data source name
+type pragmaConnector struct {
+	dsn     string
+	pragmas []string
+}
+
+func (c *pragmaConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.Driver().Open(c.dsn)
+	if err != nil {
+		return nil, err
+	}
+	for _, pragma := range c.pragmas {
+		if _, err := conn.(*sqlite3.SQLiteConn).Exec(pragma, nil); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	return conn, nil
+}
+
+func (c *pragmaConnector) Driver() driver.Driver {
+	return &sqlite3.SQLiteDriver{}
+}
@@ -0,0 +1,56 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewSQLLiteQuerierOptions(t *testing.T) {
+	f, err := ioutil.TempFile("", "querier-*.db")
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+	defer os.Remove(f.Name())
+	db, err := sql.Open("sqlite3", f.Name())
+	require.NoError(t, err)
+	load, err := NewSQLLiteLoader(db)
+	require.NoError(t, err)
+	require.NoError(t, load.Migrate(context.TODO()))
+	require.NoError(t, NewSQLLoaderForDirectory(load, "../../manifests").Populate())
+	require.NoError(t, db.Close())
+
+	querier, err := NewSQLLiteQuerier(f.Name(), WithMaxOpenConns(2), WithMaxIdleConns(2), WithQuerierBusyTimeout(3*time.Second), WithMmapSize(1<<20))
+	require.NoError(t, err)
+	defer querier.Close()
+	packages, err := querier.ListPackages(context.TODO())
+	require.NoError(t, err)
+	require.ElementsMatch(t, []string{"etcd", "prometheus", "strimzi-kafka-operator"}, packages)
+
+	// every connection of the pool is tuned, not only the first one
+	pool := querier.db.(*stmtCache).db
+	require.Equal(t, 2, pool.Stats().MaxOpenConnections)
+	var conns []*sql.Conn
+	for i := 0; i < 2; i++ {
+		conn, err := pool.Conn(context.TODO())
+		require.NoError(t, err)
+		defer conn.Close()
+		conns = append(conns, conn)
+	}
+	for _, conn := range conns {
+		var busyTimeout, mmapSize int64
+		require.NoError(t, conn.QueryRowContext(context.TODO(), "PRAGMA busy_timeout").Scan(&busyTimeout))
+		require.Equal(t, int64(3000), busyTimeout)
+		require.NoError(t, conn.QueryRowContext(context.TODO(), "PRAGMA mmap_size").Scan(&mmapSize))
+		require.Equal(t, int64(1<<20), mmapSize)
+	}
+
+	for _, opt := range []QuerierOption{WithMaxOpenConns(-1), WithMaxIdleConns(-1), WithQuerierBusyTimeout(-time.Second), WithMmapSize(-1)} {
+		_, err := NewSQLLiteQuerier(f.Name(), opt)
+		require.Error(t, err)
+	}
+}
@@ -7,18 +7,25 @@ import (
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"regexp"
+	"sort"
 	"strings"
+	"unicode"
 
+	"github.com/blang/semver"
 	_ "github.com/mattn/go-sqlite3"
 
 	"github.com/operator-framework/operator-registry/pkg/api"
 	"github.com/operator-framework/operator-registry/pkg/registry"
+	"github.com/operator-framework/operator-registry/pkg/sqlite/migrations"
 )
 
 type RowScanner interface {
 	Next() bool
 	Close() error
 	Scan(dest ...interface{}) error
+	// Err returns the error, if any, that ended the iteration over the rows early
+	Err() error
 }
 
 type Querier interface {
@@ -35,44 +42,81 @@ func (a dbQuerierAdapter) QueryContext(ctx context.Context, query string, args .
 
 type SQLQuerier struct {
 	db Querier
+
+	// close releases the statements the querier prepared, and the database if the querier opened it
+	close func() error
 }
 
 var _ registry.Query = &SQLQuerier{}
 
-func NewSQLLiteQuerier(dbFilename string) (*SQLQuerier, error) {
-	db, err := sql.Open("sqlite3", "file:"+dbFilename+"?immutable=true")
-	if err != nil {
+// NewSQLLiteQuerier opens the database at dbFilename read-only, with a pool of connections tuned by the options. Its
+// queries are prepared once and reused, and the database is closed along with the querier.
+func NewSQLLiteQuerier(dbFilename string, opts ...QuerierOption) (*SQLQuerier, error) {
+	options := &QuerierOptions{}
+	for _, o := range opts {
+		o(options)
+	}
+	if err := options.validate(); err != nil {
 		return nil, err
 	}
+	db := sql.OpenDB(options.connector(dbFilename))
+	if options.MaxOpenConns > 0 {
+		db.SetMaxOpenConns(options.MaxOpenConns)
+	}
+	if options.MaxIdleConns > 0 {
+		db.SetMaxIdleConns(options.MaxIdleConns)
+	}
 
-	return &SQLQuerier{dbQuerierAdapter{db}}, nil
+	stmts := newStmtCache(db)
+	return &SQLQuerier{db: stmts, close: func() error {
+		err := stmts.Close()
+		if cerr := db.Close(); cerr != nil && err == nil {
+			err = cerr
+		}
+		return err
+	}}, nil
 }
 
 func NewSQLLiteQuerierFromDb(db *sql.DB) *SQLQuerier {
-	return &SQLQuerier{dbQuerierAdapter{db}}
+	return &SQLQuerier{db: dbQuerierAdapter{db}}
+}
+
+// NewSQLLiteQuerierFromDbWithStmtCache returns a querier that prepares each of its queries once and reuses the
+// statements, for long-lived queriers such as those serving a database. Closing the querier finalizes the statements
+// and leaves the database open.
+func NewSQLLiteQuerierFromDbWithStmtCache(db *sql.DB) *SQLQuerier {
+	stmts := newStmtCache(db)
+	return &SQLQuerier{db: stmts, close: stmts.Close}
 }
 
 func NewSQLLiteQuerierFromDBQuerier(q Querier) *SQLQuerier {
-	return &SQLQuerier{q}
+	return &SQLQuerier{db: q}
 }
 
-func (s *SQLQuerier) ListTables(ctx context.Context) ([]string, error) {
-	query := "SELECT name FROM sqlite_master WHERE type='table' ORDER BY name;"
-	rows, err := s.db.QueryContext(ctx, query)
-	if err != nil {
-		return nil, err
+// Close finalizes the statements the querier prepared, and closes the database if the querier opened it. The querier
+// must not be used afterwards.
+func (s *SQLQuerier) Close() error {
+	if s.close == nil {
+		return nil
 	}
-	defer rows.Close()
+	return s.close()
+}
 
+func (s *SQLQuerier) ListTables(ctx context.Context) ([]string, error) {
+	query := "SELECT name FROM sqlite_master WHERE type='table' ORDER BY name;"
 	tables := []string{}
-	for rows.Next() {
+	err := s.forEachRow(ctx, "tables", query, nil, func(row scanner) error {
 		var tableName sql.NullString
-		if err := rows.Scan(&tableName); err != nil {
-			return nil, err
+		if err := row.Scan(&tableName); err != nil {
+			return err
 		}
 		if tableName.Valid {
 			tables = append(tables, tableName.String)
 		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 	return tables, nil
 }
@@ -80,80 +124,380 @@ func (s *SQLQuerier) ListTables(ctx context.Context) ([]string, error) {
 // ListPackages returns a list of package names as strings
 func (s *SQLQuerier) ListPackages(ctx context.Context) ([]string, error) {
 	query := "SELECT DISTINCT name FROM package"
-	rows, err := s.db.QueryContext(ctx, query)
+	packages := []string{}
+	err := s.forEachRow(ctx, "packages", query, nil, func(row scanner) error {
+		var pkgName sql.NullString
+		if err := row.Scan(&pkgName); err != nil {
+			return err
+		}
+		if pkgName.Valid {
+			packages = append(packages, pkgName.String)
+		}
+		return nil
+	})
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
+	return packages, nil
+}
 
+// ListPackagesPaged returns, in order, up to limit names of the packages that sort after the given name
+func (s *SQLQuerier) ListPackagesPaged(ctx context.Context, after string, limit int) ([]string, error) {
+	if limit <= 0 {
+		// a negative limit has no upper bound in sqlite
+		limit = -1
+	}
+	query := "SELECT name FROM package WHERE name > ? ORDER BY name LIMIT ?"
 	packages := []string{}
-	for rows.Next() {
+	err := s.forEachRow(ctx, "packages", query, []interface{}{after, limit}, func(row scanner) error {
 		var pkgName sql.NullString
-		if err := rows.Scan(&pkgName); err != nil {
-			return nil, err
+		if err := row.Scan(&pkgName); err != nil {
+			return err
 		}
 		if pkgName.Valid {
 			packages = append(packages, pkgName.String)
 		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 	return packages, nil
 }
 
-func (s *SQLQuerier) GetPackage(ctx context.Context, name string) (*registry.PackageManifest, error) {
-	query := `SELECT DISTINCT package.name, default_channel, channel.name, channel.head_operatorbundle_name
-              FROM package INNER JOIN channel ON channel.package_name=package.name
-              WHERE package.name=?`
-	rows, err := s.db.QueryContext(ctx, query, name)
+// ListPackagesByProvider returns the names of all packages containing a bundle published by the given provider
+func (s *SQLQuerier) ListPackagesByProvider(ctx context.Context, provider string) ([]string, error) {
+	query := `SELECT DISTINCT channel_entry.package_name FROM channel_entry
+	INNER JOIN operatorbundle ON operatorbundle.name = channel_entry.operatorbundle_name
+	WHERE operatorbundle.provider = ?`
+	packages := []string{}
+	err := s.forEachRow(ctx, "packages", query, []interface{}{provider}, func(row scanner) error {
+		var pkgName sql.NullString
+		if err := row.Scan(&pkgName); err != nil {
+			return err
+		}
+		if pkgName.Valid {
+			packages = append(packages, pkgName.String)
+		}
+		return nil
+	})
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
+	return packages, nil
+}
 
-	var pkgName sql.NullString
-	var defaultChannel sql.NullString
-	var channelName sql.NullString
-	var bundleName sql.NullString
-	if !rows.Next() {
-		return nil, fmt.Errorf("package %s not found", name)
+// GetOwnershipForBundle returns the provider, maintainers and links recorded for a bundle
+func (s *SQLQuerier) GetOwnershipForBundle(ctx context.Context, bundleName string) (*registry.Ownership, error) {
+	query := `SELECT provider, maintainers, links FROM operatorbundle WHERE name = ? LIMIT 1`
+	var provider sql.NullString
+	var maintainers sql.NullString
+	var links sql.NullString
+	found, err := s.firstRow(ctx, "bundle ownership", query, []interface{}{bundleName}, &provider, &maintainers, &links)
+	if err != nil {
+		return nil, err
 	}
-	if err := rows.Scan(&pkgName, &defaultChannel, &channelName, &bundleName); err != nil {
+	if !found {
+		return nil, registry.BundleNotFoundf("bundle %s not found", bundleName)
+	}
+
+	out := &registry.Ownership{Provider: provider.String}
+	if maintainers.Valid && maintainers.String != "" {
+		if err := json.Unmarshal([]byte(maintainers.String), &out.Maintainers); err != nil {
+			return nil, err
+		}
+	}
+	if links.Valid && links.String != "" {
+		if err := json.Unmarshal([]byte(links.String), &out.Links); err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+// GetBundleDigest returns the content digest recorded for the bundle loaded from the given image
+func (s *SQLQuerier) GetBundleDigest(ctx context.Context, image string) (string, error) {
+	query := `SELECT digest FROM operatorbundle WHERE bundlepath = ? LIMIT 1`
+	var digest sql.NullString
+	found, err := s.firstRow(ctx, "bundle digest", query, []interface{}{image}, &digest)
+	if err != nil {
+		return "", err
+	}
+	if !found {
+		return "", registry.ErrBundleImageNotInDatabase
+	}
+	return digest.String, nil
+}
+
+// GetBundleImage returns the image a bundle was loaded from, with the digest recorded when it was pulled. Images
+// pulled by digest carry their own digest.
+func (s *SQLQuerier) GetBundleImage(ctx context.Context, bundleName string) (*registry.BundleImage, error) {
+	query := `SELECT operatorbundle.bundlepath, bundle_image.digest
+			  FROM operatorbundle
+			  LEFT OUTER JOIN bundle_image ON bundle_image.operatorbundle_name = operatorbundle.name AND bundle_image.operatorbundle_path = operatorbundle.bundlepath
+			  WHERE operatorbundle.name = ? LIMIT 1`
+	var bundlePath sql.NullString
+	var digest sql.NullString
+	found, err := s.firstRow(ctx, "bundle image", query, []interface{}{bundleName}, &bundlePath, &digest)
+	if err != nil {
 		return nil, err
 	}
-	pkg := &registry.PackageManifest{
-		PackageName:        pkgName.String,
-		DefaultChannelName: defaultChannel.String,
-		Channels: []registry.PackageChannel{
-			{
-				Name:           channelName.String,
-				CurrentCSVName: bundleName.String,
-			},
-		},
+	if !found {
+		return nil, registry.BundleNotFoundf("bundle %s not found", bundleName)
+	}
+	if bundlePath.String == "" {
+		return nil, fmt.Errorf("bundle %s was not loaded from an image", bundleName)
+	}
+
+	image := &registry.BundleImage{Image: bundlePath.String, Digest: digest.String}
+	if i := strings.LastIndex(image.Image, "@"); i >= 0 && image.Digest == "" {
+		image.Digest = image.Image[i+1:]
+	}
+	return image, nil
+}
+
+// GetPackageForAlias returns the current name of a package that was renamed from alias
+func (s *SQLQuerier) GetPackageForAlias(ctx context.Context, alias string) (string, error) {
+	query := `SELECT package_name FROM package_alias WHERE alias = ?`
+	var name sql.NullString
+	found, err := s.firstRow(ctx, "package alias", query, []interface{}{alias}, &name)
+	if err != nil {
+		return "", err
+	}
+	if !found {
+		return "", registry.ErrPackageNotInDatabase
+	}
+	return name.String, nil
+}
+
+// GetPackageID returns the stable id of a package. Packages loaded before ids were stored have the id
+// derived from their name.
+func (s *SQLQuerier) GetPackageID(ctx context.Context, name string) (string, error) {
+	id, err := s.getStableID(ctx, migrations.StableIDKindPackage, name)
+	if err != nil || id != "" {
+		return id, err
+	}
+	return registry.PackageID(name), nil
+}
+
+// GetBundleID returns the stable id of a bundle. Bundles loaded before ids were stored have the id
+// derived from their name.
+func (s *SQLQuerier) GetBundleID(ctx context.Context, csvName string) (string, error) {
+	id, err := s.getStableID(ctx, migrations.StableIDKindBundle, csvName)
+	if err != nil || id != "" {
+		return id, err
+	}
+	return registry.BundleID(csvName), nil
+}
+
+// bundleIDJoin joins the stable id of the bundle of each channel entry, so that lists of bundles don't look up their
+// ids one by one. It takes the kind of the id as its argument.
+const bundleIDJoin = `LEFT OUTER JOIN stable_id ON stable_id.kind = ? AND stable_id.name = channel_entry.operatorbundle_name`
+
+// bundleID returns the stable id joined for a bundle, or the id derived from its name if it has none stored
+func bundleID(id sql.NullString, csvName string) string {
+	if id.String != "" {
+		return id.String
+	}
+	return registry.BundleID(csvName)
+}
+
+func (s *SQLQuerier) getStableID(ctx context.Context, kind, name string) (string, error) {
+	var id sql.NullString
+	if _, err := s.firstRow(ctx, "stable id", `SELECT id FROM stable_id WHERE kind = ? AND name = ?`, []interface{}{kind, name}, &id); err != nil {
+		return "", err
+	}
+	return id.String, nil
+}
+
+// GetDisplayMetadataForBundle returns the display name and description of a bundle, translated into
+// the requested locale where the bundle carries a translation
+func (s *SQLQuerier) GetDisplayMetadataForBundle(ctx context.Context, bundleName, locale string) (*registry.DisplayMetadata, error) {
+	query := `SELECT csv FROM operatorbundle WHERE name = ? LIMIT 1`
+	var csvJson sql.NullString
+	found, err := s.firstRow(ctx, "bundle csv", query, []interface{}{bundleName}, &csvJson)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, registry.BundleNotFoundf("bundle %s not found", bundleName)
 	}
 
-	for rows.Next() {
-		if err := rows.Scan(&pkgName, &defaultChannel, &channelName, &bundleName); err != nil {
+	var defaults registry.DisplayMetadata
+	if csvJson.Valid && csvJson.String != "" {
+		csv := registry.ClusterServiceVersion{}
+		if err := json.Unmarshal([]byte(csvJson.String), &csv); err != nil {
+			return nil, err
+		}
+		if defaults.DisplayName, err = csv.GetDisplayName(); err != nil {
 			return nil, err
 		}
+		if defaults.Description, err = csv.GetDescription(); err != nil {
+			return nil, err
+		}
+	}
+
+	translationQuery := `SELECT value FROM properties WHERE type = ? AND operatorbundle_name = ?`
+	var translations []registry.LocalizedProperty
+	err = s.forEachRow(ctx, "bundle translations", translationQuery, []interface{}{registry.LocalizedType, bundleName}, func(row scanner) error {
+		var value sql.NullString
+		if err := row.Scan(&value); err != nil {
+			return err
+		}
+		var translation registry.LocalizedProperty
+		if err := json.Unmarshal([]byte(value.String), &translation); err != nil {
+			return err
+		}
+		translations = append(translations, translation)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	out := registry.LocalizeDisplayMetadata(defaults, translations, locale)
+	return &out, nil
+}
+
+// GetChannelEntriesForPlatformVersion returns the channel entries of a package whose bundles support the given
+// platform version. Bundles that declare no platform version range are supported on every version.
+func (s *SQLQuerier) GetChannelEntriesForPlatformVersion(ctx context.Context, packageName, platformVersion string) ([]registry.ChannelEntryAnnotated, error) {
+	version, err := registry.ParsePlatformVersion(platformVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := s.GetChannelEntriesFromPackage(ctx, packageName)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `SELECT DISTINCT operatorbundle_name, value FROM properties
+			  WHERE type = ? AND operatorbundle_name IN (SELECT operatorbundle_name FROM channel_entry WHERE package_name = ?)`
+	// a bundle that declares several ranges supports their union
+	supported := map[string]bool{}
+	err = s.forEachRow(ctx, "bundle platform versions", query, []interface{}{registry.PlatformType, packageName}, func(row scanner) error {
+		var bundleName sql.NullString
+		var value sql.NullString
+		if err := row.Scan(&bundleName, &value); err != nil {
+			return err
+		}
+		var platform registry.PlatformVersionsProperty
+		if err := json.Unmarshal([]byte(value.String), &platform); err != nil {
+			return err
+		}
+		ok, err := platform.Supports(version)
+		if err != nil {
+			return fmt.Errorf("invalid platform version range for bundle %s: %s", bundleName.String, err)
+		}
+		supported[bundleName.String] = supported[bundleName.String] || ok
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var out []registry.ChannelEntryAnnotated
+	for _, entry := range entries {
+		if ok, declared := supported[entry.BundleName]; declared && !ok {
+			continue
+		}
+		out = append(out, entry)
+	}
+	return out, nil
+}
+
+// GetBundleAttestations returns the verification evidence recorded for a bundle
+func (s *SQLQuerier) GetBundleAttestations(ctx context.Context, bundleName string) ([]*registry.Attestation, error) {
+	query := `SELECT certificate_identity, certificate_issuer, rekor_log_index FROM bundle_attestation WHERE operatorbundle_name = ?`
+	attestations := []*registry.Attestation{}
+	err := s.forEachRow(ctx, "bundle attestations", query, []interface{}{bundleName}, func(row scanner) error {
+		var identity sql.NullString
+		var issuer sql.NullString
+		var logIndex sql.NullInt64
+		if err := row.Scan(&identity, &issuer, &logIndex); err != nil {
+			return err
+		}
+		attestations = append(attestations, &registry.Attestation{
+			CertificateIdentity: identity.String,
+			CertificateIssuer:   issuer.String,
+			RekorLogIndex:       logIndex.Int64,
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return attestations, nil
+}
+
+func (s *SQLQuerier) GetPackage(ctx context.Context, name string) (*registry.PackageManifest, error) {
+	query := `SELECT DISTINCT package.name, default_channel, channel.name, channel.head_operatorbundle_name
+              FROM package INNER JOIN channel ON channel.package_name=package.name
+              WHERE package.name=?`
+	var pkg *registry.PackageManifest
+	err := s.forEachRow(ctx, "package", query, []interface{}{name}, func(row scanner) error {
+		var pkgName sql.NullString
+		var defaultChannel sql.NullString
+		var channelName sql.NullString
+		var bundleName sql.NullString
+		if err := row.Scan(&pkgName, &defaultChannel, &channelName, &bundleName); err != nil {
+			return err
+		}
+		if pkg == nil {
+			pkg = &registry.PackageManifest{
+				PackageName:        pkgName.String,
+				DefaultChannelName: defaultChannel.String,
+			}
+		}
 		pkg.Channels = append(pkg.Channels, registry.PackageChannel{Name: channelName.String, CurrentCSVName: bundleName.String})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if pkg == nil {
+		return nil, registry.PackageNotFoundf("package %s not found", name)
 	}
 	return pkg, nil
 }
 
+// ListChannels returns every channel of a package, ordered by name, with the name of the bundle at its head.
+// Channels whose head is deprecated are still listed, marked Deprecated.
+func (s *SQLQuerier) ListChannels(ctx context.Context, pkgName string) ([]registry.PackageChannel, error) {
+	query := `SELECT name, head_operatorbundle_name, ` + isDeprecated("head_operatorbundle_name") + ` FROM channel WHERE package_name=? ORDER BY name`
+	found := false
+	channels := []registry.PackageChannel{}
+	err := s.forEachRow(ctx, "channel heads", query, []interface{}{pkgName}, func(row scanner) error {
+		var channelName sql.NullString
+		var bundleName sql.NullString
+		var deprecated bool
+		if err := row.Scan(&channelName, &bundleName, &deprecated); err != nil {
+			return err
+		}
+		found = true
+		channels = append(channels, registry.PackageChannel{Name: channelName.String, CurrentCSVName: bundleName.String, Deprecated: deprecated})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, registry.PackageNotFoundf("package %s not found", pkgName)
+	}
+	return channels, nil
+}
+
 func (s *SQLQuerier) GetDefaultPackage(ctx context.Context, name string) (string, error) {
 	query := `SELECT default_channel
               FROM package WHERE package.name=?`
-	rows, err := s.db.QueryContext(ctx, query, name)
+	var defaultChannel sql.NullString
+	found, err := s.firstRow(ctx, "default channel", query, []interface{}{name}, &defaultChannel)
 	if err != nil {
 		return "", err
 	}
-	defer rows.Close()
-
-	var defaultChannel sql.NullString
-	if !rows.Next() {
-		return "", fmt.Errorf("package %s not found", name)
-	}
-	if err := rows.Scan(&defaultChannel); err != nil {
-		return "", err
+	if !found {
+		return "", registry.PackageNotFoundf("package %s not found", name)
 	}
 
 	if !defaultChannel.Valid {
@@ -172,12 +516,6 @@ func (s *SQLQuerier) GetChannelEntriesFromPackage(ctx context.Context, packageNa
               WHERE channel_entry.package_name = ?;`
 
 	var entries []registry.ChannelEntryAnnotated
-	rows, err := s.db.QueryContext(ctx, query, packageName)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
 	var pkgName sql.NullString
 	var channelName sql.NullString
 	var bundleName sql.NullString
@@ -187,9 +525,9 @@ func (s *SQLQuerier) GetChannelEntriesFromPackage(ctx context.Context, packageNa
 	var replacesVersion sql.NullString
 	var replacesBundlePath sql.NullString
 
-	for rows.Next() {
-		if err := rows.Scan(&pkgName, &channelName, &bundleName, &version, &bundlePath, &replaces, &replacesVersion, &replacesBundlePath); err != nil {
-			return nil, err
+	err := s.forEachRow(ctx, "channel entries", query, []interface{}{packageName}, func(row scanner) error {
+		if err := row.Scan(&pkgName, &channelName, &bundleName, &version, &bundlePath, &replaces, &replacesVersion, &replacesBundlePath); err != nil {
+			return err
 		}
 
 		channelEntryNode := registry.ChannelEntryAnnotated{
@@ -204,33 +542,474 @@ func (s *SQLQuerier) GetChannelEntriesFromPackage(ctx context.Context, packageNa
 		}
 
 		entries = append(entries, channelEntryNode)
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	return entries, nil
 }
 
-func (s *SQLQuerier) GetBundle(ctx context.Context, pkgName, channelName, csvName string) (*api.Bundle, error) {
-	query := `SELECT DISTINCT channel_entry.entry_id, operatorbundle.name, operatorbundle.bundle, operatorbundle.bundlepath, operatorbundle.version, operatorbundle.skiprange
-			  FROM operatorbundle INNER JOIN channel_entry ON operatorbundle.name=channel_entry.operatorbundle_name
-              WHERE channel_entry.package_name=? AND channel_entry.channel_name=? AND operatorbundle_name=? LIMIT 1`
-	rows, err := s.db.QueryContext(ctx, query, pkgName, channelName, csvName)
+// imageDigest matches an image digest on its own, without the repository it was pulled from
+var imageDigest = regexp.MustCompile(`^[a-z0-9]+(?:[.+_-][a-z0-9]+)*:[a-zA-Z0-9=_-]+$`)
+
+// GetEntriesByBundleImage returns the channel entries of the bundles loaded from an image. The image is either
+// a reference, matched exactly, or a digest. A digest, alone or as part of a reference, also matches the bundles
+// loaded from the same digest in any other repository, such as a mirror.
+func (s *SQLQuerier) GetEntriesByBundleImage(ctx context.Context, imageRefOrDigest string) ([]registry.ChannelEntryAnnotated, error) {
+	if imageRefOrDigest == "" {
+		return nil, fmt.Errorf("no image reference or digest given")
+	}
+
+	var byDigest string
+	if i := strings.LastIndex(imageRefOrDigest, "@"); i >= 0 {
+		byDigest = imageRefOrDigest[i+1:]
+	} else if imageDigest.MatchString(imageRefOrDigest) {
+		byDigest = imageRefOrDigest
+	}
+
+	query := `SELECT DISTINCT channel_entry.package_name, channel_entry.channel_name, channel_entry.operatorbundle_name, op_bundle.version, op_bundle.bundlepath, replaces.operatorbundle_name, replacesbundle.version, replacesbundle.bundlepath
+			  FROM operatorbundle op_bundle
+			  INNER JOIN channel_entry ON channel_entry.operatorbundle_name = op_bundle.name
+			  LEFT JOIN channel_entry replaces ON channel_entry.replaces = replaces.entry_id
+			  LEFT JOIN operatorbundle replacesbundle ON replaces.operatorbundle_name = replacesbundle.name
+			  WHERE op_bundle.bundlepath = ? OR (? != '' AND op_bundle.bundlepath LIKE ?)
+			  ORDER BY channel_entry.package_name, channel_entry.channel_name, channel_entry.operatorbundle_name`
+
+	var entries []registry.ChannelEntryAnnotated
+	err := s.forEachRow(ctx, "entries by bundle image", query, []interface{}{imageRefOrDigest, byDigest, "%@" + byDigest}, func(row scanner) error {
+		var pkgName, channelName, bundleName, version, bundlePath, replaces, replacesVersion, replacesBundlePath sql.NullString
+		if err := row.Scan(&pkgName, &channelName, &bundleName, &version, &bundlePath, &replaces, &replacesVersion, &replacesBundlePath); err != nil {
+			return err
+		}
+		entries = append(entries, registry.ChannelEntryAnnotated{
+			PackageName:        pkgName.String,
+			ChannelName:        channelName.String,
+			BundleName:         bundleName.String,
+			Version:            version.String,
+			BundlePath:         bundlePath.String,
+			Replaces:           replaces.String,
+			ReplacesVersion:    replacesVersion.String,
+			ReplacesBundlePath: replacesBundlePath.String,
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// GetPresentBundles returns, in the order given, the bundles that are in the database. A bundle is named by its
+// CSV name, the image it was loaded from or the digest of that image, matched as in GetEntriesByBundleImage.
+func (s *SQLQuerier) GetPresentBundles(ctx context.Context, bundles []string) ([]string, error) {
+	known := map[string]struct{}{}
+	err := s.forEachRow(ctx, "bundles", `SELECT name, bundlepath FROM operatorbundle`, nil, func(row scanner) error {
+		var name, bundlePath sql.NullString
+		if err := row.Scan(&name, &bundlePath); err != nil {
+			return err
+		}
+		known[name.String] = struct{}{}
+		if bundlePath.String == "" {
+			return nil
+		}
+		known[bundlePath.String] = struct{}{}
+		if i := strings.LastIndex(bundlePath.String, "@"); i >= 0 {
+			known[bundlePath.String[i+1:]] = struct{}{}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var present []string
+	for _, b := range bundles {
+		_, ok := known[b]
+		if !ok {
+			// the digest of a reference is present if it was loaded from any repository
+			if i := strings.LastIndex(b, "@"); i >= 0 {
+				_, ok = known[b[i+1:]]
+			}
+		}
+		if ok && b != "" {
+			present = append(present, b)
+		}
+	}
+	return present, nil
+}
+
+// GetPackageStats returns the number of bundles, the channels, the latest version and the size of the bundles of the
+// given packages, or of every package if none are given, sorted by name. Packages that aren't in the database are left
+// out.
+func (s *SQLQuerier) GetPackageStats(ctx context.Context, packages []string) ([]*registry.PackageStats, error) {
+	query := `SELECT package.name, package.default_channel,
+		(SELECT COUNT(DISTINCT operatorbundle.name) FROM operatorbundle
+		 INNER JOIN channel_entry ON channel_entry.operatorbundle_name = operatorbundle.name
+		 WHERE channel_entry.package_name = package.name),
+		(SELECT GROUP_CONCAT(channel.name) FROM channel WHERE channel.package_name = package.name),
+		(SELECT GROUP_CONCAT(DISTINCT operatorbundle.version) FROM operatorbundle
+		 INNER JOIN channel_entry ON channel_entry.operatorbundle_name = operatorbundle.name
+		 WHERE channel_entry.package_name = package.name),
+		(SELECT SUM(bundle_stats.size) FROM bundle_stats
+		 WHERE bundle_stats.operatorbundle_name IN (SELECT operatorbundle_name FROM channel_entry WHERE channel_entry.package_name = package.name)),
+		(SELECT bundle_stats.operatorbundle_name FROM bundle_stats
+		 WHERE bundle_stats.operatorbundle_name IN (SELECT operatorbundle_name FROM channel_entry WHERE channel_entry.package_name = package.name)
+		 ORDER BY bundle_stats.size DESC, bundle_stats.operatorbundle_name LIMIT 1),
+		(SELECT MAX(bundle_stats.size) FROM bundle_stats
+		 WHERE bundle_stats.operatorbundle_name IN (SELECT operatorbundle_name FROM channel_entry WHERE channel_entry.package_name = package.name))
+		FROM package`
+	args := make([]interface{}, len(packages))
+	if len(packages) > 0 {
+		for i, p := range packages {
+			args[i] = p
+		}
+		query += ` WHERE package.name IN (?` + strings.Repeat(",?", len(packages)-1) + `)`
+	}
+	query += ` ORDER BY package.name`
+
+	stats := []*registry.PackageStats{}
+	err := s.forEachRow(ctx, "package stats", query, args, func(row scanner) error {
+		var name, defaultChannel, channels, versions, largestBundle sql.NullString
+		var bundleCount, totalSize, largestSize sql.NullInt64
+		if err := row.Scan(&name, &defaultChannel, &bundleCount, &channels, &versions, &totalSize, &largestBundle, &largestSize); err != nil {
+			return err
+		}
+		pkg := &registry.PackageStats{
+			Name:               name.String,
+			BundleCount:        int(bundleCount.Int64),
+			Channels:           []string{},
+			DefaultChannelName: defaultChannel.String,
+			TotalBundleSize:    totalSize.Int64,
+			LargestBundle:      largestBundle.String,
+			LargestBundleSize:  largestSize.Int64,
+		}
+		if channels.String != "" {
+			pkg.Channels = strings.Split(channels.String, ",")
+			sort.Strings(pkg.Channels)
+		}
+
+		// versions can't be ordered in sql, so the latest is picked here
+		var latest *semver.Version
+		for _, v := range strings.Split(versions.String, ",") {
+			version, err := semver.Parse(v)
+			if err != nil {
+				continue
+			}
+			if latest == nil || version.GT(*latest) {
+				latest = &version
+			}
+		}
+		if latest != nil {
+			pkg.LatestVersion = latest.String()
+		}
+		stats = append(stats, pkg)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+// GetBundleStats returns the size and object counts of the bundles of the given packages, or of every bundle if none
+// are given, largest first, and at most limit of them unless it is zero. A bundle in several packages is returned for
+// each; bundles loaded before their stats were recorded, whose manifests have since been cleared, are left out.
+func (s *SQLQuerier) GetBundleStats(ctx context.Context, packages []string, limit int) ([]*registry.BundleStats, error) {
+	query := `SELECT DISTINCT bundle_stats.operatorbundle_name, channel_entry.package_name, operatorbundle.version, operatorbundle.bundlepath,
+		bundle_stats.size, bundle_stats.object_count, bundle_stats.crd_count
+		FROM bundle_stats
+		INNER JOIN operatorbundle ON operatorbundle.name = bundle_stats.operatorbundle_name
+		LEFT OUTER JOIN channel_entry ON channel_entry.operatorbundle_name = bundle_stats.operatorbundle_name`
+	args := make([]interface{}, 0, len(packages)+1)
+	if len(packages) > 0 {
+		for _, p := range packages {
+			args = append(args, p)
+		}
+		query += ` WHERE channel_entry.package_name IN (?` + strings.Repeat(",?", len(packages)-1) + `)`
+	}
+	query += ` ORDER BY bundle_stats.size DESC, bundle_stats.operatorbundle_name, channel_entry.package_name`
+	if limit > 0 {
+		query += ` LIMIT ?`
+		args = append(args, limit)
+	}
+
+	stats := []*registry.BundleStats{}
+	err := s.forEachRow(ctx, "bundle stats", query, args, func(row scanner) error {
+		var name, pkg, version, bundlePath sql.NullString
+		var size, objects, crds sql.NullInt64
+		if err := row.Scan(&name, &pkg, &version, &bundlePath, &size, &objects, &crds); err != nil {
+			return err
+		}
+		stats = append(stats, &registry.BundleStats{
+			CsvName:     name.String,
+			PackageName: pkg.String,
+			Version:     version.String,
+			BundlePath:  bundlePath.String,
+			Size:        size.Int64,
+			ObjectCount: int(objects.Int64),
+			CRDCount:    int(crds.Int64),
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+// GetDefaultChannelHeads returns the head of the default channel of the given packages, or of every package if none
+// are given, sorted by package. Packages that aren't in the database are left out.
+func (s *SQLQuerier) GetDefaultChannelHeads(ctx context.Context, packages []string) ([]*registry.BundleSummary, error) {
+	query := `SELECT package.name, package.default_channel, operatorbundle.name, operatorbundle.version, operatorbundle.bundlepath, operatorbundle.replaces, operatorbundle.skips, operatorbundle.skiprange
+		FROM package
+		INNER JOIN channel ON channel.package_name = package.name AND channel.name = package.default_channel
+		INNER JOIN operatorbundle ON operatorbundle.name = channel.head_operatorbundle_name
+		WHERE ` + notDeprecated("operatorbundle.name")
+	args := make([]interface{}, len(packages))
+	if len(packages) > 0 {
+		for i, p := range packages {
+			args[i] = p
+		}
+		query += ` AND package.name IN (?` + strings.Repeat(",?", len(packages)-1) + `)`
+	}
+	query += ` ORDER BY package.name`
+
+	heads := []*registry.BundleSummary{}
+	err := s.forEachRow(ctx, "default channel heads", query, args, func(row scanner) error {
+		var pkgName, channelName, name, version, bundlePath, replaces, skips, skipRange sql.NullString
+		if err := row.Scan(&pkgName, &channelName, &name, &version, &bundlePath, &replaces, &skips, &skipRange); err != nil {
+			return err
+		}
+		heads = append(heads, &registry.BundleSummary{
+			PackageName: pkgName.String,
+			ChannelName: channelName.String,
+			CsvName:     name.String,
+			Version:     version.String,
+			BundlePath:  bundlePath.String,
+			Replaces:    replaces.String,
+			Skips:       splitSkips(skips),
+			SkipRange:   skipRange.String,
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return heads, nil
+}
+
+// SearchBundles returns the bundles of the given packages, or of every package if none are given, whose name, display
+// name, description or keywords contain every word of the query, best matches first. Words match as prefixes, so that
+// a search can be run as a user types. Databases without a full-text index, which SQLite builds without FTS5 can't
+// create or read, are searched for bundles that contain every word anywhere in their text instead, ordered by name.
+func (s *SQLQuerier) SearchBundles(ctx context.Context, query string, packages []string) ([]*registry.BundleSummary, error) {
+	bundles := []*registry.BundleSummary{}
+	words := strings.Fields(query)
+	if len(words) == 0 {
+		return bundles, nil
+	}
+	indexed, err := s.bundleSearchIndexed(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	searchQuery := `SELECT channel_entry.package_name, operatorbundle.name, operatorbundle.version, operatorbundle.bundlepath, operatorbundle.replaces, operatorbundle.skips, operatorbundle.skiprange`
+	var args []interface{}
+	var conditions []string
+	order := `operatorbundle.name`
+	if indexed {
+		searchQuery += `
+		FROM (SELECT name, rank FROM bundle_search WHERE bundle_search MATCH ?) AS matches
+		INNER JOIN operatorbundle ON operatorbundle.name = matches.name
+		INNER JOIN channel_entry ON channel_entry.operatorbundle_name = operatorbundle.name`
+		args = append(args, searchExpression(query))
+		order = `matches.rank, operatorbundle.name`
+	} else {
+		searchQuery += `
+		FROM operatorbundle
+		INNER JOIN channel_entry ON channel_entry.operatorbundle_name = operatorbundle.name`
+		// words are split where the full-text index would split them, so that punctuation, including the wildcards of
+		// LIKE, doesn't have to match
+		words = strings.FieldsFunc(query, func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) })
+		if len(words) == 0 {
+			return bundles, nil
+		}
+		for _, word := range words {
+			conditions = append(conditions, searchTextColumn+` LIKE ?`)
+			args = append(args, "%"+word+"%")
+		}
+	}
+	if len(packages) > 0 {
+		for _, p := range packages {
+			args = append(args, p)
+		}
+		conditions = append(conditions, `channel_entry.package_name IN (?`+strings.Repeat(",?", len(packages)-1)+`)`)
+	}
+	if len(conditions) > 0 {
+		searchQuery += `
+		WHERE ` + strings.Join(conditions, " AND ")
+	}
+	searchQuery += `
+		GROUP BY channel_entry.package_name, operatorbundle.name
+		ORDER BY ` + order
+	err = s.forEachRow(ctx, "bundle search", searchQuery, args, func(row scanner) error {
+		var pkgName, name, version, bundlePath, replaces, skips, skipRange sql.NullString
+		if err := row.Scan(&pkgName, &name, &version, &bundlePath, &replaces, &skips, &skipRange); err != nil {
+			return err
+		}
+		bundles = append(bundles, &registry.BundleSummary{
+			PackageName: pkgName.String,
+			CsvName:     name.String,
+			Version:     version.String,
+			BundlePath:  bundlePath.String,
+			Replaces:    replaces.String,
+			Skips:       splitSkips(skips),
+			SkipRange:   skipRange.String,
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return bundles, nil
+}
+
+// searchTextColumn is the searchable text of a bundle, for databases without a full-text index
+const searchTextColumn = `(operatorbundle.name || ' ' || CASE WHEN json_valid(operatorbundle.csv) THEN
+	coalesce(json_extract(operatorbundle.csv, '$.spec.displayName'), '') || ' ' ||
+	coalesce(json_extract(operatorbundle.csv, '$.metadata.annotations.description'), '') || ' ' ||
+	coalesce(json_extract(operatorbundle.csv, '$.spec.description'), '') || ' ' ||
+	coalesce(json_extract(operatorbundle.csv, '$.spec.keywords'), '') ELSE '' END)`
+
+// bundleSearchIndexed returns true if bundles can be searched with the full-text index of the database
+func (s *SQLQuerier) bundleSearchIndexed(ctx context.Context) (bool, error) {
+	var indexed bool
+	_, err := s.firstRow(ctx, "bundle search index", `SELECT EXISTS(SELECT 1 FROM sqlite_master WHERE type='table' AND name='bundle_search')
+		AND EXISTS(SELECT 1 FROM pragma_compile_options WHERE compile_options = 'ENABLE_FTS5')`, nil, &indexed)
+	return indexed, err
+}
+
+// searchExpression turns the words of a search into a full-text query that matches every word as a prefix. Words are
+// quoted, so that punctuation in them isn't taken as query syntax.
+func searchExpression(query string) string {
+	var terms []string
+	for _, word := range strings.Fields(query) {
+		terms = append(terms, `"`+strings.ReplaceAll(word, `"`, `""`)+`"*`)
+	}
+	return strings.Join(terms, " ")
+}
+
+// GetBundleSummary returns the name, version, upgrade graph and apis of a bundle in a channel. Unlike GetBundle, it
+// doesn't read the manifests of the bundle, which can be megabytes.
+func (s *SQLQuerier) GetBundleSummary(ctx context.Context, pkgName, channelName, csvName string) (*registry.BundleSummary, error) {
+	summaries, err := s.bundleSummaries(ctx, `WHERE channel_entry.package_name=? AND channel_entry.channel_name=? AND channel_entry.operatorbundle_name=?`, pkgName, channelName, csvName)
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
+	if len(summaries) == 0 {
+		return nil, registry.BundleNotFoundf("no entry found for %s %s %s", pkgName, channelName, csvName)
+	}
+	return summaries[0], nil
+}
 
-	if !rows.Next() {
-		return nil, fmt.Errorf("no entry found for %s %s %s", pkgName, channelName, csvName)
+// ListBundleSummaries returns the summary of every bundle in every channel it is in, without reading their manifests
+func (s *SQLQuerier) ListBundleSummaries(ctx context.Context) ([]*registry.BundleSummary, error) {
+	return s.bundleSummaries(ctx, "")
+}
+
+// ListBundleSummariesFiltered returns the summaries that ListBundleSummaries does, limited to the bundles the filter
+// selects
+func (s *SQLQuerier) ListBundleSummariesFiltered(ctx context.Context, filter registry.BundleFilter) ([]*registry.BundleSummary, error) {
+	where, args, err := s.bundleFilterClause(ctx, filter)
+	if err != nil {
+		return nil, err
 	}
+	return s.bundleSummaries(ctx, where, args...)
+}
+
+// bundleSummaries returns the summaries of the bundles in the channels selected by where, one per bundle and channel
+func (s *SQLQuerier) bundleSummaries(ctx context.Context, where string, args ...interface{}) ([]*registry.BundleSummary, error) {
+	query := `SELECT MIN(channel_entry.entry_id), channel_entry.package_name, channel_entry.channel_name, operatorbundle.name, operatorbundle.version, operatorbundle.bundlepath, operatorbundle.replaces, operatorbundle.skips, operatorbundle.skiprange, stable_id.id
+		FROM channel_entry
+		INNER JOIN operatorbundle ON operatorbundle.name = channel_entry.operatorbundle_name
+		` + bundleIDJoin + `
+		` + where + `
+		GROUP BY channel_entry.package_name, channel_entry.channel_name, operatorbundle.name
+		ORDER BY channel_entry.package_name, channel_entry.channel_name, operatorbundle.name`
+	type summaryEntry struct {
+		summary *registry.BundleSummary
+		entryID int64
+	}
+	var entries []summaryEntry
+	args = append([]interface{}{migrations.StableIDKindBundle}, args...)
+	err := s.forEachRow(ctx, "bundle summaries", query, args, func(row scanner) error {
+		var entryID sql.NullInt64
+		var pkgName, channelName, name, version, bundlePath, replaces, skips, skipRange, id sql.NullString
+		if err := row.Scan(&entryID, &pkgName, &channelName, &name, &version, &bundlePath, &replaces, &skips, &skipRange, &id); err != nil {
+			return err
+		}
+		entries = append(entries, summaryEntry{
+			summary: &registry.BundleSummary{
+				ID:          bundleID(id, name.String),
+				PackageName: pkgName.String,
+				ChannelName: channelName.String,
+				CsvName:     name.String,
+				Version:     version.String,
+				BundlePath:  bundlePath.String,
+				Replaces:    replaces.String,
+				Skips:       splitSkips(skips),
+				SkipRange:   skipRange.String,
+			},
+			entryID: entryID.Int64,
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	summaries := []*registry.BundleSummary{}
+	for _, e := range entries {
+		provided, required, err := s.GetApisForEntry(ctx, e.entryID)
+		if err != nil {
+			return nil, err
+		}
+		e.summary.ProvidedApis = apiKeys(provided)
+		e.summary.RequiredApis = apiKeys(required)
+		summaries = append(summaries, e.summary)
+	}
+	return summaries, nil
+}
+
+func apiKeys(gvks []*api.GroupVersionKind) []registry.APIKey {
+	var keys []registry.APIKey
+	for _, gvk := range gvks {
+		keys = append(keys, registry.APIKey{Group: gvk.Group, Version: gvk.Version, Kind: gvk.Kind, Plural: gvk.Plural})
+	}
+	return keys
+}
+
+func (s *SQLQuerier) GetBundle(ctx context.Context, pkgName, channelName, csvName string) (*api.Bundle, error) {
+	query := `SELECT DISTINCT channel_entry.entry_id, operatorbundle.name, operatorbundle.bundle, operatorbundle.bundlepath, operatorbundle.version, operatorbundle.skiprange, operatorbundle.replaces, operatorbundle.skips
+			  FROM operatorbundle INNER JOIN channel_entry ON operatorbundle.name=channel_entry.operatorbundle_name
+              WHERE channel_entry.package_name=? AND channel_entry.channel_name=? AND operatorbundle_name=? LIMIT 1`
 	var entryId sql.NullInt64
 	var name sql.NullString
 	var bundle sql.NullString
 	var bundlePath sql.NullString
 	var version sql.NullString
 	var skipRange sql.NullString
-	if err := rows.Scan(&entryId, &name, &bundle, &bundlePath, &version, &skipRange); err != nil {
+	var replaces sql.NullString
+	var skips sql.NullString
+	found, err := s.firstRow(ctx, "bundle", query, []interface{}{pkgName, channelName, csvName}, &entryId, &name, &bundle, &bundlePath, &version, &skipRange, &replaces, &skips)
+	if err != nil {
 		return nil, err
 	}
+	if !found {
+		return nil, registry.BundleNotFoundf("no entry found for %s %s %s", pkgName, channelName, csvName)
+	}
 
 	out := &api.Bundle{}
 	if bundle.Valid && bundle.String != "" {
@@ -245,6 +1024,8 @@ func (s *SQLQuerier) GetBundle(ctx context.Context, pkgName, channelName, csvNam
 	out.BundlePath = bundlePath.String
 	out.Version = version.String
 	out.SkipRange = skipRange.String
+	out.Replaces = replaces.String
+	out.Skips = splitSkips(skips)
 
 	provided, required, err := s.GetApisForEntry(ctx, entryId.Int64)
 	if err != nil {
@@ -268,29 +1049,40 @@ func (s *SQLQuerier) GetBundle(ctx context.Context, pkgName, channelName, csvNam
 	return out, nil
 }
 
+// GetBundleForChannel returns the bundle at the head of a channel, unless it has been deprecated
 func (s *SQLQuerier) GetBundleForChannel(ctx context.Context, pkgName string, channelName string) (*api.Bundle, error) {
-	query := `SELECT DISTINCT channel_entry.entry_id, operatorbundle.name, operatorbundle.bundle, operatorbundle.bundlepath, operatorbundle.version, operatorbundle.skiprange FROM channel
+	return s.getBundleForChannel(ctx, pkgName, channelName, false)
+}
+
+// GetBundleForChannelIncludingDeprecated returns the bundle at the head of a channel, even if it has been deprecated
+func (s *SQLQuerier) GetBundleForChannelIncludingDeprecated(ctx context.Context, pkgName string, channelName string) (*api.Bundle, error) {
+	return s.getBundleForChannel(ctx, pkgName, channelName, true)
+}
+
+func (s *SQLQuerier) getBundleForChannel(ctx context.Context, pkgName string, channelName string, includeDeprecated bool) (*api.Bundle, error) {
+	query := `SELECT DISTINCT channel_entry.entry_id, operatorbundle.name, operatorbundle.bundle, operatorbundle.bundlepath, operatorbundle.version, operatorbundle.skiprange, operatorbundle.replaces, operatorbundle.skips FROM channel
               INNER JOIN operatorbundle ON channel.head_operatorbundle_name=operatorbundle.name
               INNER JOIN channel_entry ON (channel_entry.channel_name = channel.name and channel_entry.package_name=channel.package_name and channel_entry.operatorbundle_name=operatorbundle.name)
-              WHERE channel.package_name=? AND channel.name=? LIMIT 1`
-	rows, err := s.db.QueryContext(ctx, query, pkgName, channelName)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
-	if !rows.Next() {
-		return nil, fmt.Errorf("no entry found for %s %s", pkgName, channelName)
+              WHERE channel.package_name=? AND channel.name=?`
+	if !includeDeprecated {
+		query += ` AND ` + notDeprecated("operatorbundle.name")
 	}
+	query += ` LIMIT 1`
 	var entryId sql.NullInt64
 	var name sql.NullString
 	var bundle sql.NullString
 	var bundlePath sql.NullString
 	var version sql.NullString
 	var skipRange sql.NullString
-	if err := rows.Scan(&entryId, &name, &bundle, &bundlePath, &version, &skipRange); err != nil {
+	var replaces sql.NullString
+	var skips sql.NullString
+	found, err := s.firstRow(ctx, "channel head", query, []interface{}{pkgName, channelName}, &entryId, &name, &bundle, &bundlePath, &version, &skipRange, &replaces, &skips)
+	if err != nil {
 		return nil, err
 	}
+	if !found {
+		return nil, registry.BundleNotFoundf("no entry found for %s %s", pkgName, channelName)
+	}
 
 	out := &api.Bundle{}
 	if bundle.Valid && bundle.String != "" {
@@ -305,6 +1097,8 @@ func (s *SQLQuerier) GetBundleForChannel(ctx context.Context, pkgName string, ch
 	out.BundlePath = bundlePath.String
 	out.Version = version.String
 	out.SkipRange = skipRange.String
+	out.Replaces = replaces.String
+	out.Skips = splitSkips(skips)
 
 	provided, required, err := s.GetApisForEntry(ctx, entryId.Int64)
 	if err != nil {
@@ -328,33 +1122,96 @@ func (s *SQLQuerier) GetBundleForChannel(ctx context.Context, pkgName string, ch
 	return out, nil
 }
 
-func (s *SQLQuerier) GetChannelEntriesThatReplace(ctx context.Context, name string) (entries []*registry.ChannelEntry, err error) {
-	query := `SELECT DISTINCT channel_entry.package_name, channel_entry.channel_name, channel_entry.operatorbundle_name
-			  FROM channel_entry
-			  LEFT OUTER JOIN channel_entry replaces ON channel_entry.replaces = replaces.entry_id
-              WHERE replaces.operatorbundle_name = ?`
-	rows, err := s.db.QueryContext(ctx, query, name)
+// GetChannelHeadBundles returns the bundle at the head of every channel of a package, ordered by channel, in a single
+// query rather than one per channel. Channels whose head is deprecated are left out unless includeDeprecated is set.
+func (s *SQLQuerier) GetChannelHeadBundles(ctx context.Context, pkgName string, includeDeprecated bool) ([]*api.Bundle, error) {
+	where := `INNER JOIN channel ON channel.package_name = channel_entry.package_name AND channel.name = channel_entry.channel_name AND channel.head_operatorbundle_name = channel_entry.operatorbundle_name
+	WHERE channel_entry.package_name = ?`
+	if !includeDeprecated {
+		where += ` AND ` + notDeprecated("channel_entry.operatorbundle_name")
+	}
+	heads, err := s.listBundles(ctx, where, pkgName)
 	if err != nil {
-		return
+		return nil, err
 	}
-	defer rows.Close()
+	for _, head := range heads {
+		// listBundles splits empty skips into a single empty name, which GetBundleForChannel doesn't
+		if len(head.Skips) == 1 && head.Skips[0] == "" {
+			head.Skips = nil
+		}
+	}
+	sort.Slice(heads, func(i, j int) bool {
+		return heads[i].ChannelName < heads[j].ChannelName
+	})
+	return heads, nil
+}
 
-	entries = []*registry.ChannelEntry{}
+// GetBundleForVersion returns the bundle of the given version in a package/channel. Versions are compared as semver,
+// so build metadata doesn't have to match.
+func (s *SQLQuerier) GetBundleForVersion(ctx context.Context, pkgName, channelName, version string) (*api.Bundle, error) {
+	want, err := semver.Parse(version)
+	if err != nil {
+		return nil, fmt.Errorf("invalid version %q: %s", version, err)
+	}
+	return s.getBundleForVersionMatch(ctx, pkgName, channelName, func(v semver.Version) bool { return v.EQ(want) })
+}
 
-	for rows.Next() {
-		var pkgNameSQL sql.NullString
-		var channelNameSQL sql.NullString
-		var bundleNameSQL sql.NullString
+// GetLatestBundleInRange returns the bundle with the highest version in a package/channel that satisfies the given
+// semver range, e.g. ">=1.0.0 <2.0.0"
+func (s *SQLQuerier) GetLatestBundleInRange(ctx context.Context, pkgName, channelName, versionRange string) (*api.Bundle, error) {
+	inRange, err := semver.ParseRange(versionRange)
+	if err != nil {
+		return nil, fmt.Errorf("invalid version range %q: %s", versionRange, err)
+	}
+	return s.getBundleForVersionMatch(ctx, pkgName, channelName, inRange)
+}
 
-		if err = rows.Scan(&pkgNameSQL, &channelNameSQL, &bundleNameSQL); err != nil {
-			return
+// getBundleForVersionMatch returns the bundle with the highest version in a package/channel that matches. Versions
+// can't be ordered in sql, so they are compared here; bundles without a valid version never match.
+func (s *SQLQuerier) getBundleForVersionMatch(ctx context.Context, pkgName, channelName string, match func(semver.Version) bool) (*api.Bundle, error) {
+	query := `SELECT DISTINCT operatorbundle.name, operatorbundle.version FROM operatorbundle
+              INNER JOIN channel_entry ON channel_entry.operatorbundle_name=operatorbundle.name
+              WHERE channel_entry.package_name=? AND channel_entry.channel_name=?`
+	var csvName string
+	var latest *semver.Version
+	err := s.forEachRow(ctx, "bundle versions", query, []interface{}{pkgName, channelName}, func(row scanner) error {
+		var name, version sql.NullString
+		if err := row.Scan(&name, &version); err != nil {
+			return err
 		}
-		entries = append(entries, &registry.ChannelEntry{
-			PackageName: pkgNameSQL.String,
-			ChannelName: channelNameSQL.String,
-			BundleName:  bundleNameSQL.String,
-			Replaces:    name,
-		})
+		v, err := semver.Parse(version.String)
+		if err != nil || !match(v) {
+			return nil
+		}
+		if latest == nil || v.GT(*latest) {
+			latest = &v
+			csvName = name.String
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if latest == nil {
+		return nil, registry.BundleNotFoundf("no matching bundle found for %s %s", pkgName, channelName)
+	}
+	return s.GetBundle(ctx, pkgName, channelName, csvName)
+}
+
+// splitSkips turns the comma separated skips column into a list, nil when the bundle skips nothing
+func splitSkips(skips sql.NullString) []string {
+	if !skips.Valid || skips.String == "" {
+		return nil
+	}
+	return strings.Split(skips.String, ",")
+}
+
+// GetChannelEntriesThatReplace returns the channel entries of the bundles that upgrade from the named bundle: those
+// that replace or skip it, and those whose skipRange contains its version in a channel they share with it
+func (s *SQLQuerier) GetChannelEntriesThatReplace(ctx context.Context, name string) (entries []*registry.ChannelEntry, err error) {
+	entries, err = s.channelEntriesThatReplace(ctx, name, "", "")
+	if err != nil {
+		return
 	}
 	if len(entries) == 0 {
 		err = fmt.Errorf("no channel entries found that replace %s", name)
@@ -363,65 +1220,106 @@ func (s *SQLQuerier) GetChannelEntriesThatReplace(ctx context.Context, name stri
 	return
 }
 
+// GetBundleThatReplaces returns a bundle of the channel that upgrades from the named bundle, preferring one that
+// replaces or skips it over one that only has it in its skipRange
 func (s *SQLQuerier) GetBundleThatReplaces(ctx context.Context, name, pkgName, channelName string) (*api.Bundle, error) {
-	query := `SELECT DISTINCT replaces.entry_id, operatorbundle.name, operatorbundle.bundle, operatorbundle.bundlepath, operatorbundle.version, operatorbundle.skiprange
-              FROM channel_entry
-			  LEFT  OUTER JOIN channel_entry replaces ON replaces.replaces = channel_entry.entry_id
-			  INNER JOIN operatorbundle ON replaces.operatorbundle_name = operatorbundle.name
-			  WHERE channel_entry.operatorbundle_name = ? AND channel_entry.package_name = ? AND channel_entry.channel_name = ? LIMIT 1`
-	rows, err := s.db.QueryContext(ctx, query, name, pkgName, channelName)
+	entries, err := s.channelEntriesThatReplace(ctx, name, pkgName, channelName)
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
+	if len(entries) == 0 {
+		return nil, registry.BundleNotFoundf("no entry found for %s %s", pkgName, channelName)
+	}
+	return s.GetBundle(ctx, pkgName, channelName, entries[0].BundleName)
+}
 
-	if !rows.Next() {
-		return nil, fmt.Errorf("no entry found for %s %s", pkgName, channelName)
+// channelEntriesThatReplace returns the entries that replace or skip the named bundle, followed by those whose
+// skipRange contains its version, limited to one channel if pkgName and channelName are set. Skips are matched
+// against the skips of the bundles as well as the synthetic entries added for them at load time.
+func (s *SQLQuerier) channelEntriesThatReplace(ctx context.Context, name, pkgName, channelName string) ([]*registry.ChannelEntry, error) {
+	inChannel := func(table string) string {
+		if pkgName == "" && channelName == "" {
+			return ""
+		}
+		return fmt.Sprintf(" AND %[1]s.package_name = ? AND %[1]s.channel_name = ?", table)
 	}
-	var entryId sql.NullInt64
-	var outName sql.NullString
-	var bundle sql.NullString
-	var bundlePath sql.NullString
-	var version sql.NullString
-	var skipRange sql.NullString
-	if err := rows.Scan(&entryId, &outName, &bundle, &bundlePath, &version, &skipRange); err != nil {
-		return nil, err
+	withChannel := func(args ...interface{}) []interface{} {
+		if pkgName == "" && channelName == "" {
+			return args
+		}
+		return append(args, pkgName, channelName)
 	}
 
-	out := &api.Bundle{}
-	if bundle.Valid && bundle.String != "" {
-		out, err = registry.BundleStringToAPIBundle(bundle.String)
-		if err != nil {
-			return nil, err
+	entries := []*registry.ChannelEntry{}
+	seen := map[registry.ChannelEntry]struct{}{}
+	add := func(entry registry.ChannelEntry) {
+		if _, ok := seen[entry]; ok {
+			return
 		}
+		seen[entry] = struct{}{}
+		entries = append(entries, &entry)
 	}
-	out.CsvName = outName.String
-	out.PackageName = pkgName
-	out.ChannelName = channelName
-	out.BundlePath = bundlePath.String
-	out.Version = version.String
-	out.SkipRange = skipRange.String
 
-	provided, required, err := s.GetApisForEntry(ctx, entryId.Int64)
-	if err != nil {
-		return nil, err
-	}
-	out.ProvidedApis = provided
-	out.RequiredApis = required
+	query := `SELECT DISTINCT channel_entry.package_name, channel_entry.channel_name, channel_entry.operatorbundle_name
+			  FROM channel_entry
+			  LEFT OUTER JOIN channel_entry replaces ON channel_entry.replaces = replaces.entry_id
+			  LEFT OUTER JOIN operatorbundle ON channel_entry.operatorbundle_name = operatorbundle.name
+			  WHERE (replaces.operatorbundle_name = ? OR instr(',' || operatorbundle.skips || ',', ',' || ? || ',') > 0)` + inChannel("channel_entry") + `
+			  ORDER BY replaces.operatorbundle_name IS NULL, channel_entry.entry_id`
+	err := s.forEachRow(ctx, "channel entries that replace", query, withChannel(name, name), func(row scanner) error {
+		var pkgNameSQL sql.NullString
+		var channelNameSQL sql.NullString
+		var bundleNameSQL sql.NullString
 
-	dependencies, err := s.GetDependenciesForBundle(ctx, outName.String, version.String, bundlePath.String)
+		if err := row.Scan(&pkgNameSQL, &channelNameSQL, &bundleNameSQL); err != nil {
+			return err
+		}
+		add(registry.ChannelEntry{
+			PackageName: pkgNameSQL.String,
+			ChannelName: channelNameSQL.String,
+			BundleName:  bundleNameSQL.String,
+			Replaces:    name,
+		})
+		return nil
+	})
 	if err != nil {
 		return nil, err
 	}
-	out.Dependencies = dependencies
 
-	properties, err := s.GetPropertiesForBundle(ctx, outName.String, version.String, bundlePath.String)
+	// skipRange can't be matched in sql, so the bundles with one that share a channel with the named bundle are
+	// matched against its version here
+	query = `SELECT DISTINCT channel_entry.package_name, channel_entry.channel_name, channel_entry.operatorbundle_name, operatorbundle.skiprange, skipped.version
+			  FROM channel_entry
+			  INNER JOIN operatorbundle ON channel_entry.operatorbundle_name = operatorbundle.name
+			  INNER JOIN channel_entry skipped_entry ON skipped_entry.package_name = channel_entry.package_name AND skipped_entry.channel_name = channel_entry.channel_name
+			  INNER JOIN operatorbundle skipped ON skipped_entry.operatorbundle_name = skipped.name
+			  WHERE skipped.name = ? AND channel_entry.operatorbundle_name != ? AND operatorbundle.skiprange != ''` + inChannel("channel_entry") + `
+			  ORDER BY channel_entry.entry_id`
+	err = s.forEachRow(ctx, "channel entries that skip by range", query, withChannel(name, name), func(row scanner) error {
+		var pkgNameSQL sql.NullString
+		var channelNameSQL sql.NullString
+		var bundleNameSQL sql.NullString
+		var skipRangeSQL sql.NullString
+		var versionSQL sql.NullString
+
+		if err := row.Scan(&pkgNameSQL, &channelNameSQL, &bundleNameSQL, &skipRangeSQL, &versionSQL); err != nil {
+			return err
+		}
+		if !inSkipRange(skipRangeSQL.String, versionSQL.String) {
+			return nil
+		}
+		add(registry.ChannelEntry{
+			PackageName: pkgNameSQL.String,
+			ChannelName: channelNameSQL.String,
+			BundleName:  bundleNameSQL.String,
+			Replaces:    name,
+		})
+		return nil
+	})
 	if err != nil {
 		return nil, err
 	}
-	out.Properties = properties
-
-	return out, nil
+	return entries, nil
 }
 
 func (s *SQLQuerier) GetChannelEntriesThatProvide(ctx context.Context, group, version, kind string) (entries []*registry.ChannelEntry, err error) {
@@ -440,21 +1338,53 @@ func (s *SQLQuerier) GetChannelEntriesThatProvide(ctx context.Context, group, ve
 	if err != nil {
 		return nil, err
 	}
-	rows, err := s.db.QueryContext(ctx, query, registry.GVKType, string(value))
+	entries = []*registry.ChannelEntry{}
+
+	err = s.forEachRow(ctx, "channel entries that provide", query, []interface{}{registry.GVKType, string(value)}, func(row scanner) error {
+		var pkgNameSQL sql.NullString
+		var channelNameSQL sql.NullString
+		var bundleNameSQL sql.NullString
+		var replacesSQL sql.NullString
+		if err := row.Scan(&pkgNameSQL, &channelNameSQL, &bundleNameSQL, &replacesSQL); err != nil {
+			return err
+		}
+
+		entries = append(entries, &registry.ChannelEntry{
+			PackageName: pkgNameSQL.String,
+			ChannelName: channelNameSQL.String,
+			BundleName:  bundleNameSQL.String,
+			Replaces:    replacesSQL.String,
+		})
+		return nil
+	})
 	if err != nil {
-		return nil, err
+		return
+	}
+	if len(entries) == 0 {
+		err = fmt.Errorf("no channel entries found that provide %s %s %s", group, version, kind)
+		return
 	}
-	defer rows.Close()
+	return
+}
 
+// GetChannelEntriesThatRequire returns the channel entries of the bundles that require an api, for reverse lookups of
+// the bundles that depend on the api
+func (s *SQLQuerier) GetChannelEntriesThatRequire(ctx context.Context, group, version, kind string) (entries []*registry.ChannelEntry, err error) {
+	query := `SELECT DISTINCT channel_entry.package_name, channel_entry.channel_name, channel_entry.operatorbundle_name, replaces.operatorbundle_name
+          FROM channel_entry
+          INNER JOIN api_requirer ON channel_entry.operatorbundle_name = api_requirer.operatorbundle_name
+          LEFT OUTER JOIN channel_entry replaces ON channel_entry.replaces = replaces.entry_id
+		  WHERE api_requirer.group_name = ? AND api_requirer.version = ? AND api_requirer.kind = ?
+		  ORDER BY channel_entry.package_name, channel_entry.channel_name, channel_entry.operatorbundle_name`
 	entries = []*registry.ChannelEntry{}
 
-	for rows.Next() {
+	err = s.forEachRow(ctx, "channel entries that require", query, []interface{}{group, version, kind}, func(row scanner) error {
 		var pkgNameSQL sql.NullString
 		var channelNameSQL sql.NullString
 		var bundleNameSQL sql.NullString
 		var replacesSQL sql.NullString
-		if err = rows.Scan(&pkgNameSQL, &channelNameSQL, &bundleNameSQL, &replacesSQL); err != nil {
-			return
+		if err := row.Scan(&pkgNameSQL, &channelNameSQL, &bundleNameSQL, &replacesSQL); err != nil {
+			return err
 		}
 
 		entries = append(entries, &registry.ChannelEntry{
@@ -463,14 +1393,49 @@ func (s *SQLQuerier) GetChannelEntriesThatProvide(ctx context.Context, group, ve
 			BundleName:  bundleNameSQL.String,
 			Replaces:    replacesSQL.String,
 		})
+		return nil
+	})
+	if err != nil {
+		return
 	}
 	if len(entries) == 0 {
-		err = fmt.Errorf("no channel entries found that provide %s %s %s", group, version, kind)
+		err = fmt.Errorf("no channel entries found that require %s %s %s", group, version, kind)
 		return
 	}
 	return
 }
 
+// GetBundlesRequiring returns the bundles that require an api, sorted by name. A bundle that is in several channels
+// is returned once, as found in the first of its packages and channels by name.
+func (s *SQLQuerier) GetBundlesRequiring(ctx context.Context, group, version, kind string) ([]*api.Bundle, error) {
+	entries, err := s.GetChannelEntriesThatRequire(ctx, group, version, kind)
+	if err != nil {
+		return nil, err
+	}
+
+	first := map[string]*registry.ChannelEntry{}
+	var names []string
+	for _, e := range entries {
+		if _, ok := first[e.BundleName]; ok {
+			continue
+		}
+		first[e.BundleName] = e
+		names = append(names, e.BundleName)
+	}
+	sort.Strings(names)
+
+	bundles := make([]*api.Bundle, 0, len(names))
+	for _, name := range names {
+		e := first[name]
+		bundle, err := s.GetBundle(ctx, e.PackageName, e.ChannelName, e.BundleName)
+		if err != nil {
+			return nil, err
+		}
+		bundles = append(bundles, bundle)
+	}
+	return bundles, nil
+}
+
 // Get latest channel entries that provide an api
 func (s *SQLQuerier) GetLatestChannelEntriesThatProvide(ctx context.Context, group, version, kind string) (entries []*registry.ChannelEntry, err error) {
 	query := `SELECT DISTINCT channel_entry.package_name, channel_entry.channel_name, channel_entry.operatorbundle_name, replaces.operatorbundle_name, MIN(channel_entry.depth)
@@ -489,22 +1454,16 @@ func (s *SQLQuerier) GetLatestChannelEntriesThatProvide(ctx context.Context, gro
 		return nil, err
 	}
 
-	rows, err := s.db.QueryContext(ctx, query, registry.GVKType, string(value))
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
 	entries = []*registry.ChannelEntry{}
 
-	for rows.Next() {
+	err = s.forEachRow(ctx, "latest channel entries that provide", query, []interface{}{registry.GVKType, string(value)}, func(row scanner) error {
 		var pkgNameSQL sql.NullString
 		var channelNameSQL sql.NullString
 		var bundleNameSQL sql.NullString
 		var replacesSQL sql.NullString
 		var min_depth sql.NullInt64
-		if err = rows.Scan(&pkgNameSQL, &channelNameSQL, &bundleNameSQL, &replacesSQL, &min_depth); err != nil {
-			return nil, err
+		if err := row.Scan(&pkgNameSQL, &channelNameSQL, &bundleNameSQL, &replacesSQL, &min_depth); err != nil {
+			return err
 		}
 
 		entries = append(entries, &registry.ChannelEntry{
@@ -513,6 +1472,10 @@ func (s *SQLQuerier) GetLatestChannelEntriesThatProvide(ctx context.Context, gro
 			BundleName:  bundleNameSQL.String,
 			Replaces:    replacesSQL.String,
 		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 	if len(entries) == 0 {
 		err = fmt.Errorf("no channel entries found that provide %s %s %s", group, version, kind)
@@ -523,7 +1486,7 @@ func (s *SQLQuerier) GetLatestChannelEntriesThatProvide(ctx context.Context, gro
 
 // Get the the latest bundle that provides the API in a default channel, error unless there is ONLY one
 func (s *SQLQuerier) GetBundleThatProvides(ctx context.Context, group, apiVersion, kind string) (*api.Bundle, error) {
-	query := `SELECT DISTINCT channel_entry.entry_id, operatorbundle.bundle, operatorbundle.bundlepath, MIN(channel_entry.depth), channel_entry.operatorbundle_name, channel_entry.package_name, channel_entry.channel_name, channel_entry.replaces, operatorbundle.version, operatorbundle.skiprange
+	query := `SELECT DISTINCT channel_entry.entry_id, operatorbundle.bundle, operatorbundle.bundlepath, MIN(channel_entry.depth), channel_entry.operatorbundle_name, channel_entry.package_name, channel_entry.channel_name, channel_entry.replaces, operatorbundle.version, operatorbundle.skiprange, operatorbundle.replaces, operatorbundle.skips
           FROM channel_entry
 		  INNER JOIN operatorbundle ON operatorbundle.name = channel_entry.operatorbundle_name
 		  INNER JOIN properties ON channel_entry.operatorbundle_name = properties.operatorbundle_name
@@ -536,15 +1499,6 @@ func (s *SQLQuerier) GetBundleThatProvides(ctx context.Context, group, apiVersio
 		"version": apiVersion,
 		"kind":    kind,
 	})
-	rows, err := s.db.QueryContext(ctx, query, registry.GVKType, string(value))
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
-	if !rows.Next() {
-		return nil, fmt.Errorf("no entry found that provides %s %s %s", group, apiVersion, kind)
-	}
 	var entryId sql.NullInt64
 	var bundle sql.NullString
 	var bundlePath sql.NullString
@@ -555,12 +1509,14 @@ func (s *SQLQuerier) GetBundleThatProvides(ctx context.Context, group, apiVersio
 	var replaces sql.NullString
 	var version sql.NullString
 	var skipRange sql.NullString
-	if err := rows.Scan(&entryId, &bundle, &bundlePath, &min_depth, &bundleName, &pkgName, &channelName, &replaces, &version, &skipRange); err != nil {
+	var bundleReplaces sql.NullString
+	var skips sql.NullString
+	found, err := s.firstRow(ctx, "bundle that provides", query, []interface{}{registry.GVKType, string(value)}, &entryId, &bundle, &bundlePath, &min_depth, &bundleName, &pkgName, &channelName, &replaces, &version, &skipRange, &bundleReplaces, &skips)
+	if err != nil {
 		return nil, err
 	}
-
-	if !bundle.Valid {
-		return nil, fmt.Errorf("no entry found that provides %s %s %s", group, apiVersion, kind)
+	if !found || !bundle.Valid {
+		return nil, registry.BundleNotFoundf("no entry found that provides %s %s %s", group, apiVersion, kind)
 	}
 
 	out := &api.Bundle{}
@@ -576,6 +1532,8 @@ func (s *SQLQuerier) GetBundleThatProvides(ctx context.Context, group, apiVersio
 	out.BundlePath = bundlePath.String
 	out.Version = version.String
 	out.SkipRange = skipRange.String
+	out.Replaces = bundleReplaces.String
+	out.Skips = splitSkips(skips)
 
 	provided, required, err := s.GetApisForEntry(ctx, entryId.Int64)
 	if err != nil {
@@ -601,41 +1559,38 @@ func (s *SQLQuerier) GetBundleThatProvides(ctx context.Context, group, apiVersio
 
 func (s *SQLQuerier) ListImages(ctx context.Context) ([]string, error) {
 	query := "SELECT DISTINCT image FROM related_image"
-	rows, err := s.db.QueryContext(ctx, query)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
 	images := []string{}
-	for rows.Next() {
+	err := s.forEachRow(ctx, "images", query, nil, func(row scanner) error {
 		var imgName sql.NullString
-		if err := rows.Scan(&imgName); err != nil {
-			return nil, err
+		if err := row.Scan(&imgName); err != nil {
+			return err
 		}
 		if imgName.Valid {
 			images = append(images, imgName.String)
 		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 	return images, nil
 }
 
 func (s *SQLQuerier) GetImagesForBundle(ctx context.Context, csvName string) ([]string, error) {
 	query := "SELECT DISTINCT image FROM related_image WHERE operatorbundle_name=?"
-	rows, err := s.db.QueryContext(ctx, query, csvName)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
 	images := []string{}
-	for rows.Next() {
+	err := s.forEachRow(ctx, "bundle images", query, []interface{}{csvName}, func(row scanner) error {
 		var imgName sql.NullString
-		if err := rows.Scan(&imgName); err != nil {
-			return nil, err
+		if err := row.Scan(&imgName); err != nil {
+			return err
 		}
 		if imgName.Valid {
 			images = append(images, imgName.String)
 		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 	return images, nil
 }
@@ -649,26 +1604,20 @@ func (s *SQLQuerier) GetApisForEntry(ctx context.Context, entryID int64) (provid
 		 	  		  INNER JOIN channel_entry ON channel_entry.operatorbundle_name = properties.operatorbundle_name
 			  		  WHERE properties.type=? AND channel_entry.entry_id=?`
 
-	providedRows, err := s.db.QueryContext(ctx, providedQuery, registry.GVKType, entryID)
-	if err != nil {
-		return nil, nil, err
-	}
-	defer providedRows.Close()
-
 	provided = []*api.GroupVersionKind{}
-	for providedRows.Next() {
+	err = s.forEachRow(ctx, "provided apis", providedQuery, []interface{}{registry.GVKType, entryID}, func(row scanner) error {
 		var value sql.NullString
 
-		if err := providedRows.Scan(&value); err != nil {
-			return nil, nil, err
+		if err := row.Scan(&value); err != nil {
+			return err
 		}
 
 		if !value.Valid {
-			continue
+			return nil
 		}
 		prop := registry.GVKProperty{}
 		if err := json.Unmarshal([]byte(value.String), &prop); err != nil {
-			continue
+			return nil
 		}
 
 		provided = append(provided, &api.GroupVersionKind{
@@ -679,31 +1628,29 @@ func (s *SQLQuerier) GetApisForEntry(ctx context.Context, entryID int64) (provid
 		groups[prop.Group] = struct{}{}
 		versions[prop.Version] = struct{}{}
 		kinds[prop.Kind] = struct{}{}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
 	}
 
 	requiredQuery := `SELECT DISTINCT dependencies.value FROM dependencies
 					  INNER JOIN channel_entry ON channel_entry.operatorbundle_name = dependencies.operatorbundle_name
 					  WHERE dependencies.type=? AND channel_entry.entry_id=?`
 
-	requiredRows, err := s.db.QueryContext(ctx, requiredQuery, registry.GVKType, entryID)
-	if err != nil {
-		return nil, nil, err
-	}
-	defer requiredRows.Close()
-
 	required = []*api.GroupVersionKind{}
-	for requiredRows.Next() {
+	err = s.forEachRow(ctx, "required apis", requiredQuery, []interface{}{registry.GVKType, entryID}, func(row scanner) error {
 		var value sql.NullString
 
-		if err := requiredRows.Scan(&value); err != nil {
-			return nil, nil, err
+		if err := row.Scan(&value); err != nil {
+			return err
 		}
 		if !value.Valid {
-			continue
+			return nil
 		}
 		dep := registry.GVKDependency{}
 		if err := json.Unmarshal([]byte(value.String), &dep); err != nil {
-			continue
+			return nil
 		}
 
 		required = append(required, &api.GroupVersionKind{
@@ -714,6 +1661,10 @@ func (s *SQLQuerier) GetApisForEntry(ctx context.Context, entryID int64) (provid
 		groups[dep.Group] = struct{}{}
 		versions[dep.Version] = struct{}{}
 		kinds[dep.Kind] = struct{}{}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
 	}
 
 	argsFor := func(s map[string]struct{}) string {
@@ -729,30 +1680,28 @@ func (s *SQLQuerier) GetApisForEntry(ctx context.Context, entryID int64) (provid
 		` AND api.version IN ` + argsFor(versions) +
 		` AND api.kind IN ` + argsFor(kinds)
 
-	pluralRows, err := s.db.QueryContext(ctx, pluralQuery)
-	if err != nil {
-		return nil, nil, err
-	}
-	defer pluralRows.Close()
-
 	gvkToPlural := map[registry.GVKProperty]string{}
-	for pluralRows.Next() {
+	err = s.forEachRow(ctx, "api plurals", pluralQuery, nil, func(row scanner) error {
 		var groupName sql.NullString
 		var versionName sql.NullString
 		var kindName sql.NullString
 		var pluralName sql.NullString
 
-		if err := pluralRows.Scan(&groupName, &versionName, &kindName, &pluralName); err != nil {
-			continue
+		if err := row.Scan(&groupName, &versionName, &kindName, &pluralName); err != nil {
+			return nil
 		}
 		if !groupName.Valid || !versionName.Valid || !kindName.Valid || !pluralName.Valid {
-			continue
+			return nil
 		}
 		gvkToPlural[registry.GVKProperty{
 			Group:   groupName.String,
 			Version: versionName.String,
 			Kind:    kindName.String,
 		}] = pluralName.String
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
 	}
 
 	for i, p := range provided {
@@ -788,17 +1737,9 @@ func (s *SQLQuerier) GetApisForEntry(ctx context.Context, entryID int64) (provid
 
 func (s *SQLQuerier) GetBundleVersion(ctx context.Context, image string) (string, error) {
 	query := `SELECT version FROM operatorbundle WHERE bundlepath=? LIMIT 1`
-	rows, err := s.db.QueryContext(ctx, query, image)
-	if err != nil {
-		return "", err
-	}
-	defer rows.Close()
-
 	var version sql.NullString
-	if rows.Next() {
-		if err := rows.Scan(&version); err != nil {
-			return "", err
-		}
+	if _, err := s.firstRow(ctx, "bundle version", query, []interface{}{image}, &version); err != nil {
+		return "", err
 	}
 	if version.Valid {
 		return version.String, nil
@@ -810,42 +1751,55 @@ func (s *SQLQuerier) GetBundlePathsForPackage(ctx context.Context, pkgName strin
 	query := `SELECT DISTINCT bundlepath FROM operatorbundle
 	INNER JOIN channel_entry ON operatorbundle.name=channel_entry.operatorbundle_name
 	WHERE channel_entry.package_name=?`
-	rows, err := s.db.QueryContext(ctx, query, pkgName)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
 	images := []string{}
-	for rows.Next() {
+	err := s.forEachRow(ctx, "bundle paths", query, []interface{}{pkgName}, func(row scanner) error {
 		var imgName sql.NullString
-		if err := rows.Scan(&imgName); err != nil {
-			return nil, err
+		if err := row.Scan(&imgName); err != nil {
+			return err
 		}
-		if imgName.Valid && imgName.String != "" {
-			images = append(images, imgName.String)
-		} else {
-			return nil, fmt.Errorf("Index malformed: cannot find paths to bundle images")
+		if !imgName.Valid || imgName.String == "" {
+			return fmt.Errorf("Index malformed: cannot find paths to bundle images")
 		}
+		images = append(images, imgName.String)
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 	return images, nil
 }
 
+// GetPackagesForBundle returns the packages that have the bundle with the given CSV name in one of their channels
+func (s *SQLQuerier) GetPackagesForBundle(ctx context.Context, csvName string) ([]string, error) {
+	query := `SELECT DISTINCT package_name FROM channel_entry WHERE operatorbundle_name=? ORDER BY package_name`
+	var packages []string
+	err := s.forEachRow(ctx, "bundle packages", query, []interface{}{csvName}, func(row scanner) error {
+		var pkgName sql.NullString
+		if err := row.Scan(&pkgName); err != nil {
+			return err
+		}
+		if pkgName.Valid {
+			packages = append(packages, pkgName.String)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return packages, nil
+}
+
 func (s *SQLQuerier) GetBundlesForPackage(ctx context.Context, pkgName string) (map[registry.BundleKey]struct{}, error) {
 	query := `SELECT DISTINCT name, bundlepath, version FROM operatorbundle
 	INNER JOIN channel_entry ON operatorbundle.name=channel_entry.operatorbundle_name
 	WHERE channel_entry.package_name=?`
-	rows, err := s.db.QueryContext(ctx, query, pkgName)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
 	bundles := map[registry.BundleKey]struct{}{}
-	for rows.Next() {
+	err := s.forEachRow(ctx, "package bundles", query, []interface{}{pkgName}, func(row scanner) error {
 		var name sql.NullString
 		var bundlepath sql.NullString
 		var version sql.NullString
-		if err := rows.Scan(&name, &bundlepath, &version); err != nil {
-			return nil, err
+		if err := row.Scan(&name, &bundlepath, &version); err != nil {
+			return err
 		}
 		key := registry.BundleKey{}
 		if name.Valid && name.String != "" {
@@ -858,26 +1812,22 @@ func (s *SQLQuerier) GetBundlesForPackage(ctx context.Context, pkgName string) (
 			key.Version = version.String
 		}
 		if key.IsEmpty() {
-			return nil, fmt.Errorf("Index malformed: cannot find identifier for bundle in package %s", pkgName)
+			return fmt.Errorf("Index malformed: cannot find identifier for bundle in package %s", pkgName)
 		}
 		bundles[key] = struct{}{}
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 	return bundles, nil
 }
 
 func (s *SQLQuerier) GetDefaultChannelForPackage(ctx context.Context, pkgName string) (string, error) {
 	query := `SELECT DISTINCT default_channel FROM package WHERE name=? LIMIT 1`
-	rows, err := s.db.QueryContext(ctx, query, pkgName)
-	if err != nil {
-		return "", err
-	}
-	defer rows.Close()
-
 	var defaultChannel sql.NullString
-	if rows.Next() {
-		if err := rows.Scan(&defaultChannel); err != nil {
-			return "", err
-		}
+	if _, err := s.firstRow(ctx, "default channel", query, []interface{}{pkgName}, &defaultChannel); err != nil {
+		return "", err
 	}
 	if defaultChannel.Valid {
 		return defaultChannel.String, nil
@@ -885,38 +1835,11 @@ func (s *SQLQuerier) GetDefaultChannelForPackage(ctx context.Context, pkgName st
 	return "", nil
 }
 
-func (s *SQLQuerier) ListChannels(ctx context.Context, pkgName string) ([]string, error) {
-	query := `SELECT DISTINCT name FROM channel WHERE channel.package_name=?`
-	rows, err := s.db.QueryContext(ctx, query, pkgName)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-	channels := []string{}
-	for rows.Next() {
-		var chName sql.NullString
-		if err := rows.Scan(&chName); err != nil {
-			return nil, err
-		}
-		if chName.Valid {
-			channels = append(channels, chName.String)
-		}
-	}
-	return channels, nil
-}
-
 func (s *SQLQuerier) GetCurrentCSVNameForChannel(ctx context.Context, pkgName, channel string) (string, error) {
 	query := `SELECT DISTINCT head_operatorbundle_name FROM channel WHERE channel.package_name=? AND channel.name=?`
-	rows, err := s.db.QueryContext(ctx, query, pkgName, channel)
-	if err != nil {
-		return "", err
-	}
-	defer rows.Close()
 	var csvName sql.NullString
-	if rows.Next() {
-		if err := rows.Scan(&csvName); err != nil {
-			return "", err
-		}
+	if _, err := s.firstRow(ctx, "channel head", query, []interface{}{pkgName, channel}, &csvName); err != nil {
+		return "", err
 	}
 	if csvName.Valid {
 		return csvName.String, nil
@@ -925,26 +1848,38 @@ func (s *SQLQuerier) GetCurrentCSVNameForChannel(ctx context.Context, pkgName, c
 }
 
 func (s *SQLQuerier) ListBundles(ctx context.Context) ([]*api.Bundle, error) {
+	return s.listBundles(ctx, "")
+}
+
+// ListBundlesFiltered returns the bundles that ListBundles does, limited to those the filter selects
+func (s *SQLQuerier) ListBundlesFiltered(ctx context.Context, filter registry.BundleFilter) ([]*api.Bundle, error) {
+	where, args, err := s.bundleFilterClause(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	return s.listBundles(ctx, where, args...)
+}
+
+// listBundles returns the bundles in the channels selected by where, one per bundle and channel
+func (s *SQLQuerier) listBundles(ctx context.Context, where string, args ...interface{}) ([]*api.Bundle, error) {
 	query := `SELECT DISTINCT channel_entry.entry_id, operatorbundle.bundle, operatorbundle.bundlepath,
 	channel_entry.operatorbundle_name, channel_entry.package_name, channel_entry.channel_name, operatorbundle.replaces, operatorbundle.skips,
 	operatorbundle.version, operatorbundle.skiprange,
 	dependencies.type, dependencies.value,
-	properties.type, properties.value
+	properties.type, properties.value,
+	stable_id.id
 	FROM channel_entry
 	INNER JOIN operatorbundle ON operatorbundle.name = channel_entry.operatorbundle_name
 	LEFT OUTER JOIN dependencies ON dependencies.operatorbundle_name = channel_entry.operatorbundle_name
 	LEFT OUTER JOIN properties ON properties.operatorbundle_name = channel_entry.operatorbundle_name
-	INNER JOIN package ON package.name = channel_entry.package_name`
-
-	rows, err := s.db.QueryContext(ctx, query)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
+	INNER JOIN package ON package.name = channel_entry.package_name
+	` + bundleIDJoin + `
+	` + where
+	args = append([]interface{}{migrations.StableIDKindBundle}, args...)
 
 	var bundles []*api.Bundle
 	bundlesMap := map[string]*api.Bundle{}
-	for rows.Next() {
+	err := s.forEachRow(ctx, "bundles", query, args, func(row scanner) error {
 		var (
 			entryID     sql.NullInt64
 			bundle      sql.NullString
@@ -960,13 +1895,14 @@ func (s *SQLQuerier) ListBundles(ctx context.Context) ([]*api.Bundle, error) {
 			depValue    sql.NullString
 			propType    sql.NullString
 			propValue   sql.NullString
+			id          sql.NullString
 		)
-		if err := rows.Scan(&entryID, &bundle, &bundlePath, &bundleName, &pkgName, &channelName, &replaces, &skips, &version, &skipRange, &depType, &depValue, &propType, &propValue); err != nil {
-			return nil, err
+		if err := row.Scan(&entryID, &bundle, &bundlePath, &bundleName, &pkgName, &channelName, &replaces, &skips, &version, &skipRange, &depType, &depValue, &propType, &propValue, &id); err != nil {
+			return err
 		}
 
 		if !bundleName.Valid || !version.Valid || !bundlePath.Valid || !channelName.Valid {
-			continue
+			return nil
 		}
 
 		bundleKey := fmt.Sprintf("%s/%s/%s/%s", bundleName.String, version.String, bundlePath.String, channelName.String)
@@ -989,12 +1925,14 @@ func (s *SQLQuerier) ListBundles(ctx context.Context) ([]*api.Bundle, error) {
 			// Create new bundle
 			out := &api.Bundle{}
 			if bundle.Valid && bundle.String != "" {
+				var err error
 				out, err = registry.BundleStringToAPIBundle(bundle.String)
 				if err != nil {
-					return nil, err
+					return err
 				}
 			}
 
+			out.Id = bundleID(id, bundleName.String)
 			out.CsvName = bundleName.String
 			out.PackageName = pkgName.String
 			out.ChannelName = channelName.String
@@ -1008,7 +1946,7 @@ func (s *SQLQuerier) ListBundles(ctx context.Context) ([]*api.Bundle, error) {
 
 			provided, required, err := s.GetApisForEntry(ctx, entryID.Int64)
 			if err != nil {
-				return nil, err
+				return err
 			}
 			if len(provided) > 0 {
 				out.ProvidedApis = provided
@@ -1033,6 +1971,10 @@ func (s *SQLQuerier) ListBundles(ctx context.Context) ([]*api.Bundle, error) {
 
 			bundlesMap[bundleKey] = out
 		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	for _, v := range bundlesMap {
@@ -1082,27 +2024,27 @@ func (s *SQLQuerier) GetDependenciesForBundle(ctx context.Context, name, version
 	AND (operatorbundle_version=? OR operatorbundle_version is NULL)
 	AND (operatorbundle_path=? OR operatorbundle_path is NULL)`
 
-	rows, err := s.db.QueryContext(ctx, depQuery, name, version, path)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
 	dependencies = []*api.Dependency{}
-	for rows.Next() {
+	err = s.forEachRow(ctx, "bundle dependencies", depQuery, []interface{}{name, version, path}, func(row scanner) error {
 		var typeName sql.NullString
 		var value sql.NullString
 
-		if err := rows.Scan(&typeName, &value); err != nil {
-			return nil, err
+		if err := row.Scan(&typeName, &value); err != nil {
+			return err
 		}
 		if !typeName.Valid || !value.Valid {
-			return nil, err
+			// an incomplete row leaves the bundle without any
+			dependencies = nil
+			return errStopRows
 		}
 		dependencies = append(dependencies, &api.Dependency{
 			Type:  typeName.String,
 			Value: value.String,
 		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	return
@@ -1114,27 +2056,27 @@ func (s *SQLQuerier) GetPropertiesForBundle(ctx context.Context, name, version,
 				 AND (operatorbundle_version=? OR operatorbundle_version is NULL)
 				 AND (operatorbundle_path=? OR operatorbundle_path is NULL)`
 
-	rows, err := s.db.QueryContext(ctx, propQuery, name, version, path)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
 	properties = []*api.Property{}
-	for rows.Next() {
+	err = s.forEachRow(ctx, "bundle properties", propQuery, []interface{}{name, version, path}, func(row scanner) error {
 		var typeName sql.NullString
 		var value sql.NullString
 
-		if err := rows.Scan(&typeName, &value); err != nil {
-			return nil, err
+		if err := row.Scan(&typeName, &value); err != nil {
+			return err
 		}
 		if !typeName.Valid || !value.Valid {
-			return nil, err
+			// an incomplete row leaves the bundle without any
+			properties = nil
+			return errStopRows
 		}
 		properties = append(properties, &api.Property{
 			Type:  typeName.String,
 			Value: value.String,
 		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	return
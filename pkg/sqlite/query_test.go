@@ -8,6 +8,7 @@ import (
 	"testing"
 
 	"github.com/operator-framework/operator-registry/pkg/api"
+	"github.com/operator-framework/operator-registry/pkg/registry"
 	"github.com/operator-framework/operator-registry/pkg/sqlite"
 	"github.com/operator-framework/operator-registry/pkg/sqlite/sqlitefakes"
 	"github.com/stretchr/testify/assert"
@@ -29,6 +30,7 @@ func TestListBundles(t *testing.T) {
 		DependencyValue sql.NullString
 		PropertyType    sql.NullString
 		PropertyValue   sql.NullString
+		StableID        sql.NullString
 	}
 
 	var NoRows sqlitefakes.FakeRowScanner
@@ -65,7 +67,7 @@ func TestListBundles(t *testing.T) {
 				q.QueryContextReturns(nil, fmt.Errorf("test"))
 				return &q
 			},
-			ErrorMessage: "test",
+			ErrorMessage: "error querying bundles: test",
 		},
 		{
 			Name: "returns error when scan returns error",
@@ -79,7 +81,48 @@ func TestListBundles(t *testing.T) {
 				r.ScanReturns(fmt.Errorf("test"))
 				return &q
 			},
-			ErrorMessage: "test",
+			ErrorMessage: "error querying bundles: test",
+		},
+		{
+			Name: "returns error when iterating rows returns error",
+			Querier: func(t *testing.T) sqlite.Querier {
+				var (
+					q sqlitefakes.FakeQuerier
+					r sqlitefakes.FakeRowScanner
+				)
+				q.QueryContextReturns(&r, nil)
+				r.ErrReturns(fmt.Errorf("test"))
+				return &q
+			},
+			ErrorMessage: "error querying bundles: test",
+		},
+		{
+			Name: "returns error when closing rows returns error",
+			Querier: func(t *testing.T) sqlite.Querier {
+				var (
+					q sqlitefakes.FakeQuerier
+					r sqlitefakes.FakeRowScanner
+				)
+				q.QueryContextReturns(&r, nil)
+				r.CloseReturns(fmt.Errorf("test"))
+				return &q
+			},
+			ErrorMessage: "error querying bundles: test",
+		},
+		{
+			Name: "returns both errors when scan and closing rows return errors",
+			Querier: func(t *testing.T) sqlite.Querier {
+				var (
+					q sqlitefakes.FakeQuerier
+					r sqlitefakes.FakeRowScanner
+				)
+				q.QueryContextReturns(&r, nil)
+				r.NextReturnsOnCall(0, true)
+				r.ScanReturns(fmt.Errorf("scan"))
+				r.CloseReturns(fmt.Errorf("close"))
+				return &q
+			},
+			ErrorMessage: "[error querying bundles: scan, error querying bundles: close]",
 		},
 		{
 			Name: "skips row without valid bundle name",
@@ -182,7 +225,8 @@ func TestListBundles(t *testing.T) {
 				return &q
 			},
 			Bundles: []*api.Bundle{
-				{},
+				// bundles without a stored id get the one derived from their name
+				{Id: registry.BundleID("")},
 			},
 		},
 		{
@@ -206,6 +250,7 @@ func TestListBundles(t *testing.T) {
 						DependencyValue: sql.NullString{Valid: true, String: "Dependency1Value"},
 						PropertyType:    sql.NullString{Valid: true, String: "Property1Type"},
 						PropertyValue:   sql.NullString{Valid: true, String: "Property1Value"},
+						StableID:        sql.NullString{Valid: true, String: "StableID"},
 					},
 					{
 						BundleName:      sql.NullString{Valid: true, String: "BundleName"},
@@ -216,6 +261,7 @@ func TestListBundles(t *testing.T) {
 						DependencyValue: sql.NullString{Valid: true, String: "Dependency2Value"},
 						PropertyType:    sql.NullString{Valid: true, String: "Property2Type"},
 						PropertyValue:   sql.NullString{Valid: true, String: "Property2Value"},
+						StableID:        sql.NullString{Valid: true, String: "StableID"},
 					},
 				}
 				var i int
@@ -230,6 +276,7 @@ func TestListBundles(t *testing.T) {
 			},
 			Bundles: []*api.Bundle{
 				{
+					Id:          "StableID",
 					CsvName:     "BundleName",
 					ChannelName: "ChannelName",
 					BundlePath:  "BundlePath",
@@ -276,3 +323,27 @@ func TestListBundles(t *testing.T) {
 		})
 	}
 }
+
+func TestQueriesCloseRows(t *testing.T) {
+	var (
+		q sqlitefakes.FakeQuerier
+		r sqlitefakes.FakeRowScanner
+	)
+	q.QueryContextReturns(&r, nil)
+	sq := sqlite.NewSQLLiteQuerierFromDBQuerier(&q)
+
+	// rows are closed whether every row was read, the first row was enough or reading a row failed
+	_, err := sq.ListPackages(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 1, r.CloseCallCount())
+
+	r.NextReturns(true)
+	_, err = sq.GetCurrentCSVNameForChannel(context.Background(), "etcd", "alpha")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, r.CloseCallCount())
+
+	r.ScanReturns(fmt.Errorf("test"))
+	_, err = sq.ListPackages(context.Background())
+	assert.EqualError(t, err, "error querying packages: test")
+	assert.Equal(t, 3, r.CloseCallCount())
+}
@@ -0,0 +1,77 @@
+package sqlite
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+)
+
+// errStopRows is returned by the scan function given to forEachRow to stop reading rows without an error
+var errStopRows = errors.New("stop reading rows")
+
+// scanner reads the columns of the current row of a query
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// rowScanner wraps the errors reading a row with what was queried
+type rowScanner struct {
+	rows RowScanner
+	what string
+}
+
+func (r rowScanner) Scan(dest ...interface{}) error {
+	if err := r.rows.Scan(dest...); err != nil {
+		return queryError(r.what, err)
+	}
+	return nil
+}
+
+func queryError(what string, err error) error {
+	return fmt.Errorf("error querying %s: %w", what, err)
+}
+
+// forEachRow runs a query and calls scan for each row it returns, until scan returns an error or errStopRows. The rows
+// are closed before it returns. Errors running the query and reading, iterating over or closing its rows are wrapped
+// with what was queried, and returned together with the error that ended the iteration, if they both fail. Errors
+// returned by scan for other reasons are returned as they are.
+func (s *SQLQuerier) forEachRow(ctx context.Context, what, query string, args []interface{}, scan func(row scanner) error) (err error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return queryError(what, err)
+	}
+	defer func() {
+		if cerr := rows.Close(); cerr != nil {
+			err = utilerrors.NewAggregate([]error{err, queryError(what, cerr)})
+		}
+	}()
+
+	row := rowScanner{rows: rows, what: what}
+	for rows.Next() {
+		if err := scan(row); err == errStopRows {
+			break
+		} else if err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return queryError(what, err)
+	}
+	return nil
+}
+
+// firstRow runs a query and reads the columns of its first row into dest. It returns false if the query returned no
+// rows, in which case dest is left as it was.
+func (s *SQLQuerier) firstRow(ctx context.Context, what, query string, args []interface{}, dest ...interface{}) (bool, error) {
+	found := false
+	err := s.forEachRow(ctx, what, query, args, func(row scanner) error {
+		found = true
+		if err := row.Scan(dest...); err != nil {
+			return err
+		}
+		return errStopRows
+	})
+	return found, err
+}
@@ -0,0 +1,46 @@
+package sqlite
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/operator-framework/operator-registry/pkg/registry"
+)
+
+func TestNotFoundErrors(t *testing.T) {
+	db, cleanup := CreateTestDb(t)
+	defer cleanup()
+	load, err := NewSQLLiteLoader(db)
+	require.NoError(t, err)
+	require.NoError(t, load.Migrate(context.TODO()))
+	require.NoError(t, NewSQLLoaderForDirectory(load, "../../manifests").Populate())
+	s := NewSQLLiteQuerierFromDb(db)
+
+	_, err = s.GetPackage(context.TODO(), "unknown")
+	require.True(t, errors.Is(err, registry.ErrPackageNotFound))
+	require.EqualError(t, err, "package unknown not found")
+	_, err = s.GetDefaultPackage(context.TODO(), "unknown")
+	require.True(t, errors.Is(err, registry.ErrPackageNotFound))
+	_, err = s.ListChannels(context.TODO(), "unknown")
+	require.True(t, errors.Is(err, registry.ErrPackageNotFound))
+
+	_, err = s.GetBundle(context.TODO(), "etcd", "alpha", "unknown")
+	require.True(t, errors.Is(err, registry.ErrBundleNotFound))
+	require.EqualError(t, err, "no entry found for etcd alpha unknown")
+	_, err = s.GetBundleForChannel(context.TODO(), "etcd", "unknown")
+	require.True(t, errors.Is(err, registry.ErrBundleNotFound))
+	_, err = s.GetBundleThatReplaces(context.TODO(), "etcdoperator.v0.9.2", "etcd", "alpha")
+	require.True(t, errors.Is(err, registry.ErrBundleNotFound))
+	_, err = s.GetBundleForVersion(context.TODO(), "etcd", "alpha", "9.9.9")
+	require.True(t, errors.Is(err, registry.ErrBundleNotFound))
+	_, err = s.GetBundleThatProvides(context.TODO(), "unknown.coreos.com", "v1", "Unknown")
+	require.True(t, errors.Is(err, registry.ErrBundleNotFound))
+	_, err = s.GetOwnershipForBundle(context.TODO(), "unknown")
+	require.True(t, errors.Is(err, registry.ErrBundleNotFound))
+
+	// a missing bundle isn't taken for a missing package
+	require.False(t, errors.Is(err, registry.ErrPackageNotFound))
+}
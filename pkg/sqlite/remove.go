@@ -16,8 +16,9 @@ type SQLRemover interface {
 
 // PackageRemover removes a package from the database
 type PackageRemover struct {
-	store    registry.Load
-	packages string
+	store      registry.Load
+	tombstoner registry.Tombstoner
+	packages   string
 }
 
 var _ SQLRemover = &PackageRemover{}
@@ -29,6 +30,16 @@ func NewSQLRemoverForPackages(store registry.Load, packages string) *PackageRemo
 	}
 }
 
+// NewSQLSoftRemoverForPackages returns a PackageRemover that keeps a tombstone of each
+// removed package so that the removal can be undone
+func NewSQLSoftRemoverForPackages(store MigratableLoader, packages string) *PackageRemover {
+	return &PackageRemover{
+		store:      store,
+		tombstoner: store,
+		packages:   packages,
+	}
+}
+
 func (d *PackageRemover) Remove() error {
 	log := logrus.WithField("pkg", d.packages)
 
@@ -40,6 +51,12 @@ func (d *PackageRemover) Remove() error {
 	log.Infof("packages: %s", packages)
 
 	for _, pkg := range packages {
+		if d.tombstoner != nil {
+			if err := d.tombstoner.SoftRemovePackage(pkg); err != nil {
+				errs = append(errs, fmt.Errorf("error removing operator package %s: %s", pkg, err))
+			}
+			continue
+		}
 		if err := d.store.RemovePackage(pkg); err != nil {
 			errs = append(errs, fmt.Errorf("error removing operator package %s: %s", pkg, err))
 		}
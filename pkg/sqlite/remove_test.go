@@ -5,6 +5,7 @@ import (
 	"github.com/operator-framework/operator-registry/pkg/image"
 	"github.com/operator-framework/operator-registry/pkg/registry"
 	"testing"
+	"time"
 
 	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/require"
@@ -72,3 +73,101 @@ func TestRemover(t *testing.T) {
 	require.True(t, rows.Next())
 	require.NoError(t, rows.Close())
 }
+
+func TestRemoverWithoutBundlePaths(t *testing.T) {
+	db, cleanup := CreateTestDb(t)
+	defer cleanup()
+	store, err := NewSQLLiteLoader(db)
+	require.NoError(t, err)
+	require.NoError(t, store.Migrate(context.TODO()))
+	require.NoError(t, NewSQLLoaderForDirectory(store, "../../manifests").Populate())
+
+	// prune all but etcd, as opm registry prune does
+	require.NoError(t, NewSQLRemoverForPackages(store, "prometheus,strimzi-kafka-operator").Remove())
+
+	count := func(query string) int {
+		var n int
+		require.NoError(t, db.QueryRow(query).Scan(&n))
+		return n
+	}
+	require.Equal(t, 1, count(`SELECT COUNT(*) FROM package`))
+	require.Zero(t, count(`SELECT COUNT(*) FROM channel WHERE package_name != 'etcd'`))
+	require.Zero(t, count(`SELECT COUNT(*) FROM channel_entry WHERE package_name != 'etcd'`))
+	require.Zero(t, count(`SELECT COUNT(*) FROM api_provider WHERE operatorbundle_name NOT IN (SELECT name FROM operatorbundle)`))
+	require.Zero(t, count(`SELECT COUNT(*) FROM api_requirer WHERE operatorbundle_name NOT IN (SELECT name FROM operatorbundle)`))
+	require.Zero(t, count(`SELECT COUNT(*) FROM api WHERE group_name LIKE '%kafka%'`))
+	require.NotZero(t, count(`SELECT COUNT(*) FROM api WHERE group_name = 'etcd.database.coreos.com'`))
+}
+
+func TestSoftRemover(t *testing.T) {
+	logrus.SetLevel(logrus.DebugLevel)
+	db, cleanup := CreateTestDb(t)
+	defer cleanup()
+	store, err := NewSQLLiteLoader(db)
+	require.NoError(t, err)
+	require.NoError(t, store.Migrate(context.TODO()))
+
+	query := NewSQLLiteQuerierFromDb(db)
+
+	graphLoader, err := NewSQLGraphLoaderFromDB(db)
+	require.NoError(t, err)
+
+	for _, name := range []string{"etcd.0.9.0", "etcd.0.9.2", "prometheus.0.14.0", "prometheus.0.15.0", "prometheus.0.22.2"} {
+		require.NoError(t, registry.NewDirectoryPopulator(
+			store,
+			graphLoader,
+			query,
+			map[image.Reference]string{
+				image.SimpleReference("quay.io/test/" + name): "../../bundles/" + name,
+			}).Populate(registry.ReplacesMode))
+	}
+
+	expectedPackage, err := query.GetPackage(context.TODO(), "etcd")
+	require.NoError(t, err)
+	expectedEntries, err := query.GetChannelEntriesFromPackage(context.TODO(), "etcd")
+	require.NoError(t, err)
+	expectedBundles, err := query.ListBundles(context.TODO())
+	require.NoError(t, err)
+
+	// soft delete etcd, then prometheus so that no apis are left
+	require.NoError(t, NewSQLSoftRemoverForPackages(store, "etcd,prometheus").Remove())
+
+	_, err = query.GetPackage(context.TODO(), "etcd")
+	require.EqualError(t, err, "package etcd not found")
+	tombstones, err := store.ListTombstones()
+	require.NoError(t, err)
+	require.Equal(t, []string{"etcd", "prometheus"}, tombstones)
+
+	// restoring is refused once the undo window has passed
+	_, err = db.Exec(`UPDATE package_tombstone SET deleted_at = deleted_at - 3600`)
+	require.NoError(t, err)
+	require.Error(t, store.RestorePackage("etcd", time.Minute))
+
+	// restore both packages
+	require.NoError(t, store.RestorePackage("etcd", time.Hour*2))
+	require.NoError(t, store.RestorePackage("prometheus", 0))
+
+	pkg, err := query.GetPackage(context.TODO(), "etcd")
+	require.NoError(t, err)
+	require.Equal(t, expectedPackage.DefaultChannelName, pkg.DefaultChannelName)
+	require.ElementsMatch(t, expectedPackage.Channels, pkg.Channels)
+	entries, err := query.GetChannelEntriesFromPackage(context.TODO(), "etcd")
+	require.NoError(t, err)
+	require.ElementsMatch(t, expectedEntries, entries)
+	bundles, err := query.ListBundles(context.TODO())
+	require.NoError(t, err)
+	require.ElementsMatch(t, expectedBundles, bundles)
+
+	// restored packages no longer have a tombstone
+	require.Error(t, store.RestorePackage("etcd", 0))
+
+	// purge only drops old enough tombstones
+	require.NoError(t, store.SoftRemovePackage("etcd"))
+	purged, err := store.PurgeTombstones(nil, time.Hour)
+	require.NoError(t, err)
+	require.Empty(t, purged)
+	purged, err = store.PurgeTombstones([]string{"etcd"}, 0)
+	require.NoError(t, err)
+	require.Equal(t, []string{"etcd"}, purged)
+	require.Error(t, store.RestorePackage("etcd", 0))
+}
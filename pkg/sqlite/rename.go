@@ -0,0 +1,144 @@
+package sqlite
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/operator-framework/operator-registry/pkg/registry"
+	"github.com/operator-framework/operator-registry/pkg/sqlite/migrations"
+)
+
+// RenamePackage renames a package across the catalog: the package itself, its channels and channel entries,
+// the olm.package properties of its bundles and the olm.package dependencies that other bundles declare on it.
+// The former name is kept as an alias of the new one so that existing subscriptions can still be resolved.
+func (s *sqlLoader) RenamePackage(from, to string) error {
+	if from == "" || to == "" {
+		return fmt.Errorf("package names must not be empty")
+	}
+	if from == to {
+		return fmt.Errorf("package %s can't be renamed to itself", from)
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		tx.Rollback()
+	}()
+
+	// package, channel and channel_entry reference each other, so they're only consistent again once all are renamed
+	if _, err := tx.Exec(`PRAGMA defer_foreign_keys = ON`); err != nil {
+		return err
+	}
+
+	if err := packageExists(tx, from); err != nil {
+		return err
+	}
+	if err := packageExists(tx, to); err == nil {
+		return fmt.Errorf("package %s already exists", to)
+	}
+
+	for _, stmt := range []string{
+		`UPDATE package SET name = ? WHERE name = ?`,
+		`UPDATE channel SET package_name = ? WHERE package_name = ?`,
+		`UPDATE channel_entry SET package_name = ? WHERE package_name = ?`,
+		`UPDATE channel_selector SET package_name = ? WHERE package_name = ?`,
+	} {
+		if _, err := tx.Exec(stmt, to, from); err != nil {
+			return err
+		}
+	}
+
+	// the package property of each bundle names the package the bundle belongs to
+	if err := renameInValues(tx, `SELECT rowid, value FROM properties WHERE type = ? AND operatorbundle_name IN (SELECT operatorbundle_name FROM channel_entry WHERE package_name = ?)`,
+		`UPDATE properties SET value = ? WHERE rowid = ?`, []interface{}{registry.PackageType, to}, from, to); err != nil {
+		return err
+	}
+
+	// bundles of any package may depend on the renamed one
+	if err := renameInValues(tx, `SELECT rowid, value FROM dependencies WHERE type = ?`,
+		`UPDATE dependencies SET value = ? WHERE rowid = ?`, []interface{}{registry.PackageType}, from, to); err != nil {
+		return err
+	}
+
+	// the package keeps its id under the new name, replacing any id left behind by a removed package of that name
+	if err := addPackageID(tx, from); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM stable_id WHERE kind = ? AND name = ?`, migrations.StableIDKindPackage, to); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`UPDATE stable_id SET name = ? WHERE kind = ? AND name = ?`, to, migrations.StableIDKindPackage, from); err != nil {
+		return err
+	}
+
+	// renaming back to a former name retires that alias; aliases of the old name follow it to the new one
+	if _, err := tx.Exec(`DELETE FROM package_alias WHERE alias = ?`, to); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`UPDATE package_alias SET package_name = ? WHERE package_name = ?`, to, from); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`INSERT INTO package_alias(alias, package_name, renamed_at) VALUES (?, ?, ?)`, from, to, time.Now().Unix()); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func packageExists(tx *sql.Tx, name string) error {
+	var count int
+	if err := tx.QueryRow(`SELECT COUNT(*) FROM package WHERE name = ?`, name).Scan(&count); err != nil {
+		return err
+	}
+	if count == 0 {
+		return fmt.Errorf("package %s not found", name)
+	}
+	return nil
+}
+
+// renameInValues rewrites the packageName field of the json values selected by query
+func renameInValues(tx *sql.Tx, query, update string, args []interface{}, from, to string) error {
+	rows, err := tx.Query(query, args...)
+	if err != nil {
+		return err
+	}
+
+	renamed := map[int64]string{}
+	for rows.Next() {
+		var rowid int64
+		var raw sql.NullString
+		if err := rows.Scan(&rowid, &raw); err != nil {
+			rows.Close()
+			return err
+		}
+		value := map[string]interface{}{}
+		if err := json.Unmarshal([]byte(raw.String), &value); err != nil {
+			rows.Close()
+			return err
+		}
+		if name, ok := value["packageName"].(string); !ok || name != from {
+			continue
+		}
+		value["packageName"] = to
+		out, err := json.Marshal(value)
+		if err != nil {
+			rows.Close()
+			return err
+		}
+		renamed[rowid] = string(out)
+	}
+	if err := rows.Close(); err != nil {
+		return err
+	}
+
+	for rowid, value := range renamed {
+		if _, err := tx.Exec(update, value, rowid); err != nil {
+			return err
+		}
+	}
+	return nil
+}
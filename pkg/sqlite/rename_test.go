@@ -0,0 +1,112 @@
+package sqlite
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/operator-framework/operator-registry/pkg/image"
+	"github.com/operator-framework/operator-registry/pkg/registry"
+)
+
+func TestRenamePackage(t *testing.T) {
+	db, cleanup := CreateTestDb(t)
+	defer cleanup()
+	store, err := NewSQLLiteLoader(db)
+	require.NoError(t, err)
+	require.NoError(t, store.Migrate(context.TODO()))
+
+	query := NewSQLLiteQuerierFromDb(db)
+
+	graphLoader, err := NewSQLGraphLoaderFromDB(db)
+	require.NoError(t, err)
+
+	for _, name := range []string{"etcd.0.9.0", "etcd.0.9.2", "prometheus.0.14.0"} {
+		require.NoError(t, registry.NewDirectoryPopulator(
+			store,
+			graphLoader,
+			query,
+			map[image.Reference]string{
+				image.SimpleReference("quay.io/test/" + name): "../../bundles/" + name,
+			}).Populate(registry.ReplacesMode))
+	}
+
+	// make prometheus depend on etcd
+	var name, version, path string
+	require.NoError(t, db.QueryRow(`SELECT name, version, bundlepath FROM operatorbundle WHERE bundlepath = ?`, "quay.io/test/prometheus.0.14.0").Scan(&name, &version, &path))
+	_, err = db.Exec(`INSERT INTO dependencies(type, value, operatorbundle_name, operatorbundle_version, operatorbundle_path) VALUES (?, ?, ?, ?, ?)`,
+		registry.PackageType, `{"packageName":"etcd","version":">0.9.0"}`, name, version, path)
+	require.NoError(t, err)
+
+	expectedEntries, err := query.GetChannelEntriesFromPackage(context.TODO(), "etcd")
+	require.NoError(t, err)
+
+	id, err := query.GetPackageID(context.TODO(), "etcd")
+	require.NoError(t, err)
+	require.Equal(t, registry.PackageID("etcd"), id)
+	bundleID, err := query.GetBundleID(context.TODO(), "etcdoperator.v0.9.2")
+	require.NoError(t, err)
+	require.Equal(t, registry.BundleID("etcdoperator.v0.9.2"), bundleID)
+
+	require.Error(t, store.RenamePackage("etcd", "prometheus"), "renaming onto an existing package")
+	require.Error(t, store.RenamePackage("missing", "other"), "renaming a missing package")
+
+	require.NoError(t, store.RenamePackage("etcd", "etcd-operator"))
+
+	_, err = query.GetPackage(context.TODO(), "etcd")
+	require.Error(t, err)
+	current, err := query.GetPackageForAlias(context.TODO(), "etcd")
+	require.NoError(t, err)
+	require.Equal(t, "etcd-operator", current)
+	pkg, err := query.GetPackage(context.TODO(), "etcd-operator")
+	require.NoError(t, err)
+	require.Equal(t, "alpha", pkg.DefaultChannelName)
+
+	// the package keeps its id under the new name
+	renamedID, err := query.GetPackageID(context.TODO(), "etcd-operator")
+	require.NoError(t, err)
+	require.Equal(t, id, renamedID)
+
+	entries, err := query.GetChannelEntriesFromPackage(context.TODO(), "etcd-operator")
+	require.NoError(t, err)
+	require.Len(t, entries, len(expectedEntries))
+	for _, e := range entries {
+		require.Equal(t, "etcd-operator", e.PackageName)
+	}
+
+	bundle, err := query.GetBundle(context.TODO(), "etcd-operator", "alpha", "etcdoperator.v0.9.2")
+	require.NoError(t, err)
+	require.Equal(t, "etcd-operator", bundle.PackageName)
+	for _, p := range bundle.Properties {
+		if p.Type == registry.PackageType {
+			require.JSONEq(t, `{"packageName":"etcd-operator","version":"0.9.2"}`, p.Value)
+		}
+	}
+
+	var dependency string
+	require.NoError(t, db.QueryRow(`SELECT value FROM dependencies WHERE operatorbundle_name = ? AND type = ?`, name, registry.PackageType).Scan(&dependency))
+	require.JSONEq(t, `{"packageName":"etcd-operator","version":">0.9.0"}`, dependency)
+
+	// aliases follow further renames, and renaming back retires the alias
+	require.NoError(t, store.RenamePackage("etcd-operator", "etcd-ng"))
+	var target string
+	require.NoError(t, db.QueryRow(`SELECT package_name FROM package_alias WHERE alias = ?`, "etcd").Scan(&target))
+	require.Equal(t, "etcd-ng", target)
+
+	require.NoError(t, store.RenamePackage("etcd-ng", "etcd"))
+	var aliases []string
+	rows, err := db.Query(`SELECT alias FROM package_alias WHERE package_name = ? ORDER BY alias`, "etcd")
+	require.NoError(t, err)
+	for rows.Next() {
+		var alias string
+		require.NoError(t, rows.Scan(&alias))
+		aliases = append(aliases, alias)
+	}
+	require.NoError(t, rows.Close())
+	require.Equal(t, []string{"etcd-ng", "etcd-operator"}, aliases)
+
+	renamedID, err = query.GetPackageID(context.TODO(), "etcd")
+	require.NoError(t, err)
+	require.Equal(t, id, renamedID)
+}
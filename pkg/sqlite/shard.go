@@ -0,0 +1,99 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/operator-framework/operator-registry/pkg/registry"
+)
+
+// ShardPath is the path of a shard database written by SplitDatabase
+func ShardPath(outputDir string, shard int) string {
+	return filepath.Join(outputDir, fmt.Sprintf("shard-%d.db", shard))
+}
+
+// SplitDatabase splits a database into shard databases under outputDir, keeping in each shard only the packages
+// that registry.ShardForPackage assigns to it. The shards are returned in order, which is the order they must be
+// passed to registry.NewShardedQuery in.
+func SplitDatabase(ctx context.Context, dbPath, outputDir string, shards int) ([]string, error) {
+	if shards < 1 {
+		return nil, fmt.Errorf("a catalog must be split into at least one shard, got %d", shards)
+	}
+	if err := os.MkdirAll(outputDir, 0755); err != nil {
+		return nil, err
+	}
+
+	var paths []string
+	for shard := 0; shard < shards; shard++ {
+		path := ShardPath(outputDir, shard)
+		if err := copyFile(dbPath, path); err != nil {
+			return nil, err
+		}
+		if err := keepShard(ctx, path, shard, shards); err != nil {
+			return nil, fmt.Errorf("error writing shard %d: %s", shard, err)
+		}
+		paths = append(paths, path)
+	}
+	return paths, nil
+}
+
+// keepShard removes the packages that belong to other shards from a copy of the database
+func keepShard(ctx context.Context, path string, shard, shards int) error {
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	loader, err := NewSQLLiteLoader(db)
+	if err != nil {
+		return err
+	}
+	if err := loader.Migrate(ctx); err != nil {
+		return err
+	}
+
+	packages, err := NewSQLLiteQuerierFromDb(db).ListPackages(ctx)
+	if err != nil {
+		return err
+	}
+	for _, pkg := range packages {
+		if registry.ShardForPackage(pkg, shards) == shard {
+			continue
+		}
+		if err := loader.RemovePackage(pkg); err != nil {
+			return err
+		}
+	}
+
+	// aliases stay with the package they were renamed to
+	if _, err := db.ExecContext(ctx, `DELETE FROM package_alias WHERE package_name NOT IN (SELECT name FROM package)`); err != nil {
+		return err
+	}
+
+	// give the space of the removed packages back
+	_, err = db.ExecContext(ctx, `VACUUM`)
+	return err
+}
+
+func copyFile(from, to string) error {
+	in, err := os.Open(from)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.Create(to)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
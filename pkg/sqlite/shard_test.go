@@ -0,0 +1,162 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/operator-framework/operator-registry/pkg/registry"
+)
+
+func TestSplitDatabase(t *testing.T) {
+	dir, err := ioutil.TempDir("", "shards-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	dbPath := filepath.Join(dir, "index.db")
+	db, err := sql.Open("sqlite3", dbPath)
+	require.NoError(t, err)
+	load, err := NewSQLLiteLoader(db)
+	require.NoError(t, err)
+	require.NoError(t, load.Migrate(context.TODO()))
+	require.NoError(t, NewSQLLoaderForDirectory(load, "../../manifests").Populate())
+	require.NoError(t, db.Close())
+
+	_, err = SplitDatabase(context.TODO(), dbPath, filepath.Join(dir, "shards"), 0)
+	require.Error(t, err)
+
+	// with three shards, each of the three packages lands in a shard of its own
+	const shards = 3
+	paths, err := SplitDatabase(context.TODO(), dbPath, filepath.Join(dir, "shards"), shards)
+	require.NoError(t, err)
+	require.Len(t, paths, shards)
+
+	whole, err := NewSQLLiteQuerier(dbPath)
+	require.NoError(t, err)
+	var queriers []registry.Query
+	for shard, path := range paths {
+		querier, err := NewSQLLiteQuerier(path)
+		require.NoError(t, err)
+		queriers = append(queriers, querier)
+
+		// each shard only holds its own packages
+		packages, err := querier.ListPackages(context.TODO())
+		require.NoError(t, err)
+		require.Len(t, packages, 1)
+		for _, pkg := range packages {
+			require.Equal(t, shard, registry.ShardForPackage(pkg, shards), "package %s in shard %d", pkg, shard)
+		}
+	}
+	sharded := registry.NewShardedQuery(queriers)
+
+	// the shards together answer like the whole catalog
+	expectedPackages, err := whole.ListPackages(context.TODO())
+	require.NoError(t, err)
+	packages, err := sharded.ListPackages(context.TODO())
+	require.NoError(t, err)
+	require.ElementsMatch(t, expectedPackages, packages)
+
+	// pages of packages are in order whether or not the catalog is sharded
+	for _, q := range []registry.Query{whole, sharded} {
+		page, err := q.ListPackagesPaged(context.TODO(), "", 2)
+		require.NoError(t, err)
+		require.Equal(t, []string{"etcd", "prometheus"}, page)
+		page, err = q.ListPackagesPaged(context.TODO(), page[len(page)-1], 2)
+		require.NoError(t, err)
+		require.Equal(t, []string{"strimzi-kafka-operator"}, page)
+		page, err = q.ListPackagesPaged(context.TODO(), page[len(page)-1], 2)
+		require.NoError(t, err)
+		require.Empty(t, page)
+		page, err = q.ListPackagesPaged(context.TODO(), "etcd", 0)
+		require.NoError(t, err)
+		require.Equal(t, []string{"prometheus", "strimzi-kafka-operator"}, page)
+	}
+
+	for _, pkg := range expectedPackages {
+		expected, err := whole.GetPackage(context.TODO(), pkg)
+		require.NoError(t, err)
+		actual, err := sharded.GetPackage(context.TODO(), pkg)
+		require.NoError(t, err)
+		require.Equal(t, expected, actual)
+	}
+
+	expectedBundles, err := whole.ListBundles(context.TODO())
+	require.NoError(t, err)
+	bundles, err := sharded.ListBundles(context.TODO())
+	require.NoError(t, err)
+	require.Len(t, bundles, len(expectedBundles))
+
+	expectedEntries, err := whole.GetChannelEntriesThatProvide(context.TODO(), "etcd.database.coreos.com", "v1beta2", "EtcdCluster")
+	require.NoError(t, err)
+	entries, err := sharded.GetChannelEntriesThatProvide(context.TODO(), "etcd.database.coreos.com", "v1beta2", "EtcdCluster")
+	require.NoError(t, err)
+	require.ElementsMatch(t, expectedEntries, entries)
+
+	_, err = sharded.GetChannelEntriesThatProvide(context.TODO(), "missing.example.com", "v1", "Missing")
+	require.Error(t, err)
+
+	expectedOwnership, err := whole.GetOwnershipForBundle(context.TODO(), "etcdoperator.v0.9.2")
+	require.NoError(t, err)
+	ownership, err := sharded.GetOwnershipForBundle(context.TODO(), "etcdoperator.v0.9.2")
+	require.NoError(t, err)
+	require.Equal(t, expectedOwnership, ownership)
+
+	expectedStats, err := whole.GetPackageStats(context.TODO(), nil)
+	require.NoError(t, err)
+	stats, err := sharded.GetPackageStats(context.TODO(), nil)
+	require.NoError(t, err)
+	require.Equal(t, expectedStats, stats)
+
+	// packages that aren't in the catalog are left out
+	stats, err = whole.GetPackageStats(context.TODO(), []string{"etcd", "missing"})
+	require.NoError(t, err)
+	require.Equal(t, []*registry.PackageStats{{
+		Name:               "etcd",
+		BundleCount:        3,
+		Channels:           []string{"alpha", "beta", "stable"},
+		DefaultChannelName: "alpha",
+		LatestVersion:      "0.9.2",
+		TotalBundleSize:    44675,
+		LargestBundle:      "etcdoperator.v0.9.2",
+		LargestBundleSize:  17068,
+	}}, stats)
+
+	expectedBundleStats, err := whole.GetBundleStats(context.TODO(), nil, 5)
+	require.NoError(t, err)
+	require.Len(t, expectedBundleStats, 5)
+	bundleStats, err := sharded.GetBundleStats(context.TODO(), nil, 5)
+	require.NoError(t, err)
+	require.Equal(t, expectedBundleStats, bundleStats)
+
+	expectedHeads, err := whole.GetDefaultChannelHeads(context.TODO(), nil)
+	require.NoError(t, err)
+	require.NotEmpty(t, expectedHeads)
+	heads, err := sharded.GetDefaultChannelHeads(context.TODO(), nil)
+	require.NoError(t, err)
+	require.Equal(t, expectedHeads, heads)
+
+	heads, err = whole.GetDefaultChannelHeads(context.TODO(), []string{"etcd", "missing"})
+	require.NoError(t, err)
+	require.Equal(t, []*registry.BundleSummary{{
+		PackageName: "etcd",
+		ChannelName: "alpha",
+		CsvName:     "etcdoperator.v0.9.2",
+		Version:     "0.9.2",
+		Replaces:    "etcdoperator.v0.9.0",
+		Skips:       []string{"etcdoperator.v0.9.1"},
+		SkipRange:   "< 0.6.0",
+	}}, heads)
+
+	// a sharded search finds the same bundles, though relevance can only be compared within a shard
+	expectedFound, err := whole.SearchBundles(context.TODO(), "operator", nil)
+	require.NoError(t, err)
+	require.NotEmpty(t, expectedFound)
+	found, err := sharded.SearchBundles(context.TODO(), "operator", nil)
+	require.NoError(t, err)
+	require.ElementsMatch(t, expectedFound, found)
+}
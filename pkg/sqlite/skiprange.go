@@ -0,0 +1,19 @@
+package sqlite
+
+import (
+	"github.com/blang/semver"
+)
+
+// inSkipRange returns true if version is in skipRange. A skipRange or version that can't be parsed matches
+// nothing, as OLM ignores such ranges too.
+func inSkipRange(skipRange, version string) bool {
+	inRange, err := semver.ParseRange(skipRange)
+	if err != nil {
+		return false
+	}
+	v, err := semver.Parse(version)
+	if err != nil {
+		return false
+	}
+	return inRange(v)
+}
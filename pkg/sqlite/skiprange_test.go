@@ -0,0 +1,115 @@
+package sqlite
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/operator-framework/operator-registry/pkg/registry"
+)
+
+func newUnstructuredCSVWithSkipRange(t *testing.T, name, version, replaces, skipRange string, skips ...string) *unstructured.Unstructured {
+	csv := &registry.ClusterServiceVersion{}
+	csv.TypeMeta.Kind = "ClusterServiceVersion"
+	csv.SetName(name)
+	if skipRange != "" {
+		csv.SetAnnotations(map[string]string{"olm.skipRange": skipRange})
+	}
+	spec := map[string]interface{}{"version": version, "replaces": replaces}
+	if len(skips) > 0 {
+		spec["skips"] = skips
+	}
+	var err error
+	csv.Spec, err = json.Marshal(spec)
+	require.NoError(t, err)
+
+	out, err := runtime.DefaultUnstructuredConverter.ToUnstructured(csv)
+	require.NoError(t, err)
+	return &unstructured.Unstructured{Object: out}
+}
+
+func TestReplacementsFollowSkipsAndSkipRange(t *testing.T) {
+	db, cleanup := CreateTestDb(t)
+	defer cleanup()
+	store, err := NewSQLLiteLoader(db)
+	require.NoError(t, err)
+	require.NoError(t, store.Migrate(context.TODO()))
+
+	channels := []string{"stable"}
+	for _, b := range []*registry.Bundle{
+		newBundle(t, "csv-a", "pkg", channels, newUnstructuredCSVWithSkipRange(t, "csv-a", "1.0.0", "", "")),
+		newBundle(t, "csv-b", "pkg", channels, newUnstructuredCSVWithSkipRange(t, "csv-b", "1.1.0", "csv-a", "")),
+		newBundle(t, "csv-c", "pkg", channels, newUnstructuredCSVWithSkipRange(t, "csv-c", "2.0.0", "csv-b", ">=1.0.0 <2.0.0")),
+		// an unparseable range matches nothing
+		newBundle(t, "csv-d", "pkg", channels, newUnstructuredCSVWithSkipRange(t, "csv-d", "2.1.0", "csv-c", "not a range")),
+		newBundle(t, "csv-e", "pkg", channels, newUnstructuredCSVWithSkipRange(t, "csv-e", "3.0.0", "csv-d", "", "csv-c")),
+	} {
+		require.NoError(t, store.AddOperatorBundle(b))
+	}
+	require.NoError(t, store.AddPackageChannels(registry.PackageManifest{
+		PackageName:        "pkg",
+		Channels:           []registry.PackageChannel{{Name: "stable", CurrentCSVName: "csv-e"}},
+		DefaultChannelName: "stable",
+	}))
+
+	querier := NewSQLLiteQuerierFromDb(db)
+	replacedBy := func(name string) []string {
+		entries, err := querier.GetChannelEntriesThatReplace(context.TODO(), name)
+		require.NoError(t, err)
+		var names []string
+		for _, e := range entries {
+			require.Equal(t, name, e.Replaces)
+			names = append(names, e.BundleName)
+		}
+		sort.Strings(names)
+		return names
+	}
+
+	// csv-c skips csv-a by range, and replaces csv-b, which is in range too, only once
+	require.Equal(t, []string{"csv-b", "csv-c"}, replacedBy("csv-a"))
+	require.Equal(t, []string{"csv-c"}, replacedBy("csv-b"))
+	require.Equal(t, []string{"csv-d", "csv-e"}, replacedBy("csv-c"))
+	require.Equal(t, []string{"csv-e"}, replacedBy("csv-d"))
+	_, err = querier.GetChannelEntriesThatReplace(context.TODO(), "csv-e")
+	require.Error(t, err)
+
+	replacement := func(name string) string {
+		bundle, err := querier.GetBundleThatReplaces(context.TODO(), name, "pkg", "stable")
+		require.NoError(t, err)
+		return bundle.CsvName
+	}
+	// a bundle that replaces csv-a is preferred over one that has it in range
+	require.Equal(t, "csv-b", replacement("csv-a"))
+	require.Equal(t, "csv-c", replacement("csv-b"))
+	require.Equal(t, "csv-e", replacement("csv-d"))
+	bundle, err := querier.GetBundleThatReplaces(context.TODO(), "csv-a", "pkg", "stable")
+	require.NoError(t, err)
+	require.Equal(t, "1.1.0", bundle.Version)
+	_, err = querier.GetBundleThatReplaces(context.TODO(), "csv-a", "pkg", "beta")
+	require.True(t, errors.Is(err, registry.ErrBundleNotFound), err)
+
+	// skipRange is resolved by the queries and adds no entries to the channel for its other readers, which only see
+	// the entries of replaces and the synthetic entries skips have always added
+	entries, err := querier.GetChannelEntriesFromPackage(context.TODO(), "pkg")
+	require.NoError(t, err)
+	var edges []string
+	for _, e := range entries {
+		edges = append(edges, e.BundleName+" -> "+e.Replaces)
+	}
+	sort.Strings(edges)
+	require.Equal(t, []string{
+		"csv-a -> ",
+		"csv-b -> csv-a",
+		"csv-c -> ",
+		"csv-c -> csv-b",
+		"csv-d -> csv-c",
+		"csv-e -> csv-c",
+		"csv-e -> csv-d",
+	}, edges)
+}
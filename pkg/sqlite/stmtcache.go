@@ -0,0 +1,98 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync"
+)
+
+// maxCachedStatements bounds the statements a stmtCache keeps. Queries that list their arguments inline, such as
+// those filtering by a set of packages, have a text per number of arguments, and once the cache is full they are run
+// without being prepared.
+const maxCachedStatements = 256
+
+var errStmtCacheClosed = errors.New("statement cache is closed")
+
+// stmtCache is a Querier that prepares each query it runs once and reuses the statement for later calls, instead of
+// having SQLite parse the query every time. database/sql prepares a statement again on each connection it is run on,
+// so each query is parsed at most once per connection.
+type stmtCache struct {
+	db *sql.DB
+
+	mu     sync.RWMutex
+	stmts  map[string]*sql.Stmt
+	closed bool
+}
+
+func newStmtCache(db *sql.DB) *stmtCache {
+	return &stmtCache{db: db, stmts: map[string]*sql.Stmt{}}
+}
+
+func (c *stmtCache) QueryContext(ctx context.Context, query string, args ...interface{}) (RowScanner, error) {
+	stmt, err := c.prepare(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	if stmt == nil {
+		return c.db.QueryContext(ctx, query, args...)
+	}
+	return stmt.QueryContext(ctx, args...)
+}
+
+// prepare returns the statement for query, preparing it if it hasn't been yet. It returns nil if the cache is full.
+func (c *stmtCache) prepare(ctx context.Context, query string) (*sql.Stmt, error) {
+	c.mu.RLock()
+	stmt, ok := c.stmts[query]
+	closed, full := c.closed, len(c.stmts) >= maxCachedStatements
+	c.mu.RUnlock()
+	switch {
+	case closed:
+		return nil, errStmtCacheClosed
+	case ok:
+		return stmt, nil
+	case full:
+		return nil, nil
+	}
+
+	stmt, err := c.db.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		stmt.Close()
+		return nil, errStmtCacheClosed
+	}
+	// another caller may have prepared the same query in the meantime
+	if prepared, ok := c.stmts[query]; ok {
+		stmt.Close()
+		return prepared, nil
+	}
+	if len(c.stmts) >= maxCachedStatements {
+		stmt.Close()
+		return nil, nil
+	}
+	c.stmts[query] = stmt
+	return stmt, nil
+}
+
+// Close finalizes the prepared statements. The database is left open, and queries fail afterwards.
+func (c *stmtCache) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return nil
+	}
+	c.closed = true
+	var err error
+	for query, stmt := range c.stmts {
+		if cerr := stmt.Close(); cerr != nil && err == nil {
+			err = cerr
+		}
+		delete(c.stmts, query)
+	}
+	return err
+}
@@ -0,0 +1,114 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStmtCache(t *testing.T) {
+	db, cleanup := CreateTestDb(t)
+	defer cleanup()
+	load, err := NewSQLLiteLoader(db)
+	require.NoError(t, err)
+	require.NoError(t, load.Migrate(context.TODO()))
+	require.NoError(t, NewSQLLoaderForDirectory(load, "../../manifests").Populate())
+
+	querier := NewSQLLiteQuerierFromDbWithStmtCache(db)
+	stmts := querier.db.(*stmtCache)
+	ctx := context.TODO()
+
+	// each query is prepared once, however often and from however many goroutines it is run
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 10; j++ {
+				bundle, err := querier.GetBundleForChannel(ctx, "etcd", "alpha")
+				assert.NoError(t, err)
+				assert.Equal(t, "etcdoperator.v0.9.2", bundle.GetCsvName())
+			}
+		}()
+	}
+	wg.Wait()
+	prepared := len(stmts.stmts)
+	require.NotZero(t, prepared)
+	_, err = querier.GetBundleForChannel(ctx, "etcd", "alpha")
+	require.NoError(t, err)
+	require.Len(t, stmts.stmts, prepared)
+
+	// once the cache is full, new queries are still run
+	for i := 0; i <= maxCachedStatements; i++ {
+		rows, err := stmts.QueryContext(ctx, "SELECT "+strconv.Itoa(i))
+		require.NoError(t, err)
+		require.NoError(t, rows.Close())
+	}
+	require.Len(t, stmts.stmts, maxCachedStatements)
+
+	// closing the querier finalizes its statements and leaves the database open
+	require.NoError(t, querier.Close())
+	require.Empty(t, stmts.stmts)
+	_, err = querier.GetBundleForChannel(ctx, "etcd", "alpha")
+	require.True(t, errors.Is(err, errStmtCacheClosed))
+	require.NoError(t, querier.Close())
+	require.NoError(t, db.Ping())
+}
+
+func BenchmarkSQLQuerier(b *testing.B) {
+	f, err := ioutil.TempFile("", "benchmark-*.db")
+	if err != nil {
+		b.Fatal(err)
+	}
+	f.Close()
+	defer os.Remove(f.Name())
+	db, err := sql.Open("sqlite3", f.Name())
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer db.Close()
+	load, err := NewSQLLiteLoader(db)
+	if err != nil {
+		b.Fatal(err)
+	}
+	if err := load.Migrate(context.TODO()); err != nil {
+		b.Fatal(err)
+	}
+	if err := NewSQLLoaderForDirectory(load, "../../manifests").Populate(); err != nil {
+		b.Fatal(err)
+	}
+
+	cached := NewSQLLiteQuerierFromDbWithStmtCache(db)
+	defer cached.Close()
+	for _, bc := range []struct {
+		name    string
+		querier *SQLQuerier
+	}{
+		{name: "Unprepared", querier: NewSQLLiteQuerierFromDb(db)},
+		{name: "StmtCache", querier: cached},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			ctx := context.TODO()
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, err := bc.querier.GetPackage(ctx, "etcd"); err != nil {
+					b.Fatal(err)
+				}
+				if _, err := bc.querier.GetBundleForChannel(ctx, "etcd", "alpha"); err != nil {
+					b.Fatal(err)
+				}
+				if _, err := bc.querier.GetDefaultPackage(ctx, "etcd"); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
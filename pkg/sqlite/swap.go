@@ -0,0 +1,172 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sync"
+
+	"github.com/operator-framework/operator-registry/pkg/lib/tmp"
+	"github.com/operator-framework/operator-registry/pkg/registry"
+)
+
+// BuildFunc writes a database to be served to path
+type BuildFunc func(ctx context.Context, path string) error
+
+// MemoryBuildFunc builds a database to be served in memory
+type MemoryBuildFunc func(ctx context.Context) (*MemoryDatabase, error)
+
+// ValidateFunc checks a newly built database before it is served
+type ValidateFunc func(ctx context.Context, querier registry.Query) error
+
+// ValidateServable checks that a database has tables and that its packages can be listed
+func ValidateServable(ctx context.Context, querier registry.Query) error {
+	tables, err := querier.ListTables(ctx)
+	if err != nil {
+		return fmt.Errorf("couldn't list tables: %s", err)
+	}
+	if len(tables) == 0 {
+		return fmt.Errorf("no tables found in db")
+	}
+	if _, err := querier.ListPackages(ctx); err != nil {
+		return fmt.Errorf("couldn't list packages: %s", err)
+	}
+	return nil
+}
+
+// DatabaseSwapper serves a database built into a temporary file or in memory, and replaces it with a newly built one without
+// interrupting the queries being served. The old database is only closed and removed once the queries made to it
+// have finished.
+type DatabaseSwapper struct {
+	open     func(ctx context.Context) (*sql.DB, func() error, error)
+	validate ValidateFunc
+	query    *registry.SwappableQuery
+
+	// mu serializes refreshes
+	mu     sync.Mutex
+	served *builtDatabase
+}
+
+type builtDatabase struct {
+	querier *SQLQuerier
+	// close closes the database and drops it
+	close func() error
+}
+
+func (b *builtDatabase) remove() error {
+	err := b.querier.Close()
+	if cerr := b.close(); cerr != nil && err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// NewDatabaseSwapper builds and validates the first database to serve. A nil validate defaults to ValidateServable.
+func NewDatabaseSwapper(ctx context.Context, build BuildFunc, validate ValidateFunc) (*DatabaseSwapper, error) {
+	return newDatabaseSwapper(ctx, func(ctx context.Context) (*sql.DB, func() error, error) {
+		return buildFile(ctx, build)
+	}, validate)
+}
+
+// NewMemoryDatabaseSwapper is NewDatabaseSwapper for databases that are built and served in memory, e.g. encrypted
+// databases, which must not be written to disk once decrypted
+func NewMemoryDatabaseSwapper(ctx context.Context, build MemoryBuildFunc, validate ValidateFunc) (*DatabaseSwapper, error) {
+	return newDatabaseSwapper(ctx, func(ctx context.Context) (*sql.DB, func() error, error) {
+		m, err := build(ctx)
+		if err != nil {
+			return nil, nil, fmt.Errorf("error building database: %s", err)
+		}
+		return m.DB(), m.Close, nil
+	}, validate)
+}
+
+func newDatabaseSwapper(ctx context.Context, open func(ctx context.Context) (*sql.DB, func() error, error), validate ValidateFunc) (*DatabaseSwapper, error) {
+	if validate == nil {
+		validate = ValidateServable
+	}
+	s := &DatabaseSwapper{open: open, validate: validate}
+	built, querier, err := s.buildDatabase(ctx)
+	if err != nil {
+		return nil, err
+	}
+	s.served = built
+	s.query = registry.NewSwappableQuery(querier)
+	return s, nil
+}
+
+// Query returns the Query that always serves the current database
+func (s *DatabaseSwapper) Query() registry.Query {
+	return s.query
+}
+
+// Refresh builds and validates a new database and switches to serving it. If it can't be built or isn't valid, the
+// current database keeps being served.
+func (s *DatabaseSwapper) Refresh(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	built, querier, err := s.buildDatabase(ctx)
+	if err != nil {
+		return err
+	}
+
+	s.query.Swap(querier)
+	old := s.served
+	s.served = built
+	return old.remove()
+}
+
+// Close closes and removes the database being served. The Query must not be used afterwards.
+func (s *DatabaseSwapper) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.served.remove()
+}
+
+func (s *DatabaseSwapper) buildDatabase(ctx context.Context) (*builtDatabase, *SQLQuerier, error) {
+	db, close, err := s.open(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	querier := NewSQLLiteQuerierFromDbWithStmtCache(db)
+	built := &builtDatabase{querier: querier, close: close}
+
+	if err := s.validate(ctx, querier); err != nil {
+		built.remove()
+		return nil, nil, fmt.Errorf("new database is not valid: %s", err)
+	}
+	return built, querier, nil
+}
+
+// buildFile builds a database into a temporary file and opens it, returning a func that closes and removes it
+func buildFile(ctx context.Context, build BuildFunc) (*sql.DB, func() error, error) {
+	f, err := ioutil.TempFile(tmp.WorkDir(), "db-")
+	if err != nil {
+		return nil, nil, err
+	}
+	path := f.Name()
+	if err := f.Close(); err != nil {
+		os.Remove(path)
+		return nil, nil, err
+	}
+
+	if err := build(ctx, path); err != nil {
+		os.Remove(path)
+		return nil, nil, fmt.Errorf("error building database: %s", err)
+	}
+
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		os.Remove(path)
+		return nil, nil, err
+	}
+	return db, func() error {
+		err := db.Close()
+		if rerr := os.Remove(path); rerr != nil && err == nil {
+			err = rerr
+		}
+		return err
+	}, nil
+}
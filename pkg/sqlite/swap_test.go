@@ -0,0 +1,111 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/operator-framework/operator-registry/pkg/registry"
+)
+
+func TestDatabaseSwapper(t *testing.T) {
+	ctx := context.TODO()
+
+	// each build loads the catalog, and a build after the first can be made to fail
+	var builds []string
+	var failBuild error
+	build := func(ctx context.Context, path string) error {
+		if failBuild != nil {
+			return failBuild
+		}
+		builds = append(builds, path)
+		db, err := sql.Open("sqlite3", path)
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+		load, err := NewSQLLiteLoader(db)
+		if err != nil {
+			return err
+		}
+		if err := load.Migrate(ctx); err != nil {
+			return err
+		}
+		return NewSQLLoaderForDirectory(load, "../../manifests").Populate()
+	}
+
+	swapper, err := NewDatabaseSwapper(ctx, build, nil)
+	require.NoError(t, err)
+	packages, err := swapper.Query().ListPackages(ctx)
+	require.NoError(t, err)
+	require.ElementsMatch(t, []string{"etcd", "prometheus", "strimzi-kafka-operator"}, packages)
+
+	// a refresh swaps in a new database and removes the old one
+	require.NoError(t, swapper.Refresh(ctx))
+	require.Len(t, builds, 2)
+	_, err = os.Stat(builds[0])
+	require.True(t, os.IsNotExist(err))
+	packages, err = swapper.Query().ListPackages(ctx)
+	require.NoError(t, err)
+	require.Len(t, packages, 3)
+
+	// a failed refresh keeps serving the current database
+	failBuild = errors.New("build failed")
+	require.Error(t, swapper.Refresh(ctx))
+	packages, err = swapper.Query().ListPackages(ctx)
+	require.NoError(t, err)
+	require.Len(t, packages, 3)
+
+	require.NoError(t, swapper.Close())
+	_, err = os.Stat(builds[1])
+	require.True(t, os.IsNotExist(err))
+}
+
+func TestDatabaseSwapperInvalid(t *testing.T) {
+	// an empty database has no tables to serve
+	_, err := NewDatabaseSwapper(context.TODO(), func(ctx context.Context, path string) error { return nil }, nil)
+	require.Error(t, err)
+
+	invalid := errors.New("invalid")
+	_, err = NewDatabaseSwapper(context.TODO(), func(ctx context.Context, path string) error { return nil }, func(ctx context.Context, querier registry.Query) error { return invalid })
+	require.Error(t, err)
+}
+
+func TestMemoryDatabaseSwapper(t *testing.T) {
+	ctx := context.TODO()
+
+	var built []*MemoryDatabase
+	swapper, err := NewMemoryDatabaseSwapper(ctx, func(ctx context.Context) (*MemoryDatabase, error) {
+		m, err := OpenMemoryDatabase(ctx)
+		if err != nil {
+			return nil, err
+		}
+		load, err := NewSQLLiteLoader(m.DB())
+		if err != nil {
+			return nil, err
+		}
+		if err := load.Migrate(ctx); err != nil {
+			return nil, err
+		}
+		if err := NewSQLLoaderForDirectory(load, "../../manifests").Populate(); err != nil {
+			return nil, err
+		}
+		built = append(built, m)
+		return m, nil
+	}, nil)
+	require.NoError(t, err)
+	require.NoError(t, swapper.Refresh(ctx))
+	packages, err := swapper.Query().ListPackages(ctx)
+	require.NoError(t, err)
+	require.ElementsMatch(t, []string{"etcd", "prometheus", "strimzi-kafka-operator"}, packages)
+
+	// the database that was swapped out is dropped
+	require.Len(t, built, 2)
+	require.Error(t, built[0].DB().Ping())
+	require.NoError(t, swapper.Close())
+	require.Error(t, built[1].DB().Ping())
+}
@@ -0,0 +1,357 @@
+package sqlite
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// bundlesOfPackage selects the bundles that belong to the package passed as the query argument
+const bundlesOfPackage = `SELECT operatorbundle_name FROM channel_entry WHERE package_name = ?`
+
+// tombstoneTables lists, in an order that satisfies foreign keys on restore, every table holding
+// package data along with the condition that selects the rows belonging to a package
+var tombstoneTables = []struct {
+	table string
+	where string
+}{
+	{"api", `EXISTS (SELECT 1 FROM api_provider p WHERE p.group_name = api.group_name AND p.version = api.version AND p.kind = api.kind AND p.operatorbundle_name IN (` + bundlesOfPackage + `))
+		OR EXISTS (SELECT 1 FROM api_requirer r WHERE r.group_name = api.group_name AND r.version = api.version AND r.kind = api.kind AND r.operatorbundle_name IN (` + bundlesOfPackage + `))`},
+	{"operatorbundle", `name IN (` + bundlesOfPackage + `)`},
+	{"related_image", `operatorbundle_name IN (` + bundlesOfPackage + `)`},
+	{"api_provider", `operatorbundle_name IN (` + bundlesOfPackage + `)`},
+	{"api_requirer", `operatorbundle_name IN (` + bundlesOfPackage + `)`},
+	{"dependencies", `operatorbundle_name IN (` + bundlesOfPackage + `)`},
+	{"properties", `operatorbundle_name IN (` + bundlesOfPackage + `)`},
+	{"bundle_attestation", `operatorbundle_name IN (` + bundlesOfPackage + `)`},
+	{"channel_override", `operatorbundle_name IN (` + bundlesOfPackage + `)`},
+	{"bundle_image", `operatorbundle_name IN (` + bundlesOfPackage + `)`},
+	{"deprecated", `operatorbundle_name IN (` + bundlesOfPackage + `)`},
+	{"bundle_stats", `operatorbundle_name IN (` + bundlesOfPackage + `)`},
+	{"channel", `package_name = ?`},
+	{"package", `name = ?`},
+	{"channel_entry", `package_name = ?`},
+	{"channel_selector", `package_name = ?`},
+}
+
+// tableSnapshot holds the rows of a single table
+type tableSnapshot struct {
+	Table   string          `json:"table"`
+	Columns []string        `json:"columns"`
+	Rows    [][]interface{} `json:"rows"`
+}
+
+// SoftRemovePackage removes a package, keeping a snapshot of its rows as a tombstone so that the removal can be undone
+func (s *sqlLoader) SoftRemovePackage(packageName string) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		tx.Rollback()
+	}()
+
+	var snapshot []tableSnapshot
+	found := false
+	for _, t := range tombstoneTables {
+		rows, err := snapshotRows(tx, t.table, t.where, packageName)
+		if err != nil {
+			return err
+		}
+		if t.table == "package" && len(rows.Rows) > 0 {
+			found = true
+		}
+		snapshot = append(snapshot, *rows)
+	}
+
+	// nothing to keep for packages that aren't in the database
+	if found {
+		out, err := json.Marshal(snapshot)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(`INSERT OR REPLACE INTO package_tombstone(package_name, deleted_at, snapshot) VALUES (?, ?, ?)`, packageName, time.Now().Unix(), string(out)); err != nil {
+			return err
+		}
+	}
+
+	csvNames, err := s.getCSVNames(tx, packageName)
+	if err != nil {
+		return err
+	}
+	for _, csvName := range csvNames {
+		if err := s.rmBundle(tx, csvName); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// RestorePackage restores a soft-deleted package from its tombstone. Tombstones older than window can no longer be
+// restored; a zero window restores tombstones of any age.
+func (s *sqlLoader) RestorePackage(packageName string, window time.Duration) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		tx.Rollback()
+	}()
+
+	var deletedAt int64
+	var data string
+	err = tx.QueryRow(`SELECT deleted_at, snapshot FROM package_tombstone WHERE package_name = ?`, packageName).Scan(&deletedAt, &data)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("no removed package %s to restore", packageName)
+	}
+	if err != nil {
+		return err
+	}
+	if window > 0 && time.Since(time.Unix(deletedAt, 0)) > window {
+		return fmt.Errorf("package %s was removed more than %s ago and can no longer be restored", packageName, window)
+	}
+
+	var exists int
+	if err := tx.QueryRow(`SELECT COUNT(*) FROM package WHERE name = ?`, packageName).Scan(&exists); err != nil {
+		return err
+	}
+	if exists > 0 {
+		return fmt.Errorf("package %s has been added again since it was removed", packageName)
+	}
+
+	var snapshot []tableSnapshot
+	dec := json.NewDecoder(bytes.NewReader([]byte(data)))
+	dec.UseNumber()
+	if err := dec.Decode(&snapshot); err != nil {
+		return err
+	}
+
+	for _, t := range snapshot {
+		if t.Table == "channel_entry" {
+			if err := restoreChannelEntries(tx, t); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := restoreRows(tx, t); err != nil {
+			return err
+		}
+	}
+
+	if _, err := tx.Exec(`DELETE FROM package_tombstone WHERE package_name = ?`, packageName); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// PurgeTombstones permanently drops the tombstones of the given packages (or all packages if none are given)
+// that are older than olderThan, and returns the purged package names.
+func (s *sqlLoader) PurgeTombstones(packages []string, olderThan time.Duration) ([]string, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		tx.Rollback()
+	}()
+
+	rows, err := tx.Query(`SELECT package_name, deleted_at FROM package_tombstone`)
+	if err != nil {
+		return nil, err
+	}
+
+	selected := map[string]struct{}{}
+	for _, pkg := range packages {
+		selected[pkg] = struct{}{}
+	}
+
+	cutoff := time.Now().Add(-olderThan).Unix()
+	var purged []string
+	for rows.Next() {
+		var name string
+		var deletedAt int64
+		if err := rows.Scan(&name, &deletedAt); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		if _, ok := selected[name]; len(selected) > 0 && !ok {
+			continue
+		}
+		if deletedAt <= cutoff {
+			purged = append(purged, name)
+		}
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+
+	for _, name := range purged {
+		if _, err := tx.Exec(`DELETE FROM package_tombstone WHERE package_name = ?`, name); err != nil {
+			return nil, err
+		}
+	}
+
+	return purged, tx.Commit()
+}
+
+// ListTombstones returns the names of the soft-deleted packages that can still be restored
+func (s *sqlLoader) ListTombstones() ([]string, error) {
+	rows, err := s.db.Query(`SELECT package_name FROM package_tombstone ORDER BY package_name`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+	}
+	return names, nil
+}
+
+func snapshotRows(tx *sql.Tx, table, where, packageName string) (*tableSnapshot, error) {
+	query := fmt.Sprintf(`SELECT * FROM %s WHERE %s`, table, where)
+	args := make([]interface{}, strings.Count(where, "?"))
+	for i := range args {
+		args[i] = packageName
+	}
+	rows, err := tx.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+
+	snapshot := &tableSnapshot{Table: table, Columns: columns}
+	for rows.Next() {
+		values := make([]interface{}, len(columns))
+		ptrs := make([]interface{}, len(columns))
+		for i := range values {
+			ptrs[i] = &values[i]
+		}
+		if err := rows.Scan(ptrs...); err != nil {
+			return nil, err
+		}
+		for i, v := range values {
+			if b, ok := v.([]byte); ok {
+				values[i] = string(b)
+			}
+		}
+		snapshot.Rows = append(snapshot.Rows, values)
+	}
+	return snapshot, rows.Err()
+}
+
+func restoreRows(tx *sql.Tx, t tableSnapshot) error {
+	if len(t.Rows) == 0 {
+		return nil
+	}
+	// apis are shared between packages, so they may still be present
+	verb := "INSERT"
+	if t.Table == "api" {
+		verb = "INSERT OR IGNORE"
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(t.Columns)), ", ")
+	insert, err := tx.Prepare(fmt.Sprintf(`%s INTO %s(%s) VALUES (%s)`, verb, t.Table, strings.Join(t.Columns, ", "), placeholders))
+	if err != nil {
+		return err
+	}
+	defer insert.Close()
+
+	for _, row := range t.Rows {
+		if _, err := insert.Exec(sqlValues(row)...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// restoreChannelEntries re-inserts channel entries under new ids, since the original ids may have been reused,
+// and rewrites the replaces references to match
+func restoreChannelEntries(tx *sql.Tx, t tableSnapshot) error {
+	idColumn, replacesColumn := -1, -1
+	var columns []string
+	for i, c := range t.Columns {
+		switch c {
+		case "entry_id":
+			idColumn = i
+		case "replaces":
+			replacesColumn = i
+		default:
+			columns = append(columns, c)
+		}
+	}
+	if idColumn < 0 || replacesColumn < 0 {
+		return fmt.Errorf("unexpected channel_entry columns %v", t.Columns)
+	}
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(columns)), ", ")
+	insert, err := tx.Prepare(fmt.Sprintf(`INSERT INTO channel_entry(%s) VALUES (%s)`, strings.Join(columns, ", "), placeholders))
+	if err != nil {
+		return err
+	}
+	defer insert.Close()
+
+	ids := map[string]int64{}
+	for _, row := range t.Rows {
+		var values []interface{}
+		for i, v := range row {
+			if i != idColumn && i != replacesColumn {
+				values = append(values, v)
+			}
+		}
+		res, err := insert.Exec(sqlValues(values)...)
+		if err != nil {
+			return err
+		}
+		id, err := res.LastInsertId()
+		if err != nil {
+			return err
+		}
+		ids[fmt.Sprint(row[idColumn])] = id
+	}
+
+	for _, row := range t.Rows {
+		if row[replacesColumn] == nil {
+			continue
+		}
+		replaces, ok := ids[fmt.Sprint(row[replacesColumn])]
+		if !ok {
+			continue
+		}
+		if _, err := tx.Exec(`UPDATE channel_entry SET replaces = ? WHERE entry_id = ?`, replaces, ids[fmt.Sprint(row[idColumn])]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sqlValues converts decoded json numbers back into values sqlite can bind
+func sqlValues(row []interface{}) []interface{} {
+	values := make([]interface{}, len(row))
+	for i, v := range row {
+		if n, ok := v.(json.Number); ok {
+			if iv, err := n.Int64(); err == nil {
+				values[i] = iv
+				continue
+			}
+			values[i] = n.String()
+			continue
+		}
+		values[i] = v
+	}
+	return values
+}
@@ -0,0 +1,77 @@
+package sqlite
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+	"github.com/sirupsen/logrus"
+)
+
+// transaction runs fn in a transaction, which is committed if fn succeeds and rolled back otherwise. Another process
+// may be writing to the same database, so a transaction that fails because the database is busy is run again,
+// from the start, according to the retry policy of the loader.
+func (s *sqlLoader) transaction(fn func(tx *sql.Tx) error) error {
+	attempts := s.retry.Attempts
+	if attempts < 1 {
+		attempts = 1
+	}
+	backoff := s.retry.Backoff
+
+	var err error
+	for attempt := 1; ; attempt++ {
+		if err = runTransaction(s.db, fn); err == nil || !isBusy(err) || attempt >= attempts {
+			return err
+		}
+		logrus.WithError(err).Debugf("database busy, retrying in %s", backoff)
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+func runTransaction(db *sql.DB, fn func(tx *sql.Tx) error) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		tx.Rollback()
+	}()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// savepoint runs fn within a savepoint of a transaction. If fn fails, what it wrote is rolled back while the rest of
+// the transaction is kept.
+func savepoint(tx *sql.Tx, name string, fn func() error) error {
+	if _, err := tx.Exec("SAVEPOINT " + name); err != nil {
+		return err
+	}
+	if err := fn(); err != nil {
+		if _, rerr := tx.Exec("ROLLBACK TO " + name); rerr != nil {
+			return fmt.Errorf("%s, and couldn't roll back to savepoint %s: %s", err, name, rerr)
+		}
+		if _, rerr := tx.Exec("RELEASE " + name); rerr != nil {
+			return fmt.Errorf("%s, and couldn't release savepoint %s: %s", err, name, rerr)
+		}
+		return err
+	}
+	_, err := tx.Exec("RELEASE " + name)
+	return err
+}
+
+// isBusy returns true if err is SQLite refusing a statement because another connection holds the database
+func isBusy(err error) bool {
+	var sqliteErr sqlite3.Error
+	if errors.As(err, &sqliteErr) {
+		return sqliteErr.Code == sqlite3.ErrBusy || sqliteErr.Code == sqlite3.ErrLocked
+	}
+	// most loader errors are wrapped as text
+	return strings.Contains(err.Error(), sqlite3.ErrBusy.Error()) || strings.Contains(err.Error(), sqlite3.ErrLocked.Error())
+}
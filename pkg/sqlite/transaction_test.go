@@ -0,0 +1,92 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransactionRetry(t *testing.T) {
+	dir, err := ioutil.TempDir("", "transaction-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "index.db")
+
+	opts := []DbOption{WithBusyTimeout(time.Millisecond), WithRetryPolicy(RetryPolicy{Attempts: 10, Backoff: 20 * time.Millisecond})}
+	require.Equal(t, path+"?_busy_timeout=1&_txlock=immediate", DSN(path, opts...))
+	db, err := sql.Open("sqlite3", DSN(path, opts...))
+	require.NoError(t, err)
+	defer db.Close()
+	loader, err := NewSQLLiteLoader(db, opts...)
+	require.NoError(t, err)
+	require.NoError(t, loader.Migrate(context.TODO()))
+
+	// another process writing to the database holds its write lock for a while
+	other, err := sql.Open("sqlite3", path)
+	require.NoError(t, err)
+	defer other.Close()
+	lock := func(d time.Duration) {
+		conn, err := other.Conn(context.TODO())
+		require.NoError(t, err)
+		_, err = conn.ExecContext(context.TODO(), "BEGIN IMMEDIATE")
+		require.NoError(t, err)
+		go func() {
+			defer conn.Close()
+			time.Sleep(d)
+			conn.ExecContext(context.TODO(), "COMMIT")
+		}()
+	}
+
+	lock(100 * time.Millisecond)
+	require.NoError(t, loader.ClearNonHeadBundles())
+
+	// without retries, the busy database fails the transaction
+	loader, err = NewSQLLiteLoader(db, WithBusyTimeout(time.Millisecond), WithRetryPolicy(RetryPolicy{Attempts: 1}))
+	require.NoError(t, err)
+	lock(100 * time.Millisecond)
+	err = loader.ClearNonHeadBundles()
+	require.Error(t, err)
+	require.True(t, isBusy(err))
+}
+
+func TestSavepoint(t *testing.T) {
+	db, cleanup := CreateTestDb(t)
+	defer cleanup()
+	_, err := db.Exec("CREATE TABLE names (name TEXT)")
+	require.NoError(t, err)
+
+	tx, err := db.Begin()
+	require.NoError(t, err)
+	_, err = tx.Exec("INSERT INTO names VALUES ('kept')")
+	require.NoError(t, err)
+	require.NoError(t, savepoint(tx, "added", func() error {
+		_, err := tx.Exec("INSERT INTO names VALUES ('added')")
+		return err
+	}))
+	// what a failed savepoint wrote is rolled back, the rest of the transaction isn't
+	require.Error(t, savepoint(tx, "failed", func() error {
+		if _, err := tx.Exec("INSERT INTO names VALUES ('rolled back')"); err != nil {
+			return err
+		}
+		_, err := tx.Exec("INSERT INTO missing VALUES ('rolled back')")
+		return err
+	}))
+	require.NoError(t, tx.Commit())
+
+	rows, err := db.Query("SELECT name FROM names ORDER BY name")
+	require.NoError(t, err)
+	defer rows.Close()
+	var names []string
+	for rows.Next() {
+		var name string
+		require.NoError(t, rows.Scan(&name))
+		names = append(names, name)
+	}
+	require.Equal(t, []string{"added", "kept"}, names)
+}
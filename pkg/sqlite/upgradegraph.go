@@ -0,0 +1,120 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/blang/semver"
+
+	"github.com/operator-framework/operator-registry/pkg/registry"
+)
+
+// GetUpgradeGraph returns the upgrade graph of every channel of a package. Edges are only drawn between bundles of the
+// same channel, since installs never upgrade out of their channel: to each bundle from the bundle it replaces, the
+// bundles it skips and the bundles whose version is in its skip range.
+func (s *SQLQuerier) GetUpgradeGraph(ctx context.Context, pkgName string) (*registry.UpgradeGraph, error) {
+	defaultChannel, err := s.GetDefaultPackage(ctx, pkgName)
+	if err != nil {
+		return nil, err
+	}
+	graph := &registry.UpgradeGraph{
+		PackageName:    pkgName,
+		DefaultChannel: defaultChannel,
+		Channels:       []registry.UpgradeGraphChannel{},
+	}
+
+	channels := map[string]*registry.UpgradeGraphChannel{}
+	err = s.forEachRow(ctx, "channels", `SELECT name, head_operatorbundle_name FROM channel WHERE package_name=? ORDER BY name`, []interface{}{pkgName}, func(row scanner) error {
+		var name, head sql.NullString
+		if err := row.Scan(&name, &head); err != nil {
+			return err
+		}
+		graph.Channels = append(graph.Channels, registry.UpgradeGraphChannel{
+			Name:  name.String,
+			Head:  head.String,
+			Nodes: []registry.UpgradeGraphNode{},
+			Edges: []registry.UpgradeGraphEdge{},
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	for i := range graph.Channels {
+		channels[graph.Channels[i].Name] = &graph.Channels[i]
+	}
+
+	query := `SELECT DISTINCT channel_entry.channel_name, operatorbundle.name, operatorbundle.version, operatorbundle.bundlepath, operatorbundle.replaces, operatorbundle.skips, operatorbundle.skiprange, ` + isDeprecated("operatorbundle.name") + `
+		FROM channel_entry
+		INNER JOIN operatorbundle ON operatorbundle.name = channel_entry.operatorbundle_name
+		WHERE channel_entry.package_name = ?
+		ORDER BY channel_entry.channel_name, operatorbundle.name`
+	type upgrades struct {
+		replaces  string
+		skips     []string
+		skipRange string
+	}
+	declared := map[string][]upgrades{}
+	err = s.forEachRow(ctx, "channel entries", query, []interface{}{pkgName}, func(row scanner) error {
+		var channelName, name, version, bundlePath, replaces, skips, skipRange sql.NullString
+		var deprecated bool
+		if err := row.Scan(&channelName, &name, &version, &bundlePath, &replaces, &skips, &skipRange, &deprecated); err != nil {
+			return err
+		}
+		channel, ok := channels[channelName.String]
+		if !ok {
+			return nil
+		}
+		channel.Nodes = append(channel.Nodes, registry.UpgradeGraphNode{
+			CsvName:    name.String,
+			Version:    version.String,
+			BundlePath: bundlePath.String,
+			SkipRange:  skipRange.String,
+			Deprecated: deprecated,
+		})
+		declared[channel.Name] = append(declared[channel.Name], upgrades{replaces: replaces.String, skips: splitSkips(skips), skipRange: skipRange.String})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range graph.Channels {
+		channel := &graph.Channels[i]
+		inChannel := map[string]bool{}
+		versions := map[string]semver.Version{}
+		for _, node := range channel.Nodes {
+			inChannel[node.CsvName] = true
+			if v, err := semver.Parse(node.Version); err == nil {
+				versions[node.CsvName] = v
+			}
+		}
+		edge := func(from, to string, edgeType registry.UpgradeEdgeType) {
+			if from != to && inChannel[from] {
+				channel.Edges = append(channel.Edges, registry.UpgradeGraphEdge{From: from, To: to, Type: edgeType})
+			}
+		}
+		for j, node := range channel.Nodes {
+			u := declared[channel.Name][j]
+			edge(u.replaces, node.CsvName, registry.UpgradeEdgeReplaces)
+			for _, skip := range u.skips {
+				edge(skip, node.CsvName, registry.UpgradeEdgeSkips)
+			}
+			if u.skipRange == "" {
+				continue
+			}
+			inRange, err := semver.ParseRange(u.skipRange)
+			if err != nil {
+				// an invalid skip range upgrades nothing, as for OLM
+				continue
+			}
+			for _, other := range channel.Nodes {
+				if v, ok := versions[other.CsvName]; ok && inRange(v) {
+					edge(other.CsvName, node.CsvName, registry.UpgradeEdgeSkipRange)
+				}
+			}
+		}
+	}
+
+	return graph, nil
+}
@@ -0,0 +1,80 @@
+package sqlite
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/operator-framework/operator-registry/pkg/registry"
+)
+
+func TestGetUpgradeGraph(t *testing.T) {
+	db, cleanup := CreateTestDb(t)
+	defer cleanup()
+	store, err := NewSQLLiteLoader(db)
+	require.NoError(t, err)
+	require.NoError(t, store.Migrate(context.TODO()))
+
+	for _, b := range []*registry.Bundle{
+		newBundle(t, "csv-a", "pkg", []string{"stable", "beta"}, newUnstructuredCSVWithSkipRange(t, "csv-a", "1.0.0", "", "")),
+		newBundle(t, "csv-b", "pkg", []string{"stable", "beta"}, newUnstructuredCSVWithSkipRange(t, "csv-b", "1.1.0", "csv-a", "")),
+		newBundle(t, "csv-c", "pkg", []string{"stable"}, newUnstructuredCSVWithSkipRange(t, "csv-c", "2.0.0", "csv-b", ">=1.0.0 <2.0.0")),
+		newBundle(t, "csv-d", "pkg", []string{"stable"}, newUnstructuredCSVwithSkips(t, "csv-d", "csv-c", "csv-b", "csv-missing")),
+	} {
+		b.BundleImage = "quay.io/example/" + b.Name
+		require.NoError(t, store.AddOperatorBundle(b))
+	}
+	require.NoError(t, store.AddPackageChannels(registry.PackageManifest{
+		PackageName: "pkg",
+		Channels: []registry.PackageChannel{
+			{Name: "stable", CurrentCSVName: "csv-d"},
+			{Name: "beta", CurrentCSVName: "csv-b"},
+		},
+		DefaultChannelName: "stable",
+	}))
+	require.NoError(t, store.MarkBundleDeprecated("quay.io/example/csv-a"))
+
+	querier := NewSQLLiteQuerierFromDb(db)
+	graph, err := querier.GetUpgradeGraph(context.TODO(), "pkg")
+	require.NoError(t, err)
+	require.Equal(t, &registry.UpgradeGraph{
+		PackageName:    "pkg",
+		DefaultChannel: "stable",
+		Channels: []registry.UpgradeGraphChannel{
+			{
+				Name: "beta",
+				Head: "csv-b",
+				Nodes: []registry.UpgradeGraphNode{
+					{CsvName: "csv-a", Version: "1.0.0", BundlePath: "quay.io/example/csv-a", Deprecated: true},
+					{CsvName: "csv-b", Version: "1.1.0", BundlePath: "quay.io/example/csv-b"},
+				},
+				Edges: []registry.UpgradeGraphEdge{
+					{From: "csv-a", To: "csv-b", Type: registry.UpgradeEdgeReplaces},
+				},
+			},
+			{
+				Name: "stable",
+				Head: "csv-d",
+				Nodes: []registry.UpgradeGraphNode{
+					{CsvName: "csv-a", Version: "1.0.0", BundlePath: "quay.io/example/csv-a", Deprecated: true},
+					{CsvName: "csv-b", Version: "1.1.0", BundlePath: "quay.io/example/csv-b"},
+					{CsvName: "csv-c", Version: "2.0.0", BundlePath: "quay.io/example/csv-c", SkipRange: ">=1.0.0 <2.0.0"},
+					{CsvName: "csv-d", BundlePath: "quay.io/example/csv-d"},
+				},
+				// skips of bundles that aren't in the channel draw no edges
+				Edges: []registry.UpgradeGraphEdge{
+					{From: "csv-a", To: "csv-b", Type: registry.UpgradeEdgeReplaces},
+					{From: "csv-b", To: "csv-c", Type: registry.UpgradeEdgeReplaces},
+					{From: "csv-a", To: "csv-c", Type: registry.UpgradeEdgeSkipRange},
+					{From: "csv-b", To: "csv-c", Type: registry.UpgradeEdgeSkipRange},
+					{From: "csv-c", To: "csv-d", Type: registry.UpgradeEdgeReplaces},
+					{From: "csv-b", To: "csv-d", Type: registry.UpgradeEdgeSkips},
+				},
+			},
+		},
+	}, graph)
+
+	_, err = querier.GetUpgradeGraph(context.TODO(), "missing")
+	require.Error(t, err)
+}
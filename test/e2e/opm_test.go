@@ -91,7 +91,7 @@ func buildIndexWith(containerTool, indexImage, bundleImage string, bundleTags []
 		Permissive:        false,
 	}
 
-	return indexAdder.AddToIndex(request)
+	return indexAdder.AddToIndex(context.TODO(), request)
 }
 
 func buildFromIndexWith(containerTool string) error {
@@ -111,7 +111,7 @@ func buildFromIndexWith(containerTool string) error {
 		Permissive:        false,
 	}
 
-	return indexAdder.AddToIndex(request)
+	return indexAdder.AddToIndex(context.TODO(), request)
 }
 
 // TODO(djzager): make this more complete than what should be a simple no-op
@@ -129,7 +129,7 @@ func pruneIndexWith(containerTool string) error {
 		Permissive:        false,
 	}
 
-	return indexAdder.PruneFromIndex(request)
+	return indexAdder.PruneFromIndex(context.TODO(), request)
 }
 
 func pushWith(containerTool, image string) error {
@@ -149,7 +149,7 @@ func exportPackageWith(containerTool string) error {
 		ContainerTool: containertools.NewContainerTool(containerTool, containertools.NoneTool),
 	}
 
-	return indexExporter.ExportFromIndex(request)
+	return indexExporter.ExportFromIndex(context.TODO(), request)
 }
 
 
@@ -165,7 +165,7 @@ func exportIndexImageWith(containerTool string) error {
 		ContainerTool: containertools.NewContainerTool(containerTool, containertools.NoneTool),
 	}
 
-	return indexExporter.ExportFromIndex(request)
+	return indexExporter.ExportFromIndex(context.TODO(), request)
 }
 
 func initialize() error {